@@ -28,6 +28,14 @@ Returns an empty string when experimentalBrackets.enabled is false.
 {{- end -}}
 {{- end -}}
 
+{{- define "kuberpult.autoRollbackEnvironments" -}}
+{{- $environments := list -}}
+{{- range $env, $gracePeriod := .Values.rollout.autoRollback.environments -}}
+{{- $environments = append $environments (printf "%s:%s" $env $gracePeriod) -}}
+{{- end -}}
+{{- sortAlpha $environments | join "," -}}
+{{- end -}}
+
 # Selects all root app filter clusters that are set to true
 {{- define "kuberpult.experimentalRootAppFilter" -}}
 {{- $envs := list -}}
@@ -93,6 +93,14 @@ func RunCLI() ReturnCode {
 		return handleGetDeploymentCommit(*kpClientParams, subflags)
 	case "delete-environment":
 		return handleDeleteEnvironment(*kpClientParams, subflags)
+	case "get-queued-deployments":
+		return handleGetQueuedDeployments(*kpClientParams, subflags)
+	case "cancel-queued-version":
+		return handleCancelQueuedVersion(*kpClientParams, subflags)
+	case "deploy-queued-version":
+		return handleDeployQueuedVersion(*kpClientParams, subflags)
+	case "get-release-notes":
+		return handleGetReleaseNotes(*kpClientParams, subflags)
 	default:
 		log.Printf("unknown subcommand %s\n", subcommand)
 		return ReturnCodeInvalidArguments
@@ -298,6 +298,118 @@ func handleGetDeploymentCommit(kpClientParams kuberpultClientParameters, args []
 	return ReturnCodeSuccess
 }
 
+func handleGetQueuedDeployments(kpClientParams kuberpultClientParameters, args []string) ReturnCode {
+	parsedArgs, err := deployments.ParseArgsQueuedDeployments(args)
+
+	if err != nil {
+		log.Printf("error while parsing command line args, error: %v", err)
+		return ReturnCodeInvalidArguments
+	}
+
+	authParams := kutil.AuthenticationParameters{
+		IapToken:    kpClientParams.iapToken,
+		DexToken:    kpClientParams.dexToken,
+		AuthorName:  kpClientParams.authorName,
+		AuthorEmail: kpClientParams.authorEmail,
+	}
+
+	requestParameters := kutil.RequestParameters{
+		Url:         &kpClientParams.url,
+		Retries:     kpClientParams.retries,
+		HttpTimeout: cli_utils.HttpDefaultTimeout,
+	}
+
+	if err = deployments.HandleGetQueuedDeployments(requestParameters, authParams, parsedArgs); err != nil {
+		log.Printf("error on get queued deployments, error: %v", err)
+		return ReturnCodeFailure
+	}
+	return ReturnCodeSuccess
+}
+
+func handleCancelQueuedVersion(kpClientParams kuberpultClientParameters, args []string) ReturnCode {
+	parsedArgs, err := deployments.ParseArgsCancelQueuedVersion(args)
+
+	if err != nil {
+		log.Printf("error while parsing command line args, error: %v", err)
+		return ReturnCodeInvalidArguments
+	}
+
+	authParams := kutil.AuthenticationParameters{
+		IapToken:    kpClientParams.iapToken,
+		DexToken:    kpClientParams.dexToken,
+		AuthorName:  kpClientParams.authorName,
+		AuthorEmail: kpClientParams.authorEmail,
+	}
+
+	requestParameters := kutil.RequestParameters{
+		Url:         &kpClientParams.url,
+		Retries:     kpClientParams.retries,
+		HttpTimeout: cli_utils.HttpDefaultTimeout,
+	}
+
+	if err = deployments.HandleCancelQueuedVersion(requestParameters, authParams, parsedArgs); err != nil {
+		log.Printf("error on cancel queued version, error: %v", err)
+		return ReturnCodeFailure
+	}
+	return ReturnCodeSuccess
+}
+
+func handleDeployQueuedVersion(kpClientParams kuberpultClientParameters, args []string) ReturnCode {
+	parsedArgs, err := deployments.ParseArgsDeployQueuedVersion(args)
+
+	if err != nil {
+		log.Printf("error while parsing command line args, error: %v", err)
+		return ReturnCodeInvalidArguments
+	}
+
+	authParams := kutil.AuthenticationParameters{
+		IapToken:    kpClientParams.iapToken,
+		DexToken:    kpClientParams.dexToken,
+		AuthorName:  kpClientParams.authorName,
+		AuthorEmail: kpClientParams.authorEmail,
+	}
+
+	requestParameters := kutil.RequestParameters{
+		Url:         &kpClientParams.url,
+		Retries:     kpClientParams.retries,
+		HttpTimeout: cli_utils.HttpDefaultTimeout,
+	}
+
+	if err = deployments.HandleDeployQueuedVersion(requestParameters, authParams, parsedArgs); err != nil {
+		log.Printf("error on deploy queued version, error: %v", err)
+		return ReturnCodeFailure
+	}
+	return ReturnCodeSuccess
+}
+
+func handleGetReleaseNotes(kpClientParams kuberpultClientParameters, args []string) ReturnCode {
+	parsedArgs, err := releasetrain.ParseArgsGetReleaseNotes(args)
+
+	if err != nil {
+		log.Printf("error while parsing command line args, error: %v", err)
+		return ReturnCodeInvalidArguments
+	}
+
+	authParams := kutil.AuthenticationParameters{
+		IapToken:    kpClientParams.iapToken,
+		DexToken:    kpClientParams.dexToken,
+		AuthorName:  kpClientParams.authorName,
+		AuthorEmail: kpClientParams.authorEmail,
+	}
+
+	requestParameters := kutil.RequestParameters{
+		Url:         &kpClientParams.url,
+		Retries:     kpClientParams.retries,
+		HttpTimeout: cli_utils.HttpDefaultTimeout,
+	}
+
+	if err = releasetrain.HandleGetReleaseNotes(requestParameters, authParams, parsedArgs); err != nil {
+		log.Printf("error on get release notes, error: %v", err)
+		return ReturnCodeFailure
+	}
+	return ReturnCodeSuccess
+}
+
 func handleDeleteEnvironment(kpClientParams kuberpultClientParameters, args []string) ReturnCode {
 	parsedArgs, err := environments.ParseArgsDeleteEnvironment(args)
 	if err != nil {
@@ -0,0 +1,154 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package deployments
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	urllib "net/url"
+	"strconv"
+
+	"github.com/freiheit-com/kuberpult/cli/pkg/cli_utils"
+	kutil "github.com/freiheit-com/kuberpult/cli/pkg/kuberpult_utils"
+)
+
+type QueuedDeploymentsParameters struct {
+	Env string
+	App string
+}
+
+type QueuedVersionParameters struct {
+	Env     string
+	App     string
+	Version uint64
+	// Reason is only set when the queued version is deployed
+	Reason string
+}
+
+type deployQueuedVersionJsonData struct {
+	Reason string `json:"reason"`
+}
+
+func HandleGetQueuedDeployments(requestParams kutil.RequestParameters, authParams kutil.AuthenticationParameters, params *QueuedDeploymentsParameters) error {
+	req, err := createHttpRequestGetQueuedDeployments(*requestParams.Url, authParams, params)
+	if err != nil {
+		return fmt.Errorf("error while preparing HTTP request, error: %w", err)
+	}
+	body, err := cli_utils.IssueHttpRequestWithBodyReturn(*req, requestParams.HttpTimeout)
+	if err != nil {
+		return fmt.Errorf("error while issuing HTTP request, error: %v", err)
+	}
+
+	fmt.Println(string(body))
+
+	return nil
+}
+
+func HandleCancelQueuedVersion(requestParams kutil.RequestParameters, authParams kutil.AuthenticationParameters, params *QueuedVersionParameters) error {
+	req, err := createHttpRequestQueuedVersion(*requestParams.Url, authParams, params, false)
+	if err != nil {
+		return fmt.Errorf("error while preparing HTTP request, error: %w", err)
+	}
+	if err = cli_utils.IssueHttpRequest(*req, requestParams.Retries, requestParams.HttpTimeout); err != nil {
+		return fmt.Errorf("error while issuing HTTP request, error: %v", err)
+	}
+	return nil
+}
+
+func HandleDeployQueuedVersion(requestParams kutil.RequestParameters, authParams kutil.AuthenticationParameters, params *QueuedVersionParameters) error {
+	req, err := createHttpRequestQueuedVersion(*requestParams.Url, authParams, params, true)
+	if err != nil {
+		return fmt.Errorf("error while preparing HTTP request, error: %w", err)
+	}
+	if err = cli_utils.IssueHttpRequest(*req, requestParams.Retries, requestParams.HttpTimeout); err != nil {
+		return fmt.Errorf("error while issuing HTTP request, error: %v", err)
+	}
+	return nil
+}
+
+func createHttpRequestGetQueuedDeployments(url string, authParams kutil.AuthenticationParameters, parameters *QueuedDeploymentsParameters) (*http.Request, error) {
+	urlStruct, err := urllib.Parse(url)
+	if err != nil {
+		return nil, fmt.Errorf("the provided url %s is invalid, error: %w", url, err)
+	}
+
+	values := urlStruct.Query()
+	if parameters.Env != "" {
+		values.Add("environment", parameters.Env)
+	}
+	if parameters.App != "" {
+		values.Add("application", parameters.App)
+	}
+	urlStruct.RawQuery = values.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, urlStruct.JoinPath("/api/queued-deployments").String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating the HTTP request, error: %w", err)
+	}
+	addAuthHeaders(req, authParams)
+	return req, nil
+}
+
+func createHttpRequestQueuedVersion(url string, authParams kutil.AuthenticationParameters, parameters *QueuedVersionParameters, deploy bool) (*http.Request, error) {
+	urlStruct, err := urllib.Parse(url)
+	if err != nil {
+		return nil, fmt.Errorf("the provided url %s is invalid, error: %w", url, err)
+	}
+
+	path := "/api/environments/" + parameters.Env + "/applications/" + parameters.App + "/queued-version/" + strconv.FormatUint(parameters.Version, 10)
+	method := http.MethodDelete
+	var jsonData []byte
+	if deploy {
+		path += "/deploy"
+		method = http.MethodPost
+		jsonData, err = json.Marshal(deployQueuedVersionJsonData{
+			Reason: parameters.Reason,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not marshal queued version data to json: %w", err)
+		}
+	}
+
+	req, err := http.NewRequest(method, urlStruct.JoinPath(path).String(), bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("error creating the HTTP request, error: %w", err)
+	}
+	if jsonData != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	addAuthHeaders(req, authParams)
+	if authParams.AuthorName != nil {
+		req.Header.Add("author-name", base64.StdEncoding.EncodeToString([]byte(*authParams.AuthorName)))
+	}
+	if authParams.AuthorEmail != nil {
+		req.Header.Add("author-email", base64.StdEncoding.EncodeToString([]byte(*authParams.AuthorEmail)))
+	}
+	return req, nil
+}
+
+func addAuthHeaders(req *http.Request, authParams kutil.AuthenticationParameters) {
+	if authParams.IapToken != nil {
+		req.Header.Add("Proxy-Authorization", "Bearer "+*authParams.IapToken)
+	}
+
+	if authParams.DexToken != nil {
+		req.Header.Add("Authorization", "Bearer "+*authParams.DexToken)
+	}
+}
@@ -0,0 +1,91 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package deployments
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+func ParseArgsQueuedDeployments(args []string) (*QueuedDeploymentsParameters, error) {
+	cmdArgs := QueuedDeploymentsParameters{
+		Env: "",
+		App: "",
+	}
+
+	fs := flag.NewFlagSet("flag set", flag.ContinueOnError)
+	fs.StringVar(&cmdArgs.Env, "environment", "", "only list the queued versions on this environment")
+	fs.StringVar(&cmdArgs.App, "application", "", "only list the queued versions of this application")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, fmt.Errorf("error while parsing command line arguments, error: %w", err)
+	}
+
+	if len(fs.Args()) != 0 {
+		return nil, fmt.Errorf("these arguments are not recognised: \"%v\"", strings.Join(fs.Args(), " "))
+	}
+
+	return &cmdArgs, nil
+}
+
+func ParseArgsCancelQueuedVersion(args []string) (*QueuedVersionParameters, error) {
+	return parseArgsQueuedVersion(args, false)
+}
+
+func ParseArgsDeployQueuedVersion(args []string) (*QueuedVersionParameters, error) {
+	return parseArgsQueuedVersion(args, true)
+}
+
+func parseArgsQueuedVersion(args []string, deploy bool) (*QueuedVersionParameters, error) {
+	cmdArgs := QueuedVersionParameters{
+		Env:     "",
+		App:     "",
+		Version: 0,
+		Reason:  "",
+	}
+
+	fs := flag.NewFlagSet("flag set", flag.ContinueOnError)
+	fs.StringVar(&cmdArgs.Env, "environment", "", "the environment of the queued version")
+	fs.StringVar(&cmdArgs.App, "application", "", "the application of the queued version")
+	fs.Uint64Var(&cmdArgs.Version, "version", 0, "the queued version")
+	if deploy {
+		fs.StringVar(&cmdArgs.Reason, "reason", "", "why the queued version is deployed despite the locks")
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, fmt.Errorf("error while parsing command line arguments, error: %w", err)
+	}
+
+	if len(fs.Args()) != 0 {
+		return nil, fmt.Errorf("these arguments are not recognised: \"%v\"", strings.Join(fs.Args(), " "))
+	}
+	if cmdArgs.Env == "" {
+		return nil, fmt.Errorf("the environment name must be set with the --environment flag")
+	}
+	if cmdArgs.App == "" {
+		return nil, fmt.Errorf("the application name must be set with the --application flag")
+	}
+	if cmdArgs.Version == 0 {
+		return nil, fmt.Errorf("the version must be set with the --version flag")
+	}
+	if deploy && cmdArgs.Reason == "" {
+		return nil, fmt.Errorf("the reason must be set with the --reason flag")
+	}
+
+	return &cmdArgs, nil
+}
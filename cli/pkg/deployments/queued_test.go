@@ -0,0 +1,182 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package deployments
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	kutil "github.com/freiheit-com/kuberpult/cli/pkg/kuberpult_utils"
+)
+
+func TestParseArgsQueuedVersion(t *testing.T) {
+	tests := []struct {
+		name          string
+		args          []string
+		deploy        bool
+		expected      *QueuedVersionParameters
+		expectedError error
+	}{
+		{
+			name: "cancel",
+			args: []string{"--environment", "production", "--application", "app1", "--version", "2"},
+			expected: &QueuedVersionParameters{
+				Env:     "production",
+				App:     "app1",
+				Version: 2,
+			},
+		},
+		{
+			name:          "cancel does not accept a reason",
+			args:          []string{"--environment", "production", "--application", "app1", "--version", "2", "--reason", "hotfix"},
+			expectedError: fmt.Errorf("error while parsing command line arguments, error: flag provided but not defined: -reason"),
+		},
+		{
+			name:   "deploy",
+			args:   []string{"--environment", "production", "--application", "app1", "--version", "2", "--reason", "hotfix"},
+			deploy: true,
+			expected: &QueuedVersionParameters{
+				Env:     "production",
+				App:     "app1",
+				Version: 2,
+				Reason:  "hotfix",
+			},
+		},
+		{
+			name:          "deploy requires a reason",
+			args:          []string{"--environment", "production", "--application", "app1", "--version", "2"},
+			deploy:        true,
+			expectedError: fmt.Errorf("the reason must be set with the --reason flag"),
+		},
+		{
+			name:          "missing version",
+			args:          []string{"--environment", "production", "--application", "app1"},
+			expectedError: fmt.Errorf("the version must be set with the --version flag"),
+		},
+		{
+			name:          "missing environment",
+			args:          []string{"--application", "app1", "--version", "2"},
+			expectedError: fmt.Errorf("the environment name must be set with the --environment flag"),
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var cliArgs *QueuedVersionParameters
+			var err error
+			if tc.deploy {
+				cliArgs, err = ParseArgsDeployQueuedVersion(tc.args)
+			} else {
+				cliArgs, err = ParseArgsCancelQueuedVersion(tc.args)
+			}
+			if err != nil {
+				if tc.expectedError == nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if err.Error() != tc.expectedError.Error() {
+					t.Fatalf("expected %v, got %v", tc.expectedError, err)
+				}
+				return
+			}
+			if tc.expectedError != nil {
+				t.Fatalf("expected error %v, got none", tc.expectedError)
+			}
+			if diff := cmp.Diff(tc.expected, cliArgs); diff != "" {
+				t.Errorf("parameters mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCreateHttpRequestQueuedVersion(t *testing.T) {
+	tests := []struct {
+		name           string
+		parameters     *QueuedVersionParameters
+		deploy         bool
+		expectedMethod string
+		expectedPath   string
+		expectedBody   string
+	}{
+		{
+			name: "cancel",
+			parameters: &QueuedVersionParameters{
+				Env:     "production",
+				App:     "app1",
+				Version: 2,
+			},
+			expectedMethod: "DELETE",
+			expectedPath:   "/api/environments/production/applications/app1/queued-version/2",
+			expectedBody:   "",
+		},
+		{
+			name: "deploy",
+			parameters: &QueuedVersionParameters{
+				Env:     "production",
+				App:     "app1",
+				Version: 2,
+				Reason:  "hotfix",
+			},
+			deploy:         true,
+			expectedMethod: "POST",
+			expectedPath:   "/api/environments/production/applications/app1/queued-version/2/deploy",
+			expectedBody:   `{"reason":"hotfix"}`,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			req, err := createHttpRequestQueuedVersion("http://example.com", kutil.AuthenticationParameters{DexToken: &dexToken}, tc.parameters, tc.deploy)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if req.Method != tc.expectedMethod {
+				t.Errorf("expected method %s, got %s", tc.expectedMethod, req.Method)
+			}
+			if req.URL.Path != tc.expectedPath {
+				t.Errorf("expected path %s, got %s", tc.expectedPath, req.URL.Path)
+			}
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(body) != tc.expectedBody {
+				t.Errorf("expected body %s, got %s", tc.expectedBody, body)
+			}
+			if req.Header.Get("Authorization") != "Bearer "+dexToken {
+				t.Errorf("expected dex token header, got %s", req.Header.Get("Authorization"))
+			}
+		})
+	}
+}
+
+func TestCreateHttpRequestGetQueuedDeployments(t *testing.T) {
+	req, err := createHttpRequestGetQueuedDeployments("http://example.com", kutil.AuthenticationParameters{DexToken: &dexToken}, &QueuedDeploymentsParameters{
+		Env: "production",
+		App: "",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.URL.String() != "http://example.com/api/queued-deployments?environment=production" {
+		t.Errorf("unexpected url %s", req.URL.String())
+	}
+}
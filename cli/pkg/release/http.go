@@ -111,6 +111,12 @@ func prepareHttpReleaseRequest(url string, authParams kutil.AuthenticationParame
 		}
 	}
 
+	for _, dependency := range parsedArgs.Dependencies {
+		if err := writer.WriteField("dependency", dependency); err != nil {
+			return nil, fmt.Errorf("error writing dependency field, error: %w", err)
+		}
+	}
+
 	if parsedArgs.IsPrepublish {
 		if !parsedArgs.UseDexAuthentication {
 			return nil, fmt.Errorf("prepublish endpoint is only available for the new api endpoint which is only available through dex authentication")
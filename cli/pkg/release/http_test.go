@@ -686,6 +686,29 @@ func TestRequestCreationRelease(t *testing.T) {
 
 			responseCode: http.StatusOK,
 		},
+		{
+			name: "one environment manifest with dependencies",
+			params: ReleaseParameters{
+				Application: "potato",
+				Manifests: map[string][]byte{
+					"development": []byte("some development manifest"),
+				},
+				Dependencies: []string{"tomato>=412", "carrot>=7"},
+			},
+			expectedMultipartFormValue: map[string][]string{
+				"application": {"potato"},
+				"dependency":  {"tomato>=412", "carrot>=7"},
+			},
+			expectedMultipartFormFile: map[string][]simpleMultipartFormFileHeader{
+				"manifests[development]": {
+					{
+						filename: "development-manifest",
+						content:  "some development manifest",
+					},
+				},
+			},
+			responseCode: http.StatusOK,
+		},
 	}
 
 	for _, tc := range tcs {
@@ -51,6 +51,7 @@ type commandLineArguments struct {
 	isPrepublish         bool
 	ciLink               cli_utils.RepeatedString
 	dryRun               bool
+	dependencies         cli_utils.RepeatedString
 }
 
 // checks whether every --environment arg is matched with a --manifest arg
@@ -91,6 +92,9 @@ func manifestsSignaturesPaired(args []string) (result bool, errorMessage string)
 	return true, ""
 }
 
+// the frontend-service parses the same format
+var dependencyRegex = regexp.MustCompile(`^[^>=\s]+>=[0-9]+$`)
+
 func argsValid(cmdArgs *commandLineArguments) (result bool, errorMessage string) {
 	var commitIDRegex = regexp.MustCompile(`^[0-9a-fA-F]{40}$`)
 	isCommitID := func(s string) bool {
@@ -187,6 +191,12 @@ func argsValid(cmdArgs *commandLineArguments) (result bool, errorMessage string)
 		}
 	}
 
+	for _, dependency := range cmdArgs.dependencies.Values {
+		if !dependencyRegex.MatchString(dependency) {
+			return false, fmt.Sprintf("the --dependency arg must have the form <application>>=<version>, but is '%s'", dependency)
+		}
+	}
+
 	if len(cmdArgs.ciLink.Values) > 1 {
 		return false, "the --ci_link arg must be set at most once"
 	} else if len(cmdArgs.ciLink.Values) == 1 {
@@ -222,6 +232,7 @@ func readArgs(args []string) (*commandLineArguments, error) {
 	fs.BoolVar(&cmdArgs.useDexAuthentication, "use_dex_auth", false, "use /api/release endpoint, if set to true, dex must be enabled and dex token must be provided otherwise the request will be denied")
 	fs.BoolVar(&cmdArgs.isPrepublish, "prepublish", false, "if set to true, it will create a prepublish release")
 	fs.BoolVar(&cmdArgs.dryRun, "dry-run", false, "Run in dry-run mode, do not publish, but return the manifest diff instead")
+	fs.Var(&cmdArgs.dependencies, "dependency", "another application that must be deployed in at least the given version before this release, e.g. payments>=412 (can be set multiple times)")
 
 	if err := fs.Parse(args); err != nil {
 		return nil, fmt.Errorf("error while parsing command line arguments, error: %w", err)
@@ -294,6 +305,7 @@ func convertToParams(cmdArgs commandLineArguments) (*ReleaseParameters, error) {
 	if len(cmdArgs.ciLink.Values) == 1 {
 		rp.CiLink = &cmdArgs.ciLink.Values[0]
 	}
+	rp.Dependencies = cmdArgs.dependencies.Values
 	for i := range cmdArgs.environments.Values {
 		manifestFile := cmdArgs.manifests.Values[i]
 		environment := cmdArgs.environments.Values[i]
@@ -723,6 +723,41 @@ func TestReadArgs(t *testing.T) {
 				msg: "the --ci_link arg must be set at most once",
 			},
 		},
+		{
+			name: "--dependency is specified twice",
+			args: []string{"--skip_signatures", "--application", "potato", "--environment", "production", "--manifest", "manifest-file.yaml", "--dependency", "tomato>=412", "--dependency", "carrot>=7"},
+			expectedCmdArgs: &commandLineArguments{
+				skipSignatures: true,
+				application: cli_utils.RepeatedString{
+					Values: []string{
+						"potato",
+					},
+				},
+				environments: cli_utils.RepeatedString{
+					Values: []string{
+						"production",
+					},
+				},
+				manifests: cli_utils.RepeatedString{
+					Values: []string{
+						"manifest-file.yaml",
+					},
+				},
+				dependencies: cli_utils.RepeatedString{
+					Values: []string{
+						"tomato>=412",
+						"carrot>=7",
+					},
+				},
+			},
+		},
+		{
+			name: "--dependency without version",
+			args: []string{"--skip_signatures", "--application", "potato", "--environment", "production", "--manifest", "manifest-file.yaml", "--dependency", "tomato"},
+			expectedError: errMatcher{
+				msg: "the --dependency arg must have the form <application>>=<version>, but is 'tomato'",
+			},
+		},
 		{
 			name: "use_dex_auth is passed",
 			args: []string{"--skip_signatures", "--application", "potato", "--environment", "production", "--manifest", "manifest-file.yaml", "--use_dex_auth"},
@@ -971,6 +1006,23 @@ func TestParseArgs(t *testing.T) {
 				DryRun: true,
 			},
 		},
+		{
+			name: "with dependencies",
+			setup: []fileCreation{
+				{
+					filename: "production-manifest.yaml",
+					content:  "some production manifest",
+				},
+			},
+			cmdArgs: []string{"--skip_signatures", "--application", "potato", "--environment", "production", "--manifest", "production-manifest.yaml", "--dependency", "tomato>=412"},
+			expectedParams: &ReleaseParameters{
+				Application: "potato",
+				Manifests: map[string][]byte{
+					"production": []byte("some production manifest"),
+				},
+				Dependencies: []string{"tomato>=412"},
+			},
+		},
 	}
 
 	for _, tc := range tcs {
@@ -40,6 +40,7 @@ type ReleaseParameters struct {
 	UseDexAuthentication bool
 	IsPrepublish         bool
 	DryRun               bool
+	Dependencies         []string // of the form <application>>=<version>
 }
 
 // Release calls the release endpoint with the specified parameters
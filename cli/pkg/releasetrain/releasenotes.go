@@ -0,0 +1,104 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package releasetrain
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	urllib "net/url"
+	"strconv"
+
+	"github.com/freiheit-com/kuberpult/cli/pkg/cli_utils"
+	kutil "github.com/freiheit-com/kuberpult/cli/pkg/kuberpult_utils"
+)
+
+type ReleaseNotesParameters struct {
+	Application       string
+	Environment       string
+	SourceEnvironment string
+	FromVersion       uint64
+	ToVersion         uint64
+	Team              string
+	// Format is either "markdown" or "json"
+	Format string
+}
+
+func HandleGetReleaseNotes(requestParams kutil.RequestParameters, authParams kutil.AuthenticationParameters, params *ReleaseNotesParameters) error {
+	req, err := createHttpRequestGetReleaseNotes(*requestParams.Url, authParams, params)
+	if err != nil {
+		return fmt.Errorf("error while preparing HTTP request, error: %w", err)
+	}
+	body, err := cli_utils.IssueHttpRequestWithBodyReturn(*req, requestParams.HttpTimeout)
+	if err != nil {
+		return fmt.Errorf("error while issuing HTTP request, error: %v", err)
+	}
+
+	fmt.Print(string(body))
+
+	return nil
+}
+
+func createHttpRequestGetReleaseNotes(url string, authParams kutil.AuthenticationParameters, parameters *ReleaseNotesParameters) (*http.Request, error) {
+	urlStruct, err := urllib.Parse(url)
+	if err != nil {
+		return nil, fmt.Errorf("the provided url %s is invalid, error: %w", url, err)
+	}
+
+	values := urlStruct.Query()
+	for name, value := range map[string]string{
+		"application":       parameters.Application,
+		"environment":       parameters.Environment,
+		"sourceEnvironment": parameters.SourceEnvironment,
+		"team":              parameters.Team,
+		"format":            parameters.Format,
+	} {
+		if value != "" {
+			values.Add(name, value)
+		}
+	}
+	if parameters.FromVersion != 0 {
+		values.Add("fromVersion", strconv.FormatUint(parameters.FromVersion, 10))
+	}
+	if parameters.ToVersion != 0 {
+		values.Add("toVersion", strconv.FormatUint(parameters.ToVersion, 10))
+	}
+	urlStruct.RawQuery = values.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, urlStruct.JoinPath("/api/release-notes/").String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating the HTTP request, error: %w", err)
+	}
+
+	if authParams.IapToken != nil {
+		req.Header.Add("Proxy-Authorization", "Bearer "+*authParams.IapToken)
+	}
+
+	if authParams.DexToken != nil {
+		req.Header.Add("Authorization", "Bearer "+*authParams.DexToken)
+	}
+
+	if authParams.AuthorName != nil {
+		req.Header.Add("author-name", base64.StdEncoding.EncodeToString([]byte(*authParams.AuthorName)))
+	}
+
+	if authParams.AuthorEmail != nil {
+		req.Header.Add("author-email", base64.StdEncoding.EncodeToString([]byte(*authParams.AuthorEmail)))
+	}
+
+	return req, nil
+}
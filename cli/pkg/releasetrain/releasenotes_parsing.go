@@ -0,0 +1,65 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package releasetrain
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+func ParseArgsGetReleaseNotes(args []string) (*ReleaseNotesParameters, error) {
+	cmdArgs := ReleaseNotesParameters{
+		Application:       "",
+		Environment:       "",
+		SourceEnvironment: "",
+		FromVersion:       0,
+		ToVersion:         0,
+		Team:              "",
+		Format:            "",
+	}
+
+	fs := flag.NewFlagSet("flag set", flag.ContinueOnError)
+	fs.StringVar(&cmdArgs.Application, "application", "", "the application of the release notes, required unless --source_environment is set")
+	fs.StringVar(&cmdArgs.Environment, "environment", "", "the release notes start after the version deployed on this environment")
+	fs.StringVar(&cmdArgs.SourceEnvironment, "source_environment", "", "returns the release notes of all applications that would be promoted from this environment to --environment")
+	fs.Uint64Var(&cmdArgs.FromVersion, "from_version", 0, "the release notes start after this version")
+	fs.Uint64Var(&cmdArgs.ToVersion, "to_version", 0, "the release notes end with this version, defaults to the latest release")
+	fs.StringVar(&cmdArgs.Team, "team", "", "only used with --source_environment: only include the applications of this team")
+	fs.StringVar(&cmdArgs.Format, "format", "markdown", "the output format, either markdown or json")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, fmt.Errorf("error while parsing command line arguments, error: %w", err)
+	}
+
+	if len(fs.Args()) != 0 {
+		return nil, fmt.Errorf("these arguments are not recognised: \"%v\"", strings.Join(fs.Args(), " "))
+	}
+
+	if cmdArgs.Application == "" && cmdArgs.SourceEnvironment == "" {
+		return nil, errors.New("either the --application or the --source_environment arg must be set")
+	}
+	if cmdArgs.SourceEnvironment != "" && cmdArgs.Environment == "" {
+		return nil, errors.New("the --environment arg must be set together with --source_environment")
+	}
+	if cmdArgs.Format != "markdown" && cmdArgs.Format != "json" {
+		return nil, fmt.Errorf("the --format arg must be either markdown or json, got '%s'", cmdArgs.Format)
+	}
+
+	return &cmdArgs, nil
+}
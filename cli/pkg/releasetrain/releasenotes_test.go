@@ -0,0 +1,160 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package releasetrain
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	kutil "github.com/freiheit-com/kuberpult/cli/pkg/kuberpult_utils"
+)
+
+func TestParseArgsGetReleaseNotes(t *testing.T) {
+	tests := []struct {
+		name          string
+		args          []string
+		expected      *ReleaseNotesParameters
+		expectedError error
+	}{
+		{
+			name: "application on environment",
+			args: []string{"--application", "app1", "--environment", "production"},
+			expected: &ReleaseNotesParameters{
+				Application: "app1",
+				Environment: "production",
+				Format:      "markdown",
+			},
+		},
+		{
+			name: "two versions as json",
+			args: []string{"--application", "app1", "--from_version", "3", "--to_version", "7", "--format", "json"},
+			expected: &ReleaseNotesParameters{
+				Application: "app1",
+				FromVersion: 3,
+				ToVersion:   7,
+				Format:      "json",
+			},
+		},
+		{
+			name: "promotion",
+			args: []string{"--environment", "production", "--source_environment", "staging", "--team", "team1"},
+			expected: &ReleaseNotesParameters{
+				Environment:       "production",
+				SourceEnvironment: "staging",
+				Team:              "team1",
+				Format:            "markdown",
+			},
+		},
+		{
+			name:          "neither application nor source environment",
+			args:          []string{"--environment", "production"},
+			expectedError: fmt.Errorf("either the --application or the --source_environment arg must be set"),
+		},
+		{
+			name:          "source environment without environment",
+			args:          []string{"--source_environment", "staging"},
+			expectedError: fmt.Errorf("the --environment arg must be set together with --source_environment"),
+		},
+		{
+			name:          "invalid format",
+			args:          []string{"--application", "app1", "--format", "html"},
+			expectedError: fmt.Errorf("the --format arg must be either markdown or json, got 'html'"),
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			params, err := ParseArgsGetReleaseNotes(tc.args)
+			if err != nil {
+				if tc.expectedError == nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if err.Error() != tc.expectedError.Error() {
+					t.Fatalf("expected %v, got %v", tc.expectedError, err)
+				}
+				return
+			}
+			if tc.expectedError != nil {
+				t.Fatalf("expected error %v, got none", tc.expectedError)
+			}
+			if diff := cmp.Diff(tc.expected, params); diff != "" {
+				t.Errorf("parameters mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCreateHttpRequestGetReleaseNotes(t *testing.T) {
+	tests := []struct {
+		name        string
+		params      *ReleaseNotesParameters
+		expectedUrl string
+	}{
+		{
+			name: "application on environment",
+			params: &ReleaseNotesParameters{
+				Application: "app1",
+				Environment: "production",
+				Format:      "markdown",
+			},
+			expectedUrl: "http://localhost:8081/api/release-notes/?application=app1&environment=production&format=markdown",
+		},
+		{
+			name: "two versions",
+			params: &ReleaseNotesParameters{
+				Application: "app1",
+				FromVersion: 3,
+				ToVersion:   7,
+				Format:      "json",
+			},
+			expectedUrl: "http://localhost:8081/api/release-notes/?application=app1&format=json&fromVersion=3&toVersion=7",
+		},
+		{
+			name: "promotion",
+			params: &ReleaseNotesParameters{
+				Environment:       "production",
+				SourceEnvironment: "staging",
+				Team:              "team1",
+				Format:            "markdown",
+			},
+			expectedUrl: "http://localhost:8081/api/release-notes/?environment=production&format=markdown&sourceEnvironment=staging&team=team1",
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			dexToken := "token"
+			req, err := createHttpRequestGetReleaseNotes("http://localhost:8081", kutil.AuthenticationParameters{DexToken: &dexToken}, tc.params)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expectedUrl, req.URL.String()); diff != "" {
+				t.Errorf("url mismatch (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff("GET", req.Method); diff != "" {
+				t.Errorf("method mismatch (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff("Bearer token", req.Header.Get("Authorization")); diff != "" {
+				t.Errorf("authorization header mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -38,6 +38,12 @@ type ReleaseTrainParameters struct {
 	CiLink               *string
 	UseEnvGroupTarget    bool
 	UseDexAuthentication bool
+	// ReleaseNotes prints the release notes of the release train as markdown
+	ReleaseNotes bool
+}
+
+type releaseTrainResponse struct {
+	ReleaseNotesMarkdown string `json:"release_notes_markdown"`
 }
 
 func HandleReleaseTrain(requestParams kutil.RequestParameters, authParams kutil.AuthenticationParameters, params ReleaseTrainParameters) error {
@@ -45,9 +51,21 @@ func HandleReleaseTrain(requestParams kutil.RequestParameters, authParams kutil.
 	if err != nil {
 		return fmt.Errorf("error while preparing HTTP request, error: %w", err)
 	}
-	if err := cli_utils.IssueHttpRequest(*req, requestParams.Retries, requestParams.HttpTimeout); err != nil {
+	if !params.ReleaseNotes {
+		if err := cli_utils.IssueHttpRequest(*req, requestParams.Retries, requestParams.HttpTimeout); err != nil {
+			return fmt.Errorf("error while issuing HTTP request, error: %v", err)
+		}
+		return nil
+	}
+	body, err := cli_utils.IssueHttpRequestWithBodyReturn(*req, requestParams.HttpTimeout)
+	if err != nil {
 		return fmt.Errorf("error while issuing HTTP request, error: %v", err)
 	}
+	var response releaseTrainResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return fmt.Errorf("could not parse the release train response, error: %w", err)
+	}
+	fmt.Print(response.ReleaseNotesMarkdown)
 	return nil
 }
 
@@ -63,11 +81,14 @@ func createHttpRequest(url string, authParams kutil.AuthenticationParameters, pa
 	}
 	path := fmt.Sprintf("%s/%s/releasetrain", prefix, parameters.TargetEnvironment)
 
+	values := urlStruct.Query()
 	if parameters.Team != nil {
-		values := urlStruct.Query()
 		values.Add("team", *parameters.Team)
-		urlStruct.RawQuery = values.Encode()
 	}
+	if parameters.ReleaseNotes {
+		values.Add("releaseNotes", "true")
+	}
+	urlStruct.RawQuery = values.Encode()
 
 	var jsonData []byte
 	if parameters.CiLink != nil {
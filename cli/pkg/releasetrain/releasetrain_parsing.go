@@ -32,6 +32,7 @@ type ReleaseTrainCommandLineArguments struct {
 	ciLink               cli_utils.RepeatedString
 	useEnvGroupTarget    bool
 	useDexAuthentication bool
+	releaseNotes         bool
 }
 
 func releaseTrainArgsValid(cmdArgs *ReleaseTrainCommandLineArguments) (result bool, errorMessage string) {
@@ -65,6 +66,7 @@ func readArgs(args []string) (*ReleaseTrainCommandLineArguments, error) {
 	fs.BoolVar(&cmdArgs.useDexAuthentication, "use_dex_auth", false, "if set to true, the /api/* endpoint will be used. Dex must be enabled on the server side and a dex token must be provided, otherwise the request will be denied")
 	fs.Var(&cmdArgs.ciLink, "ci_link", "the link to the CI run that created this release train")
 	fs.BoolVar(&cmdArgs.useEnvGroupTarget, "use_env_group_target", false, "if set to true, sets target type to environment-group")
+	fs.BoolVar(&cmdArgs.releaseNotes, "release_notes", false, "if set to true, prints the release notes of everything the release train deploys as markdown")
 
 	if err := fs.Parse(args); err != nil {
 		return nil, fmt.Errorf("error while parsing command line arguments, error: %w", err)
@@ -92,6 +94,7 @@ func convertToParams(cmdArgs ReleaseTrainCommandLineArguments) (*ReleaseTrainPar
 		TargetEnvironment:    cmdArgs.targetEnvironment.Values[0],
 		UseDexAuthentication: cmdArgs.useDexAuthentication,
 		UseEnvGroupTarget:    cmdArgs.useEnvGroupTarget,
+		ReleaseNotes:         cmdArgs.releaseNotes,
 	}
 
 	if len(cmdArgs.team.Values) == 1 {
@@ -215,6 +215,15 @@ func TestParseArgsReleaseTrainLock(t *testing.T) {
 				UseDexAuthentication: true,
 			},
 		},
+		{
+			name:    "target and release notes",
+			cmdArgs: []string{"--target-environment", "production", "--release_notes"},
+			expectedParams: ReleaseTrainParameters{
+				TargetEnvironment:    "production",
+				UseDexAuthentication: false,
+				ReleaseNotes:         true,
+			},
+		},
 	}
 
 	for _, tc := range tcs {
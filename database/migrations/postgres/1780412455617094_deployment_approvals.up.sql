@@ -0,0 +1,25 @@
+-- Pending, approved, rejected and expired deployments to environments that require a four-eyes approval.
+-- The request column holds the deployment or release train that is executed once the approval is granted.
+CREATE TABLE IF NOT EXISTS deployment_approvals
+(
+    approval_id                 VARCHAR   NOT NULL,
+    created                     TIMESTAMP NOT NULL,
+    expires_at                  TIMESTAMP NOT NULL,
+    status                      VARCHAR   NOT NULL,
+    app                         VARCHAR   NOT NULL,
+    environments                VARCHAR   NOT NULL,
+    request                     VARCHAR   NOT NULL,
+    requested_by_name           VARCHAR   NOT NULL,
+    requested_by_email          VARCHAR   NOT NULL,
+    requested_by_roles          VARCHAR   NOT NULL, -- the request is executed with the roles of the requester
+    request_esl_version         INTEGER   NOT NULL,
+    decided_at                  TIMESTAMP,
+    decided_by_name             VARCHAR,
+    decided_by_email            VARCHAR,
+    reason                      VARCHAR,
+    decision_esl_version        INTEGER,
+    PRIMARY KEY (approval_id)
+);
+
+CREATE INDEX IF NOT EXISTS deployment_approvals_status_idx
+    ON deployment_approvals (status);
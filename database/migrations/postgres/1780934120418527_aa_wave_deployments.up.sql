@@ -0,0 +1,18 @@
+-- The state of deployments to active/active environments that are rolled out in waves,
+-- one row per concrete environment. The cd-service writes the target version on every deployment,
+-- the manifest-repo-export-service moves deployed_version to the target one concrete environment after the other.
+CREATE TABLE IF NOT EXISTS aa_wave_deployments
+(
+    appname                     VARCHAR   NOT NULL,
+    envname                     VARCHAR   NOT NULL,
+    concrete_envname            VARCHAR   NOT NULL,
+    position                    INTEGER   NOT NULL,
+    target_version              BIGINT    NOT NULL,
+    target_revision             BIGINT    NOT NULL,
+    target_created              TIMESTAMP NOT NULL,
+    deployed_version            BIGINT,
+    deployed_revision           BIGINT,
+    deployed_at                 TIMESTAMP,
+    transformereslversion       INTEGER   NOT NULL,
+    PRIMARY KEY (appname, envname, concrete_envname)
+);
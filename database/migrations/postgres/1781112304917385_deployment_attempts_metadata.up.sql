@@ -0,0 +1,6 @@
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'deployment_attempts_latest' AND column_name='metadata') THEN
+    ALTER TABLE IF EXISTS deployment_attempts_latest ADD COLUMN metadata VARCHAR NOT NULL DEFAULT '{}';
+END IF;
+END $$;
@@ -0,0 +1,13 @@
+-- Retention rules that override the global release versions limit of the cleanup for one application or for all applications of a team.
+-- An application policy takes precedence over the policy of its team.
+CREATE TABLE IF NOT EXISTS release_retention_policies
+(
+    scope                       VARCHAR   NOT NULL, -- 'application' or 'team'
+    name                        VARCHAR   NOT NULL, -- the application or team name
+    keep_last                   INTEGER   NOT NULL, -- 0 means the global release versions limit applies
+    keep_days                   INTEGER   NOT NULL, -- 0 means releases are not kept because of their age
+    keep_prod_deployed          BOOLEAN   NOT NULL,
+    created                     TIMESTAMP NOT NULL,
+    eslversion                  INTEGER   NOT NULL,
+    PRIMARY KEY (scope, name)
+);
@@ -0,0 +1,50 @@
+-- Subscriptions of external endpoints to kuberpult events, see docs/users/14_webhooks.md.
+-- filter is a json object with the lists eventTypes, applications, teams and environments.
+CREATE TABLE IF NOT EXISTS webhook_subscriptions
+(
+    id                          SERIAL    PRIMARY KEY,
+    url                         VARCHAR   NOT NULL,
+    secret                      VARCHAR   NOT NULL,
+    filter                      VARCHAR   NOT NULL,
+    created                     TIMESTAMP NOT NULL,
+    created_by_name             VARCHAR   NOT NULL,
+    created_by_email            VARCHAR   NOT NULL
+);
+
+-- One row per event and matching subscription. The payload is stored, so that a redelivery sends the same body.
+CREATE TABLE IF NOT EXISTS webhook_deliveries
+(
+    id                          SERIAL    PRIMARY KEY,
+    subscription_id             INTEGER   NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+    event_uuid                  VARCHAR   NOT NULL,
+    event_type                  VARCHAR   NOT NULL,
+    payload                     VARCHAR   NOT NULL,
+    status                      VARCHAR   NOT NULL, -- 'pending', 'delivered' or 'failed'
+    attempts                    INTEGER   NOT NULL,
+    created                     TIMESTAMP NOT NULL,
+    next_attempt                TIMESTAMP NOT NULL,
+    last_attempt                TIMESTAMP,
+    response_status             INTEGER   NOT NULL, -- 0 if the last attempt did not get a response
+    last_error                  VARCHAR   NOT NULL,
+    UNIQUE (subscription_id, event_uuid)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_status_next_attempt_idx
+    ON webhook_deliveries (status, next_attempt);
+
+-- Readers of new commit events keep their position as (txid, id): txid is the transaction that wrote the event,
+-- id orders the events of one transaction. Readers only read events of transactions below the oldest running one,
+-- so an event of a transaction that commits late is never behind a position that was already read.
+ALTER TABLE commit_events ADD COLUMN IF NOT EXISTS txid BIGINT NOT NULL DEFAULT txid_current();
+ALTER TABLE commit_events ADD COLUMN IF NOT EXISTS id BIGSERIAL;
+
+CREATE INDEX IF NOT EXISTS commit_events_txid_id_idx
+    ON commit_events (txid, id);
+
+-- The position of the newest commit event that was matched against the subscriptions. It has at most one row.
+CREATE TABLE IF NOT EXISTS webhook_cursor
+(
+    id                          INTEGER   PRIMARY KEY,
+    event_txid                  BIGINT    NOT NULL,
+    event_id                    BIGINT    NOT NULL
+);
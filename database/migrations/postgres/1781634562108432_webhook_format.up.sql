@@ -0,0 +1,2 @@
+-- Payload format of a webhook subscription, "kuberpult" or "cloudevents", see docs/users/15_cloudevents.md.
+ALTER TABLE webhook_subscriptions ADD COLUMN IF NOT EXISTS format VARCHAR NOT NULL DEFAULT 'kuberpult';
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS event_sourcing_light_created_idx ON event_sourcing_light (created);
@@ -0,0 +1,4 @@
+-- The verified identity of the requester and the approver, see auth.User.Identity.
+-- Self-approvals are detected with it, since the email can be chosen freely by users that are not logged in.
+ALTER TABLE deployment_approvals ADD COLUMN IF NOT EXISTS requested_by_identity VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE deployment_approvals ADD COLUMN IF NOT EXISTS decided_by_identity VARCHAR;
@@ -0,0 +1,18 @@
+-- Every change of a release retention policy, so that the manifest-repo-export-service applies the policy
+-- that was in effect when the cd-service processed an event, instead of the current one.
+CREATE TABLE IF NOT EXISTS release_retention_policies_history
+(
+    scope                       VARCHAR   NOT NULL,
+    name                        VARCHAR   NOT NULL,
+    keep_last                   INTEGER   NOT NULL,
+    keep_days                   INTEGER   NOT NULL,
+    keep_prod_deployed          BOOLEAN   NOT NULL,
+    deleted                     BOOLEAN   NOT NULL, -- true if the policy was deleted by this event
+    created                     TIMESTAMP NOT NULL,
+    eslversion                  INTEGER   NOT NULL,
+    PRIMARY KEY (scope, name, eslversion)
+);
+INSERT INTO release_retention_policies_history (scope, name, keep_last, keep_days, keep_prod_deployed, deleted, created, eslversion)
+SELECT scope, name, keep_last, keep_days, keep_prod_deployed, false, created, eslversion
+FROM release_retention_policies
+ON CONFLICT DO NOTHING;
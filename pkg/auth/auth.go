@@ -46,8 +46,14 @@ const (
 	HeaderUserEmail  = "author-email"
 	HeaderUserRole   = "author-role"
 	HeaderClientUUID = "client-uuid"
+	// HeaderUserIdentity is only set by the frontend-service for users that logged in, see User.Identity.
+	HeaderUserIdentity = "author-identity"
 )
 
+// RolloutServiceIdentity is the User.Identity of the rollout-service.
+// The frontend-service never forwards it, so only the rollout-service can send it to the cd-service.
+const RolloutServiceIdentity = "kuberpult:rollout-service"
+
 func Encode64(s string) string {
 	return base64.StdEncoding.EncodeToString([]byte(s))
 }
@@ -75,7 +81,11 @@ func WriteUserToContext(ctx context.Context, u User) context.Context {
 }
 
 func WriteUserToGrpcContext(ctx context.Context, u User) context.Context {
-	return metadata.AppendToOutgoingContext(ctx, HeaderUserEmail, Encode64(u.Email), HeaderUserName, Encode64(u.Name))
+	ctx = metadata.AppendToOutgoingContext(ctx, HeaderUserEmail, Encode64(u.Email), HeaderUserName, Encode64(u.Name))
+	if u.Identity != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, HeaderUserIdentity, Encode64(u.Identity))
+	}
+	return ctx
 }
 
 func WriteClientUUIDToGrpcContext(ctx context.Context, clientUUID string) context.Context {
@@ -103,8 +113,9 @@ type DummyGrpcContextReader struct {
 
 func (x *DummyGrpcContextReader) ReadUserFromGrpcContext(ctx context.Context) (*User, error) {
 	user := &User{
-		Email: "dummyMail@example.com",
-		Name:  "userName",
+		Email:    "dummyMail@example.com",
+		Name:     "userName",
+		Identity: "",
 		DexAuthContext: &DexAuthContext{
 			Role: []string{x.Role},
 		},
@@ -138,10 +149,18 @@ func (x *DexGrpcContextReader) ReadUserFromGrpcContext(ctx context.Context) (*Us
 	if err != nil {
 		return nil, grpc.AuthError(ctx, fmt.Errorf("extract: non-base64 in author-username in grpc context %s", userName))
 	}
+	userIdentity := ""
+	if originalIdentityArr := md.Get(HeaderUserIdentity); len(originalIdentityArr) == 1 {
+		userIdentity, err = Decode64(originalIdentityArr[0])
+		if err != nil {
+			return nil, grpc.AuthError(ctx, fmt.Errorf("extract: non-base64 in author-identity in grpc context %s", originalIdentityArr[0]))
+		}
+	}
 	u := &User{
 		DexAuthContext: nil,
 		Email:          userMail,
 		Name:           userName,
+		Identity:       userIdentity,
 	}
 	if u.Email == "" || u.Name == "" {
 		return nil, grpc.AuthError(ctx, errors.New("email and name in grpc context cannot both be empty"))
@@ -205,11 +224,17 @@ func ReadUserFromHttpHeader(ctx context.Context, r *http.Request) (*User, error)
 	if err != nil {
 		return nil, grpc.AuthError(ctx, fmt.Errorf("ExtractUserHttp: invalid data in role: '%s'", headerRole64))
 	}
+	headerIdentity64 := r.Header.Get(HeaderUserIdentity)
+	headerIdentity, err := Decode64(headerIdentity64)
+	if err != nil {
+		return nil, grpc.AuthError(ctx, fmt.Errorf("ExtractUserHttp: invalid data in identity: '%s'", headerIdentity64))
+	}
 
 	if headerName != "" && headerEmail != "" {
 		return &User{
-			Email: headerEmail,
-			Name:  headerName,
+			Email:    headerEmail,
+			Name:     headerName,
+			Identity: headerIdentity,
 			DexAuthContext: &DexAuthContext{
 				Role: strings.Split(headerRole, ","),
 			},
@@ -224,6 +249,11 @@ func ReadUserFromHttpHeader(ctx context.Context, r *http.Request) (*User, error)
 func WriteUserToHttpHeader(r *http.Request, user User) {
 	r.Header.Set(HeaderUserName, Encode64(user.Name))
 	r.Header.Set(HeaderUserEmail, Encode64(user.Email))
+	if user.Identity != "" {
+		r.Header.Set(HeaderUserIdentity, Encode64(user.Identity))
+	} else {
+		r.Header.Del(HeaderUserIdentity)
+	}
 }
 
 // WriteUserRoleToHttpHeader should only be used in the frontend-service
@@ -238,6 +268,10 @@ func GetUserOrDefault(u *User, defaultUser User) User {
 		DexAuthContext: nil,
 		Email:          defaultUser.Email,
 		Name:           defaultUser.Name,
+		Identity:       "",
+	}
+	if u != nil {
+		userAdapted.Identity = u.Identity
 	}
 	if u != nil && u.Email != "" {
 		userAdapted.Email = u.Email
@@ -259,6 +293,9 @@ func GetUserOrDefault(u *User, defaultUser User) User {
 type User struct {
 	Email string
 	Name  string
+	// Identity is the verified subject of the login, prefixed with the login provider, like "dex:alice@example.com".
+	// It is empty if the user could not be verified, for example if the author headers were set by the client.
+	Identity string
 	// Optional. User role, only used if RBAC is enabled.
 	DexAuthContext *DexAuthContext
 }
@@ -18,6 +18,8 @@ package auth
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -151,3 +153,43 @@ func TestGetUserOrDefault(t *testing.T) {
 		})
 	}
 }
+
+func TestUserIdentityHttpHeader(t *testing.T) {
+	tcs := []struct {
+		Name             string
+		ClientIdentity   string
+		User             User
+		ExpectedIdentity string
+	}{
+		{
+			Name:             "identity of a user that logged in",
+			User:             User{Email: "a@example.com", Name: "a", Identity: "dex:a@example.com"},
+			ExpectedIdentity: "dex:a@example.com",
+		},
+		{
+			Name:             "identity set by the client is removed",
+			ClientIdentity:   Encode64("dex:b@example.com"),
+			User:             User{Email: "a@example.com", Name: "a"},
+			ExpectedIdentity: "",
+		},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.ClientIdentity != "" {
+				r.Header.Set(HeaderUserIdentity, tc.ClientIdentity)
+			}
+			WriteUserToHttpHeader(r, tc.User)
+			u, err := ReadUserFromHttpHeader(context.Background(), r)
+			if err != nil {
+				t.Fatalf("Unexpected error: %#v \n", err)
+			}
+			if u.Identity != tc.ExpectedIdentity {
+				t.Fatalf("Unexpected Identity was extracted from header.\nexpected: %#v \nrecieved: %#v \n", tc.ExpectedIdentity, u.Identity)
+			}
+		})
+	}
+}
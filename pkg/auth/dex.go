@@ -393,6 +393,10 @@ func AppendRoleForPolicy(userGroup string, roles []string, policy *RBACPolicies)
 	return roles
 }
 
+// DexIdentityPrefix is the prefix of User.Identity for users that logged in with Dex.
+const DexIdentityPrefix = "dex:"
+
+// GetContextFromDex verifies the Dex token and writes the roles and the identity of the user into the request headers.
 func GetContextFromDex(ctx context.Context, req *http.Request, clientID, baseURL, dexServiceURL string, DexRbacPolicy *RBACPolicies, useClusterInternalCommunication bool) (context.Context, error) {
 	claimsParsed, err := VerifyToken(ctx, req, clientID, baseURL, dexServiceURL, useClusterInternalCommunication, DexRbacPolicy)
 	if err != nil {
@@ -403,6 +407,7 @@ func GetContextFromDex(ctx context.Context, req *http.Request, clientID, baseURL
 	var roles []string
 	roles = append(roles, claimsParsed.Roles...)
 	if claimsParsed.Email != "" {
+		req.Header.Set(HeaderUserIdentity, Encode64(DexIdentityPrefix+claimsParsed.Email))
 		roles = AppendRoleForPolicy(claimsParsed.Email, roles, DexRbacPolicy)
 	} else if len(claimsParsed.Groups) == 0 {
 		return nil, fmt.Errorf("unable to parse token with expected fields for DEX login")
@@ -41,9 +41,13 @@ const (
 	PermissionDeleteEnvironment            = "DeleteEnvironment"
 	PermissionDeleteEnvironmentApplication = "DeleteEnvironmentApplication"
 	PermissionDeployReleaseTrain           = "DeployReleaseTrain"
+	PermissionApproveDeployment            = "ApproveDeployment"
+	PermissionAutoRollback                 = "AutoRollback"
 
 	PermissionSkipEslEvent     = "SkipEslEvent"
 	PermissionRetryFailedEvent = "RetryFailedEvent"
+	PermissionManageWebhooks   = "ManageWebhooks"
+	PermissionReadAuditLog     = "ReadAuditLog"
 
 	// The default permission template.
 	PermissionTemplate = "p,role:%s,%s,%s:%s,%s,allow"
@@ -74,9 +78,13 @@ func initPolicyConfig() policyConfig {
 			PermissionDeleteEnvironment,
 			PermissionDeleteEnvironmentApplication,
 			PermissionDeployReleaseTrain,
+			PermissionApproveDeployment,
+			PermissionAutoRollback,
 
 			PermissionSkipEslEvent,
 			PermissionRetryFailedEvent,
+			PermissionManageWebhooks,
+			PermissionReadAuditLog,
 		},
 	}
 }
@@ -0,0 +1,290 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+// Package cloudevents converts kuberpult events to CloudEvents 1.0.
+//
+// The type names and the data of each type are part of the public api, see docs/users/15_cloudevents.md.
+// Changes to the data of a type must be backwards compatible, otherwise the version suffix of the type is increased.
+package cloudevents
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/freiheit-com/kuberpult/pkg/event"
+)
+
+const (
+	SpecVersion = "1.0"
+	// ContentType is the content type of a structured mode CloudEvent
+	ContentType     = "application/cloudevents+json"
+	DataContentType = "application/json"
+
+	SourceCdService      = "/kuberpult/cd-service"
+	SourceRolloutService = "/kuberpult/rollout-service"
+)
+
+const (
+	TypeReleaseCreated            = "com.freiheit.kuberpult.release.created.v1"
+	TypeDeploymentCreated         = "com.freiheit.kuberpult.deployment.created.v1"
+	TypeDeploymentLockPrevented   = "com.freiheit.kuberpult.deployment.lock_prevented.v1"
+	TypeDeploymentFreezePrevented = "com.freiheit.kuberpult.deployment.freeze_prevented.v1"
+	TypeDeploymentReplaced        = "com.freiheit.kuberpult.deployment.replaced.v1"
+	TypeDeploymentRolledBack      = "com.freiheit.kuberpult.deployment.rolled_back.v1"
+	TypeLockCreated               = "com.freiheit.kuberpult.lock.created.v1"
+	TypeLockDeleted               = "com.freiheit.kuberpult.lock.deleted.v1"
+	TypeEnvironmentChanged        = "com.freiheit.kuberpult.environment.changed.v1"
+	TypeRolloutStatusChanged      = "com.freiheit.kuberpult.rollout.status_changed.v1"
+)
+
+// Types maps the kuberpult event types to their CloudEvent types. Events of other types are not converted.
+var Types = map[event.EventType]string{
+	event.EventTypeNewRelease:                TypeReleaseCreated,
+	event.EventTypeDeployment:                TypeDeploymentCreated,
+	event.EventTypeLockPreventedDeployment:   TypeDeploymentLockPrevented,
+	event.EventTypeFreezePreventedDeployment: TypeDeploymentFreezePrevented,
+	event.EventTypeReplaceBy:                 TypeDeploymentReplaced,
+	event.EventTypeAutoRollback:              TypeDeploymentRolledBack,
+	event.EventTypeLockCreated:               TypeLockCreated,
+	event.EventTypeLockDeleted:               TypeLockDeleted,
+	event.EventTypeEnvironmentChanged:        TypeEnvironmentChanged,
+	event.EventTypeRolloutStatusChanged:      TypeRolloutStatusChanged,
+}
+
+// Event is a CloudEvent in the json format of the structured content mode.
+type Event struct {
+	SpecVersion     string          `json:"specversion"`
+	Id              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// ReleaseCreated is the data of TypeReleaseCreated.
+type ReleaseCreated struct {
+	Application  string   `json:"application"`
+	Team         string   `json:"team"`
+	CommitHash   string   `json:"commitHash"`
+	Environments []string `json:"environments"`
+}
+
+// DeploymentCreated is the data of TypeDeploymentCreated. ReleaseTrain is nil for single deployments.
+type DeploymentCreated struct {
+	Application  string        `json:"application"`
+	Team         string        `json:"team"`
+	Environment  string        `json:"environment"`
+	CommitHash   string        `json:"commitHash"`
+	ReleaseTrain *ReleaseTrain `json:"releaseTrain,omitempty"`
+}
+
+type ReleaseTrain struct {
+	Upstream         string `json:"upstream"`
+	EnvironmentGroup string `json:"environmentGroup,omitempty"`
+}
+
+// DeploymentLockPrevented is the data of TypeDeploymentLockPrevented.
+type DeploymentLockPrevented struct {
+	Application string `json:"application"`
+	Team        string `json:"team"`
+	Environment string `json:"environment"`
+	CommitHash  string `json:"commitHash"`
+	LockType    string `json:"lockType"`
+	LockMessage string `json:"lockMessage"`
+}
+
+// DeploymentFreezePrevented is the data of TypeDeploymentFreezePrevented.
+type DeploymentFreezePrevented struct {
+	Application   string `json:"application"`
+	Team          string `json:"team"`
+	Environment   string `json:"environment"`
+	CommitHash    string `json:"commitHash"`
+	FreezeId      string `json:"freezeId"`
+	FreezeMessage string `json:"freezeMessage"`
+}
+
+// DeploymentReplaced is the data of TypeDeploymentReplaced.
+type DeploymentReplaced struct {
+	Application          string `json:"application"`
+	Team                 string `json:"team"`
+	Environment          string `json:"environment"`
+	CommitHash           string `json:"commitHash"`
+	ReplacedByCommitHash string `json:"replacedByCommitHash"`
+}
+
+// DeploymentRolledBack is the data of TypeDeploymentRolledBack.
+type DeploymentRolledBack struct {
+	Application       string `json:"application"`
+	Team              string `json:"team"`
+	Environment       string `json:"environment"`
+	CommitHash        string `json:"commitHash"`
+	RolledBackVersion uint64 `json:"rolledBackVersion"`
+	RestoredVersion   uint64 `json:"restoredVersion"`
+	Reason            string `json:"reason"`
+}
+
+// Lock is the data of TypeLockCreated and TypeLockDeleted.
+// Message is only set for created locks, Reason only for locks that kuberpult deleted on its own.
+type Lock struct {
+	LockType    string `json:"lockType"`
+	LockId      string `json:"lockId"`
+	Environment string `json:"environment"`
+	Application string `json:"application,omitempty"`
+	Team        string `json:"team,omitempty"`
+	Message     string `json:"message,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// EnvironmentChanged is the data of TypeEnvironmentChanged.
+type EnvironmentChanged struct {
+	Environment string `json:"environment"`
+	// Change is created, updated or deleted
+	Change string `json:"change"`
+}
+
+// RolloutStatusChanged is the data of TypeRolloutStatusChanged.
+type RolloutStatusChanged struct {
+	Application string `json:"application"`
+	Team        string `json:"team"`
+	Environment string `json:"environment"`
+	CommitHash  string `json:"commitHash"`
+	// Version is 0 for brackets
+	Version uint64 `json:"version"`
+	// Status is successful, progressing, pending, error, unhealthy or unknown
+	Status string `json:"status"`
+}
+
+// FromEvent converts an event of the commit events table. team is the team of the application of the event.
+// It returns nil for event types that have no CloudEvent type.
+func FromEvent(id string, timestamp time.Time, commitHash, team string, ev event.Event) (*Event, error) {
+	var (
+		eventType   string
+		source      = SourceCdService
+		application string
+		environment string
+		data        any
+	)
+	switch e := ev.(type) {
+	case *event.NewRelease:
+		envs := make([]string, 0, len(e.Environments))
+		for env := range e.Environments {
+			envs = append(envs, env)
+		}
+		slices.Sort(envs)
+		if e.Application != nil {
+			application = *e.Application
+		}
+		eventType = TypeReleaseCreated
+		data = ReleaseCreated{Application: application, Team: team, CommitHash: commitHash, Environments: envs}
+	case *event.Deployment:
+		var train *ReleaseTrain
+		if e.SourceTrainUpstream != nil {
+			train = &ReleaseTrain{Upstream: *e.SourceTrainUpstream, EnvironmentGroup: ""}
+			if e.SourceTrainEnvironmentGroup != nil {
+				train.EnvironmentGroup = *e.SourceTrainEnvironmentGroup
+			}
+		}
+		application, environment = e.Application, e.Environment
+		eventType = TypeDeploymentCreated
+		data = DeploymentCreated{Application: application, Team: team, Environment: environment, CommitHash: commitHash, ReleaseTrain: train}
+	case *event.LockPreventedDeployment:
+		application, environment = e.Application, e.Environment
+		eventType = TypeDeploymentLockPrevented
+		data = DeploymentLockPrevented{Application: application, Team: team, Environment: environment, CommitHash: commitHash, LockType: e.LockType, LockMessage: e.LockMessage}
+	case *event.FreezePreventedDeployment:
+		application, environment = e.Application, e.Environment
+		eventType = TypeDeploymentFreezePrevented
+		data = DeploymentFreezePrevented{Application: application, Team: team, Environment: environment, CommitHash: commitHash, FreezeId: e.FreezeId, FreezeMessage: e.FreezeMessage}
+	case *event.ReplacedBy:
+		application, environment = e.Application, e.Environment
+		eventType = TypeDeploymentReplaced
+		data = DeploymentReplaced{Application: application, Team: team, Environment: environment, CommitHash: commitHash, ReplacedByCommitHash: e.CommitIDtoReplace}
+	case *event.AutoRollback:
+		application, environment = e.Application, e.Environment
+		eventType = TypeDeploymentRolledBack
+		source = SourceRolloutService
+		data = DeploymentRolledBack{
+			Application:       application,
+			Team:              team,
+			Environment:       environment,
+			CommitHash:        commitHash,
+			RolledBackVersion: parseVersion(e.RolledBackVersion),
+			RestoredVersion:   parseVersion(e.RestoredVersion),
+			Reason:            e.Reason,
+		}
+	case *event.LockCreated:
+		application, environment = e.Application, e.Environment
+		eventType = TypeLockCreated
+		data = Lock{LockType: e.LockType, LockId: e.LockId, Environment: environment, Application: application, Team: e.Team, Message: e.Message, Reason: ""}
+	case *event.LockDeleted:
+		application, environment = e.Application, e.Environment
+		eventType = TypeLockDeleted
+		data = Lock{LockType: e.LockType, LockId: e.LockId, Environment: environment, Application: application, Team: e.Team, Message: "", Reason: e.Reason}
+	case *event.EnvironmentChanged:
+		environment = e.Environment
+		eventType = TypeEnvironmentChanged
+		data = EnvironmentChanged{Environment: environment, Change: e.Change}
+	case *event.RolloutStatusChanged:
+		application, environment = e.Application, e.Environment
+		eventType = TypeRolloutStatusChanged
+		source = SourceRolloutService
+		data = RolloutStatusChanged{Application: application, Team: team, Environment: environment, CommitHash: commitHash, Version: parseVersion(e.Version), Status: e.Status}
+	default:
+		return nil, nil
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode the data of event %s: %w", id, err)
+	}
+	return &Event{
+		SpecVersion:     SpecVersion,
+		Id:              id,
+		Source:          source,
+		Type:            eventType,
+		Subject:         Subject(environment, application),
+		Time:            timestamp.UTC(),
+		DataContentType: DataContentType,
+		Data:            encoded,
+	}, nil
+}
+
+// Subject is "environments/<environment>/applications/<application>", leaving out the parts that are empty.
+func Subject(environment, application string) string {
+	subject := ""
+	if environment != "" {
+		subject = "environments/" + environment
+	}
+	if application != "" {
+		if subject != "" {
+			subject += "/"
+		}
+		subject += "applications/" + application
+	}
+	return subject
+}
+
+func parseVersion(version string) uint64 {
+	v, err := strconv.ParseUint(version, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
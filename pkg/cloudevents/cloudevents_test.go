@@ -0,0 +1,115 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package cloudevents
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/freiheit-com/kuberpult/pkg/conversion"
+	"github.com/freiheit-com/kuberpult/pkg/event"
+)
+
+func TestFromEvent(t *testing.T) {
+	timestamp := time.Date(2026, 10, 17, 9, 12, 45, 0, time.UTC)
+	tcs := []struct {
+		Name       string
+		CommitHash string
+		Event      event.Event
+		// Expected is the json of the structured mode CloudEvent, it must not change for existing types
+		Expected string
+	}{
+		{
+			Name:       "new release",
+			CommitHash: "abc",
+			Event: &event.NewRelease{
+				Environments: map[string]struct{}{"staging": {}, "dev": {}},
+				Application:  conversion.FromString("app"),
+			},
+			Expected: `{"specversion":"1.0","id":"00000000-0000-0000-0000-000000000001","source":"/kuberpult/cd-service","type":"com.freiheit.kuberpult.release.created.v1","subject":"applications/app","time":"2026-10-17T09:12:45Z","datacontenttype":"application/json","data":{"application":"app","team":"team","commitHash":"abc","environments":["dev","staging"]}}`,
+		},
+		{
+			Name:       "release train deployment",
+			CommitHash: "abc",
+			Event: &event.Deployment{
+				Application:                 "app",
+				Environment:                 "production",
+				SourceTrainEnvironmentGroup: nil,
+				SourceTrainUpstream:         conversion.FromString("staging"),
+			},
+			Expected: `{"specversion":"1.0","id":"00000000-0000-0000-0000-000000000001","source":"/kuberpult/cd-service","type":"com.freiheit.kuberpult.deployment.created.v1","subject":"environments/production/applications/app","time":"2026-10-17T09:12:45Z","datacontenttype":"application/json","data":{"application":"app","team":"team","environment":"production","commitHash":"abc","releaseTrain":{"upstream":"staging"}}}`,
+		},
+		{
+			Name:       "team lock",
+			CommitHash: "",
+			Event: &event.LockCreated{
+				LockType:    "team",
+				LockId:      "l1",
+				Environment: "production",
+				Application: "",
+				Team:        "team",
+				Message:     "incident",
+			},
+			Expected: `{"specversion":"1.0","id":"00000000-0000-0000-0000-000000000001","source":"/kuberpult/cd-service","type":"com.freiheit.kuberpult.lock.created.v1","subject":"environments/production","time":"2026-10-17T09:12:45Z","datacontenttype":"application/json","data":{"lockType":"team","lockId":"l1","environment":"production","team":"team","message":"incident"}}`,
+		},
+		{
+			Name:       "rollout status",
+			CommitHash: "abc",
+			Event: &event.RolloutStatusChanged{
+				Application: "app",
+				Environment: "production",
+				Version:     "12",
+				Status:      "unhealthy",
+			},
+			Expected: `{"specversion":"1.0","id":"00000000-0000-0000-0000-000000000001","source":"/kuberpult/rollout-service","type":"com.freiheit.kuberpult.rollout.status_changed.v1","subject":"environments/production/applications/app","time":"2026-10-17T09:12:45Z","datacontenttype":"application/json","data":{"application":"app","team":"team","environment":"production","commitHash":"abc","version":12,"status":"unhealthy"}}`,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			ev, err := FromEvent("00000000-0000-0000-0000-000000000001", timestamp, tc.CommitHash, "team", tc.Event)
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual, err := json.Marshal(ev)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.Expected, string(actual)); diff != "" {
+				t.Fatalf("cloudevent mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTypesCoverAllEvents(t *testing.T) {
+	for eventType, ceType := range Types {
+		parsed, err := event.UnMarshallEvent(eventType, `{"EventData":{},"EventMetadata":{}}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ev, err := FromEvent("id", time.Time{}, "", "", parsed.EventData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ev == nil || ev.Type != ceType {
+			t.Errorf("event type %s is not converted to %s", eventType, ceType)
+		}
+	}
+}
@@ -16,7 +16,12 @@ Copyright freiheit.com*/
 
 package config
 
-import "github.com/freiheit-com/kuberpult/pkg/types"
+import (
+	"fmt"
+	"time"
+
+	"github.com/freiheit-com/kuberpult/pkg/types"
+)
 
 type EnvironmentConfig struct {
 	Upstream         *EnvironmentConfigUpstream `json:"upstream,omitempty"`
@@ -24,16 +29,101 @@ type EnvironmentConfig struct {
 	EnvironmentGroup *string                    `json:"environmentGroup,omitempty"`
 	ArgoCdConfigs    *ArgoCDConfigs             `json:"argocdConfigs,omitempty"`
 	IsActiveActive   *bool                      `json:"isActiveActive,omitempty"`
+	Freezes          []EnvironmentFreeze        `json:"freezes,omitempty"`
+	// ManifestValidation rejects new releases whose manifests for this environment violate its rules.
+	// Without it, manifests are not validated.
+	ManifestValidation *ManifestValidation `json:"manifestValidation,omitempty"`
+	// SignaturePolicy only allows releases to be deployed to this environment if trusted keys signed their manifest.
+	// Without it, signatures are not checked.
+	SignaturePolicy *SignaturePolicy `json:"signaturePolicy,omitempty"`
+	// DeploymentGates must all approve a deployment to this environment before it is executed.
+	DeploymentGates []DeploymentGate `json:"deploymentGates,omitempty"`
+}
+
+type ManifestValidation struct {
+	// Rules are the names of the rules to check, see the manifestvalidation package.
+	// If empty, only the manifests are parsed.
+	Rules []string `json:"rules,omitempty"`
+	// DisallowedKinds must not be deployed to this environment, either "Kind" or "group/Kind".
+	DisallowedKinds []string `json:"disallowedKinds,omitempty"`
+}
+
+type SignaturePolicy struct {
+	// Keys that are trusted to sign the manifests of releases for this environment.
+	Keys []SigningKey `json:"keys"`
+	// Threshold is the number of Keys that must have signed the manifest. 0 means all Keys.
+	Threshold uint32 `json:"threshold,omitempty"`
+}
+
+type SigningKey struct {
+	// Name identifies the key in error messages.
+	Name string `json:"name"`
+	// Type is "openpgp", "ssh" or "cosign", see the signing package.
+	Type string `json:"type"`
+	// PublicKey is an armored OpenPGP public key, an ssh public key in authorized_keys format,
+	// or a PEM encoded public key for cosign.
+	PublicKey string `json:"publicKey"`
+}
+
+type DeploymentGate struct {
+	// Name identifies the gate in queued versions and events.
+	Name string `json:"name"`
+	// Url receives a POST request for every deployment, see the gates package of the cd-service.
+	Url string `json:"url"`
 }
 
 type ArgoCDConfigs struct {
 	ArgoCdConfigurations []*EnvironmentConfigArgoCd
 	CommonEnvPrefix      *string
+	// Waves rolls out deployments to one concrete environment after the other,
+	// in the order of ArgoCdConfigurations, instead of to all of them at once.
+	Waves *ArgoCDWaves `json:"Waves,omitempty"`
+}
+
+type ArgoCDWaves struct {
+	// Delay after which the next concrete environment is deployed, even if the rollout-service
+	// did not report the previous one as healthy yet, e.g. "30m", see time.ParseDuration.
+	// Without a delay, the next concrete environment is only deployed once the previous one is healthy.
+	Delay string `json:"delay,omitempty"`
+}
+
+// DelayDuration returns the parsed Delay, or 0 if there is none.
+func (w *ArgoCDWaves) DelayDuration() (time.Duration, error) {
+	if w == nil || w.Delay == "" {
+		return 0, nil
+	}
+	delay, err := time.ParseDuration(w.Delay)
+	if err != nil {
+		return 0, fmt.Errorf("invalid wave delay %q: %w", w.Delay, err)
+	}
+	if delay < 0 {
+		return 0, fmt.Errorf("invalid wave delay %q: must not be negative", w.Delay)
+	}
+	return delay, nil
 }
 
 type EnvironmentConfigUpstream struct {
 	Environment types.EnvName `json:"environment,omitempty"`
 	Latest      bool          `json:"latest,omitempty"`
+	// SoakTime is the minimum time a version has to be deployed on the upstream environment
+	// before a release train promotes it, e.g. "2h", see time.ParseDuration.
+	// Only applies to upstream environments, not to latest.
+	SoakTime string `json:"soakTime,omitempty"`
+}
+
+// SoakDuration returns the parsed SoakTime, or 0 if there is none.
+func (u *EnvironmentConfigUpstream) SoakDuration() (time.Duration, error) {
+	if u == nil || u.SoakTime == "" {
+		return 0, nil
+	}
+	soak, err := time.ParseDuration(u.SoakTime)
+	if err != nil {
+		return 0, fmt.Errorf("invalid soak time %q: %w", u.SoakTime, err)
+	}
+	if soak < 0 {
+		return 0, fmt.Errorf("invalid soak time %q: must not be negative", u.SoakTime)
+	}
+	return soak, nil
 }
 
 type AccessEntry struct {
@@ -49,6 +139,45 @@ type EnvironmentConfigArgoCd struct {
 	IgnoreDifferences        []ArgoCdIgnoreDifference `json:"ignoreDifferences,omitempty"`
 	SyncOptions              []string                 `json:"syncOptions,omitempty"`
 	ConcreteEnvName          string                   `json:"name,omitempty"`
+	// FluxCd renders Flux CD objects for the environment instead of Argo CD applications, if set.
+	FluxCd *EnvironmentConfigFluxCd `json:"fluxcd,omitempty"`
+}
+
+// EnvironmentConfigFluxCd configures the Flux CD objects that are rendered for an environment.
+// The target namespace and the application annotations are taken from the Argo CD configuration.
+type EnvironmentConfigFluxCd struct {
+	// Namespace of the Flux objects, DefaultFluxNamespace if empty.
+	Namespace string `json:"namespace,omitempty"`
+	// Interval in which Flux reconciles the objects, e.g. "5m", see time.ParseDuration.
+	// DefaultFluxInterval if empty.
+	Interval string `json:"interval,omitempty"`
+	// SecretRef is the name of the secret with the credentials for the manifest repository.
+	SecretRef string `json:"secretRef,omitempty"`
+	// KubeConfigSecretRef is the name of the secret with the kubeconfig of the target cluster.
+	// The manifests are applied to the cluster that Flux runs in if empty.
+	KubeConfigSecretRef string `json:"kubeConfigSecretRef,omitempty"`
+	// ServiceAccountName is the service account that Flux impersonates when applying the manifests.
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+}
+
+const (
+	DefaultFluxNamespace = "flux-system"
+	DefaultFluxInterval  = "5m"
+)
+
+// IntervalDuration returns the parsed Interval, or the DefaultFluxInterval if there is none.
+func (f *EnvironmentConfigFluxCd) IntervalDuration() (time.Duration, error) {
+	if f == nil || f.Interval == "" {
+		return time.ParseDuration(DefaultFluxInterval)
+	}
+	interval, err := time.ParseDuration(f.Interval)
+	if err != nil {
+		return 0, fmt.Errorf("invalid flux interval %q: %w", f.Interval, err)
+	}
+	if interval <= 0 {
+		return 0, fmt.Errorf("invalid flux interval %q: must be positive", f.Interval)
+	}
+	return interval, nil
 }
 
 // ArgoCdDestination
@@ -92,3 +221,19 @@ func IsAAEnv(config *EnvironmentConfig) bool {
 	}
 	return config.ArgoCdConfigs.CommonEnvPrefix != nil && *config.ArgoCdConfigs.CommonEnvPrefix != ""
 }
+
+// WaveEnvironments returns the concrete environment names of an active/active environment in the order
+// in which deployments are rolled out to them, or nil if the environment is not rolled out in waves.
+func WaveEnvironments(config *EnvironmentConfig) []string {
+	if !IsAAEnv(config) || config.ArgoCdConfigs == nil || config.ArgoCdConfigs.Waves == nil {
+		return nil
+	}
+	result := make([]string, 0, len(config.ArgoCdConfigs.ArgoCdConfigurations))
+	for _, cfg := range config.ArgoCdConfigs.ArgoCdConfigurations {
+		result = append(result, cfg.ConcreteEnvName)
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
@@ -0,0 +1,110 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+package config
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWaveEnvironments(t *testing.T) {
+	tcs := []struct {
+		Name     string
+		Json     string
+		Expected []string
+	}{
+		{
+			Name:     "not an active/active environment",
+			Json:     `{"argocd": {"destination": {"name": "d", "server": "s"}}}`,
+			Expected: nil,
+		},
+		{
+			Name:     "active/active environment without waves",
+			Json:     `{"argocdConfigs": {"CommonEnvPrefix": "aa", "ArgoCdConfigurations": [{"name": "de-1"}, {"name": "de-2"}]}}`,
+			Expected: nil,
+		},
+		{
+			Name:     "active/active environment with waves",
+			Json:     `{"argocdConfigs": {"CommonEnvPrefix": "aa", "ArgoCdConfigurations": [{"name": "de-1"}, {"name": "de-2"}, {"name": "de-3"}], "Waves": {"delay": "30m"}}}`,
+			Expected: []string{"de-1", "de-2", "de-3"},
+		},
+		{
+			Name:     "waves without concrete environments",
+			Json:     `{"argocdConfigs": {"CommonEnvPrefix": "aa", "ArgoCdConfigurations": [], "Waves": {}}}`,
+			Expected: nil,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			var cfg EnvironmentConfig
+			if err := json.Unmarshal([]byte(tc.Json), &cfg); err != nil {
+				t.Fatalf("could not parse config: %v", err)
+			}
+			if diff := cmp.Diff(tc.Expected, WaveEnvironments(&cfg)); diff != "" {
+				t.Errorf("wave environments mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestArgoCDWavesDelayDuration(t *testing.T) {
+	tcs := []struct {
+		Name     string
+		Waves    *ArgoCDWaves
+		Expected time.Duration
+		WantErr  bool
+	}{
+		{
+			Name:     "no waves",
+			Waves:    nil,
+			Expected: 0,
+		},
+		{
+			Name:     "no delay",
+			Waves:    &ArgoCDWaves{Delay: ""},
+			Expected: 0,
+		},
+		{
+			Name:     "delay",
+			Waves:    &ArgoCDWaves{Delay: "1h30m"},
+			Expected: 90 * time.Minute,
+		},
+		{
+			Name:    "invalid delay",
+			Waves:   &ArgoCDWaves{Delay: "soon"},
+			WantErr: true,
+		},
+		{
+			Name:    "negative delay",
+			Waves:   &ArgoCDWaves{Delay: "-5m"},
+			WantErr: true,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			actual, err := tc.Waves.DelayDuration()
+			if (err != nil) != tc.WantErr {
+				t.Fatalf("expected error %t, got %v", tc.WantErr, err)
+			}
+			if actual != tc.Expected {
+				t.Errorf("expected delay %v, got %v", tc.Expected, actual)
+			}
+		})
+	}
+}
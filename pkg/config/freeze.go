@@ -0,0 +1,113 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// EnvironmentFreeze prevents deployments to an environment while it is active.
+// A freeze is either recurring (Schedule and Duration, like an ArgoCdSyncWindow)
+// or a one-off date range (Start and End), never both.
+type EnvironmentFreeze struct {
+	Id       string    `json:"id"`
+	Message  string    `json:"message,omitempty"`
+	Schedule string    `json:"schedule,omitempty"` // crontab format, evaluated in UTC
+	Duration string    `json:"duration,omitempty"` // e.g. "12h", see time.ParseDuration
+	Start    time.Time `json:"start,omitzero"`     // inclusive
+	End      time.Time `json:"end,omitzero"`       // exclusive
+}
+
+func (f EnvironmentFreeze) IsRecurring() bool {
+	return f.Schedule != ""
+}
+
+// Validate returns an error if the freeze can never be evaluated.
+func (f EnvironmentFreeze) Validate() error {
+	if f.Id == "" {
+		return fmt.Errorf("freeze id must not be empty")
+	}
+	if f.IsRecurring() {
+		if !f.Start.IsZero() || !f.End.IsZero() {
+			return fmt.Errorf("freeze %q: a recurring freeze must not have start or end", f.Id)
+		}
+		if _, err := cron.ParseStandard(f.Schedule); err != nil {
+			return fmt.Errorf("freeze %q: invalid schedule %q: %w", f.Id, f.Schedule, err)
+		}
+		duration, err := time.ParseDuration(f.Duration)
+		if err != nil {
+			return fmt.Errorf("freeze %q: invalid duration %q: %w", f.Id, f.Duration, err)
+		}
+		if duration <= 0 {
+			return fmt.Errorf("freeze %q: duration must be positive, got %q", f.Id, f.Duration)
+		}
+		return nil
+	}
+	if f.Duration != "" {
+		return fmt.Errorf("freeze %q: duration is only allowed together with a schedule", f.Id)
+	}
+	if f.Start.IsZero() || f.End.IsZero() {
+		return fmt.Errorf("freeze %q: either schedule and duration or start and end are required", f.Id)
+	}
+	if !f.End.After(f.Start) {
+		return fmt.Errorf("freeze %q: end (%s) must be after start (%s)", f.Id, f.End.Format(time.RFC3339), f.Start.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// ActiveAt returns true if the freeze covers the given point in time.
+func (f EnvironmentFreeze) ActiveAt(now time.Time) (bool, error) {
+	if err := f.Validate(); err != nil {
+		return false, err
+	}
+	if !f.IsRecurring() {
+		return !now.Before(f.Start) && now.Before(f.End), nil
+	}
+	schedule, err := cron.ParseStandard(f.Schedule)
+	if err != nil {
+		return false, err
+	}
+	duration, err := time.ParseDuration(f.Duration)
+	if err != nil {
+		return false, err
+	}
+	// Same approach as argocd sync windows: the freeze is active if the schedule
+	// fired within the last duration.
+	now = now.UTC()
+	return !schedule.Next(now.Add(-duration)).After(now), nil
+}
+
+// ActiveFreeze returns the first freeze of the config that is active at the given time,
+// or nil if deployments are allowed.
+func (c *EnvironmentConfig) ActiveFreeze(now time.Time) (*EnvironmentFreeze, error) {
+	if c == nil {
+		return nil, nil
+	}
+	for i := range c.Freezes {
+		active, err := c.Freezes[i].ActiveAt(now)
+		if err != nil {
+			return nil, err
+		}
+		if active {
+			return &c.Freezes[i], nil
+		}
+	}
+	return nil, nil
+}
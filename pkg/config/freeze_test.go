@@ -0,0 +1,153 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEnvironmentFreezeValidate(t *testing.T) {
+	start := time.Date(2024, 12, 20, 0, 0, 0, 0, time.UTC)
+	tcs := []struct {
+		Name    string
+		Freeze  EnvironmentFreeze
+		WantErr bool
+	}{
+		{
+			Name:   "recurring freeze",
+			Freeze: EnvironmentFreeze{Id: "weekend", Schedule: "0 18 * * 5", Duration: "62h"},
+		},
+		{
+			Name:   "one-off freeze",
+			Freeze: EnvironmentFreeze{Id: "holidays", Start: start, End: start.Add(14 * 24 * time.Hour)},
+		},
+		{
+			Name:    "missing id",
+			Freeze:  EnvironmentFreeze{Schedule: "0 18 * * 5", Duration: "62h"},
+			WantErr: true,
+		},
+		{
+			Name:    "invalid schedule",
+			Freeze:  EnvironmentFreeze{Id: "f", Schedule: "every friday", Duration: "62h"},
+			WantErr: true,
+		},
+		{
+			Name:    "schedule without duration",
+			Freeze:  EnvironmentFreeze{Id: "f", Schedule: "0 18 * * 5"},
+			WantErr: true,
+		},
+		{
+			Name:    "negative duration",
+			Freeze:  EnvironmentFreeze{Id: "f", Schedule: "0 18 * * 5", Duration: "-1h"},
+			WantErr: true,
+		},
+		{
+			Name:    "schedule and date range",
+			Freeze:  EnvironmentFreeze{Id: "f", Schedule: "0 18 * * 5", Duration: "1h", Start: start, End: start.Add(time.Hour)},
+			WantErr: true,
+		},
+		{
+			Name:    "end before start",
+			Freeze:  EnvironmentFreeze{Id: "f", Start: start, End: start.Add(-time.Hour)},
+			WantErr: true,
+		},
+		{
+			Name:    "neither schedule nor date range",
+			Freeze:  EnvironmentFreeze{Id: "f"},
+			WantErr: true,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := tc.Freeze.Validate()
+			if tc.WantErr != (err != nil) {
+				t.Fatalf("expected error: %v, got: %v", tc.WantErr, err)
+			}
+		})
+	}
+}
+
+func TestEnvironmentConfigActiveFreeze(t *testing.T) {
+	// Friday 18:00 until Monday 08:00:
+	weekend := EnvironmentFreeze{Id: "weekend", Schedule: "0 18 * * 5", Duration: "62h"}
+	holidays := EnvironmentFreeze{
+		Id:    "holidays",
+		Start: time.Date(2024, 12, 20, 0, 0, 0, 0, time.UTC),
+		End:   time.Date(2025, 1, 7, 0, 0, 0, 0, time.UTC),
+	}
+	cfg := EnvironmentConfig{Freezes: []EnvironmentFreeze{weekend, holidays}}
+	tcs := []struct {
+		Name     string
+		Now      time.Time
+		Expected *EnvironmentFreeze
+	}{
+		{
+			Name:     "weekday",
+			Now:      time.Date(2024, 6, 12, 12, 0, 0, 0, time.UTC),
+			Expected: nil,
+		},
+		{
+			Name:     "start of the recurring window",
+			Now:      time.Date(2024, 6, 14, 18, 0, 0, 0, time.UTC),
+			Expected: &weekend,
+		},
+		{
+			Name:     "during the recurring window",
+			Now:      time.Date(2024, 6, 16, 12, 0, 0, 0, time.UTC),
+			Expected: &weekend,
+		},
+		{
+			Name:     "end of the recurring window",
+			Now:      time.Date(2024, 6, 17, 8, 0, 0, 0, time.UTC),
+			Expected: nil,
+		},
+		{
+			Name:     "start of the date range",
+			Now:      holidays.Start,
+			Expected: &holidays,
+		},
+		{
+			Name:     "during the date range",
+			Now:      time.Date(2024, 12, 30, 12, 0, 0, 0, time.UTC),
+			Expected: &holidays,
+		},
+		{
+			Name:     "end of the date range",
+			Now:      holidays.End,
+			Expected: nil,
+		},
+		{
+			Name:     "other time zones are converted",
+			Now:      time.Date(2024, 6, 14, 20, 30, 0, 0, time.FixedZone("CEST", 2*60*60)),
+			Expected: &weekend,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			actual, err := cfg.ActiveFreeze(tc.Now)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.Expected, actual); diff != "" {
+				t.Errorf("active freeze mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
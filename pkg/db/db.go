@@ -274,6 +274,18 @@ const (
 	EvtDeleteAAEnvironmentConfig        EventType = "EvtDeleteAAEnvironmentConfig"
 	EvtCreateManifestLock               EventType = "CreateManifestLock"
 	EvtDeleteManifestLock               EventType = "DeleteManifestLock"
+	EvtCreateEnvironmentFreeze          EventType = "CreateEnvironmentFreeze"
+	EvtDeleteEnvironmentFreeze          EventType = "DeleteEnvironmentFreeze"
+	EvtCreateEnvironmentGroupFreeze     EventType = "CreateEnvironmentGroupFreeze"
+	EvtDeleteEnvironmentGroupFreeze     EventType = "DeleteEnvironmentGroupFreeze"
+	EvtCreateDeploymentApproval         EventType = "CreateDeploymentApproval"
+	EvtApproveDeployment                EventType = "ApproveDeployment"
+	EvtRejectDeployment                 EventType = "RejectDeployment"
+	EvtPromoteAAWave                    EventType = "PromoteAAWave"
+	EvtCancelQueuedVersion              EventType = "CancelQueuedVersion"
+	EvtDeployQueuedVersion              EventType = "DeployQueuedVersion"
+	EvtSetReleaseRetentionPolicy        EventType = "SetReleaseRetentionPolicy"
+	EvtDeleteReleaseRetentionPolicy     EventType = "DeleteReleaseRetentionPolicy"
 )
 
 /*
@@ -781,6 +793,58 @@ func (h *DBHandler) DBWriteLockPreventedDeploymentEvent(ctx context.Context, tra
 	return h.WriteEvent(ctx, transaction, transformerID, uuid, event.EventTypeLockPreventedDeployment, sourceCommitHash, jsonToInsert)
 }
 
+func (h *DBHandler) DBWriteFreezePreventedDeploymentEvent(ctx context.Context, transaction *sql.Tx, transformerID TransformerID, uuid, sourceCommitHash string, freezePreventedDeploymentEvent *event.FreezePreventedDeployment) error {
+	metadata := event.Metadata{
+		Uuid:           uuid,
+		EventType:      string(event.EventTypeFreezePreventedDeployment),
+		ReleaseVersion: 0, // don't care about release version for this event
+	}
+	jsonToInsert, err := json.Marshal(event.DBEventGo{
+		EventData:     freezePreventedDeploymentEvent,
+		EventMetadata: metadata,
+	})
+
+	if err != nil {
+		return fmt.Errorf("error marshalling freeze prevented deployment event to Json. Error: %v", err)
+	}
+	return h.WriteEvent(ctx, transaction, transformerID, uuid, event.EventTypeFreezePreventedDeployment, sourceCommitHash, jsonToInsert)
+}
+
+func (h *DBHandler) DBWriteAutoRollbackEvent(ctx context.Context, transaction *sql.Tx, transformerID TransformerID, uuid, sourceCommitHash string, autoRollbackEvent *event.AutoRollback) error {
+	metadata := event.Metadata{
+		Uuid:           uuid,
+		EventType:      string(event.EventTypeAutoRollback),
+		ReleaseVersion: 0, // don't care about release version for this event
+	}
+	jsonToInsert, err := json.Marshal(event.DBEventGo{
+		EventData:     autoRollbackEvent,
+		EventMetadata: metadata,
+	})
+
+	if err != nil {
+		return fmt.Errorf("error marshalling auto rollback event to Json. Error: %v", err)
+	}
+	return h.WriteEvent(ctx, transaction, transformerID, uuid, event.EventTypeAutoRollback, sourceCommitHash, jsonToInsert)
+}
+
+// DBWriteRolloutStatusChangedEvent is called by the rollout-service, which has no transformer, so the transformerID is always 0.
+func (h *DBHandler) DBWriteRolloutStatusChangedEvent(ctx context.Context, transaction *sql.Tx, uuid, sourceCommitHash string, rolloutStatusChanged *event.RolloutStatusChanged) error {
+	metadata := event.Metadata{
+		Uuid:           uuid,
+		EventType:      string(event.EventTypeRolloutStatusChanged),
+		ReleaseVersion: 0, // don't care about release version for this event
+	}
+	jsonToInsert, err := json.Marshal(event.DBEventGo{
+		EventData:     rolloutStatusChanged,
+		EventMetadata: metadata,
+	})
+
+	if err != nil {
+		return fmt.Errorf("error marshalling rollout status changed event to Json. Error: %v", err)
+	}
+	return h.WriteEvent(ctx, transaction, 0, uuid, event.EventTypeRolloutStatusChanged, sourceCommitHash, jsonToInsert)
+}
+
 func (h *DBHandler) DBWriteReplacedByEvent(ctx context.Context, transaction *sql.Tx, transformerID TransformerID, uuid, sourceCommitHash string, replacedBy *event.ReplacedBy) error {
 	metadata := event.Metadata{
 		Uuid:           uuid,
@@ -821,6 +885,60 @@ func (h *DBHandler) DBWriteDeploymentEvent(ctx context.Context, transaction *sql
 	return h.WriteEvent(ctx, transaction, transformerID, uuid, event.EventTypeDeployment, sourceCommitHash, jsonToInsert)
 }
 
+// DBWriteLockCreatedEvent writes an event that is not linked to any commit, so sourceCommitHash is usually empty.
+func (h *DBHandler) DBWriteLockCreatedEvent(ctx context.Context, transaction *sql.Tx, transformerID TransformerID, uuid, sourceCommitHash string, lockCreated *event.LockCreated) error {
+	metadata := event.Metadata{
+		Uuid:           uuid,
+		EventType:      string(event.EventTypeLockCreated),
+		ReleaseVersion: 0, // don't care about release version for this event
+	}
+	jsonToInsert, err := json.Marshal(event.DBEventGo{
+		EventData:     lockCreated,
+		EventMetadata: metadata,
+	})
+
+	if err != nil {
+		return fmt.Errorf("error marshalling lock created event to Json. Error: %v", err)
+	}
+	return h.WriteEvent(ctx, transaction, transformerID, uuid, event.EventTypeLockCreated, sourceCommitHash, jsonToInsert)
+}
+
+// DBWriteLockDeletedEvent writes an event that is not linked to any commit, so sourceCommitHash is usually empty.
+func (h *DBHandler) DBWriteLockDeletedEvent(ctx context.Context, transaction *sql.Tx, transformerID TransformerID, uuid, sourceCommitHash string, lockDeleted *event.LockDeleted) error {
+	metadata := event.Metadata{
+		Uuid:           uuid,
+		EventType:      string(event.EventTypeLockDeleted),
+		ReleaseVersion: 0, // don't care about release version for this event
+	}
+	jsonToInsert, err := json.Marshal(event.DBEventGo{
+		EventData:     lockDeleted,
+		EventMetadata: metadata,
+	})
+
+	if err != nil {
+		return fmt.Errorf("error marshalling lock deleted event to Json. Error: %v", err)
+	}
+	return h.WriteEvent(ctx, transaction, transformerID, uuid, event.EventTypeLockDeleted, sourceCommitHash, jsonToInsert)
+}
+
+// DBWriteEnvironmentChangedEvent writes an event that is not linked to any commit, so sourceCommitHash is usually empty.
+func (h *DBHandler) DBWriteEnvironmentChangedEvent(ctx context.Context, transaction *sql.Tx, transformerID TransformerID, uuid, sourceCommitHash string, environmentChanged *event.EnvironmentChanged) error {
+	metadata := event.Metadata{
+		Uuid:           uuid,
+		EventType:      string(event.EventTypeEnvironmentChanged),
+		ReleaseVersion: 0, // don't care about release version for this event
+	}
+	jsonToInsert, err := json.Marshal(event.DBEventGo{
+		EventData:     environmentChanged,
+		EventMetadata: metadata,
+	})
+
+	if err != nil {
+		return fmt.Errorf("error marshalling environment changed event to Json. Error: %v", err)
+	}
+	return h.WriteEvent(ctx, transaction, transformerID, uuid, event.EventTypeEnvironmentChanged, sourceCommitHash, jsonToInsert)
+}
+
 func (h *DBHandler) DBSelectAnyEvent(ctx context.Context, transaction *sql.Tx) (_ *EventRow, err error) {
 	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectAnyEvent")
 	defer func() {
@@ -1060,6 +1178,8 @@ type LockMetadata struct {
 type LockDeletionMetadata struct {
 	DeletedByUser  string
 	DeletedByEmail string
+	// Reason is empty for locks deleted by a user and set for locks that kuberpult deleted on its own, e.g. "expired".
+	Reason string
 }
 
 type ReleaseWithManifest struct {
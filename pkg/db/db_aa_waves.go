@@ -0,0 +1,291 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	"github.com/freiheit-com/kuberpult/pkg/types"
+)
+
+/*
+aa_wave_deployments holds the state of deployments to active/active environments that are rolled out in waves.
+
+There is one row per app, environment and concrete environment.
+The cd-service replaces the rows of an app and environment on every deployment: all rows get the new target,
+and the first concrete environment is marked as deployed right away.
+The manifest-repo-export-service then marks the remaining concrete environments as deployed one after the other.
+*/
+const aaWaveDeploymentsTable = "aa_wave_deployments"
+
+type AAWaveDeployment struct {
+	App           types.AppName
+	Env           types.EnvName
+	ConcreteEnv   string
+	Position      int // the order of the concrete environment in the wave, starting at 0
+	Target        types.ReleaseNumbers
+	TargetCreated time.Time
+	Deployed      *types.ReleaseNumbers // nil if the wave never reached this concrete environment
+	DeployedAt    *time.Time
+	TransformerID TransformerID // the deployment that started the wave
+}
+
+// IsDeployed returns true if the target version is deployed on the concrete environment.
+func (w *AAWaveDeployment) IsDeployed() bool {
+	return w.Deployed != nil && types.Equal(*w.Deployed, w.Target)
+}
+
+// INSERTS
+
+// DBReplaceAAWaveDeployments replaces all rows of the app and environment with the given ones.
+func (h *DBHandler) DBReplaceAAWaveDeployments(ctx context.Context, tx *sql.Tx, app types.AppName, env types.EnvName, waves []AAWaveDeployment) (err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBReplaceAAWaveDeployments")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	if err := h.DBDeleteAAWaveDeployments(ctx, tx, app, env); err != nil {
+		return err
+	}
+	insertQuery := h.AdaptQuery(`
+		INSERT INTO ` + aaWaveDeploymentsTable + ` (appname, envname, concrete_envname, position, target_version, target_revision, target_created, deployed_version, deployed_revision, deployed_at, transformereslversion)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+	`)
+	span.SetTag("query", insertQuery)
+	for _, wave := range waves {
+		var deployedVersion, deployedRevision sql.NullInt64
+		if wave.Deployed != nil && wave.Deployed.Version != nil {
+			deployedVersion = sql.NullInt64{Int64: int64(*wave.Deployed.Version), Valid: true}
+			deployedRevision = sql.NullInt64{Int64: int64(wave.Deployed.Revision), Valid: true}
+		}
+		_, err = tx.ExecContext(ctx, insertQuery,
+			app,
+			env,
+			wave.ConcreteEnv,
+			wave.Position,
+			*wave.Target.Version,
+			wave.Target.Revision,
+			wave.TargetCreated,
+			deployedVersion,
+			deployedRevision,
+			wave.DeployedAt,
+			wave.TransformerID,
+		)
+		if err != nil {
+			return fmt.Errorf("could not insert wave deployment of app %s on %s/%s: %w", app, env, wave.ConcreteEnv, err)
+		}
+	}
+	return nil
+}
+
+// UPDATES
+
+// DBUpdateAAWaveDeployed marks the target version as deployed on the concrete environment.
+// It returns false if the target of the concrete environment changed in the meantime.
+func (h *DBHandler) DBUpdateAAWaveDeployed(ctx context.Context, tx *sql.Tx, wave AAWaveDeployment, deployedAt time.Time) (_ bool, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBUpdateAAWaveDeployed")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	updateQuery := h.AdaptQuery(`
+		UPDATE ` + aaWaveDeploymentsTable + `
+		SET deployed_version = target_version, deployed_revision = target_revision, deployed_at = ?
+		WHERE appname = ? AND envname = ? AND concrete_envname = ? AND target_version = ? AND target_revision = ?;
+	`)
+	span.SetTag("query", updateQuery)
+	result, err := tx.ExecContext(ctx, updateQuery,
+		deployedAt,
+		wave.App,
+		wave.Env,
+		wave.ConcreteEnv,
+		*wave.Target.Version,
+		wave.Target.Revision,
+	)
+	if err != nil {
+		return false, fmt.Errorf("could not update wave deployment of app %s on %s/%s: %w", wave.App, wave.Env, wave.ConcreteEnv, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("could not read affected rows of wave deployment of app %s on %s/%s: %w", wave.App, wave.Env, wave.ConcreteEnv, err)
+	}
+	return affected == 1, nil
+}
+
+// DELETES
+
+// DBDeleteAAWaveDeployments deletes all rows of the app and environment.
+func (h *DBHandler) DBDeleteAAWaveDeployments(ctx context.Context, tx *sql.Tx, app types.AppName, env types.EnvName) (err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBDeleteAAWaveDeployments")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	deleteQuery := h.AdaptQuery(`
+		DELETE FROM ` + aaWaveDeploymentsTable + `
+		WHERE appname = ? AND envname = ?;
+	`)
+	span.SetTag("query", deleteQuery)
+	_, err = tx.ExecContext(ctx, deleteQuery, app, env)
+	if err != nil {
+		return fmt.Errorf("could not delete wave deployments of app %s on %s: %w", app, env, err)
+	}
+	return nil
+}
+
+// SELECTS
+
+const selectAAWaveDeploymentColumns = `appname, envname, concrete_envname, position, target_version, target_revision, target_created, deployed_version, deployed_revision, deployed_at, transformereslversion`
+
+// DBSelectAAWaveDeployments returns the rows of the app and environment in wave order.
+func (h *DBHandler) DBSelectAAWaveDeployments(ctx context.Context, tx *sql.Tx, app types.AppName, env types.EnvName) (_ []*AAWaveDeployment, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectAAWaveDeployments")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	selectQuery := h.AdaptQuery(`
+		SELECT ` + selectAAWaveDeploymentColumns + `
+		FROM ` + aaWaveDeploymentsTable + `
+		WHERE appname = ? AND envname = ?
+		ORDER BY position ASC;
+	`)
+	span.SetTag("query", selectQuery)
+	rows, err := tx.QueryContext(ctx, selectQuery, app, env)
+	if err != nil {
+		return nil, fmt.Errorf("could not query wave deployments of app %s on %s: %w", app, env, err)
+	}
+	defer closeRowsAndLog(rows, ctx, "DBSelectAAWaveDeployments")
+	return processAAWaveDeployments(rows)
+}
+
+// DBSelectAAWaveDeploymentsForApp returns the rows of the app on all environments, grouped by environment in wave order.
+func (h *DBHandler) DBSelectAAWaveDeploymentsForApp(ctx context.Context, tx *sql.Tx, app types.AppName) (_ map[types.EnvName][]*AAWaveDeployment, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectAAWaveDeploymentsForApp")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	selectQuery := h.AdaptQuery(`
+		SELECT ` + selectAAWaveDeploymentColumns + `
+		FROM ` + aaWaveDeploymentsTable + `
+		WHERE appname = ?
+		ORDER BY envname ASC, position ASC;
+	`)
+	span.SetTag("query", selectQuery)
+	rows, err := tx.QueryContext(ctx, selectQuery, app)
+	if err != nil {
+		return nil, fmt.Errorf("could not query wave deployments of app %s: %w", app, err)
+	}
+	defer closeRowsAndLog(rows, ctx, "DBSelectAAWaveDeploymentsForApp")
+	waves, err := processAAWaveDeployments(rows)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[types.EnvName][]*AAWaveDeployment)
+	for _, wave := range waves {
+		result[wave.Env] = append(result[wave.Env], wave)
+	}
+	return result, nil
+}
+
+// DBSelectUnfinishedAAWaveDeployments returns all rows of the apps and environments whose wave
+// did not reach all concrete environments yet, ordered by app, environment and wave order.
+func (h *DBHandler) DBSelectUnfinishedAAWaveDeployments(ctx context.Context, tx *sql.Tx) (_ []*AAWaveDeployment, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectUnfinishedAAWaveDeployments")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	selectQuery := h.AdaptQuery(`
+		SELECT ` + selectAAWaveDeploymentColumns + `
+		FROM ` + aaWaveDeploymentsTable + ` AS waves
+		WHERE EXISTS (
+			SELECT 1 FROM ` + aaWaveDeploymentsTable + ` AS unfinished
+			WHERE unfinished.appname = waves.appname AND unfinished.envname = waves.envname
+			AND (unfinished.deployed_version IS NULL OR unfinished.deployed_version != unfinished.target_version OR unfinished.deployed_revision != unfinished.target_revision)
+		)
+		ORDER BY appname ASC, envname ASC, position ASC;
+	`)
+	span.SetTag("query", selectQuery)
+	rows, err := tx.QueryContext(ctx, selectQuery)
+	if err != nil {
+		return nil, fmt.Errorf("could not query unfinished wave deployments: %w", err)
+	}
+	defer closeRowsAndLog(rows, ctx, "DBSelectUnfinishedAAWaveDeployments")
+	return processAAWaveDeployments(rows)
+}
+
+// DBSelectAllAAWaveDeployments returns all rows, ordered by app, environment and wave order.
+func (h *DBHandler) DBSelectAllAAWaveDeployments(ctx context.Context, tx *sql.Tx) (_ []*AAWaveDeployment, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectAllAAWaveDeployments")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	selectQuery := h.AdaptQuery(`
+		SELECT ` + selectAAWaveDeploymentColumns + `
+		FROM ` + aaWaveDeploymentsTable + `
+		ORDER BY appname ASC, envname ASC, position ASC;
+	`)
+	span.SetTag("query", selectQuery)
+	rows, err := tx.QueryContext(ctx, selectQuery)
+	if err != nil {
+		return nil, fmt.Errorf("could not query wave deployments: %w", err)
+	}
+	defer closeRowsAndLog(rows, ctx, "DBSelectAllAAWaveDeployments")
+	return processAAWaveDeployments(rows)
+}
+
+func processAAWaveDeployments(rows *sql.Rows) ([]*AAWaveDeployment, error) {
+	result := make([]*AAWaveDeployment, 0)
+	for rows.Next() {
+		var (
+			row              AAWaveDeployment
+			targetVersion    int64
+			deployedVersion  sql.NullInt64
+			deployedRevision sql.NullInt64
+			deployedAt       sql.NullTime
+		)
+		err := rows.Scan(
+			&row.App,
+			&row.Env,
+			&row.ConcreteEnv,
+			&row.Position,
+			&targetVersion,
+			&row.Target.Revision,
+			&row.TargetCreated,
+			&deployedVersion,
+			&deployedRevision,
+			&deployedAt,
+			&row.TransformerID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan aa_wave_deployments row: %w", err)
+		}
+		row.Target = types.MakeReleaseNumbers(uint64(targetVersion), row.Target.Revision)
+		if deployedVersion.Valid {
+			deployed := types.MakeReleaseNumbers(uint64(deployedVersion.Int64), uint64(deployedRevision.Int64))
+			row.Deployed = &deployed
+		}
+		if deployedAt.Valid {
+			row.DeployedAt = &deployedAt.Time
+		}
+		result = append(result, &row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
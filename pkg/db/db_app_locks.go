@@ -148,6 +148,40 @@ func (h *DBHandler) DBSelectAllActiveAppLocksForApp(ctx context.Context, tx *sql
 	return h.processAppLockRows(ctx, err, rows)
 }
 
+// DBSelectAllActiveAppLocksForAppAtTimestamp returns the application locks of the app that existed at the given time.
+func (h *DBHandler) DBSelectAllActiveAppLocksForAppAtTimestamp(ctx context.Context, tx *sql.Tx, appName types.AppName, ts time.Time) (_ []ApplicationLock, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectAllActiveAppLocksForAppAtTimestamp")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+
+	if h == nil {
+		return nil, nil
+	}
+	if tx == nil {
+		return nil, fmt.Errorf("DBSelectAllActiveAppLocksForAppAtTimestamp: no transaction provided")
+	}
+	selectQuery := h.AdaptQuery(`
+		SELECT h.created, h.lockid, h.envname, h.appname, h.metadata
+		FROM (
+			SELECT MAX(version) AS latest, envname, lockid
+			FROM ` + appLocksHistoryTable + `
+			WHERE appName = (?) AND created <= (?)
+			GROUP BY envname, lockid
+		) AS latest
+		JOIN ` + appLocksHistoryTable + ` AS h
+		ON latest.latest = h.version
+		WHERE h.deleted = false
+		ORDER BY h.lockId;`)
+	span.SetTag("query", selectQuery)
+
+	rows, err := tx.QueryContext(ctx, selectQuery, appName, ts)
+	if err != nil {
+		return nil, fmt.Errorf("could not query application locks history table from DB. Error: %w", err)
+	}
+	return h.processAppLockRows(ctx, err, rows)
+}
+
 func (h *DBHandler) DBSelectAllActiveAppLocksForSliceApps(ctx context.Context, tx *sql.Tx, appNames []types.AppName) (_ []ApplicationLock, err error) {
 	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectAllActiveAppLocksForSliceApps")
 	defer func() {
@@ -418,6 +452,7 @@ func (h *DBHandler) DBSelectAppLockHistory(ctx context.Context, tx *sql.Tx, envi
 			DeletionMetadata: LockDeletionMetadata{
 				DeletedByUser:  "",
 				DeletedByEmail: "",
+				Reason:         "",
 			},
 		}
 		var metaData string
@@ -471,7 +506,7 @@ func (h *DBHandler) DBWriteApplicationLock(ctx context.Context, tx *sql.Tx, lock
 	if err != nil {
 		return err
 	}
-	err = h.insertAppLockHistoryRow(ctx, tx, lockID, environment, appName, metadata, false, LockDeletionMetadata{DeletedByUser: "", DeletedByEmail: ""}) //Empty deletion metadata on insertion
+	err = h.insertAppLockHistoryRow(ctx, tx, lockID, environment, appName, metadata, false, LockDeletionMetadata{DeletedByUser: "", DeletedByEmail: "", Reason: ""}) //Empty deletion metadata on insertion
 	if err != nil {
 		return err
 	}
@@ -123,3 +123,44 @@ func (h *DBHandler) DBReadArgoEvent(ctx context.Context, tx *sql.Tx, appName typ
 	}
 	return toReturn, nil
 }
+
+// DBSelectAllArgoEvents returns the latest event of every app and environment.
+func (h *DBHandler) DBSelectAllArgoEvents(ctx context.Context, tx *sql.Tx) (_ []*ArgoEvent, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectAllArgoEvents")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	if h == nil {
+		return nil, nil
+	}
+	if tx == nil {
+		return nil, fmt.Errorf("DBSelectAllArgoEvents: no transaction provided")
+	}
+
+	selectQuery := h.AdaptQuery(`SELECT app, env, json, discarded
+		FROM ` + argoCdEventsTable + `
+		ORDER BY app, env;`)
+	rows, err := tx.QueryContext(ctx, selectQuery)
+	if err != nil {
+		return nil, fmt.Errorf("error reading argo cd events. Error: %w", err)
+	}
+	defer closeRowsAndLog(rows, ctx, "DBSelectAllArgoEvents")
+
+	result := []*ArgoEvent{}
+	for rows.Next() {
+		event := ArgoEvent{
+			App:       "",
+			Env:       "",
+			JsonEvent: []byte(""),
+			Discarded: false,
+		}
+		if err := rows.Scan(&event.App, &event.Env, &event.JsonEvent, &event.Discarded); err != nil {
+			return nil, fmt.Errorf("error table for next argo_cd_events. Error: %w", err)
+		}
+		result = append(result, &event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,149 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+// EventTypes lists all event types of the event_sourcing_light table.
+var EventTypes = []EventType{
+	EvtCreateApplicationVersion,
+	EvtDeployApplicationVersion,
+	EvtCreateUndeployApplicationVersion,
+	EvtUndeployApplication,
+	EvtDeleteEnvFromApp,
+	EvtCreateEnvironmentLock,
+	EvtDeleteEnvironmentLock,
+	EvtCreateEnvironmentTeamLock,
+	EvtDeleteEnvironmentTeamLock,
+	EvtCreateEnvironmentGroupLock,
+	EvtDeleteEnvironmentGroupLock,
+	EvtCreateEnvironment,
+	EvtRenderEnvironment,
+	EvtDeleteEnvironment,
+	EvtCreateEnvironmentApplicationLock,
+	EvtDeleteEnvironmentApplicationLock,
+	EvtReleaseTrain,
+	EvtMigrationTransformer,
+	EvtEnvReleaseTrain,
+	EvtCleanupOldApplicationVersions,
+	EvtSkippedServices,
+	EvtExtendAAEnvironment,
+	EvtDeleteAAEnvironmentConfig,
+	EvtCreateManifestLock,
+	EvtDeleteManifestLock,
+	EvtCreateEnvironmentFreeze,
+	EvtDeleteEnvironmentFreeze,
+	EvtCreateEnvironmentGroupFreeze,
+	EvtDeleteEnvironmentGroupFreeze,
+	EvtCreateDeploymentApproval,
+	EvtApproveDeployment,
+	EvtRejectDeployment,
+	EvtPromoteAAWave,
+	EvtCancelQueuedVersion,
+	EvtDeployQueuedVersion,
+	EvtSetReleaseRetentionPolicy,
+	EvtDeleteReleaseRetentionPolicy,
+}
+
+// EslEventFilter restricts the esl events of DBSelectEslEventsBefore. Empty fields match everything.
+type EslEventFilter struct {
+	// From and To are inclusive
+	From       *time.Time
+	To         *time.Time
+	EventTypes []EventType
+	// Author matches the name or the email of the author in the metadata of the event
+	Author string
+}
+
+// DBSelectEslEventsBefore returns up to limit events with an eslVersion lower than before that match the filter,
+// newest first. before = 0 starts at the newest event.
+func (h *DBHandler) DBSelectEslEventsBefore(ctx context.Context, tx *sql.Tx, filter EslEventFilter, before EslVersion, limit uint) (_ []*EslEventRow, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectEslEventsBefore")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	if h == nil {
+		return nil, nil
+	}
+	if tx == nil {
+		return nil, fmt.Errorf("DBSelectEslEventsBefore: no transaction provided")
+	}
+	conditions := []string{}
+	args := []any{}
+	if before != 0 {
+		conditions = append(conditions, "eslVersion < ?")
+		args = append(args, before)
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "created >= ?")
+		args = append(args, *filter.From)
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "created <= ?")
+		args = append(args, *filter.To)
+	}
+	if len(filter.EventTypes) > 0 {
+		conditions = append(conditions, "event_type IN (?"+strings.Repeat(",?", len(filter.EventTypes)-1)+")")
+		for _, eventType := range filter.EventTypes {
+			args = append(args, string(eventType))
+		}
+	}
+	if filter.Author != "" {
+		conditions = append(conditions, "(json::jsonb->'metadata'->>'authorEmail' = ? OR json::jsonb->'metadata'->>'authorName' = ?)")
+		args = append(args, filter.Author, filter.Author)
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	selectQuery := h.AdaptQuery("SELECT eslVersion, created, event_type, json, trace_id, span_id FROM " + eslTable + " " + where + " ORDER BY eslVersion DESC LIMIT ?;")
+	span.SetTag("query", selectQuery)
+	args = append(args, limit)
+	rows, err := tx.QueryContext(ctx, selectQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("could not query event_sourcing_light table from DB. Error: %w", err)
+	}
+	defer closeRowsAndLog(rows, ctx, "DBSelectEslEventsBefore")
+	result := make([]*EslEventRow, 0)
+	for rows.Next() {
+		row := &EslEventRow{
+			EslVersion: 0,
+			Created:    time.Unix(0, 0),
+			EventType:  "",
+			EventJson:  "",
+			TraceId:    nil,
+			SpanId:     nil,
+		}
+		err := rows.Scan(&row.EslVersion, &row.Created, &row.EventType, &row.EventJson, &row.TraceId, &row.SpanId)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning event_sourcing_light row from DB. Error: %w", err)
+		}
+		result = append(result, row)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
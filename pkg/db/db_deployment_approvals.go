@@ -0,0 +1,371 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	"github.com/freiheit-com/kuberpult/pkg/types"
+)
+
+/*
+deployment_approvals holds deployments that wait for a four-eyes approval.
+
+The cd-service writes a pending row instead of deploying when a deployment or a
+release train targets an environment that requires an approval. A second user
+then approves the row, which executes the stored request, or rejects it.
+Pending rows that are not decided before expires_at are treated as expired,
+until DBExpireDeploymentApprovals marks them as expired.
+*/
+const deploymentApprovalsTable = "deployment_approvals"
+
+type DeploymentApprovalStatus string
+
+const (
+	DeploymentApprovalStatusPending  DeploymentApprovalStatus = "pending"
+	DeploymentApprovalStatusApproved DeploymentApprovalStatus = "approved"
+	DeploymentApprovalStatusRejected DeploymentApprovalStatus = "rejected"
+	DeploymentApprovalStatusExpired  DeploymentApprovalStatus = "expired"
+)
+
+// DeploymentApprovalRequest is the deployment that is executed once approved.
+// Exactly one of Deployment and ReleaseTrain is set.
+type DeploymentApprovalRequest struct {
+	Deployment   *DeploymentApprovalDeployment   `json:"deployment,omitempty"`
+	ReleaseTrain *DeploymentApprovalReleaseTrain `json:"releaseTrain,omitempty"`
+}
+
+type DeploymentApprovalDeployment struct {
+	Environment   types.EnvName `json:"env"`
+	Application   types.AppName `json:"app"`
+	Version       uint64        `json:"version"`
+	Revision      uint64        `json:"revision"`
+	LockBehaviour string        `json:"lockBehaviour"`
+	Author        string        `json:"author,omitempty"`
+	CiLink        string        `json:"ciLink,omitempty"`
+	// AutoRollback is set if the deployment is an automatic rollback of the rollout-service
+	AutoRollback *DeploymentApprovalRollback `json:"autoRollback,omitempty"`
+}
+
+type DeploymentApprovalRollback struct {
+	RolledBackVersion uint64 `json:"rolledBackVersion"`
+	Reason            string `json:"reason"`
+}
+
+type DeploymentApprovalReleaseTrain struct {
+	Target     string       `json:"target"`
+	TargetType string       `json:"targetType"`
+	Team       string       `json:"team,omitempty"`
+	CommitHash string       `json:"commitHash,omitempty"`
+	CiLink     string       `json:"ciLink,omitempty"`
+	GitTag     types.GitTag `json:"gitTag,omitempty"`
+}
+
+type DeploymentApproval struct {
+	ApprovalId       string
+	Created          time.Time
+	ExpiresAt        time.Time
+	Status           DeploymentApprovalStatus
+	App              types.AppName // empty for release trains
+	Environments     []types.EnvName
+	Request          DeploymentApprovalRequest
+	RequestedByName  string
+	RequestedByEmail string
+	// the verified identity of the requester, empty if the requester was not logged in, see auth.User
+	RequestedByIdentity string
+	RequestedByRoles    []string // the dex roles of the requester, the request is executed with them
+	RequestEslVersion   TransformerID
+
+	// only set once the approval is decided:
+	DecidedAt          *time.Time
+	DecidedByName      string
+	DecidedByEmail     string
+	DecidedByIdentity  string
+	Reason             string
+	DecisionEslVersion TransformerID
+}
+
+// IsExpired returns true if the approval is still pending, but can no longer be approved.
+func (a *DeploymentApproval) IsExpired(now time.Time) bool {
+	return a.Status == DeploymentApprovalStatusPending && !now.Before(a.ExpiresAt)
+}
+
+// INSERTS
+
+func (h *DBHandler) DBInsertDeploymentApproval(ctx context.Context, tx *sql.Tx, approval DeploymentApproval) (err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBInsertDeploymentApproval")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	environmentsJson, err := json.Marshal(approval.Environments)
+	if err != nil {
+		return fmt.Errorf("could not marshal environments of deployment approval %s: %w", approval.ApprovalId, err)
+	}
+	requestJson, err := json.Marshal(approval.Request)
+	if err != nil {
+		return fmt.Errorf("could not marshal request of deployment approval %s: %w", approval.ApprovalId, err)
+	}
+	rolesJson, err := json.Marshal(approval.RequestedByRoles)
+	if err != nil {
+		return fmt.Errorf("could not marshal roles of deployment approval %s: %w", approval.ApprovalId, err)
+	}
+	insertQuery := h.AdaptQuery(`
+		INSERT INTO ` + deploymentApprovalsTable + ` (approval_id, created, expires_at, status, app, environments, request, requested_by_name, requested_by_email, requested_by_identity, requested_by_roles, request_esl_version)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+	`)
+	span.SetTag("query", insertQuery)
+	_, err = tx.ExecContext(ctx, insertQuery,
+		approval.ApprovalId,
+		approval.Created,
+		approval.ExpiresAt,
+		approval.Status,
+		approval.App,
+		string(environmentsJson),
+		string(requestJson),
+		approval.RequestedByName,
+		approval.RequestedByEmail,
+		approval.RequestedByIdentity,
+		string(rolesJson),
+		approval.RequestEslVersion,
+	)
+	if err != nil {
+		return fmt.Errorf("could not insert deployment approval %s: %w", approval.ApprovalId, err)
+	}
+	return nil
+}
+
+// UPDATES
+
+// DBUpdateDeploymentApprovalDecision stores the decision on a pending approval.
+// It returns false if the approval does not exist or is not pending anymore.
+func (h *DBHandler) DBUpdateDeploymentApprovalDecision(ctx context.Context, tx *sql.Tx, approval DeploymentApproval) (_ bool, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBUpdateDeploymentApprovalDecision")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	updateQuery := h.AdaptQuery(`
+		UPDATE ` + deploymentApprovalsTable + `
+		SET status = ?, decided_at = ?, decided_by_name = ?, decided_by_email = ?, decided_by_identity = ?, reason = ?, decision_esl_version = ?
+		WHERE approval_id = ? AND status = ?;
+	`)
+	span.SetTag("query", updateQuery)
+	result, err := tx.ExecContext(ctx, updateQuery,
+		approval.Status,
+		approval.DecidedAt,
+		approval.DecidedByName,
+		approval.DecidedByEmail,
+		approval.DecidedByIdentity,
+		approval.Reason,
+		approval.DecisionEslVersion,
+		approval.ApprovalId,
+		DeploymentApprovalStatusPending,
+	)
+	if err != nil {
+		return false, fmt.Errorf("could not update deployment approval %s: %w", approval.ApprovalId, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("could not read affected rows of deployment approval %s: %w", approval.ApprovalId, err)
+	}
+	return affected == 1, nil
+}
+
+// DBExpireDeploymentApprovals marks all pending approvals that expired before now as expired.
+// It returns the number of expired approvals.
+func (h *DBHandler) DBExpireDeploymentApprovals(ctx context.Context, tx *sql.Tx, now time.Time) (_ int64, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBExpireDeploymentApprovals")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	updateQuery := h.AdaptQuery(`
+		UPDATE ` + deploymentApprovalsTable + `
+		SET status = ?
+		WHERE status = ? AND expires_at <= ?;
+	`)
+	span.SetTag("query", updateQuery)
+	result, err := tx.ExecContext(ctx, updateQuery, DeploymentApprovalStatusExpired, DeploymentApprovalStatusPending, now)
+	if err != nil {
+		return 0, fmt.Errorf("could not expire deployment approvals: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// DELETES
+
+// DBDeleteDeploymentApprovals deletes approved, rejected and expired approvals that were decided or expired before the given time.
+// Approvals whose last event was not processed by the manifest-export-service yet (see cutoff) are kept, since it still reads them.
+func (h *DBHandler) DBDeleteDeploymentApprovals(ctx context.Context, tx *sql.Tx, before time.Time, cutoff EslVersion) (_ int64, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBDeleteDeploymentApprovals")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	deleteQuery := h.AdaptQuery(`
+		DELETE FROM ` + deploymentApprovalsTable + `
+		WHERE status <> ?
+		  AND COALESCE(decided_at, expires_at) < ?
+		  AND COALESCE(decision_esl_version, request_esl_version) <= ?;
+	`)
+	span.SetTag("query", deleteQuery)
+	result, err := tx.ExecContext(ctx, deleteQuery, DeploymentApprovalStatusPending, before, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("could not delete deployment approvals: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// SELECTS
+
+const selectDeploymentApprovalColumns = `approval_id, created, expires_at, status, app, environments, request, requested_by_name, requested_by_email, requested_by_identity, requested_by_roles, request_esl_version, decided_at, decided_by_name, decided_by_email, decided_by_identity, reason, decision_esl_version`
+
+// DBSelectDeploymentApproval returns the approval with the given id, or nil if it does not exist.
+func (h *DBHandler) DBSelectDeploymentApproval(ctx context.Context, tx *sql.Tx, approvalId string) (_ *DeploymentApproval, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectDeploymentApproval")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	selectQuery := h.AdaptQuery(`
+		SELECT ` + selectDeploymentApprovalColumns + `
+		FROM ` + deploymentApprovalsTable + `
+		WHERE approval_id = ?
+		LIMIT 1;
+	`)
+	span.SetTag("query", selectQuery)
+	rows, err := tx.QueryContext(ctx, selectQuery, approvalId)
+	if err != nil {
+		return nil, fmt.Errorf("could not query deployment approval %s: %w", approvalId, err)
+	}
+	defer closeRowsAndLog(rows, ctx, "DBSelectDeploymentApproval")
+	approvals, err := processDeploymentApprovals(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(approvals) == 0 {
+		return nil, nil
+	}
+	return approvals[0], nil
+}
+
+// DBSelectPendingDeploymentApprovals returns all pending approvals, oldest first.
+// This includes approvals that already expired, use IsExpired to filter them.
+func (h *DBHandler) DBSelectPendingDeploymentApprovals(ctx context.Context, tx *sql.Tx) (_ []*DeploymentApproval, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectPendingDeploymentApprovals")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	selectQuery := h.AdaptQuery(`
+		SELECT ` + selectDeploymentApprovalColumns + `
+		FROM ` + deploymentApprovalsTable + `
+		WHERE status = ?
+		ORDER BY created ASC, approval_id ASC;
+	`)
+	span.SetTag("query", selectQuery)
+	rows, err := tx.QueryContext(ctx, selectQuery, DeploymentApprovalStatusPending)
+	if err != nil {
+		return nil, fmt.Errorf("could not query pending deployment approvals: %w", err)
+	}
+	defer closeRowsAndLog(rows, ctx, "DBSelectPendingDeploymentApprovals")
+	return processDeploymentApprovals(rows)
+}
+
+// DBSelectAllDeploymentApprovals returns all approvals regardless of their status, oldest first.
+func (h *DBHandler) DBSelectAllDeploymentApprovals(ctx context.Context, tx *sql.Tx) (_ []*DeploymentApproval, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectAllDeploymentApprovals")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	selectQuery := h.AdaptQuery(`
+		SELECT ` + selectDeploymentApprovalColumns + `
+		FROM ` + deploymentApprovalsTable + `
+		ORDER BY created ASC, approval_id ASC;
+	`)
+	span.SetTag("query", selectQuery)
+	rows, err := tx.QueryContext(ctx, selectQuery)
+	if err != nil {
+		return nil, fmt.Errorf("could not query deployment approvals: %w", err)
+	}
+	defer closeRowsAndLog(rows, ctx, "DBSelectAllDeploymentApprovals")
+	return processDeploymentApprovals(rows)
+}
+
+func processDeploymentApprovals(rows *sql.Rows) ([]*DeploymentApproval, error) {
+	result := make([]*DeploymentApproval, 0)
+	for rows.Next() {
+		var (
+			row                DeploymentApproval
+			environmentsJson   string
+			requestJson        string
+			rolesJson          string
+			decidedAt          sql.NullTime
+			decidedByName      sql.NullString
+			decidedByEmail     sql.NullString
+			decidedByIdentity  sql.NullString
+			reason             sql.NullString
+			decisionEslVersion sql.NullInt64
+		)
+		err := rows.Scan(
+			&row.ApprovalId,
+			&row.Created,
+			&row.ExpiresAt,
+			&row.Status,
+			&row.App,
+			&environmentsJson,
+			&requestJson,
+			&row.RequestedByName,
+			&row.RequestedByEmail,
+			&row.RequestedByIdentity,
+			&rolesJson,
+			&row.RequestEslVersion,
+			&decidedAt,
+			&decidedByName,
+			&decidedByEmail,
+			&decidedByIdentity,
+			&reason,
+			&decisionEslVersion,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan deployment_approvals row: %w", err)
+		}
+		if err := json.Unmarshal([]byte(environmentsJson), &row.Environments); err != nil {
+			return nil, fmt.Errorf("could not unmarshal environments of deployment approval %s: %w", row.ApprovalId, err)
+		}
+		if err := json.Unmarshal([]byte(requestJson), &row.Request); err != nil {
+			return nil, fmt.Errorf("could not unmarshal request of deployment approval %s: %w", row.ApprovalId, err)
+		}
+		if err := json.Unmarshal([]byte(rolesJson), &row.RequestedByRoles); err != nil {
+			return nil, fmt.Errorf("could not unmarshal roles of deployment approval %s: %w", row.ApprovalId, err)
+		}
+		if decidedAt.Valid {
+			row.DecidedAt = &decidedAt.Time
+		}
+		row.DecidedByName = decidedByName.String
+		row.DecidedByEmail = decidedByEmail.String
+		row.DecidedByIdentity = decidedByIdentity.String
+		row.Reason = reason.String
+		row.DecisionEslVersion = TransformerID(decisionEslVersion.Int64)
+		result = append(result, &row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
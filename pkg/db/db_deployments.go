@@ -61,6 +61,15 @@ type QueuedDeployment struct {
 	Env            types.EnvName
 	App            types.AppName
 	ReleaseNumbers types.ReleaseNumbers
+	Metadata       QueuedDeploymentMetadata
+}
+
+// QueuedDeploymentMetadata records who queued a version and why it was not deployed right away.
+// It is only stored for the latest deployment attempt.
+type QueuedDeploymentMetadata struct {
+	QueuedByName  string
+	QueuedByEmail string
+	Reason        string
 }
 
 /*
@@ -211,6 +220,40 @@ func (h *DBHandler) DBSelectAllLatestDeploymentsForApplication(ctx context.Conte
 	return processAllLatestDeploymentsForApp(rows)
 }
 
+// DBSelectAllLatestDeploymentsForApplicationAtTimestamp returns the deployments of the app on all environments as they were at the given time.
+func (h *DBHandler) DBSelectAllLatestDeploymentsForApplicationAtTimestamp(ctx context.Context, tx *sql.Tx, appName types.AppName, ts time.Time) (_ map[types.EnvName]Deployment, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectAllLatestDeploymentsForApplicationAtTimestamp")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+
+	selectQuery := h.AdaptQuery(`
+		SELECT h.created, h.appname, h.releaseVersion, h.envName, h.metadata, h.transformereslversion, h.revision
+		FROM (
+			SELECT MAX(version) AS latest, envName
+			FROM ` + deploymentsHistoryTable + `
+			WHERE appname = (?) AND created <= (?)
+			GROUP BY envName
+		) AS latest
+		JOIN ` + deploymentsHistoryTable + ` AS h
+		ON latest.latest = h.version
+		WHERE h.releaseVersion IS NOT NULL
+		ORDER BY h.envName;
+	`)
+	span.SetTag("query", selectQuery)
+	rows, err := tx.QueryContext(
+		ctx,
+		selectQuery,
+		appName,
+		ts,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not select deployment history of app %s from DB. Error: %w", appName, err)
+	}
+	defer closeRowsAndLog(rows, ctx, "DBSelectAllLatestDeploymentsForApplicationAtTimestamp")
+	return processAllLatestDeploymentsForApp(rows)
+}
+
 func (h *DBHandler) DBSelectOldestDeploymentForApplication(ctx context.Context, tx *sql.Tx, appName types.AppName) (*Deployment, error) {
 	selectQuery := h.AdaptQuery(`
 		SELECT created, releaseVersion, appName, envName, metadata, transformereslVersion, revision
@@ -328,6 +371,70 @@ func (h *DBHandler) DBSelectAllLatestDeploymentsOnEnvironment(ctx context.Contex
 	return processAllLatestDeployments(rows)
 }
 
+// DBSelectAllLatestDeploymentTimesOnEnvironment returns when the current version of each app was deployed on the environment.
+func (h *DBHandler) DBSelectAllLatestDeploymentTimesOnEnvironment(ctx context.Context, tx *sql.Tx, envName types.EnvName) (_ map[types.AppName]time.Time, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectAllLatestDeploymentTimesOnEnvironment")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+
+	selectQuery := h.AdaptQuery(`
+		SELECT appName, created
+		FROM ` + deploymentsTable + `
+		WHERE envName = ? AND releaseVersion IS NOT NULL
+		ORDER BY appName;
+	`)
+
+	span.SetTag("query", selectQuery)
+	span.SetTag("kuberpultEnvironment", envName)
+	rows, err := tx.QueryContext(
+		ctx,
+		selectQuery,
+		envName,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not select deployment times for env %s from DB. Error: %w", envName, err)
+	}
+	defer closeRowsAndLog(rows, ctx, "DBSelectAllLatestDeploymentTimesOnEnvironment")
+	return processAllLatestDeploymentTimes(rows)
+}
+
+// DBSelectAllLatestDeploymentTimesOnEnvironmentAtTimestamp is DBSelectAllLatestDeploymentTimesOnEnvironment as it was at ts.
+func (h *DBHandler) DBSelectAllLatestDeploymentTimesOnEnvironmentAtTimestamp(ctx context.Context, tx *sql.Tx, envName types.EnvName, ts time.Time) (_ map[types.AppName]time.Time, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectAllLatestDeploymentTimesOnEnvironmentAtTimestamp")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+
+	selectQuery := h.AdaptQuery(`
+	SELECT appname, created
+	FROM (
+		SELECT DISTINCT
+			ON (appName) appname,
+			releaseVersion,
+			created
+		FROM ` + deploymentsHistoryTable + `
+		WHERE
+			envname = ?
+			AND created <= ?
+		ORDER BY appname, version DESC
+	) AS latest
+	WHERE releaseVersion IS NOT NULL;
+	`)
+
+	rows, err := tx.QueryContext(
+		ctx,
+		selectQuery,
+		envName,
+		ts,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not select deployment times for env %s from DB. Error: %w", envName, err)
+	}
+	defer closeRowsAndLog(rows, ctx, "DBSelectAllLatestDeploymentTimesOnEnvironmentAtTimestamp")
+	return processAllLatestDeploymentTimes(rows)
+}
+
 func (h *DBHandler) DBSelectSpecificDeployment(ctx context.Context, tx *sql.Tx, appSelector types.AppName, envSelector string, releaseVersion uint64) (*Deployment, error) {
 	selectQuery := h.AdaptQuery(`
 		SELECT created, releaseVersion, appName, envName, metadata, transformereslVersion, revision
@@ -620,7 +727,7 @@ func (h *DBHandler) DBSelectLatestDeploymentAttemptOfAllApps(ctx context.Context
 
 	query := h.AdaptQuery(
 		`
-SELECT created, envName, appName, releaseVersion, revision
+SELECT created, envName, appName, releaseVersion, revision, metadata
 FROM ` + deploymentAttemptsLatestTable + `
 WHERE envName=?
 ORDER BY appName;
@@ -641,7 +748,7 @@ func (h *DBHandler) DBSelectLatestDeploymentAttemptOnAllEnvironments(ctx context
 
 	query := h.AdaptQuery(
 		`
-SELECT created, envName, appName, releaseVersion, revision
+SELECT created, envName, appName, releaseVersion, revision, metadata
 FROM ` + deploymentAttemptsLatestTable + `
 WHERE appName=?
 ORDER BY envName;
@@ -654,6 +761,88 @@ ORDER BY envName;
 	return h.processDeploymentAttemptsRows(ctx, rows, err)
 }
 
+// DBSelectLatestDeploymentAttemptOnAllEnvironmentsAtTimestamp returns the versions of the app that were queued at the given time.
+// The history does not contain the metadata of the queued versions, so it is always empty.
+func (h *DBHandler) DBSelectLatestDeploymentAttemptOnAllEnvironmentsAtTimestamp(ctx context.Context, tx *sql.Tx, appName types.AppName, ts time.Time) (_ []*QueuedDeployment, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectLatestDeploymentAttemptOnAllEnvironmentsAtTimestamp")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+
+	query := h.AdaptQuery(
+		`
+SELECT h.created, h.envName, h.appName, h.releaseVersion, COALESCE(h.revision, 0), '{}'
+FROM (
+	SELECT MAX(eslId) AS latest, envName
+	FROM ` + deploymentAttemptsHistoryTable + `
+	WHERE appName=? AND created <= ?
+	GROUP BY envName
+) AS latest
+JOIN ` + deploymentAttemptsHistoryTable + ` AS h
+ON latest.latest = h.eslId
+WHERE h.releaseVersion IS NOT NULL
+ORDER BY h.envName;
+		`)
+	span.SetTag("query", query)
+	rows, err := tx.QueryContext(
+		ctx,
+		query,
+		appName,
+		ts)
+	return h.processDeploymentAttemptsRows(ctx, rows, err)
+}
+
+// DBSelectAllLatestDeploymentAttempts returns the queued versions of all apps on all environments.
+func (h *DBHandler) DBSelectAllLatestDeploymentAttempts(ctx context.Context, tx *sql.Tx) (_ []*QueuedDeployment, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectAllLatestDeploymentAttempts")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+
+	query := h.AdaptQuery(
+		`
+SELECT created, envName, appName, releaseVersion, revision, metadata
+FROM ` + deploymentAttemptsLatestTable + `
+ORDER BY envName, appName;
+		`)
+	span.SetTag("query", query)
+	rows, err := tx.QueryContext(
+		ctx,
+		query)
+	return h.processDeploymentAttemptsRows(ctx, rows, err)
+}
+
+// DBSelectLatestDeploymentAttempt returns the latest deployment attempt of one app on one environment, or nil if there never was one.
+// If the queued version was deleted, the version is 0.
+func (h *DBHandler) DBSelectLatestDeploymentAttempt(ctx context.Context, tx *sql.Tx, environmentName types.EnvName, appName types.AppName) (_ *QueuedDeployment, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectLatestDeploymentAttempt")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+
+	query := h.AdaptQuery(
+		`
+SELECT created, envName, appName, releaseVersion, revision, metadata
+FROM ` + deploymentAttemptsLatestTable + `
+WHERE envName=? AND appName=?;
+		`)
+	span.SetTag("query", query)
+	rows, err := tx.QueryContext(
+		ctx,
+		query,
+		environmentName,
+		appName)
+	result, err := h.processDeploymentAttemptsRows(ctx, rows, err)
+	if err != nil || len(result) == 0 {
+		return nil, err
+	}
+	if result[0].ReleaseNumbers.Version == nil {
+		var deleted uint64 = 0
+		result[0].ReleaseNumbers.Version = &deleted
+	}
+	return result[0], nil
+}
+
 // UPDATE, DELETE, INSERT
 
 func (h *DBHandler) DBUpdateOrCreateDeployment(ctx context.Context, tx *sql.Tx, deployment Deployment) (err error) {
@@ -673,11 +862,20 @@ func (h *DBHandler) DBUpdateOrCreateDeployment(ctx context.Context, tx *sql.Tx,
 }
 
 func (h *DBHandler) DBWriteDeploymentAttempt(ctx context.Context, tx *sql.Tx, envName types.EnvName, appName types.AppName, version types.ReleaseNumbers) (err error) {
+	return h.DBWriteDeploymentAttemptWithMetadata(ctx, tx, envName, appName, version, QueuedDeploymentMetadata{
+		QueuedByName:  "",
+		QueuedByEmail: "",
+		Reason:        "",
+	})
+}
+
+func (h *DBHandler) DBWriteDeploymentAttemptWithMetadata(ctx context.Context, tx *sql.Tx, envName types.EnvName, appName types.AppName, version types.ReleaseNumbers, metadata QueuedDeploymentMetadata) (err error) {
 	return h.dbWriteDeploymentAttemptInternal(ctx, tx, &QueuedDeployment{
 		Created:        time.Time{},
 		Env:            envName,
 		App:            appName,
 		ReleaseNumbers: version,
+		Metadata:       metadata,
 	})
 }
 
@@ -695,6 +893,11 @@ func (h *DBHandler) DBDeleteDeploymentAttempt(ctx context.Context, tx *sql.Tx, e
 			Version:  nil,
 			Revision: 0,
 		},
+		Metadata: QueuedDeploymentMetadata{
+			QueuedByName:  "",
+			QueuedByEmail: "",
+			Reason:        "",
+		},
 	})
 }
 
@@ -1066,6 +1269,28 @@ func processAllLatestDeployments(rows *sql.Rows) (map[types.AppName]types.Releas
 	return result, nil
 }
 
+func processAllLatestDeploymentTimes(rows *sql.Rows) (map[types.AppName]time.Time, error) {
+	result := make(map[types.AppName]time.Time)
+	for rows.Next() {
+		var appName types.AppName
+		var created time.Time
+		err := rows.Scan(&appName, &created)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning deployments row from DB. Error: %w", err)
+		}
+		result[appName] = created
+	}
+	err := rows.Close()
+	if err != nil {
+		return nil, fmt.Errorf("deployments: row closing error: %v", err)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("deployments: row has error: %v", err)
+	}
+	return result, nil
+}
+
 func (h *DBHandler) processSingleDeploymentRow(_ context.Context, rows *sql.Rows) (*Deployment, error) {
 	var row = &DBDeployment{
 		Created:        time.Time{},
@@ -1145,10 +1370,15 @@ func (h *DBHandler) processDeploymentAttemptsRows(ctx context.Context, rows *sql
 	for rows.Next() {
 		//exhaustruct:ignore
 		var deployment = QueuedDeployment{}
-		err = rows.Scan(&deployment.Created, &deployment.Env, &deployment.App, &deployment.ReleaseNumbers.Version, &deployment.ReleaseNumbers.Revision)
+		var metadata string
+		err = rows.Scan(&deployment.Created, &deployment.Env, &deployment.App, &deployment.ReleaseNumbers.Version, &deployment.ReleaseNumbers.Revision, &metadata)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning deployment attempts row from DB. Error: %w", err)
 		}
+		err = json.Unmarshal([]byte(metadata), &deployment.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("error during json unmarshal of deployment attempt metadata. Error: %w. Data: %s", err, metadata)
+		}
 		result = append(result, &deployment)
 	}
 	return result, nil
@@ -1185,14 +1415,20 @@ INSERT INTO ` + deploymentAttemptsLatestTable + ` (
 	envName,
 	appName,
 	releaseVersion,
-	revision
-) VALUES (?, ?, ?, ?, ?)
+	revision,
+	metadata
+) VALUES (?, ?, ?, ?, ?, ?)
 ON CONFLICT (appName, envName) DO UPDATE SET
 	created = excluded.created,
 	releaseVersion = excluded.releaseVersion,
-	revision = excluded.revision;
+	revision = excluded.revision,
+	metadata = excluded.metadata;
 		`)
 
+		jsonToInsert, err := json.Marshal(deployment.Metadata)
+		if err != nil {
+			return fmt.Errorf("could not marshal json data: %w", err)
+		}
 		_, err = tx.ExecContext(
 			ctx,
 			upsertQuery,
@@ -1201,6 +1437,7 @@ ON CONFLICT (appName, envName) DO UPDATE SET
 			deployment.App,
 			nullVersion,
 			deployment.ReleaseNumbers.Revision,
+			jsonToInsert,
 		)
 		if err != nil {
 			return fmt.Errorf("could not write deployment_attempts_latest table in DB. Error: %w", err)
@@ -0,0 +1,132 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+// DeployedRelease is what the DORA metrics need to know about a deployed release.
+type DeployedRelease struct {
+	// Created is the time of the first version of the release
+	Created         time.Time
+	UndeployVersion bool
+}
+
+// DBSelectDeploymentsInTimeWindow returns all deployments between from and to, and the last deployment before from
+// of every app and environment, in the order they happened.
+func (h *DBHandler) DBSelectDeploymentsInTimeWindow(ctx context.Context, tx *sql.Tx, from, to time.Time) (_ []Deployment, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectDeploymentsInTimeWindow")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	if tx == nil {
+		return nil, fmt.Errorf("DBSelectDeploymentsInTimeWindow: no transaction provided")
+	}
+	selectQuery := h.AdaptQuery(`
+		SELECT created, releaseVersion, appName, envName, metadata, transformereslVersion, revision
+		FROM (
+			(SELECT DISTINCT ON (appName, envName) *
+			FROM ` + deploymentsHistoryTable + `
+			WHERE created < ?
+			ORDER BY appName, envName, version DESC)
+			UNION ALL
+			(SELECT *
+			FROM ` + deploymentsHistoryTable + `
+			WHERE created >= ? AND created <= ?)
+		) AS window_deployments
+		ORDER BY version;
+	`)
+	span.SetTag("query", selectQuery)
+	rows, err := tx.QueryContext(ctx, selectQuery, from, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("could not select deployments between %v and %v from DB. Error: %w", from, to, err)
+	}
+	defer closeRowsAndLog(rows, ctx, "DBSelectDeploymentsInTimeWindow")
+
+	result := make([]Deployment, 0)
+	for rows.Next() {
+		row, err := h.processSingleDeploymentRow(ctx, rows)
+		if err != nil {
+			return nil, err
+		}
+		if row != nil {
+			result = append(result, *row)
+		}
+	}
+	return result, rows.Err()
+}
+
+// DBSelectReleasesDeployedInTimeWindow returns the releases of all deployments between from and to,
+// including deleted releases.
+func (h *DBHandler) DBSelectReleasesDeployedInTimeWindow(ctx context.Context, tx *sql.Tx, from, to time.Time) (_ map[ReleaseKey]DeployedRelease, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectReleasesDeployedInTimeWindow")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	if tx == nil {
+		return nil, fmt.Errorf("DBSelectReleasesDeployedInTimeWindow: no transaction provided")
+	}
+	selectQuery := h.AdaptQuery(`
+		SELECT r.appName, r.releaseVersion, r.revision, r.created, r.metadata
+		FROM ` + releasesHistoryTable + ` AS r
+		JOIN (
+			SELECT DISTINCT appName, releaseVersion, revision
+			FROM ` + deploymentsHistoryTable + `
+			WHERE releaseVersion IS NOT NULL AND created >= ? AND created <= ?
+		) AS d
+		ON r.appName = d.appName AND r.releaseVersion = d.releaseVersion AND r.revision = d.revision
+		ORDER BY r.version;
+	`)
+	span.SetTag("query", selectQuery)
+	rows, err := tx.QueryContext(ctx, selectQuery, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("could not select the releases deployed between %v and %v from DB. Error: %w", from, to, err)
+	}
+	defer closeRowsAndLog(rows, ctx, "DBSelectReleasesDeployedInTimeWindow")
+
+	result := map[ReleaseKey]DeployedRelease{}
+	for rows.Next() {
+		//exhaustruct:ignore
+		var key = ReleaseKey{}
+		var created time.Time
+		var metadataStr string
+		if err := rows.Scan(&key.AppName, &key.ReleaseVersion, &key.Revision, &created, &metadataStr); err != nil {
+			return nil, fmt.Errorf("error scanning releases row from DB. Error: %w", err)
+		}
+		if _, ok := result[key]; ok {
+			// only the first version of a release counts
+			continue
+		}
+		//exhaustruct:ignore
+		var metadata = DBReleaseMetaData{}
+		if err := json.Unmarshal([]byte(metadataStr), &metadata); err != nil {
+			return nil, fmt.Errorf("error during json unmarshal of metadata for releases. Error: %w. Data: %s", err, metadataStr)
+		}
+		result[key] = DeployedRelease{
+			Created:         created,
+			UndeployVersion: metadata.UndeployVersion,
+		}
+	}
+	return result, rows.Err()
+}
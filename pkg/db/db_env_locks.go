@@ -132,6 +132,44 @@ func (h *DBHandler) DBSelectAllEnvLocksOfAllEnvs(ctx context.Context, tx *sql.Tx
 	return envLocks, nil
 }
 
+// DBSelectAllEnvLocksOfAllEnvsAtTimestamp returns the environment locks that existed at the given time, grouped by environment.
+func (h *DBHandler) DBSelectAllEnvLocksOfAllEnvsAtTimestamp(ctx context.Context, tx *sql.Tx, ts time.Time) (_ map[types.EnvName][]EnvironmentLock, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectAllEnvLocksOfAllEnvsAtTimestamp")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	if h == nil {
+		return nil, nil
+	}
+	if tx == nil {
+		return nil, fmt.Errorf("DBSelectAllEnvLocksOfAllEnvsAtTimestamp: no transaction provided")
+	}
+
+	selectQuery := h.AdaptQuery(`
+		SELECT h.created, h.lockId, h.envName, h.metadata
+		FROM (
+			SELECT MAX(version) AS latest, envName, lockId
+			FROM ` + envLocksHistoryTable + `
+			WHERE created <= (?)
+			GROUP BY envName, lockId
+		) AS latest
+		JOIN ` + envLocksHistoryTable + ` AS h
+		ON latest.latest = h.version
+		WHERE h.deleted = false
+		ORDER BY h.lockId, h.envName;`)
+	span.SetTag("query", selectQuery)
+	rows, err := tx.QueryContext(ctx, selectQuery, ts)
+	locks, err := h.processEnvLockRows(ctx, err, rows)
+	if err != nil {
+		return nil, err
+	}
+	envLocks := make(map[types.EnvName][]EnvironmentLock)
+	for _, lock := range locks {
+		envLocks[lock.Env] = append(envLocks[lock.Env], lock)
+	}
+	return envLocks, nil
+}
+
 func (h *DBHandler) DBHasAnyActiveEnvLock(ctx context.Context, tx *sql.Tx) (bool, error) {
 	selectQuery := h.AdaptQuery(`
 		SELECT created, lockid, envname, metadata
@@ -306,6 +344,7 @@ func (h *DBHandler) DBSelectEnvLockHistory(ctx context.Context, tx *sql.Tx, envi
 			DeletionMetadata: LockDeletionMetadata{
 				DeletedByEmail: "",
 				DeletedByUser:  "",
+				Reason:         "",
 			},
 		}
 		var metadata string
@@ -358,7 +397,7 @@ func (h *DBHandler) DBWriteEnvironmentLock(ctx context.Context, tx *sql.Tx, lock
 	if err != nil {
 		return err
 	}
-	err = h.insertEnvLockHistoryRow(ctx, tx, lockID, environment, metadata, false, LockDeletionMetadata{DeletedByEmail: "", DeletedByUser: ""}) //empty deletion metadata on insert
+	err = h.insertEnvLockHistoryRow(ctx, tx, lockID, environment, metadata, false, LockDeletionMetadata{DeletedByEmail: "", DeletedByUser: "", Reason: ""}) //empty deletion metadata on insert
 	if err != nil {
 		return err
 	}
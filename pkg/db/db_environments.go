@@ -211,6 +211,45 @@ func (h *DBHandler) DBSelectAllLatestEnvironmentsAtTimestamp(ctx context.Context
 	return result, nil
 }
 
+// DBSelectAllActiveEnvironmentsAtTimestamp returns the environments as they were at the given time.
+// Unlike DBSelectAllLatestEnvironmentsAtTimestamp, environments that were deleted before ts are not returned.
+func (h *DBHandler) DBSelectAllActiveEnvironmentsAtTimestamp(ctx context.Context, tx *sql.Tx, ts time.Time) (_ *[]DBEnvironment, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectAllActiveEnvironmentsAtTimestamp")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	selectQuery := h.AdaptQuery(`
+	SELECT
+	    ` + environmentsHistoryTable + `.created,
+		` + environmentsHistoryTable + `.name,
+		` + environmentsHistoryTable + `.json,
+		` + environmentsHistoryTable + `.applications
+	FROM (
+	SELECT
+		MAX(version) AS latest,
+		name
+	FROM
+		` + environmentsHistoryTable + `
+	WHERE created <= (?)
+	GROUP BY
+		name
+	) AS latest
+	JOIN
+		` + environmentsHistoryTable + ` AS ` + environmentsHistoryTable + `
+	ON
+		latest.latest=` + environmentsHistoryTable + `.version
+		AND latest.name=` + environmentsHistoryTable + `.name
+	WHERE ` + environmentsHistoryTable + `.deleted = false
+	ORDER BY ` + environmentsHistoryTable + `.name;
+`)
+	span.SetTag("query", selectQuery)
+	rows, err := tx.QueryContext(ctx, selectQuery, ts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query for environments at %v, error: %w", ts, err)
+	}
+	return processEnvironmentRows(ctx, rows)
+}
+
 func (h *DBHandler) DBSelectAllEnvironments(ctx context.Context, transaction *sql.Tx) (_ []types.EnvName, err error) {
 	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectAllEnvironments")
 	defer func() {
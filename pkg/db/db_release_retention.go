@@ -0,0 +1,428 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	"github.com/freiheit-com/kuberpult/pkg/types"
+)
+
+/*
+release_retention_policies overrides the global release versions limit of the cleanup
+of old releases, either for one application or for all applications of a team.
+
+Both the cd-service and the manifest-repo-export-service read the policy of an application
+when they look for old releases, so that both delete the same releases.
+An application policy takes precedence over the policy of its team, they are not merged.
+
+release_retention_policies_history has one row per change. The manifest-repo-export-service
+processes the events later than the cd-service, so it reads the policy that was in effect
+at the event from the history, see DBSelectReleaseRetentionPolicyForAppAt.
+*/
+const (
+	releaseRetentionPoliciesTable        = "release_retention_policies"
+	releaseRetentionPoliciesHistoryTable = "release_retention_policies_history"
+)
+
+type ReleaseRetentionScope string
+
+const (
+	ReleaseRetentionScopeApplication ReleaseRetentionScope = "application"
+	ReleaseRetentionScopeTeam        ReleaseRetentionScope = "team"
+)
+
+type ReleaseRetentionPolicy struct {
+	Scope ReleaseRetentionScope
+	Name  string // the application or team name
+	// KeepLast is the number of releases that are kept before the oldest deployed release.
+	// 0 means the global release versions limit applies.
+	KeepLast uint64
+	// KeepDays keeps all releases that are younger than this number of days. 0 disables it.
+	KeepDays uint64
+	// KeepProdDeployed keeps all releases that were ever deployed to an environment with priority "prod".
+	KeepProdDeployed bool
+	Created          time.Time
+	EslVersion       TransformerID
+}
+
+// ReleaseVersionsLimit returns the number of releases to keep before the oldest deployed release.
+// A nil policy keeps the default.
+func (p *ReleaseRetentionPolicy) ReleaseVersionsLimit(defaultLimit uint) uint {
+	if p == nil || p.KeepLast == 0 {
+		return defaultLimit
+	}
+	return uint(p.KeepLast)
+}
+
+// INSERTS
+
+func (h *DBHandler) DBUpsertReleaseRetentionPolicy(ctx context.Context, tx *sql.Tx, policy ReleaseRetentionPolicy) (err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBUpsertReleaseRetentionPolicy")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	upsertQuery := h.AdaptQuery(`
+		INSERT INTO ` + releaseRetentionPoliciesTable + ` (scope, name, keep_last, keep_days, keep_prod_deployed, created, eslversion)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(scope, name)
+		DO UPDATE SET keep_last = excluded.keep_last, keep_days = excluded.keep_days, keep_prod_deployed = excluded.keep_prod_deployed,
+		              created = excluded.created, eslversion = excluded.eslversion;
+	`)
+	span.SetTag("query", upsertQuery)
+	_, err = tx.ExecContext(ctx, upsertQuery,
+		policy.Scope,
+		policy.Name,
+		policy.KeepLast,
+		policy.KeepDays,
+		policy.KeepProdDeployed,
+		policy.Created,
+		policy.EslVersion,
+	)
+	if err != nil {
+		return fmt.Errorf("could not upsert release retention policy of %s %s: %w", policy.Scope, policy.Name, err)
+	}
+	return h.dbInsertReleaseRetentionPolicyHistory(ctx, tx, policy, false)
+}
+
+func (h *DBHandler) dbInsertReleaseRetentionPolicyHistory(ctx context.Context, tx *sql.Tx, policy ReleaseRetentionPolicy, deleted bool) (err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "dbInsertReleaseRetentionPolicyHistory")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	insertQuery := h.AdaptQuery(`
+		INSERT INTO ` + releaseRetentionPoliciesHistoryTable + ` (scope, name, keep_last, keep_days, keep_prod_deployed, deleted, created, eslversion)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?);
+	`)
+	span.SetTag("query", insertQuery)
+	_, err = tx.ExecContext(ctx, insertQuery,
+		policy.Scope,
+		policy.Name,
+		policy.KeepLast,
+		policy.KeepDays,
+		policy.KeepProdDeployed,
+		deleted,
+		policy.Created,
+		policy.EslVersion,
+	)
+	if err != nil {
+		return fmt.Errorf("could not write release retention policy history of %s %s: %w", policy.Scope, policy.Name, err)
+	}
+	return nil
+}
+
+// DELETES
+
+// DBDeleteReleaseRetentionPolicy deletes the policy and returns false if it did not exist.
+// The deletion is recorded in the history with the given time and event.
+func (h *DBHandler) DBDeleteReleaseRetentionPolicy(ctx context.Context, tx *sql.Tx, scope ReleaseRetentionScope, name string, deletedAt time.Time, eslVersion TransformerID) (_ bool, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBDeleteReleaseRetentionPolicy")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	deleteQuery := h.AdaptQuery(`
+		DELETE FROM ` + releaseRetentionPoliciesTable + `
+		WHERE scope = ? AND name = ?;
+	`)
+	span.SetTag("query", deleteQuery)
+	result, err := tx.ExecContext(ctx, deleteQuery, scope, name)
+	if err != nil {
+		return false, fmt.Errorf("could not delete release retention policy of %s %s: %w", scope, name, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("could not read affected rows of release retention policy of %s %s: %w", scope, name, err)
+	}
+	if affected != 1 {
+		return false, nil
+	}
+	err = h.dbInsertReleaseRetentionPolicyHistory(ctx, tx, ReleaseRetentionPolicy{
+		Scope:            scope,
+		Name:             name,
+		KeepLast:         0,
+		KeepDays:         0,
+		KeepProdDeployed: false,
+		Created:          deletedAt,
+		EslVersion:       eslVersion,
+	}, true)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SELECTS
+
+const selectReleaseRetentionPolicyColumns = `scope, name, keep_last, keep_days, keep_prod_deployed, created, eslversion`
+
+// DBSelectAllReleaseRetentionPolicies returns all policies, sorted by scope and name.
+func (h *DBHandler) DBSelectAllReleaseRetentionPolicies(ctx context.Context, tx *sql.Tx) (_ []*ReleaseRetentionPolicy, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectAllReleaseRetentionPolicies")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	selectQuery := h.AdaptQuery(`
+		SELECT ` + selectReleaseRetentionPolicyColumns + `
+		FROM ` + releaseRetentionPoliciesTable + `
+		ORDER BY scope ASC, name ASC;
+	`)
+	span.SetTag("query", selectQuery)
+	rows, err := tx.QueryContext(ctx, selectQuery)
+	if err != nil {
+		return nil, fmt.Errorf("could not query release retention policies: %w", err)
+	}
+	defer closeRowsAndLog(rows, ctx, "DBSelectAllReleaseRetentionPolicies")
+	return processReleaseRetentionPolicies(rows)
+}
+
+// DBSelectReleaseRetentionPolicy returns the policy, or nil if it does not exist.
+func (h *DBHandler) DBSelectReleaseRetentionPolicy(ctx context.Context, tx *sql.Tx, scope ReleaseRetentionScope, name string) (_ *ReleaseRetentionPolicy, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectReleaseRetentionPolicy")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	selectQuery := h.AdaptQuery(`
+		SELECT ` + selectReleaseRetentionPolicyColumns + `
+		FROM ` + releaseRetentionPoliciesTable + `
+		WHERE scope = ? AND name = ?
+		LIMIT 1;
+	`)
+	span.SetTag("query", selectQuery)
+	rows, err := tx.QueryContext(ctx, selectQuery, scope, name)
+	if err != nil {
+		return nil, fmt.Errorf("could not query release retention policy of %s %s: %w", scope, name, err)
+	}
+	defer closeRowsAndLog(rows, ctx, "DBSelectReleaseRetentionPolicy")
+	policies, err := processReleaseRetentionPolicies(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(policies) == 0 {
+		return nil, nil
+	}
+	return policies[0], nil
+}
+
+// DBSelectReleaseRetentionPolicyForApp returns the policy of the application, or else the policy of its team.
+// It returns nil if neither exists.
+func (h *DBHandler) DBSelectReleaseRetentionPolicyForApp(ctx context.Context, tx *sql.Tx, appName types.AppName) (*ReleaseRetentionPolicy, error) {
+	return h.dbSelectReleaseRetentionPolicyForApp(ctx, tx, appName, func(scope ReleaseRetentionScope, name string) (*ReleaseRetentionPolicy, error) {
+		return h.DBSelectReleaseRetentionPolicy(ctx, tx, scope, name)
+	})
+}
+
+// DBSelectReleaseRetentionPolicyForAppAt is DBSelectReleaseRetentionPolicyForApp as it was right after the event eslVersion.
+func (h *DBHandler) DBSelectReleaseRetentionPolicyForAppAt(ctx context.Context, tx *sql.Tx, appName types.AppName, eslVersion TransformerID) (*ReleaseRetentionPolicy, error) {
+	return h.dbSelectReleaseRetentionPolicyForApp(ctx, tx, appName, func(scope ReleaseRetentionScope, name string) (*ReleaseRetentionPolicy, error) {
+		return h.DBSelectReleaseRetentionPolicyAt(ctx, tx, scope, name, eslVersion)
+	})
+}
+
+func (h *DBHandler) dbSelectReleaseRetentionPolicyForApp(ctx context.Context, tx *sql.Tx, appName types.AppName, selectPolicy func(scope ReleaseRetentionScope, name string) (*ReleaseRetentionPolicy, error)) (*ReleaseRetentionPolicy, error) {
+	policy, err := selectPolicy(ReleaseRetentionScopeApplication, string(appName))
+	if err != nil || policy != nil {
+		return policy, err
+	}
+	app, err := h.DBSelectApp(ctx, tx, appName)
+	if err != nil {
+		return nil, fmt.Errorf("could not get team of app %s: %w", appName, err)
+	}
+	if app == nil || app.Metadata.Team == "" {
+		return nil, nil
+	}
+	return selectPolicy(ReleaseRetentionScopeTeam, app.Metadata.Team)
+}
+
+// DBSelectReleaseRetentionPolicyAt returns the policy as it was right after the event eslVersion, or nil if it did not exist then.
+func (h *DBHandler) DBSelectReleaseRetentionPolicyAt(ctx context.Context, tx *sql.Tx, scope ReleaseRetentionScope, name string, eslVersion TransformerID) (_ *ReleaseRetentionPolicy, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectReleaseRetentionPolicyAt")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	selectQuery := h.AdaptQuery(`
+		SELECT ` + selectReleaseRetentionPolicyColumns + `, deleted
+		FROM ` + releaseRetentionPoliciesHistoryTable + `
+		WHERE scope = ? AND name = ? AND eslversion <= ?
+		ORDER BY eslversion DESC
+		LIMIT 1;
+	`)
+	span.SetTag("query", selectQuery)
+	var row ReleaseRetentionPolicy
+	var deleted bool
+	err = tx.QueryRowContext(ctx, selectQuery, scope, name, eslVersion).Scan(
+		&row.Scope,
+		&row.Name,
+		&row.KeepLast,
+		&row.KeepDays,
+		&row.KeepProdDeployed,
+		&row.Created,
+		&row.EslVersion,
+		&deleted,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not query release retention policy history of %s %s: %w", scope, name, err)
+	}
+	if deleted {
+		return nil, nil
+	}
+	return &row, nil
+}
+
+// DBSelectReleasesEverDeployedToEnvironments returns all releases of the app that were deployed to any of the environments at some point.
+func (h *DBHandler) DBSelectReleasesEverDeployedToEnvironments(ctx context.Context, tx *sql.Tx, app types.AppName, envs []types.EnvName) (_ []types.ReleaseNumbers, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectReleasesEverDeployedToEnvironments")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	if len(envs) == 0 {
+		return []types.ReleaseNumbers{}, nil
+	}
+	selectQuery := h.AdaptQuery(`
+		SELECT DISTINCT releaseVersion, revision
+		FROM ` + deploymentsHistoryTable + `
+		WHERE appName = ? AND releaseVersion IS NOT NULL AND envName IN (?` + strings.Repeat(",?", len(envs)-1) + `)
+		ORDER BY releaseVersion, revision;
+	`)
+	span.SetTag("query", selectQuery)
+	args := []any{app}
+	for _, env := range envs {
+		args = append(args, env)
+	}
+	rows, err := tx.QueryContext(ctx, selectQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("could not query deployed releases of app %s: %w", app, err)
+	}
+	defer closeRowsAndLog(rows, ctx, "DBSelectReleasesEverDeployedToEnvironments")
+	result := make([]types.ReleaseNumbers, 0)
+	for rows.Next() {
+		var version, revision uint64
+		if err := rows.Scan(&version, &revision); err != nil {
+			return nil, fmt.Errorf("could not scan deployments_history row: %w", err)
+		}
+		result = append(result, types.MakeReleaseNumbers(version, revision))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DBFilterReleasesRetainedByPolicy removes the releases from candidates that the policy keeps
+// because of their age or because they were deployed to one of the prodEnvs.
+// The age is measured at the creation of the event that triggered the cleanup,
+// so that the cd-service and the manifest-repo-export-service come to the same result.
+func (h *DBHandler) DBFilterReleasesRetainedByPolicy(ctx context.Context, tx *sql.Tx, app types.AppName, policy *ReleaseRetentionPolicy, prodEnvs []types.EnvName, candidates []types.ReleaseNumbers, eslVersion TransformerID) (_ []types.ReleaseNumbers, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBFilterReleasesRetainedByPolicy")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	if policy == nil || len(candidates) == 0 || (policy.KeepDays == 0 && !policy.KeepProdDeployed) {
+		return candidates, nil
+	}
+	retained := map[string]bool{}
+	if policy.KeepDays > 0 {
+		versions := make([]uint64, 0, len(candidates))
+		for _, candidate := range candidates {
+			if candidate.Version != nil {
+				versions = append(versions, *candidate.Version)
+			}
+		}
+		releases, err := h.DBSelectReleasesByVersionsAndRevision(ctx, tx, app, versions, false)
+		if err != nil {
+			return nil, err
+		}
+		now, err := h.dbSelectEslEventCreated(ctx, tx, eslVersion)
+		if err != nil {
+			return nil, err
+		}
+		cutoff := now.Add(-time.Duration(policy.KeepDays) * 24 * time.Hour)
+		for _, release := range releases {
+			if release.Created.After(cutoff) {
+				retained[release.ReleaseNumbers.String()] = true
+			}
+		}
+	}
+	if policy.KeepProdDeployed {
+		deployed, err := h.DBSelectReleasesEverDeployedToEnvironments(ctx, tx, app, prodEnvs)
+		if err != nil {
+			return nil, err
+		}
+		for _, release := range deployed {
+			retained[release.String()] = true
+		}
+	}
+	span.SetTag("numOfRetainedReleases", len(retained))
+	result := make([]types.ReleaseNumbers, 0, len(candidates))
+	for _, candidate := range candidates {
+		if !retained[candidate.String()] {
+			result = append(result, candidate)
+		}
+	}
+	return result, nil
+}
+
+// dbSelectEslEventCreated returns when the event was created, or the transaction timestamp if the event does not exist.
+func (h *DBHandler) dbSelectEslEventCreated(ctx context.Context, tx *sql.Tx, eslVersion TransformerID) (time.Time, error) {
+	selectQuery := h.AdaptQuery("SELECT created FROM " + eslTable + " WHERE eslVersion = ? LIMIT 1;")
+	var created time.Time
+	err := tx.QueryRowContext(ctx, selectQuery, eslVersion).Scan(&created)
+	if err == nil {
+		return created, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, fmt.Errorf("could not read creation time of event %d: %w", eslVersion, err)
+	}
+	now, err := h.DBReadTransactionTimestamp(ctx, tx)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return *now, nil
+}
+
+func processReleaseRetentionPolicies(rows *sql.Rows) ([]*ReleaseRetentionPolicy, error) {
+	result := make([]*ReleaseRetentionPolicy, 0)
+	for rows.Next() {
+		var row ReleaseRetentionPolicy
+		err := rows.Scan(
+			&row.Scope,
+			&row.Name,
+			&row.KeepLast,
+			&row.KeepDays,
+			&row.KeepProdDeployed,
+			&row.Created,
+			&row.EslVersion,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan release_retention_policies row: %w", err)
+		}
+		result = append(result, &row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,81 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSelectReleaseRetentionPolicyAt(t *testing.T) {
+	ctx := context.Background()
+	dbHandler := setupDB(t)
+	created := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	policy := func(keepLast uint64, eslVersion TransformerID) ReleaseRetentionPolicy {
+		return ReleaseRetentionPolicy{
+			Scope:            ReleaseRetentionScopeApplication,
+			Name:             "app1",
+			KeepLast:         keepLast,
+			KeepDays:         0,
+			KeepProdDeployed: false,
+			Created:          created,
+			EslVersion:       eslVersion,
+		}
+	}
+	err := dbHandler.WithTransaction(ctx, false, func(ctx context.Context, transaction *sql.Tx) error {
+		if err := dbHandler.DBUpsertReleaseRetentionPolicy(ctx, transaction, policy(5, 2)); err != nil {
+			return err
+		}
+		if err := dbHandler.DBUpsertReleaseRetentionPolicy(ctx, transaction, policy(10, 4)); err != nil {
+			return err
+		}
+		deleted, err := dbHandler.DBDeleteReleaseRetentionPolicy(ctx, transaction, ReleaseRetentionScopeApplication, "app1", created, 6)
+		if err != nil {
+			return err
+		}
+		if !deleted {
+			t.Fatalf("expected the policy to be deleted")
+		}
+		p2, p4 := policy(5, 2), policy(10, 4)
+		expected := map[TransformerID]*ReleaseRetentionPolicy{
+			1: nil,
+			2: &p2,
+			3: &p2,
+			4: &p4,
+			5: &p4,
+			6: nil,
+			7: nil,
+		}
+		for eslVersion, expectedPolicy := range expected {
+			actual, err := dbHandler.DBSelectReleaseRetentionPolicyAt(ctx, transaction, ReleaseRetentionScopeApplication, "app1", eslVersion)
+			if err != nil {
+				return err
+			}
+			if diff := cmp.Diff(expectedPolicy, actual); diff != "" {
+				t.Errorf("policy after event %d mismatch (-want, +got):\n%s", eslVersion, diff)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("transaction error: %v", err)
+	}
+}
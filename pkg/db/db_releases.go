@@ -43,6 +43,22 @@ type DBReleaseMetaData struct {
 	IsMinor         bool
 	CiLink          string
 	IsPrepublish    bool
+	Dependencies    []ReleaseDependency `json:",omitempty"`
+	Signatures      []ReleaseSignature  `json:",omitempty"`
+}
+
+// ReleaseDependency requires that an application is deployed in at least MinVersion
+// in an environment before a release that depends on it can be deployed there.
+type ReleaseDependency struct {
+	Application types.AppName
+	MinVersion  uint64
+}
+
+// ReleaseSignature is a detached signature over the manifest of a release for one environment.
+type ReleaseSignature struct {
+	Environment types.EnvName
+	Type        string
+	Signature   string
 }
 
 type DBReleaseManifests struct {
@@ -218,6 +234,8 @@ func (h *DBHandler) DBSelectReleaseByVersionAtTimestamp(ctx context.Context, tx
 			IsMinor:         false,
 			CiLink:          "",
 			IsPrepublish:    false,
+			Dependencies:    nil,
+			Signatures:      nil,
 		}
 		err = json.Unmarshal(([]byte)(metadataStr), &metaData)
 		if err != nil {
@@ -349,6 +367,31 @@ func (h *DBHandler) DBSelectLatestReleasesSinceVersion(ctx context.Context, tx *
 	return h.processReleaseRows(ctx, err, rows, ignorePrepublishes, true)
 }
 
+// DBSelectReleasesBetweenVersions returns the releases of an app with from < version <= to, newest first.
+// It does not load the manifests.
+func (h *DBHandler) DBSelectReleasesBetweenVersions(ctx context.Context, tx *sql.Tx, app types.AppName, from, to uint64, ignorePrepublishes bool) (_ []*DBReleaseWithMetaData, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectReleasesBetweenVersions")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	selectQuery := h.AdaptQuery(`
+		SELECT created, appName, metadata, releaseVersion, environments, revision
+		FROM ` + releasesTable + `
+		WHERE appname = ? AND releaseVersion > ? AND releaseVersion <= ?
+		ORDER BY releaseversion DESC, revision DESC;
+	`)
+	span.SetTag("query", selectQuery)
+	rows, err := tx.QueryContext(
+		ctx,
+		selectQuery,
+		app,
+		from,
+		to,
+	)
+
+	return h.processReleaseRows(ctx, err, rows, ignorePrepublishes, false)
+}
+
 func (h *DBHandler) DBSelectLatestReleaseOfApp(ctx context.Context, tx *sql.Tx, app types.AppName, ignorePrepublishes bool) (_ *DBReleaseWithMetaData, err error) {
 	selectQuery := h.AdaptQuery(`
 		SELECT created, appName, metadata, releaseVersion, environments, revision
@@ -485,6 +528,31 @@ func (h *DBHandler) DBSelectAllReleasesOfAllAppsAtTimestamp(ctx context.Context,
 	return h.processAllAppsReleaseVersionsRows(ctx, err, rows)
 }
 
+// DBSelectReleasesOfAppAtTimestamp returns the releases of the app that existed at the given time, without manifests.
+// Releases that were deleted before ts are not returned.
+func (h *DBHandler) DBSelectReleasesOfAppAtTimestamp(ctx context.Context, tx *sql.Tx, app types.AppName, ts time.Time, ignorePrepublishes bool) (_ []*DBReleaseWithMetaData, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectReleasesOfAppAtTimestamp")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	selectQuery := h.AdaptQuery(`
+		SELECT h.created, h.appName, h.metadata, h.releaseVersion, h.environments, h.revision
+		FROM (
+			SELECT MAX(version) AS latest, releaseVersion, revision
+			FROM ` + releasesHistoryTable + `
+			WHERE appName = (?) AND created <= (?)
+			GROUP BY releaseVersion, revision
+		) AS latest
+		JOIN ` + releasesHistoryTable + ` AS h
+		ON latest.latest = h.version
+		WHERE h.deleted = false
+		ORDER BY h.releaseVersion DESC, h.revision DESC;
+	`)
+	span.SetTag("query", selectQuery)
+	rows, err := tx.QueryContext(ctx, selectQuery, app, ts)
+	return h.processReleaseRows(ctx, err, rows, ignorePrepublishes, false)
+}
+
 func (h *DBHandler) DBSelectAllReleasesOfAllApps(ctx context.Context, tx *sql.Tx) (_ map[types.AppName][]types.ReleaseNumbers, err error) {
 	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectAllReleasesOfAllApps")
 	defer func() {
@@ -817,6 +885,8 @@ func (h *DBHandler) processReleaseRows(ctx context.Context, err error, rows *sql
 			IsMinor:         false,
 			CiLink:          "",
 			IsPrepublish:    false,
+			Dependencies:    nil,
+			Signatures:      nil,
 		}
 		err = json.Unmarshal(([]byte)(metadataStr), &metaData)
 		if err != nil {
@@ -1055,6 +1125,8 @@ func (h *DBHandler) DBSelectCommitHashesTimeWindow(ctx context.Context, transact
 			IsMinor:         false,
 			CiLink:          "",
 			IsPrepublish:    false,
+			Dependencies:    nil,
+			Signatures:      nil,
 		}
 		err = json.Unmarshal(([]byte)(metadataStr), &metaData)
 		if err != nil {
@@ -1153,6 +1225,8 @@ func (h *DBHandler) DBSelectCommitIdAppReleaseVersionsAtTimestamp(ctx context.Co
 			IsMinor:         false,
 			CiLink:          "",
 			IsPrepublish:    false,
+			Dependencies:    nil,
+			Signatures:      nil,
 		}
 		err = json.Unmarshal(([]byte)(metadataStr), &metaData)
 		if err != nil {
@@ -1171,7 +1245,25 @@ func (h *DBHandler) DBSelectCommitIdAppReleaseVersions(ctx context.Context, tran
 	defer func() {
 		span.Finish(tracer.WithError(err))
 	}()
-	result := make(map[types.AppName]string)
+	metadataByApp, err := h.DBSelectMetadataAppReleaseVersions(ctx, transaction, versionByApp)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[types.AppName]string, len(metadataByApp))
+	for appName, metaData := range metadataByApp {
+		result[appName] = metaData.SourceCommitId
+	}
+	return result, nil
+}
+
+// DBSelectMetadataAppReleaseVersions returns the metadata of one release per app.
+// Apps whose release does not exist are missing in the result.
+func (h *DBHandler) DBSelectMetadataAppReleaseVersions(ctx context.Context, transaction *sql.Tx, versionByApp map[types.AppName]types.ReleaseNumbers) (_ map[types.AppName]DBReleaseMetaData, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectMetadataAppReleaseVersions")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	result := make(map[types.AppName]DBReleaseMetaData)
 	if len(versionByApp) < 1 {
 		return result, nil
 	}
@@ -1240,12 +1332,14 @@ func (h *DBHandler) DBSelectCommitIdAppReleaseVersions(ctx context.Context, tran
 			IsMinor:         false,
 			CiLink:          "",
 			IsPrepublish:    false,
+			Dependencies:    nil,
+			Signatures:      nil,
 		}
 		err = json.Unmarshal(([]byte)(metadataStr), &metaData)
 		if err != nil {
 			return nil, fmt.Errorf("error during json unmarshal of metadata for releases. Error: %w. Data: %s", err, metadataStr)
 		}
-		result[appName] = metaData.SourceCommitId
+		result[appName] = metaData
 	}
 	if err = metadataRows.Err(); err != nil {
 		return nil, err
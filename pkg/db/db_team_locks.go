@@ -211,6 +211,78 @@ func (h *DBHandler) DBSelectAllActiveTeamLocksForTeam(ctx context.Context, tx *s
 	return h.processTeamLockRows(ctx, err, rows)
 }
 
+// DBSelectAllActiveTeamLocksForTeamAtTimestamp returns the team locks of the team that existed at the given time.
+func (h *DBHandler) DBSelectAllActiveTeamLocksForTeamAtTimestamp(ctx context.Context, tx *sql.Tx, teamName string, ts time.Time) (_ []TeamLock, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectAllActiveTeamLocksForTeamAtTimestamp")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+
+	if h == nil {
+		return nil, nil
+	}
+	if tx == nil {
+		return nil, fmt.Errorf("DBSelectAllActiveTeamLocksForTeamAtTimestamp: no transaction provided")
+	}
+
+	selectQuery := h.AdaptQuery(`
+		SELECT h.created, h.lockId, h.envName, h.teamName, h.metadata
+		FROM (
+			SELECT MAX(version) AS latest, envName, lockId
+			FROM ` + teamLocksHistoryTable + `
+			WHERE teamName = (?) AND created <= (?)
+			GROUP BY envName, lockId
+		) AS latest
+		JOIN ` + teamLocksHistoryTable + ` AS h
+		ON latest.latest = h.version
+		WHERE h.deleted = false
+		ORDER BY h.lockId, h.envName;`)
+	span.SetTag("query", selectQuery)
+	rows, err := tx.QueryContext(ctx, selectQuery, teamName, ts)
+	return h.processTeamLockRows(ctx, err, rows)
+}
+
+// DBSelectAllTeamLocksOfAllEnvsAtTimestamp returns the team locks that existed at the given time, grouped by environment and team.
+func (h *DBHandler) DBSelectAllTeamLocksOfAllEnvsAtTimestamp(ctx context.Context, tx *sql.Tx, ts time.Time) (_ map[types.EnvName]map[string][]TeamLock, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectAllTeamLocksOfAllEnvsAtTimestamp")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	if h == nil {
+		return nil, nil
+	}
+	if tx == nil {
+		return nil, fmt.Errorf("DBSelectAllTeamLocksOfAllEnvsAtTimestamp: no transaction provided")
+	}
+
+	selectQuery := h.AdaptQuery(`
+		SELECT h.created, h.lockId, h.envName, h.teamName, h.metadata
+		FROM (
+			SELECT MAX(version) AS latest, envName, teamName, lockId
+			FROM ` + teamLocksHistoryTable + `
+			WHERE created <= (?)
+			GROUP BY envName, teamName, lockId
+		) AS latest
+		JOIN ` + teamLocksHistoryTable + ` AS h
+		ON latest.latest = h.version
+		WHERE h.deleted = false
+		ORDER BY h.lockId;`)
+	span.SetTag("query", selectQuery)
+	rows, err := tx.QueryContext(ctx, selectQuery, ts)
+	locks, err := h.processTeamLockRows(ctx, err, rows)
+	if err != nil {
+		return nil, err
+	}
+	teamLocks := make(map[types.EnvName]map[string][]TeamLock)
+	for _, lock := range locks {
+		if _, ok := teamLocks[lock.Env]; !ok {
+			teamLocks[lock.Env] = make(map[string][]TeamLock)
+		}
+		teamLocks[lock.Env][lock.Team] = append(teamLocks[lock.Env][lock.Team], lock)
+	}
+	return teamLocks, nil
+}
+
 func (h *DBHandler) DBSelectTeamLock(ctx context.Context, tx *sql.Tx, environment types.EnvName, teamName, lockID string) (*TeamLock, error) {
 	selectQuery := h.AdaptQuery(`
 		SELECT created, lockID, envName, teamName, metadata
@@ -317,6 +389,7 @@ func (h *DBHandler) DBSelectTeamLockHistory(ctx context.Context, tx *sql.Tx, env
 			DeletionMetadata: LockDeletionMetadata{
 				DeletedByUser:  "",
 				DeletedByEmail: "",
+				Reason:         "",
 			},
 		}
 		var metadata string
@@ -370,7 +443,7 @@ func (h *DBHandler) DBWriteTeamLock(ctx context.Context, tx *sql.Tx, lockID stri
 	if err != nil {
 		return err
 	}
-	err = h.insertTeamLockHistoryRow(ctx, tx, lockID, environment, teamName, metadata, false, LockDeletionMetadata{DeletedByUser: "", DeletedByEmail: ""}) // Empty metadata on insertion
+	err = h.insertTeamLockHistoryRow(ctx, tx, lockID, environment, teamName, metadata, false, LockDeletionMetadata{DeletedByUser: "", DeletedByEmail: "", Reason: ""}) // Empty metadata on insertion
 	if err != nil {
 		return err
 	}
@@ -3013,6 +3013,76 @@ func TestQueueApplicationVersionDelete(t *testing.T) {
 	}
 }
 
+func TestSelectLatestDeploymentAttempt(t *testing.T) {
+	const envName = "dev"
+	const appName = "deployment"
+	tcs := []struct {
+		Name           string
+		Write          bool
+		Delete         bool
+		ExpectedQueued *QueuedDeployment
+	}{
+		{
+			Name:           "never queued",
+			ExpectedQueued: nil,
+		},
+		{
+			Name:  "queued",
+			Write: true,
+			ExpectedQueued: &QueuedDeployment{
+				Env:            envName,
+				App:            appName,
+				ReleaseNumbers: types.MakeReleaseNumberVersion(1),
+			},
+		},
+		{
+			Name:   "deleted queued version is version 0",
+			Write:  true,
+			Delete: true,
+			ExpectedQueued: &QueuedDeployment{
+				Env:            envName,
+				App:            appName,
+				ReleaseNumbers: types.MakeReleaseNumberVersion(0),
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			ctx := testutilauth.MakeTestContext()
+
+			dbHandler := setupDB(t)
+			err := dbHandler.WithTransaction(ctx, false, func(ctx context.Context, transaction *sql.Tx) error {
+				if tc.Write {
+					err := dbHandler.DBWriteDeploymentAttempt(ctx, transaction, envName, appName, types.MakeReleaseNumberVersion(1))
+					if err != nil {
+						return err
+					}
+				}
+				if tc.Delete {
+					err := dbHandler.DBDeleteDeploymentAttempt(ctx, transaction, envName, appName)
+					if err != nil {
+						return err
+					}
+				}
+				actual, err := dbHandler.DBSelectLatestDeploymentAttempt(ctx, transaction, envName, appName)
+				if err != nil {
+					return err
+				}
+				if diff := cmp.Diff(tc.ExpectedQueued, actual, cmpopts.IgnoreFields(QueuedDeployment{}, "Created")); diff != "" {
+					t.Fatalf("queued deployment mismatch (-want, +got):\n%s", diff)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("transaction error: %v", err)
+			}
+		})
+	}
+}
+
 func TestAllQueuedApplicationVersionsOfApp(t *testing.T) {
 	const appName = "foo"
 
@@ -4684,6 +4754,43 @@ func TestReadReleasesByApp(t *testing.T) {
 	}
 }
 
+func TestReadReleasesBetweenVersions(t *testing.T) {
+	ctx := testutilauth.MakeTestContext()
+	dbHandler := setupDB(t)
+	err := dbHandler.WithTransaction(ctx, false, func(ctx context.Context, transaction *sql.Tx) error {
+		for _, release := range []DBReleaseWithMetaData{
+			{ReleaseNumbers: types.MakeReleaseNumberVersion(1), App: "app1", Manifests: DBReleaseManifests{Manifests: map[types.EnvName]string{"dev": "manifest1"}}},
+			{ReleaseNumbers: types.MakeReleaseNumberVersion(2), App: "app1", Manifests: DBReleaseManifests{Manifests: map[types.EnvName]string{"dev": "manifest2"}}},
+			{ReleaseNumbers: types.MakeReleaseNumbers(2, 1), App: "app1", Manifests: DBReleaseManifests{Manifests: map[types.EnvName]string{"dev": "manifest2.1"}}},
+			{ReleaseNumbers: types.MakeReleaseNumberVersion(3), App: "app1", Manifests: DBReleaseManifests{Manifests: map[types.EnvName]string{"dev": "manifest3"}}, Metadata: DBReleaseMetaData{IsPrepublish: true}},
+			{ReleaseNumbers: types.MakeReleaseNumberVersion(4), App: "app1", Manifests: DBReleaseManifests{Manifests: map[types.EnvName]string{"dev": "manifest4"}}},
+			{ReleaseNumbers: types.MakeReleaseNumberVersion(5), App: "app1", Manifests: DBReleaseManifests{Manifests: map[types.EnvName]string{"dev": "manifest5"}}},
+			{ReleaseNumbers: types.MakeReleaseNumberVersion(3), App: "app2", Manifests: DBReleaseManifests{Manifests: map[types.EnvName]string{"dev": "manifest3"}}},
+		} {
+			err := dbHandler.DBUpdateOrCreateRelease(ctx, transaction, release)
+			if err != nil {
+				return fmt.Errorf("error while writing release, error: %w", err)
+			}
+		}
+		releases, err := dbHandler.DBSelectReleasesBetweenVersions(ctx, transaction, "app1", 1, 4, true)
+		if err != nil {
+			return fmt.Errorf("error while selecting releases, error: %w", err)
+		}
+		expected := []*DBReleaseWithMetaData{
+			{ReleaseNumbers: types.MakeReleaseNumberVersion(4), App: "app1", Manifests: DBReleaseManifests{Manifests: map[types.EnvName]string{}}, Environments: []types.EnvName{"dev"}},
+			{ReleaseNumbers: types.MakeReleaseNumbers(2, 1), App: "app1", Manifests: DBReleaseManifests{Manifests: map[types.EnvName]string{}}, Environments: []types.EnvName{"dev"}},
+			{ReleaseNumbers: types.MakeReleaseNumberVersion(2), App: "app1", Manifests: DBReleaseManifests{Manifests: map[types.EnvName]string{}}, Environments: []types.EnvName{"dev"}},
+		}
+		if diff := cmp.Diff(expected, releases, cmpopts.IgnoreFields(DBReleaseWithMetaData{}, "Created")); diff != "" {
+			return fmt.Errorf("releases mismatch (-want +got):\n%s", diff)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("error while running the transaction, error: %v", err)
+	}
+}
+
 func TestReadReleasesByVersion(t *testing.T) {
 
 	tcs := []struct {
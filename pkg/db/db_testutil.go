@@ -18,7 +18,6 @@ package db
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
 	"os"
@@ -28,7 +27,6 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/freiheit-com/kuberpult/pkg/logging"
-	"github.com/freiheit-com/kuberpult/pkg/types"
 )
 
 // simpleHash is a basic hash function for strings
@@ -113,40 +111,3 @@ func ConnectToPostgresContainer(ctx context.Context, t testing.TB, migrationsPat
 
 	return dbConfig, nil
 }
-
-func (h *DBHandler) DBSelectLatestDeploymentAttempt(ctx context.Context, tx *sql.Tx, environmentName types.EnvName, appName types.AppName) (*QueuedDeployment, error) {
-	query := h.AdaptQuery("SELECT created, envName, appName, releaseVersion, revision FROM deployment_attempts_latest WHERE envName=? AND appName=?;")
-
-	rows, err := tx.QueryContext(
-		ctx,
-		query,
-		environmentName,
-		appName)
-
-	if err != nil {
-		return nil, fmt.Errorf("could not query deployment attempts table from DB. Error: %w", err)
-	}
-	defer func(rows *sql.Rows) {
-		err := rows.Close()
-		if err != nil {
-			logging.Error(ctx, "row closing error.", zap.Error(err))
-		}
-	}(rows)
-	if rows.Next() {
-		//exhaustruct:ignore
-		var deployment = QueuedDeployment{}
-		var releaseVersion sql.NullInt64
-
-		err = rows.Scan(&deployment.Created, &deployment.Env, &deployment.App, &releaseVersion, &deployment.ReleaseNumbers.Revision)
-		if err != nil {
-			return nil, fmt.Errorf("error scanning deployment attempts row from DB. Error: %w", err)
-		}
-		conv := uint64(releaseVersion.Int64)
-		deployment.ReleaseNumbers.Version = &conv
-		return &deployment, nil
-	}
-	if err = rows.Err(); err != nil {
-		return nil, err
-	}
-	return nil, nil
-}
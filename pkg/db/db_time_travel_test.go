@@ -0,0 +1,204 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/freiheit-com/kuberpult/pkg/testutil"
+	"github.com/freiheit-com/kuberpult/pkg/types"
+)
+
+func TestSelectStateAtTimestamp(t *testing.T) {
+	ctx := context.Background()
+	dbHandler := setupDB(t)
+	version := uint64(1)
+	release := DBReleaseWithMetaData{
+		App:            "app1",
+		ReleaseNumbers: types.ReleaseNumbers{Revision: 0, Version: &version},
+		Manifests:      DBReleaseManifests{Manifests: map[types.EnvName]string{"dev": "manifest1"}},
+		Environments:   []types.EnvName{"dev"},
+	}
+	lockMetadata := LockMetadata{CreatedByName: "user", CreatedByEmail: "u@example.com", Message: "incident"}
+
+	// step 1: environment, release, deployment and locks exist
+	var before *time.Time
+	err := dbHandler.WithTransaction(ctx, false, func(ctx context.Context, tx *sql.Tx) error {
+		if err := dbHandler.DBWriteMigrationsTransformer(ctx, tx); err != nil {
+			return err
+		}
+		if err := dbHandler.DBWriteEnvironment(ctx, tx, "dev", testutil.MakeEnvConfigLatest(nil)); err != nil {
+			return err
+		}
+		if err := dbHandler.DBUpdateOrCreateRelease(ctx, tx, release); err != nil {
+			return err
+		}
+		//exhaustruct:ignore
+		if err := dbHandler.DBUpdateOrCreateDeployment(ctx, tx, Deployment{App: "app1", Env: "dev", ReleaseNumbers: release.ReleaseNumbers}); err != nil {
+			return err
+		}
+		if err := dbHandler.DBWriteApplicationLock(ctx, tx, "l1", "dev", "app1", lockMetadata); err != nil {
+			return err
+		}
+		if err := dbHandler.DBWriteTeamLock(ctx, tx, "l2", "dev", "team1", lockMetadata); err != nil {
+			return err
+		}
+		if err := dbHandler.DBWriteEnvironmentLock(ctx, tx, "l3", "dev", lockMetadata); err != nil {
+			return err
+		}
+		queuedVersion := uint64(2)
+		if err := dbHandler.DBWriteDeploymentAttempt(ctx, tx, "dev", "app1", types.ReleaseNumbers{Revision: 0, Version: &queuedVersion}); err != nil {
+			return err
+		}
+		var err error
+		before, err = dbHandler.DBReadTransactionTimestamp(ctx, tx)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("error writing initial state: %v", err)
+	}
+
+	// step 2: everything is removed again
+	var after *time.Time
+	err = dbHandler.WithTransaction(ctx, false, func(ctx context.Context, tx *sql.Tx) error {
+		//exhaustruct:ignore
+		if err := dbHandler.DBUpdateOrCreateDeployment(ctx, tx, Deployment{App: "app1", Env: "dev", ReleaseNumbers: types.ReleaseNumbers{Revision: 0, Version: nil}}); err != nil {
+			return err
+		}
+		if err := dbHandler.DBDeleteFromReleases(ctx, tx, "app1", release.ReleaseNumbers); err != nil {
+			return err
+		}
+		//exhaustruct:ignore
+		if err := dbHandler.DBDeleteApplicationLock(ctx, tx, "dev", "app1", "l1", LockDeletionMetadata{}); err != nil {
+			return err
+		}
+		//exhaustruct:ignore
+		if err := dbHandler.DBDeleteTeamLock(ctx, tx, "dev", "team1", "l2", LockDeletionMetadata{}); err != nil {
+			return err
+		}
+		//exhaustruct:ignore
+		if err := dbHandler.DBDeleteEnvironmentLock(ctx, tx, "dev", "l3", LockDeletionMetadata{}); err != nil {
+			return err
+		}
+		if err := dbHandler.DBDeleteDeploymentAttempt(ctx, tx, "dev", "app1"); err != nil {
+			return err
+		}
+		if err := dbHandler.DBDeleteEnvironment(ctx, tx, "dev"); err != nil {
+			return err
+		}
+		var err error
+		after, err = dbHandler.DBReadTransactionTimestamp(ctx, tx)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("error removing state: %v", err)
+	}
+
+	err = dbHandler.WithTransaction(ctx, true, func(ctx context.Context, tx *sql.Tx) error {
+		tcs := []struct {
+			Name                string
+			Timestamp           time.Time
+			ExpectedEnvs        int
+			ExpectedReleases    int
+			ExpectedDeployments int
+			ExpectedAppLocks    int
+			ExpectedTeamLocks   int
+			ExpectedEnvLocks    int
+			ExpectedQueued      int
+		}{
+			{Name: "before the removal", Timestamp: *before, ExpectedEnvs: 1, ExpectedReleases: 1, ExpectedDeployments: 1, ExpectedAppLocks: 1, ExpectedTeamLocks: 1, ExpectedEnvLocks: 1, ExpectedQueued: 1},
+			{Name: "after the removal", Timestamp: *after, ExpectedEnvs: 0, ExpectedReleases: 0, ExpectedDeployments: 0, ExpectedAppLocks: 0, ExpectedTeamLocks: 0, ExpectedEnvLocks: 0, ExpectedQueued: 0},
+			{Name: "before anything was written", Timestamp: before.Add(-time.Hour), ExpectedEnvs: 0, ExpectedReleases: 0, ExpectedDeployments: 0, ExpectedAppLocks: 0, ExpectedTeamLocks: 0, ExpectedEnvLocks: 0, ExpectedQueued: 0},
+		}
+		for _, tc := range tcs {
+			envs, err := dbHandler.DBSelectAllActiveEnvironmentsAtTimestamp(ctx, tx, tc.Timestamp)
+			if err != nil {
+				return err
+			}
+			if len(*envs) != tc.ExpectedEnvs {
+				t.Errorf("%s: expected %d environments, got %d", tc.Name, tc.ExpectedEnvs, len(*envs))
+			}
+			releases, err := dbHandler.DBSelectReleasesOfAppAtTimestamp(ctx, tx, "app1", tc.Timestamp, false)
+			if err != nil {
+				return err
+			}
+			if len(releases) != tc.ExpectedReleases {
+				t.Errorf("%s: expected %d releases, got %d", tc.Name, tc.ExpectedReleases, len(releases))
+			}
+			deployments, err := dbHandler.DBSelectAllLatestDeploymentsForApplicationAtTimestamp(ctx, tx, "app1", tc.Timestamp)
+			if err != nil {
+				return err
+			}
+			if len(deployments) != tc.ExpectedDeployments {
+				t.Errorf("%s: expected %d deployments, got %d", tc.Name, tc.ExpectedDeployments, len(deployments))
+			}
+			appLocks, err := dbHandler.DBSelectAllActiveAppLocksForAppAtTimestamp(ctx, tx, "app1", tc.Timestamp)
+			if err != nil {
+				return err
+			}
+			if len(appLocks) != tc.ExpectedAppLocks {
+				t.Errorf("%s: expected %d app locks, got %d", tc.Name, tc.ExpectedAppLocks, len(appLocks))
+			}
+			teamLocks, err := dbHandler.DBSelectAllActiveTeamLocksForTeamAtTimestamp(ctx, tx, "team1", tc.Timestamp)
+			if err != nil {
+				return err
+			}
+			if len(teamLocks) != tc.ExpectedTeamLocks {
+				t.Errorf("%s: expected %d team locks, got %d", tc.Name, tc.ExpectedTeamLocks, len(teamLocks))
+			}
+			allTeamLocks, err := dbHandler.DBSelectAllTeamLocksOfAllEnvsAtTimestamp(ctx, tx, tc.Timestamp)
+			if err != nil {
+				return err
+			}
+			if len(allTeamLocks["dev"]["team1"]) != tc.ExpectedTeamLocks {
+				t.Errorf("%s: expected %d team locks of all environments, got %d", tc.Name, tc.ExpectedTeamLocks, len(allTeamLocks["dev"]["team1"]))
+			}
+			envLocks, err := dbHandler.DBSelectAllEnvLocksOfAllEnvsAtTimestamp(ctx, tx, tc.Timestamp)
+			if err != nil {
+				return err
+			}
+			if len(envLocks["dev"]) != tc.ExpectedEnvLocks {
+				t.Errorf("%s: expected %d environment locks, got %d", tc.Name, tc.ExpectedEnvLocks, len(envLocks["dev"]))
+			}
+			queued, err := dbHandler.DBSelectLatestDeploymentAttemptOnAllEnvironmentsAtTimestamp(ctx, tx, "app1", tc.Timestamp)
+			if err != nil {
+				return err
+			}
+			if len(queued) != tc.ExpectedQueued {
+				t.Errorf("%s: expected %d queued versions, got %d", tc.Name, tc.ExpectedQueued, len(queued))
+			}
+		}
+
+		appLocks, err := dbHandler.DBSelectAllActiveAppLocksForAppAtTimestamp(ctx, tx, "app1", *before)
+		if err != nil {
+			return err
+		}
+		expectedLocks := []ApplicationLock{{LockID: "l1", Env: "dev", App: "app1", Metadata: lockMetadata}}
+		if diff := testutil.CmpDiff(expectedLocks, appLocks, cmpopts.IgnoreFields(ApplicationLock{}, "Created")); diff != "" {
+			t.Errorf("app locks mismatch (-want, +got):\n%s", diff)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("error reading state: %v", err)
+	}
+}
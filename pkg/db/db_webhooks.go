@@ -0,0 +1,513 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	"github.com/freiheit-com/kuberpult/pkg/event"
+)
+
+/*
+webhook_subscriptions, webhook_deliveries and webhook_cursor hold the state of the outbound webhooks.
+
+The webhook worker of the cd-service reads new rows of commit_events after the webhook_cursor,
+inserts one delivery per event and matching subscription, and sends the pending deliveries.
+Deliveries are kept after they succeeded or failed, so that they can be inspected and redelivered.
+*/
+const (
+	webhookSubscriptionsTable = "webhook_subscriptions"
+	webhookDeliveriesTable    = "webhook_deliveries"
+	webhookCursorTable        = "webhook_cursor"
+)
+
+// WebhookFilter restricts the events of a subscription. An empty list matches everything.
+type WebhookFilter struct {
+	EventTypes   []event.EventType `json:"eventTypes,omitempty"`
+	Applications []string          `json:"applications,omitempty"`
+	Teams        []string          `json:"teams,omitempty"`
+	Environments []string          `json:"environments,omitempty"`
+}
+
+type WebhookFormat string
+
+const (
+	// WebhookFormatKuberpult sends the kuberpult payload of the webhooks package
+	WebhookFormatKuberpult WebhookFormat = "kuberpult"
+	// WebhookFormatCloudEvents sends structured mode CloudEvents
+	WebhookFormatCloudEvents WebhookFormat = "cloudevents"
+)
+
+type WebhookSubscription struct {
+	Id     int64
+	Url    string
+	Secret string
+	Filter WebhookFilter
+	Format WebhookFormat
+	// Created is also the timestamp of the oldest event that is delivered to the subscription
+	Created        time.Time
+	CreatedByName  string
+	CreatedByEmail string
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryStatusDelivered WebhookDeliveryStatus = "delivered"
+	WebhookDeliveryStatusFailed    WebhookDeliveryStatus = "failed"
+)
+
+type WebhookDelivery struct {
+	Id             int64
+	SubscriptionId int64
+	EventUuid      string
+	EventType      event.EventType
+	Payload        string
+	Status         WebhookDeliveryStatus
+	Attempts       uint32
+	Created        time.Time
+	NextAttempt    time.Time
+	// LastAttempt is nil before the first attempt
+	LastAttempt *time.Time
+	// ResponseStatus is 0 if the last attempt did not get a response
+	ResponseStatus int
+	LastError      string
+}
+
+// CommitEventPosition is the position of a commit event for readers of new events:
+// events are ordered by the transaction that wrote them, and then by the order in which the transaction wrote them.
+type CommitEventPosition struct {
+	TransactionId int64
+	Id            int64
+}
+
+// PositionedEventRow is a commit event and its position.
+type PositionedEventRow struct {
+	EventRow
+	Position CommitEventPosition
+}
+
+// INSERTS
+
+// DBInsertWebhookSubscription inserts the subscription and returns its id. The Id of the argument is ignored.
+func (h *DBHandler) DBInsertWebhookSubscription(ctx context.Context, tx *sql.Tx, subscription WebhookSubscription) (_ int64, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBInsertWebhookSubscription")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	filterJson, err := json.Marshal(subscription.Filter)
+	if err != nil {
+		return 0, fmt.Errorf("could not marshal filter of webhook subscription: %w", err)
+	}
+	insertQuery := h.AdaptQuery(`
+		INSERT INTO ` + webhookSubscriptionsTable + ` (url, secret, filter, format, created, created_by_name, created_by_email)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id;
+	`)
+	span.SetTag("query", insertQuery)
+	var id int64
+	err = tx.QueryRowContext(ctx, insertQuery,
+		subscription.Url,
+		subscription.Secret,
+		string(filterJson),
+		subscription.Format,
+		subscription.Created,
+		subscription.CreatedByName,
+		subscription.CreatedByEmail,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("could not insert webhook subscription: %w", err)
+	}
+	return id, nil
+}
+
+// DBInsertWebhookDelivery inserts the delivery unless the event was already queued for the subscription.
+func (h *DBHandler) DBInsertWebhookDelivery(ctx context.Context, tx *sql.Tx, delivery WebhookDelivery) (err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBInsertWebhookDelivery")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	insertQuery := h.AdaptQuery(`
+		INSERT INTO ` + webhookDeliveriesTable + ` (subscription_id, event_uuid, event_type, payload, status, attempts, created, next_attempt, last_attempt, response_status, last_error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(subscription_id, event_uuid) DO NOTHING;
+	`)
+	span.SetTag("query", insertQuery)
+	_, err = tx.ExecContext(ctx, insertQuery,
+		delivery.SubscriptionId,
+		delivery.EventUuid,
+		delivery.EventType,
+		delivery.Payload,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.Created,
+		delivery.NextAttempt,
+		delivery.LastAttempt,
+		delivery.ResponseStatus,
+		delivery.LastError,
+	)
+	if err != nil {
+		return fmt.Errorf("could not insert webhook delivery of event %s for subscription %d: %w", delivery.EventUuid, delivery.SubscriptionId, err)
+	}
+	return nil
+}
+
+// DBWriteWebhookCursor replaces the position of the newest commit event that was matched against the subscriptions.
+func (h *DBHandler) DBWriteWebhookCursor(ctx context.Context, tx *sql.Tx, cursor CommitEventPosition) (err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBWriteWebhookCursor")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	upsertQuery := h.AdaptQuery(`
+		INSERT INTO ` + webhookCursorTable + ` (id, event_txid, event_id)
+		VALUES (1, ?, ?)
+		ON CONFLICT(id)
+		DO UPDATE SET event_txid = excluded.event_txid, event_id = excluded.event_id;
+	`)
+	span.SetTag("query", upsertQuery)
+	_, err = tx.ExecContext(ctx, upsertQuery, cursor.TransactionId, cursor.Id)
+	if err != nil {
+		return fmt.Errorf("could not write webhook cursor: %w", err)
+	}
+	return nil
+}
+
+// UPDATES
+
+// DBUpdateWebhookDelivery stores the result of a delivery attempt, or resets the delivery for a redelivery.
+func (h *DBHandler) DBUpdateWebhookDelivery(ctx context.Context, tx *sql.Tx, delivery WebhookDelivery) (err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBUpdateWebhookDelivery")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	updateQuery := h.AdaptQuery(`
+		UPDATE ` + webhookDeliveriesTable + `
+		SET status = ?, attempts = ?, next_attempt = ?, last_attempt = ?, response_status = ?, last_error = ?
+		WHERE id = ?;
+	`)
+	span.SetTag("query", updateQuery)
+	_, err = tx.ExecContext(ctx, updateQuery,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.NextAttempt,
+		delivery.LastAttempt,
+		delivery.ResponseStatus,
+		delivery.LastError,
+		delivery.Id,
+	)
+	if err != nil {
+		return fmt.Errorf("could not update webhook delivery %d: %w", delivery.Id, err)
+	}
+	return nil
+}
+
+// DELETES
+
+// DBDeleteWebhookSubscription deletes the subscription with all its deliveries and returns false if it did not exist.
+func (h *DBHandler) DBDeleteWebhookSubscription(ctx context.Context, tx *sql.Tx, id int64) (_ bool, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBDeleteWebhookSubscription")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	deleteQuery := h.AdaptQuery(`
+		DELETE FROM ` + webhookSubscriptionsTable + `
+		WHERE id = ?;
+	`)
+	span.SetTag("query", deleteQuery)
+	result, err := tx.ExecContext(ctx, deleteQuery, id)
+	if err != nil {
+		return false, fmt.Errorf("could not delete webhook subscription %d: %w", id, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("could not read affected rows of webhook subscription %d: %w", id, err)
+	}
+	return affected == 1, nil
+}
+
+// SELECTS
+
+// DBSelectAllWebhookSubscriptions returns all subscriptions including their secrets, sorted by id.
+func (h *DBHandler) DBSelectAllWebhookSubscriptions(ctx context.Context, tx *sql.Tx) (_ []WebhookSubscription, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectAllWebhookSubscriptions")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	selectQuery := h.AdaptQuery(`
+		SELECT id, url, secret, filter, format, created, created_by_name, created_by_email
+		FROM ` + webhookSubscriptionsTable + `
+		ORDER BY id ASC;
+	`)
+	span.SetTag("query", selectQuery)
+	rows, err := tx.QueryContext(ctx, selectQuery)
+	if err != nil {
+		return nil, fmt.Errorf("could not query webhook subscriptions: %w", err)
+	}
+	defer closeRowsAndLog(rows, ctx, "DBSelectAllWebhookSubscriptions")
+	result := make([]WebhookSubscription, 0)
+	for rows.Next() {
+		var (
+			row        WebhookSubscription
+			filterJson string
+		)
+		err := rows.Scan(&row.Id, &row.Url, &row.Secret, &filterJson, &row.Format, &row.Created, &row.CreatedByName, &row.CreatedByEmail)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan webhook_subscriptions row: %w", err)
+		}
+		if err := json.Unmarshal([]byte(filterJson), &row.Filter); err != nil {
+			return nil, fmt.Errorf("could not unmarshal filter of webhook subscription %d: %w", row.Id, err)
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+const selectWebhookDeliveryColumns = `id, subscription_id, event_uuid, event_type, payload, status, attempts, created, next_attempt, last_attempt, response_status, last_error`
+
+// DBSelectDueWebhookDeliveries returns up to limit pending deliveries whose next attempt is not after now, oldest first.
+func (h *DBHandler) DBSelectDueWebhookDeliveries(ctx context.Context, tx *sql.Tx, now time.Time, limit uint) (_ []WebhookDelivery, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectDueWebhookDeliveries")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	selectQuery := h.AdaptQuery(`
+		SELECT ` + selectWebhookDeliveryColumns + `
+		FROM ` + webhookDeliveriesTable + `
+		WHERE status = ? AND next_attempt <= ?
+		ORDER BY next_attempt ASC, id ASC
+		LIMIT ?;
+	`)
+	span.SetTag("query", selectQuery)
+	rows, err := tx.QueryContext(ctx, selectQuery, WebhookDeliveryStatusPending, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("could not query due webhook deliveries: %w", err)
+	}
+	defer closeRowsAndLog(rows, ctx, "DBSelectDueWebhookDeliveries")
+	return processWebhookDeliveries(rows)
+}
+
+// DBSelectWebhookDeliveries returns the deliveries of one subscription, or of all subscriptions if subscriptionId is 0, newest first.
+// A nil status returns deliveries of all statuses.
+func (h *DBHandler) DBSelectWebhookDeliveries(ctx context.Context, tx *sql.Tx, subscriptionId int64, status *WebhookDeliveryStatus, limit, offset uint64) (_ []WebhookDelivery, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectWebhookDeliveries")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	where := "WHERE 1 = 1"
+	args := []any{}
+	if subscriptionId != 0 {
+		where += " AND subscription_id = ?"
+		args = append(args, subscriptionId)
+	}
+	if status != nil {
+		where += " AND status = ?"
+		args = append(args, *status)
+	}
+	selectQuery := h.AdaptQuery(`
+		SELECT ` + selectWebhookDeliveryColumns + `
+		FROM ` + webhookDeliveriesTable + `
+		` + where + `
+		ORDER BY id DESC
+		LIMIT ?
+		OFFSET ?;
+	`)
+	span.SetTag("query", selectQuery)
+	args = append(args, limit, offset)
+	rows, err := tx.QueryContext(ctx, selectQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("could not query webhook deliveries: %w", err)
+	}
+	defer closeRowsAndLog(rows, ctx, "DBSelectWebhookDeliveries")
+	return processWebhookDeliveries(rows)
+}
+
+// DBSelectWebhookDelivery returns the delivery with the given id, or nil if it does not exist.
+func (h *DBHandler) DBSelectWebhookDelivery(ctx context.Context, tx *sql.Tx, id int64) (_ *WebhookDelivery, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectWebhookDelivery")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	selectQuery := h.AdaptQuery(`
+		SELECT ` + selectWebhookDeliveryColumns + `
+		FROM ` + webhookDeliveriesTable + `
+		WHERE id = ?
+		LIMIT 1;
+	`)
+	span.SetTag("query", selectQuery)
+	rows, err := tx.QueryContext(ctx, selectQuery, id)
+	if err != nil {
+		return nil, fmt.Errorf("could not query webhook delivery %d: %w", id, err)
+	}
+	defer closeRowsAndLog(rows, ctx, "DBSelectWebhookDelivery")
+	deliveries, err := processWebhookDeliveries(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(deliveries) == 0 {
+		return nil, nil
+	}
+	return &deliveries[0], nil
+}
+
+func processWebhookDeliveries(rows *sql.Rows) ([]WebhookDelivery, error) {
+	result := make([]WebhookDelivery, 0)
+	for rows.Next() {
+		var (
+			row         WebhookDelivery
+			lastAttempt sql.NullTime
+		)
+		err := rows.Scan(
+			&row.Id,
+			&row.SubscriptionId,
+			&row.EventUuid,
+			&row.EventType,
+			&row.Payload,
+			&row.Status,
+			&row.Attempts,
+			&row.Created,
+			&row.NextAttempt,
+			&lastAttempt,
+			&row.ResponseStatus,
+			&row.LastError,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan webhook_deliveries row: %w", err)
+		}
+		if lastAttempt.Valid {
+			row.LastAttempt = &lastAttempt.Time
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DBReadWebhookCursor returns the position of the newest commit event that was matched against the subscriptions,
+// or nil if no event was matched yet.
+func (h *DBHandler) DBReadWebhookCursor(ctx context.Context, tx *sql.Tx) (_ *CommitEventPosition, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBReadWebhookCursor")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	selectQuery := h.AdaptQuery(`
+		SELECT event_txid, event_id
+		FROM ` + webhookCursorTable + `
+		WHERE id = 1;
+	`)
+	span.SetTag("query", selectQuery)
+	var cursor CommitEventPosition
+	err = tx.QueryRowContext(ctx, selectQuery).Scan(&cursor.TransactionId, &cursor.Id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not read webhook cursor: %w", err)
+	}
+	return &cursor, nil
+}
+
+// DBReadCommitEventsHead returns the position before the events of all transactions that are still running.
+// Readers that start there only read events that are written from now on.
+func (h *DBHandler) DBReadCommitEventsHead(ctx context.Context, tx *sql.Tx) (_ CommitEventPosition, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBReadCommitEventsHead")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	selectQuery := h.AdaptQuery(`SELECT txid_snapshot_xmin(txid_current_snapshot());`)
+	span.SetTag("query", selectQuery)
+	var oldestRunning int64
+	err = tx.QueryRowContext(ctx, selectQuery).Scan(&oldestRunning)
+	if err != nil {
+		return CommitEventPosition{}, fmt.Errorf("could not read the oldest running transaction: %w", err)
+	}
+	return CommitEventPosition{TransactionId: oldestRunning - 1, Id: math.MaxInt64}, nil
+}
+
+// DBSelectCommitEventsAfter returns up to limit events that come after the position, ordered by their position.
+// It only returns events of transactions that are older than all running transactions,
+// so that a transaction that commits late cannot add events before the position of the last returned event.
+func (h *DBHandler) DBSelectCommitEventsAfter(ctx context.Context, tx *sql.Tx, after CommitEventPosition, limit uint) (_ []PositionedEventRow, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectCommitEventsAfter")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	selectQuery := h.AdaptQuery(`
+		SELECT uuid, timestamp, commitHash, eventType, json, transformereslVersion, txid, id
+		FROM ` + commitEventsTable + `
+		WHERE (txid, id) > (?, ?) AND txid < txid_snapshot_xmin(txid_current_snapshot())
+		ORDER BY txid ASC, id ASC
+		LIMIT ?;
+	`)
+	span.SetTag("query", selectQuery)
+	rows, err := tx.QueryContext(ctx, selectQuery, after.TransactionId, after.Id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying commit_events. Error: %w", err)
+	}
+	defer closeRowsAndLog(rows, ctx, "commit_events")
+	result := []PositionedEventRow{}
+	for rows.Next() {
+		//exhaustruct:ignore
+		var row PositionedEventRow
+		err := rows.Scan(&row.Uuid, &row.Timestamp, &row.CommitHash, &row.EventType, &row.EventJson, &row.TransformerID, &row.Position.TransactionId, &row.Position.Id)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning commit_events row from DB. Error: %w", err)
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("commit_events: row has error: %v", err)
+	}
+	return result, nil
+}
+
+// DBSelectCommitEventPosition returns the position of the event with the uuid, or nil if it does not exist.
+func (h *DBHandler) DBSelectCommitEventPosition(ctx context.Context, tx *sql.Tx, eventUuid string) (_ *CommitEventPosition, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "DBSelectCommitEventPosition")
+	defer func() {
+		span.Finish(tracer.WithError(err))
+	}()
+	selectQuery := h.AdaptQuery(`
+		SELECT txid, id
+		FROM ` + commitEventsTable + `
+		WHERE uuid = ?
+		LIMIT 1;
+	`)
+	span.SetTag("query", selectQuery)
+	var position CommitEventPosition
+	err = tx.QueryRowContext(ctx, selectQuery, eventUuid).Scan(&position.TransactionId, &position.Id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not read the position of event %s: %w", eventUuid, err)
+	}
+	return &position, nil
+}
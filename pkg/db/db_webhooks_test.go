@@ -0,0 +1,111 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/freiheit-com/kuberpult/pkg/event"
+)
+
+func TestSelectCommitEventsAfterLateCommit(t *testing.T) {
+	ctx := context.Background()
+	dbHandler := setupDB(t)
+	const (
+		lateUuid  = "00000000-0000-0000-0000-000000000001"
+		earlyUuid = "00000000-0000-0000-0000-000000000002"
+	)
+	writeEvent := func(transaction *sql.Tx, eventUuid string) {
+		t.Helper()
+		if err := dbHandler.WriteEvent(ctx, transaction, 0, eventUuid, event.EventTypeLockCreated, "", []byte("{}")); err != nil {
+			t.Fatalf("could not write event: %v", err)
+		}
+	}
+	// readUuids waits until the transactions of other tests, which also hold back the events, are done
+	readUuids := func(after CommitEventPosition, expected int) []PositionedEventRow {
+		t.Helper()
+		deadline := time.Now().Add(10 * time.Second)
+		for {
+			rows, err := WithTransactionMultipleEntriesT(dbHandler, ctx, true, func(ctx context.Context, transaction *sql.Tx) ([]PositionedEventRow, error) {
+				return dbHandler.DBSelectCommitEventsAfter(ctx, transaction, after, 10)
+			})
+			if err != nil {
+				t.Fatalf("could not select events: %v", err)
+			}
+			if len(rows) >= expected || time.Now().After(deadline) {
+				return rows
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	start, err := WithTransactionT(dbHandler, ctx, 0, true, func(ctx context.Context, transaction *sql.Tx) (*CommitEventPosition, error) {
+		head, err := dbHandler.DBReadCommitEventsHead(ctx, transaction)
+		return &head, err
+	})
+	if err != nil {
+		t.Fatalf("could not read head: %v", err)
+	}
+
+	// the late transaction writes its event first, but commits after the early one
+	late, err := dbHandler.BeginTransaction(ctx, false)
+	if err != nil {
+		t.Fatalf("could not begin transaction: %v", err)
+	}
+	defer func() { _ = late.Rollback() }()
+	writeEvent(late, lateUuid)
+	err = dbHandler.WithTransaction(ctx, false, func(ctx context.Context, transaction *sql.Tx) error {
+		writeEvent(transaction, earlyUuid)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("could not write early event: %v", err)
+	}
+
+	if rows := readUuids(*start, 0); len(rows) != 0 {
+		t.Fatalf("expected no events while the late transaction is running, got %d", len(rows))
+	}
+	if err := late.Commit(); err != nil {
+		t.Fatalf("could not commit late transaction: %v", err)
+	}
+
+	rows := readUuids(*start, 2)
+	uuids := []string{}
+	for _, row := range rows {
+		uuids = append(uuids, row.Uuid)
+	}
+	if diff := cmp.Diff([]string{lateUuid, earlyUuid}, uuids); diff != "" {
+		t.Fatalf("events mismatch (-want, +got):\n%s", diff)
+	}
+	if rest := readUuids(rows[0].Position, 1); len(rest) != 1 || rest[0].Uuid != earlyUuid {
+		t.Errorf("expected only the early event after the late one, got %v", rest)
+	}
+	position, err := WithTransactionT(dbHandler, ctx, 0, true, func(ctx context.Context, transaction *sql.Tx) (*CommitEventPosition, error) {
+		return dbHandler.DBSelectCommitEventPosition(ctx, transaction, earlyUuid)
+	})
+	if err != nil {
+		t.Fatalf("could not select position: %v", err)
+	}
+	if diff := cmp.Diff(&rows[1].Position, position); diff != "" {
+		t.Errorf("position mismatch (-want, +got):\n%s", diff)
+	}
+}
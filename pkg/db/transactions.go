@@ -35,6 +35,10 @@ const (
 	DefaultNumRetries uint8 = 3 // number of retries, so number of total tries is always 1 more.
 )
 
+// ErrRollback can be wrapped by the error of a transaction function to roll back the transaction on purpose.
+// The error is returned to the caller like any other error, but it is neither retried nor logged.
+var ErrRollback = errors.New("transaction rolled back")
+
 type DBFunction func(ctx context.Context, transaction *sql.Tx) error
 type DBFunctionT[T any] func(ctx context.Context, transaction *sql.Tx) (*T, error)
 type DBFunctionMultipleEntriesT[T any] func(ctx context.Context, transaction *sql.Tx) ([]T, error)
@@ -124,6 +128,10 @@ func withTransactionAllOptions[T any](h *DBHandler, ctx context.Context, opts tr
 	}
 
 	retryMaybe := func(msg string, e error, transaction *sql.Tx) ([]T, error) {
+		if errors.Is(e, ErrRollback) {
+			// the transaction function asked for the rollback, so this is not a failure
+			return nil, e
+		}
 		if opts.maxRetries == 0 {
 			return onError(fmt.Errorf("error %s transaction: %w", msg, e))
 		}
@@ -17,11 +17,19 @@ Copyright freiheit.com*/
 package db
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/lib/pq"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/freiheit-com/kuberpult/pkg/logger"
 )
 
 func TestIsRetryableError(t *testing.T) {
@@ -133,3 +141,25 @@ func TestIsNotRetryableErrorEndlessLoop(t *testing.T) {
 		})
 	}
 }
+
+func TestWithTransactionRollback(t *testing.T) {
+	dbHandler := setupDB(t)
+	core, logs := observer.New(zapcore.WarnLevel)
+	ctx := logger.WithLogger(context.Background(), zap.New(core))
+	errPending := fmt.Errorf("something is pending: %w", ErrRollback)
+
+	attempts := 0
+	err := dbHandler.WithTransaction(ctx, false, func(ctx context.Context, transaction *sql.Tx) error {
+		attempts++
+		return errPending
+	})
+	if !errors.Is(err, errPending) {
+		t.Errorf("expected %v, got %v", errPending, err)
+	}
+	if diff := cmp.Diff(1, attempts); diff != "" {
+		t.Errorf("attempts mismatch (-want, +got):\n%s", diff)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("expected no warnings or errors, got %v", logs.All())
+	}
+}
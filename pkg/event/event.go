@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"io/fs"
 	"slices"
+	"strconv"
 
 	"github.com/go-git/go-billy/v5"
 	"github.com/onokonem/sillyQueueServer/timeuuid"
@@ -33,11 +34,17 @@ import (
 type EventType string
 
 const (
-	EventTypeDeployment              EventType = "deployment"
-	EventTypeLockPreventedDeployment EventType = "lock-prevented-deployment"
-	EventTypeReplaceBy               EventType = "replaced-by"
-	EventTypeNewRelease              EventType = "new-release"
-	EventTypeDBMigrationEventType    EventType = "db-migration"
+	EventTypeDeployment                EventType = "deployment"
+	EventTypeLockPreventedDeployment   EventType = "lock-prevented-deployment"
+	EventTypeFreezePreventedDeployment EventType = "freeze-prevented-deployment"
+	EventTypeAutoRollback              EventType = "auto-rollback"
+	EventTypeReplaceBy                 EventType = "replaced-by"
+	EventTypeNewRelease                EventType = "new-release"
+	EventTypeDBMigrationEventType      EventType = "db-migration"
+	EventTypeLockCreated               EventType = "lock-created"
+	EventTypeLockDeleted               EventType = "lock-deleted"
+	EventTypeEnvironmentChanged        EventType = "environment-changed"
+	EventTypeRolloutStatusChanged      EventType = "rollout-status-changed"
 )
 
 type eventType struct {
@@ -48,6 +55,8 @@ type eventType struct {
 // for the first time.
 type NewRelease struct {
 	Environments map[string]struct{} `fs:"environments"`
+	// Application is nil for events written before it was recorded
+	Application *string `fs:"application" json:"Application,omitempty"`
 }
 
 func (*NewRelease) eventType() string {
@@ -123,6 +132,8 @@ func (ev *LockPreventedDeployment) toProto(trg *api.Event) {
 		lockType = api.LockPreventedDeploymentEvent_LOCK_TYPE_ENV
 	case "team":
 		lockType = api.LockPreventedDeploymentEvent_LOCK_TYPE_TEAM
+	case "gate":
+		lockType = api.LockPreventedDeploymentEvent_LOCK_TYPE_GATE
 	default:
 		lockType = api.LockPreventedDeploymentEvent_LOCK_TYPE_UNKNOWN
 	}
@@ -136,6 +147,65 @@ func (ev *LockPreventedDeployment) toProto(trg *api.Event) {
 	}
 }
 
+// FreezePreventedDeployment is an event that denotes that a deployment was
+// prevented because a freeze of the environment was active.
+type FreezePreventedDeployment struct {
+	Application   string `fs:"application"`
+	Environment   string `fs:"environment"`
+	FreezeId      string `fs:"freeze_id"`
+	FreezeMessage string `fs:"freeze_message"`
+}
+
+func (*FreezePreventedDeployment) eventType() string {
+	return string(EventTypeFreezePreventedDeployment)
+}
+
+func (ev *FreezePreventedDeployment) toProto(trg *api.Event) {
+	trg.EventType = &api.Event_FreezePreventedDeploymentEvent{
+		FreezePreventedDeploymentEvent: &api.FreezePreventedDeploymentEvent{
+			Application:   ev.Application,
+			Environment:   ev.Environment,
+			FreezeId:      ev.FreezeId,
+			FreezeMessage: ev.FreezeMessage,
+		},
+	}
+}
+
+// AutoRollback is an event that denotes that the rollout-service rolled back
+// a release because its rollout stayed degraded for too long.
+type AutoRollback struct {
+	Application       string `fs:"application"`
+	Environment       string `fs:"environment"`
+	RolledBackVersion string `fs:"rolled_back_version"`
+	RestoredVersion   string `fs:"restored_version"`
+	Reason            string `fs:"reason"`
+}
+
+func (*AutoRollback) eventType() string {
+	return string(EventTypeAutoRollback)
+}
+
+func (ev *AutoRollback) toProto(trg *api.Event) {
+	trg.EventType = &api.Event_AutoRollbackEvent{
+		AutoRollbackEvent: &api.AutoRollbackEvent{
+			Application:       ev.Application,
+			Environment:       ev.Environment,
+			RolledBackVersion: parseVersion(ev.RolledBackVersion),
+			RestoredVersion:   parseVersion(ev.RestoredVersion),
+			Reason:            ev.Reason,
+		},
+	}
+}
+
+// parseVersion returns 0 for versions that are not numbers, the event store only holds strings.
+func parseVersion(version string) uint64 {
+	v, err := strconv.ParseUint(version, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
 type ReplacedBy struct {
 	Application       string `fs:"application"`
 	Environment       string `fs:"environment"`
@@ -156,6 +226,111 @@ func (ev *ReplacedBy) toProto(trg *api.Event) {
 	}
 }
 
+// LockCreated is an event that denotes that an environment, application, team or manifest lock was created.
+// Application is only set for application and manifest locks, Team only for team locks.
+type LockCreated struct {
+	LockType    string `fs:"lock_type"`
+	LockId      string `fs:"lock_id"`
+	Environment string `fs:"environment"`
+	Application string `fs:"application"`
+	Team        string `fs:"team"`
+	Message     string `fs:"message"`
+}
+
+func (*LockCreated) eventType() string {
+	return string(EventTypeLockCreated)
+}
+
+func (ev *LockCreated) toProto(trg *api.Event) {
+	trg.EventType = &api.Event_LockCreatedEvent{
+		LockCreatedEvent: &api.LockCreatedEvent{
+			LockType:    ev.LockType,
+			LockId:      ev.LockId,
+			Environment: ev.Environment,
+			Application: ev.Application,
+			Team:        ev.Team,
+			Message:     ev.Message,
+		},
+	}
+}
+
+// LockDeleted is an event that denotes that a lock was deleted.
+// Reason is only set if kuberpult deleted the lock on its own, e.g. because it expired.
+type LockDeleted struct {
+	LockType    string `fs:"lock_type"`
+	LockId      string `fs:"lock_id"`
+	Environment string `fs:"environment"`
+	Application string `fs:"application"`
+	Team        string `fs:"team"`
+	Reason      string `fs:"reason"`
+}
+
+func (*LockDeleted) eventType() string {
+	return string(EventTypeLockDeleted)
+}
+
+func (ev *LockDeleted) toProto(trg *api.Event) {
+	trg.EventType = &api.Event_LockDeletedEvent{
+		LockDeletedEvent: &api.LockDeletedEvent{
+			LockType:    ev.LockType,
+			LockId:      ev.LockId,
+			Environment: ev.Environment,
+			Application: ev.Application,
+			Team:        ev.Team,
+			Reason:      ev.Reason,
+		},
+	}
+}
+
+const (
+	EnvironmentCreated = "created"
+	EnvironmentUpdated = "updated"
+	EnvironmentDeleted = "deleted"
+)
+
+// EnvironmentChanged is an event that denotes that an environment was created, updated or deleted.
+type EnvironmentChanged struct {
+	Environment string `fs:"environment"`
+	Change      string `fs:"change"`
+}
+
+func (*EnvironmentChanged) eventType() string {
+	return string(EventTypeEnvironmentChanged)
+}
+
+func (ev *EnvironmentChanged) toProto(trg *api.Event) {
+	trg.EventType = &api.Event_EnvironmentChangedEvent{
+		EnvironmentChangedEvent: &api.EnvironmentChangedEvent{
+			Environment: ev.Environment,
+			Change:      ev.Change,
+		},
+	}
+}
+
+// RolloutStatusChanged is an event that denotes that the rollout-service saw a new rollout status of an application.
+// Version is the version that kuberpult deployed, it is empty for brackets and if kuberpult has not deployed anything.
+type RolloutStatusChanged struct {
+	Application string `fs:"application"`
+	Environment string `fs:"environment"`
+	Version     string `fs:"version"`
+	Status      string `fs:"status"`
+}
+
+func (*RolloutStatusChanged) eventType() string {
+	return string(EventTypeRolloutStatusChanged)
+}
+
+func (ev *RolloutStatusChanged) toProto(trg *api.Event) {
+	trg.EventType = &api.Event_RolloutStatusChangedEvent{
+		RolloutStatusChangedEvent: &api.RolloutStatusChangedEvent{
+			Application: ev.Application,
+			Environment: ev.Environment,
+			Version:     parseVersion(ev.Version),
+			Status:      ev.Status,
+		},
+	}
+}
+
 // Event is a commit-releated event
 type Event interface {
 	eventType() string
@@ -179,9 +354,27 @@ func Read(filesystem billy.Filesystem, eventDir string) (Event, error) {
 	case "lock-prevented-deployment":
 		//exhaustruct:ignore
 		result = &LockPreventedDeployment{}
+	case "freeze-prevented-deployment":
+		//exhaustruct:ignore
+		result = &FreezePreventedDeployment{}
+	case "auto-rollback":
+		//exhaustruct:ignore
+		result = &AutoRollback{}
 	case "replaced-by":
 		//exhaustruct:ignore
 		result = &ReplacedBy{}
+	case "lock-created":
+		//exhaustruct:ignore
+		result = &LockCreated{}
+	case "lock-deleted":
+		//exhaustruct:ignore
+		result = &LockDeleted{}
+	case "environment-changed":
+		//exhaustruct:ignore
+		result = &EnvironmentChanged{}
+	case "rollout-status-changed":
+		//exhaustruct:ignore
+		result = &RolloutStatusChanged{}
 	default:
 		return nil, fmt.Errorf("unknown event type: %q", tp.EventType)
 	}
@@ -205,9 +398,27 @@ func UnMarshallEvent(eventType EventType, eventJson string) (DBEventGo, error) {
 	case "lock-prevented-deployment":
 		//exhaustruct:ignore
 		generalEvent.EventData = &LockPreventedDeployment{}
+	case "freeze-prevented-deployment":
+		//exhaustruct:ignore
+		generalEvent.EventData = &FreezePreventedDeployment{}
+	case "auto-rollback":
+		//exhaustruct:ignore
+		generalEvent.EventData = &AutoRollback{}
 	case "replaced-by":
 		//exhaustruct:ignore
 		generalEvent.EventData = &ReplacedBy{}
+	case "lock-created":
+		//exhaustruct:ignore
+		generalEvent.EventData = &LockCreated{}
+	case "lock-deleted":
+		//exhaustruct:ignore
+		generalEvent.EventData = &LockDeleted{}
+	case "environment-changed":
+		//exhaustruct:ignore
+		generalEvent.EventData = &EnvironmentChanged{}
+	case "rollout-status-changed":
+		//exhaustruct:ignore
+		generalEvent.EventData = &RolloutStatusChanged{}
 	default:
 		return DBEventGo{}, fmt.Errorf("unknown event type: %q", eventType)
 	}
@@ -70,6 +70,70 @@ func Test_roundtrip(t *testing.T) {
 				LockType:    "application",
 			},
 		},
+		{
+			Name: "freeze-prevented-deployment",
+			Event: &FreezePreventedDeployment{
+				Application:   "app",
+				Environment:   "env",
+				FreezeId:      "holidays",
+				FreezeMessage: "msg",
+			},
+		},
+		{
+			Name: "auto-rollback",
+			Event: &AutoRollback{
+				Application:       "app",
+				Environment:       "env",
+				RolledBackVersion: "2",
+				RestoredVersion:   "1",
+				Reason:            "msg",
+			},
+		},
+		{
+			Name: "new-release-with-application",
+			Event: &NewRelease{
+				Environments: map[string]struct{}{
+					"env1": {},
+				},
+				Application: ptr("app"),
+			},
+		},
+		{
+			Name: "lock-created",
+			Event: &LockCreated{
+				LockType:    "team",
+				LockId:      "lock-1",
+				Environment: "env",
+				Team:        "team",
+				Message:     "msg",
+			},
+		},
+		{
+			Name: "lock-deleted",
+			Event: &LockDeleted{
+				LockType:    "application",
+				LockId:      "lock-1",
+				Environment: "env",
+				Application: "app",
+				Reason:      "expired",
+			},
+		},
+		{
+			Name: "environment-changed",
+			Event: &EnvironmentChanged{
+				Environment: "env",
+				Change:      EnvironmentUpdated,
+			},
+		},
+		{
+			Name: "rollout-status-changed",
+			Event: &RolloutStatusChanged{
+				Application: "app",
+				Environment: "env",
+				Version:     "3",
+				Status:      "unhealthy",
+			},
+		},
 	} {
 		test := test
 		t.Run(test.Name, func(t *testing.T) {
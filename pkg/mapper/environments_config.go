@@ -19,6 +19,8 @@ package mapper
 import (
 	"sort"
 
+	"google.golang.org/protobuf/types/known/timestamppb"
+
 	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
 	"github.com/freiheit-com/kuberpult/pkg/config"
 	"github.com/freiheit-com/kuberpult/pkg/types"
@@ -302,6 +304,13 @@ func (s EnvironmentGroupsByDistance) Less(i, j int) bool {
 	return s[i].Environments[0].Name < s[j].Environments[0].Name
 }
 
+func soakTimePtr(soakTime string) *string {
+	if soakTime == "" {
+		return nil
+	}
+	return &soakTime
+}
+
 func TransformUpstream(upstream *config.EnvironmentConfigUpstream) *api.EnvironmentConfig_Upstream {
 	if upstream == nil {
 		return nil
@@ -310,12 +319,14 @@ func TransformUpstream(upstream *config.EnvironmentConfigUpstream) *api.Environm
 		return &api.EnvironmentConfig_Upstream{
 			Environment: nil,
 			Latest:      &upstream.Latest,
+			SoakTime:    nil,
 		}
 	}
 	if upstream.Environment != "" {
 		return &api.EnvironmentConfig_Upstream{
 			Latest:      nil,
 			Environment: types.StringPtr(upstream.Environment),
+			SoakTime:    soakTimePtr(upstream.SoakTime),
 		}
 	}
 	return nil
@@ -370,6 +381,20 @@ func TransformArgocd(cfg config.EnvironmentConfigArgoCd) *api.ArgoCDEnvironmentC
 		ApplicationAnnotations: cfg.ApplicationAnnotations,
 		SyncOptions:            cfg.SyncOptions,
 		ConcreteEnvName:        cfg.ConcreteEnvName,
+		Fluxcd:                 TransformFluxCd(cfg.FluxCd),
+	}
+}
+
+func TransformFluxCd(cfg *config.EnvironmentConfigFluxCd) *api.ArgoCDEnvironmentConfiguration_FluxCd {
+	if cfg == nil {
+		return nil
+	}
+	return &api.ArgoCDEnvironmentConfiguration_FluxCd{
+		Namespace:           cfg.Namespace,
+		Interval:            cfg.Interval,
+		SecretRef:           cfg.SecretRef,
+		KubeConfigSecretRef: cfg.KubeConfigSecretRef,
+		ServiceAccountName:  cfg.ServiceAccountName,
 	}
 }
 func TransformArgocdConfigs(cfg config.ArgoCDConfigs) *api.EnvironmentConfig_ArgoConfigs {
@@ -380,3 +405,81 @@ func TransformArgocdConfigs(cfg config.ArgoCDConfigs) *api.EnvironmentConfig_Arg
 	}
 	return &toReturn
 }
+
+func TransformFreeze(freeze config.EnvironmentFreeze) *api.EnvironmentFreeze {
+	var start, end *timestamppb.Timestamp
+	if !freeze.Start.IsZero() {
+		start = timestamppb.New(freeze.Start)
+	}
+	if !freeze.End.IsZero() {
+		end = timestamppb.New(freeze.End)
+	}
+	return &api.EnvironmentFreeze{
+		FreezeId: freeze.Id,
+		Message:  freeze.Message,
+		Schedule: freeze.Schedule,
+		Duration: freeze.Duration,
+		Start:    start,
+		End:      end,
+	}
+}
+
+func TransformFreezes(freezes []config.EnvironmentFreeze) []*api.EnvironmentFreeze {
+	var result []*api.EnvironmentFreeze
+	for _, freeze := range freezes {
+		result = append(result, TransformFreeze(freeze))
+	}
+	return result
+}
+
+func TransformManifestValidation(validation *config.ManifestValidation) *api.EnvironmentConfig_ManifestValidation {
+	if validation == nil {
+		return nil
+	}
+	return &api.EnvironmentConfig_ManifestValidation{
+		Rules:           validation.Rules,
+		DisallowedKinds: validation.DisallowedKinds,
+	}
+}
+
+func TransformDeploymentGates(gates []config.DeploymentGate) []*api.EnvironmentConfig_DeploymentGate {
+	var result []*api.EnvironmentConfig_DeploymentGate
+	for _, gate := range gates {
+		result = append(result, &api.EnvironmentConfig_DeploymentGate{
+			Name: gate.Name,
+			Url:  gate.Url,
+		})
+	}
+	return result
+}
+
+func TransformSignaturePolicy(policy *config.SignaturePolicy) *api.EnvironmentConfig_SignaturePolicy {
+	if policy == nil {
+		return nil
+	}
+	keys := make([]*api.EnvironmentConfig_SignaturePolicy_SigningKey, 0, len(policy.Keys))
+	for _, key := range policy.Keys {
+		keys = append(keys, &api.EnvironmentConfig_SignaturePolicy_SigningKey{
+			Name:      key.Name,
+			Type:      key.Type,
+			PublicKey: key.PublicKey,
+		})
+	}
+	return &api.EnvironmentConfig_SignaturePolicy{
+		Keys:      keys,
+		Threshold: policy.Threshold,
+	}
+}
+
+// ProdEnvironments returns the sorted names of all environments with the priority "prod".
+func ProdEnvironments(envs map[types.EnvName]config.EnvironmentConfig) []types.EnvName {
+	result := []types.EnvName{}
+	for _, group := range MapEnvironmentsToGroups(envs) {
+		for _, env := range group.Environments {
+			if env.Priority == api.Priority_PROD {
+				result = append(result, types.EnvName(env.Name))
+			}
+		}
+	}
+	return types.Sort(result)
+}
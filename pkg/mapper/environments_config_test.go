@@ -673,3 +673,25 @@ func TestMapEnvironmentsToGroup(t *testing.T) {
 		})
 	}
 }
+
+func TestProdEnvironments(t *testing.T) {
+	envs := map[types.EnvName]config.EnvironmentConfig{
+		nameDevDe: {
+			Upstream: &config.EnvironmentConfigUpstream{Latest: true},
+		},
+		nameStagingDe: {
+			Upstream: &config.EnvironmentConfigUpstream{Environment: nameDevDe},
+		},
+		nameProdDe: {
+			Upstream: &config.EnvironmentConfigUpstream{Environment: nameStagingDe},
+		},
+		nameProdFr: {
+			Upstream: &config.EnvironmentConfigUpstream{Environment: nameStagingDe},
+		},
+	}
+	expected := []types.EnvName{nameProdDe, nameProdFr}
+	actual := ProdEnvironments(envs)
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Fatalf("prod environments mismatch (-want, +got):\n%s", diff)
+	}
+}
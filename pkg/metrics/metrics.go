@@ -31,6 +31,7 @@ const (
 	EventTagApplication      = "kuberpult_application"
 	EventTagEnvironment      = "kuberpult_environment"
 	EventTagEnvironmentGroup = "kuberpult_environment_group"
+	EventTagTeam             = "kuberpult_team"
 )
 
 func Init() (metric.MeterProvider, http.Handler, error) {
@@ -0,0 +1,269 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+// Package signing verifies detached signatures over release manifests.
+// All signature types are verified offline with the public keys of the signature policy of an environment.
+package signing
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/freiheit-com/kuberpult/pkg/config"
+)
+
+const (
+	// TypeOpenPGP is an armored detached OpenPGP signature, e.g. from `gpg --armor --detach-sign`.
+	TypeOpenPGP = "openpgp"
+	// TypeSSH is an ssh signature, e.g. from `ssh-keygen -Y sign -n kuberpult`.
+	TypeSSH = "ssh"
+	// TypeCosign is a base64 encoded signature of a keyed `cosign sign-blob`.
+	TypeCosign = "cosign"
+
+	// SSHNamespace is the namespace that ssh signatures of manifests must use.
+	SSHNamespace = "kuberpult"
+)
+
+var Types = []string{TypeOpenPGP, TypeSSH, TypeCosign}
+
+// Signature is a detached signature over the manifest of a release for one environment.
+type Signature struct {
+	Type      string
+	Signature string
+}
+
+// ValidType returns whether kuberpult can verify signatures of this type.
+func ValidType(signatureType string) bool {
+	for _, t := range Types {
+		if t == signatureType {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidatePolicy checks that all keys of the policy can be parsed and the threshold can be reached.
+func ValidatePolicy(policy *config.SignaturePolicy) error {
+	if policy == nil {
+		return nil
+	}
+	if len(policy.Keys) == 0 {
+		return fmt.Errorf("signature policy: at least one key is required")
+	}
+	if int(policy.Threshold) > len(policy.Keys) {
+		return fmt.Errorf("signature policy: threshold %d is higher than the number of keys (%d)", policy.Threshold, len(policy.Keys))
+	}
+	names := make(map[string]bool, len(policy.Keys))
+	for _, key := range policy.Keys {
+		if key.Name == "" {
+			return fmt.Errorf("signature policy: every key needs a name")
+		}
+		if names[key.Name] {
+			return fmt.Errorf("signature policy: key name %q is used more than once", key.Name)
+		}
+		names[key.Name] = true
+		if err := verify(key, nil, ""); err != nil && !errors.Is(err, errNoSignature) {
+			return fmt.Errorf("signature policy: key %q: %w", key.Name, err)
+		}
+	}
+	return nil
+}
+
+// CheckPolicy returns an error if fewer keys of the policy than required signed content.
+// Signatures that do not verify with any key are ignored, as they may be meant for other environments.
+func CheckPolicy(policy *config.SignaturePolicy, content []byte, signatures []Signature) error {
+	if policy == nil {
+		return nil
+	}
+	required := int(policy.Threshold)
+	if required == 0 {
+		required = len(policy.Keys)
+	}
+	var signers, missing []string
+	for _, key := range policy.Keys {
+		if signedBy(key, content, signatures) {
+			signers = append(signers, key.Name)
+		} else {
+			missing = append(missing, key.Name)
+		}
+	}
+	if len(signers) >= required {
+		return nil
+	}
+	return fmt.Errorf("the manifest is signed by %d of the %d required trusted keys, missing signatures of: %s", len(signers), required, strings.Join(missing, ", "))
+}
+
+func signedBy(key config.SigningKey, content []byte, signatures []Signature) bool {
+	for _, signature := range signatures {
+		if signature.Type != key.Type {
+			continue
+		}
+		if verify(key, content, signature.Signature) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+var errNoSignature = errors.New("no signature")
+
+// verify checks that signature is a valid signature of content with key.
+// With an empty signature, it only parses the key and returns errNoSignature.
+func verify(key config.SigningKey, content []byte, signature string) error {
+	switch key.Type {
+	case TypeOpenPGP:
+		return verifyOpenPGP(key.PublicKey, content, signature)
+	case TypeSSH:
+		return verifySSH(key.PublicKey, content, signature)
+	case TypeCosign:
+		return verifyCosign(key.PublicKey, content, signature)
+	default:
+		return fmt.Errorf("unknown key type %q, must be one of %s", key.Type, strings.Join(Types, ", "))
+	}
+}
+
+func verifyOpenPGP(publicKey string, content []byte, signature string) error {
+	keyRing, err := openpgp.ReadArmoredKeyRing(strings.NewReader(publicKey))
+	if err != nil {
+		return fmt.Errorf("invalid openpgp public key: %w", err)
+	}
+	if signature == "" {
+		return errNoSignature
+	}
+	_, err = openpgp.CheckArmoredDetachedSignature(keyRing, bytes.NewReader(content), strings.NewReader(signature), nil)
+	return err
+}
+
+// sshSignature is the armored blob of an ssh signature, see PROTOCOL.sshsig in openssh
+type sshSignature struct {
+	MagicPreamble [6]byte
+	Version       uint32
+	PublicKey     []byte
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Signature     []byte
+}
+
+// sshSignedData is the data that is actually signed by an ssh signature
+type sshSignedData struct {
+	MagicPreamble [6]byte
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Hash          []byte
+}
+
+var sshMagicPreamble = [6]byte{'S', 'S', 'H', 'S', 'I', 'G'}
+
+func verifySSH(publicKey string, content []byte, signature string) error {
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(publicKey))
+	if err != nil {
+		return fmt.Errorf("invalid ssh public key: %w", err)
+	}
+	if signature == "" {
+		return errNoSignature
+	}
+	block, _ := pem.Decode([]byte(signature))
+	if block == nil || block.Type != "SSH SIGNATURE" {
+		return fmt.Errorf("ssh signature is not armored")
+	}
+	var sig sshSignature
+	if err := ssh.Unmarshal(block.Bytes, &sig); err != nil {
+		return fmt.Errorf("invalid ssh signature: %w", err)
+	}
+	if sig.MagicPreamble != sshMagicPreamble || sig.Version != 1 {
+		return fmt.Errorf("unsupported ssh signature format")
+	}
+	if sig.Namespace != SSHNamespace {
+		return fmt.Errorf("ssh signature has namespace %q instead of %q", sig.Namespace, SSHNamespace)
+	}
+	if !bytes.Equal(sig.PublicKey, key.Marshal()) {
+		return fmt.Errorf("ssh signature was made by another key")
+	}
+	var hash []byte
+	switch sig.HashAlgorithm {
+	case "sha256":
+		h := sha256.Sum256(content)
+		hash = h[:]
+	case "sha512":
+		h := sha512.Sum512(content)
+		hash = h[:]
+	default:
+		return fmt.Errorf("unsupported hash algorithm %q of ssh signature", sig.HashAlgorithm)
+	}
+	var sshSig ssh.Signature
+	if err := ssh.Unmarshal(sig.Signature, &sshSig); err != nil {
+		return fmt.Errorf("invalid ssh signature: %w", err)
+	}
+	signedData := ssh.Marshal(sshSignedData{
+		MagicPreamble: sshMagicPreamble,
+		Namespace:     sig.Namespace,
+		Reserved:      sig.Reserved,
+		HashAlgorithm: sig.HashAlgorithm,
+		Hash:          hash,
+	})
+	return key.Verify(signedData, &sshSig)
+}
+
+func verifyCosign(publicKey string, content []byte, signature string) error {
+	block, _ := pem.Decode([]byte(publicKey))
+	if block == nil {
+		return fmt.Errorf("cosign public key is not PEM encoded")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("invalid cosign public key: %w", err)
+	}
+	if signature == "" {
+		return errNoSignature
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(signature))
+	if err != nil {
+		return fmt.Errorf("cosign signature is not base64 encoded: %w", err)
+	}
+	digest := sha256.Sum256(content)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(k, digest[:], sig) {
+			return fmt.Errorf("invalid cosign signature")
+		}
+		return nil
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig)
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, content, sig) {
+			return fmt.Errorf("invalid cosign signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported cosign public key type %T", key)
+	}
+}
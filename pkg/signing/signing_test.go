@@ -0,0 +1,250 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package signing
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+
+	"github.com/freiheit-com/kuberpult/pkg/config"
+)
+
+const manifest = "kind: ConfigMap\n"
+
+// created with `ssh-keygen -t ed25519` and `ssh-keygen -Y sign -n <namespace>`
+const (
+	sshPublicKey     = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIEpIRCGG+pIi0TR9HSlQnzkfNzr+gGu7fN/uVYEDa4JX ci@example.com"
+	sshTestSignature = `-----BEGIN SSH SIGNATURE-----
+U1NIU0lHAAAAAQAAADMAAAALc3NoLWVkMjU1MTkAAAAgSkhEIYb6kiLRNH0dKVCfOR83Ov
+6Aa7t83+5VgQNrglcAAAAJa3ViZXJwdWx0AAAAAAAAAAZzaGE1MTIAAABTAAAAC3NzaC1l
+ZDI1NTE5AAAAQLNKP5wKIL0nCbhaPcQWpaTeQoynR9T3Blcuf3RWgWfnxZoQJPx2vOR+wL
+N+5L7G2yPzSrSWZOb0nA3SCuF/RwU=
+-----END SSH SIGNATURE-----
+`
+	sshSignatureGitNamespace = `-----BEGIN SSH SIGNATURE-----
+U1NIU0lHAAAAAQAAADMAAAALc3NoLWVkMjU1MTkAAAAgSkhEIYb6kiLRNH0dKVCfOR83Ov
+6Aa7t83+5VgQNrglcAAAADZ2l0AAAAAAAAAAZzaGE1MTIAAABTAAAAC3NzaC1lZDI1NTE5
+AAAAQH8raQQJaaYckcc5QItrz89uy39Du3KP5nx0XKAk3M54iFVQyFtJMjajHBlT9bmjri
+CjYYfhjkHwz7jFO6W/2AI=
+-----END SSH SIGNATURE-----
+`
+)
+
+func openPGPKey(t *testing.T) (publicKey string, sign func(content string) string) {
+	entity, err := openpgp.NewEntity("ci", "", "ci@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String(), func(content string) string {
+		var sig bytes.Buffer
+		if err := openpgp.ArmoredDetachSign(&sig, entity, strings.NewReader(content), nil); err != nil {
+			t.Fatal(err)
+		}
+		return sig.String()
+	}
+}
+
+func cosignKey(t *testing.T) (publicKey string, sign func(content string) string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), func(content string) string {
+		digest := sha256.Sum256([]byte(content))
+		sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.StdEncoding.EncodeToString(sig)
+	}
+}
+
+func TestCheckPolicy(t *testing.T) {
+	pgpPublicKey, pgpSign := openPGPKey(t)
+	cosignPublicKey, cosignSign := cosignKey(t)
+	otherCosignPublicKey, _ := cosignKey(t)
+	keys := []config.SigningKey{
+		{Name: "ci-pgp", Type: TypeOpenPGP, PublicKey: pgpPublicKey},
+		{Name: "ci-ssh", Type: TypeSSH, PublicKey: sshPublicKey},
+		{Name: "ci-cosign", Type: TypeCosign, PublicKey: cosignPublicKey},
+	}
+
+	tcs := []struct {
+		Name          string
+		Policy        *config.SignaturePolicy
+		Content       string
+		Signatures    []Signature
+		ExpectedError string
+	}{
+		{
+			Name:          "no policy",
+			Policy:        nil,
+			Content:       manifest,
+			Signatures:    nil,
+			ExpectedError: "",
+		},
+		{
+			Name:    "all keys signed",
+			Policy:  &config.SignaturePolicy{Keys: keys, Threshold: 0},
+			Content: manifest,
+			Signatures: []Signature{
+				{Type: TypeOpenPGP, Signature: pgpSign(manifest)},
+				{Type: TypeSSH, Signature: sshTestSignature},
+				{Type: TypeCosign, Signature: cosignSign(manifest)},
+			},
+			ExpectedError: "",
+		},
+		{
+			Name:    "threshold reached",
+			Policy:  &config.SignaturePolicy{Keys: keys, Threshold: 1},
+			Content: manifest,
+			Signatures: []Signature{
+				{Type: TypeSSH, Signature: sshTestSignature},
+			},
+			ExpectedError: "",
+		},
+		{
+			Name:    "missing signatures",
+			Policy:  &config.SignaturePolicy{Keys: keys, Threshold: 2},
+			Content: manifest,
+			Signatures: []Signature{
+				{Type: TypeCosign, Signature: cosignSign(manifest)},
+			},
+			ExpectedError: "the manifest is signed by 1 of the 2 required trusted keys, missing signatures of: ci-pgp, ci-ssh",
+		},
+		{
+			Name:    "signatures of another manifest",
+			Policy:  &config.SignaturePolicy{Keys: keys, Threshold: 1},
+			Content: manifest,
+			Signatures: []Signature{
+				{Type: TypeOpenPGP, Signature: pgpSign("kind: Secret\n")},
+				{Type: TypeCosign, Signature: cosignSign("kind: Secret\n")},
+			},
+			ExpectedError: "the manifest is signed by 0 of the 1 required trusted keys, missing signatures of: ci-pgp, ci-ssh, ci-cosign",
+		},
+		{
+			Name:    "ssh signature with another namespace",
+			Policy:  &config.SignaturePolicy{Keys: keys[1:2], Threshold: 0},
+			Content: manifest,
+			Signatures: []Signature{
+				{Type: TypeSSH, Signature: sshSignatureGitNamespace},
+			},
+			ExpectedError: "the manifest is signed by 0 of the 1 required trusted keys, missing signatures of: ci-ssh",
+		},
+		{
+			Name:    "signature of an untrusted key",
+			Policy:  &config.SignaturePolicy{Keys: []config.SigningKey{{Name: "other", Type: TypeCosign, PublicKey: otherCosignPublicKey}}, Threshold: 0},
+			Content: manifest,
+			Signatures: []Signature{
+				{Type: TypeCosign, Signature: cosignSign(manifest)},
+			},
+			ExpectedError: "the manifest is signed by 0 of the 1 required trusted keys, missing signatures of: other",
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := CheckPolicy(tc.Policy, []byte(tc.Content), tc.Signatures)
+			actual := ""
+			if err != nil {
+				actual = err.Error()
+			}
+			if actual != tc.ExpectedError {
+				t.Fatalf("expected error %q, got %q", tc.ExpectedError, actual)
+			}
+		})
+	}
+}
+
+func TestValidatePolicy(t *testing.T) {
+	tcs := []struct {
+		Name          string
+		Policy        *config.SignaturePolicy
+		ExpectedError string
+	}{
+		{
+			Name:          "valid policy",
+			Policy:        &config.SignaturePolicy{Keys: []config.SigningKey{{Name: "ci", Type: TypeSSH, PublicKey: sshPublicKey}}, Threshold: 1},
+			ExpectedError: "",
+		},
+		{
+			Name:          "no keys",
+			Policy:        &config.SignaturePolicy{Keys: nil, Threshold: 0},
+			ExpectedError: "signature policy: at least one key is required",
+		},
+		{
+			Name:          "threshold too high",
+			Policy:        &config.SignaturePolicy{Keys: []config.SigningKey{{Name: "ci", Type: TypeSSH, PublicKey: sshPublicKey}}, Threshold: 2},
+			ExpectedError: "signature policy: threshold 2 is higher than the number of keys (1)",
+		},
+		{
+			Name: "duplicate names",
+			Policy: &config.SignaturePolicy{Keys: []config.SigningKey{
+				{Name: "ci", Type: TypeSSH, PublicKey: sshPublicKey},
+				{Name: "ci", Type: TypeSSH, PublicKey: sshPublicKey},
+			}, Threshold: 0},
+			ExpectedError: `signature policy: key name "ci" is used more than once`,
+		},
+		{
+			Name:          "unknown type",
+			Policy:        &config.SignaturePolicy{Keys: []config.SigningKey{{Name: "ci", Type: "x509", PublicKey: sshPublicKey}}, Threshold: 0},
+			ExpectedError: `signature policy: key "ci": unknown key type "x509", must be one of openpgp, ssh, cosign`,
+		},
+		{
+			Name:          "invalid key",
+			Policy:        &config.SignaturePolicy{Keys: []config.SigningKey{{Name: "ci", Type: TypeCosign, PublicKey: sshPublicKey}}, Threshold: 0},
+			ExpectedError: `signature policy: key "ci": cosign public key is not PEM encoded`,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := ValidatePolicy(tc.Policy)
+			actual := ""
+			if err != nil {
+				actual = err.Error()
+			}
+			if actual != tc.ExpectedError {
+				t.Fatalf("expected error %q, got %q", tc.ExpectedError, actual)
+			}
+		})
+	}
+}
@@ -85,6 +85,7 @@ func MakeArgoCdConfigDestination(concreteEnvName, destinationName, destinationSe
 		IgnoreDifferences:        nil,
 		SyncOptions:              nil,
 		ConcreteEnvName:          concreteEnvName,
+		FluxCd:                   nil,
 	}
 }
 
@@ -92,6 +93,7 @@ func MakeArgoCDConfigs(commonName, concreteName string, envNumber int) *config.A
 	toReturn := config.ArgoCDConfigs{
 		CommonEnvPrefix:      &commonName,
 		ArgoCdConfigurations: make([]*config.EnvironmentConfigArgoCd, 0),
+		Waves:                nil,
 	}
 
 	for i := 0; i < envNumber; i++ {
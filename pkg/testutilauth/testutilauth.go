@@ -33,6 +33,7 @@ func MakeTestContext() context.Context {
 		DexAuthContext: nil,
 		Email:          "testmail@example.com",
 		Name:           "test tester",
+		Identity:       "",
 	}
 	ctx := auth.WriteUserToContext(context.Background(), u)
 
@@ -50,6 +51,7 @@ func MakeTestContextDexEnabledUser(role string) context.Context {
 		Email:          "testmail@example.com",
 		Name:           "test tester",
 		DexAuthContext: &auth.DexAuthContext{Role: []string{role}},
+		Identity:       "",
 	}
 	ctx := auth.WriteUserToContext(context.Background(), u)
 	ctx = metadata.NewIncomingContext(ctx, metadata.New(map[string]string{
@@ -17,8 +17,22 @@ Copyright freiheit.com*/
 // Main file for microservice cd-service.
 package main
 
-import "github.com/freiheit-com/kuberpult/services/cd-service/pkg/cmd"
+import (
+	"os"
+
+	"github.com/freiheit-com/kuberpult/services/cd-service/pkg/cmd"
+)
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "backup":
+			cmd.RunBackup(os.Args[2:])
+			return
+		case "restore":
+			cmd.RunRestore(os.Args[2:])
+			return
+		}
+	}
 	cmd.RunServer()
 }
@@ -0,0 +1,239 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/freiheit-com/kuberpult/pkg/db"
+)
+
+// FormatVersion is the version of the archive layout.
+// It must be increased whenever an entity is added, removed or changes its encoding.
+const FormatVersion = 2
+
+const headerFile = "backup.json"
+
+const (
+	environmentsFile     = "environments.json"
+	appsFile             = "apps.json"
+	releasesFile         = "releases.json"
+	deploymentsFile      = "deployments.json"
+	environmentLocksFile = "environment_locks.json"
+	applicationLocksFile = "application_locks.json"
+	teamLocksFile        = "team_locks.json"
+	manifestLocksFile    = "manifest_locks.json"
+	queuedVersionsFile   = "queued_versions.json"
+	bracketsFile         = "brackets.json"
+
+	releaseRetentionPoliciesFile = "release_retention_policies.json"
+	deploymentApprovalsFile      = "deployment_approvals.json"
+	aaWaveDeploymentsFile        = "aa_wave_deployments.json"
+	webhookSubscriptionsFile     = "webhook_subscriptions.json"
+)
+
+// Header is the first entry of every archive.
+type Header struct {
+	FormatVersion    int            `json:"formatVersion"`
+	CreatedAt        time.Time      `json:"createdAt"`
+	KuberpultVersion string         `json:"kuberpultVersion"`
+	Counts           map[string]int `json:"counts"`
+}
+
+// State is the complete logical state of kuberpult.
+// Teams are part of the apps, because kuberpult only knows teams as an attribute of an app.
+type State struct {
+	Environments     []db.DBEnvironment
+	Apps             []db.DBAppWithMetaData
+	Releases         []db.DBReleaseWithMetaData
+	Deployments      []db.Deployment
+	EnvironmentLocks []db.EnvironmentLock
+	ApplicationLocks []db.ApplicationLock
+	TeamLocks        []db.TeamLock
+	ManifestLocks    []db.ManifestLock
+	QueuedVersions   []db.QueuedDeployment
+	// Brackets is nil if no bracket was ever written.
+	Brackets *db.BracketRow
+
+	ReleaseRetentionPolicies []db.ReleaseRetentionPolicy
+	// DeploymentApprovals contains pending and decided approvals.
+	DeploymentApprovals []db.DeploymentApproval
+	// AAWaveDeployments is the progress of the waves on active/active environments.
+	AAWaveDeployments []db.AAWaveDeployment
+	// WebhookSubscriptions includes the secrets of the subscriptions.
+	WebhookSubscriptions []db.WebhookSubscription
+}
+
+// ErrUnsupportedFormatVersion is returned when the archive was written by an incompatible version of kuberpult.
+var ErrUnsupportedFormatVersion = errors.New("unsupported backup format version")
+
+func (s *State) entries() []struct {
+	name  string
+	value any
+} {
+	return []struct {
+		name  string
+		value any
+	}{
+		{environmentsFile, &s.Environments},
+		{appsFile, &s.Apps},
+		{releasesFile, &s.Releases},
+		{deploymentsFile, &s.Deployments},
+		{environmentLocksFile, &s.EnvironmentLocks},
+		{applicationLocksFile, &s.ApplicationLocks},
+		{teamLocksFile, &s.TeamLocks},
+		{manifestLocksFile, &s.ManifestLocks},
+		{queuedVersionsFile, &s.QueuedVersions},
+		{bracketsFile, &s.Brackets},
+		{releaseRetentionPoliciesFile, &s.ReleaseRetentionPolicies},
+		{deploymentApprovalsFile, &s.DeploymentApprovals},
+		{aaWaveDeploymentsFile, &s.AAWaveDeployments},
+		{webhookSubscriptionsFile, &s.WebhookSubscriptions},
+	}
+}
+
+func (s *State) counts() map[string]int {
+	brackets := 0
+	if s.Brackets != nil {
+		brackets = len(s.Brackets.AllBracketsJsonBlob.BracketMap)
+	}
+	return map[string]int{
+		"environments":             len(s.Environments),
+		"apps":                     len(s.Apps),
+		"releases":                 len(s.Releases),
+		"deployments":              len(s.Deployments),
+		"environmentLocks":         len(s.EnvironmentLocks),
+		"applicationLocks":         len(s.ApplicationLocks),
+		"teamLocks":                len(s.TeamLocks),
+		"manifestLocks":            len(s.ManifestLocks),
+		"queuedVersions":           len(s.QueuedVersions),
+		"brackets":                 brackets,
+		"releaseRetentionPolicies": len(s.ReleaseRetentionPolicies),
+		"deploymentApprovals":      len(s.DeploymentApprovals),
+		"aaWaveDeployments":        len(s.AAWaveDeployments),
+		"webhookSubscriptions":     len(s.WebhookSubscriptions),
+	}
+}
+
+// WriteArchive writes the state as gzip compressed tar archive.
+// The header is always the first entry, so that readers can reject unsupported archives early.
+func WriteArchive(w io.Writer, kuberpultVersion string, createdAt time.Time, state *State) (err error) {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	defer func() {
+		err = errors.Join(err, tw.Close(), gz.Close())
+	}()
+
+	header := Header{
+		FormatVersion:    FormatVersion,
+		CreatedAt:        createdAt,
+		KuberpultVersion: kuberpultVersion,
+		Counts:           state.counts(),
+	}
+	if err := writeEntry(tw, headerFile, createdAt, header); err != nil {
+		return err
+	}
+	for _, entry := range state.entries() {
+		if err := writeEntry(tw, entry.name, createdAt, entry.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeEntry(tw *tar.Writer, name string, modTime time.Time, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("could not marshal %s: %w", name, err)
+	}
+	//exhaustruct:ignore
+	err = tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(data)),
+		ModTime:  modTime,
+	})
+	if err != nil {
+		return fmt.Errorf("could not write tar header of %s: %w", name, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("could not write %s: %w", name, err)
+	}
+	return nil
+}
+
+// ReadArchive reads an archive written by WriteArchive.
+// It fails if the header is missing or has a different format version.
+func ReadArchive(r io.Reader) (*Header, *State, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not open backup archive: %w", err)
+	}
+	defer func() { _ = gz.Close() }()
+	tr := tar.NewReader(gz)
+
+	th, err := tr.Next()
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not read backup archive: %w", err)
+	}
+	if th.Name != headerFile {
+		return nil, nil, fmt.Errorf("invalid backup archive: expected %s as first entry but got %s", headerFile, th.Name)
+	}
+	//exhaustruct:ignore
+	header := &Header{}
+	if err := json.NewDecoder(tr).Decode(header); err != nil {
+		return nil, nil, fmt.Errorf("could not decode %s: %w", headerFile, err)
+	}
+	if header.FormatVersion != FormatVersion {
+		return nil, nil, fmt.Errorf("%w: archive has version %d but this kuberpult supports version %d", ErrUnsupportedFormatVersion, header.FormatVersion, FormatVersion)
+	}
+
+	//exhaustruct:ignore
+	state := &State{}
+	targets := map[string]any{}
+	for _, entry := range state.entries() {
+		targets[entry.name] = entry.value
+	}
+	for {
+		th, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not read backup archive: %w", err)
+		}
+		target, ok := targets[th.Name]
+		if !ok {
+			return nil, nil, fmt.Errorf("invalid backup archive: unknown entry %s", th.Name)
+		}
+		if err := json.NewDecoder(tr).Decode(target); err != nil {
+			return nil, nil, fmt.Errorf("could not decode %s: %w", th.Name, err)
+		}
+		delete(targets, th.Name)
+	}
+	if len(targets) != 0 {
+		return nil, nil, fmt.Errorf("invalid backup archive: %d entries are missing", len(targets))
+	}
+	return header, state, nil
+}
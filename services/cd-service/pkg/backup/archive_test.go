@@ -0,0 +1,273 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/freiheit-com/kuberpult/pkg/config"
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/types"
+)
+
+func uversion(v uint64) *uint64 {
+	return &v
+}
+
+// testState returns a state that contains every entity at least once.
+func testState(created time.Time) *State {
+	lock := db.LockMetadata{
+		CreatedByName:     "alice",
+		CreatedByEmail:    "alice@example.com",
+		Message:           "incident",
+		CiLink:            "",
+		CreatedAt:         created,
+		SuggestedLifeTime: "2h",
+	}
+	return &State{
+		Environments: []db.DBEnvironment{
+			{
+				Created: created,
+				Name:    "production",
+				//exhaustruct:ignore
+				Config: config.EnvironmentConfig{
+					//exhaustruct:ignore
+					Upstream: &config.EnvironmentConfigUpstream{Environment: "staging"},
+				},
+			},
+		},
+		Apps: []db.DBAppWithMetaData{
+			{App: "payments", Metadata: db.DBAppMetaData{Team: "payments-team"}, StateChange: db.AppStateChangeCreate, ArgoBracket: "payments"},
+		},
+		Releases: []db.DBReleaseWithMetaData{
+			{
+				ReleaseNumbers: types.ReleaseNumbers{Version: uversion(12), Revision: 1},
+				Created:        created,
+				App:            "payments",
+				Manifests:      db.DBReleaseManifests{Manifests: map[types.EnvName]string{"production": "kind: Deployment\n"}},
+				//exhaustruct:ignore
+				Metadata:     db.DBReleaseMetaData{SourceCommitId: "cafe", SourceAuthor: "alice"},
+				Environments: []types.EnvName{"production"},
+				Deleted:      false,
+			},
+		},
+		Deployments: []db.Deployment{
+			{
+				Created:        created,
+				App:            "payments",
+				Env:            "production",
+				ReleaseNumbers: types.ReleaseNumbers{Version: uversion(12), Revision: 1},
+				Metadata:       db.DeploymentMetadata{DeployedByName: "alice", DeployedByEmail: "alice@example.com", CiLink: ""},
+				TransformerID:  7,
+			},
+		},
+		EnvironmentLocks: []db.EnvironmentLock{{Created: created, LockID: "l1", Env: "production", Metadata: lock}},
+		ApplicationLocks: []db.ApplicationLock{{Created: created, LockID: "l2", Env: "production", App: "payments", Metadata: lock}},
+		TeamLocks:        []db.TeamLock{{Created: created, LockID: "l3", Env: "production", Team: "payments-team", Metadata: lock}},
+		ManifestLocks: []db.ManifestLock{
+			{LockID: 1, RecordedAt: created, App: "payments", Env: "production", Metadata: lock, Active: true, EventType: db.ManifestLockEventTypeCreated},
+		},
+		QueuedVersions: []db.QueuedDeployment{
+			{
+				Created:        created,
+				Env:            "production",
+				App:            "payments",
+				ReleaseNumbers: types.ReleaseNumbers{Version: uversion(13), Revision: 0},
+				Metadata:       db.QueuedDeploymentMetadata{QueuedByName: "alice", QueuedByEmail: "alice@example.com", Reason: "locked"},
+			},
+		},
+		Brackets: &db.BracketRow{
+			CreatedAt:              created,
+			AllBracketsJsonBlob:    db.BracketJsonBlob{BracketMap: map[types.ArgoBracketName]db.AppNames{"payments": {"payments"}}},
+			SourceTransformerEslId: 3,
+		},
+		ReleaseRetentionPolicies: []db.ReleaseRetentionPolicy{
+			{Scope: db.ReleaseRetentionScopeTeam, Name: "payments-team", KeepLast: 5, KeepDays: 30, KeepProdDeployed: true, Created: created, EslVersion: 4},
+		},
+		DeploymentApprovals: []db.DeploymentApproval{
+			{
+				ApprovalId:   "a1",
+				Created:      created,
+				ExpiresAt:    created.Add(time.Hour),
+				Status:       db.DeploymentApprovalStatusApproved,
+				App:          "payments",
+				Environments: []types.EnvName{"production"},
+				Request: db.DeploymentApprovalRequest{
+					Deployment:   &db.DeploymentApprovalDeployment{Environment: "production", Application: "payments", Version: 12, Revision: 1, LockBehaviour: "Fail"},
+					ReleaseTrain: nil,
+				},
+				RequestedByName:     "alice",
+				RequestedByEmail:    "alice@example.com",
+				RequestedByIdentity: "alice@example.com",
+				RequestedByRoles:    []string{"developer"},
+				RequestEslVersion:   5,
+				DecidedAt:           &created,
+				DecidedByName:       "bob",
+				DecidedByEmail:      "bob@example.com",
+				DecidedByIdentity:   "bob@example.com",
+				Reason:              "looks good",
+				DecisionEslVersion:  6,
+			},
+		},
+		AAWaveDeployments: []db.AAWaveDeployment{
+			{
+				App:           "payments",
+				Env:           "production",
+				ConcreteEnv:   "production-de",
+				Position:      0,
+				Target:        types.ReleaseNumbers{Version: uversion(12), Revision: 1},
+				TargetCreated: created,
+				Deployed:      &types.ReleaseNumbers{Version: uversion(12), Revision: 1},
+				DeployedAt:    &created,
+				TransformerID: 7,
+			},
+		},
+		WebhookSubscriptions: []db.WebhookSubscription{
+			{
+				Id:     1,
+				Url:    "https://hooks.example.com/kuberpult",
+				Secret: "s3cret",
+				//exhaustruct:ignore
+				Filter:         db.WebhookFilter{Environments: []string{"production"}},
+				Format:         db.WebhookFormatKuberpult,
+				Created:        created,
+				CreatedByName:  "alice",
+				CreatedByEmail: "alice@example.com",
+			},
+		},
+	}
+}
+
+func TestArchiveRoundtrip(t *testing.T) {
+	created := time.Date(2026, 10, 17, 9, 12, 45, 0, time.UTC)
+	state := testState(created)
+
+	var buf bytes.Buffer
+	if err := WriteArchive(&buf, "v11.0.0", created, state); err != nil {
+		t.Fatalf("unexpected error writing archive: %v", err)
+	}
+	header, actual, err := ReadArchive(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error reading archive: %v", err)
+	}
+	if diff := cmp.Diff(state, actual); diff != "" {
+		t.Errorf("state mismatch (-want, +got):\n%s", diff)
+	}
+	expectedHeader := &Header{
+		FormatVersion:    FormatVersion,
+		CreatedAt:        created,
+		KuberpultVersion: "v11.0.0",
+		Counts: map[string]int{
+			"environments":             1,
+			"apps":                     1,
+			"releases":                 1,
+			"deployments":              1,
+			"environmentLocks":         1,
+			"applicationLocks":         1,
+			"teamLocks":                1,
+			"manifestLocks":            1,
+			"queuedVersions":           1,
+			"brackets":                 1,
+			"releaseRetentionPolicies": 1,
+			"deploymentApprovals":      1,
+			"aaWaveDeployments":        1,
+			"webhookSubscriptions":     1,
+		},
+	}
+	if diff := cmp.Diff(expectedHeader, header); diff != "" {
+		t.Errorf("header mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestReadArchiveRejectsInvalidArchives(t *testing.T) {
+	tcs := []struct {
+		Name          string
+		Entries       map[string]string
+		Order         []string
+		ExpectedError string
+		ExpectedIs    error
+	}{
+		{
+			Name:          "newer format version",
+			Entries:       map[string]string{headerFile: `{"formatVersion":3}`},
+			Order:         []string{headerFile},
+			ExpectedError: "unsupported backup format version: archive has version 3 but this kuberpult supports version 2",
+			ExpectedIs:    ErrUnsupportedFormatVersion,
+		},
+		{
+			Name:          "missing header",
+			Entries:       map[string]string{environmentsFile: `[]`},
+			Order:         []string{environmentsFile},
+			ExpectedError: "invalid backup archive: expected backup.json as first entry but got environments.json",
+			ExpectedIs:    nil,
+		},
+		{
+			Name:          "missing entries",
+			Entries:       map[string]string{headerFile: `{"formatVersion":2}`, environmentsFile: `[]`},
+			Order:         []string{headerFile, environmentsFile},
+			ExpectedError: "invalid backup archive: 13 entries are missing",
+			ExpectedIs:    nil,
+		},
+		{
+			Name:          "unknown entry",
+			Entries:       map[string]string{headerFile: `{"formatVersion":2}`, "secrets.json": `{}`},
+			Order:         []string{headerFile, "secrets.json"},
+			ExpectedError: "invalid backup archive: unknown entry secrets.json",
+			ExpectedIs:    nil,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			tw := tar.NewWriter(gz)
+			for _, name := range tc.Order {
+				//exhaustruct:ignore
+				if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(tc.Entries[name]))}); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := tw.Write([]byte(tc.Entries[name])); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := tw.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if err := gz.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			_, _, err := ReadArchive(&buf)
+			if err == nil {
+				t.Fatalf("expected error %q but got none", tc.ExpectedError)
+			}
+			if diff := cmp.Diff(tc.ExpectedError, err.Error()); diff != "" {
+				t.Errorf("error mismatch (-want, +got):\n%s", diff)
+			}
+			if tc.ExpectedIs != nil && !errors.Is(err, tc.ExpectedIs) {
+				t.Errorf("expected error to wrap %v", tc.ExpectedIs)
+			}
+		})
+	}
+}
@@ -0,0 +1,375 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+// Package backup exports the complete logical state of kuberpult into a portable archive and restores it into an empty database.
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/types"
+)
+
+// ErrDatabaseNotEmpty is returned by Import when the database already contains state and force is not set.
+var ErrDatabaseNotEmpty = errors.New("database is not empty")
+
+// restoreMetadata is the author of all events written during the restore.
+var restoreMetadata = db.ESLMetadata{
+	AuthorName:  "Restore",
+	AuthorEmail: "Restore",
+}
+
+type renderEnvironmentEvent struct {
+	Environment types.EnvName `json:"env"`
+}
+
+// Export reads the complete state in one read-only transaction.
+func Export(ctx context.Context, dbHandler *db.DBHandler) (*State, error) {
+	return db.WithTransactionT(dbHandler, ctx, db.DefaultNumRetries, true, func(ctx context.Context, tx *sql.Tx) (*State, error) {
+		return exportState(ctx, dbHandler, tx)
+	})
+}
+
+func exportState(ctx context.Context, h *db.DBHandler, tx *sql.Tx) (*State, error) {
+	//exhaustruct:ignore
+	state := &State{}
+
+	envNames, err := h.DBSelectAllEnvironments(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("could not read environments: %w", err)
+	}
+	if len(envNames) > 0 {
+		envs, err := h.DBSelectEnvironmentsBatch(ctx, tx, envNames)
+		if err != nil {
+			return nil, fmt.Errorf("could not read environment configs: %w", err)
+		}
+		state.Environments = *envs
+	}
+
+	apps, err := h.DBSelectAllAppsMetadata(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("could not read apps: %w", err)
+	}
+	for _, appName := range slices.Sorted(maps.Keys(apps)) {
+		state.Apps = append(state.Apps, *apps[appName])
+
+		releases, err := h.DBSelectReleasesByAppLatestEslVersion(ctx, tx, appName, false)
+		if err != nil {
+			return nil, fmt.Errorf("could not read releases of app %s: %w", appName, err)
+		}
+		for _, release := range releases {
+			state.Releases = append(state.Releases, *release)
+		}
+
+		deployments, err := h.DBSelectAllLatestDeploymentsForApplication(ctx, tx, appName)
+		if err != nil {
+			return nil, fmt.Errorf("could not read deployments of app %s: %w", appName, err)
+		}
+		for _, envName := range slices.Sorted(maps.Keys(deployments)) {
+			state.Deployments = append(state.Deployments, deployments[envName])
+		}
+	}
+
+	envLocks, err := h.DBSelectAllEnvLocksOfAllEnvs(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("could not read environment locks: %w", err)
+	}
+	for _, envName := range slices.Sorted(maps.Keys(envLocks)) {
+		state.EnvironmentLocks = append(state.EnvironmentLocks, envLocks[envName]...)
+	}
+
+	for _, envName := range envNames {
+		appLocks, err := h.DBSelectAllAppLocksForEnv(ctx, tx, envName)
+		if err != nil {
+			return nil, fmt.Errorf("could not read application locks of environment %s: %w", envName, err)
+		}
+		state.ApplicationLocks = append(state.ApplicationLocks, appLocks...)
+	}
+
+	teamLocks, err := h.DBSelectAllTeamLocksOfAllEnvs(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("could not read team locks: %w", err)
+	}
+	for _, envName := range slices.Sorted(maps.Keys(teamLocks)) {
+		for _, team := range slices.Sorted(maps.Keys(teamLocks[envName])) {
+			state.TeamLocks = append(state.TeamLocks, teamLocks[envName][team]...)
+		}
+	}
+
+	state.ManifestLocks, err = h.DBSelectAllActiveManifestLocks(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("could not read manifest locks: %w", err)
+	}
+
+	queued, err := h.DBSelectAllLatestDeploymentAttempts(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("could not read queued versions: %w", err)
+	}
+	for _, q := range queued {
+		// a deployment attempt without version means that the queue was cleared:
+		if q.ReleaseNumbers.Version != nil {
+			state.QueuedVersions = append(state.QueuedVersions, *q)
+		}
+	}
+
+	state.Brackets, err = db.DBSelectBracketHistoryLatest(ctx, h, tx)
+	if err != nil {
+		return nil, fmt.Errorf("could not read brackets: %w", err)
+	}
+
+	policies, err := h.DBSelectAllReleaseRetentionPolicies(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("could not read release retention policies: %w", err)
+	}
+	for _, policy := range policies {
+		state.ReleaseRetentionPolicies = append(state.ReleaseRetentionPolicies, *policy)
+	}
+
+	approvals, err := h.DBSelectAllDeploymentApprovals(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("could not read deployment approvals: %w", err)
+	}
+	for _, approval := range approvals {
+		state.DeploymentApprovals = append(state.DeploymentApprovals, *approval)
+	}
+
+	waves, err := h.DBSelectAllAAWaveDeployments(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("could not read wave deployments: %w", err)
+	}
+	for _, wave := range waves {
+		state.AAWaveDeployments = append(state.AAWaveDeployments, *wave)
+	}
+
+	state.WebhookSubscriptions, err = h.DBSelectAllWebhookSubscriptions(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("could not read webhook subscriptions: %w", err)
+	}
+	return state, nil
+}
+
+// Import writes the state into the database in one transaction.
+// Unless force is set, it refuses to run if the database already contains environments, releases or deployments.
+// With force, existing rows are overwritten, but rows that are not part of the state are kept.
+// Afterwards, one RenderEnvironment event per environment makes the manifest export service render the restored state.
+func Import(ctx context.Context, dbHandler *db.DBHandler, state *State, force bool) error {
+	return dbHandler.WithTransaction(ctx, false, func(ctx context.Context, tx *sql.Tx) error {
+		if !force {
+			empty, err := isEmpty(ctx, dbHandler, tx)
+			if err != nil {
+				return err
+			}
+			if !empty {
+				return fmt.Errorf("%w: use force to restore anyway", ErrDatabaseNotEmpty)
+			}
+		}
+		return importState(ctx, dbHandler, tx, state)
+	})
+}
+
+func isEmpty(ctx context.Context, h *db.DBHandler, tx *sql.Tx) (bool, error) {
+	hasEnvs, err := h.DBHasAnyEnvironment(ctx, tx)
+	if err != nil {
+		return false, err
+	}
+	hasReleases, err := h.DBHasAnyRelease(ctx, tx, false)
+	if err != nil {
+		return false, err
+	}
+	hasDeployments, err := h.DBHasAnyDeployment(ctx, tx)
+	if err != nil {
+		return false, err
+	}
+	return !hasEnvs && !hasReleases && !hasDeployments, nil
+}
+
+func importState(ctx context.Context, h *db.DBHandler, tx *sql.Tx, state *State) error {
+	// All restored rows reference the migration event, because the original events are not part of the backup:
+	if err := ensureMigrationEvent(ctx, h, tx); err != nil {
+		return err
+	}
+
+	for _, env := range state.Environments {
+		if err := h.DBWriteEnvironment(ctx, tx, env.Name, env.Config); err != nil {
+			return fmt.Errorf("could not write environment %s: %w", env.Name, err)
+		}
+	}
+	for _, app := range state.Apps {
+		if err := h.DBInsertOrUpdateApplication(ctx, tx, app.App, app.StateChange, app.Metadata, app.ArgoBracket); err != nil {
+			return fmt.Errorf("could not write app %s: %w", app.App, err)
+		}
+	}
+	for _, release := range state.Releases {
+		if err := h.DBUpdateOrCreateRelease(ctx, tx, release); err != nil {
+			return fmt.Errorf("could not write release %v of app %s: %w", release.ReleaseNumbers, release.App, err)
+		}
+		if err := h.DBMigrationUpdateReleasesTimestamp(ctx, tx, release.App, release.ReleaseNumbers, release.Created); err != nil {
+			return err
+		}
+	}
+	for _, deployment := range state.Deployments {
+		if deployment.ReleaseNumbers.Version == nil {
+			continue
+		}
+		deployment.TransformerID = 0
+		if err := h.DBUpdateOrCreateDeployment(ctx, tx, deployment); err != nil {
+			return fmt.Errorf("could not write deployment of app %s on environment %s: %w", deployment.App, deployment.Env, err)
+		}
+		err := h.DBMigrationUpdateDeploymentsTimestamp(ctx, tx, deployment.App, *deployment.ReleaseNumbers.Version, deployment.Env, deployment.Created, deployment.ReleaseNumbers.Revision)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, lock := range state.EnvironmentLocks {
+		if err := h.DBWriteEnvironmentLock(ctx, tx, lock.LockID, lock.Env, lock.Metadata); err != nil {
+			return fmt.Errorf("could not write environment lock %s: %w", lock.LockID, err)
+		}
+	}
+	for _, lock := range state.ApplicationLocks {
+		if err := h.DBWriteApplicationLock(ctx, tx, lock.LockID, lock.Env, lock.App, lock.Metadata); err != nil {
+			return fmt.Errorf("could not write application lock %s: %w", lock.LockID, err)
+		}
+	}
+	for _, lock := range state.TeamLocks {
+		if err := h.DBWriteTeamLock(ctx, tx, lock.LockID, lock.Env, lock.Team, lock.Metadata); err != nil {
+			return fmt.Errorf("could not write team lock %s: %w", lock.LockID, err)
+		}
+	}
+	for _, lock := range state.ManifestLocks {
+		if err := h.DBWriteManifestLock(ctx, tx, lock.App, lock.Env, lock.Metadata); err != nil {
+			return fmt.Errorf("could not write manifest lock of app %s on environment %s: %w", lock.App, lock.Env, err)
+		}
+	}
+	for _, queued := range state.QueuedVersions {
+		if err := h.DBWriteDeploymentAttemptWithMetadata(ctx, tx, queued.Env, queued.App, queued.ReleaseNumbers, queued.Metadata); err != nil {
+			return fmt.Errorf("could not write queued version of app %s on environment %s: %w", queued.App, queued.Env, err)
+		}
+	}
+	if state.Brackets != nil {
+		if err := db.DBInsertBracketHistory(ctx, h, tx, *state.Brackets, 0); err != nil {
+			return err
+		}
+	}
+	for _, policy := range state.ReleaseRetentionPolicies {
+		// This also starts the history of the policy, which the manifest export service reads for the restored events:
+		policy.EslVersion = 0
+		if err := h.DBUpsertReleaseRetentionPolicy(ctx, tx, policy); err != nil {
+			return err
+		}
+	}
+	for _, approval := range state.DeploymentApprovals {
+		if err := importDeploymentApproval(ctx, h, tx, approval); err != nil {
+			return err
+		}
+	}
+	if err := importAAWaveDeployments(ctx, h, tx, state.AAWaveDeployments); err != nil {
+		return err
+	}
+	for _, subscription := range state.WebhookSubscriptions {
+		if _, err := h.DBInsertWebhookSubscription(ctx, tx, subscription); err != nil {
+			return err
+		}
+	}
+
+	// The restored apps already have their teams, so the go migration must not run again:
+	hasCutoff, err := h.DBHasGoMigrationCutoff(ctx, tx, db.GoMigration_AppsHistory)
+	if err != nil {
+		return err
+	}
+	if !hasCutoff {
+		if err := h.DBInsertGoMigrationCutoff(ctx, tx, db.GoMigration_AppsHistory); err != nil {
+			return err
+		}
+	}
+
+	envs := make([]types.EnvName, 0, len(state.Environments))
+	for _, env := range state.Environments {
+		envs = append(envs, env.Name)
+	}
+	slices.SortFunc(envs, func(a, b types.EnvName) int { return strings.Compare(string(a), string(b)) })
+	for _, env := range envs {
+		err := h.DBWriteEslEventInternal(ctx, db.EvtRenderEnvironment, tx, renderEnvironmentEvent{Environment: env}, restoreMetadata)
+		if err != nil {
+			return fmt.Errorf("could not write render event for environment %s: %w", env, err)
+		}
+	}
+	return nil
+}
+
+// importDeploymentApproval writes the approval as pending first, because decisions can only be stored on pending approvals.
+func importDeploymentApproval(ctx context.Context, h *db.DBHandler, tx *sql.Tx, approval db.DeploymentApproval) error {
+	// the original events are not part of the backup, see importState:
+	approval.RequestEslVersion = 0
+	approval.DecisionEslVersion = 0
+	pending := approval
+	pending.Status = db.DeploymentApprovalStatusPending
+	if err := h.DBInsertDeploymentApproval(ctx, tx, pending); err != nil {
+		return err
+	}
+	if approval.Status == db.DeploymentApprovalStatusPending {
+		return nil
+	}
+	decided, err := h.DBUpdateDeploymentApprovalDecision(ctx, tx, approval)
+	if err != nil {
+		return err
+	}
+	if !decided {
+		return fmt.Errorf("could not write decision of deployment approval %s", approval.ApprovalId)
+	}
+	return nil
+}
+
+func importAAWaveDeployments(ctx context.Context, h *db.DBHandler, tx *sql.Tx, waves []db.AAWaveDeployment) error {
+	type appEnv struct {
+		app types.AppName
+		env types.EnvName
+	}
+	grouped := map[appEnv][]db.AAWaveDeployment{}
+	keys := []appEnv{}
+	for _, wave := range waves {
+		key := appEnv{app: wave.App, env: wave.Env}
+		if _, ok := grouped[key]; !ok {
+			keys = append(keys, key)
+		}
+		wave.TransformerID = 0
+		grouped[key] = append(grouped[key], wave)
+	}
+	for _, key := range keys {
+		if err := h.DBReplaceAAWaveDeployments(ctx, tx, key.app, key.env, grouped[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ensureMigrationEvent(ctx context.Context, h *db.DBHandler, tx *sql.Tx) error {
+	first, err := h.DBReadEslEventInternal(ctx, tx, true)
+	if err != nil {
+		return err
+	}
+	if first != nil && first.EslVersion == 0 {
+		return nil
+	}
+	return h.DBWriteMigrationsTransformer(ctx, tx)
+}
@@ -0,0 +1,152 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package backup
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/freiheit-com/kuberpult/pkg/db"
+)
+
+func setupDB(t *testing.T, name string) *db.DBHandler {
+	ctx := context.Background()
+	migrationsPath, err := db.CreateMigrationsPath(4)
+	if err != nil {
+		t.Fatalf("migrationspath: %v", err)
+	}
+	dbConfig, err := db.ConnectToPostgresContainer(ctx, t, migrationsPath, t.Name()+name)
+	if err != nil {
+		t.Fatalf("ConnectToPostgresContainer: %v", err)
+	}
+	if err := db.RunDBMigrations(ctx, *dbConfig); err != nil {
+		t.Fatalf("RunDBMigrations: %v", err)
+	}
+	dbHandler, err := db.Connect(ctx, *dbConfig)
+	if err != nil {
+		t.Fatalf("db.Connect: %v", err)
+	}
+	return dbHandler
+}
+
+func countRows(ctx context.Context, h *db.DBHandler, table string) (*int, error) {
+	return db.WithTransactionT(h, ctx, db.DefaultNumRetries, true, func(ctx context.Context, tx *sql.Tx) (*int, error) {
+		var count int
+		err := tx.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s;", table)).Scan(&count)
+		return &count, err
+	})
+}
+
+func selectTables(ctx context.Context, h *db.DBHandler) ([]string, error) {
+	return db.WithTransactionMultipleEntriesT(h, ctx, true, func(ctx context.Context, tx *sql.Tx) ([]string, error) {
+		rows, err := tx.QueryContext(ctx, "SELECT table_name FROM information_schema.tables WHERE table_schema = 'public' AND table_type = 'BASE TABLE' ORDER BY table_name;")
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = rows.Close() }()
+		result := []string{}
+		for rows.Next() {
+			var table string
+			if err := rows.Scan(&table); err != nil {
+				return nil, err
+			}
+			result = append(result, table)
+		}
+		return result, rows.Err()
+	})
+}
+
+func TestBackupCoversAllTables(t *testing.T) {
+	ctx := context.Background()
+	dbHandler := setupDB(t, "")
+
+	tables, err := selectTables(ctx, dbHandler)
+	if err != nil {
+		t.Fatalf("could not read tables: %v", err)
+	}
+	for _, table := range tables {
+		_, backedUp := backedUpTables[table]
+		_, skipped := skippedTables[table]
+		if !backedUp && !skipped {
+			t.Errorf("table %s is neither backed up nor skipped: add it to the State, or add it to skippedTables with the reason why it is not part of the backup", table)
+		}
+		if backedUp && skipped {
+			t.Errorf("table %s is both backed up and skipped", table)
+		}
+	}
+}
+
+func TestBackupRoundtrip(t *testing.T) {
+	ctx := context.Background()
+	created := time.Date(2026, 10, 17, 9, 12, 45, 0, time.UTC)
+	source := setupDB(t, "source")
+	target := setupDB(t, "target")
+
+	if err := Import(ctx, source, testState(created), false); err != nil {
+		t.Fatalf("could not import the test state: %v", err)
+	}
+	for table := range backedUpTables {
+		count, err := countRows(ctx, source, table)
+		if err != nil {
+			t.Fatalf("could not count rows of %s: %v", table, err)
+		}
+		if *count == 0 {
+			t.Errorf("table %s is backed up, but the import did not write it: extend testState and importState", table)
+		}
+	}
+
+	exported, err := Export(ctx, source)
+	if err != nil {
+		t.Fatalf("could not export the source: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := WriteArchive(&buf, "v11.0.0", created, exported); err != nil {
+		t.Fatalf("could not write archive: %v", err)
+	}
+	_, archived, err := ReadArchive(&buf)
+	if err != nil {
+		t.Fatalf("could not read archive: %v", err)
+	}
+	if err := Import(ctx, target, archived, false); err != nil {
+		t.Fatalf("could not import into the target: %v", err)
+	}
+	reexported, err := Export(ctx, target)
+	if err != nil {
+		t.Fatalf("could not export the target: %v", err)
+	}
+
+	// these columns are set to the time of the restore:
+	ignoreRestoreTime := cmp.Options{
+		cmpopts.IgnoreFields(db.DBEnvironment{}, "Created"),
+		cmpopts.IgnoreFields(db.EnvironmentLock{}, "Created"),
+		cmpopts.IgnoreFields(db.ApplicationLock{}, "Created"),
+		cmpopts.IgnoreFields(db.TeamLock{}, "Created"),
+		cmpopts.IgnoreFields(db.ManifestLock{}, "RecordedAt"),
+		cmpopts.IgnoreFields(db.QueuedDeployment{}, "Created"),
+		cmpopts.IgnoreFields(db.BracketRow{}, "CreatedAt"),
+	}
+	if diff := cmp.Diff(exported, reexported, ignoreRestoreTime); diff != "" {
+		t.Errorf("state changed by the backup and restore (-want, +got):\n%s", diff)
+	}
+}
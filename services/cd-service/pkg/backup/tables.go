@@ -0,0 +1,72 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package backup
+
+// backedUpTables maps every table that is part of the backup to the archive entry that restores it.
+// Every table of the database must either be listed here or in skippedTables, see TestBackupCoversAllTables.
+var backedUpTables = map[string]string{
+	"environments":               environmentsFile,
+	"apps":                       appsFile,
+	"releases":                   releasesFile,
+	"deployments":                deploymentsFile,
+	"environment_locks":          environmentLocksFile,
+	"app_locks":                  applicationLocksFile,
+	"team_locks":                 teamLocksFile,
+	"manifest_locks_history":     manifestLocksFile,
+	"deployment_attempts_latest": queuedVersionsFile,
+	"brackets_history":           bracketsFile,
+	"release_retention_policies": releaseRetentionPoliciesFile,
+	// restored as one entry per current policy at the migration event, because the original events are not restored:
+	"release_retention_policies_history": releaseRetentionPoliciesFile,
+	"deployment_approvals":               deploymentApprovalsFile,
+	"aa_wave_deployments":                aaWaveDeploymentsFile,
+	"webhook_subscriptions":              webhookSubscriptionsFile,
+}
+
+// skippedTables maps every table that is not part of the backup to the reason why.
+var skippedTables = map[string]string{
+	"schema_migrations": "written by the database migrations",
+
+	"apps_history":                        "history, restarted by the restore",
+	"apps_teams_history":                  "history, restarted by the restore",
+	"releases_history":                    "history, restarted by the restore",
+	"deployments_history":                 "history, restarted by the restore",
+	"environments_history":                "history, restarted by the restore",
+	"environment_locks_history":           "history, restarted by the restore",
+	"app_locks_history":                   "history, restarted by the restore",
+	"team_locks_history":                  "history, restarted by the restore",
+	"deployment_attempts_history":         "history, restarted by the restore",
+	"event_sourcing_light_failed_history": "history, restarted by the restore",
+
+	"event_sourcing_light":          "events, the restore writes its own events",
+	"event_sourcing_light_failed":   "events, the restore writes its own events",
+	"commit_events":                 "events, the restore writes its own events",
+	"commits_history":               "events, the restore writes its own events",
+	"commit_transaction_timestamps": "events, the restore writes its own events",
+
+	"cutoff":                          "processing state of the manifest export service, restarted with the restored events",
+	"custom_migration_cutoff":         "processing state of the migrations, run again after the restore",
+	"go_migration_cutoff":             "processing state of the migrations, written by the restore",
+	"git_sync_status":                 "processing state of the manifest export service, restarted with the restored events",
+	"argo_cd_events":                  "processing state of the rollout service, received again from Argo CD",
+	"rollout_should_undeploy_cascade": "processing state of the rollout service, refers to events that are not restored",
+	"webhook_deliveries":              "processing state of the webhooks, refers to events that are not restored",
+	"webhook_cursor":                  "processing state of the webhooks, refers to events that are not restored",
+
+	"deployment_attempts": "legacy, replaced by deployment_attempts_latest",
+	"queued_deployments":  "legacy, replaced by deployment_attempts_latest",
+}
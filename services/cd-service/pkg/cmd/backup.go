@@ -0,0 +1,135 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package cmd
+
+import (
+	"context"
+	"flag"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/logging"
+	"github.com/freiheit-com/kuberpult/pkg/valid"
+	"github.com/freiheit-com/kuberpult/services/cd-service/pkg/backup"
+)
+
+// RunBackup exports the complete state of kuberpult into an archive.
+// It only needs the KUBERPULT_DB_* environment variables of the cd-service.
+func RunBackup(args []string) {
+	logging.Wrap(context.Background(), func(ctx context.Context) error {
+		fs := flag.NewFlagSet("backup", flag.ExitOnError)
+		output := fs.String("output", "", "path of the archive to write (required)")
+		_ = fs.Parse(args)
+		if *output == "" {
+			logging.Fatal(ctx, "backup.config", zap.String("details", "--output is required"))
+		}
+
+		dbHandler, _ := connectBackupDB(ctx)
+		state, err := backup.Export(ctx, dbHandler)
+		if err != nil {
+			logging.Fatal(ctx, "backup.export", zap.Error(err))
+		}
+		file, err := os.Create(*output)
+		if err != nil {
+			logging.Fatal(ctx, "backup.create", zap.Error(err))
+		}
+		err = backup.WriteArchive(file, valid.ReadEnvVarWithDefault("KUBERPULT_VERSION", ""), time.Now().UTC(), state)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			logging.Fatal(ctx, "backup.write", zap.Error(err))
+		}
+		logging.Info(ctx, "backup.done", zap.String("output", *output))
+		return nil
+	})
+}
+
+// RunRestore imports an archive written by RunBackup into the database.
+func RunRestore(args []string) {
+	logging.Wrap(context.Background(), func(ctx context.Context) error {
+		fs := flag.NewFlagSet("restore", flag.ExitOnError)
+		input := fs.String("input", "", "path of the archive to restore (required)")
+		force := fs.Bool("force", false, "restore even if the database is not empty")
+		_ = fs.Parse(args)
+		if *input == "" {
+			logging.Fatal(ctx, "restore.config", zap.String("details", "--input is required"))
+		}
+
+		file, err := os.Open(*input)
+		if err != nil {
+			logging.Fatal(ctx, "restore.open", zap.Error(err))
+		}
+		header, state, err := backup.ReadArchive(file)
+		_ = file.Close()
+		if err != nil {
+			logging.Fatal(ctx, "restore.read", zap.Error(err))
+		}
+		logging.Info(ctx, "restore.archive",
+			zap.Time("created", header.CreatedAt),
+			zap.String("kuberpultVersion", header.KuberpultVersion),
+			zap.Any("counts", header.Counts))
+
+		dbHandler, dbCfg := connectBackupDB(ctx)
+		if err := db.RunDBMigrations(ctx, dbCfg); err != nil {
+			logging.Fatal(ctx, "restore.migrations", zap.Error(err))
+		}
+		if err := backup.Import(ctx, dbHandler, state, *force); err != nil {
+			logging.Fatal(ctx, "restore.import", zap.Error(err))
+		}
+		logging.Info(ctx, "restore.done", zap.String("input", *input))
+		return nil
+	})
+}
+
+func connectBackupDB(ctx context.Context) (*db.DBHandler, db.DBConfig) {
+	maxIdle, err := valid.ReadEnvVarUIntWithDefault("KUBERPULT_DB_MAX_IDLE_CONNECTIONS", 1)
+	if err != nil {
+		logging.Fatal(ctx, "backup.config", zap.Error(err))
+	}
+	maxOpen, err := valid.ReadEnvVarUIntWithDefault("KUBERPULT_DB_MAX_OPEN_CONNECTIONS", 1)
+	if err != nil {
+		logging.Fatal(ctx, "backup.config", zap.Error(err))
+	}
+	dbCfg := db.DBConfig{
+		DbHost:         valid.ReadEnvVarWithDefault("KUBERPULT_DB_LOCATION", "/kp/database"),
+		DbPort:         valid.ReadEnvVarWithDefault("KUBERPULT_DB_AUTH_PROXY_PORT", "5432"),
+		DriverName:     "postgres",
+		DbName:         valid.ReadEnvVarWithDefault("KUBERPULT_DB_NAME", ""),
+		DbPassword:     valid.ReadEnvVarWithDefault("KUBERPULT_DB_USER_PASSWORD", ""),
+		DbUser:         valid.ReadEnvVarWithDefault("KUBERPULT_DB_USER_NAME", ""),
+		MigrationsPath: valid.ReadEnvVarWithDefault("KUBERPULT_DB_MIGRATIONS_LOCATION", ""),
+		SSLMode:        valid.ReadEnvVarWithDefault("KUBERPULT_DB_SSL_MODE", "verify-full"),
+
+		MaxIdleConnections: maxIdle,
+		MaxOpenConnections: maxOpen,
+
+		DatadogEnabled:     false,
+		DatadogServiceName: datadogNameCd,
+	}
+	dbHandler, err := db.Connect(ctx, dbCfg)
+	if err != nil {
+		logging.Fatal(ctx, "Error establishing DB connection: ", zap.Error(err))
+	}
+	if err := dbHandler.DB.Ping(); err != nil {
+		logging.Fatal(ctx, "Error pinging DB: ", zap.Error(err))
+	}
+	return dbHandler, dbCfg
+}
@@ -42,11 +42,19 @@ import (
 	"github.com/freiheit-com/kuberpult/pkg/interceptors"
 	"github.com/freiheit-com/kuberpult/pkg/logger"
 	"github.com/freiheit-com/kuberpult/pkg/logging"
+	"github.com/freiheit-com/kuberpult/pkg/metrics"
 	"github.com/freiheit-com/kuberpult/pkg/setup"
 	"github.com/freiheit-com/kuberpult/pkg/tracing"
+	"github.com/freiheit-com/kuberpult/pkg/types"
 	"github.com/freiheit-com/kuberpult/pkg/valid"
+	"github.com/freiheit-com/kuberpult/services/cd-service/pkg/dora"
+	"github.com/freiheit-com/kuberpult/services/cd-service/pkg/gates"
+	"github.com/freiheit-com/kuberpult/services/cd-service/pkg/lockexpiry"
+	"github.com/freiheit-com/kuberpult/services/cd-service/pkg/policy"
 	"github.com/freiheit-com/kuberpult/services/cd-service/pkg/repository"
 	"github.com/freiheit-com/kuberpult/services/cd-service/pkg/service"
+	"github.com/freiheit-com/kuberpult/services/cd-service/pkg/waves"
+	"github.com/freiheit-com/kuberpult/services/cd-service/pkg/webhooks"
 )
 
 const (
@@ -55,6 +63,9 @@ const (
 	maxReleaseVersionsLimit = 30
 
 	megaBytes int = 1024 * 1024
+
+	// aaWavesCheckInterval is how often we check if the next concrete environment of a wave can be deployed
+	aaWavesCheckInterval = 30 * time.Second
 )
 
 type Config struct {
@@ -109,6 +120,37 @@ type Config struct {
 	MinorRegexes string
 
 	ExperimentalBracketsClusters []string
+
+	LockExpiryEnabled  bool
+	LockExpiryDryRun   bool
+	LockExpiryInterval time.Duration
+
+	DeploymentApprovalEnabled      bool
+	DeploymentApprovalEnvironments []string
+	DeploymentApprovalExpiry       time.Duration
+	DeploymentApprovalRetention    time.Duration
+
+	// PersistArgoEvents is true if the rollout-service writes the Argo CD events to the database,
+	// which active/active environments that are rolled out in waves need.
+	PersistArgoEvents bool
+
+	PolicyPath           string
+	PolicyReloadInterval time.Duration
+
+	DeploymentGateTimeout       time.Duration
+	DeploymentGateRetries       uint
+	DeploymentGateCacheDuration time.Duration
+
+	WebhooksEnabled        bool
+	WebhooksInterval       time.Duration
+	WebhooksTimeout        time.Duration
+	WebhooksMaxAttempts    uint
+	WebhooksInitialBackoff time.Duration
+	WebhooksMaxBackoff     time.Duration
+
+	DoraMetricsEnabled  bool
+	DoraMetricsWindow   time.Duration
+	DoraMetricsInterval time.Duration
 }
 
 func (c *Config) storageBackend() repository.StorageBackend {
@@ -208,6 +250,81 @@ func parseEnvVars() (_ *Config, err error) {
 		return nil, err
 	}
 
+	c.LockExpiryEnabled = valid.ReadEnvVarBoolWithDefault("KUBERPULT_LOCK_EXPIRY_ENABLED", false)
+	c.LockExpiryDryRun = valid.ReadEnvVarBoolWithDefault("KUBERPULT_LOCK_EXPIRY_DRY_RUN", false)
+	c.LockExpiryInterval, err = valid.ReadEnvVarDurationWithDefault("KUBERPULT_LOCK_EXPIRY_INTERVAL", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	c.PersistArgoEvents = valid.ReadEnvVarBoolWithDefault("KUBERPULT_PERSIST_ARGO_EVENTS", false)
+	c.DeploymentApprovalEnabled = valid.ReadEnvVarBoolWithDefault("KUBERPULT_DEPLOYMENT_APPROVAL_ENABLED", false)
+	if c.DeploymentApprovalEnabled {
+		c.DeploymentApprovalEnvironments, err = valid.ReadEnvVarAsList("KUBERPULT_DEPLOYMENT_APPROVAL_ENVIRONMENTS", ",")
+		if err != nil {
+			return nil, err
+		}
+		c.DeploymentApprovalExpiry, err = valid.ReadEnvVarDurationWithDefault("KUBERPULT_DEPLOYMENT_APPROVAL_EXPIRY", 24*time.Hour)
+		if err != nil {
+			return nil, err
+		}
+		c.DeploymentApprovalRetention, err = valid.ReadEnvVarDurationWithDefault("KUBERPULT_DEPLOYMENT_APPROVAL_RETENTION", 30*24*time.Hour)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c.PolicyPath = valid.ReadEnvVarWithDefault("KUBERPULT_POLICY_PATH", "")
+	c.PolicyReloadInterval, err = valid.ReadEnvVarDurationWithDefault("KUBERPULT_POLICY_RELOAD_INTERVAL", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	c.DeploymentGateTimeout, err = valid.ReadEnvVarDurationWithDefault("KUBERPULT_DEPLOYMENT_GATE_TIMEOUT", gates.DefaultConfig.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	c.DeploymentGateRetries, err = valid.ReadEnvVarUIntWithDefault("KUBERPULT_DEPLOYMENT_GATE_RETRIES", gates.DefaultConfig.Retries)
+	if err != nil {
+		return nil, err
+	}
+	c.DeploymentGateCacheDuration, err = valid.ReadEnvVarDurationWithDefault("KUBERPULT_DEPLOYMENT_GATE_CACHE_DURATION", gates.DefaultConfig.CacheDuration)
+	if err != nil {
+		return nil, err
+	}
+
+	c.WebhooksEnabled = valid.ReadEnvVarBoolWithDefault("KUBERPULT_WEBHOOKS_ENABLED", false)
+	c.WebhooksInterval, err = valid.ReadEnvVarDurationWithDefault("KUBERPULT_WEBHOOKS_INTERVAL", webhooks.DefaultConfig.Interval)
+	if err != nil {
+		return nil, err
+	}
+	c.WebhooksTimeout, err = valid.ReadEnvVarDurationWithDefault("KUBERPULT_WEBHOOKS_TIMEOUT", webhooks.DefaultConfig.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	c.WebhooksMaxAttempts, err = valid.ReadEnvVarUIntWithDefault("KUBERPULT_WEBHOOKS_MAX_ATTEMPTS", webhooks.DefaultConfig.MaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	c.WebhooksInitialBackoff, err = valid.ReadEnvVarDurationWithDefault("KUBERPULT_WEBHOOKS_INITIAL_BACKOFF", webhooks.DefaultConfig.InitialBackoff)
+	if err != nil {
+		return nil, err
+	}
+	c.WebhooksMaxBackoff, err = valid.ReadEnvVarDurationWithDefault("KUBERPULT_WEBHOOKS_MAX_BACKOFF", webhooks.DefaultConfig.MaxBackoff)
+	if err != nil {
+		return nil, err
+	}
+
+	c.DoraMetricsEnabled = valid.ReadEnvVarBoolWithDefault("KUBERPULT_DORA_METRICS_ENABLED", false)
+	c.DoraMetricsWindow, err = valid.ReadEnvVarDurationWithDefault("KUBERPULT_DORA_METRICS_WINDOW", 30*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	c.DoraMetricsInterval, err = valid.ReadEnvVarDurationWithDefault("KUBERPULT_DORA_METRICS_INTERVAL", 10*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
 	return &c, nil
 }
 
@@ -243,6 +360,14 @@ func RunServer() {
 			defer profiler.Stop()
 		}
 
+		var policies *policy.Engine
+		if c.PolicyPath != "" {
+			policies, err = policy.NewEngine(ctx, c.PolicyPath)
+			if err != nil {
+				logging.Fatal(ctx, "config.policy.error", zap.Error(err))
+			}
+		}
+
 		var reader auth.GrpcContextReader
 		if c.DexMock {
 			if !c.DexEnabled {
@@ -385,7 +510,19 @@ func RunServer() {
 			AllowLongAppNames:    c.AllowLongAppNames,
 			ArgoCdGenerateFiles:  c.ArgoCdGenerateFiles,
 			AllowBracketMoves:    c.AllowBracketMoves,
-			DBHandler:            dbHandler,
+			DeploymentGates: gates.New(gates.Config{
+				Timeout:       c.DeploymentGateTimeout,
+				Retries:       c.DeploymentGateRetries,
+				CacheDuration: c.DeploymentGateCacheDuration,
+			}),
+			WriteWebhookEvents: c.WebhooksEnabled,
+			DeploymentApproval: repository.DeploymentApprovalConfig{
+				Enabled:      c.DeploymentApprovalEnabled,
+				Environments: types.StringsToEnvNames(c.DeploymentApprovalEnvironments),
+				Expiry:       c.DeploymentApprovalExpiry,
+				Retention:    c.DeploymentApprovalRetention,
+			},
+			DBHandler: dbHandler,
 		}
 
 		repo, err := repository.New(ctx, cfg)
@@ -401,6 +538,93 @@ func RunServer() {
 
 		span.Finish()
 
+		backgroundTasks := []setup.BackgroundTaskConfig{
+			{
+				Shutdown: nil,
+				Name:     "ddmetrics",
+				Run: func(ctx context.Context, reporter *setup.HealthReporter) error {
+					reporter.ReportReady("sending metrics")
+					repository.RegularlySendDatadogMetrics(repo, 300, func(repository2 repository.Repository, even bool) {
+						repository.GetRepositoryStateAndUpdateMetrics(ctx, repository2, even)
+					})
+					return nil
+				},
+			},
+		}
+		if c.LockExpiryEnabled {
+			backgroundTasks = append(backgroundTasks, setup.BackgroundTaskConfig{
+				Shutdown: nil,
+				Name:     "lock-expiry",
+				Run: func(ctx context.Context, reporter *setup.HealthReporter) error {
+					return lockexpiry.ExpireLocks(ctx, repo, dbHandler, lockexpiry.Config{
+						Interval: c.LockExpiryInterval,
+						DryRun:   c.LockExpiryDryRun,
+					}, reporter)
+				},
+			})
+		}
+
+		backgroundTasks = append(backgroundTasks, setup.BackgroundTaskConfig{
+			Shutdown: nil,
+			Name:     "promote-aa-waves",
+			Run: func(ctx context.Context, reporter *setup.HealthReporter) error {
+				promoter := &waves.Promoter{
+					Repository: repo,
+					DBHandler:  dbHandler,
+					Interval:   aaWavesCheckInterval,
+				}
+				return promoter.Run(ctx, reporter)
+			},
+		})
+
+		if c.DeploymentApprovalEnabled {
+			backgroundTasks = append(backgroundTasks, setup.BackgroundTaskConfig{
+				Shutdown: nil,
+				Name:     "deployment-approval-expiry",
+				Run: func(ctx context.Context, reporter *setup.HealthReporter) error {
+					return repository.ExpireDeploymentApprovals(ctx, dbHandler, cfg.DeploymentApproval, reporter)
+				},
+			})
+		}
+
+		if policies != nil {
+			backgroundTasks = append(backgroundTasks, setup.BackgroundTaskConfig{
+				Shutdown: nil,
+				Name:     "policy-reload",
+				Run: func(ctx context.Context, reporter *setup.HealthReporter) error {
+					return policies.ReloadRegularly(ctx, c.PolicyReloadInterval, reporter)
+				},
+			})
+		}
+
+		if c.WebhooksEnabled {
+			webhookWorker := webhooks.New(dbHandler, webhooks.Config{
+				Interval:       c.WebhooksInterval,
+				Timeout:        c.WebhooksTimeout,
+				MaxAttempts:    c.WebhooksMaxAttempts,
+				InitialBackoff: c.WebhooksInitialBackoff,
+				MaxBackoff:     c.WebhooksMaxBackoff,
+			})
+			backgroundTasks = append(backgroundTasks, setup.BackgroundTaskConfig{
+				Shutdown: nil,
+				Name:     "webhooks",
+				Run:      webhookWorker.Run,
+			})
+		}
+
+		if c.DoraMetricsEnabled && dbHandler != nil {
+			backgroundTasks = append(backgroundTasks, setup.BackgroundTaskConfig{
+				Shutdown: nil,
+				Name:     "dora-metrics",
+				Run: func(ctx context.Context, reporter *setup.HealthReporter) error {
+					return dora.ExportMetrics(ctx, dbHandler, metrics.FromContext(ctx), dora.MetricsConfig{
+						Window:   c.DoraMetricsWindow,
+						Interval: c.DoraMetricsInterval,
+					}, reporter)
+				},
+			})
+		}
+
 		// Shutdown channel is used to terminate server side streams.
 		shutdownCh := make(chan struct{})
 		setup.Run(ctx, setup.ServerConfig{
@@ -442,6 +666,8 @@ func RunServer() {
 							WriteCommitData:      c.GitWriteCommitData,
 							AllowedCILinkDomains: c.AllowedDomains,
 							LockType:             lockType,
+							Policies:             policies,
+							PersistArgoEvents:    c.PersistArgoEvents,
 						},
 					})
 
@@ -466,26 +692,36 @@ func RunServer() {
 						},
 					})
 					api.RegisterEslServiceServer(srv, &service.EslServiceServer{Repository: repo})
+					api.RegisterReleaseNotesServiceServer(srv, &service.ReleaseNotesServer{Repository: repo})
 					reflection.Register(srv)
 
 					if dbHandler != nil {
 						api.RegisterCommitDeploymentServiceServer(srv, &service.CommitDeploymentServer{DBHandler: dbHandler})
-					}
-				},
-			},
-			Background: []setup.BackgroundTaskConfig{
-				{
-					Shutdown: nil,
-					Name:     "ddmetrics",
-					Run: func(ctx context.Context, reporter *setup.HealthReporter) error {
-						reporter.ReportReady("sending metrics")
-						repository.RegularlySendDatadogMetrics(repo, 300, func(repository2 repository.Repository, even bool) {
-							repository.GetRepositoryStateAndUpdateMetrics(ctx, repository2, even)
+						api.RegisterCloudEventServiceServer(srv, &service.CloudEventServer{
+							DBHandler: dbHandler,
+							Shutdown:  shutdownCh,
 						})
-						return nil
-					},
+						api.RegisterWebhookServiceServer(srv, &service.WebhookServer{
+							DBHandler: dbHandler,
+							RBACConfig: auth.RBACConfig{
+								DexEnabled: c.DexEnabled,
+								Policy:     dexRbacPolicy,
+								Team:       dexRbacTeam,
+							},
+						})
+						api.RegisterDoraMetricsServiceServer(srv, &service.DoraMetricsServer{DBHandler: dbHandler})
+						api.RegisterAuditServiceServer(srv, &service.AuditServer{
+							DBHandler: dbHandler,
+							RBACConfig: auth.RBACConfig{
+								DexEnabled: c.DexEnabled,
+								Policy:     dexRbacPolicy,
+								Team:       dexRbacTeam,
+							},
+						})
+					}
 				},
 			},
+			Background: backgroundTasks,
 			Shutdown: func(ctx context.Context) error {
 				close(shutdownCh)
 				return nil
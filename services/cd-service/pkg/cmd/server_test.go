@@ -127,6 +127,18 @@ func TestEnvVarParsing(t *testing.T) {
 				DbSslMode:            "verify-full",
 
 				ExperimentalBracketsClusters: []string{},
+				LockExpiryInterval:           5 * time.Minute,
+				PolicyReloadInterval:         time.Minute,
+				DeploymentGateTimeout:        5 * time.Second,
+				DeploymentGateRetries:        2,
+				DeploymentGateCacheDuration:  0,
+				WebhooksInterval:             10 * time.Second,
+				WebhooksTimeout:              10 * time.Second,
+				WebhooksMaxAttempts:          10,
+				WebhooksInitialBackoff:       30 * time.Second,
+				WebhooksMaxBackoff:           time.Hour,
+				DoraMetricsWindow:            30 * 24 * time.Hour,
+				DoraMetricsInterval:          10 * time.Minute,
 			},
 			ExpectedError: nil,
 		},
@@ -217,6 +229,18 @@ func TestEnvVarParsing(t *testing.T) {
 				DexEnabled: false,
 
 				ExperimentalBracketsClusters: []string{},
+				LockExpiryInterval:           5 * time.Minute,
+				PolicyReloadInterval:         time.Minute,
+				DeploymentGateTimeout:        5 * time.Second,
+				DeploymentGateRetries:        2,
+				DeploymentGateCacheDuration:  0,
+				WebhooksInterval:             10 * time.Second,
+				WebhooksTimeout:              10 * time.Second,
+				WebhooksMaxAttempts:          10,
+				WebhooksInitialBackoff:       30 * time.Second,
+				WebhooksMaxBackoff:           time.Hour,
+				DoraMetricsWindow:            30 * 24 * time.Hour,
+				DoraMetricsInterval:          10 * time.Minute,
 			},
 			ExpectedError: nil,
 		},
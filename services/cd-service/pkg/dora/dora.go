@@ -0,0 +1,296 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+// Package dora computes the DORA metrics (deployment frequency, lead time, change failure rate
+// and time to restore) from the deployment history in the database.
+//
+// A deployment is counted if it deploys a new version of an app to an environment; redeployments
+// of the same version and undeployments are not counted. A deployment failed if the next deployment
+// of the app on that environment is an older version (a rollback), or if it is still deployed and
+// Argo CD reports the app as degraded. Since kuberpult does not know when a commit was made, the
+// lead time is measured from the creation of the release.
+package dora
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"slices"
+	"time"
+
+	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/types"
+)
+
+// Input is the data the metrics are computed from.
+type Input struct {
+	From time.Time
+	To   time.Time
+	// Deployments contains the deployments between From and To and the last deployment before From
+	// of every app and environment, in the order they happened.
+	Deployments []db.Deployment
+	Releases    map[db.ReleaseKey]db.DeployedRelease
+	Teams       map[types.AppName]string
+	// ArgoEvents contains the latest Argo CD event of every app and environment.
+	ArgoEvents []*db.ArgoEvent
+}
+
+// Filter restricts and groups the deployments. Empty fields match everything.
+type Filter struct {
+	Team        string
+	Application string
+	Environment string
+	GroupBy     []api.GetDoraMetricsRequest_GroupBy
+}
+
+// ReadInput reads everything that is needed to compute the metrics between from and to.
+func ReadInput(ctx context.Context, dbHandler *db.DBHandler, transaction *sql.Tx, from, to time.Time) (*Input, error) {
+	deployments, err := dbHandler.DBSelectDeploymentsInTimeWindow(ctx, transaction, from, to)
+	if err != nil {
+		return nil, err
+	}
+	releases, err := dbHandler.DBSelectReleasesDeployedInTimeWindow(ctx, transaction, from, to)
+	if err != nil {
+		return nil, err
+	}
+	apps, err := dbHandler.DBSelectAllAppsMetadata(ctx, transaction)
+	if err != nil {
+		return nil, err
+	}
+	teams := make(map[types.AppName]string, len(apps))
+	for name, app := range apps {
+		teams[name] = app.Metadata.Team
+	}
+	argoEvents, err := dbHandler.DBSelectAllArgoEvents(ctx, transaction)
+	if err != nil {
+		return nil, err
+	}
+	return &Input{
+		From:        from,
+		To:          to,
+		Deployments: deployments,
+		Releases:    releases,
+		Teams:       teams,
+		ArgoEvents:  argoEvents,
+	}, nil
+}
+
+// change is a deployment of a new version.
+type change struct {
+	App           types.AppName
+	Env           types.EnvName
+	Team          string
+	Version       types.ReleaseNumbers
+	Created       time.Time
+	LeadTime      *time.Duration
+	Failed        bool
+	TimeToRestore *time.Duration
+}
+
+type appEnv struct {
+	App types.AppName
+	Env types.EnvName
+}
+
+// argoEvent is the part of the events that the rollout-service persists that we need to know if an app is degraded
+type argoEvent struct {
+	HealthStatusCode string
+	OperationState   *struct {
+		FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	}
+}
+
+// changes returns the deployments of new versions between From and To.
+func changes(input *Input) []*change {
+	latest := map[appEnv]*change{}
+	result := []*change{}
+	for _, deployment := range input.Deployments {
+		key := appEnv{App: deployment.App, Env: deployment.Env}
+		previous := latest[key]
+		if deployment.ReleaseNumbers.Version == nil {
+			delete(latest, key)
+			continue
+		}
+		release, releaseKnown := input.Releases[db.ReleaseKey{
+			AppName:        deployment.App,
+			ReleaseVersion: *deployment.ReleaseNumbers.Version,
+			Revision:       deployment.ReleaseNumbers.Revision,
+		}]
+		if releaseKnown && release.UndeployVersion {
+			delete(latest, key)
+			continue
+		}
+		if previous != nil && types.Equal(previous.Version, deployment.ReleaseNumbers) {
+			continue
+		}
+		current := &change{
+			App:           deployment.App,
+			Env:           deployment.Env,
+			Team:          input.Teams[deployment.App],
+			Version:       deployment.ReleaseNumbers,
+			Created:       deployment.Created,
+			LeadTime:      nil,
+			Failed:        false,
+			TimeToRestore: nil,
+		}
+		if releaseKnown && !release.Created.After(deployment.Created) {
+			leadTime := deployment.Created.Sub(release.Created)
+			current.LeadTime = &leadTime
+		}
+		if previous != nil && types.Greater(previous.Version, deployment.ReleaseNumbers) {
+			previous.Failed = true
+			timeToRestore := deployment.Created.Sub(previous.Created)
+			previous.TimeToRestore = &timeToRestore
+		}
+		latest[key] = current
+		if !deployment.Created.Before(input.From) {
+			result = append(result, current)
+		}
+	}
+	for _, event := range input.ArgoEvents {
+		current := latest[appEnv{App: types.AppName(event.App), Env: types.EnvName(event.Env)}]
+		if current != nil && !current.Failed && isDegraded(event, current.Created) {
+			current.Failed = true
+		}
+	}
+	return result
+}
+
+// isDegraded returns true if Argo CD reports the app as degraded after a sync that finished after the deployment.
+func isDegraded(event *db.ArgoEvent, deployedAt time.Time) bool {
+	if event.Discarded {
+		return false
+	}
+	var parsed argoEvent
+	if err := json.Unmarshal(event.JsonEvent, &parsed); err != nil {
+		return false
+	}
+	if parsed.HealthStatusCode != "Degraded" {
+		return false
+	}
+	if parsed.OperationState == nil || parsed.OperationState.FinishedAt == nil {
+		return false
+	}
+	return parsed.OperationState.FinishedAt.After(deployedAt)
+}
+
+func (f Filter) matches(c *change) bool {
+	return (f.Team == "" || f.Team == c.Team) &&
+		(f.Application == "" || f.Application == string(c.App)) &&
+		(f.Environment == "" || f.Environment == string(c.Env))
+}
+
+type groupKey struct {
+	Team        string
+	Application string
+	Environment string
+}
+
+func (f Filter) group(c *change) groupKey {
+	key := groupKey{Team: "", Application: "", Environment: ""}
+	for _, groupBy := range f.GroupBy {
+		switch groupBy {
+		case api.GetDoraMetricsRequest_TEAM:
+			key.Team = c.Team
+		case api.GetDoraMetricsRequest_APPLICATION:
+			key.Application = string(c.App)
+		case api.GetDoraMetricsRequest_ENVIRONMENT:
+			key.Environment = string(c.Env)
+		case api.GetDoraMetricsRequest_NONE:
+		}
+	}
+	return key
+}
+
+// Compute returns the metrics of the deployments that match the filter, one entry per group.
+// Without GroupBy, there is exactly one entry.
+func Compute(input *Input, filter Filter) []*api.DoraMetrics {
+	groups := map[groupKey][]*change{}
+	if len(filter.GroupBy) == 0 {
+		groups[groupKey{Team: "", Application: "", Environment: ""}] = []*change{}
+	}
+	for _, c := range changes(input) {
+		if !filter.matches(c) {
+			continue
+		}
+		key := filter.group(c)
+		groups[key] = append(groups[key], c)
+	}
+	days := input.To.Sub(input.From).Hours() / 24
+	result := make([]*api.DoraMetrics, 0, len(groups))
+	for key, group := range groups {
+		result = append(result, aggregate(key, group, days))
+	}
+	slices.SortFunc(result, func(a, b *api.DoraMetrics) int {
+		return cmp.Or(
+			cmp.Compare(a.Team, b.Team),
+			cmp.Compare(a.Application, b.Application),
+			cmp.Compare(a.Environment, b.Environment),
+		)
+	})
+	return result
+}
+
+func aggregate(key groupKey, group []*change, days float64) *api.DoraMetrics {
+	result := &api.DoraMetrics{
+		Team:                 key.Team,
+		Application:          key.Application,
+		Environment:          key.Environment,
+		Deployments:          uint64(len(group)),
+		DeploymentsPerDay:    0,
+		LeadTimeSeconds:      nil,
+		FailedDeployments:    0,
+		ChangeFailureRate:    0,
+		TimeToRestoreSeconds: nil,
+	}
+	if days > 0 {
+		result.DeploymentsPerDay = float64(len(group)) / days
+	}
+	leadTimes := []time.Duration{}
+	timesToRestore := []time.Duration{}
+	for _, c := range group {
+		if c.LeadTime != nil {
+			leadTimes = append(leadTimes, *c.LeadTime)
+		}
+		if c.Failed {
+			result.FailedDeployments++
+		}
+		if c.TimeToRestore != nil {
+			timesToRestore = append(timesToRestore, *c.TimeToRestore)
+		}
+	}
+	if len(group) > 0 {
+		result.ChangeFailureRate = float64(result.FailedDeployments) / float64(len(group))
+	}
+	result.LeadTimeSeconds = medianSeconds(leadTimes)
+	result.TimeToRestoreSeconds = medianSeconds(timesToRestore)
+	return result
+}
+
+func medianSeconds(durations []time.Duration) *float64 {
+	if len(durations) == 0 {
+		return nil
+	}
+	slices.Sort(durations)
+	middle := len(durations) / 2
+	median := durations[middle].Seconds()
+	if len(durations)%2 == 0 {
+		median = (durations[middle-1].Seconds() + median) / 2
+	}
+	return &median
+}
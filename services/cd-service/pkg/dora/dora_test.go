@@ -0,0 +1,236 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package dora
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/types"
+)
+
+var day = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func makeDeployment(app, env string, version uint64, created time.Time) db.Deployment {
+	return db.Deployment{
+		Created:        created,
+		App:            types.AppName(app),
+		Env:            types.EnvName(env),
+		ReleaseNumbers: types.MakeReleaseNumberVersion(version),
+		Metadata:       db.DeploymentMetadata{},
+		TransformerID:  0,
+	}
+}
+
+func makeRelease(app string, version uint64) db.ReleaseKey {
+	return db.ReleaseKey{AppName: types.AppName(app), ReleaseVersion: version, Revision: 0}
+}
+
+func makeArgoEvent(app, env, health string, finishedAt time.Time) *db.ArgoEvent {
+	return &db.ArgoEvent{
+		App:       app,
+		Env:       env,
+		JsonEvent: fmt.Appendf(nil, `{"HealthStatusCode":%q,"OperationState":{"finishedAt":%q}}`, health, finishedAt.Format(time.RFC3339)),
+		Discarded: false,
+	}
+}
+
+func seconds(d time.Duration) *float64 {
+	s := d.Seconds()
+	return &s
+}
+
+// testInput contains ten days of deployments of two apps:
+//   - payments (team finance) on production is deployed in version 2, redeployed, rolled back to 1 and then
+//     deployed in version 3.
+//   - shop (team sales) on staging is deployed in version 1, which Argo CD reports as degraded afterwards.
+func testInput() *Input {
+	return &Input{
+		From: day,
+		To:   day.Add(10 * 24 * time.Hour),
+		Deployments: []db.Deployment{
+			makeDeployment("payments", "production", 1, day.Add(-48*time.Hour)),
+			makeDeployment("payments", "production", 2, day.Add(24*time.Hour)),
+			makeDeployment("payments", "production", 2, day.Add(48*time.Hour)),
+			makeDeployment("payments", "production", 1, day.Add(72*time.Hour)),
+			makeDeployment("payments", "production", 3, day.Add(96*time.Hour)),
+			makeDeployment("shop", "staging", 1, day.Add(120*time.Hour)),
+		},
+		Releases: map[db.ReleaseKey]db.DeployedRelease{
+			makeRelease("payments", 1): {Created: day.Add(-72 * time.Hour), UndeployVersion: false},
+			makeRelease("payments", 2): {Created: day.Add(12 * time.Hour), UndeployVersion: false},
+			makeRelease("payments", 3): {Created: day.Add(95 * time.Hour), UndeployVersion: false},
+			makeRelease("shop", 1):     {Created: day.Add(118 * time.Hour), UndeployVersion: false},
+		},
+		Teams: map[types.AppName]string{
+			"payments": "finance",
+			"shop":     "sales",
+		},
+		ArgoEvents: []*db.ArgoEvent{
+			makeArgoEvent("payments", "production", "Healthy", day.Add(97*time.Hour)),
+			makeArgoEvent("shop", "staging", "Degraded", day.Add(121*time.Hour)),
+		},
+	}
+}
+
+func TestCompute(t *testing.T) {
+	tcs := []struct {
+		Name     string
+		Input    *Input
+		Filter   Filter
+		Expected []*api.DoraMetrics
+	}{
+		{
+			Name:   "all deployments",
+			Input:  testInput(),
+			Filter: Filter{},
+			Expected: []*api.DoraMetrics{
+				{
+					Deployments:       4,
+					DeploymentsPerDay: 0.4,
+					// lead times are 1h, 2h, 12h and 144h
+					LeadTimeSeconds:      seconds(7 * time.Hour),
+					FailedDeployments:    2,
+					ChangeFailureRate:    0.5,
+					TimeToRestoreSeconds: seconds(48 * time.Hour),
+				},
+			},
+		},
+		{
+			Name:  "grouped by team",
+			Input: testInput(),
+			Filter: Filter{
+				GroupBy: []api.GetDoraMetricsRequest_GroupBy{api.GetDoraMetricsRequest_TEAM},
+			},
+			Expected: []*api.DoraMetrics{
+				{
+					Team:                 "finance",
+					Deployments:          3,
+					DeploymentsPerDay:    0.3,
+					LeadTimeSeconds:      seconds(12 * time.Hour),
+					FailedDeployments:    1,
+					ChangeFailureRate:    1.0 / 3,
+					TimeToRestoreSeconds: seconds(48 * time.Hour),
+				},
+				{
+					Team:              "sales",
+					Deployments:       1,
+					DeploymentsPerDay: 0.1,
+					LeadTimeSeconds:   seconds(2 * time.Hour),
+					FailedDeployments: 1,
+					ChangeFailureRate: 1,
+				},
+			},
+		},
+		{
+			Name:  "filtered by environment and grouped by application and environment",
+			Input: testInput(),
+			Filter: Filter{
+				Environment: "staging",
+				GroupBy:     []api.GetDoraMetricsRequest_GroupBy{api.GetDoraMetricsRequest_APPLICATION, api.GetDoraMetricsRequest_ENVIRONMENT},
+			},
+			Expected: []*api.DoraMetrics{
+				{
+					Application:       "shop",
+					Environment:       "staging",
+					Deployments:       1,
+					DeploymentsPerDay: 0.1,
+					LeadTimeSeconds:   seconds(2 * time.Hour),
+					FailedDeployments: 1,
+					ChangeFailureRate: 1,
+				},
+			},
+		},
+		{
+			Name:  "no matching deployments",
+			Input: testInput(),
+			Filter: Filter{
+				Team: "marketing",
+			},
+			Expected: []*api.DoraMetrics{
+				{},
+			},
+		},
+		{
+			Name: "undeployments are not counted",
+			Input: &Input{
+				From: day,
+				To:   day.Add(24 * time.Hour),
+				Deployments: []db.Deployment{
+					makeDeployment("legacy", "production", 2, day.Add(1*time.Hour)),
+					makeDeployment("legacy", "production", 3, day.Add(2*time.Hour)),
+					makeDeployment("legacy", "production", 2, day.Add(3*time.Hour)),
+				},
+				Releases: map[db.ReleaseKey]db.DeployedRelease{
+					makeRelease("legacy", 2): {Created: day, UndeployVersion: false},
+					makeRelease("legacy", 3): {Created: day, UndeployVersion: true},
+				},
+				Teams:      map[types.AppName]string{},
+				ArgoEvents: []*db.ArgoEvent{},
+			},
+			Filter: Filter{},
+			Expected: []*api.DoraMetrics{
+				{
+					Deployments:       2,
+					DeploymentsPerDay: 2,
+					LeadTimeSeconds:   seconds(2 * time.Hour),
+				},
+			},
+		},
+		{
+			Name: "degraded before the deployment",
+			Input: &Input{
+				From: day,
+				To:   day.Add(24 * time.Hour),
+				Deployments: []db.Deployment{
+					makeDeployment("shop", "staging", 1, day.Add(2*time.Hour)),
+				},
+				Releases: map[db.ReleaseKey]db.DeployedRelease{
+					makeRelease("shop", 1): {Created: day, UndeployVersion: false},
+				},
+				Teams: map[types.AppName]string{},
+				ArgoEvents: []*db.ArgoEvent{
+					makeArgoEvent("shop", "staging", "Degraded", day.Add(1*time.Hour)),
+				},
+			},
+			Filter: Filter{},
+			Expected: []*api.DoraMetrics{
+				{
+					Deployments:       1,
+					DeploymentsPerDay: 1,
+					LeadTimeSeconds:   seconds(2 * time.Hour),
+				},
+			},
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			actual := Compute(tc.Input, tc.Filter)
+			if diff := cmp.Diff(tc.Expected, actual, protocmp.Transform()); diff != "" {
+				t.Errorf("metrics mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
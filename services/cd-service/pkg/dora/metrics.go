@@ -0,0 +1,133 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package dora
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/metrics"
+	"github.com/freiheit-com/kuberpult/pkg/setup"
+)
+
+type MetricsConfig struct {
+	// Window is the time span the metrics are computed for, ending now.
+	Window time.Duration
+	// Interval is the time between two computations.
+	Interval time.Duration
+}
+
+// Read computes the metrics between from and to.
+func Read(ctx context.Context, dbHandler *db.DBHandler, from, to time.Time, filter Filter) ([]*api.DoraMetrics, error) {
+	input, err := db.WithTransactionT(dbHandler, ctx, db.DefaultNumRetries, true, func(ctx context.Context, transaction *sql.Tx) (*Input, error) {
+		return ReadInput(ctx, dbHandler, transaction, from, to)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("dora: could not read the deployments: %w", err)
+	}
+	return Compute(input, filter), nil
+}
+
+// ExportMetrics regularly computes the metrics of every team, app and environment and exports them as gauges.
+func ExportMetrics(ctx context.Context, dbHandler *db.DBHandler, meterProvider metric.MeterProvider, cfg MetricsConfig, health *setup.HealthReporter) error {
+	meter := meterProvider.Meter("kuberpult")
+	deploymentsPerDay, err := meter.Float64ObservableGauge("dora_deployments_per_day")
+	if err != nil {
+		return fmt.Errorf("registering meter: %w", err)
+	}
+	leadTime, err := meter.Float64ObservableGauge("dora_lead_time_seconds")
+	if err != nil {
+		return fmt.Errorf("registering meter: %w", err)
+	}
+	changeFailureRate, err := meter.Float64ObservableGauge("dora_change_failure_rate")
+	if err != nil {
+		return fmt.Errorf("registering meter: %w", err)
+	}
+	timeToRestore, err := meter.Float64ObservableGauge("dora_time_to_restore_seconds")
+	if err != nil {
+		return fmt.Errorf("registering meter: %w", err)
+	}
+
+	var stateMx sync.Mutex
+	state := []*api.DoraMetrics{}
+	reg, err := meter.RegisterCallback(
+		func(_ context.Context, o metric.Observer) error {
+			stateMx.Lock()
+			defer stateMx.Unlock()
+			for _, m := range state {
+				attributes := metric.WithAttributes(
+					attribute.String(metrics.EventTagTeam, m.Team),
+					attribute.String(metrics.EventTagApplication, m.Application),
+					attribute.String(metrics.EventTagEnvironment, m.Environment),
+				)
+				o.ObserveFloat64(deploymentsPerDay, m.DeploymentsPerDay, attributes)
+				o.ObserveFloat64(changeFailureRate, m.ChangeFailureRate, attributes)
+				if m.LeadTimeSeconds != nil {
+					o.ObserveFloat64(leadTime, *m.LeadTimeSeconds, attributes)
+				}
+				if m.TimeToRestoreSeconds != nil {
+					o.ObserveFloat64(timeToRestore, *m.TimeToRestoreSeconds, attributes)
+				}
+			}
+			return nil
+		},
+		deploymentsPerDay, leadTime, changeFailureRate, timeToRestore,
+	)
+	if err != nil {
+		return fmt.Errorf("registering callback: %w", err)
+	}
+	defer func() {
+		_ = reg.Unregister()
+	}()
+
+	filter := Filter{
+		Team:        "",
+		Application: "",
+		Environment: "",
+		GroupBy: []api.GetDoraMetricsRequest_GroupBy{
+			api.GetDoraMetricsRequest_TEAM,
+			api.GetDoraMetricsRequest_APPLICATION,
+			api.GetDoraMetricsRequest_ENVIRONMENT,
+		},
+	}
+	return health.Retry(ctx, func() error {
+		health.ReportReady("computing dora metrics")
+		for {
+			now := time.Now().UTC()
+			result, err := Read(ctx, dbHandler, now.Add(-cfg.Window), now, filter)
+			if err != nil {
+				return err
+			}
+			stateMx.Lock()
+			state = result
+			stateMx.Unlock()
+			select {
+			case <-ctx.Done():
+				return setup.Permanent(nil)
+			case <-time.After(cfg.Interval):
+			}
+		}
+	})
+}
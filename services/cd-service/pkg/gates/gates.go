@@ -0,0 +1,227 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+// Package gates asks external services whether a deployment may be executed.
+//
+// Environments can configure deployment gates, see config.DeploymentGate.
+// Before a version is deployed to such an environment, the cd-service POSTs a Request as JSON to every gate.
+// A gate approves the deployment by answering with status 200 and the Response {"approved": true}.
+// Any other answer rejects it, as does a gate that cannot be reached.
+package gates
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/freiheit-com/kuberpult/pkg/config"
+)
+
+// Request describes the deployment that a gate decides on.
+type Request struct {
+	Application string `json:"application"`
+	Environment string `json:"environment"`
+	// FromVersion is nil if the application is not deployed in the environment yet
+	FromVersion *uint64 `json:"fromVersion,omitempty"`
+	ToVersion   uint64  `json:"toVersion"`
+	Actor       Actor   `json:"actor"`
+	// CommitId is the source commit of the version to deploy
+	CommitId string `json:"commitId,omitempty"`
+}
+
+type Actor struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// Response is the answer of a gate.
+type Response struct {
+	Approved bool `json:"approved"`
+	// Message explains the decision, it is shown to the user if the deployment is rejected
+	Message string `json:"message"`
+}
+
+// Rejection is the decision of the first gate that did not approve a deployment.
+type Rejection struct {
+	Gate    string
+	Message string
+}
+
+func (r *Rejection) String() string {
+	return fmt.Sprintf("deployment gate %q: %s", r.Gate, r.Message)
+}
+
+type Config struct {
+	// Timeout of a single request to a gate
+	Timeout time.Duration
+	// Retries of a request that failed or was answered with a 5xx status
+	Retries uint
+	// CacheDuration is how long decisions are reused for the same request. 0 disables the cache.
+	CacheDuration time.Duration
+}
+
+var DefaultConfig = Config{
+	Timeout:       5 * time.Second,
+	Retries:       2,
+	CacheDuration: 0,
+}
+
+// Client asks the gates, it is safe for concurrent use.
+type Client struct {
+	config     Config
+	httpClient *http.Client
+	// retryDelay is multiplied with the number of the attempt
+	retryDelay time.Duration
+	now        func() time.Time
+
+	mx    sync.Mutex
+	cache map[cacheKey]cacheEntry
+}
+
+type cacheKey struct {
+	url     string
+	request string
+}
+
+type cacheEntry struct {
+	response Response
+	expires  time.Time
+}
+
+func New(cfg Config) *Client {
+	return &Client{
+		config:     cfg,
+		httpClient: &http.Client{Timeout: cfg.Timeout}, //exhaustruct:ignore
+		retryDelay: 200 * time.Millisecond,
+		now:        time.Now,
+		mx:         sync.Mutex{},
+		cache:      map[cacheKey]cacheEntry{},
+	}
+}
+
+// Validate checks the gates of an environment config.
+func Validate(gates []config.DeploymentGate) error {
+	names := make(map[string]bool, len(gates))
+	for _, gate := range gates {
+		if gate.Name == "" {
+			return fmt.Errorf("deployment gates: every gate needs a name")
+		}
+		if names[gate.Name] {
+			return fmt.Errorf("deployment gates: gate name %q is used more than once", gate.Name)
+		}
+		names[gate.Name] = true
+		u, err := url.Parse(gate.Url)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("deployment gates: gate %q needs an http or https url, got %q", gate.Name, gate.Url)
+		}
+	}
+	return nil
+}
+
+// Check asks all gates in order and returns the first rejection, or nil if all gates approve.
+func (c *Client) Check(ctx context.Context, gates []config.DeploymentGate, request Request) *Rejection {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return &Rejection{Gate: "", Message: fmt.Sprintf("could not encode the request: %v", err)}
+	}
+	for _, gate := range gates {
+		response, err := c.ask(ctx, gate.Url, body)
+		if err != nil {
+			return &Rejection{Gate: gate.Name, Message: fmt.Sprintf("gate could not be asked: %v", err)}
+		}
+		if !response.Approved {
+			message := response.Message
+			if message == "" {
+				message = "deployment was rejected"
+			}
+			return &Rejection{Gate: gate.Name, Message: message}
+		}
+	}
+	return nil
+}
+
+func (c *Client) ask(ctx context.Context, url string, body []byte) (Response, error) {
+	key := cacheKey{url: url, request: string(body)}
+	if c.config.CacheDuration > 0 {
+		c.mx.Lock()
+		entry, ok := c.cache[key]
+		c.mx.Unlock()
+		if ok && c.now().Before(entry.expires) {
+			return entry.response, nil
+		}
+	}
+	var response Response
+	var err error
+	for attempt := uint(0); attempt <= c.config.Retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return Response{}, ctx.Err()
+			case <-time.After(time.Duration(attempt) * c.retryDelay):
+			}
+		}
+		var retry bool
+		response, retry, err = c.post(ctx, url, body)
+		if !retry {
+			break
+		}
+	}
+	if err != nil {
+		return Response{}, err
+	}
+	if c.config.CacheDuration > 0 {
+		c.mx.Lock()
+		c.cache[key] = cacheEntry{response: response, expires: c.now().Add(c.config.CacheDuration)}
+		c.mx.Unlock()
+	}
+	return response, nil
+}
+
+// post sends one request to a gate. It returns whether the request should be retried.
+func (c *Client) post(ctx context.Context, url string, body []byte) (_ Response, retry bool, _ error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return Response{}, false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return Response{}, ctx.Err() == nil, err
+	}
+	defer func() { _ = res.Body.Close() }()
+	content, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return Response{}, true, err
+	}
+	if res.StatusCode >= 500 {
+		return Response{}, true, fmt.Errorf("status %d: %s", res.StatusCode, bytes.TrimSpace(content))
+	}
+	if res.StatusCode != http.StatusOK {
+		// e.g. 403 rejects the deployment, the body is the message
+		return Response{Approved: false, Message: fmt.Sprintf("status %d: %s", res.StatusCode, bytes.TrimSpace(content))}, false, nil
+	}
+	var response Response
+	if err := json.Unmarshal(content, &response); err != nil {
+		return Response{}, false, fmt.Errorf("invalid response: %w", err)
+	}
+	return response, false, nil
+}
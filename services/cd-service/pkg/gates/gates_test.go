@@ -0,0 +1,257 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package gates
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/freiheit-com/kuberpult/pkg/config"
+)
+
+var testRequest = Request{
+	Application: "frontend",
+	Environment: "production",
+	FromVersion: nil,
+	ToVersion:   2,
+	Actor:       Actor{Name: "test", Email: "test@example.com"},
+	CommitId:    "cafe",
+}
+
+// gateServer answers with the given statuses in order, and the last one for all following requests
+func gateServer(t *testing.T, requests *atomic.Int32, answers ...func(w http.ResponseWriter)) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request Request
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("invalid request: %v", err)
+		}
+		if diff := cmp.Diff(testRequest, request); diff != "" {
+			t.Errorf("request mismatch (-want, +got):\n%s", diff)
+		}
+		n := int(requests.Add(1))
+		answers[min(n, len(answers))-1](w)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func respond(status int, response Response) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(response)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	approve := respond(http.StatusOK, Response{Approved: true, Message: ""})
+	tcs := []struct {
+		Name              string
+		Answers           []func(w http.ResponseWriter)
+		ExpectedRejection *Rejection
+		ExpectedRequests  int32
+	}{
+		{
+			Name:              "approved",
+			Answers:           []func(w http.ResponseWriter){approve},
+			ExpectedRejection: nil,
+			ExpectedRequests:  1,
+		},
+		{
+			Name:              "rejected",
+			Answers:           []func(w http.ResponseWriter){respond(http.StatusOK, Response{Approved: false, Message: "no change request"})},
+			ExpectedRejection: &Rejection{Gate: "cm", Message: "no change request"},
+			ExpectedRequests:  1,
+		},
+		{
+			Name: "rejected with a status",
+			Answers: []func(w http.ResponseWriter){func(w http.ResponseWriter) {
+				http.Error(w, "forbidden", http.StatusForbidden)
+			}},
+			ExpectedRejection: &Rejection{Gate: "cm", Message: "status 403: forbidden"},
+			ExpectedRequests:  1,
+		},
+		{
+			Name: "retried after a server error",
+			Answers: []func(w http.ResponseWriter){func(w http.ResponseWriter) {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			}, approve},
+			ExpectedRejection: nil,
+			ExpectedRequests:  2,
+		},
+		{
+			Name: "rejected after all retries",
+			Answers: []func(w http.ResponseWriter){func(w http.ResponseWriter) {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			}},
+			ExpectedRejection: &Rejection{Gate: "cm", Message: "gate could not be asked: status 503: unavailable"},
+			ExpectedRequests:  3,
+		},
+		{
+			Name: "invalid response",
+			Answers: []func(w http.ResponseWriter){func(w http.ResponseWriter) {
+				_, _ = w.Write([]byte("yes"))
+			}},
+			ExpectedRejection: &Rejection{Gate: "cm", Message: "gate could not be asked: invalid response: invalid character 'y' looking for beginning of value"},
+			ExpectedRequests:  1,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			var requests atomic.Int32
+			url := gateServer(t, &requests, tc.Answers...)
+			client := New(Config{Timeout: time.Second, Retries: 2, CacheDuration: 0})
+			client.retryDelay = time.Millisecond
+
+			rejection := client.Check(context.Background(), []config.DeploymentGate{{Name: "cm", Url: url}}, testRequest)
+			if diff := cmp.Diff(tc.ExpectedRejection, rejection); diff != "" {
+				t.Errorf("rejection mismatch (-want, +got):\n%s", diff)
+			}
+			if requests.Load() != tc.ExpectedRequests {
+				t.Errorf("expected %d requests, got %d", tc.ExpectedRequests, requests.Load())
+			}
+		})
+	}
+}
+
+func TestCheckStopsAtFirstRejection(t *testing.T) {
+	var approving, rejecting, notAsked atomic.Int32
+	gates := []config.DeploymentGate{
+		{Name: "first", Url: gateServer(t, &approving, respond(http.StatusOK, Response{Approved: true, Message: ""}))},
+		{Name: "second", Url: gateServer(t, &rejecting, respond(http.StatusOK, Response{Approved: false, Message: ""}))},
+		{Name: "third", Url: gateServer(t, &notAsked, respond(http.StatusOK, Response{Approved: true, Message: ""}))},
+	}
+	rejection := New(DefaultConfig).Check(context.Background(), gates, testRequest)
+	if diff := cmp.Diff(&Rejection{Gate: "second", Message: "deployment was rejected"}, rejection); diff != "" {
+		t.Errorf("rejection mismatch (-want, +got):\n%s", diff)
+	}
+	if approving.Load() != 1 || rejecting.Load() != 1 || notAsked.Load() != 0 {
+		t.Errorf("unexpected requests: %d, %d, %d", approving.Load(), rejecting.Load(), notAsked.Load())
+	}
+}
+
+func TestCheckCache(t *testing.T) {
+	var requests atomic.Int32
+	gates := []config.DeploymentGate{
+		{Name: "cm", Url: gateServer(t, &requests, respond(http.StatusOK, Response{Approved: true, Message: ""}))},
+	}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	client := New(Config{Timeout: time.Second, Retries: 0, CacheDuration: time.Minute})
+	client.now = func() time.Time { return now }
+
+	for range 3 {
+		if rejection := client.Check(context.Background(), gates, testRequest); rejection != nil {
+			t.Fatalf("unexpected rejection: %v", rejection)
+		}
+	}
+	if requests.Load() != 1 {
+		t.Fatalf("expected the decision to be cached, got %d requests", requests.Load())
+	}
+	now = now.Add(2 * time.Minute)
+	if rejection := client.Check(context.Background(), gates, testRequest); rejection != nil {
+		t.Fatalf("unexpected rejection: %v", rejection)
+	}
+	if requests.Load() != 2 {
+		t.Fatalf("expected the cached decision to expire, got %d requests", requests.Load())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tcs := []struct {
+		Name          string
+		Gates         []config.DeploymentGate
+		ExpectedError string
+	}{
+		{
+			Name:          "valid gates",
+			Gates:         []config.DeploymentGate{{Name: "cm", Url: "https://cm.example.com/gate"}, {Name: "other", Url: "http://other:8080"}},
+			ExpectedError: "",
+		},
+		{
+			Name:          "no name",
+			Gates:         []config.DeploymentGate{{Name: "", Url: "https://cm.example.com/gate"}},
+			ExpectedError: "deployment gates: every gate needs a name",
+		},
+		{
+			Name:          "duplicate name",
+			Gates:         []config.DeploymentGate{{Name: "cm", Url: "https://cm.example.com/gate"}, {Name: "cm", Url: "https://cm.example.com/other"}},
+			ExpectedError: `deployment gates: gate name "cm" is used more than once`,
+		},
+		{
+			Name:          "invalid url",
+			Gates:         []config.DeploymentGate{{Name: "cm", Url: "cm.example.com/gate"}},
+			ExpectedError: `deployment gates: gate "cm" needs an http or https url, got "cm.example.com/gate"`,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := Validate(tc.Gates)
+			actual := ""
+			if err != nil {
+				actual = err.Error()
+			}
+			if actual != tc.ExpectedError {
+				t.Fatalf("expected error %q, got %q", tc.ExpectedError, actual)
+			}
+		})
+	}
+}
+
+func TestVerdicts(t *testing.T) {
+	var requests atomic.Int32
+	gates := []config.DeploymentGate{{Name: "cm", Url: gateServer(t, &requests, respond(http.StatusOK, Response{Approved: false, Message: "no change request"}))}}
+	verdicts := NewVerdicts()
+
+	if _, decided := verdicts.Lookup(gates, testRequest); decided {
+		t.Fatalf("expected the request to be undecided before the gates were asked")
+	}
+	if !verdicts.Pending() {
+		t.Fatalf("expected the request to be pending")
+	}
+	if requests.Load() != 0 {
+		t.Fatalf("expected Lookup not to ask the gates, got %d requests", requests.Load())
+	}
+
+	verdicts.Evaluate(context.Background(), New(Config{Timeout: time.Second, Retries: 0, CacheDuration: 0}))
+	if verdicts.Pending() {
+		t.Fatalf("expected no pending requests after Evaluate")
+	}
+	for range 2 {
+		rejection, decided := verdicts.Lookup(gates, testRequest)
+		if !decided {
+			t.Fatalf("expected the request to be decided")
+		}
+		if diff := cmp.Diff(&Rejection{Gate: "cm", Message: "no change request"}, rejection); diff != "" {
+			t.Errorf("rejection mismatch (-want, +got):\n%s", diff)
+		}
+	}
+	if requests.Load() != 1 {
+		t.Errorf("expected 1 request, got %d", requests.Load())
+	}
+
+	otherRequest := testRequest
+	otherRequest.ToVersion = 3
+	if _, decided := verdicts.Lookup(gates, otherRequest); decided {
+		t.Errorf("expected a different request to be undecided")
+	}
+}
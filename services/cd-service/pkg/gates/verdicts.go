@@ -0,0 +1,105 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package gates
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/freiheit-com/kuberpult/pkg/config"
+)
+
+// Verdicts holds the decisions of the gates for the deployments of one batch of transformers.
+//
+// Transformers run inside a database transaction, so they must not ask the gates themselves.
+// Instead, they look up the decision with Lookup, which remembers the deployments that were not decided yet.
+// The caller then rolls the transaction back, asks the gates with Evaluate and runs the transaction again.
+type Verdicts struct {
+	mx        sync.Mutex
+	decisions map[string]*Rejection
+	pending   map[string]check
+}
+
+type check struct {
+	Gates   []config.DeploymentGate `json:"gates"`
+	Request Request                 `json:"request"`
+}
+
+func NewVerdicts() *Verdicts {
+	return &Verdicts{
+		mx:        sync.Mutex{},
+		decisions: map[string]*Rejection{},
+		pending:   map[string]check{},
+	}
+}
+
+// Lookup returns the decision of the gates for the request.
+// If the gates were not asked yet, it returns false and remembers the request for Evaluate.
+func (v *Verdicts) Lookup(gates []config.DeploymentGate, request Request) (*Rejection, bool) {
+	c := check{Gates: gates, Request: request}
+	key, err := json.Marshal(c)
+	if err != nil {
+		return &Rejection{Gate: "", Message: "could not encode the request: " + err.Error()}, true
+	}
+	v.mx.Lock()
+	defer v.mx.Unlock()
+	if rejection, ok := v.decisions[string(key)]; ok {
+		return rejection, true
+	}
+	v.pending[string(key)] = c
+	return nil, false
+}
+
+// Pending returns true if Lookup was called for requests that Evaluate did not decide yet.
+func (v *Verdicts) Pending() bool {
+	v.mx.Lock()
+	defer v.mx.Unlock()
+	return len(v.pending) > 0
+}
+
+// Evaluate asks the gates for all pending requests. It must not be called inside a transaction.
+func (v *Verdicts) Evaluate(ctx context.Context, client *Client) {
+	v.mx.Lock()
+	pending := v.pending
+	v.pending = map[string]check{}
+	v.mx.Unlock()
+	for key, c := range pending {
+		var rejection *Rejection
+		if client == nil {
+			rejection = &Rejection{Gate: "", Message: "deployment gates are configured, but the cd-service cannot ask them"}
+		} else {
+			rejection = client.Check(ctx, c.Gates, c.Request)
+		}
+		v.mx.Lock()
+		v.decisions[key] = rejection
+		v.mx.Unlock()
+	}
+}
+
+type verdictsKey struct{}
+
+// WithVerdicts returns a context in which transformers look up the decisions of the gates in v.
+func WithVerdicts(ctx context.Context, v *Verdicts) context.Context {
+	return context.WithValue(ctx, verdictsKey{}, v)
+}
+
+// VerdictsFromContext returns the verdicts of WithVerdicts, or nil.
+func VerdictsFromContext(ctx context.Context) *Verdicts {
+	v, _ := ctx.Value(verdictsKey{}).(*Verdicts)
+	return v
+}
@@ -0,0 +1,327 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+// Package lockexpiry deletes environment, application and team locks whose
+// suggested lifetime has elapsed. Group locks are stored as one environment
+// lock per environment of the group, so they expire environment by environment.
+//
+// Locks are deleted through the regular Delete*Lock transformers, so queued
+// versions are processed and the manifest-export-service sees the deletion
+// like any other ESL event. The actor recorded in the ESL and in the lock
+// history is ExpiryUser, and the deletion metadata carries ReasonExpired.
+package lockexpiry
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"go.uber.org/zap"
+
+	"github.com/freiheit-com/kuberpult/pkg/auth"
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/logger"
+	"github.com/freiheit-com/kuberpult/pkg/metrics"
+	"github.com/freiheit-com/kuberpult/pkg/setup"
+	"github.com/freiheit-com/kuberpult/pkg/types"
+	"github.com/freiheit-com/kuberpult/services/cd-service/pkg/repository"
+)
+
+// ReasonExpired is written to the LockDeletionMetadata of every lock deleted by this package.
+const ReasonExpired = "expired"
+
+// ExpiryUser is the system actor that deletes expired locks.
+var ExpiryUser = auth.User{
+	DexAuthContext: nil,
+	Email:          "kuberpult-lock-expiry@local",
+	Name:           "kuberpult-lock-expiry",
+	Identity:       "",
+}
+
+type LockType string
+
+const (
+	LockTypeEnvironment LockType = "environment"
+	LockTypeApplication LockType = "application"
+	LockTypeTeam        LockType = "team"
+)
+
+type Config struct {
+	// Interval is the time between two runs.
+	Interval time.Duration
+	// DryRun only logs and counts the expired locks, it never deletes them.
+	DryRun bool
+}
+
+// ExpiredLock is a lock whose suggested lifetime has elapsed.
+// Application is only set for application locks, Team only for team locks.
+type ExpiredLock struct {
+	Type        LockType
+	Environment types.EnvName
+	Application types.AppName
+	Team        string
+	LockId      string
+	CreatedAt   time.Time
+	LifeTime    string
+}
+
+// ParseLifeTime converts a suggested lifetime like "2h", "3d" or "1w" into a duration.
+// The format is the one enforced on lock creation.
+// Lifetimes that do not fit into a time.Duration (about 292 years) are rejected,
+// since they would overflow to a negative duration and expire the lock right away.
+func ParseLifeTime(lifeTime string) (time.Duration, error) {
+	if len(lifeTime) < 2 {
+		return 0, fmt.Errorf("invalid lifetime %q", lifeTime)
+	}
+	number, err := strconv.ParseUint(lifeTime[:len(lifeTime)-1], 10, 32)
+	if err != nil || number == 0 {
+		return 0, fmt.Errorf("invalid lifetime %q: expected a positive number followed by h, d or w", lifeTime)
+	}
+	var unit time.Duration
+	switch lifeTime[len(lifeTime)-1] {
+	case 'h':
+		unit = time.Hour
+	case 'd':
+		unit = 24 * time.Hour
+	case 'w':
+		unit = 7 * 24 * time.Hour
+	default:
+		return 0, fmt.Errorf("invalid lifetime %q: unknown unit %q", lifeTime, lifeTime[len(lifeTime)-1])
+	}
+	if number > uint64(math.MaxInt64/unit) {
+		return 0, fmt.Errorf("invalid lifetime %q: must not be longer than %s", lifeTime, time.Duration(math.MaxInt64).Truncate(time.Hour))
+	}
+	return time.Duration(number) * unit, nil
+}
+
+// isExpired returns true if the lock has a valid suggested lifetime that elapsed before now.
+// Locks without a lifetime never expire. Locks with an unparsable lifetime are logged and kept.
+func isExpired(ctx context.Context, metadata db.LockMetadata, created time.Time, now time.Time) (bool, time.Time) {
+	if metadata.SuggestedLifeTime == "" {
+		return false, time.Time{}
+	}
+	lifeTime, err := ParseLifeTime(metadata.SuggestedLifeTime)
+	if err != nil {
+		logger.FromContext(ctx).Warn("lockexpiry.lifetime.invalid", zap.String("lifetime", metadata.SuggestedLifeTime), zap.Error(err))
+		return false, time.Time{}
+	}
+	createdAt := metadata.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = created
+	}
+	return !createdAt.Add(lifeTime).After(now), createdAt
+}
+
+// FindExpiredLocks filters the given locks down to the ones that expired before now.
+func FindExpiredLocks(
+	ctx context.Context,
+	now time.Time,
+	envLocks map[types.EnvName][]db.EnvironmentLock,
+	appLocks []db.ApplicationLock,
+	teamLocks map[types.EnvName]map[string][]db.TeamLock,
+) []ExpiredLock {
+	result := []ExpiredLock{}
+	for _, locks := range envLocks {
+		for _, lock := range locks {
+			if expired, createdAt := isExpired(ctx, lock.Metadata, lock.Created, now); expired {
+				result = append(result, ExpiredLock{
+					Type:        LockTypeEnvironment,
+					Environment: lock.Env,
+					Application: "",
+					Team:        "",
+					LockId:      lock.LockID,
+					CreatedAt:   createdAt,
+					LifeTime:    lock.Metadata.SuggestedLifeTime,
+				})
+			}
+		}
+	}
+	for _, lock := range appLocks {
+		if expired, createdAt := isExpired(ctx, lock.Metadata, lock.Created, now); expired {
+			result = append(result, ExpiredLock{
+				Type:        LockTypeApplication,
+				Environment: lock.Env,
+				Application: lock.App,
+				Team:        "",
+				LockId:      lock.LockID,
+				CreatedAt:   createdAt,
+				LifeTime:    lock.Metadata.SuggestedLifeTime,
+			})
+		}
+	}
+	for _, teams := range teamLocks {
+		for _, locks := range teams {
+			for _, lock := range locks {
+				if expired, createdAt := isExpired(ctx, lock.Metadata, lock.Created, now); expired {
+					result = append(result, ExpiredLock{
+						Type:        LockTypeTeam,
+						Environment: lock.Env,
+						Application: "",
+						Team:        lock.Team,
+						LockId:      lock.LockID,
+						CreatedAt:   createdAt,
+						LifeTime:    lock.Metadata.SuggestedLifeTime,
+					})
+				}
+			}
+		}
+	}
+	return result
+}
+
+// Transformer returns the transformer that deletes the given lock.
+// RBAC is not checked, since the deletion is done by kuberpult itself and not on behalf of a user.
+func (l ExpiredLock) Transformer() repository.Transformer {
+	authentication := repository.Authentication{RBACConfig: auth.RBACConfig{DexEnabled: false, Policy: nil, Team: nil}}
+	switch l.Type {
+	case LockTypeApplication:
+		return &repository.DeleteEnvironmentApplicationLock{
+			Authentication:        authentication,
+			Environment:           l.Environment,
+			Application:           l.Application,
+			LockId:                l.LockId,
+			Reason:                ReasonExpired,
+			TransformerEslVersion: 0,
+		}
+	case LockTypeTeam:
+		return &repository.DeleteEnvironmentTeamLock{
+			Authentication:        authentication,
+			Environment:           l.Environment,
+			Team:                  l.Team,
+			LockId:                l.LockId,
+			Reason:                ReasonExpired,
+			TransformerEslVersion: 0,
+		}
+	default:
+		return &repository.DeleteEnvironmentLock{
+			Authentication:        authentication,
+			Environment:           l.Environment,
+			LockId:                l.LockId,
+			Reason:                ReasonExpired,
+			TransformerEslVersion: 0,
+		}
+	}
+}
+
+// ExpireLocks is the BackgroundFunc registered in cmd/server.go.
+// It runs until ctx is cancelled and deletes expired locks every cfg.Interval.
+func ExpireLocks(ctx context.Context, repo repository.Repository, dbHandler *db.DBHandler, cfg Config, health *setup.HealthReporter) error {
+	return health.Retry(ctx, func() error {
+		health.ReportReady("expiring locks")
+		for {
+			if err := expireLocksOnce(ctx, repo, dbHandler, cfg); err != nil {
+				return err
+			}
+			select {
+			case <-ctx.Done():
+				return setup.Permanent(nil)
+			case <-time.After(cfg.Interval):
+			}
+		}
+	})
+}
+
+func expireLocksOnce(ctx context.Context, repo repository.Repository, dbHandler *db.DBHandler, cfg Config) error {
+	expiredLocks, err := db.WithTransactionMultipleEntriesT(dbHandler, ctx, true, func(ctx context.Context, transaction *sql.Tx) ([]ExpiredLock, error) {
+		return readExpiredLocks(ctx, repo.State(), transaction)
+	})
+	if err != nil {
+		return fmt.Errorf("lockexpiry: could not read locks: %w", err)
+	}
+	log := logger.FromContext(ctx)
+	userCtx := auth.WriteUserToContext(ctx, ExpiryUser)
+	for _, lock := range expiredLocks {
+		fields := []zap.Field{
+			zap.String("type", string(lock.Type)),
+			zap.String("environment", string(lock.Environment)),
+			zap.String("application", string(lock.Application)),
+			zap.String("team", lock.Team),
+			zap.String("lockId", lock.LockId),
+			zap.Time("createdAt", lock.CreatedAt),
+			zap.String("lifetime", lock.LifeTime),
+			zap.Bool("dryRun", cfg.DryRun),
+		}
+		if cfg.DryRun {
+			log.Info("lockexpiry.expired.dryrun", fields...)
+			reportExpiredLock(ctx, lock, true)
+			continue
+		}
+		// Each lock is deleted in its own transaction, so that one failing lock does not keep the others alive:
+		if err := repo.Apply(userCtx, lock.Transformer()); err != nil {
+			log.Error("lockexpiry.delete.failed", append(fields, zap.Error(err))...)
+			continue
+		}
+		log.Info("lockexpiry.expired.deleted", fields...)
+		reportExpiredLock(ctx, lock, false)
+	}
+	return nil
+}
+
+func readExpiredLocks(ctx context.Context, state *repository.State, transaction *sql.Tx) ([]ExpiredLock, error) {
+	now, err := state.DBHandler.DBReadTransactionTimestamp(ctx, transaction)
+	if err != nil {
+		return nil, err
+	}
+	if now == nil {
+		return nil, fmt.Errorf("could not get transaction timestamp: nil")
+	}
+	envLocks, err := state.DBHandler.DBSelectAllEnvLocksOfAllEnvs(ctx, transaction)
+	if err != nil {
+		return nil, err
+	}
+	teamLocks, err := state.DBHandler.DBSelectAllTeamLocksOfAllEnvs(ctx, transaction)
+	if err != nil {
+		return nil, err
+	}
+	envNames, err := state.GetAllEnvironmentNames(ctx, transaction)
+	if err != nil {
+		return nil, err
+	}
+	appLocks := []db.ApplicationLock{}
+	for _, envName := range envNames {
+		locks, err := state.DBHandler.DBSelectAllAppLocksForEnv(ctx, transaction, envName)
+		if err != nil {
+			return nil, err
+		}
+		appLocks = append(appLocks, locks...)
+	}
+	return FindExpiredLocks(ctx, *now, envLocks, appLocks, teamLocks), nil
+}
+
+func reportExpiredLock(ctx context.Context, lock ExpiredLock, dryRun bool) {
+	ddMetrics, ok := ctx.Value(repository.DdMetricsKey).(statsd.ClientInterface)
+	if !ok || ddMetrics == nil {
+		return
+	}
+	tags := []string{
+		metrics.EventTagEnvironment + ":" + string(lock.Environment),
+		"kuberpult_lock_type:" + string(lock.Type),
+		"kuberpult_dry_run:" + strconv.FormatBool(dryRun),
+	}
+	if lock.Application != "" {
+		tags = append(tags, metrics.EventTagApplication+":"+string(lock.Application))
+	}
+	if lock.Team != "" {
+		tags = append(tags, "kuberpult_team:"+lock.Team)
+	}
+	if err := ddMetrics.Incr("lock_expired", tags, 1); err != nil {
+		logger.FromContext(ctx).Warn("lockexpiry.metrics.error", zap.Error(err))
+	}
+}
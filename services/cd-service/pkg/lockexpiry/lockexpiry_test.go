@@ -0,0 +1,240 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package lockexpiry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/types"
+	"github.com/freiheit-com/kuberpult/services/cd-service/pkg/repository"
+)
+
+func TestParseLifeTime(t *testing.T) {
+	tcs := []struct {
+		Name     string
+		Input    string
+		Expected time.Duration
+		WantErr  bool
+	}{
+		{
+			Name:     "hours",
+			Input:    "5h",
+			Expected: 5 * time.Hour,
+		},
+		{
+			Name:     "days",
+			Input:    "2d",
+			Expected: 48 * time.Hour,
+		},
+		{
+			Name:     "weeks",
+			Input:    "1w",
+			Expected: 7 * 24 * time.Hour,
+		},
+		{
+			Name:     "longest lifetime",
+			Input:    "2562047h",
+			Expected: 2562047 * time.Hour,
+		},
+		{
+			Name:    "hours that overflow",
+			Input:   "9999999h",
+			WantErr: true,
+		},
+		{
+			Name:    "weeks that overflow",
+			Input:   "999999w",
+			WantErr: true,
+		},
+		{
+			Name:    "zero is invalid",
+			Input:   "0d",
+			WantErr: true,
+		},
+		{
+			Name:    "unknown unit",
+			Input:   "3m",
+			WantErr: true,
+		},
+		{
+			Name:    "missing number",
+			Input:   "h",
+			WantErr: true,
+		},
+		{
+			Name:    "empty",
+			Input:   "",
+			WantErr: true,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			actual, err := ParseLifeTime(tc.Input)
+			if tc.WantErr {
+				if err == nil {
+					t.Fatalf("expected an error for %q, got %v", tc.Input, actual)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.Expected, actual); diff != "" {
+				t.Errorf("lifetime mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFindExpiredLocks(t *testing.T) {
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+	twoDaysAgo := now.Add(-48 * time.Hour)
+	oneHourAgo := now.Add(-1 * time.Hour)
+	metadata := func(createdAt time.Time, lifeTime string) db.LockMetadata {
+		return db.LockMetadata{
+			CreatedByName:     "user",
+			CreatedByEmail:    "user@example.com",
+			Message:           "msg",
+			CiLink:            "",
+			CreatedAt:         createdAt,
+			SuggestedLifeTime: lifeTime,
+		}
+	}
+	tcs := []struct {
+		Name      string
+		EnvLocks  map[types.EnvName][]db.EnvironmentLock
+		AppLocks  []db.ApplicationLock
+		TeamLocks map[types.EnvName]map[string][]db.TeamLock
+		Expected  []ExpiredLock
+	}{
+		{
+			Name: "locks without lifetime never expire",
+			EnvLocks: map[types.EnvName][]db.EnvironmentLock{
+				"dev": {{Created: twoDaysAgo, LockID: "l1", Env: "dev", Metadata: metadata(twoDaysAgo, "")}},
+			},
+			Expected: []ExpiredLock{},
+		},
+		{
+			Name: "invalid lifetimes are kept",
+			AppLocks: []db.ApplicationLock{
+				{Created: twoDaysAgo, LockID: "l1", Env: "dev", App: "app1", Metadata: metadata(twoDaysAgo, "2x")},
+			},
+			Expected: []ExpiredLock{},
+		},
+		{
+			Name: "lifetimes that overflow are kept",
+			EnvLocks: map[types.EnvName][]db.EnvironmentLock{
+				"dev": {{Created: twoDaysAgo, LockID: "l1", Env: "dev", Metadata: metadata(twoDaysAgo, "999999w")}},
+			},
+			Expected: []ExpiredLock{},
+		},
+		{
+			Name: "only elapsed locks of each type are returned",
+			EnvLocks: map[types.EnvName][]db.EnvironmentLock{
+				"dev": {
+					{Created: twoDaysAgo, LockID: "env-expired", Env: "dev", Metadata: metadata(twoDaysAgo, "1d")},
+					{Created: oneHourAgo, LockID: "env-active", Env: "dev", Metadata: metadata(oneHourAgo, "1d")},
+				},
+			},
+			AppLocks: []db.ApplicationLock{
+				{Created: twoDaysAgo, LockID: "app-expired", Env: "staging", App: "app1", Metadata: metadata(twoDaysAgo, "2d")},
+				{Created: twoDaysAgo, LockID: "app-active", Env: "staging", App: "app1", Metadata: metadata(twoDaysAgo, "1w")},
+			},
+			TeamLocks: map[types.EnvName]map[string][]db.TeamLock{
+				"prod": {
+					"team1": {
+						{Created: twoDaysAgo, LockID: "team-expired", Env: "prod", Team: "team1", Metadata: metadata(twoDaysAgo, "1h")},
+					},
+				},
+			},
+			Expected: []ExpiredLock{
+				{Type: LockTypeEnvironment, Environment: "dev", LockId: "env-expired", CreatedAt: twoDaysAgo, LifeTime: "1d"},
+				{Type: LockTypeApplication, Environment: "staging", Application: "app1", LockId: "app-expired", CreatedAt: twoDaysAgo, LifeTime: "2d"},
+				{Type: LockTypeTeam, Environment: "prod", Team: "team1", LockId: "team-expired", CreatedAt: twoDaysAgo, LifeTime: "1h"},
+			},
+		},
+		{
+			Name: "falls back to the row creation time without metadata timestamp",
+			EnvLocks: map[types.EnvName][]db.EnvironmentLock{
+				"dev": {{Created: twoDaysAgo, LockID: "l1", Env: "dev", Metadata: metadata(time.Time{}, "1d")}},
+			},
+			Expected: []ExpiredLock{
+				{Type: LockTypeEnvironment, Environment: "dev", LockId: "l1", CreatedAt: twoDaysAgo, LifeTime: "1d"},
+			},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			actual := FindExpiredLocks(context.Background(), now, tc.EnvLocks, tc.AppLocks, tc.TeamLocks)
+			sortOpt := cmpopts.SortSlices(func(a, b ExpiredLock) bool { return a.LockId < b.LockId })
+			if diff := cmp.Diff(tc.Expected, actual, sortOpt); diff != "" {
+				t.Errorf("expired locks mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestExpiredLockTransformer(t *testing.T) {
+	tcs := []struct {
+		Name     string
+		Lock     ExpiredLock
+		Expected repository.Transformer
+	}{
+		{
+			Name: "environment lock",
+			Lock: ExpiredLock{Type: LockTypeEnvironment, Environment: "dev", LockId: "l1"},
+			Expected: &repository.DeleteEnvironmentLock{
+				Environment: "dev",
+				LockId:      "l1",
+				Reason:      ReasonExpired,
+			},
+		},
+		{
+			Name: "application lock",
+			Lock: ExpiredLock{Type: LockTypeApplication, Environment: "dev", Application: "app1", LockId: "l1"},
+			Expected: &repository.DeleteEnvironmentApplicationLock{
+				Environment: "dev",
+				Application: "app1",
+				LockId:      "l1",
+				Reason:      ReasonExpired,
+			},
+		},
+		{
+			Name: "team lock",
+			Lock: ExpiredLock{Type: LockTypeTeam, Environment: "dev", Team: "team1", LockId: "l1"},
+			Expected: &repository.DeleteEnvironmentTeamLock{
+				Environment: "dev",
+				Team:        "team1",
+				LockId:      "l1",
+				Reason:      ReasonExpired,
+			},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.Expected, tc.Lock.Transformer()); diff != "" {
+				t.Errorf("transformer mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
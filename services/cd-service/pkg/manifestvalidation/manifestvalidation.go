@@ -0,0 +1,333 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+// Package manifestvalidation checks the Kubernetes objects in the manifests of a new release
+// against the manifest validation rules of each environment.
+package manifestvalidation
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+
+	yaml3 "gopkg.in/yaml.v3"
+
+	"github.com/freiheit-com/kuberpult/pkg/config"
+	"github.com/freiheit-com/kuberpult/pkg/types"
+)
+
+const (
+	RuleRequiredFields    = "required-fields"
+	RulePlaintextSecret   = "plaintext-secret"
+	RuleClusterScoped     = "cluster-scoped-access-list"
+	RuleNamespaceRequired = "namespace-required"
+	RuleDisallowedKinds   = "disallowed-kinds"
+	// ruleParse reports documents that are not Kubernetes objects. It cannot be disabled.
+	ruleParse = "parse"
+)
+
+// Object is one Kubernetes object of a manifest.
+type Object struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+	Data       map[string]any `yaml:"data"`
+	StringData map[string]any `yaml:"stringData"`
+}
+
+// Group returns the API group of the object, which is empty for the core group.
+func (o *Object) Group() string {
+	group, _, found := strings.Cut(o.APIVersion, "/")
+	if !found {
+		return ""
+	}
+	return group
+}
+
+// Rule checks one object in the manifest of an environment and returns a message per violation.
+type Rule func(obj *Object, env config.EnvironmentConfig) []string
+
+// Registry holds the rules that environments can enable by name.
+type Registry struct {
+	rules map[string]Rule
+}
+
+// NewRegistry returns a registry with the built-in rules and the additional rules.
+// An additional rule replaces the built-in rule with the same name.
+func NewRegistry(additional map[string]Rule) *Registry {
+	rules := map[string]Rule{
+		RuleRequiredFields:    checkRequiredFields,
+		RulePlaintextSecret:   checkPlaintextSecret,
+		RuleClusterScoped:     checkClusterScoped,
+		RuleNamespaceRequired: checkNamespaceRequired,
+		RuleDisallowedKinds:   checkDisallowedKinds,
+	}
+	for name, rule := range additional {
+		rules[name] = rule
+	}
+	return &Registry{rules: rules}
+}
+
+// RuleNames returns the names of all rules in sorted order.
+func (r *Registry) RuleNames() []string {
+	names := make([]string, 0, len(r.rules))
+	for name := range r.rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateConfig checks that the configuration only refers to known rules.
+func (r *Registry) ValidateConfig(cfg *config.ManifestValidation) error {
+	if cfg == nil {
+		return nil
+	}
+	for _, name := range cfg.Rules {
+		if _, ok := r.rules[name]; !ok {
+			return fmt.Errorf("unknown manifest validation rule %q, known rules are %s", name, strings.Join(r.RuleNames(), ", "))
+		}
+	}
+	for _, kind := range cfg.DisallowedKinds {
+		if kind == "" || strings.HasSuffix(kind, "/") {
+			return fmt.Errorf("invalid disallowed kind %q", kind)
+		}
+	}
+	return nil
+}
+
+// Finding is one violation of a rule by a document of a manifest.
+type Finding struct {
+	Environment types.EnvName
+	// Document is the index of the YAML document in the manifest, starting at 0.
+	Document int
+	Kind     string
+	Name     string
+	Rule     string
+	Message  string
+}
+
+// Validate checks the manifests of all environments that have a manifest validation configured.
+// The findings are sorted by environment and document.
+func (r *Registry) Validate(manifests map[types.EnvName]string, configs map[types.EnvName]config.EnvironmentConfig) []Finding {
+	findings := []Finding{}
+	for env, manifest := range manifests {
+		envConfig, ok := configs[env]
+		if !ok || envConfig.ManifestValidation == nil {
+			continue
+		}
+		findings = append(findings, r.validateManifest(env, manifest, envConfig)...)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Environment != findings[j].Environment {
+			return findings[i].Environment < findings[j].Environment
+		}
+		return findings[i].Document < findings[j].Document
+	})
+	return findings
+}
+
+// validateManifest only checks the rules that the environment enables. Documents that are not
+// Kubernetes objects are always reported.
+func (r *Registry) validateManifest(env types.EnvName, manifest string, envConfig config.EnvironmentConfig) []Finding {
+	enabled := envConfig.ManifestValidation.Rules
+	findings := []Finding{}
+	decoder := yaml3.NewDecoder(strings.NewReader(manifest))
+	for document := 0; ; document++ {
+		var node yaml3.Node
+		err := decoder.Decode(&node)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// the decoder cannot continue after a syntax error
+			findings = append(findings, Finding{
+				Environment: env,
+				Document:    document,
+				Kind:        "",
+				Name:        "",
+				Rule:        ruleParse,
+				Message:     fmt.Sprintf("invalid yaml: %v", err),
+			})
+			break
+		}
+		if isEmptyDocument(&node) {
+			continue
+		}
+		//exhaustruct:ignore
+		obj := Object{}
+		if err := node.Decode(&obj); err != nil {
+			findings = append(findings, Finding{
+				Environment: env,
+				Document:    document,
+				Kind:        "",
+				Name:        "",
+				Rule:        ruleParse,
+				Message:     fmt.Sprintf("document is not a Kubernetes object: %v", err),
+			})
+			continue
+		}
+		for _, name := range enabled {
+			rule, ok := r.rules[name]
+			if !ok {
+				// ValidateConfig rejects unknown rules, so this is a rule that no longer exists
+				continue
+			}
+			for _, message := range rule(&obj, envConfig) {
+				findings = append(findings, Finding{
+					Environment: env,
+					Document:    document,
+					Kind:        obj.Kind,
+					Name:        obj.Metadata.Name,
+					Rule:        name,
+					Message:     message,
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// isEmptyDocument reports documents that only consist of comments, e.g. from helm templates that render nothing.
+func isEmptyDocument(node *yaml3.Node) bool {
+	if node.Kind == yaml3.DocumentNode && len(node.Content) == 1 {
+		node = node.Content[0]
+	}
+	return node.Kind == 0 || (node.Kind == yaml3.ScalarNode && node.Tag == "!!null")
+}
+
+func checkRequiredFields(obj *Object, _ config.EnvironmentConfig) []string {
+	messages := []string{}
+	if obj.APIVersion == "" {
+		messages = append(messages, "apiVersion is missing")
+	}
+	if obj.Kind == "" {
+		messages = append(messages, "kind is missing")
+	}
+	if obj.Metadata.Name == "" {
+		messages = append(messages, "metadata.name is missing")
+	}
+	return messages
+}
+
+func checkPlaintextSecret(obj *Object, _ config.EnvironmentConfig) []string {
+	if obj.Kind != "Secret" || obj.Group() != "" {
+		return nil
+	}
+	if len(obj.Data) > 0 || len(obj.StringData) > 0 {
+		return []string{"secrets must not contain plaintext data, use an encrypted secret instead"}
+	}
+	return nil
+}
+
+func checkClusterScoped(obj *Object, env config.EnvironmentConfig) []string {
+	if !isClusterScoped(obj) {
+		return nil
+	}
+	argoConfigs := []*config.EnvironmentConfigArgoCd{}
+	if env.ArgoCd != nil {
+		argoConfigs = append(argoConfigs, env.ArgoCd)
+	}
+	if env.ArgoCdConfigs != nil {
+		argoConfigs = append(argoConfigs, env.ArgoCdConfigs.ArgoCdConfigurations...)
+	}
+	if len(argoConfigs) == 0 {
+		return []string{fmt.Sprintf("cluster-scoped resource %s is not in the accessList of the environment", kindWithGroup(obj))}
+	}
+	for _, argoConfig := range argoConfigs {
+		allowed := slices.ContainsFunc(argoConfig.ClusterResourceWhitelist, func(entry config.AccessEntry) bool {
+			return matches(entry.Group, obj.Group()) && matches(entry.Kind, obj.Kind)
+		})
+		if !allowed {
+			return []string{fmt.Sprintf("cluster-scoped resource %s is not in the accessList of the environment", kindWithGroup(obj))}
+		}
+	}
+	return nil
+}
+
+func checkNamespaceRequired(obj *Object, _ config.EnvironmentConfig) []string {
+	if obj.Kind == "" || isClusterScoped(obj) {
+		return nil
+	}
+	if obj.Metadata.Namespace == "" {
+		return []string{"metadata.namespace is missing"}
+	}
+	return nil
+}
+
+func checkDisallowedKinds(obj *Object, env config.EnvironmentConfig) []string {
+	if obj.Kind == "" {
+		return nil
+	}
+	for _, disallowed := range env.ManifestValidation.DisallowedKinds {
+		group, kind, hasGroup := strings.Cut(disallowed, "/")
+		if !hasGroup {
+			group, kind = obj.Group(), disallowed
+		}
+		if group == obj.Group() && kind == obj.Kind {
+			return []string{fmt.Sprintf("kind %s is not allowed in this environment", kindWithGroup(obj))}
+		}
+	}
+	return nil
+}
+
+// matches compares a field of an accessList entry, which may be a wildcard, to the value of an object.
+func matches(pattern string, value string) bool {
+	return pattern == "*" || pattern == value
+}
+
+func kindWithGroup(obj *Object) string {
+	if obj.Group() == "" {
+		return obj.Kind
+	}
+	return obj.Group() + "/" + obj.Kind
+}
+
+// clusterScopedKinds are the cluster-scoped kinds of Kubernetes, by group.
+// Custom resources are assumed to be namespaced.
+var clusterScopedKinds = map[string][]string{
+	"": {
+		"ComponentStatus",
+		"Namespace",
+		"Node",
+		"PersistentVolume",
+	},
+	"admissionregistration.k8s.io": {
+		"MutatingWebhookConfiguration",
+		"ValidatingAdmissionPolicy",
+		"ValidatingAdmissionPolicyBinding",
+		"ValidatingWebhookConfiguration",
+	},
+	"apiextensions.k8s.io":         {"CustomResourceDefinition"},
+	"apiregistration.k8s.io":       {"APIService"},
+	"certificates.k8s.io":          {"CertificateSigningRequest"},
+	"flowcontrol.apiserver.k8s.io": {"FlowSchema", "PriorityLevelConfiguration"},
+	"networking.k8s.io":            {"IngressClass"},
+	"node.k8s.io":                  {"RuntimeClass"},
+	"rbac.authorization.k8s.io":    {"ClusterRole", "ClusterRoleBinding"},
+	"scheduling.k8s.io":            {"PriorityClass"},
+	"storage.k8s.io":               {"CSIDriver", "CSINode", "StorageClass", "VolumeAttachment"},
+}
+
+func isClusterScoped(obj *Object) bool {
+	return slices.Contains(clusterScopedKinds[obj.Group()], obj.Kind)
+}
@@ -0,0 +1,315 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package manifestvalidation
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/freiheit-com/kuberpult/pkg/config"
+	"github.com/freiheit-com/kuberpult/pkg/types"
+)
+
+const envName types.EnvName = "production"
+
+func envConfig(validation *config.ManifestValidation, accessList ...config.AccessEntry) config.EnvironmentConfig {
+	return config.EnvironmentConfig{
+		Upstream: nil,
+		ArgoCd: &config.EnvironmentConfigArgoCd{
+			Destination:              config.ArgoCdDestination{Name: "prod", Server: "https://prod.example.com"},
+			ClusterResourceWhitelist: accessList,
+		},
+		EnvironmentGroup:   nil,
+		ArgoCdConfigs:      nil,
+		IsActiveActive:     nil,
+		Freezes:            nil,
+		ManifestValidation: validation,
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tcs := []struct {
+		Name             string
+		Manifest         string
+		Config           config.EnvironmentConfig
+		ExpectedFindings []Finding
+	}{
+		{
+			Name: "valid objects and empty documents",
+			Manifest: `---
+# Source: empty template
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: app
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: app-reader
+`,
+			Config:           envConfig(&config.ManifestValidation{Rules: NewRegistry(nil).RuleNames()}, config.AccessEntry{Group: "rbac.authorization.k8s.io", Kind: "*"}),
+			ExpectedFindings: []Finding{},
+		},
+		{
+			Name: "missing required fields",
+			Manifest: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  namespace: app
+---
+metadata:
+  name: nothing
+`,
+			Config: envConfig(&config.ManifestValidation{Rules: []string{RuleRequiredFields}}),
+			ExpectedFindings: []Finding{
+				{Environment: envName, Document: 0, Kind: "ConfigMap", Rule: RuleRequiredFields, Message: "metadata.name is missing"},
+				{Environment: envName, Document: 1, Name: "nothing", Rule: RuleRequiredFields, Message: "apiVersion is missing"},
+				{Environment: envName, Document: 1, Name: "nothing", Rule: RuleRequiredFields, Message: "kind is missing"},
+			},
+		},
+		{
+			Name: "plaintext secrets",
+			Manifest: `apiVersion: v1
+kind: Secret
+metadata:
+  name: password
+  namespace: app
+stringData:
+  password: hunter2
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: pull-secret
+  namespace: app
+type: kubernetes.io/dockerconfigjson
+---
+apiVersion: bitnami.com/v1alpha1
+kind: SealedSecret
+metadata:
+  name: sealed
+  namespace: app
+`,
+			Config: envConfig(&config.ManifestValidation{Rules: []string{RulePlaintextSecret}}),
+			ExpectedFindings: []Finding{
+				{Environment: envName, Document: 0, Kind: "Secret", Name: "password", Rule: RulePlaintextSecret, Message: "secrets must not contain plaintext data, use an encrypted secret instead"},
+			},
+		},
+		{
+			Name: "cluster-scoped resources not in the access list",
+			Manifest: `apiVersion: v1
+kind: Namespace
+metadata:
+  name: app
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: app-admin
+`,
+			Config: envConfig(&config.ManifestValidation{Rules: []string{RuleClusterScoped}}, config.AccessEntry{Group: "", Kind: "Namespace"}),
+			ExpectedFindings: []Finding{
+				{Environment: envName, Document: 1, Kind: "ClusterRoleBinding", Name: "app-admin", Rule: RuleClusterScoped, Message: "cluster-scoped resource rbac.authorization.k8s.io/ClusterRoleBinding is not in the accessList of the environment"},
+			},
+		},
+		{
+			Name: "missing namespace",
+			Manifest: `apiVersion: v1
+kind: Service
+metadata:
+  name: app
+`,
+			Config: envConfig(&config.ManifestValidation{Rules: []string{RuleNamespaceRequired, RuleClusterScoped}}),
+			ExpectedFindings: []Finding{
+				{Environment: envName, Document: 0, Kind: "Service", Name: "app", Rule: RuleNamespaceRequired, Message: "metadata.namespace is missing"},
+			},
+		},
+		{
+			Name: "disallowed kinds",
+			Manifest: `apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: app
+  namespace: app
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: app
+  namespace: app
+---
+apiVersion: example.com/v1
+kind: CronJob
+metadata:
+  name: custom
+  namespace: app
+`,
+			Config: envConfig(&config.ManifestValidation{Rules: []string{RuleDisallowedKinds}, DisallowedKinds: []string{"networking.k8s.io/Ingress", "CronJob"}}),
+			ExpectedFindings: []Finding{
+				{Environment: envName, Document: 0, Kind: "Ingress", Name: "app", Rule: RuleDisallowedKinds, Message: "kind networking.k8s.io/Ingress is not allowed in this environment"},
+				{Environment: envName, Document: 1, Kind: "CronJob", Name: "app", Rule: RuleDisallowedKinds, Message: "kind batch/CronJob is not allowed in this environment"},
+				{Environment: envName, Document: 2, Kind: "CronJob", Name: "custom", Rule: RuleDisallowedKinds, Message: "kind example.com/CronJob is not allowed in this environment"},
+			},
+		},
+		{
+			Name: "documents that are not objects",
+			Manifest: `- apiVersion: v1
+---
+apiVersion: v1
+kind: ConfigMap
+metadata: {name: app, namespace: app
+`,
+			Config: envConfig(&config.ManifestValidation{}),
+			ExpectedFindings: []Finding{
+				{Environment: envName, Document: 0, Rule: ruleParse, Message: "document is not a Kubernetes object: yaml: unmarshal errors:\n  line 1: cannot unmarshal !!seq into manifestvalidation.Object"},
+				{Environment: envName, Document: 1, Rule: ruleParse, Message: "invalid yaml: yaml: line 4: did not find expected ',' or '}'"},
+			},
+		},
+		{
+			Name: "no rules enabled",
+			Manifest: `apiVersion: v1
+kind: Secret
+stringData:
+  password: hunter2
+`,
+			Config:           envConfig(&config.ManifestValidation{}),
+			ExpectedFindings: []Finding{},
+		},
+		{
+			Name: "no validation configured",
+			Manifest: `kind: Secret
+stringData:
+  password: hunter2
+`,
+			Config:           envConfig(nil),
+			ExpectedFindings: []Finding{},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			actual := NewRegistry(nil).Validate(
+				map[types.EnvName]string{envName: tc.Manifest, "unknown": "kind: Secret"},
+				map[types.EnvName]config.EnvironmentConfig{envName: tc.Config},
+			)
+			if diff := cmp.Diff(tc.ExpectedFindings, actual); diff != "" {
+				t.Errorf("findings mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestClusterScopedActiveActive(t *testing.T) {
+	cfg := config.EnvironmentConfig{
+		Upstream: nil,
+		ArgoCd:   nil,
+		ArgoCdConfigs: &config.ArgoCDConfigs{
+			ArgoCdConfigurations: []*config.EnvironmentConfigArgoCd{
+				{ConcreteEnvName: "de", ClusterResourceWhitelist: []config.AccessEntry{{Group: "*", Kind: "*"}}},
+				{ConcreteEnvName: "fr", ClusterResourceWhitelist: []config.AccessEntry{{Group: "storage.k8s.io", Kind: "StorageClass"}}},
+			},
+		},
+		EnvironmentGroup:   nil,
+		IsActiveActive:     nil,
+		Freezes:            nil,
+		ManifestValidation: &config.ManifestValidation{Rules: []string{RuleClusterScoped}},
+	}
+	manifest := `apiVersion: storage.k8s.io/v1
+kind: StorageClass
+metadata:
+  name: fast
+---
+apiVersion: scheduling.k8s.io/v1
+kind: PriorityClass
+metadata:
+  name: high
+`
+	actual := NewRegistry(nil).Validate(map[types.EnvName]string{envName: manifest}, map[types.EnvName]config.EnvironmentConfig{envName: cfg})
+	expected := []Finding{
+		{Environment: envName, Document: 1, Kind: "PriorityClass", Name: "high", Rule: RuleClusterScoped, Message: "cluster-scoped resource scheduling.k8s.io/PriorityClass is not in the accessList of the environment"},
+	}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("findings mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	tcs := []struct {
+		Name    string
+		Config  *config.ManifestValidation
+		WantErr bool
+	}{
+		{
+			Name:   "no validation",
+			Config: nil,
+		},
+		{
+			Name:   "known rules",
+			Config: &config.ManifestValidation{Rules: []string{RuleRequiredFields, RuleDisallowedKinds}, DisallowedKinds: []string{"Secret", "networking.k8s.io/Ingress"}},
+		},
+		{
+			Name:    "unknown rule",
+			Config:  &config.ManifestValidation{Rules: []string{"no-latest-tag"}},
+			WantErr: true,
+		},
+		{
+			Name:    "invalid kind",
+			Config:  &config.ManifestValidation{DisallowedKinds: []string{"apps/"}},
+			WantErr: true,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := NewRegistry(nil).ValidateConfig(tc.Config)
+			if tc.WantErr != (err != nil) {
+				t.Fatalf("expected error %t, got %v", tc.WantErr, err)
+			}
+		})
+	}
+}
+
+func TestRegistryAdditionalRules(t *testing.T) {
+	noLatestTag := func(obj *Object, _ config.EnvironmentConfig) []string {
+		if obj.Metadata.Name == "latest" {
+			return []string{"latest is not a release"}
+		}
+		return nil
+	}
+	registry := NewRegistry(map[string]Rule{"no-latest": noLatestTag})
+	validation := &config.ManifestValidation{Rules: []string{"no-latest"}}
+	if err := registry.ValidateConfig(validation); err != nil {
+		t.Fatalf("expected the additional rule to be known, got: %v", err)
+	}
+	if err := NewRegistry(nil).ValidateConfig(validation); err == nil {
+		t.Fatalf("expected the additional rule to be unknown to other registries")
+	}
+	manifest := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: latest
+`
+	actual := registry.Validate(map[types.EnvName]string{envName: manifest}, map[types.EnvName]config.EnvironmentConfig{envName: envConfig(validation)})
+	expected := []Finding{
+		{Environment: envName, Document: 0, Kind: "ConfigMap", Name: "latest", Rule: "no-latest", Message: "latest is not a release"},
+	}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("findings mismatch (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,213 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package policy
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	yaml3 "gopkg.in/yaml.v3"
+
+	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
+	"github.com/freiheit-com/kuberpult/pkg/auth"
+	"github.com/freiheit-com/kuberpult/pkg/config"
+	"github.com/freiheit-com/kuberpult/pkg/mapper"
+	"github.com/freiheit-com/kuberpult/pkg/types"
+	"github.com/freiheit-com/kuberpult/services/cd-service/pkg/repository"
+)
+
+const (
+	ActionCreateRelease = "create_release"
+	ActionDeploy        = "deploy"
+	ActionReleaseTrain  = "release_train"
+)
+
+// Input is the `input` document of the policies.
+type Input struct {
+	// Action is one of ActionCreateRelease, ActionDeploy and ActionReleaseTrain.
+	Action string    `json:"action"`
+	User   User      `json:"user"`
+	Time   time.Time `json:"time"`
+	// Request is the CreateReleaseRequest, DeployRequest or ReleaseTrainRequest in its JSON form, e.g. `input.request.environment`.
+	Request map[string]interface{} `json:"request"`
+	// Manifests are the Kubernetes objects in the manifests of a new release, by environment.
+	Manifests map[types.EnvName][]interface{} `json:"manifests,omitempty"`
+	State     State                           `json:"state"`
+}
+
+type User struct {
+	Name  string   `json:"name"`
+	Email string   `json:"email"`
+	Roles []string `json:"roles"`
+}
+
+// State is the current state of kuberpult, before the action is applied.
+type State struct {
+	Environments map[types.EnvName]Environment `json:"environments"`
+	// Application is the application of a new release or deployment. It is nil for new applications and release trains.
+	Application *Application `json:"application,omitempty"`
+}
+
+type Environment struct {
+	Group string `json:"group"`
+	// Priority is the name of the api.Priority of the environment, e.g. "PROD".
+	Priority string                   `json:"priority"`
+	Config   config.EnvironmentConfig `json:"config"`
+}
+
+type Application struct {
+	Name string `json:"name"`
+	Team string `json:"team"`
+	// Deployments are the currently deployed versions by environment.
+	Deployments map[types.EnvName]uint64 `json:"deployments"`
+}
+
+// NewInput builds the input for a batch action. It returns nil for actions that are not subject to policies.
+func NewInput(ctx context.Context, state *repository.State, transaction *sql.Tx, action *api.BatchAction, user auth.User, now time.Time) (*Input, error) {
+	var name string
+	var request proto.Message
+	var app types.AppName
+	var manifests map[types.EnvName][]interface{}
+	switch a := action.Action.(type) {
+	case *api.BatchAction_CreateRelease:
+		name, request, app = ActionCreateRelease, a.CreateRelease, types.AppName(a.CreateRelease.Application)
+		manifests = parseManifests(a.CreateRelease.Manifests)
+	case *api.BatchAction_Deploy:
+		name, request, app = ActionDeploy, a.Deploy, types.AppName(a.Deploy.Application)
+	case *api.BatchAction_ReleaseTrain:
+		name, request = ActionReleaseTrain, a.ReleaseTrain
+	default:
+		return nil, nil
+	}
+	requestJson, err := protojson.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	var requestMap map[string]interface{}
+	if err := json.Unmarshal(requestJson, &requestMap); err != nil {
+		return nil, err
+	}
+	environments, err := readEnvironments(ctx, state, transaction)
+	if err != nil {
+		return nil, err
+	}
+	var application *Application
+	if app != "" {
+		application, err = readApplication(ctx, state, transaction, app)
+		if err != nil {
+			return nil, err
+		}
+	}
+	roles := []string{}
+	if user.DexAuthContext != nil {
+		roles = user.DexAuthContext.Role
+	}
+	return &Input{
+		Action: name,
+		User: User{
+			Name:  user.Name,
+			Email: user.Email,
+			Roles: roles,
+		},
+		Time:      now,
+		Request:   requestMap,
+		Manifests: manifests,
+		State: State{
+			Environments: environments,
+			Application:  application,
+		},
+	}, nil
+}
+
+func readEnvironments(ctx context.Context, state *repository.State, transaction *sql.Tx) (map[types.EnvName]Environment, error) {
+	configs, err := state.GetAllEnvironmentConfigs(ctx, transaction)
+	if err != nil {
+		return nil, err
+	}
+	result := map[types.EnvName]Environment{}
+	for _, group := range mapper.MapEnvironmentsToGroups(configs) {
+		for _, env := range group.Environments {
+			envName := types.EnvName(env.Name)
+			result[envName] = Environment{
+				Group:    group.EnvironmentGroupName,
+				Priority: env.Priority.String(),
+				Config:   configs[envName],
+			}
+		}
+	}
+	return result, nil
+}
+
+func readApplication(ctx context.Context, state *repository.State, transaction *sql.Tx, name types.AppName) (*Application, error) {
+	app, err := state.DBHandler.DBSelectApp(ctx, transaction, name)
+	if err != nil {
+		return nil, fmt.Errorf("could not read application %s: %w", name, err)
+	}
+	if app == nil {
+		return nil, nil
+	}
+	deployments, err := state.DBHandler.DBSelectAllDeploymentsForApp(ctx, transaction, name)
+	if err != nil {
+		return nil, fmt.Errorf("could not read deployments of application %s: %w", name, err)
+	}
+	result := &Application{
+		Name:        string(name),
+		Team:        app.Metadata.Team,
+		Deployments: map[types.EnvName]uint64{},
+	}
+	for env, release := range deployments {
+		if release.Version != nil {
+			result.Deployments[env] = *release.Version
+		}
+	}
+	return result, nil
+}
+
+// parseManifests returns the documents of each manifest. Documents after invalid YAML are skipped,
+// the manifest validation reports them.
+func parseManifests(manifests map[string]string) map[types.EnvName][]interface{} {
+	result := map[types.EnvName][]interface{}{}
+	for env, manifest := range manifests {
+		documents := []interface{}{}
+		decoder := yaml3.NewDecoder(strings.NewReader(manifest))
+		for {
+			var document map[string]interface{}
+			err := decoder.Decode(&document)
+			if errors.Is(err, io.EOF) || (err != nil && !isTypeError(err)) {
+				break
+			}
+			if err == nil && document != nil {
+				documents = append(documents, document)
+			}
+		}
+		result[types.EnvName(env)] = documents
+	}
+	return result
+}
+
+func isTypeError(err error) bool {
+	var typeError *yaml3.TypeError
+	return errors.As(err, &typeError)
+}
@@ -0,0 +1,258 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+// Package policy evaluates Rego policies against new releases, deployments and release trains,
+// before the cd-service applies them.
+//
+// The policies are the .rego files of a file or directory. They are evaluated with the embedded
+// OPA library, and reloaded when the files change. Policies are written in the package `kuberpult`
+// and define the sets `deny` and `warn` of messages:
+//
+//	package kuberpult
+//
+//	deny contains msg if {
+//		input.action == "deploy"
+//		input.state.environments[input.request.environment].priority == "PROD"
+//		time.weekday(time.now_ns()) == "Friday"
+//		msg := "no deployments to production on Fridays"
+//	}
+//
+// The input is described by Input.
+package policy
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/rego"
+	"go.uber.org/zap"
+
+	"github.com/freiheit-com/kuberpult/pkg/logger"
+	"github.com/freiheit-com/kuberpult/pkg/setup"
+)
+
+const (
+	denyQuery = "data.kuberpult.deny"
+	warnQuery = "data.kuberpult.warn"
+)
+
+// Result is the outcome of evaluating the policies for one input.
+type Result struct {
+	// Deny contains the messages of all violated deny rules. The action must not be executed if it is not empty.
+	Deny []string
+	// Warn contains the messages of all violated warn rules.
+	Warn []string
+}
+
+// Engine evaluates the policies of a file or directory.
+type Engine struct {
+	path string
+
+	mx          sync.RWMutex
+	loaded      bool
+	fingerprint [sha256.Size]byte
+	deny        rego.PreparedEvalQuery
+	warn        rego.PreparedEvalQuery
+}
+
+// NewEngine loads the policies at path. It fails if they do not compile.
+func NewEngine(ctx context.Context, path string) (*Engine, error) {
+	//exhaustruct:ignore
+	engine := &Engine{path: path}
+	if _, err := engine.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return engine, nil
+}
+
+// Reload compiles the policies again if their files changed since the last load, and reports whether they did.
+// If the changed policies do not compile, the previous policies stay active.
+func (e *Engine) Reload(ctx context.Context) (bool, error) {
+	modules, fingerprint, err := readModules(e.path)
+	if err != nil {
+		return false, err
+	}
+	e.mx.RLock()
+	unchanged := e.loaded && e.fingerprint == fingerprint
+	e.mx.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+	deny, err := prepare(ctx, denyQuery, modules)
+	if err != nil {
+		return false, err
+	}
+	warn, err := prepare(ctx, warnQuery, modules)
+	if err != nil {
+		return false, err
+	}
+	e.mx.Lock()
+	defer e.mx.Unlock()
+	e.loaded = true
+	e.fingerprint = fingerprint
+	e.deny = deny
+	e.warn = warn
+	return true, nil
+}
+
+// ReloadRegularly checks the policy files for changes every interval, so that changed policies
+// take effect without a restart.
+func (e *Engine) ReloadRegularly(ctx context.Context, interval time.Duration, health *setup.HealthReporter) error {
+	return health.Retry(ctx, func() error {
+		health.ReportReady("reloading policies")
+		log := logger.FromContext(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return setup.Permanent(nil)
+			case <-time.After(interval):
+			}
+			reloaded, err := e.Reload(ctx)
+			if err != nil {
+				log.Error("policy.reload.failed", zap.String("path", e.path), zap.Error(err))
+				continue
+			}
+			if reloaded {
+				log.Info("policy.reloaded", zap.String("path", e.path))
+			}
+		}
+	})
+}
+
+// Evaluate evaluates all policies for the input.
+func (e *Engine) Evaluate(ctx context.Context, input *Input) (*Result, error) {
+	e.mx.RLock()
+	deny, warn := e.deny, e.warn
+	e.mx.RUnlock()
+	denyMessages, err := evaluate(ctx, deny, input)
+	if err != nil {
+		return nil, err
+	}
+	warnMessages, err := evaluate(ctx, warn, input)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{
+		Deny: denyMessages,
+		Warn: warnMessages,
+	}, nil
+}
+
+func evaluate(ctx context.Context, query rego.PreparedEvalQuery, input *Input) ([]string, error) {
+	resultSet, err := query.Eval(ctx, rego.EvalInput(input))
+	if err != nil {
+		return nil, fmt.Errorf("could not evaluate policies: %w", err)
+	}
+	messages := []string{}
+	for _, result := range resultSet {
+		for _, expression := range result.Expressions {
+			values, ok := expression.Value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s must be a set of messages, but is %T", expression.Text, expression.Value)
+			}
+			for _, value := range values {
+				if message, ok := value.(string); ok {
+					messages = append(messages, message)
+				} else {
+					messages = append(messages, fmt.Sprint(value))
+				}
+			}
+		}
+	}
+	sort.Strings(messages)
+	return messages, nil
+}
+
+func prepare(ctx context.Context, query string, modules []module) (rego.PreparedEvalQuery, error) {
+	options := []func(*rego.Rego){rego.Query(query)}
+	for _, m := range modules {
+		options = append(options, rego.Module(m.name, m.content))
+	}
+	prepared, err := rego.New(options...).PrepareForEval(ctx)
+	if err != nil {
+		return rego.PreparedEvalQuery{}, fmt.Errorf("could not compile policies: %w", err)
+	}
+	return prepared, nil
+}
+
+type module struct {
+	name    string
+	content string
+}
+
+// readModules reads all .rego files at path, sorted by name, and a fingerprint of their contents.
+// Symbolic links are followed and names starting with ".." are skipped,
+// so that a mounted Kubernetes ConfigMap is read only once.
+func readModules(path string) ([]module, [sha256.Size]byte, error) {
+	modules := []module{}
+	err := walk(path, func(file string) error {
+		if filepath.Ext(file) != ".rego" {
+			return nil
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		modules = append(modules, module{name: file, content: string(content)})
+		return nil
+	})
+	if err != nil {
+		return nil, [sha256.Size]byte{}, fmt.Errorf("could not read policies: %w", err)
+	}
+	if len(modules) == 0 {
+		return nil, [sha256.Size]byte{}, fmt.Errorf("could not read policies: no .rego files found in %s", path)
+	}
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].name < modules[j].name
+	})
+	hash := sha256.New()
+	for _, m := range modules {
+		_, _ = fmt.Fprintf(hash, "%s\x00%s\x00", m.name, m.content)
+	}
+	var fingerprint [sha256.Size]byte
+	copy(fingerprint[:], hash.Sum(nil))
+	return modules, fingerprint, nil
+}
+
+func walk(path string, fn func(file string) error) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fn(path)
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "..") {
+			continue
+		}
+		if err := walk(filepath.Join(path, entry.Name()), fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package policy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/freiheit-com/kuberpult/pkg/types"
+)
+
+const fridayPolicy = `package kuberpult
+
+deny contains msg if {
+	input.action == "deploy"
+	input.state.environments[input.request.environment].priority == "PROD"
+	time.weekday(time.parse_rfc3339_ns(input.time)) == "Friday"
+	msg := sprintf("%s must not be deployed to %s on Fridays", [input.request.application, input.request.environment])
+}
+`
+
+const latestTagPolicy = `package kuberpult
+
+warn contains msg if {
+	input.action == "create_release"
+	some env, i
+	container := input.manifests[env][i].spec.template.spec.containers[_]
+	endswith(container.image, ":latest")
+	msg := sprintf("%s uses the latest tag in %s", [container.name, env])
+}
+`
+
+func writePolicy(t *testing.T, dir string, name string, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func deployInput(now time.Time) *Input {
+	return &Input{
+		Action:    ActionDeploy,
+		User:      User{Name: "test", Email: "test@example.com", Roles: []string{}},
+		Time:      now,
+		Request:   map[string]interface{}{"environment": "production", "application": "app1", "version": "3"},
+		Manifests: nil,
+		State: State{
+			Environments: map[types.EnvName]Environment{
+				"production": {Group: "production", Priority: "PROD"},
+				"staging":    {Group: "staging", Priority: "UPSTREAM"},
+			},
+			Application: nil,
+		},
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	friday := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	monday := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	manifest := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app1
+spec:
+  template:
+    spec:
+      containers:
+      - name: app1
+        image: registry.example.com/app1:latest
+`
+	tcs := []struct {
+		Name     string
+		Input    *Input
+		Expected *Result
+	}{
+		{
+			Name:     "deployment to production on a friday",
+			Input:    deployInput(friday),
+			Expected: &Result{Deny: []string{"app1 must not be deployed to production on Fridays"}, Warn: []string{}},
+		},
+		{
+			Name:     "deployment to production on a monday",
+			Input:    deployInput(monday),
+			Expected: &Result{Deny: []string{}, Warn: []string{}},
+		},
+		{
+			Name: "release with the latest tag",
+			Input: &Input{
+				Action:    ActionCreateRelease,
+				Time:      friday,
+				Request:   map[string]interface{}{"application": "app1"},
+				Manifests: parseManifests(map[string]string{"staging": manifest, "production": "---\n# empty"}),
+			},
+			Expected: &Result{Deny: []string{}, Warn: []string{"app1 uses the latest tag in staging"}},
+		},
+	}
+	dir := t.TempDir()
+	writePolicy(t, dir, "friday.rego", fridayPolicy)
+	writePolicy(t, dir, "latest.rego", latestTagPolicy)
+	engine, err := NewEngine(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			actual, err := engine.Evaluate(context.Background(), tc.Input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.Expected, actual); diff != "" {
+				t.Errorf("result mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReload(t *testing.T) {
+	ctx := context.Background()
+	friday := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	// a mounted ConfigMap links its files to a timestamped directory:
+	dir := t.TempDir()
+	data := filepath.Join(dir, "..2026_10_16")
+	if err := os.Mkdir(data, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writePolicy(t, data, "friday.rego", fridayPolicy)
+	if err := os.Symlink(filepath.Join(data, "friday.rego"), filepath.Join(dir, "friday.rego")); err != nil {
+		t.Fatal(err)
+	}
+
+	engine, err := NewEngine(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertDeny := func(expected []string) {
+		t.Helper()
+		result, err := engine.Evaluate(ctx, deployInput(friday))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(expected, result.Deny); diff != "" {
+			t.Errorf("deny mismatch (-want, +got):\n%s", diff)
+		}
+	}
+	assertDeny([]string{"app1 must not be deployed to production on Fridays"})
+
+	if reloaded, err := engine.Reload(ctx); err != nil || reloaded {
+		t.Fatalf("expected no reload without changes, got %t, %v", reloaded, err)
+	}
+
+	writePolicy(t, data, "friday.rego", "package kuberpult\n\ndeny contains \"frozen\" if { true }\n")
+	if reloaded, err := engine.Reload(ctx); err != nil || !reloaded {
+		t.Fatalf("expected a reload after a change, got %t, %v", reloaded, err)
+	}
+	assertDeny([]string{"frozen"})
+
+	writePolicy(t, data, "friday.rego", "package kuberpult\n\ndeny contains")
+	if _, err := engine.Reload(ctx); err == nil {
+		t.Fatalf("expected an error for an invalid policy")
+	}
+	assertDeny([]string{"frozen"})
+}
+
+func TestNewEngineWithoutPolicies(t *testing.T) {
+	if _, err := NewEngine(context.Background(), t.TempDir()); err == nil {
+		t.Fatalf("expected an error for a directory without policies")
+	}
+}
@@ -0,0 +1,535 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/freiheit-com/kuberpult/pkg/api/v1"
+	"github.com/freiheit-com/kuberpult/pkg/auth"
+	"github.com/freiheit-com/kuberpult/pkg/config"
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/grpc"
+	"github.com/freiheit-com/kuberpult/pkg/logging"
+	"github.com/freiheit-com/kuberpult/pkg/mapper"
+	"github.com/freiheit-com/kuberpult/pkg/setup"
+	"github.com/freiheit-com/kuberpult/pkg/types"
+)
+
+// DeploymentApprovalConfig configures which deployments need a four-eyes approval.
+type DeploymentApprovalConfig struct {
+	Enabled bool
+	// Environments that require an approval. If empty, all environments with priority PROD require an approval.
+	Environments []types.EnvName
+	// Pending approvals can only be approved during this time.
+	Expiry time.Duration
+	// Approved, rejected and expired approvals are deleted after this time.
+	Retention time.Duration
+}
+
+// approvalExpiryInterval is the time between two runs of ExpireDeploymentApprovals.
+const approvalExpiryInterval = time.Minute
+
+// EnvironmentsRequiringApproval returns the sorted environments that the given deployment or
+// release train would deploy to and that require an approval.
+// A release train to an environment group requires an approval as a whole, if any of its environments does.
+func (c DeploymentApprovalConfig) EnvironmentsRequiringApproval(configs map[types.EnvName]config.EnvironmentConfig, transformer Transformer) []types.EnvName {
+	if !c.Enabled {
+		return nil
+	}
+	var targets []types.EnvName
+	switch tr := transformer.(type) {
+	case *DeployApplicationVersion:
+		targets = []types.EnvName{tr.Environment}
+	case *DeployQueuedVersion:
+		targets = []types.EnvName{tr.Environment}
+	case *ReleaseTrain:
+		envConfigs, _ := GetEnvironmentGroupsEnvironmentsOrEnvironment(configs, tr.Target, tr.TargetType)
+		for env := range envConfigs {
+			targets = append(targets, env)
+		}
+	default:
+		return nil
+	}
+	required := c.approvalEnvironments(configs)
+	result := []types.EnvName{}
+	for _, env := range targets {
+		if required[env] {
+			result = append(result, env)
+		}
+	}
+	return types.Sort(result)
+}
+
+func (c DeploymentApprovalConfig) approvalEnvironments(configs map[types.EnvName]config.EnvironmentConfig) map[types.EnvName]bool {
+	result := map[types.EnvName]bool{}
+	if len(c.Environments) > 0 {
+		for _, env := range c.Environments {
+			result[env] = true
+		}
+		return result
+	}
+	for _, group := range mapper.MapEnvironmentsToGroups(configs) {
+		for _, env := range group.Environments {
+			if env.Priority == api.Priority_PROD {
+				result[types.EnvName(env.Name)] = true
+			}
+		}
+	}
+	return result
+}
+
+// NewDeploymentApprovalRequest converts a deployment or release train into the request that is stored with the approval.
+func NewDeploymentApprovalRequest(transformer Transformer) (db.DeploymentApprovalRequest, error) {
+	switch tr := transformer.(type) {
+	case *DeployApplicationVersion:
+		var rollback *db.DeploymentApprovalRollback
+		if tr.AutoRollback != nil {
+			rollback = &db.DeploymentApprovalRollback{
+				RolledBackVersion: tr.AutoRollback.RolledBackVersion,
+				Reason:            tr.AutoRollback.Reason,
+			}
+		}
+		return db.DeploymentApprovalRequest{
+			Deployment: &db.DeploymentApprovalDeployment{
+				Environment:   tr.Environment,
+				Application:   tr.Application,
+				Version:       tr.Version,
+				Revision:      tr.Revision,
+				LockBehaviour: tr.LockBehaviour.String(),
+				Author:        tr.Author,
+				CiLink:        tr.CiLink,
+				AutoRollback:  rollback,
+			},
+			ReleaseTrain: nil,
+		}, nil
+	case *DeployQueuedVersion:
+		// queued versions always have revision 0, see QueueApplicationVersion
+		return db.DeploymentApprovalRequest{
+			Deployment: &db.DeploymentApprovalDeployment{
+				Environment:   tr.Environment,
+				Application:   tr.Application,
+				Version:       tr.Version,
+				Revision:      0,
+				LockBehaviour: api.LockBehavior_IGNORE.String(),
+				Author:        "",
+				CiLink:        "",
+				AutoRollback:  nil,
+			},
+			ReleaseTrain: nil,
+		}, nil
+	case *ReleaseTrain:
+		return db.DeploymentApprovalRequest{
+			Deployment: nil,
+			ReleaseTrain: &db.DeploymentApprovalReleaseTrain{
+				Target:     tr.Target,
+				TargetType: tr.TargetType,
+				Team:       tr.Team,
+				CommitHash: tr.CommitHash,
+				CiLink:     tr.CiLink,
+				GitTag:     tr.GitTag,
+			},
+		}, nil
+	default:
+		return db.DeploymentApprovalRequest{}, fmt.Errorf("transformer %s cannot be approved", transformer.GetDBEventType())
+	}
+}
+
+// CreateDeploymentApproval stores a deployment or release train that waits for an approval.
+// The permissions of the requester are checked right away, so that only deployments the requester is allowed to do can be approved.
+type CreateDeploymentApproval struct {
+	Authentication        `json:"-"`
+	ApprovalId            string                       `json:"approvalId"`
+	Environments          []types.EnvName              `json:"environments"`
+	Request               db.DeploymentApprovalRequest `json:"request"`
+	Expiry                time.Duration                `json:"expiry"`
+	TransformerEslVersion db.TransformerID             `json:"-"` // Tags the transformer with EventSourcingLight eslVersion
+}
+
+func (c *CreateDeploymentApproval) GetDBEventType() db.EventType {
+	return db.EvtCreateDeploymentApproval
+}
+
+func (c *CreateDeploymentApproval) SetEslVersion(id db.TransformerID) {
+	c.TransformerEslVersion = id
+}
+
+func (c *CreateDeploymentApproval) GetEslVersion() db.TransformerID {
+	return c.TransformerEslVersion
+}
+
+func (c *CreateDeploymentApproval) Transform(
+	ctx context.Context,
+	state *State,
+	t TransformerContext,
+	transaction *sql.Tx,
+) (string, error) {
+	user, err := auth.ReadUserFromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	var app types.AppName
+	switch {
+	case c.Request.Deployment != nil:
+		deployment := c.Request.Deployment
+		app = deployment.Application
+		err = state.checkUserPermissions(ctx, transaction, deployment.Environment, deployment.Application, auth.PermissionDeployRelease, "", c.RBACConfig, true)
+		if err != nil {
+			return "", err
+		}
+		version := deployment.Version
+		release, err := state.DBHandler.DBSelectReleaseByVersion(ctx, transaction, deployment.Application, types.ReleaseNumbers{Version: &version, Revision: deployment.Revision}, true)
+		if err != nil {
+			return "", err
+		}
+		if release == nil {
+			return "", grpc.FailedPrecondition(ctx, fmt.Errorf("could not find version %d for app %s", deployment.Version, deployment.Application))
+		}
+	case c.Request.ReleaseTrain != nil:
+		for _, env := range c.Environments {
+			err = state.checkUserPermissions(ctx, transaction, env, "*", auth.PermissionDeployReleaseTrain, c.Request.ReleaseTrain.Team, c.RBACConfig, false)
+			if err != nil {
+				return "", err
+			}
+		}
+	default:
+		return "", grpc.InvalidArgument(ctx, fmt.Errorf("deployment approval %s has no request", c.ApprovalId))
+	}
+
+	now, err := state.DBHandler.DBReadTransactionTimestamp(ctx, transaction)
+	if err != nil {
+		return "", err
+	}
+	if now == nil {
+		return "", fmt.Errorf("could not get transaction timestamp: nil")
+	}
+	var roles []string
+	if user.DexAuthContext != nil {
+		roles = user.DexAuthContext.Role
+	}
+	err = state.DBHandler.DBInsertDeploymentApproval(ctx, transaction, db.DeploymentApproval{
+		ApprovalId:          c.ApprovalId,
+		Created:             *now,
+		ExpiresAt:           now.Add(c.Expiry),
+		Status:              db.DeploymentApprovalStatusPending,
+		App:                 app,
+		Environments:        c.Environments,
+		Request:             c.Request,
+		RequestedByName:     user.Name,
+		RequestedByEmail:    user.Email,
+		RequestedByIdentity: user.Identity,
+		RequestedByRoles:    roles,
+		RequestEslVersion:   c.TransformerEslVersion,
+		DecidedAt:           nil,
+		DecidedByName:       "",
+		DecidedByEmail:      "",
+		DecidedByIdentity:   "",
+		Reason:              "",
+		DecisionEslVersion:  0,
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Deployment to %s waits for approval %s", strings.Join(types.EnvNamesToStrings(c.Environments), ", "), c.ApprovalId), nil
+}
+
+// readPendingApproval returns the approval if it can still be decided by the current user.
+func readPendingApproval(ctx context.Context, state *State, transaction *sql.Tx, approvalId string, rbacConfig auth.RBACConfig) (*db.DeploymentApproval, *auth.User, time.Time, error) {
+	approval, err := state.DBHandler.DBSelectDeploymentApproval(ctx, transaction, approvalId)
+	if err != nil {
+		return nil, nil, time.Time{}, err
+	}
+	if approval == nil {
+		return nil, nil, time.Time{}, grpc.NotFoundError(ctx, fmt.Errorf("deployment approval %s not found", approvalId))
+	}
+	if approval.Status != db.DeploymentApprovalStatusPending {
+		return nil, nil, time.Time{}, grpc.FailedPrecondition(ctx, fmt.Errorf("deployment approval %s was already %s", approvalId, approval.Status))
+	}
+	now, err := state.DBHandler.DBReadTransactionTimestamp(ctx, transaction)
+	if err != nil {
+		return nil, nil, time.Time{}, err
+	}
+	if now == nil {
+		return nil, nil, time.Time{}, fmt.Errorf("could not get transaction timestamp: nil")
+	}
+	if approval.IsExpired(*now) {
+		return nil, nil, time.Time{}, grpc.FailedPrecondition(ctx, fmt.Errorf("deployment approval %s expired at %s", approvalId, approval.ExpiresAt.Format(time.RFC3339)))
+	}
+	user, err := auth.ReadUserFromContext(ctx)
+	if err != nil {
+		return nil, nil, time.Time{}, err
+	}
+	for _, env := range approval.Environments {
+		app := approval.App
+		if app == "" {
+			app = "*"
+		}
+		err = state.checkUserPermissions(ctx, transaction, env, app, auth.PermissionApproveDeployment, "", rbacConfig, false)
+		if err != nil {
+			return nil, nil, time.Time{}, err
+		}
+	}
+	return approval, user, *now, nil
+}
+
+// checkApprover returns an error if the user must not approve the approval.
+// Only users that logged in can approve, since name and email can be chosen freely otherwise.
+// The approver must not be the requester, which is compared by the verified identity.
+// If the requester was not logged in, the email is compared as well, so they cannot approve it after logging in.
+func checkApprover(ctx context.Context, approval *db.DeploymentApproval, user *auth.User) error {
+	if user.Identity == "" {
+		return grpc.AuthError(ctx, fmt.Errorf("deployment approval %s can only be approved by a user that logged in", approval.ApprovalId))
+	}
+	if user.Identity == approval.RequestedByIdentity || strings.EqualFold(user.Email, approval.RequestedByEmail) {
+		return grpc.FailedPrecondition(ctx, fmt.Errorf("deployment approval %s cannot be approved by the user that requested it", approval.ApprovalId))
+	}
+	return nil
+}
+
+// ApproveDeployment approves a pending deployment and executes it.
+// The approver must not be the requester. The deployment itself is executed on behalf of the requester.
+type ApproveDeployment struct {
+	Authentication        `json:"-"`
+	ApprovalId            string           `json:"approvalId"`
+	WriteCommitData       bool             `json:"writeCommitData"`
+	Repo                  Repository       `json:"-"`
+	AllowedDomains        []string         `json:"-"`
+	TransformerEslVersion db.TransformerID `json:"-"` // Tags the transformer with EventSourcingLight eslVersion
+}
+
+func (c *ApproveDeployment) GetDBEventType() db.EventType {
+	return db.EvtApproveDeployment
+}
+
+func (c *ApproveDeployment) SetEslVersion(id db.TransformerID) {
+	c.TransformerEslVersion = id
+}
+
+func (c *ApproveDeployment) GetEslVersion() db.TransformerID {
+	return c.TransformerEslVersion
+}
+
+func (c *ApproveDeployment) Transform(
+	ctx context.Context,
+	state *State,
+	t TransformerContext,
+	transaction *sql.Tx,
+) (string, error) {
+	approval, user, now, err := readPendingApproval(ctx, state, transaction, c.ApprovalId, c.RBACConfig)
+	if err != nil {
+		return "", err
+	}
+	if err := checkApprover(ctx, approval, user); err != nil {
+		return "", err
+	}
+	approval.Status = db.DeploymentApprovalStatusApproved
+	approval.DecidedAt = &now
+	approval.DecidedByName = user.Name
+	approval.DecidedByEmail = user.Email
+	approval.DecidedByIdentity = user.Identity
+	approval.DecisionEslVersion = c.TransformerEslVersion
+	updated, err := state.DBHandler.DBUpdateDeploymentApprovalDecision(ctx, transaction, *approval)
+	if err != nil {
+		return "", err
+	}
+	if !updated {
+		return "", grpc.FailedPrecondition(ctx, fmt.Errorf("deployment approval %s is not pending anymore", c.ApprovalId))
+	}
+
+	var requesterDexContext *auth.DexAuthContext
+	if approval.RequestedByRoles != nil {
+		requesterDexContext = &auth.DexAuthContext{Role: approval.RequestedByRoles}
+	}
+	requesterCtx := auth.WriteUserToContext(ctx, auth.User{
+		Email:          approval.RequestedByEmail,
+		Name:           approval.RequestedByName,
+		Identity:       approval.RequestedByIdentity,
+		DexAuthContext: requesterDexContext,
+	})
+	var deployment Transformer
+	if request := approval.Request.Deployment; request != nil {
+		var rollback *DeployApplicationVersionRollback
+		if request.AutoRollback != nil {
+			rollback = &DeployApplicationVersionRollback{
+				RolledBackVersion: request.AutoRollback.RolledBackVersion,
+				Reason:            request.AutoRollback.Reason,
+			}
+		}
+		deployment = &DeployApplicationVersion{
+			Authentication:        c.Authentication,
+			Environment:           request.Environment,
+			Application:           request.Application,
+			Version:               request.Version,
+			Revision:              request.Revision,
+			LockBehaviour:         api.LockBehavior(api.LockBehavior_value[request.LockBehaviour]),
+			WriteCommitData:       c.WriteCommitData,
+			SourceTrain:           nil,
+			AutoRollback:          rollback,
+			Author:                request.Author,
+			CiLink:                request.CiLink,
+			TransformerEslVersion: c.TransformerEslVersion,
+			SkipCleanup:           false,
+		}
+	} else if request := approval.Request.ReleaseTrain; request != nil {
+		deployment = &ReleaseTrain{
+			Authentication:        c.Authentication,
+			Target:                request.Target,
+			Team:                  request.Team,
+			CommitHash:            request.CommitHash,
+			WriteCommitData:       c.WriteCommitData,
+			Repo:                  c.Repo,
+			TransformerEslVersion: c.TransformerEslVersion,
+			TargetType:            request.TargetType,
+			CiLink:                request.CiLink,
+			AllowedDomains:        c.AllowedDomains,
+			GitTag:                request.GitTag,
+		}
+	} else {
+		return "", fmt.Errorf("deployment approval %s has no request", c.ApprovalId)
+	}
+	if err := t.Execute(requesterCtx, deployment, transaction); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Approved deployment %s requested by %s", c.ApprovalId, approval.RequestedByEmail), nil
+}
+
+// RejectDeployment rejects a pending deployment, which is then never executed.
+type RejectDeployment struct {
+	Authentication        `json:"-"`
+	ApprovalId            string           `json:"approvalId"`
+	Reason                string           `json:"reason"`
+	TransformerEslVersion db.TransformerID `json:"-"` // Tags the transformer with EventSourcingLight eslVersion
+}
+
+func (c *RejectDeployment) GetDBEventType() db.EventType {
+	return db.EvtRejectDeployment
+}
+
+func (c *RejectDeployment) SetEslVersion(id db.TransformerID) {
+	c.TransformerEslVersion = id
+}
+
+func (c *RejectDeployment) GetEslVersion() db.TransformerID {
+	return c.TransformerEslVersion
+}
+
+func (c *RejectDeployment) Transform(
+	ctx context.Context,
+	state *State,
+	t TransformerContext,
+	transaction *sql.Tx,
+) (string, error) {
+	approval, user, now, err := readPendingApproval(ctx, state, transaction, c.ApprovalId, c.RBACConfig)
+	if err != nil {
+		return "", err
+	}
+	approval.Status = db.DeploymentApprovalStatusRejected
+	approval.DecidedAt = &now
+	approval.DecidedByName = user.Name
+	approval.DecidedByEmail = user.Email
+	approval.DecidedByIdentity = user.Identity
+	approval.Reason = c.Reason
+	approval.DecisionEslVersion = c.TransformerEslVersion
+	updated, err := state.DBHandler.DBUpdateDeploymentApprovalDecision(ctx, transaction, *approval)
+	if err != nil {
+		return "", err
+	}
+	if !updated {
+		return "", grpc.FailedPrecondition(ctx, fmt.Errorf("deployment approval %s is not pending anymore", c.ApprovalId))
+	}
+	return fmt.Sprintf("Rejected deployment %s requested by %s", c.ApprovalId, approval.RequestedByEmail), nil
+}
+
+// deployOrRequestApproval executes the automatic deployment of a new release, like the deployment to an
+// environment that is configured to always get the latest version.
+// If the environment requires an approval, a pending approval is created instead, so that automatic deployments
+// follow the same rules as deployments that were requested explicitly.
+func deployOrRequestApproval(ctx context.Context, state *State, t TransformerContext, transaction *sql.Tx, configs map[types.EnvName]config.EnvironmentConfig, deployment *DeployApplicationVersion) error {
+	envs := state.DeploymentApproval.EnvironmentsRequiringApproval(configs, deployment)
+	if len(envs) == 0 {
+		return t.Execute(ctx, deployment, transaction)
+	}
+	request, err := NewDeploymentApprovalRequest(deployment)
+	if err != nil {
+		return err
+	}
+	return t.Execute(ctx, &CreateDeploymentApproval{
+		Authentication:        deployment.Authentication,
+		ApprovalId:            getGenerator(ctx).Generate(),
+		Environments:          envs,
+		Request:               request,
+		Expiry:                state.DeploymentApproval.Expiry,
+		TransformerEslVersion: deployment.TransformerEslVersion,
+	}, transaction)
+}
+
+// ExpireDeploymentApprovals is the BackgroundFunc registered in cmd/server.go.
+// It runs until ctx is cancelled. Every approvalExpiryInterval, it marks pending approvals as expired
+// once they can no longer be approved, and deletes decided approvals after cfg.Retention.
+func ExpireDeploymentApprovals(ctx context.Context, dbHandler *db.DBHandler, cfg DeploymentApprovalConfig, health *setup.HealthReporter) error {
+	return health.Retry(ctx, func() error {
+		health.ReportReady("expiring deployment approvals")
+		for {
+			err := dbHandler.WithTransaction(ctx, false, func(ctx context.Context, transaction *sql.Tx) error {
+				return expireDeploymentApprovalsOnce(ctx, dbHandler, transaction, cfg.Retention)
+			})
+			if err != nil {
+				return err
+			}
+			select {
+			case <-ctx.Done():
+				return setup.Permanent(nil)
+			case <-time.After(approvalExpiryInterval):
+			}
+		}
+	})
+}
+
+func expireDeploymentApprovalsOnce(ctx context.Context, dbHandler *db.DBHandler, transaction *sql.Tx, retention time.Duration) error {
+	now, err := dbHandler.DBReadTransactionTimestamp(ctx, transaction)
+	if err != nil {
+		return err
+	}
+	if now == nil {
+		return fmt.Errorf("could not get transaction timestamp: nil")
+	}
+	expired, err := dbHandler.DBExpireDeploymentApprovals(ctx, transaction, *now)
+	if err != nil {
+		return err
+	}
+	cutoff, err := db.DBReadCutoff(dbHandler, ctx, transaction)
+	if err != nil {
+		return err
+	}
+	var deleted int64
+	if cutoff != nil {
+		deleted, err = dbHandler.DBDeleteDeploymentApprovals(ctx, transaction, now.Add(-retention), *cutoff)
+		if err != nil {
+			return err
+		}
+	}
+	if expired > 0 || deleted > 0 {
+		logging.Info(ctx, "deployment approvals cleaned up", zap.Int64("expired", expired), zap.Int64("deleted", deleted))
+	}
+	return nil
+}
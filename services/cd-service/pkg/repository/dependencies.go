@@ -0,0 +1,178 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/freiheit-com/kuberpult/pkg/api/v1"
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/types"
+	"github.com/freiheit-com/kuberpult/pkg/valid"
+)
+
+// UnmetDependency is a dependency of a release that is not deployed in the required version in an environment
+type UnmetDependency struct {
+	db.ReleaseDependency
+	// DeployedVersion is nil if the dependency is not deployed in the environment
+	DeployedVersion *uint64
+}
+
+func (u UnmetDependency) String() string {
+	if u.DeployedVersion == nil {
+		return fmt.Sprintf("%s >= %d (not deployed)", u.Application, u.MinVersion)
+	}
+	return fmt.Sprintf("%s >= %d (deployed: %d)", u.Application, u.MinVersion, *u.DeployedVersion)
+}
+
+func describeUnmetDependencies(unmet []UnmetDependency) string {
+	result := make([]string, 0, len(unmet))
+	for _, u := range unmet {
+		result = append(result, u.String())
+	}
+	return strings.Join(result, ", ")
+}
+
+// checkDependencies validates the dependencies of a new release of app
+func checkDependencies(app types.AppName, dependencies []db.ReleaseDependency) error {
+	seen := make(map[types.AppName]bool, len(dependencies))
+	for _, dependency := range dependencies {
+		if !valid.ApplicationName(dependency.Application) {
+			return fmt.Errorf("invalid application name in dependency: '%s'", dependency.Application)
+		}
+		if dependency.Application == app {
+			return fmt.Errorf("application %q cannot depend on itself", app)
+		}
+		if dependency.MinVersion == 0 {
+			return fmt.Errorf("the minimum version of dependency %q must be positive", dependency.Application)
+		}
+		if seen[dependency.Application] {
+			return fmt.Errorf("application %q is listed as dependency more than once", dependency.Application)
+		}
+		seen[dependency.Application] = true
+	}
+	return nil
+}
+
+// unmetDependencies returns the dependencies that are not deployed in at least their minimum version.
+// deployedVersion returns the version of an app in the environment, or nil if it is not deployed there.
+func unmetDependencies(dependencies []db.ReleaseDependency, deployedVersion func(app types.AppName) *uint64) []UnmetDependency {
+	var result []UnmetDependency
+	for _, dependency := range dependencies {
+		version := deployedVersion(dependency.Application)
+		if version != nil && *version >= dependency.MinVersion {
+			continue
+		}
+		result = append(result, UnmetDependency{
+			ReleaseDependency: dependency,
+			DeployedVersion:   version,
+		})
+	}
+	return result
+}
+
+// GetUnmetDependencies checks the dependencies against the latest deployments in the environment
+func GetUnmetDependencies(ctx context.Context, state *State, transaction *sql.Tx, env types.EnvName, dependencies []db.ReleaseDependency) ([]UnmetDependency, error) {
+	deployed := make(map[types.AppName]*uint64, len(dependencies))
+	for _, dependency := range dependencies {
+		deployment, err := state.DBHandler.DBSelectLatestDeployment(ctx, transaction, dependency.Application, env)
+		if err != nil {
+			return nil, err
+		}
+		if deployment != nil {
+			deployed[dependency.Application] = deployment.ReleaseNumbers.Version
+		}
+	}
+	return unmetDependencies(dependencies, func(app types.AppName) *uint64 {
+		return deployed[app]
+	}), nil
+}
+
+// skipAppsWithUnmetDependencies marks all apps of a release train as skipped whose dependencies are met
+// neither by the current deployments in the environment nor by the versions that the train deploys.
+// Skipping an app can break the dependencies of others, so this repeats until nothing changes.
+func skipAppsWithUnmetDependencies(appsPrognoses map[types.AppName]ReleaseTrainApplicationPrognosis, deployed map[types.AppName]types.ReleaseNumbers) {
+	versionAfterTrain := func(app types.AppName) *uint64 {
+		if prognosis, ok := appsPrognoses[app]; ok && prognosis.SkipCause == nil {
+			return prognosis.Version.Version
+		}
+		return deployed[app].Version
+	}
+	for changed := true; changed; {
+		changed = false
+		for appName, prognosis := range appsPrognoses {
+			if prognosis.SkipCause != nil || len(prognosis.Dependencies) == 0 {
+				continue
+			}
+			unmet := unmetDependencies(prognosis.Dependencies, versionAfterTrain)
+			if len(unmet) == 0 {
+				continue
+			}
+			appsPrognoses[appName] = ReleaseTrainApplicationPrognosis{
+				SkipCause: &api.ReleaseTrainAppPrognosis_SkipCause{
+					SkipCause: api.ReleaseTrainAppSkipCause_APP_DEPENDENCY_NOT_MET,
+				},
+				EnvLocks:           nil,
+				TeamLocks:          nil,
+				AppLocks:           nil,
+				Version:            types.MakeReleaseNumberVersion(0),
+				Team:               prognosis.Team,
+				NewReleaseCommitId: prognosis.NewReleaseCommitId,
+				ExistingDeployment: nil,
+				RevivedRelease:     nil,
+				ArgoBracket:        "",
+				OldReleaseCommitId: "",
+				Dependencies:       prognosis.Dependencies,
+				UnmetDependencies:  unmet,
+				SignatureError:     nil,
+			}
+			changed = true
+		}
+	}
+}
+
+// sortByDependencies orders apps so that every app comes after the apps it depends on.
+// Apart from that, the order of appNames is kept.
+func sortByDependencies(appNames []types.AppName, dependencies func(app types.AppName) []db.ReleaseDependency) []types.AppName {
+	result := make([]types.AppName, 0, len(appNames))
+	added := make(map[types.AppName]bool, len(appNames))
+	visiting := make(map[types.AppName]bool)
+	var visit func(app types.AppName)
+	visit = func(app types.AppName) {
+		if added[app] || visiting[app] {
+			// cycles cannot be resolved, the order within them stays arbitrary
+			return
+		}
+		visiting[app] = true
+		for _, dependency := range dependencies(app) {
+			if slices.Contains(appNames, dependency.Application) {
+				visit(dependency.Application)
+			}
+		}
+		visiting[app] = false
+		added[app] = true
+		result = append(result, app)
+	}
+	for _, app := range appNames {
+		visit(app)
+	}
+	return result
+}
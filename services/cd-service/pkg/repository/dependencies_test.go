@@ -0,0 +1,188 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package repository
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/types"
+)
+
+func TestCheckDependencies(t *testing.T) {
+	tcs := []struct {
+		Name          string
+		Dependencies  []db.ReleaseDependency
+		ExpectedError string
+	}{
+		{
+			Name:          "no dependencies",
+			Dependencies:  nil,
+			ExpectedError: "",
+		},
+		{
+			Name: "valid dependencies",
+			Dependencies: []db.ReleaseDependency{
+				{Application: "payments", MinVersion: 412},
+				{Application: "accounts", MinVersion: 1},
+			},
+			ExpectedError: "",
+		},
+		{
+			Name: "depends on itself",
+			Dependencies: []db.ReleaseDependency{
+				{Application: "frontend", MinVersion: 3},
+			},
+			ExpectedError: `application "frontend" cannot depend on itself`,
+		},
+		{
+			Name: "invalid application name",
+			Dependencies: []db.ReleaseDependency{
+				{Application: "Payments!", MinVersion: 3},
+			},
+			ExpectedError: "invalid application name in dependency: 'Payments!'",
+		},
+		{
+			Name: "version zero",
+			Dependencies: []db.ReleaseDependency{
+				{Application: "payments", MinVersion: 0},
+			},
+			ExpectedError: `the minimum version of dependency "payments" must be positive`,
+		},
+		{
+			Name: "duplicate dependency",
+			Dependencies: []db.ReleaseDependency{
+				{Application: "payments", MinVersion: 1},
+				{Application: "payments", MinVersion: 2},
+			},
+			ExpectedError: `application "payments" is listed as dependency more than once`,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := checkDependencies("frontend", tc.Dependencies)
+			actual := ""
+			if err != nil {
+				actual = err.Error()
+			}
+			if actual != tc.ExpectedError {
+				t.Fatalf("expected error %q, got %q", tc.ExpectedError, actual)
+			}
+		})
+	}
+}
+
+func TestSkipAppsWithUnmetDependencies(t *testing.T) {
+	deploy := func(version uint64, dependencies ...db.ReleaseDependency) ReleaseTrainApplicationPrognosis {
+		return ReleaseTrainApplicationPrognosis{
+			Version:      types.MakeReleaseNumberVersion(version),
+			Dependencies: dependencies,
+		} //exhaustruct:ignore
+	}
+	locked := ReleaseTrainApplicationPrognosis{
+		SkipCause: &api.ReleaseTrainAppPrognosis_SkipCause{
+			SkipCause: api.ReleaseTrainAppSkipCause_APP_IS_LOCKED,
+		},
+	} //exhaustruct:ignore
+
+	tcs := []struct {
+		Name            string
+		Prognoses       map[types.AppName]ReleaseTrainApplicationPrognosis
+		Deployed        map[types.AppName]types.ReleaseNumbers
+		ExpectedSkipped map[types.AppName]api.ReleaseTrainAppSkipCause
+	}{
+		{
+			Name: "dependency already deployed",
+			Prognoses: map[types.AppName]ReleaseTrainApplicationPrognosis{
+				"frontend": deploy(5, db.ReleaseDependency{Application: "payments", MinVersion: 412}),
+			},
+			Deployed: map[types.AppName]types.ReleaseNumbers{
+				"payments": types.MakeReleaseNumberVersion(413),
+			},
+			ExpectedSkipped: map[types.AppName]api.ReleaseTrainAppSkipCause{},
+		},
+		{
+			Name: "dependency deployed by the same train",
+			Prognoses: map[types.AppName]ReleaseTrainApplicationPrognosis{
+				"frontend": deploy(5, db.ReleaseDependency{Application: "payments", MinVersion: 412}),
+				"payments": deploy(412),
+			},
+			Deployed: map[types.AppName]types.ReleaseNumbers{
+				"payments": types.MakeReleaseNumberVersion(400),
+			},
+			ExpectedSkipped: map[types.AppName]api.ReleaseTrainAppSkipCause{},
+		},
+		{
+			Name: "dependency not deployed",
+			Prognoses: map[types.AppName]ReleaseTrainApplicationPrognosis{
+				"frontend": deploy(5, db.ReleaseDependency{Application: "payments", MinVersion: 412}),
+			},
+			Deployed: map[types.AppName]types.ReleaseNumbers{},
+			ExpectedSkipped: map[types.AppName]api.ReleaseTrainAppSkipCause{
+				"frontend": api.ReleaseTrainAppSkipCause_APP_DEPENDENCY_NOT_MET,
+			},
+		},
+		{
+			Name: "skipped apps break the dependencies of other apps",
+			Prognoses: map[types.AppName]ReleaseTrainApplicationPrognosis{
+				"frontend": deploy(5, db.ReleaseDependency{Application: "payments", MinVersion: 412}),
+				"payments": deploy(412, db.ReleaseDependency{Application: "accounts", MinVersion: 20}),
+				"accounts": locked,
+			},
+			Deployed: map[types.AppName]types.ReleaseNumbers{
+				"payments": types.MakeReleaseNumberVersion(400),
+				"accounts": types.MakeReleaseNumberVersion(19),
+			},
+			ExpectedSkipped: map[types.AppName]api.ReleaseTrainAppSkipCause{
+				"frontend": api.ReleaseTrainAppSkipCause_APP_DEPENDENCY_NOT_MET,
+				"payments": api.ReleaseTrainAppSkipCause_APP_DEPENDENCY_NOT_MET,
+				"accounts": api.ReleaseTrainAppSkipCause_APP_IS_LOCKED,
+			},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			skipAppsWithUnmetDependencies(tc.Prognoses, tc.Deployed)
+			actual := map[types.AppName]api.ReleaseTrainAppSkipCause{}
+			for appName, prognosis := range tc.Prognoses {
+				if prognosis.SkipCause != nil {
+					actual[appName] = prognosis.SkipCause.SkipCause
+				}
+			}
+			if diff := cmp.Diff(tc.ExpectedSkipped, actual); diff != "" {
+				t.Fatalf("skipped apps mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSortByDependencies(t *testing.T) {
+	dependencies := map[types.AppName][]db.ReleaseDependency{
+		"a-frontend": {{Application: "c-payments", MinVersion: 1}},
+		"c-payments": {{Application: "d-accounts", MinVersion: 1}, {Application: "not-in-train", MinVersion: 1}},
+	}
+	actual := sortByDependencies([]types.AppName{"a-frontend", "b-other", "c-payments", "d-accounts"}, func(app types.AppName) []db.ReleaseDependency {
+		return dependencies[app]
+	})
+	expected := []types.AppName{"d-accounts", "c-payments", "a-frontend", "b-other"}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Fatalf("order mismatch (-want, +got):\n%s", diff)
+	}
+}
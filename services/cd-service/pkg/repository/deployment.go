@@ -20,6 +20,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strconv"
 	"time"
 
 	"go.uber.org/zap"
@@ -29,9 +30,11 @@ import (
 	"github.com/freiheit-com/kuberpult/pkg/config"
 	"github.com/freiheit-com/kuberpult/pkg/db"
 	"github.com/freiheit-com/kuberpult/pkg/event"
+	"github.com/freiheit-com/kuberpult/pkg/grpc"
 	"github.com/freiheit-com/kuberpult/pkg/logging"
 	"github.com/freiheit-com/kuberpult/pkg/types"
 	"github.com/freiheit-com/kuberpult/pkg/valid"
+	"github.com/freiheit-com/kuberpult/services/cd-service/pkg/gates"
 )
 
 func (c *DeployApplicationVersion) Transform(
@@ -40,6 +43,11 @@ func (c *DeployApplicationVersion) Transform(
 	t TransformerContext,
 	transaction *sql.Tx,
 ) (string, error) {
+	if c.AutoRollback != nil {
+		if err := c.checkAutoRollback(ctx, state, transaction); err != nil {
+			return "", err
+		}
+	}
 	prognosis, err := c.Prognosis(ctx, state, transaction)
 	if err != nil {
 		return "", err
@@ -47,19 +55,37 @@ func (c *DeployApplicationVersion) Transform(
 	return c.ApplyPrognosis(ctx, state, t, transaction, prognosis)
 }
 
+// checkAutoRollback only allows the rollout-service to mark a deployment as an automatic rollback,
+// since the rollback is shown as an event on the commit of the rolled back release.
+// If Dex is enabled, users with the AutoRollback permission on the environment may do so as well.
+func (c *DeployApplicationVersion) checkAutoRollback(ctx context.Context, state *State, transaction *sql.Tx) error {
+	user, err := auth.ReadUserFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if user.Identity == auth.RolloutServiceIdentity {
+		return nil
+	}
+	if c.RBACConfig.DexEnabled {
+		return state.checkUserPermissions(ctx, transaction, c.Environment, c.Application, auth.PermissionAutoRollback, "", c.RBACConfig, false)
+	}
+	return grpc.AuthError(ctx, fmt.Errorf("only the rollout-service can roll back app %q on environment %q automatically", c.Application, c.Environment))
+}
+
 type DeployApplicationVersion struct {
 	Authentication        `json:"-"`
-	Environment           types.EnvName                   `json:"env"`
-	Application           types.AppName                   `json:"app"`
-	Version               uint64                          `json:"version"`
-	Revision              uint64                          `json:"revision"`
-	LockBehaviour         api.LockBehavior                `json:"lockBehaviour"`
-	WriteCommitData       bool                            `json:"writeCommitData"`
-	SourceTrain           *DeployApplicationVersionSource `json:"sourceTrain"`
-	Author                string                          `json:"author"`
-	CiLink                string                          `json:"cilink"`
-	TransformerEslVersion db.TransformerID                `json:"-"` // Tags the transformer with EventSourcingLight eslVersion
-	SkipCleanup           bool                            `json:"-"`
+	Environment           types.EnvName                     `json:"env"`
+	Application           types.AppName                     `json:"app"`
+	Version               uint64                            `json:"version"`
+	Revision              uint64                            `json:"revision"`
+	LockBehaviour         api.LockBehavior                  `json:"lockBehaviour"`
+	WriteCommitData       bool                              `json:"writeCommitData"`
+	SourceTrain           *DeployApplicationVersionSource   `json:"sourceTrain"`
+	AutoRollback          *DeployApplicationVersionRollback `json:"autoRollback,omitempty"`
+	Author                string                            `json:"author"`
+	CiLink                string                            `json:"cilink"`
+	TransformerEslVersion db.TransformerID                  `json:"-"` // Tags the transformer with EventSourcingLight eslVersion
+	SkipCleanup           bool                              `json:"-"`
 }
 
 func (c *DeployApplicationVersion) GetDBEventType() db.EventType {
@@ -79,6 +105,12 @@ type DeployApplicationVersionSource struct {
 	Upstream    types.EnvName `json:"upstream"`
 }
 
+// DeployApplicationVersionRollback is set when the rollout-service rolls back a degraded rollout
+type DeployApplicationVersionRollback struct {
+	RolledBackVersion uint64 `json:"rolledBackVersion"`
+	Reason            string `json:"reason"`
+}
+
 type DeployPrognosis struct {
 	TeamName          string
 	EnvironmentConfig *config.EnvironmentConfig
@@ -87,10 +119,16 @@ type DeployPrognosis struct {
 	EnvLocks  map[string]Lock
 	AppLocks  map[string]Lock
 	TeamLocks map[string]Lock
+	// ActiveFreeze is the freeze of the environment that is active at the time of the transaction, if any
+	ActiveFreeze *config.EnvironmentFreeze
 
 	NewReleaseCommitId string
 	ExistingDeployment *db.Deployment
 	OldReleaseCommitId string
+	// UnmetDependencies are the dependencies of the release that are not deployed in the environment yet
+	UnmetDependencies []UnmetDependency
+	// SignatureError is set if the manifest is not signed as the signature policy of the environment requires
+	SignatureError error
 }
 
 func (c *DeployApplicationVersion) Prognosis(
@@ -138,6 +176,14 @@ func (c *DeployApplicationVersion) Prognosis(
 	if err != nil {
 		return nil, err
 	}
+	now, err := state.DBHandler.DBReadTransactionTimestamp(ctx, transaction)
+	if err != nil {
+		return nil, fmt.Errorf("could not get transaction timestamp: %w", err)
+	}
+	activeFreeze, err := envConfig.ActiveFreeze(*now)
+	if err != nil {
+		return nil, fmt.Errorf("could not evaluate freezes of environment %q: %w", c.Environment, err)
+	}
 
 	newReleaseCommitId, _ := getCommitID(ctx, transaction, state, c.Version, c.Application)
 	// continue anyway, it's ok if there is no commitId!
@@ -158,6 +204,11 @@ func (c *DeployApplicationVersion) Prognosis(
 		// continue anyway, this is only for events
 	}
 
+	unmet, err := GetUnmetDependencies(ctx, state, transaction, c.Environment, version.Metadata.Dependencies)
+	if err != nil {
+		return nil, err
+	}
+
 	return &DeployPrognosis{
 		TeamName:           team,
 		EnvironmentConfig:  envConfig,
@@ -165,12 +216,47 @@ func (c *DeployApplicationVersion) Prognosis(
 		EnvLocks:           envLocks,
 		AppLocks:           appLocks,
 		TeamLocks:          teamLocks,
+		ActiveFreeze:       activeFreeze,
 		NewReleaseCommitId: newReleaseCommitId,
 		ExistingDeployment: existingDeployment,
 		OldReleaseCommitId: oldReleaseCommitId,
+		UnmetDependencies:  unmet,
+		SignatureError:     checkSignaturePolicy(envConfig, c.Environment, version),
 	}, nil
 }
 
+// checkDeploymentGates returns the first rejection of the deployment gates of the environment.
+// The gates are remote services, so they are not asked here: Apply asks them outside the transaction and runs it again.
+func (c *DeployApplicationVersion) checkDeploymentGates(ctx context.Context, prognosisData *DeployPrognosis, user *auth.User) *gates.Rejection {
+	if prognosisData.EnvironmentConfig == nil || len(prognosisData.EnvironmentConfig.DeploymentGates) == 0 {
+		return nil
+	}
+	verdicts := gates.VerdictsFromContext(ctx)
+	if verdicts == nil {
+		return &gates.Rejection{Gate: "", Message: "deployment gates are configured, but were not asked before the transaction"}
+	}
+	var fromVersion *uint64
+	if prognosisData.ExistingDeployment != nil {
+		fromVersion = prognosisData.ExistingDeployment.ReleaseNumbers.Version
+	}
+	rejection, decided := verdicts.Lookup(prognosisData.EnvironmentConfig.DeploymentGates, gates.Request{
+		Application: string(c.Application),
+		Environment: string(c.Environment),
+		FromVersion: fromVersion,
+		ToVersion:   c.Version,
+		Actor: gates.Actor{
+			Name:  user.Name,
+			Email: user.Email,
+		},
+		CommitId: prognosisData.NewReleaseCommitId,
+	})
+	if !decided {
+		// the transaction is rolled back, so it does not matter how the deployment continues
+		return &gates.Rejection{Gate: "", Message: "deployment gates were not asked yet"}
+	}
+	return rejection
+}
+
 func (c *DeployApplicationVersion) ApplyPrognosis(
 	ctx context.Context,
 	state *State,
@@ -188,29 +274,29 @@ func (c *DeployApplicationVersion) ApplyPrognosis(
 	if c.LockBehaviour != api.LockBehavior_IGNORE {
 		// Check that the environment is not locked
 		if len(prognosisData.EnvLocks) > 0 || len(prognosisData.AppLocks) > 0 || len(prognosisData.TeamLocks) > 0 {
-			if c.WriteCommitData {
-				var lockType, lockMsg string
-				if len(prognosisData.EnvLocks) > 0 {
-					lockType = "environment"
-					for _, lock := range prognosisData.EnvLocks {
+			var lockType, lockMsg string
+			if len(prognosisData.EnvLocks) > 0 {
+				lockType = "environment"
+				for _, lock := range prognosisData.EnvLocks {
+					lockMsg = lock.Message
+					break
+				}
+			} else {
+				if len(prognosisData.AppLocks) > 0 {
+					lockType = "application"
+					for _, lock := range prognosisData.AppLocks {
 						lockMsg = lock.Message
 						break
 					}
 				} else {
-					if len(prognosisData.AppLocks) > 0 {
-						lockType = "application"
-						for _, lock := range prognosisData.AppLocks {
-							lockMsg = lock.Message
-							break
-						}
-					} else {
-						lockType = "team"
-						for _, lock := range prognosisData.TeamLocks {
-							lockMsg = lock.Message
-							break
-						}
+					lockType = "team"
+					for _, lock := range prognosisData.TeamLocks {
+						lockMsg = lock.Message
+						break
 					}
 				}
+			}
+			if c.WriteCommitData {
 				ev := createLockPreventedDeploymentEvent(c.Application, envName, lockMsg, lockType)
 				if prognosisData.NewReleaseCommitId == "" {
 					logging.Info(ctx, "could not write event data - continuing.", zap.Error(err))
@@ -230,6 +316,7 @@ func (c *DeployApplicationVersion) ApplyPrognosis(
 					Environment: c.Environment,
 					Application: c.Application,
 					Version:     c.Version,
+					Reason:      fmt.Sprintf("%s lock: %s", lockType, lockMsg),
 				}
 				return q.Transform(ctx, state, t, transaction)
 			case api.LockBehavior_FAIL:
@@ -240,6 +327,65 @@ func (c *DeployApplicationVersion) ApplyPrognosis(
 				}
 			}
 		}
+		// Freezes behave like environment locks, so they are also ignored with LockBehavior_IGNORE
+		if freeze := prognosisData.ActiveFreeze; freeze != nil {
+			if c.WriteCommitData {
+				if prognosisData.NewReleaseCommitId == "" {
+					logging.Info(ctx, "could not write event data - continuing.")
+				} else {
+					ev := createFreezePreventedDeploymentEvent(c.Application, envName, freeze)
+					gen := getGenerator(ctx)
+					eventUuid := gen.Generate()
+					err = state.DBHandler.DBWriteFreezePreventedDeploymentEvent(ctx, transaction, c.TransformerEslVersion, eventUuid, prognosisData.NewReleaseCommitId, ev)
+					if err != nil {
+						return "", GetCreateReleaseGeneralFailure(err)
+					}
+				}
+			}
+			switch c.LockBehaviour {
+			case api.LockBehavior_RECORD:
+				q := QueueApplicationVersion{
+					Environment: c.Environment,
+					Application: c.Application,
+					Version:     c.Version,
+					Reason:      fmt.Sprintf("environment freeze %s: %s", freeze.Id, freeze.Message),
+				}
+				return q.Transform(ctx, state, t, transaction)
+			case api.LockBehavior_FAIL:
+				return "", &FrozenError{
+					Environment: envName,
+					Freeze:      *freeze,
+				}
+			}
+		}
+	}
+
+	// Unlike locks, dependencies cannot be ignored
+	if len(prognosisData.UnmetDependencies) > 0 {
+		unmet := describeUnmetDependencies(prognosisData.UnmetDependencies)
+		if c.LockBehaviour == api.LockBehavior_RECORD {
+			q := QueueApplicationVersion{
+				Environment: c.Environment,
+				Application: c.Application,
+				Version:     c.Version,
+				Reason:      fmt.Sprintf("unmet dependencies: %s", unmet),
+			}
+			return q.Transform(ctx, state, t, transaction)
+		}
+		return "", grpc.FailedPrecondition(ctx, fmt.Errorf("cannot deploy version %d of %q to %q because of unmet dependencies: %s", c.Version, c.Application, envName, unmet))
+	}
+
+	if prognosisData.SignatureError != nil {
+		if c.LockBehaviour == api.LockBehavior_RECORD {
+			q := QueueApplicationVersion{
+				Environment: c.Environment,
+				Application: c.Application,
+				Version:     c.Version,
+				Reason:      fmt.Sprintf("signature policy not met: %v", prognosisData.SignatureError),
+			}
+			return q.Transform(ctx, state, t, transaction)
+		}
+		return "", grpc.FailedPrecondition(ctx, fmt.Errorf("cannot deploy version %d of %q to %q: %w", c.Version, c.Application, envName, prognosisData.SignatureError))
 	}
 
 	user, err := auth.ReadUserFromContext(ctx)
@@ -247,6 +393,35 @@ func (c *DeployApplicationVersion) ApplyPrognosis(
 		return "", err
 	}
 
+	// Rejections are handled like locks, but unlike locks, deployment gates cannot be ignored:
+	// LockBehavior_IGNORE fails like LockBehavior_FAIL.
+	if rejection := c.checkDeploymentGates(ctx, prognosisData, user); rejection != nil {
+		if c.WriteCommitData && prognosisData.NewReleaseCommitId != "" {
+			ev := createLockPreventedDeploymentEvent(c.Application, envName, rejection.String(), "gate")
+			gen := getGenerator(ctx)
+			eventUuid := gen.Generate()
+			err = state.DBHandler.DBWriteLockPreventedDeploymentEvent(ctx, transaction, c.TransformerEslVersion, eventUuid, prognosisData.NewReleaseCommitId, ev)
+			if err != nil {
+				return "", GetCreateReleaseGeneralFailure(err)
+			}
+		}
+		if c.LockBehaviour == api.LockBehavior_RECORD {
+			q := QueueApplicationVersion{
+				Environment: c.Environment,
+				Application: c.Application,
+				Version:     c.Version,
+				Reason:      rejection.String(),
+			}
+			return q.Transform(ctx, state, t, transaction)
+		}
+		return "", &GateRejectedError{
+			Environment: envName,
+			Application: c.Application,
+			Version:     c.Version,
+			Rejection:   *rejection,
+		}
+	}
+
 	firstDeployment := false
 	var oldVersion *uint64
 
@@ -275,6 +450,11 @@ func (c *DeployApplicationVersion) ApplyPrognosis(
 	if err != nil {
 		return "", fmt.Errorf("could not write deployment for %v - %v", newDeployment, err)
 	}
+	if concreteEnvs := config.WaveEnvironments(prognosisData.EnvironmentConfig); concreteEnvs != nil {
+		if err := c.startWave(ctx, state, transaction, concreteEnvs); err != nil {
+			return "", err
+		}
+	}
 	t.AddAppEnv(c.Application, envName, prognosisData.TeamName)
 	s := State{
 		MinorRegexes:         state.MinorRegexes,
@@ -341,9 +521,72 @@ func (c *DeployApplicationVersion) ApplyPrognosis(
 		} else {
 			logging.Info(ctx, "Release to replace detected, but could not retrieve new commit information. Replaced-by event not stored.")
 		}
+
+		if c.AutoRollback != nil {
+			// the rollback event is shown on the commit of the release that was rolled back
+			oldReleaseCommitId := prognosisData.OldReleaseCommitId
+			if !valid.SHA1CommitID(oldReleaseCommitId) {
+				logging.Info(ctx, "could not find commit for rolled back release - skipping auto-rollback event", zap.Uint64("version", c.AutoRollback.RolledBackVersion), zap.String("application", string(c.Application)))
+			} else {
+				ev := createAutoRollbackEvent(c.Application, envName, c.AutoRollback, c.Version)
+				gen := getGenerator(ctx)
+				eventUuid := gen.Generate()
+				err = state.DBHandler.DBWriteAutoRollbackEvent(ctx, transaction, c.TransformerEslVersion, eventUuid, oldReleaseCommitId, ev)
+				if err != nil {
+					return "", err
+				}
+			}
+		}
+	}
+	if c.AutoRollback != nil {
+		return fmt.Sprintf("rolled back %q on %q from version %d to version %d", c.Application, c.Environment, c.AutoRollback.RolledBackVersion, c.Version), nil
 	}
 	return fmt.Sprintf("deployed version %d of %q to %q", c.Version, c.Application, c.Environment), nil
 }
+
+// startWave makes the new version the target of all concrete environments of an active/active environment
+// that is rolled out in waves. Only the first concrete environment gets it right away, the
+// waves package deploys it to the others one after the other.
+func (c *DeployApplicationVersion) startWave(ctx context.Context, state *State, transaction *sql.Tx, concreteEnvs []string) error {
+	now, err := state.DBHandler.DBReadTransactionTimestamp(ctx, transaction)
+	if err != nil {
+		return fmt.Errorf("could not get transaction timestamp: %w", err)
+	}
+	existingWaves, err := state.DBHandler.DBSelectAAWaveDeployments(ctx, transaction, c.Application, c.Environment)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]*db.AAWaveDeployment, len(existingWaves))
+	for _, wave := range existingWaves {
+		existing[wave.ConcreteEnv] = wave
+	}
+	target := types.MakeReleaseNumbers(c.Version, c.Revision)
+	waves := make([]db.AAWaveDeployment, 0, len(concreteEnvs))
+	for position, concreteEnv := range concreteEnvs {
+		wave := db.AAWaveDeployment{
+			App:           c.Application,
+			Env:           c.Environment,
+			ConcreteEnv:   concreteEnv,
+			Position:      position,
+			Target:        target,
+			TargetCreated: *now,
+			Deployed:      nil,
+			DeployedAt:    nil,
+			TransformerID: c.TransformerEslVersion,
+		}
+		if position == 0 {
+			wave.Deployed = &target
+			wave.DeployedAt = now
+		} else if previous, ok := existing[concreteEnv]; ok {
+			// the concrete environment keeps the version it has until the wave reaches it
+			wave.Deployed = previous.Deployed
+			wave.DeployedAt = previous.DeployedAt
+		}
+		waves = append(waves, wave)
+	}
+	return state.DBHandler.DBReplaceAAWaveDeployments(ctx, transaction, c.Application, c.Environment, waves)
+}
+
 func getCommitID(ctx context.Context, transaction *sql.Tx, state *State, release uint64, app types.AppName) (string, error) {
 	tmpList, err := state.DBHandler.DBSelectReleasesByVersions(ctx, transaction, app, []uint64{release}, false)
 	if err != nil {
@@ -378,6 +621,16 @@ func createDeploymentEvent(application types.AppName, environment types.EnvName,
 	return &ev
 }
 
+func createAutoRollbackEvent(application types.AppName, environment types.EnvName, rollback *DeployApplicationVersionRollback, restoredVersion uint64) *event.AutoRollback {
+	return &event.AutoRollback{
+		Application:       string(application),
+		Environment:       string(environment),
+		RolledBackVersion: strconv.FormatUint(rollback.RolledBackVersion, 10),
+		RestoredVersion:   strconv.FormatUint(restoredVersion, 10),
+		Reason:            rollback.Reason,
+	}
+}
+
 func createReplacedByEvent(application types.AppName, environment types.EnvName, commitId string) *event.ReplacedBy {
 	ev := event.ReplacedBy{
 		Application:       string(application),
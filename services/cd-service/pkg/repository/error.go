@@ -19,10 +19,15 @@ package repository
 import (
 	"fmt"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 
 	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
+	"github.com/freiheit-com/kuberpult/pkg/config"
 	"github.com/freiheit-com/kuberpult/pkg/types"
+	"github.com/freiheit-com/kuberpult/services/cd-service/pkg/gates"
+	"github.com/freiheit-com/kuberpult/services/cd-service/pkg/manifestvalidation"
 )
 
 type CreateReleaseError struct {
@@ -173,6 +178,30 @@ func GetCreateReleaseBracketMoveNotAllowed(appName types.AppName, currentBracket
 	}
 }
 
+func GetCreateReleaseInvalidManifest(findings []manifestvalidation.Finding) *CreateReleaseError {
+	response := api.CreateReleaseResponseInvalidManifest{
+		Findings: make([]*api.ManifestValidationFinding, 0, len(findings)),
+	}
+	for _, finding := range findings {
+		response.Findings = append(response.Findings, &api.ManifestValidationFinding{
+			Environment: string(finding.Environment),
+			Document:    uint32(finding.Document),
+			Kind:        finding.Kind,
+			Name:        finding.Name,
+			Rule:        finding.Rule,
+			Message:     finding.Message,
+		})
+	}
+	return &CreateReleaseError{
+		innerError: nil,
+		response: api.CreateReleaseResponse{
+			Response: &api.CreateReleaseResponse_InvalidManifest{
+				InvalidManifest: &response,
+			},
+		},
+	}
+}
+
 type LockedError struct {
 	EnvironmentApplicationLocks map[string]Lock
 	EnvironmentLocks            map[string]Lock
@@ -189,6 +218,44 @@ func (l *LockedError) Error() string {
 
 var _ error = (*LockedError)(nil)
 
+type FrozenError struct {
+	Environment types.EnvName
+	Freeze      config.EnvironmentFreeze
+}
+
+func (f *FrozenError) String() string {
+	return fmt.Sprintf("environment %q is frozen by freeze %q", f.Environment, f.Freeze.Id)
+}
+
+func (f *FrozenError) Error() string {
+	return f.String()
+}
+
+var _ error = (*FrozenError)(nil)
+
+// GateRejectedError is returned if a deployment gate rejected a deployment that must not be queued.
+// Unlike LockedError, it is a FailedPrecondition in the api.
+type GateRejectedError struct {
+	Environment types.EnvName
+	Application types.AppName
+	Version     uint64
+	Rejection   gates.Rejection
+}
+
+func (g *GateRejectedError) String() string {
+	return fmt.Sprintf("cannot deploy version %d of %q to %q: %s", g.Version, g.Application, g.Environment, g.Rejection.String())
+}
+
+func (g *GateRejectedError) Error() string {
+	return g.String()
+}
+
+func (g *GateRejectedError) GRPCStatus() *status.Status {
+	return status.New(codes.FailedPrecondition, g.String())
+}
+
+var _ error = (*GateRejectedError)(nil)
+
 type TeamNotFoundErr struct {
 	err error
 }
@@ -0,0 +1,227 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+
+	"github.com/freiheit-com/kuberpult/pkg/auth"
+	"github.com/freiheit-com/kuberpult/pkg/config"
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/grpc"
+	"github.com/freiheit-com/kuberpult/pkg/types"
+)
+
+// Freezes are part of the environment config, so changing them requires the same permission as changing the config.
+const permissionChangeFreeze = auth.PermissionCreateEnvironment
+
+// Creates or replaces the freeze with the same id
+type CreateEnvironmentFreeze struct {
+	Authentication        `json:"-"`
+	Environment           types.EnvName            `json:"env"`
+	Freeze                config.EnvironmentFreeze `json:"freeze"`
+	TransformerEslVersion db.TransformerID         `json:"-"` // Tags the transformer with EventSourcingLight eslVersion
+}
+
+func (c *CreateEnvironmentFreeze) GetDBEventType() db.EventType {
+	return db.EvtCreateEnvironmentFreeze
+}
+
+func (c *CreateEnvironmentFreeze) SetEslVersion(id db.TransformerID) {
+	c.TransformerEslVersion = id
+}
+
+func (c *CreateEnvironmentFreeze) GetEslVersion() db.TransformerID {
+	return c.TransformerEslVersion
+}
+
+func (c *CreateEnvironmentFreeze) Transform(
+	ctx context.Context,
+	state *State,
+	t TransformerContext,
+	transaction *sql.Tx,
+) (string, error) {
+	envName := c.Environment
+	err := state.checkUserPermissions(ctx, transaction, envName, "*", permissionChangeFreeze, "", c.RBACConfig, false)
+	if err != nil {
+		return "", err
+	}
+	if err := c.Freeze.Validate(); err != nil {
+		return "", grpc.FailedPrecondition(ctx, err)
+	}
+	env, err := state.DBHandler.DBSelectEnvironment(ctx, transaction, envName)
+	if err != nil {
+		return "", err
+	}
+	if env == nil {
+		return "", grpc.FailedPrecondition(ctx, fmt.Errorf("environment with name %q not found", envName))
+	}
+	idx := slices.IndexFunc(env.Config.Freezes, func(f config.EnvironmentFreeze) bool {
+		return f.Id == c.Freeze.Id
+	})
+	if idx == -1 {
+		env.Config.Freezes = append(env.Config.Freezes, c.Freeze)
+	} else {
+		env.Config.Freezes[idx] = c.Freeze
+	}
+	err = state.DBHandler.DBWriteEnvironment(ctx, transaction, envName, env.Config)
+	if err != nil {
+		return "", fmt.Errorf("could not write freeze %q of environment %q: %w", c.Freeze.Id, envName, err)
+	}
+	return fmt.Sprintf("Created freeze %q on environment %q", c.Freeze.Id, envName), nil
+}
+
+type DeleteEnvironmentFreeze struct {
+	Authentication        `json:"-"`
+	Environment           types.EnvName    `json:"env"`
+	FreezeId              string           `json:"freezeId"`
+	TransformerEslVersion db.TransformerID `json:"-"` // Tags the transformer with EventSourcingLight eslVersion
+}
+
+func (c *DeleteEnvironmentFreeze) GetDBEventType() db.EventType {
+	return db.EvtDeleteEnvironmentFreeze
+}
+
+func (c *DeleteEnvironmentFreeze) SetEslVersion(id db.TransformerID) {
+	c.TransformerEslVersion = id
+}
+
+func (c *DeleteEnvironmentFreeze) GetEslVersion() db.TransformerID {
+	return c.TransformerEslVersion
+}
+
+func (c *DeleteEnvironmentFreeze) Transform(
+	ctx context.Context,
+	state *State,
+	t TransformerContext,
+	transaction *sql.Tx,
+) (string, error) {
+	envName := c.Environment
+	err := state.checkUserPermissions(ctx, transaction, envName, "*", permissionChangeFreeze, "", c.RBACConfig, false)
+	if err != nil {
+		return "", err
+	}
+	env, err := state.DBHandler.DBSelectEnvironment(ctx, transaction, envName)
+	if err != nil {
+		return "", err
+	}
+	if env == nil {
+		return "", grpc.FailedPrecondition(ctx, fmt.Errorf("environment with name %q not found", envName))
+	}
+	idx := slices.IndexFunc(env.Config.Freezes, func(f config.EnvironmentFreeze) bool {
+		return f.Id == c.FreezeId
+	})
+	// We don't error out when the freeze does not exist to make this operation idempotent
+	if idx != -1 {
+		env.Config.Freezes = slices.Delete(env.Config.Freezes, idx, idx+1)
+		err = state.DBHandler.DBWriteEnvironment(ctx, transaction, envName, env.Config)
+		if err != nil {
+			return "", fmt.Errorf("could not delete freeze %q of environment %q: %w", c.FreezeId, envName, err)
+		}
+	}
+	return fmt.Sprintf("Deleted freeze %q on environment %q", c.FreezeId, envName), nil
+}
+
+// Creates or replaces the freeze with the same id on all environments of the group
+type CreateEnvironmentGroupFreeze struct {
+	Authentication        `json:"-"`
+	EnvironmentGroup      string                   `json:"envGroup"`
+	Freeze                config.EnvironmentFreeze `json:"freeze"`
+	TransformerEslVersion db.TransformerID         `json:"-"` // Tags the transformer with EventSourcingLight eslVersion
+}
+
+func (c *CreateEnvironmentGroupFreeze) GetDBEventType() db.EventType {
+	return db.EvtCreateEnvironmentGroupFreeze
+}
+
+func (c *CreateEnvironmentGroupFreeze) SetEslVersion(id db.TransformerID) {
+	c.TransformerEslVersion = id
+}
+
+func (c *CreateEnvironmentGroupFreeze) GetEslVersion() db.TransformerID {
+	return c.TransformerEslVersion
+}
+
+func (c *CreateEnvironmentGroupFreeze) Transform(
+	ctx context.Context,
+	state *State,
+	t TransformerContext,
+	transaction *sql.Tx,
+) (string, error) {
+	envNamesSorted, err := state.GetEnvironmentConfigsForGroup(ctx, transaction, c.EnvironmentGroup)
+	if err != nil {
+		return "", grpc.PublicError(ctx, err)
+	}
+	for _, envName := range envNamesSorted {
+		x := CreateEnvironmentFreeze{
+			Authentication:        c.Authentication,
+			Environment:           envName,
+			Freeze:                c.Freeze,
+			TransformerEslVersion: c.TransformerEslVersion,
+		}
+		if err := t.Execute(ctx, &x, transaction); err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("Created freeze %q on environment group %q", c.Freeze.Id, c.EnvironmentGroup), nil
+}
+
+type DeleteEnvironmentGroupFreeze struct {
+	Authentication        `json:"-"`
+	EnvironmentGroup      string           `json:"envGroup"`
+	FreezeId              string           `json:"freezeId"`
+	TransformerEslVersion db.TransformerID `json:"-"` // Tags the transformer with EventSourcingLight eslVersion
+}
+
+func (c *DeleteEnvironmentGroupFreeze) GetDBEventType() db.EventType {
+	return db.EvtDeleteEnvironmentGroupFreeze
+}
+
+func (c *DeleteEnvironmentGroupFreeze) SetEslVersion(id db.TransformerID) {
+	c.TransformerEslVersion = id
+}
+
+func (c *DeleteEnvironmentGroupFreeze) GetEslVersion() db.TransformerID {
+	return c.TransformerEslVersion
+}
+
+func (c *DeleteEnvironmentGroupFreeze) Transform(
+	ctx context.Context,
+	state *State,
+	t TransformerContext,
+	transaction *sql.Tx,
+) (string, error) {
+	envNamesSorted, err := state.GetEnvironmentConfigsForGroup(ctx, transaction, c.EnvironmentGroup)
+	if err != nil {
+		return "", grpc.PublicError(ctx, err)
+	}
+	for _, envName := range envNamesSorted {
+		x := DeleteEnvironmentFreeze{
+			Authentication:        c.Authentication,
+			Environment:           envName,
+			FreezeId:              c.FreezeId,
+			TransformerEslVersion: c.TransformerEslVersion,
+		}
+		if err := t.Execute(ctx, &x, transaction); err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("Deleted freeze %q on environment group %q", c.FreezeId, c.EnvironmentGroup), nil
+}
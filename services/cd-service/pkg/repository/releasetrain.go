@@ -21,6 +21,7 @@ import (
 	"database/sql"
 	"fmt"
 	"slices"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
@@ -50,6 +51,16 @@ func createLockPreventedDeploymentEvent(application types.AppName, environment t
 	return &ev
 }
 
+func createFreezePreventedDeploymentEvent(application types.AppName, environment types.EnvName, freeze *config.EnvironmentFreeze) *event.FreezePreventedDeployment {
+	ev := event.FreezePreventedDeployment{
+		Application:   string(application),
+		Environment:   string(environment),
+		FreezeId:      freeze.Id,
+		FreezeMessage: freeze.Message,
+	}
+	return &ev
+}
+
 type ReleaseTrain struct {
 	Authentication        `json:"-"`
 	Target                string           `json:"target"`
@@ -85,7 +96,7 @@ func (c *ReleaseTrain) getUpstreamLatestApp(ctx context.Context, transaction *sq
 		// check that commit hash is not older than 20 commits in the past
 		for _, app := range appVersions {
 			apps = append(apps, app.App)
-			versions, err := findOldApplicationVersions(ctx, transaction, state, app.App)
+			versions, err := findOldApplicationVersions(ctx, transaction, state, app.App, c.TransformerEslVersion)
 			if err != nil {
 				return nil, nil, grpc.PublicError(ctx, fmt.Errorf("unable to find findOldApplicationVersions for app %s: %w", app.App, err))
 			}
@@ -127,12 +138,19 @@ type ReleaseTrainApplicationPrognosis struct {
 	RevivedRelease     *db.DBReleaseWithMetaData
 	ArgoBracket        types.ArgoBracketName
 	OldReleaseCommitId string
+	// dependencies of the version to deploy, and the ones the release train cannot meet
+	Dependencies      []db.ReleaseDependency
+	UnmetDependencies []UnmetDependency
+	// SignatureError is only set if SkipCause is APP_SIGNATURE_POLICY_NOT_MET
+	SignatureError error
 }
 
 type ReleaseTrainEnvironmentPrognosis struct {
 	SkipCause *api.ReleaseTrainEnvPrognosis_SkipCause
 	Error     error
 	EnvLocks  map[string]*api.Lock
+	// ActiveFreeze is only set if SkipCause is ENV_IS_FROZEN
+	ActiveFreeze *config.EnvironmentFreeze
 
 	AppsPrognoses        map[types.AppName]ReleaseTrainApplicationPrognosis // map key is app name
 	AllLatestDeployments map[types.AppName]types.ReleaseNumbers             // map key is app name
@@ -150,6 +168,7 @@ func failedPrognosis(err error) *ReleaseTrainEnvironmentPrognosis {
 		SkipCause:            nil,
 		Error:                err,
 		EnvLocks:             nil,
+		ActiveFreeze:         nil,
 		AppsPrognoses:        nil,
 		AllLatestDeployments: nil,
 	}
@@ -494,6 +513,7 @@ func (c *envReleaseTrain) prognosis(ctx context.Context, state *State, transacti
 			},
 			Error:                nil,
 			EnvLocks:             nil,
+			ActiveFreeze:         nil,
 			AppsPrognoses:        nil,
 			AllLatestDeployments: map[types.AppName]types.ReleaseNumbers{},
 		}
@@ -524,6 +544,7 @@ func (c *envReleaseTrain) prognosis(ctx context.Context, state *State, transacti
 			},
 			Error:                nil,
 			EnvLocks:             nil,
+			ActiveFreeze:         nil,
 			AppsPrognoses:        nil,
 			AllLatestDeployments: map[types.AppName]types.ReleaseNumbers{},
 		}
@@ -536,6 +557,7 @@ func (c *envReleaseTrain) prognosis(ctx context.Context, state *State, transacti
 			},
 			Error:                nil,
 			EnvLocks:             nil,
+			ActiveFreeze:         nil,
 			AppsPrognoses:        nil,
 			AllLatestDeployments: map[types.AppName]types.ReleaseNumbers{},
 		}
@@ -550,6 +572,7 @@ func (c *envReleaseTrain) prognosis(ctx context.Context, state *State, transacti
 				},
 				Error:                nil,
 				EnvLocks:             nil,
+				ActiveFreeze:         nil,
 				AppsPrognoses:        nil,
 				AllLatestDeployments: map[types.AppName]types.ReleaseNumbers{},
 			}
@@ -649,6 +672,41 @@ func (c *envReleaseTrain) prognosis(ctx context.Context, state *State, transacti
 			},
 			Error:                nil,
 			EnvLocks:             envLocksMap,
+			ActiveFreeze:         nil,
+			AppsPrognoses:        appsPrognoses,
+			AllLatestDeployments: allLatestDeploymentsTargetEnv,
+		}
+	}
+
+	now, err := state.DBHandler.DBReadTransactionTimestamp(ctx, transaction)
+	if err != nil {
+		return failedPrognosis(grpc.InternalError(ctx, fmt.Errorf("could not get transaction timestamp: %w", err)))
+	}
+	activeFreeze, err := envConfig.ActiveFreeze(*now)
+	if err != nil {
+		return failedPrognosis(grpc.InternalError(ctx, fmt.Errorf("could not evaluate freezes of environment %q: %w", envName, err)))
+	}
+	if activeFreeze != nil {
+		for _, appName := range apps {
+			appsPrognoses[appName] = ReleaseTrainApplicationPrognosis{
+				SkipCause:          nil,
+				EnvLocks:           nil,
+				TeamLocks:          nil,
+				AppLocks:           nil,
+				Version:            types.MakeReleaseNumberVersion(0),
+				Team:               "",
+				NewReleaseCommitId: upstreamCommitIDByApp[appName],
+				ExistingDeployment: nil,
+				OldReleaseCommitId: "",
+			}
+		}
+		return &ReleaseTrainEnvironmentPrognosis{
+			SkipCause: &api.ReleaseTrainEnvPrognosis_SkipCause{
+				SkipCause: api.ReleaseTrainEnvSkipCause_ENV_IS_FROZEN,
+			},
+			Error:                nil,
+			EnvLocks:             nil,
+			ActiveFreeze:         activeFreeze,
 			AppsPrognoses:        appsPrognoses,
 			AllLatestDeployments: allLatestDeploymentsTargetEnv,
 		}
@@ -692,6 +750,25 @@ func (c *envReleaseTrain) prognosis(ctx context.Context, state *State, transacti
 		return failedPrognosis(fmt.Errorf("failed to get app locks for env=%s: %w", c.Env, err))
 	}
 
+	// versions that were deployed on the upstream env less than soakTime ago are not promoted yet.
+	// Release trains to a specific commit pick their own versions, so they are not affected.
+	// Only the elapsed time is checked, not the health of the version during the soak time.
+	soakTime, err := envConfig.Upstream.SoakDuration()
+	if err != nil {
+		return failedPrognosis(grpc.PublicError(ctx, fmt.Errorf("environment %q: %w", envName, err)))
+	}
+	var upstreamDeploymentTimes map[types.AppName]time.Time
+	soakNow := *now
+	if soakTime > 0 && !upstreamLatest && overrideVersions == nil {
+		upstreamDeploymentTimes, err = state.GetAllLatestDeploymentTimes(ctx, transaction, upstreamEnvName, ts)
+		if err != nil {
+			return failedPrognosis(grpc.PublicError(ctx, fmt.Errorf("could not obtain latest deployment times for env %s: %w", upstreamEnvName, err)))
+		}
+		if ts != nil {
+			soakNow = *ts
+		}
+	}
+
 	for _, appName := range apps {
 		if c.Parent.Team != "" {
 			team, ok := allTeams[appName]
@@ -773,6 +850,23 @@ func (c *envReleaseTrain) prognosis(ctx context.Context, state *State, transacti
 			continue
 		}
 
+		if deployedAt, ok := upstreamDeploymentTimes[appName]; ok && soakNow.Sub(deployedAt) < soakTime {
+			appsPrognoses[appName] = ReleaseTrainApplicationPrognosis{
+				SkipCause: &api.ReleaseTrainAppPrognosis_SkipCause{
+					SkipCause: api.ReleaseTrainAppSkipCause_APP_UPSTREAM_VERSION_TOO_YOUNG,
+				},
+				EnvLocks:           nil,
+				TeamLocks:          nil,
+				AppLocks:           nil,
+				Version:            types.MakeReleaseNumberVersion(0),
+				Team:               "",
+				NewReleaseCommitId: upstreamCommitIDByApp[appName],
+				ExistingDeployment: nil,
+				OldReleaseCommitId: "",
+			}
+			continue
+		}
+
 		if appLocks, ok := prefetchedAppLocks[appName]; !ok {
 			logging.Info(ctx, "app locks were not prefetched.", zap.String("application", string(appName)), zap.String("environment", string(envName)))
 		} else if len(appLocks) > 0 {
@@ -952,15 +1046,99 @@ func (c *envReleaseTrain) prognosis(ctx context.Context, state *State, transacti
 		}
 
 	}
+
+	err = c.skipAppsWithUnmetSignaturePolicy(ctx, state, transaction, &envConfig, appsPrognoses)
+	if err != nil {
+		return failedPrognosis(err)
+	}
+	err = c.loadDependencies(ctx, state, transaction, appsPrognoses)
+	if err != nil {
+		return failedPrognosis(err)
+	}
+	skipAppsWithUnmetDependencies(appsPrognoses, allLatestDeploymentsTargetEnv)
+
 	return &ReleaseTrainEnvironmentPrognosis{
 		SkipCause:            nil,
 		Error:                nil,
 		EnvLocks:             nil,
+		ActiveFreeze:         nil,
 		AppsPrognoses:        appsPrognoses,
 		AllLatestDeployments: allLatestDeploymentsTargetEnv,
 	}
 }
 
+// loadDependencies sets the dependencies of all apps that the release train deploys
+func (c *envReleaseTrain) loadDependencies(ctx context.Context, state *State, transaction *sql.Tx, appsPrognoses map[types.AppName]ReleaseTrainApplicationPrognosis) error {
+	versionByApp := make(map[types.AppName]types.ReleaseNumbers)
+	for appName, prognosis := range appsPrognoses {
+		if prognosis.SkipCause != nil {
+			continue
+		}
+		if prognosis.RevivedRelease != nil {
+			prognosis.Dependencies = prognosis.RevivedRelease.Metadata.Dependencies
+			appsPrognoses[appName] = prognosis
+			continue
+		}
+		versionByApp[appName] = prognosis.Version
+	}
+	metadataByApp, err := state.DBHandler.DBSelectMetadataAppReleaseVersions(ctx, transaction, versionByApp)
+	if err != nil {
+		return grpc.InternalError(ctx, fmt.Errorf("could not get the dependencies of the apps to deploy to %q: %w", c.Env, err))
+	}
+	for appName, metadata := range metadataByApp {
+		prognosis := appsPrognoses[appName]
+		prognosis.Dependencies = metadata.Dependencies
+		appsPrognoses[appName] = prognosis
+	}
+	return nil
+}
+
+// skipAppsWithUnmetSignaturePolicy marks all apps as skipped whose manifests are not signed as the signature policy of the environment requires
+func (c *envReleaseTrain) skipAppsWithUnmetSignaturePolicy(ctx context.Context, state *State, transaction *sql.Tx, envConfig *config.EnvironmentConfig, appsPrognoses map[types.AppName]ReleaseTrainApplicationPrognosis) error {
+	if envConfig.SignaturePolicy == nil {
+		return nil
+	}
+	for appName, prognosis := range appsPrognoses {
+		if prognosis.SkipCause != nil {
+			continue
+		}
+		release := prognosis.RevivedRelease
+		if release == nil {
+			var err error
+			release, err = state.DBHandler.DBSelectReleaseByVersion(ctx, transaction, appName, prognosis.Version, true)
+			if err != nil {
+				return grpc.InternalError(ctx, fmt.Errorf("could not get release %v of app %q: %w", prognosis.Version, appName, err))
+			}
+			if release == nil {
+				return grpc.InternalError(ctx, fmt.Errorf("could not find release %v of app %q", prognosis.Version, appName))
+			}
+		}
+		signatureErr := checkSignaturePolicy(envConfig, c.Env, release)
+		if signatureErr == nil {
+			continue
+		}
+		appsPrognoses[appName] = ReleaseTrainApplicationPrognosis{
+			SkipCause: &api.ReleaseTrainAppPrognosis_SkipCause{
+				SkipCause: api.ReleaseTrainAppSkipCause_APP_SIGNATURE_POLICY_NOT_MET,
+			},
+			EnvLocks:           nil,
+			TeamLocks:          nil,
+			AppLocks:           nil,
+			Version:            types.MakeReleaseNumberVersion(0),
+			Team:               prognosis.Team,
+			NewReleaseCommitId: prognosis.NewReleaseCommitId,
+			ExistingDeployment: nil,
+			RevivedRelease:     nil,
+			ArgoBracket:        "",
+			OldReleaseCommitId: "",
+			Dependencies:       nil,
+			UnmetDependencies:  nil,
+			SignatureError:     signatureErr,
+		}
+	}
+	return nil
+}
+
 func (c *envReleaseTrain) Transform(
 	ctx context.Context,
 	state *State,
@@ -985,7 +1163,7 @@ func (c *envReleaseTrain) Transform(
 	return c.applyPrognosis(ctx, state, t, transaction, prognosis, span)
 }
 
-func RecordQueuedAppVersion(ctx context.Context, state *State, tx *sql.Tx, t TransformerContext, appName types.AppName, srcEnvName types.EnvName, destEnvName types.EnvName) {
+func RecordQueuedAppVersion(ctx context.Context, state *State, tx *sql.Tx, t TransformerContext, appName types.AppName, srcEnvName types.EnvName, destEnvName types.EnvName, reason string) {
 	span, ctx := tracer.StartSpanFromContext(ctx, "RecordQueuedAppVersion")
 	span.SetTag("srcEnv", srcEnvName)
 	span.SetTag("destEnv", destEnvName)
@@ -1006,6 +1184,7 @@ func RecordQueuedAppVersion(ctx context.Context, state *State, tx *sql.Tx, t Tra
 		Environment: destEnvName,
 		Application: appName,
 		Version:     version,
+		Reason:      reason,
 	}
 	_, err = q.Transform(ctx, state, t, tx)
 	if err != nil {
@@ -1034,6 +1213,20 @@ func (c *envReleaseTrain) applyPrognosis(
 			return renderEnvironmentSkipCause(prognosis.SkipCause), nil
 		}
 		for appName, appPrognosis := range prognosis.AppsPrognoses {
+			if prognosis.ActiveFreeze != nil {
+				commitID := appPrognosis.NewReleaseCommitId
+				if commitID == "" {
+					continue
+				}
+				gen := getGenerator(ctx)
+				eventUuid := gen.Generate()
+				newEvent := createFreezePreventedDeploymentEvent(appName, c.Env, prognosis.ActiveFreeze)
+				err := state.DBHandler.DBWriteFreezePreventedDeploymentEvent(ctx, transaction, c.TransformerEslVersion, eventUuid, commitID, newEvent)
+				if err != nil {
+					return "", GetCreateReleaseGeneralFailure(err)
+				}
+				continue
+			}
 			eventMessage := ""
 			if len(prognosis.EnvLocks) > 0 {
 				for e := range prognosis.EnvLocks {
@@ -1077,14 +1270,19 @@ func (c *envReleaseTrain) applyPrognosis(
 	}
 
 	slices.Sort(appNames)
+	// dependencies are deployed before the apps that need them
+	appNames = sortByDependencies(appNames, func(app types.AppName) []db.ReleaseDependency {
+		return prognosis.AppsPrognoses[app].Dependencies
+	})
 
 	span.SetTag("ConsideredApps", len(appNames))
 	var deployCounter uint = 0
 	for _, appName := range appNames {
 		appPrognosis := prognosis.AppsPrognoses[appName]
 		if appPrognosis.SkipCause != nil {
-			skipped = append(skipped, renderApplicationSkipCause(&appPrognosis, appName))
-			RecordQueuedAppVersion(ctx, state, transaction, t, appName, envConfig.Upstream.Environment, c.Env)
+			skipCause := renderApplicationSkipCause(&appPrognosis, appName)
+			skipped = append(skipped, skipCause)
+			RecordQueuedAppVersion(ctx, state, transaction, t, appName, envConfig.Upstream.Environment, c.Env, strings.TrimSpace(skipCause))
 			continue
 		}
 
@@ -1113,6 +1311,8 @@ func (c *envReleaseTrain) applyPrognosis(
 				ArgoBracket:           appPrognosis.ArgoBracket,
 				SkipDeployment:        true,
 				Override:              true,
+				Dependencies:          revivedRelease.Metadata.Dependencies,
+				Signatures:            revivedRelease.Metadata.Signatures,
 			}
 			_, err := newRelease.Transform(ctx, state, t, transaction)
 			if err != nil {
@@ -1131,6 +1331,7 @@ func (c *envReleaseTrain) applyPrognosis(
 				Upstream:    upstreamEnvName,
 				TargetGroup: c.TrainGroup,
 			},
+			AutoRollback:          nil,
 			Author:                "",
 			TransformerEslVersion: c.TransformerEslVersion,
 			CiLink:                c.CiLink,
@@ -1147,6 +1348,9 @@ func (c *envReleaseTrain) applyPrognosis(
 			NewReleaseCommitId: appPrognosis.NewReleaseCommitId,
 			ExistingDeployment: appPrognosis.ExistingDeployment,
 			OldReleaseCommitId: appPrognosis.OldReleaseCommitId,
+			ActiveFreeze:       nil, // the environment prognosis already skips frozen environments
+			UnmetDependencies:  nil, // and apps with unmet dependencies
+			SignatureError:     nil, // or unsigned manifests
 		}
 		_, err := d.ApplyPrognosis(
 			ctx,
@@ -1194,6 +1398,8 @@ func (c *envReleaseTrain) renderEnvironmentSkipCause() func(SkipCause *api.Relea
 			return fmt.Sprintf("Could not find environment config for upstream env %q. Target env was %q", upstreamEnvName, c.Env)
 		case api.ReleaseTrainEnvSkipCause_ENV_IS_LOCKED:
 			return fmt.Sprintf("Target Environment '%s' is locked - skipping.", c.Env)
+		case api.ReleaseTrainEnvSkipCause_ENV_IS_FROZEN:
+			return fmt.Sprintf("Target Environment '%s' is frozen - skipping.", c.Env)
 		default:
 			return fmt.Sprintf("Environment '%s' is skipped for an unrecognised reason", c.Env)
 		}
@@ -1224,6 +1430,12 @@ func (c *envReleaseTrain) renderApplicationSkipCause(
 			return fmt.Sprintf("skipping application %q in environment %q due to team lock on team %q", appName, c.Env, Prognosis.Team)
 		case api.ReleaseTrainAppSkipCause_NO_TEAM_PERMISSION:
 			return fmt.Sprintf("skipping application %q in environment %q because the user team %q is not the same as the apllication", appName, c.Env, Prognosis.Team)
+		case api.ReleaseTrainAppSkipCause_APP_UPSTREAM_VERSION_TOO_YOUNG:
+			return fmt.Sprintf("skipping application %q in environment %q because it was deployed on %q less than %s ago", appName, c.Env, upstreamEnvName, envConfig.Upstream.SoakTime)
+		case api.ReleaseTrainAppSkipCause_APP_DEPENDENCY_NOT_MET:
+			return fmt.Sprintf("skipping application %q in environment %q because of unmet dependencies: %s", appName, c.Env, describeUnmetDependencies(Prognosis.UnmetDependencies))
+		case api.ReleaseTrainAppSkipCause_APP_SIGNATURE_POLICY_NOT_MET:
+			return fmt.Sprintf("skipping application %q in environment %q because its signature policy is not met: %v", appName, c.Env, Prognosis.SignatureError)
 		default:
 			return fmt.Sprintf("skipping application %q in environment %q for an unrecognised reason", appName, c.Env)
 		}
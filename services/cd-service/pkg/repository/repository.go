@@ -40,6 +40,8 @@ import (
 	"github.com/freiheit-com/kuberpult/pkg/mapper"
 	time2 "github.com/freiheit-com/kuberpult/pkg/time"
 	"github.com/freiheit-com/kuberpult/pkg/types"
+	"github.com/freiheit-com/kuberpult/services/cd-service/pkg/gates"
+	"github.com/freiheit-com/kuberpult/services/cd-service/pkg/manifestvalidation"
 	"github.com/freiheit-com/kuberpult/services/cd-service/pkg/notify"
 )
 
@@ -166,6 +168,15 @@ type RepositoryConfig struct {
 
 	AllowBracketMoves bool
 
+	// DeploymentGates asks the gates of environments before deployments, see the gates package
+	DeploymentGates *gates.Client
+	// ManifestValidation has the rules that environments can enable to validate the manifests of new releases
+	ManifestValidation *manifestvalidation.Registry
+	// WriteWebhookEvents writes lock and environment events to the commit events, only webhooks read them
+	WriteWebhookEvents bool
+	// DeploymentApproval configures which deployments wait for a four-eyes approval
+	DeploymentApproval DeploymentApprovalConfig
+
 	DBHandler *db.DBHandler
 }
 
@@ -188,6 +199,12 @@ func New(ctx context.Context, cfg RepositoryConfig) (Repository, error) {
 	if cfg.ReleaseVersionsLimit == 0 {
 		cfg.ReleaseVersionsLimit = keptVersionsOnCleanup
 	}
+	if cfg.DeploymentGates == nil {
+		cfg.DeploymentGates = gates.New(gates.DefaultConfig)
+	}
+	if cfg.ManifestValidation == nil {
+		cfg.ManifestValidation = manifestvalidation.NewRegistry(nil)
+	}
 
 	var err error
 
@@ -424,17 +441,39 @@ func (r *repository) ApplyTransformers(ctx context.Context, transaction *sql.Tx,
 	return result, nil
 }
 
+// maxGateRounds limits how often Apply asks the deployment gates for one batch of transformers.
+// A batch needs another round if a deployment changed while the gates were asked.
+const maxGateRounds = 3
+
+// errGatesPending rolls back the transaction, so that the gates can be asked outside of it.
+var errGatesPending = fmt.Errorf("deployment gates have not been asked yet: %w", db.ErrRollback)
+
 func (r *repository) Apply(ctx context.Context, transformers ...Transformer) error {
-	changes, err := db.WithTransactionT(r.DB, ctx, 2, false, func(ctx context.Context, transaction *sql.Tx) (*TransformerResult, error) {
-		subChanges, applyErr := r.ApplyTransformers(ctx, transaction, transformers...)
-		if applyErr != nil {
-			return nil, applyErr
+	// Deployment gates are remote services, so they are asked between transactions:
+	// the transformers record the deployments that need a decision and the transaction is run again.
+	verdicts := gates.NewVerdicts()
+	ctx = gates.WithVerdicts(ctx, verdicts)
+	var changes *TransformerResult
+	for round := 1; ; round++ {
+		var err error
+		changes, err = db.WithTransactionT(r.DB, ctx, 2, false, func(ctx context.Context, transaction *sql.Tx) (*TransformerResult, error) {
+			subChanges, applyErr := r.ApplyTransformers(ctx, transaction, transformers...)
+			if verdicts.Pending() {
+				return nil, errGatesPending
+			}
+			if applyErr != nil {
+				return nil, applyErr
+			}
+			return subChanges, nil
+		})
+		if errors.Is(err, errGatesPending) && round < maxGateRounds {
+			verdicts.Evaluate(ctx, r.config.DeploymentGates)
+			continue
 		}
-		return subChanges, nil
-	})
-
-	if err != nil {
-		return err
+		if err != nil {
+			return err
+		}
+		break
 	}
 	r.notify.Notify()
 	r.notifyChangedApps(changes)
@@ -491,6 +530,9 @@ func (r *repository) StateAt() (*State, error) {
 		MinorRegexes:         r.config.MinorRegexes,
 		MaxNumThreads:        int(r.config.MaxNumThreads),
 		AllowBracketMoves:    r.config.AllowBracketMoves,
+		ManifestValidation:   r.config.ManifestValidation,
+		WriteWebhookEvents:   r.config.WriteWebhookEvents,
+		DeploymentApproval:   r.config.DeploymentApproval,
 		DBHandler:            r.DB,
 	}, nil
 }
@@ -504,6 +546,9 @@ type State struct {
 	MinorRegexes         []*regexp.Regexp
 	MaxNumThreads        int
 	AllowBracketMoves    bool
+	ManifestValidation   *manifestvalidation.Registry
+	WriteWebhookEvents   bool
+	DeploymentApproval   DeploymentApprovalConfig
 	// DbHandler will be nil if the DB is disabled
 	DBHandler *db.DBHandler
 }
@@ -714,6 +759,13 @@ func (s *State) GetAllLatestDeployments(ctx context.Context, transaction *sql.Tx
 	return s.DBHandler.DBSelectAllLatestDeploymentsOnEnvironment(ctx, transaction, environment)
 }
 
+func (s *State) GetAllLatestDeploymentTimes(ctx context.Context, transaction *sql.Tx, environment types.EnvName, ts *time.Time) (map[types.AppName]time.Time, error) {
+	if ts != nil {
+		return s.DBHandler.DBSelectAllLatestDeploymentTimesOnEnvironmentAtTimestamp(ctx, transaction, environment, *ts)
+	}
+	return s.DBHandler.DBSelectAllLatestDeploymentTimesOnEnvironment(ctx, transaction, environment)
+}
+
 func (s *State) GetCommitIdFromAppReleaseVersions(ctx context.Context, transaction *sql.Tx, appReleaseVersions map[types.AppName]types.ReleaseNumbers, ts *time.Time) (map[types.AppName]string, error) {
 	if ts != nil {
 		return s.DBHandler.DBSelectCommitIdAppReleaseVersionsAtTimestamp(ctx, transaction, appReleaseVersions, *ts)
@@ -815,6 +867,24 @@ func (s *State) GetAllEnvironmentConfigs(ctx context.Context, transaction *sql.T
 	return s.GetAllEnvironmentConfigsFromDB(ctx, transaction)
 }
 
+// GetAllEnvironmentConfigsAtTimestamp returns the environments as they were at ts, or the current ones if ts is nil.
+func (s *State) GetAllEnvironmentConfigsAtTimestamp(ctx context.Context, transaction *sql.Tx, ts *time.Time) (map[types.EnvName]config.EnvironmentConfig, error) {
+	if ts == nil {
+		return s.GetAllEnvironmentConfigs(ctx, transaction)
+	}
+	envs, err := s.DBHandler.DBSelectAllActiveEnvironmentsAtTimestamp(ctx, transaction, *ts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve environments at %v, error: %w", *ts, err)
+	}
+	ret := make(map[types.EnvName]config.EnvironmentConfig)
+	if envs != nil {
+		for _, env := range *envs {
+			ret[env.Name] = env.Config
+		}
+	}
+	return ret, nil
+}
+
 func (s *State) GetAllDeploymentsForAppFromDB(ctx context.Context, transaction *sql.Tx, appName types.AppName) (map[types.EnvName]types.ReleaseNumbers, error) {
 	result, err := s.DBHandler.DBSelectAllDeploymentsForApp(ctx, transaction, appName)
 	if err != nil {
@@ -0,0 +1,170 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/freiheit-com/kuberpult/pkg/auth"
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/grpc"
+	"github.com/freiheit-com/kuberpult/pkg/mapper"
+	"github.com/freiheit-com/kuberpult/pkg/types"
+)
+
+// SetReleaseRetentionPolicy creates or replaces the release retention policy of an application or a team.
+// The policy is applied by the next cleanup of old releases of the affected applications.
+type SetReleaseRetentionPolicy struct {
+	Authentication        `json:"-"`
+	Scope                 db.ReleaseRetentionScope `json:"scope"`
+	Name                  string                   `json:"name"`
+	KeepLast              uint64                   `json:"keepLast"`
+	KeepDays              uint64                   `json:"keepDays"`
+	KeepProdDeployed      bool                     `json:"keepProdDeployed"`
+	TransformerEslVersion db.TransformerID         `json:"-"` // Tags the transformer with EventSourcingLight eslVersion
+}
+
+func (c *SetReleaseRetentionPolicy) GetDBEventType() db.EventType {
+	return db.EvtSetReleaseRetentionPolicy
+}
+
+func (c *SetReleaseRetentionPolicy) SetEslVersion(id db.TransformerID) {
+	c.TransformerEslVersion = id
+}
+
+func (c *SetReleaseRetentionPolicy) GetEslVersion() db.TransformerID {
+	return c.TransformerEslVersion
+}
+
+func (c *SetReleaseRetentionPolicy) Transform(
+	ctx context.Context,
+	state *State,
+	_ TransformerContext,
+	transaction *sql.Tx,
+) (string, error) {
+	err := checkReleaseRetentionPermissions(ctx, state, transaction, c.Scope, c.Name, c.RBACConfig)
+	if err != nil {
+		return "", err
+	}
+	now, err := state.DBHandler.DBReadTransactionTimestamp(ctx, transaction)
+	if err != nil {
+		return "", err
+	}
+	if now == nil {
+		return "", fmt.Errorf("could not get transaction timestamp: nil")
+	}
+	err = state.DBHandler.DBUpsertReleaseRetentionPolicy(ctx, transaction, db.ReleaseRetentionPolicy{
+		Scope:            c.Scope,
+		Name:             c.Name,
+		KeepLast:         c.KeepLast,
+		KeepDays:         c.KeepDays,
+		KeepProdDeployed: c.KeepProdDeployed,
+		Created:          *now,
+		EslVersion:       c.TransformerEslVersion,
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Set the release retention policy of %s %q", c.Scope, c.Name), nil
+}
+
+// DeleteReleaseRetentionPolicy deletes the release retention policy of an application or a team.
+// Afterwards, the policy of the team or the global release versions limit applies again.
+type DeleteReleaseRetentionPolicy struct {
+	Authentication        `json:"-"`
+	Scope                 db.ReleaseRetentionScope `json:"scope"`
+	Name                  string                   `json:"name"`
+	TransformerEslVersion db.TransformerID         `json:"-"` // Tags the transformer with EventSourcingLight eslVersion
+}
+
+func (c *DeleteReleaseRetentionPolicy) GetDBEventType() db.EventType {
+	return db.EvtDeleteReleaseRetentionPolicy
+}
+
+func (c *DeleteReleaseRetentionPolicy) SetEslVersion(id db.TransformerID) {
+	c.TransformerEslVersion = id
+}
+
+func (c *DeleteReleaseRetentionPolicy) GetEslVersion() db.TransformerID {
+	return c.TransformerEslVersion
+}
+
+func (c *DeleteReleaseRetentionPolicy) Transform(
+	ctx context.Context,
+	state *State,
+	_ TransformerContext,
+	transaction *sql.Tx,
+) (string, error) {
+	err := checkReleaseRetentionPermissions(ctx, state, transaction, c.Scope, c.Name, c.RBACConfig)
+	if err != nil {
+		return "", err
+	}
+	now, err := state.DBHandler.DBReadTransactionTimestamp(ctx, transaction)
+	if err != nil {
+		return "", err
+	}
+	if now == nil {
+		return "", fmt.Errorf("could not get transaction timestamp: nil")
+	}
+	deleted, err := state.DBHandler.DBDeleteReleaseRetentionPolicy(ctx, transaction, c.Scope, c.Name, *now, c.TransformerEslVersion)
+	if err != nil {
+		return "", err
+	}
+	if !deleted {
+		return "", grpc.NotFoundError(ctx, fmt.Errorf("there is no release retention policy for %s %q", c.Scope, c.Name))
+	}
+	return fmt.Sprintf("Deleted the release retention policy of %s %q", c.Scope, c.Name), nil
+}
+
+// checkReleaseRetentionPermissions checks that the user belongs to the team of the policy.
+// For application policies, this is the team that owns the application.
+func checkReleaseRetentionPermissions(ctx context.Context, state *State, transaction *sql.Tx, scope db.ReleaseRetentionScope, name string, rbacConfig auth.RBACConfig) error {
+	team := name
+	switch scope {
+	case db.ReleaseRetentionScopeApplication:
+		var err error
+		team, err = state.GetApplicationTeamOwner(ctx, transaction, types.AppName(name))
+		if err != nil {
+			return grpc.FailedPrecondition(ctx, err)
+		}
+	case db.ReleaseRetentionScopeTeam:
+	default:
+		return grpc.InvalidArgument(ctx, fmt.Errorf("invalid release retention scope %q", scope))
+	}
+	if !rbacConfig.DexEnabled {
+		return nil
+	}
+	user, err := auth.ReadUserFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("checkReleaseRetentionPermissions: user not found: %v", err)
+	}
+	return auth.CheckUserTeamPermissions(rbacConfig, user, team, auth.PermissionCreateRelease)
+}
+
+// withoutRetainedVersions removes the old versions found by the cleanup that the release retention policy of the app keeps.
+func withoutRetainedVersions(ctx context.Context, transaction *sql.Tx, state *State, name types.AppName, policy *db.ReleaseRetentionPolicy, oldVersions []types.ReleaseNumbers, eslVersion db.TransformerID) ([]types.ReleaseNumbers, error) {
+	if policy == nil || len(oldVersions) == 0 {
+		return oldVersions, nil
+	}
+	configs, err := state.GetAllEnvironmentConfigs(ctx, transaction)
+	if err != nil {
+		return nil, err
+	}
+	return state.DBHandler.DBFilterReleasesRetainedByPolicy(ctx, transaction, name, policy, mapper.ProdEnvironments(configs), oldVersions, eslVersion)
+}
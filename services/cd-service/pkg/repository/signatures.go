@@ -0,0 +1,60 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package repository
+
+import (
+	"fmt"
+
+	"github.com/freiheit-com/kuberpult/pkg/config"
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/signing"
+	"github.com/freiheit-com/kuberpult/pkg/types"
+)
+
+// checkSignatures validates the signatures of a new release
+func checkSignatures(manifests map[types.EnvName]string, signatures []db.ReleaseSignature) error {
+	for _, signature := range signatures {
+		if _, ok := manifests[signature.Environment]; !ok {
+			return fmt.Errorf("signature for environment %q, but the release has no manifest for it", signature.Environment)
+		}
+		if !signing.ValidType(signature.Type) {
+			return fmt.Errorf("signature for environment %q has unknown type %q", signature.Environment, signature.Type)
+		}
+		if signature.Signature == "" {
+			return fmt.Errorf("signature for environment %q is empty", signature.Environment)
+		}
+	}
+	return nil
+}
+
+// checkSignaturePolicy returns an error if the manifest of release for env is not signed as the signature policy of env requires.
+// Undeploy versions are created by kuberpult and cannot be signed, so they are always allowed.
+func checkSignaturePolicy(envConfig *config.EnvironmentConfig, env types.EnvName, release *db.DBReleaseWithMetaData) error {
+	if envConfig == nil || envConfig.SignaturePolicy == nil || release.Metadata.UndeployVersion {
+		return nil
+	}
+	var signatures []signing.Signature
+	for _, signature := range release.Metadata.Signatures {
+		if signature.Environment == env {
+			signatures = append(signatures, signing.Signature{
+				Type:      signature.Type,
+				Signature: signature.Signature,
+			})
+		}
+	}
+	return signing.CheckPolicy(envConfig.SignaturePolicy, []byte(release.Manifests.Manifests[env]), signatures)
+}
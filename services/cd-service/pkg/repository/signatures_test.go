@@ -0,0 +1,170 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package repository
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+
+	"github.com/freiheit-com/kuberpult/pkg/config"
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/signing"
+	"github.com/freiheit-com/kuberpult/pkg/types"
+)
+
+func TestCheckSignatures(t *testing.T) {
+	manifests := map[types.EnvName]string{"production": "kind: ConfigMap\n"}
+	tcs := []struct {
+		Name          string
+		Signatures    []db.ReleaseSignature
+		ExpectedError string
+	}{
+		{
+			Name:          "no signatures",
+			Signatures:    nil,
+			ExpectedError: "",
+		},
+		{
+			Name: "valid signatures",
+			Signatures: []db.ReleaseSignature{
+				{Environment: "production", Type: signing.TypeSSH, Signature: "sig"},
+				{Environment: "production", Type: signing.TypeCosign, Signature: "sig"},
+			},
+			ExpectedError: "",
+		},
+		{
+			Name: "environment without manifest",
+			Signatures: []db.ReleaseSignature{
+				{Environment: "staging", Type: signing.TypeSSH, Signature: "sig"},
+			},
+			ExpectedError: `signature for environment "staging", but the release has no manifest for it`,
+		},
+		{
+			Name: "unknown type",
+			Signatures: []db.ReleaseSignature{
+				{Environment: "production", Type: "x509", Signature: "sig"},
+			},
+			ExpectedError: `signature for environment "production" has unknown type "x509"`,
+		},
+		{
+			Name: "empty signature",
+			Signatures: []db.ReleaseSignature{
+				{Environment: "production", Type: signing.TypeOpenPGP, Signature: ""},
+			},
+			ExpectedError: `signature for environment "production" is empty`,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := checkSignatures(manifests, tc.Signatures)
+			actual := ""
+			if err != nil {
+				actual = err.Error()
+			}
+			if actual != tc.ExpectedError {
+				t.Fatalf("expected error %q, got %q", tc.ExpectedError, actual)
+			}
+		})
+	}
+}
+
+func TestCheckSignaturePolicy(t *testing.T) {
+	const manifest = "kind: ConfigMap\n"
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte(manifest))
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := &config.SignaturePolicy{
+		Keys: []config.SigningKey{
+			{Name: "ci", Type: signing.TypeCosign, PublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))},
+		},
+		Threshold: 0,
+	}
+	release := func(undeploy bool, signatureEnv types.EnvName) *db.DBReleaseWithMetaData {
+		return &db.DBReleaseWithMetaData{
+			Manifests: db.DBReleaseManifests{
+				Manifests: map[types.EnvName]string{"production": manifest, "staging": manifest},
+			},
+			Metadata: db.DBReleaseMetaData{
+				UndeployVersion: undeploy,
+				Signatures: []db.ReleaseSignature{
+					{Environment: signatureEnv, Type: signing.TypeCosign, Signature: base64.StdEncoding.EncodeToString(sig)},
+				},
+			}, //exhaustruct:ignore
+		} //exhaustruct:ignore
+	}
+
+	tcs := []struct {
+		Name          string
+		Policy        *config.SignaturePolicy
+		Release       *db.DBReleaseWithMetaData
+		ExpectedError string
+	}{
+		{
+			Name:          "no policy",
+			Policy:        nil,
+			Release:       release(false, "staging"),
+			ExpectedError: "",
+		},
+		{
+			Name:          "signed for the environment",
+			Policy:        policy,
+			Release:       release(false, "production"),
+			ExpectedError: "",
+		},
+		{
+			Name:          "only signed for another environment",
+			Policy:        policy,
+			Release:       release(false, "staging"),
+			ExpectedError: "the manifest is signed by 0 of the 1 required trusted keys, missing signatures of: ci",
+		},
+		{
+			Name:          "undeploy version",
+			Policy:        policy,
+			Release:       release(true, "staging"),
+			ExpectedError: "",
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			envConfig := &config.EnvironmentConfig{SignaturePolicy: tc.Policy} //exhaustruct:ignore
+			err := checkSignaturePolicy(envConfig, "production", tc.Release)
+			actual := ""
+			if err != nil {
+				actual = err.Error()
+			}
+			if actual != tc.ExpectedError {
+				t.Fatalf("expected error %q, got %q", tc.ExpectedError, actual)
+			}
+		})
+	}
+}
@@ -391,6 +391,8 @@ type CreateApplicationVersion struct {
 	ArgoBracket                    types.ArgoBracketName    `json:"argoBracket"`
 	SkipDeployment                 bool                     `json:"skipDeployment"`
 	Override                       bool                     `json:"override"`
+	Dependencies                   []db.ReleaseDependency   `json:"dependencies,omitempty"`
+	Signatures                     []db.ReleaseSignature    `json:"signatures,omitempty"`
 }
 
 func (c *CreateApplicationVersion) GetDBEventType() db.EventType {
@@ -498,6 +500,13 @@ func (c *CreateApplicationVersion) CheckPreconditions(ctx context.Context) error
 		return err
 	}
 
+	if err := checkDependencies(c.Application, c.Dependencies); err != nil {
+		return GetCreateReleaseGeneralFailure(err)
+	}
+	if err := checkSignatures(c.Manifests, c.Signatures); err != nil {
+		return GetCreateReleaseGeneralFailure(err)
+	}
+
 	return nil
 }
 
@@ -507,6 +516,8 @@ func (c *CreateApplicationVersion) Transform(
 	t TransformerContext,
 	transaction *sql.Tx,
 ) (string, error) {
+	// calculateVersion reports a release that already exists, so retrying a release is not
+	// rejected by rules that were enabled after it was created
 	version, err := c.calculateVersion(ctx, transaction, state)
 	if err != nil {
 		return "", err
@@ -516,6 +527,19 @@ func (c *CreateApplicationVersion) Transform(
 		return "", err
 	}
 
+	configs, err := state.GetAllEnvironmentConfigs(ctx, transaction)
+	if err != nil {
+		if errors.Is(err, ErrInvalidJson) {
+			return "", err
+		}
+		return "", GetCreateReleaseGeneralFailure(err)
+	}
+
+	// checks the manifests against the manifest validation rules of their environments
+	if findings := state.ManifestValidation.Validate(c.Manifests, configs); len(findings) > 0 {
+		return "", GetCreateReleaseInvalidManifest(findings)
+	}
+
 	allApps, err := state.DBHandler.DBSelectAllApplications(ctx, transaction)
 	if err != nil {
 		return "", GetCreateReleaseGeneralFailure(err)
@@ -601,21 +625,13 @@ func (c *CreateApplicationVersion) Transform(
 		}
 	}
 
-	configs, err := state.GetAllEnvironmentConfigs(ctx, transaction)
-	if err != nil {
-		if errors.Is(err, ErrInvalidJson) {
-			return "", err
-		}
-		return "", GetCreateReleaseGeneralFailure(err)
-	}
-
 	isLatest, err := isLatestVersion(ctx, transaction, state, c.Application, version)
 	if err != nil {
 		return "", GetCreateReleaseGeneralFailure(err)
 	}
 	if !isLatest {
 		// check that we can actually backfill this version
-		oldVersions, err := findOldApplicationVersions(ctx, transaction, state, c.Application)
+		oldVersions, err := findOldApplicationVersions(ctx, transaction, state, c.Application, c.TransformerEslVersion)
 		if err != nil {
 			return "", GetCreateReleaseGeneralFailure(err)
 		}
@@ -672,6 +688,8 @@ func (c *CreateApplicationVersion) Transform(
 			IsMinor:         isMinor,
 			CiLink:          c.CiLink,
 			IsPrepublish:    c.IsPrepublish,
+			Dependencies:    c.Dependencies,
+			Signatures:      c.Signatures,
 		},
 		Environments: []types.EnvName{},
 		Created:      *now,
@@ -712,6 +730,7 @@ func (c *CreateApplicationVersion) Transform(
 		if !c.SkipDeployment && (envIsConfiguredLatest || downstreamDeploymentRequested) && !c.IsPrepublish {
 			d := &DeployApplicationVersion{
 				SourceTrain:           nil,
+				AutoRollback:          nil,
 				Environment:           env,
 				Application:           c.Application,
 				Version:               *version.Version, // the train should queue deployments, instead of giving up:
@@ -724,7 +743,7 @@ func (c *CreateApplicationVersion) Transform(
 				TransformerEslVersion: c.TransformerEslVersion,
 				SkipCleanup:           false,
 			}
-			err := t.Execute(ctx, d, transaction)
+			err := deployOrRequestApproval(ctx, state, t, transaction, configs, d)
 			if err != nil {
 				_, ok := err.(*LockedError)
 				if ok {
@@ -881,8 +900,10 @@ func writeCommitData(ctx context.Context, h *db.DBHandler, transaction *sql.Tx,
 		envMap[string(env)] = struct{}{}
 	}
 
+	appName := string(app)
 	ev := &event.NewRelease{
 		Environments: envMap,
+		Application:  &appName,
 	}
 	var writeError error
 	gen := getGenerator(ctx)
@@ -895,6 +916,30 @@ func writeCommitData(ctx context.Context, h *db.DBHandler, transaction *sql.Tx,
 	return nil
 }
 
+// writeWebhookEvent stores an event that is not linked to any commit and is only read by the webhook worker.
+// It does nothing unless webhook events are enabled.
+func writeWebhookEvent(ctx context.Context, state *State, transaction *sql.Tx, transformerEslVersion db.TransformerID, ev event.Event) error {
+	if !state.WriteWebhookEvents {
+		return nil
+	}
+	eventUuid := getGenerator(ctx).Generate()
+	var err error
+	switch e := ev.(type) {
+	case *event.LockCreated:
+		err = state.DBHandler.DBWriteLockCreatedEvent(ctx, transaction, transformerEslVersion, eventUuid, "", e)
+	case *event.LockDeleted:
+		err = state.DBHandler.DBWriteLockDeletedEvent(ctx, transaction, transformerEslVersion, eventUuid, "", e)
+	case *event.EnvironmentChanged:
+		err = state.DBHandler.DBWriteEnvironmentChangedEvent(ctx, transaction, transformerEslVersion, eventUuid, "", e)
+	default:
+		err = fmt.Errorf("event of type %T cannot be written for webhooks", ev)
+	}
+	if err != nil {
+		return fmt.Errorf("error while writing webhook event: %w", err)
+	}
+	return nil
+}
+
 func (c *CreateApplicationVersion) calculateVersion(ctx context.Context, transaction *sql.Tx, state *State) (types.ReleaseNumbers, error) {
 	if c.Version == 0 {
 		return types.MakeEmptyReleaseNumbers(), fmt.Errorf("version is required when using the database")
@@ -1075,6 +1120,8 @@ func (c *CreateUndeployApplicationVersion) Transform(
 			IsMinor:         false,
 			IsPrepublish:    false,
 			CiLink:          "",
+			Dependencies:    nil,
+			Signatures:      nil,
 		},
 		Environments: envs,
 		Created:      *now,
@@ -1105,10 +1152,11 @@ func (c *CreateUndeployApplicationVersion) Transform(
 		t.AddAppEnv(c.Application, env, teamOwner)
 		if hasUpstream && cfg.Upstream.Latest {
 			d := &DeployApplicationVersion{
-				SourceTrain: nil,
-				Environment: env,
-				Application: c.Application,
-				Version:     *lastRelease.Version + 1,
+				SourceTrain:  nil,
+				AutoRollback: nil,
+				Environment:  env,
+				Application:  c.Application,
+				Version:      *lastRelease.Version + 1,
 				// the train should queue deployments, instead of giving up:
 				LockBehaviour:         api.LockBehavior_RECORD,
 				Authentication:        c.Authentication,
@@ -1119,7 +1167,7 @@ func (c *CreateUndeployApplicationVersion) Transform(
 				SkipCleanup:           false,
 				Revision:              0,
 			}
-			err := t.Execute(ctx, d, transaction)
+			err := deployOrRequestApproval(ctx, state, t, transaction, configs, d)
 			if err != nil {
 				_, ok := err.(*LockedError)
 				if ok {
@@ -1219,6 +1267,7 @@ func (u *UndeployApplication) Transform(
 			err := state.DBHandler.DBDeleteApplicationLock(ctx, transaction, env, u.Application, currentLockID, db.LockDeletionMetadata{
 				DeletedByUser:  user.Name,
 				DeletedByEmail: user.Email,
+				Reason:         "",
 			})
 			if err != nil {
 				return "", err
@@ -1464,7 +1513,7 @@ func (c *CleanupOldApplicationVersions) GetEslVersion() db.TransformerID {
 }
 
 // Finds old releases for an application
-func findOldApplicationVersions(ctx context.Context, transaction *sql.Tx, state *State, name types.AppName) (_ []types.ReleaseNumbers, err error) {
+func findOldApplicationVersions(ctx context.Context, transaction *sql.Tx, state *State, name types.AppName, eslVersion db.TransformerID) (_ []types.ReleaseNumbers, err error) {
 	span, ctx := tracer.StartSpanFromContext(ctx, "findOldApplicationVersions")
 	defer func() {
 		span.Finish(tracer.WithError(err))
@@ -1508,14 +1557,24 @@ func findOldApplicationVersions(ctx context.Context, transaction *sql.Tx, state
 		return types.GreaterOrEqual(versions[i], oldestDeployedVersion)
 	})
 	span.SetTag("positionOfOldestVersion", positionOfOldestVersion)
-	span.SetTag("releaseVersionsLimit", state.ReleaseVersionsLimit)
 
-	if positionOfOldestVersion < (int(state.ReleaseVersionsLimit) - 1) {
+	policy, err := state.DBHandler.DBSelectReleaseRetentionPolicyForApp(ctx, transaction, name)
+	if err != nil {
+		return nil, err
+	}
+	releaseVersionsLimit := int(policy.ReleaseVersionsLimit(state.ReleaseVersionsLimit))
+	span.SetTag("releaseVersionsLimit", releaseVersionsLimit)
+
+	if positionOfOldestVersion < (releaseVersionsLimit - 1) {
 		return nil, nil
 	}
 
-	span.SetTag("numOfOldReleasesToDelete", positionOfOldestVersion-(int(state.ReleaseVersionsLimit)-1))
-	return versions[0 : positionOfOldestVersion-(int(state.ReleaseVersionsLimit)-1)], err
+	oldVersions, err := withoutRetainedVersions(ctx, transaction, state, name, policy, versions[0:positionOfOldestVersion-(releaseVersionsLimit-1)], eslVersion)
+	if err != nil {
+		return nil, err
+	}
+	span.SetTag("numOfOldReleasesToDelete", len(oldVersions))
+	return oldVersions, nil
 }
 
 func (c *CleanupOldApplicationVersions) Transform(
@@ -1579,7 +1638,7 @@ func (c *CleanupOldApplicationVersions) Transform(
 	}
 	span.SetTag("numCleanedUpDeployments", numCleanedUpDeployments)
 
-	oldVersions, err := findOldApplicationVersions(ctx, transaction, state, c.Application)
+	oldVersions, err := findOldApplicationVersions(ctx, transaction, state, c.Application, c.TransformerEslVersion)
 	if err != nil {
 		return "", fmt.Errorf("cleanup: could not get application releases for app '%s': %w", c.Application, err)
 	}
@@ -1727,6 +1786,17 @@ func (c *CreateEnvironmentLock) Transform(
 	if errW != nil {
 		return "", errW
 	}
+	err = writeWebhookEvent(ctx, state, transaction, c.TransformerEslVersion, &event.LockCreated{
+		LockType:    "environment",
+		LockId:      c.LockId,
+		Environment: string(envName),
+		Application: "",
+		Team:        "",
+		Message:     c.Message,
+	})
+	if err != nil {
+		return "", err
+	}
 	GaugeEnvLockMetric(ctx, state, transaction, envName)
 	return fmt.Sprintf("Created lock %q on environment %q", c.LockId, c.Environment), nil
 }
@@ -1735,7 +1805,8 @@ type DeleteEnvironmentLock struct {
 	Authentication        `json:"-"`
 	Environment           types.EnvName    `json:"env"`
 	LockId                string           `json:"lockId"`
-	TransformerEslVersion db.TransformerID `json:"-"` // Tags the transformer with EventSourcingLight eslVersion
+	Reason                string           `json:"reason,omitempty"` // only set when kuberpult deletes the lock on its own
+	TransformerEslVersion db.TransformerID `json:"-"`                // Tags the transformer with EventSourcingLight eslVersion
 
 }
 
@@ -1773,7 +1844,18 @@ func (c *DeleteEnvironmentLock) Transform(
 		ReleaseVersionsLimit: state.ReleaseVersionsLimit,
 	}
 
-	err = state.DBHandler.DBDeleteEnvironmentLock(ctx, transaction, envName, c.LockId, db.LockDeletionMetadata{DeletedByUser: user.Name, DeletedByEmail: user.Email})
+	err = state.DBHandler.DBDeleteEnvironmentLock(ctx, transaction, envName, c.LockId, db.LockDeletionMetadata{DeletedByUser: user.Name, DeletedByEmail: user.Email, Reason: c.Reason})
+	if err != nil {
+		return "", err
+	}
+	err = writeWebhookEvent(ctx, state, transaction, c.TransformerEslVersion, &event.LockDeleted{
+		LockType:    "environment",
+		LockId:      c.LockId,
+		Environment: string(envName),
+		Application: "",
+		Team:        "",
+		Reason:      c.Reason,
+	})
 	if err != nil {
 		return "", err
 	}
@@ -1890,6 +1972,7 @@ func (c *DeleteEnvironmentGroupLock) Transform(
 			Authentication:        c.Authentication,
 			Environment:           envName,
 			LockId:                c.LockId,
+			Reason:                "",
 			TransformerEslVersion: c.TransformerEslVersion,
 		}
 		if err := t.Execute(ctx, &x, transaction); err != nil {
@@ -1970,6 +2053,17 @@ func (c *CreateEnvironmentApplicationLock) Transform(
 	if errW != nil {
 		return "", errW
 	}
+	err = writeWebhookEvent(ctx, state, transaction, c.TransformerEslVersion, &event.LockCreated{
+		LockType:    "application",
+		LockId:      c.LockId,
+		Environment: string(envName),
+		Application: string(c.Application),
+		Team:        "",
+		Message:     c.Message,
+	})
+	if err != nil {
+		return "", err
+	}
 
 	//Add it to all locks
 	allAppLocks, err := state.DBHandler.DBSelectAllAppLocks(ctx, transaction, envName, c.Application)
@@ -1994,7 +2088,8 @@ type DeleteEnvironmentApplicationLock struct {
 	Environment           types.EnvName    `json:"env"`
 	Application           types.AppName    `json:"app"`
 	LockId                string           `json:"lockId"`
-	TransformerEslVersion db.TransformerID `json:"-"` // Tags the transformer with EventSourcingLight eslVersion
+	Reason                string           `json:"reason,omitempty"` // only set when kuberpult deletes the lock on its own
+	TransformerEslVersion db.TransformerID `json:"-"`                // Tags the transformer with EventSourcingLight eslVersion
 
 }
 
@@ -2030,10 +2125,22 @@ func (c *DeleteEnvironmentApplicationLock) Transform(
 		db.LockDeletionMetadata{
 			DeletedByUser:  user.Name,
 			DeletedByEmail: user.Email,
+			Reason:         c.Reason,
 		})
 	if err != nil {
 		return "", err
 	}
+	err = writeWebhookEvent(ctx, state, transaction, c.TransformerEslVersion, &event.LockDeleted{
+		LockType:    "application",
+		LockId:      c.LockId,
+		Environment: string(envName),
+		Application: string(c.Application),
+		Team:        "",
+		Reason:      c.Reason,
+	})
+	if err != nil {
+		return "", err
+	}
 	allAppLocks, err := state.DBHandler.DBSelectAllAppLocks(ctx, transaction, envName, c.Application)
 	if err != nil {
 		return "", fmt.Errorf("DeleteEnvironmentApplicationLock: could not select all env app locks for app '%v' on '%v': '%w'", c.Application, c.Environment, err)
@@ -2118,6 +2225,17 @@ func (c *CreateEnvironmentTeamLock) Transform(
 	if errW != nil {
 		return "", errW
 	}
+	err = writeWebhookEvent(ctx, state, transaction, c.TransformerEslVersion, &event.LockCreated{
+		LockType:    "team",
+		LockId:      c.LockId,
+		Environment: string(envName),
+		Application: "",
+		Team:        c.Team,
+		Message:     c.Message,
+	})
+	if err != nil {
+		return "", err
+	}
 
 	return fmt.Sprintf("Created lock %q on environment %q for team %q", c.LockId, c.Environment, c.Team), nil
 }
@@ -2127,7 +2245,8 @@ type DeleteEnvironmentTeamLock struct {
 	Environment           types.EnvName    `json:"env"`
 	Team                  string           `json:"team"`
 	LockId                string           `json:"lockId"`
-	TransformerEslVersion db.TransformerID `json:"-"` // Tags the transformer with EventSourcingLight eslVersion
+	Reason                string           `json:"reason,omitempty"` // only set when kuberpult deletes the lock on its own
+	TransformerEslVersion db.TransformerID `json:"-"`                // Tags the transformer with EventSourcingLight eslVersion
 }
 
 func (c *DeleteEnvironmentTeamLock) GetDBEventType() db.EventType {
@@ -2162,6 +2281,18 @@ func (c *DeleteEnvironmentTeamLock) Transform(
 	err = state.DBHandler.DBDeleteTeamLock(ctx, transaction, envName, c.Team, c.LockId, db.LockDeletionMetadata{
 		DeletedByUser:  user.Name,
 		DeletedByEmail: user.Email,
+		Reason:         c.Reason,
+	})
+	if err != nil {
+		return "", err
+	}
+	err = writeWebhookEvent(ctx, state, transaction, c.TransformerEslVersion, &event.LockDeleted{
+		LockType:    "team",
+		LockId:      c.LockId,
+		Environment: string(envName),
+		Application: "",
+		Team:        c.Team,
+		Reason:      c.Reason,
 	})
 	if err != nil {
 		return "", err
@@ -2232,6 +2363,17 @@ func (c *CreateManifestLock) Transform(
 	if err := state.DBHandler.DBWriteManifestLock(ctx, transaction, c.App, c.Env, metadata); err != nil {
 		return "", err
 	}
+	err = writeWebhookEvent(ctx, state, transaction, c.TransformerEslVersion, &event.LockCreated{
+		LockType:    "manifest",
+		LockId:      "",
+		Environment: string(c.Env),
+		Application: string(c.App),
+		Team:        "",
+		Message:     c.Message,
+	})
+	if err != nil {
+		return "", err
+	}
 	return fmt.Sprintf("Created manifest lock for app %q on environment %q", c.App, c.Env), nil
 }
 
@@ -2269,6 +2411,17 @@ func (c *DeleteManifestLock) Transform(
 	if err := state.DBHandler.DBDeleteManifestLock(ctx, transaction, c.App, c.Env); err != nil {
 		return "", err
 	}
+	err := writeWebhookEvent(ctx, state, transaction, c.TransformerEslVersion, &event.LockDeleted{
+		LockType:    "manifest",
+		LockId:      "",
+		Environment: string(c.Env),
+		Application: string(c.App),
+		Team:        "",
+		Reason:      "",
+	})
+	if err != nil {
+		return "", err
+	}
 	return fmt.Sprintf("Deleted manifest lock for app %q on environment %q", c.App, c.Env), nil
 }
 
@@ -2330,10 +2483,32 @@ func (c *CreateEnvironment) Transform(
 		return fmt.Sprintf("Dry-run to create environment %q successful", c.Environment), nil
 	}
 
-	err = state.DBHandler.DBWriteEnvironment(ctx, transaction, envName, c.Config)
+	envConfig := c.Config
+	existingEnv, err := state.DBHandler.DBSelectEnvironment(ctx, transaction, envName)
+	if err != nil {
+		return "", fmt.Errorf("unable to read the environment table, error: %w", err)
+	}
+	if envConfig.Freezes == nil && existingEnv != nil {
+		// Freezes are usually managed with their own transformers and not by the pipeline that creates the environment,
+		// so we keep them unless the request explicitly provides freezes:
+		envConfig.Freezes = existingEnv.Config.Freezes
+	}
+
+	err = state.DBHandler.DBWriteEnvironment(ctx, transaction, envName, envConfig)
 	if err != nil {
 		return "", fmt.Errorf("unable to write to the environment table, error: %w", err)
 	}
+	change := event.EnvironmentCreated
+	if existingEnv != nil {
+		change = event.EnvironmentUpdated
+	}
+	err = writeWebhookEvent(ctx, state, transaction, c.TransformerEslVersion, &event.EnvironmentChanged{
+		Environment: string(envName),
+		Change:      change,
+	})
+	if err != nil {
+		return "", err
+	}
 
 	// Should be empty on new environments, but we might be only updating config
 	envApps, err := state.GetEnvironmentApplications(ctx, transaction, envName)
@@ -2474,7 +2649,7 @@ func (c *DeleteEnvironment) Transform(
 	// 5) now we actually start deleting locks
 
 	for _, envLockId := range envLocks {
-		err := state.DBHandler.DBDeleteEnvironmentLock(ctx, transaction, envName, envLockId, db.LockDeletionMetadata{DeletedByUser: user.Name, DeletedByEmail: user.Email})
+		err := state.DBHandler.DBDeleteEnvironmentLock(ctx, transaction, envName, envLockId, db.LockDeletionMetadata{DeletedByUser: user.Name, DeletedByEmail: user.Email, Reason: ""})
 		if err != nil {
 			return "", err
 		}
@@ -2484,6 +2659,7 @@ func (c *DeleteEnvironment) Transform(
 		err := state.DBHandler.DBDeleteApplicationLock(ctx, transaction, envName, appLock.App, appLock.LockID, db.LockDeletionMetadata{
 			DeletedByUser:  user.Name,
 			DeletedByEmail: user.Email,
+			Reason:         "",
 		})
 		if err != nil {
 			return "", err
@@ -2494,6 +2670,7 @@ func (c *DeleteEnvironment) Transform(
 		err := state.DBHandler.DBDeleteTeamLock(ctx, transaction, envName, teamLock.Team, teamLock.LockID, db.LockDeletionMetadata{
 			DeletedByUser:  user.Name,
 			DeletedByEmail: user.Email,
+			Reason:         "",
 		})
 		if err != nil {
 			return "", err
@@ -2524,6 +2701,13 @@ func (c *DeleteEnvironment) Transform(
 	//Delete env from environments table
 	err = state.DBHandler.DBDeleteEnvironment(ctx, transaction, envName)
 
+	if err != nil {
+		return "", err
+	}
+	err = writeWebhookEvent(ctx, state, transaction, c.TransformerEslVersion, &event.EnvironmentChanged{
+		Environment: string(envName),
+		Change:      event.EnvironmentDeleted,
+	})
 	if err != nil {
 		return "", err
 	}
@@ -2593,6 +2777,13 @@ func (c *ExtendAAEnvironment) Transform(
 	if err != nil {
 		return "", fmt.Errorf("could not extend Active/Active environment: %q. %w", envName, err)
 	}
+	err = writeWebhookEvent(ctx, state, transaction, c.TransformerEslVersion, &event.EnvironmentChanged{
+		Environment: string(envName),
+		Change:      event.EnvironmentUpdated,
+	})
+	if err != nil {
+		return "", err
+	}
 	return fmt.Sprintf("Successfully added ArgoCD configuration '%s'", c.Environment), nil
 }
 
@@ -2660,6 +2851,13 @@ func (c *DeleteAAEnvironmentConfig) Transform(
 		if err != nil {
 			return "", fmt.Errorf("could not delete configuration from Active/Active environment %q. Error writing environment into database: %w", envName, err)
 		}
+		err = writeWebhookEvent(ctx, state, transaction, c.TransformerEslVersion, &event.EnvironmentChanged{
+			Environment: string(envName),
+			Change:      event.EnvironmentUpdated,
+		})
+		if err != nil {
+			return "", err
+		}
 	}
 	return fmt.Sprintf("Successfully deleted ArgoCD configuration from '%s'", c.Environment), nil
 }
@@ -2668,6 +2866,8 @@ type QueueApplicationVersion struct {
 	Environment types.EnvName
 	Application types.AppName
 	Version     uint64
+	// Reason describes why the version was not deployed, e.g. which lock prevented it
+	Reason string
 }
 
 func (c *QueueApplicationVersion) Transform(
@@ -2676,13 +2876,142 @@ func (c *QueueApplicationVersion) Transform(
 	t TransformerContext,
 	transaction *sql.Tx,
 ) (string, error) {
-	err := state.DBHandler.DBWriteDeploymentAttempt(ctx, transaction, c.Environment, c.Application, types.MakeReleaseNumberVersion(c.Version))
+	metadata := db.QueuedDeploymentMetadata{
+		QueuedByName:  "",
+		QueuedByEmail: "",
+		Reason:        c.Reason,
+	}
+	// Queueing never required a user, so the metadata stays empty without one:
+	if user, err := auth.ReadUserFromContext(ctx); err == nil {
+		metadata.QueuedByName = user.Name
+		metadata.QueuedByEmail = user.Email
+	}
+	err := state.DBHandler.DBWriteDeploymentAttemptWithMetadata(ctx, transaction, c.Environment, c.Application, types.MakeReleaseNumberVersion(c.Version), metadata)
 	if err != nil {
 		return "", err
 	}
 	return fmt.Sprintf("Queued version %d of app %q in env %q", c.Version, c.Application, c.Environment), nil
 }
 
+// readQueuedVersion returns the queued version of the application on the environment,
+// and fails if it is not the expected version, e.g. because another version was queued in the meantime.
+func readQueuedVersion(ctx context.Context, state *State, transaction *sql.Tx, environment types.EnvName, application types.AppName, version uint64) (*db.QueuedDeployment, error) {
+	queued, err := state.DBHandler.DBSelectLatestDeploymentAttempt(ctx, transaction, environment, application)
+	if err != nil {
+		return nil, err
+	}
+	if queued == nil || *queued.ReleaseNumbers.Version == 0 {
+		return nil, grpc.FailedPrecondition(ctx, fmt.Errorf("there is no queued version of app %q in env %q", application, environment))
+	}
+	if *queued.ReleaseNumbers.Version != version {
+		return nil, grpc.FailedPrecondition(ctx, fmt.Errorf("the queued version of app %q in env %q is %d, not %d", application, environment, *queued.ReleaseNumbers.Version, version))
+	}
+	return queued, nil
+}
+
+// CancelQueuedVersion removes a queued version without deploying anything.
+type CancelQueuedVersion struct {
+	Authentication        `json:"-"`
+	Environment           types.EnvName    `json:"env"`
+	Application           types.AppName    `json:"app"`
+	Version               uint64           `json:"version"`
+	TransformerEslVersion db.TransformerID `json:"-"` // Tags the transformer with EventSourcingLight eslVersion
+}
+
+func (c *CancelQueuedVersion) GetDBEventType() db.EventType {
+	return db.EvtCancelQueuedVersion
+}
+
+func (c *CancelQueuedVersion) SetEslVersion(id db.TransformerID) {
+	c.TransformerEslVersion = id
+}
+
+func (c *CancelQueuedVersion) GetEslVersion() db.TransformerID {
+	return c.TransformerEslVersion
+}
+
+func (c *CancelQueuedVersion) Transform(
+	ctx context.Context,
+	state *State,
+	t TransformerContext,
+	transaction *sql.Tx,
+) (string, error) {
+	team, err := state.GetApplicationTeamOwner(ctx, transaction, c.Application)
+	if err != nil {
+		return "", err
+	}
+	err = state.checkUserPermissions(ctx, transaction, c.Environment, c.Application, auth.PermissionDeployRelease, team, c.RBACConfig, true)
+	if err != nil {
+		return "", err
+	}
+	if _, err := readQueuedVersion(ctx, state, transaction, c.Environment, c.Application, c.Version); err != nil {
+		return "", err
+	}
+	if err := state.DeleteQueuedVersion(ctx, transaction, c.Environment, c.Application); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Cancelled queued version %d of app %q in env %q", c.Version, c.Application, c.Environment), nil
+}
+
+// DeployQueuedVersion deploys a queued version right away, ignoring the locks and freezes that prevented it.
+// The reason is stored in the event, so it is clear later why the locks were ignored.
+type DeployQueuedVersion struct {
+	Authentication        `json:"-"`
+	Environment           types.EnvName    `json:"env"`
+	Application           types.AppName    `json:"app"`
+	Version               uint64           `json:"version"`
+	Reason                string           `json:"reason"`
+	WriteCommitData       bool             `json:"writeCommitData"`
+	TransformerEslVersion db.TransformerID `json:"-"` // Tags the transformer with EventSourcingLight eslVersion
+}
+
+func (c *DeployQueuedVersion) GetDBEventType() db.EventType {
+	return db.EvtDeployQueuedVersion
+}
+
+func (c *DeployQueuedVersion) SetEslVersion(id db.TransformerID) {
+	c.TransformerEslVersion = id
+}
+
+func (c *DeployQueuedVersion) GetEslVersion() db.TransformerID {
+	return c.TransformerEslVersion
+}
+
+func (c *DeployQueuedVersion) Transform(
+	ctx context.Context,
+	state *State,
+	t TransformerContext,
+	transaction *sql.Tx,
+) (string, error) {
+	if c.Reason == "" {
+		return "", grpc.InvalidArgument(ctx, fmt.Errorf("a reason is required to deploy the queued version of app %q in env %q", c.Application, c.Environment))
+	}
+	queued, err := readQueuedVersion(ctx, state, transaction, c.Environment, c.Application, c.Version)
+	if err != nil {
+		return "", err
+	}
+	// the deployment checks the permissions and removes the queued version
+	err = t.Execute(ctx, &DeployApplicationVersion{
+		Authentication:        c.Authentication,
+		Environment:           c.Environment,
+		Application:           c.Application,
+		Version:               c.Version,
+		Revision:              queued.ReleaseNumbers.Revision,
+		LockBehaviour:         api.LockBehavior_IGNORE,
+		WriteCommitData:       c.WriteCommitData,
+		SourceTrain:           nil,
+		AutoRollback:          nil,
+		Author:                "",
+		CiLink:                "",
+		TransformerEslVersion: c.TransformerEslVersion,
+		SkipCleanup:           false,
+	}, transaction)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Deployed queued version %d of app %q to env %q ignoring locks: %s", c.Version, c.Application, c.Environment, c.Reason), nil
+}
+
 type Overview struct {
 	App     types.AppName
 	Version types.ReleaseNumbers
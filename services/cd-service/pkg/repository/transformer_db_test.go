@@ -22,8 +22,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"regexp"
 	"sort"
+	"strings"
+	"sync/atomic"
 	"testing"
 	gotime "time"
 
@@ -31,6 +35,8 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/lib/pq"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/testing/protocmp"
 
 	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
@@ -42,6 +48,8 @@ import (
 	"github.com/freiheit-com/kuberpult/pkg/testutilauth"
 	"github.com/freiheit-com/kuberpult/pkg/time"
 	"github.com/freiheit-com/kuberpult/pkg/types"
+	"github.com/freiheit-com/kuberpult/services/cd-service/pkg/gates"
+	"github.com/freiheit-com/kuberpult/services/cd-service/pkg/manifestvalidation"
 )
 
 var (
@@ -1607,6 +1615,185 @@ func TestQueueDeploymentTransformer(t *testing.T) {
 	}
 }
 
+func TestQueueApplicationVersionWithoutUserDB(t *testing.T) {
+	ctx := testutilauth.MakeTestContext()
+	repo := SetupRepositoryTestWithDB(t)
+	err := repo.State().DBHandler.WithTransaction(ctx, false, func(ctx context.Context, transaction *sql.Tx) error {
+		state := repo.State()
+		queue := &QueueApplicationVersion{
+			Environment: envProduction,
+			Application: testAppName,
+			Version:     1,
+			Reason:      "environment lock: don't",
+		}
+		// queueing does not require a user in the context
+		if _, err := queue.Transform(context.Background(), state, nil, transaction); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		queued, err := state.DBHandler.DBSelectLatestDeploymentAttempt(ctx, transaction, envProduction, testAppName)
+		if err != nil {
+			return err
+		}
+		expected := &db.QueuedDeployment{
+			Env:            envProduction,
+			App:            testAppName,
+			ReleaseNumbers: types.MakeReleaseNumberVersion(1),
+			Metadata: db.QueuedDeploymentMetadata{
+				QueuedByName:  "",
+				QueuedByEmail: "",
+				Reason:        "environment lock: don't",
+			},
+		}
+		if diff := cmp.Diff(expected, queued, cmpopts.IgnoreFields(db.QueuedDeployment{}, "Created")); diff != "" {
+			t.Errorf("queued deployment mismatch (-want, +got):\n%s", diff)
+		}
+
+		if err := state.DeleteQueuedVersion(ctx, transaction, envProduction, testAppName); err != nil {
+			return err
+		}
+		_, err = readQueuedVersion(ctx, state, transaction, envProduction, testAppName, 1)
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Errorf("expected a failed precondition for the deleted queued version, got %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestCancelAndDeployQueuedVersion(t *testing.T) {
+	queueTransformers := []Transformer{
+		&CreateEnvironment{
+			Environment: envProduction,
+			Config:      config.EnvironmentConfig{Upstream: &config.EnvironmentConfigUpstream{Latest: true}},
+		},
+		&CreateEnvironmentLock{
+			Authentication: Authentication{},
+			Environment:    envProduction,
+			Message:        "don't",
+			LockId:         "manual",
+		},
+		&CreateApplicationVersion{
+			Application: testAppName,
+			Manifests: map[types.EnvName]string{
+				envProduction: "productionmanifest",
+			},
+			WriteCommitData: true,
+			Version:         1,
+		},
+	}
+	tcs := []struct {
+		Name               string
+		Transformer        Transformer
+		ExpectedError      string
+		ExpectedQueued     []*db.QueuedDeployment
+		ExpectedDeployment *uint64
+	}{
+		{
+			Name: "cancel the queued version",
+			Transformer: &CancelQueuedVersion{
+				Environment: envProduction,
+				Application: testAppName,
+				Version:     1,
+			},
+			ExpectedQueued:     []*db.QueuedDeployment{},
+			ExpectedDeployment: nil,
+		},
+		{
+			Name: "cancel fails if another version is queued",
+			Transformer: &CancelQueuedVersion{
+				Environment: envProduction,
+				Application: testAppName,
+				Version:     2,
+			},
+			ExpectedError: "the queued version of app \"test\" in env \"production\" is 1, not 2",
+		},
+		{
+			Name: "deploy the queued version ignoring the lock",
+			Transformer: &DeployQueuedVersion{
+				Environment: envProduction,
+				Application: testAppName,
+				Version:     1,
+				Reason:      "hotfix",
+			},
+			ExpectedQueued:     []*db.QueuedDeployment{},
+			ExpectedDeployment: uversion(1),
+		},
+		{
+			Name: "deploy requires a reason",
+			Transformer: &DeployQueuedVersion{
+				Environment: envProduction,
+				Application: testAppName,
+				Version:     1,
+			},
+			ExpectedError: "a reason is required to deploy the queued version of app \"test\" in env \"production\"",
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			ctxWithTime := time.WithTimeNow(testutilauth.MakeTestContext(), timeNowOld)
+			repo := SetupRepositoryTestWithDB(t)
+			errTx := repo.State().DBHandler.WithTransaction(ctxWithTime, false, func(ctx context.Context, transaction *sql.Tx) error {
+				_, state, _, applyErr := repo.ApplyTransformersInternal(ctx, transaction, queueTransformers...)
+				if applyErr != nil {
+					t.Fatalf("expected no error, got %v", applyErr)
+				}
+				queued, err := state.DBHandler.DBSelectLatestDeploymentAttempt(ctx, transaction, envProduction, testAppName)
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				expectedMetadata := db.QueuedDeploymentMetadata{
+					QueuedByName:  "test tester",
+					QueuedByEmail: "testmail@example.com",
+					Reason:        "environment lock: don't",
+				}
+				if diff := cmp.Diff(expectedMetadata, queued.Metadata); diff != "" {
+					t.Errorf("queued metadata mismatch (-want, +got):\n%s", diff)
+				}
+
+				_, _, _, applyErr = repo.ApplyTransformersInternal(ctx, transaction, tc.Transformer)
+				if tc.ExpectedError != "" {
+					if applyErr == nil {
+						t.Fatalf("expected error %q, got none", tc.ExpectedError)
+					}
+					if !strings.Contains(applyErr.Error(), tc.ExpectedError) {
+						t.Fatalf("expected error %q, got %v", tc.ExpectedError, applyErr)
+					}
+					return nil
+				}
+				if applyErr != nil {
+					t.Fatalf("expected no error, got %v", applyErr)
+				}
+				allQueued, err := state.DBHandler.DBSelectAllLatestDeploymentAttempts(ctx, transaction)
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				if diff := cmp.Diff(tc.ExpectedQueued, allQueued); diff != "" {
+					t.Errorf("queued deployments mismatch (-want, +got):\n%s", diff)
+				}
+				deployment, err := state.DBHandler.DBSelectLatestDeployment(ctx, transaction, testAppName, envProduction)
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				var deployedVersion *uint64
+				if deployment != nil {
+					deployedVersion = deployment.ReleaseNumbers.Version
+				}
+				if diff := cmp.Diff(tc.ExpectedDeployment, deployedVersion); diff != "" {
+					t.Errorf("deployed version mismatch (-want, +got):\n%s", diff)
+				}
+				return nil
+			})
+			if errTx != nil {
+				t.Fatalf("expected no error, got %v", errTx)
+			}
+		})
+	}
+}
+
 func TestCleanupOldVersionDB(t *testing.T) {
 	const appName types.AppName = "app1"
 	tcs := []struct {
@@ -1948,6 +2135,279 @@ func TestCleanupOldVersionDB(t *testing.T) {
 	}
 }
 
+func TestCleanupWithReleaseRetentionPolicyDB(t *testing.T) {
+	const appName types.AppName = "app1"
+	createVersion := func(version uint64) Transformer {
+		return &CreateApplicationVersion{
+			Application: appName,
+			Version:     version,
+			Manifests: map[types.EnvName]string{
+				envAcceptance: "{}",
+				envProduction: "{}",
+			},
+			Team: "myteam",
+		}
+	}
+	deployToProduction := func(version uint64) Transformer {
+		return &DeployApplicationVersion{
+			Environment:   envProduction,
+			Application:   appName,
+			Version:       version,
+			LockBehaviour: api.LockBehavior_FAIL,
+		}
+	}
+	environments := []Transformer{
+		&CreateEnvironment{
+			Environment: envAcceptance,
+			Config: config.EnvironmentConfig{
+				Upstream: &config.EnvironmentConfigUpstream{Latest: true},
+			},
+		},
+		&CreateEnvironment{
+			Environment: envProduction,
+			Config: config.EnvironmentConfig{
+				Upstream: &config.EnvironmentConfigUpstream{Environment: envAcceptance},
+			},
+		},
+	}
+	tcs := []struct {
+		Name                   string
+		Transformers           []Transformer
+		ExpectedActiveReleases []types.ReleaseNumbers
+	}{
+		{
+			Name: "without policy, the global limit applies",
+			Transformers: []Transformer{
+				createVersion(1),
+				createVersion(2),
+				createVersion(3),
+				createVersion(4),
+			},
+			ExpectedActiveReleases: []types.ReleaseNumbers{
+				types.MakeReleaseNumberVersion(3),
+				types.MakeReleaseNumberVersion(4),
+			},
+		},
+		{
+			Name: "application policy keeps the last 3 releases",
+			Transformers: []Transformer{
+				createVersion(1),
+				&SetReleaseRetentionPolicy{
+					Scope:    db.ReleaseRetentionScopeApplication,
+					Name:     string(appName),
+					KeepLast: 3,
+				},
+				createVersion(2),
+				createVersion(3),
+				createVersion(4),
+			},
+			ExpectedActiveReleases: []types.ReleaseNumbers{
+				types.MakeReleaseNumberVersion(2),
+				types.MakeReleaseNumberVersion(3),
+				types.MakeReleaseNumberVersion(4),
+			},
+		},
+		{
+			Name: "team policy keeps young releases",
+			Transformers: []Transformer{
+				&SetReleaseRetentionPolicy{
+					Scope:    db.ReleaseRetentionScopeTeam,
+					Name:     "myteam",
+					KeepDays: 30,
+				},
+				createVersion(1),
+				createVersion(2),
+				createVersion(3),
+				createVersion(4),
+			},
+			ExpectedActiveReleases: []types.ReleaseNumbers{
+				types.MakeReleaseNumberVersion(1),
+				types.MakeReleaseNumberVersion(2),
+				types.MakeReleaseNumberVersion(3),
+				types.MakeReleaseNumberVersion(4),
+			},
+		},
+		{
+			Name: "team policy keeps releases that were deployed to prod",
+			Transformers: []Transformer{
+				&SetReleaseRetentionPolicy{
+					Scope:            db.ReleaseRetentionScopeTeam,
+					Name:             "myteam",
+					KeepProdDeployed: true,
+				},
+				createVersion(1),
+				deployToProduction(1),
+				createVersion(2),
+				createVersion(3),
+				createVersion(4),
+				deployToProduction(4),
+			},
+			ExpectedActiveReleases: []types.ReleaseNumbers{
+				types.MakeReleaseNumberVersion(1),
+				types.MakeReleaseNumberVersion(3),
+				types.MakeReleaseNumberVersion(4),
+			},
+		},
+		{
+			Name: "application policy takes precedence over the team policy",
+			Transformers: []Transformer{
+				&SetReleaseRetentionPolicy{
+					Scope:    db.ReleaseRetentionScopeTeam,
+					Name:     "myteam",
+					KeepDays: 30,
+				},
+				createVersion(1),
+				&SetReleaseRetentionPolicy{
+					Scope:    db.ReleaseRetentionScopeApplication,
+					Name:     string(appName),
+					KeepLast: 2,
+				},
+				createVersion(2),
+				createVersion(3),
+				createVersion(4),
+			},
+			ExpectedActiveReleases: []types.ReleaseNumbers{
+				types.MakeReleaseNumberVersion(3),
+				types.MakeReleaseNumberVersion(4),
+			},
+		},
+		{
+			Name: "deleting the application policy restores the global limit",
+			Transformers: []Transformer{
+				createVersion(1),
+				&SetReleaseRetentionPolicy{
+					Scope:    db.ReleaseRetentionScopeApplication,
+					Name:     string(appName),
+					KeepLast: 5,
+				},
+				createVersion(2),
+				createVersion(3),
+				&DeleteReleaseRetentionPolicy{
+					Scope: db.ReleaseRetentionScopeApplication,
+					Name:  string(appName),
+				},
+				createVersion(4),
+			},
+			ExpectedActiveReleases: []types.ReleaseNumbers{
+				types.MakeReleaseNumberVersion(3),
+				types.MakeReleaseNumberVersion(4),
+			},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			ctxWithTime := time.WithTimeNow(testutilauth.MakeTestContext(), timeNowOld)
+			repo := SetupRepositoryTestWithDB(t)
+			repo.(*repository).config.ReleaseVersionsLimit = 2
+			err3 := repo.State().DBHandler.WithTransaction(ctxWithTime, false, func(ctx context.Context, transaction *sql.Tx) error {
+				_, state, _, err := repo.ApplyTransformersInternal(ctx, transaction, append(environments, tc.Transformers...)...)
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				res, err2 := state.DBHandler.DBSelectAllReleasesOfApp(ctx, transaction, appName)
+				if err2 != nil {
+					return fmt.Errorf("error: %v", err2)
+				}
+				if diff := cmp.Diff(tc.ExpectedActiveReleases, res); diff != "" {
+					t.Errorf("error mismatch (-want, +got):\n%s", diff)
+				}
+				return nil
+			})
+			if err3 != nil {
+				t.Fatalf("expected no error, got %v", err3)
+			}
+		})
+	}
+}
+
+func TestPromoteAAWaveDB(t *testing.T) {
+	const appName types.AppName = "app1"
+	ctx := testutilauth.MakeTestContext()
+	repo := SetupRepositoryTestWithDB(t)
+	err := repo.State().DBHandler.WithTransaction(ctx, false, func(ctx context.Context, transaction *sql.Tx) error {
+		_, _, _, applyErr := repo.ApplyTransformersInternal(ctx, transaction,
+			&CreateEnvironment{
+				Environment: envProduction,
+				Config: config.EnvironmentConfig{
+					Upstream:       &config.EnvironmentConfigUpstream{Latest: true},
+					IsActiveActive: conversion.Bool(true),
+					ArgoCdConfigs: &config.ArgoCDConfigs{
+						CommonEnvPrefix: conversion.FromString("aa"),
+						ArgoCdConfigurations: []*config.EnvironmentConfigArgoCd{
+							{ConcreteEnvName: "de-1"},
+							{ConcreteEnvName: "de-2"},
+						},
+						Waves: &config.ArgoCDWaves{},
+					},
+				},
+			},
+			&CreateApplicationVersion{
+				Application: appName,
+				Version:     1,
+				Manifests: map[types.EnvName]string{
+					envProduction: "{}",
+				},
+				Team: "myteam",
+			},
+		)
+		if applyErr != nil {
+			t.Fatalf("expected no error, got %v", applyErr)
+		}
+		promote := &PromoteAAWave{
+			Environment:         envProduction,
+			Application:         string(appName),
+			ConcreteEnvironment: "de-2",
+			Version:             1,
+			Revision:            0,
+		}
+		_, state, _, applyErr := repo.ApplyTransformersInternal(ctx, transaction, promote)
+		if applyErr != nil {
+			t.Fatalf("expected no error, got %v", applyErr)
+		}
+		waves, err := state.DBHandler.DBSelectAAWaveDeployments(ctx, transaction, appName, envProduction)
+		if err != nil {
+			return err
+		}
+		for _, wave := range waves {
+			if !wave.IsDeployed() {
+				t.Errorf("expected version 1 to be deployed on %s", wave.ConcreteEnv)
+			}
+		}
+		// the wave does not wait for the version anymore:
+		_, _, _, applyErr = repo.ApplyTransformersInternal(ctx, transaction, promote)
+		if applyErr == nil || status.Code(applyErr.TransformerError) != codes.FailedPrecondition {
+			t.Errorf("expected a failed precondition, got %v", applyErr)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestDeleteReleaseRetentionPolicyNotFoundDB(t *testing.T) {
+	ctx := testutilauth.MakeTestContext()
+	repo := SetupRepositoryTestWithDB(t)
+	err := repo.State().DBHandler.WithTransaction(ctx, false, func(ctx context.Context, transaction *sql.Tx) error {
+		_, _, _, applyErr := repo.ApplyTransformersInternal(ctx, transaction, &DeleteReleaseRetentionPolicy{
+			Scope: db.ReleaseRetentionScopeTeam,
+			Name:  "myteam",
+		})
+		if applyErr == nil {
+			t.Fatalf("expected an error when deleting a policy that does not exist")
+		}
+		if !strings.Contains(applyErr.Error(), `there is no release retention policy for team "myteam"`) {
+			t.Errorf("unexpected error: %v", applyErr)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
 func TestCleanupZombieDeploymentsDB(t *testing.T) {
 	const appName types.AppName = "app1"
 	const envOld types.EnvName = "envOld"
@@ -3071,7 +3531,7 @@ func TestEvents(t *testing.T) {
 			},
 			testPageSize: true,
 			expectedDBEvents: []event.Event{
-				&event.NewRelease{Environments: map[string]struct{}{"staging": {}}},
+				&event.NewRelease{Environments: map[string]struct{}{"staging": {}}, Application: conversion.FromString("app")},
 			},
 		},
 		{
@@ -3104,7 +3564,7 @@ func TestEvents(t *testing.T) {
 				},
 			},
 			expectedDBEvents: []event.Event{
-				&event.NewRelease{Environments: map[string]struct{}{"staging": {}}},
+				&event.NewRelease{Environments: map[string]struct{}{"staging": {}}, Application: conversion.FromString("app")},
 				&event.Deployment{
 					Application: "app",
 					Environment: "staging",
@@ -3140,7 +3600,7 @@ func TestEvents(t *testing.T) {
 				},
 			},
 			expectedDBEvents: []event.Event{
-				&event.NewRelease{Environments: map[string]struct{}{"dev": {}}},
+				&event.NewRelease{Environments: map[string]struct{}{"dev": {}}, Application: conversion.FromString("app")},
 				&event.LockPreventedDeployment{
 					Application: "app",
 					Environment: "dev",
@@ -3184,7 +3644,7 @@ func TestEvents(t *testing.T) {
 				},
 			},
 			expectedDBEvents: []event.Event{
-				&event.NewRelease{Environments: map[string]struct{}{"dev": {}}},
+				&event.NewRelease{Environments: map[string]struct{}{"dev": {}}, Application: conversion.FromString("app")},
 				&event.Deployment{
 					Application: "app",
 					Environment: "dev",
@@ -4261,6 +4721,173 @@ func TestCreateApplicationVersionBracketMoveProtection(t *testing.T) {
 	}
 }
 
+func TestCreateApplicationVersionManifestValidation(t *testing.T) {
+	const env = envAcceptance
+	validManifest := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app1
+  namespace: app1
+`
+	tcs := []struct {
+		Name       string
+		Validation *config.ManifestValidation
+		Manifest   string
+		// Retry creates the release before the validation is configured and then creates it again
+		Retry         bool
+		expectedError *TransformerBatchApplyError
+	}{
+		{
+			Name:       "valid manifest",
+			Validation: &config.ManifestValidation{},
+			Manifest:   validManifest,
+		},
+		{
+			Name:       "invalid manifest without validation",
+			Validation: nil,
+			Manifest:   "kind: Secret",
+		},
+		{
+			Name:       "invalid manifest",
+			Validation: &config.ManifestValidation{Rules: []string{manifestvalidation.RuleDisallowedKinds}, DisallowedKinds: []string{"ConfigMap"}},
+			Manifest:   validManifest,
+			expectedError: &TransformerBatchApplyError{
+				Index: 1,
+				TransformerError: errMatcher{GetCreateReleaseInvalidManifest([]manifestvalidation.Finding{
+					{
+						Environment: env,
+						Document:    0,
+						Kind:        "ConfigMap",
+						Name:        "app1",
+						Rule:        manifestvalidation.RuleDisallowedKinds,
+						Message:     "kind ConfigMap is not allowed in this environment",
+					},
+				}).Error()},
+			},
+		},
+		{
+			Name:       "retrying an existing release is not validated again",
+			Validation: &config.ManifestValidation{Rules: []string{manifestvalidation.RuleDisallowedKinds}, DisallowedKinds: []string{"ConfigMap"}},
+			Manifest:   validManifest,
+			Retry:      true,
+			expectedError: &TransformerBatchApplyError{
+				Index:            3,
+				TransformerError: errMatcher{GetCreateReleaseAlreadyExistsSame().Error()},
+			},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			ctx := testutilauth.MakeTestContext()
+			repo := SetupRepositoryTestWithDB(t)
+			createEnvironment := func(validation *config.ManifestValidation) Transformer {
+				return &CreateEnvironment{Environment: env, Config: config.EnvironmentConfig{
+					Upstream:           &config.EnvironmentConfigUpstream{Environment: env, Latest: true},
+					ManifestValidation: validation,
+				}}
+			}
+			createRelease := func() Transformer {
+				return &CreateApplicationVersion{Application: "app1", Version: 1, Manifests: map[types.EnvName]string{env: tc.Manifest}, Team: "t", WriteCommitData: true}
+			}
+			transformers := []Transformer{createEnvironment(tc.Validation), createRelease()}
+			if tc.Retry {
+				transformers = []Transformer{createEnvironment(nil), createRelease(), createEnvironment(tc.Validation), createRelease()}
+			}
+			err := repo.State().DBHandler.WithTransaction(ctx, false, func(ctx context.Context, transaction *sql.Tx) error {
+				if _, _, _, applyErr := repo.ApplyTransformersInternal(ctx, transaction, transformers...); applyErr != nil {
+					return applyErr
+				}
+				return nil
+			})
+			if tc.expectedError == nil {
+				if err != nil {
+					t.Fatalf("expected no error, got: %v", err)
+				}
+				return
+			}
+			if diff := cmp.Diff(tc.expectedError, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("error mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDeployApplicationVersionDependencies(t *testing.T) {
+	const env = envAcceptance
+	createReleases := []Transformer{
+		&CreateEnvironment{Environment: env, Config: config.EnvironmentConfig{Upstream: &config.EnvironmentConfigUpstream{Latest: true}}},
+		&CreateApplicationVersion{Application: "payments", Version: 1, Manifests: map[types.EnvName]string{env: "payments"}, Team: "t", WriteCommitData: true},
+		&CreateApplicationVersion{Application: "payments", Version: 2, Manifests: map[types.EnvName]string{env: "payments"}, Team: "t", WriteCommitData: true, SkipDeployment: true},
+		&CreateApplicationVersion{Application: "frontend", Version: 1, Manifests: map[types.EnvName]string{env: "frontend"}, Team: "t", WriteCommitData: true, SkipDeployment: true,
+			Dependencies: []db.ReleaseDependency{{Application: "payments", MinVersion: 2}}},
+	}
+	tcs := []struct {
+		Name            string
+		Transformers    []Transformer
+		expectedError   *TransformerBatchApplyError
+		expectedVersion *uint64
+	}{
+		{
+			Name: "dependency not deployed",
+			Transformers: []Transformer{
+				&DeployApplicationVersion{Environment: env, Application: "frontend", Version: 1, LockBehaviour: api.LockBehavior_FAIL},
+			},
+			expectedError: &TransformerBatchApplyError{
+				Index:            4,
+				TransformerError: errMatcher{`rpc error: code = FailedPrecondition desc = error: cannot deploy version 1 of "frontend" to "acceptance" because of unmet dependencies: payments >= 2 (deployed: 1)`},
+			},
+		},
+		{
+			Name: "dependency not deployed queues the version",
+			Transformers: []Transformer{
+				&DeployApplicationVersion{Environment: env, Application: "frontend", Version: 1, LockBehaviour: api.LockBehavior_RECORD},
+			},
+			expectedVersion: nil,
+		},
+		{
+			Name: "dependency deployed",
+			Transformers: []Transformer{
+				&DeployApplicationVersion{Environment: env, Application: "payments", Version: 2, LockBehaviour: api.LockBehavior_FAIL},
+				&DeployApplicationVersion{Environment: env, Application: "frontend", Version: 1, LockBehaviour: api.LockBehavior_FAIL},
+			},
+			expectedVersion: uversion(1),
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			ctx := testutilauth.MakeTestContext()
+			repo := SetupRepositoryTestWithDB(t)
+			var deployment *db.Deployment
+			err := repo.State().DBHandler.WithTransaction(ctx, false, func(ctx context.Context, transaction *sql.Tx) error {
+				if _, _, _, applyErr := repo.ApplyTransformersInternal(ctx, transaction, append(createReleases, tc.Transformers...)...); applyErr != nil {
+					return applyErr
+				}
+				var err error
+				deployment, err = repo.State().DBHandler.DBSelectLatestDeployment(ctx, transaction, "frontend", env)
+				return err
+			})
+			if tc.expectedError != nil {
+				if diff := cmp.Diff(tc.expectedError, err, cmpopts.EquateErrors()); diff != "" {
+					t.Errorf("error mismatch (-want, +got):\n%s", diff)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			var actualVersion *uint64
+			if deployment != nil {
+				actualVersion = deployment.ReleaseNumbers.Version
+			}
+			if diff := cmp.Diff(tc.expectedVersion, actualVersion); diff != "" {
+				t.Errorf("deployed version mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestUndeployApplicationDB(t *testing.T) {
 	tcs := []struct {
 		Name          string
@@ -6635,3 +7262,88 @@ func TestChangedAppsSyncStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestDeployApplicationVersionDeploymentGatesDB(t *testing.T) {
+	const env = envAcceptance
+	tcs := []struct {
+		Name          string
+		LockBehaviour api.LockBehavior
+		ExpectQueued  bool
+	}{
+		{
+			Name:          "fail returns the rejection",
+			LockBehaviour: api.LockBehavior_FAIL,
+		},
+		{
+			Name:          "gates cannot be ignored",
+			LockBehaviour: api.LockBehavior_IGNORE,
+		},
+		{
+			Name:          "record queues the version",
+			LockBehaviour: api.LockBehavior_RECORD,
+			ExpectQueued:  true,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				_ = json.NewEncoder(w).Encode(gates.Response{Approved: false, Message: "no change request"})
+			}))
+			t.Cleanup(server.Close)
+
+			ctx := testutilauth.MakeTestContext()
+			repo := SetupRepositoryTestWithDB(t)
+			err := repo.Apply(ctx,
+				&CreateEnvironment{Environment: env, Config: config.EnvironmentConfig{
+					Upstream:        &config.EnvironmentConfigUpstream{Latest: true},
+					DeploymentGates: []config.DeploymentGate{{Name: "cm", Url: server.URL}},
+				}},
+				&CreateApplicationVersion{Application: "app1", Version: 1, Manifests: map[types.EnvName]string{env: "app1"}, Team: "t", WriteCommitData: true, SkipDeployment: true},
+			)
+			if err != nil {
+				t.Fatalf("setup failed: %v", err)
+			}
+
+			err = repo.Apply(ctx, &DeployApplicationVersion{Environment: env, Application: "app1", Version: 1, LockBehaviour: tc.LockBehaviour})
+			if tc.ExpectQueued {
+				if err != nil {
+					t.Fatalf("expected the version to be queued, got: %v", err)
+				}
+				err = repo.State().DBHandler.WithTransaction(ctx, true, func(ctx context.Context, transaction *sql.Tx) error {
+					attempt, err := repo.State().DBHandler.DBSelectLatestDeploymentAttempt(ctx, transaction, env, "app1")
+					if err != nil {
+						return err
+					}
+					if attempt == nil || attempt.ReleaseNumbers.Version == nil || *attempt.ReleaseNumbers.Version != 1 {
+						t.Fatalf("expected version 1 to be queued, got: %v", attempt)
+					}
+					if !strings.Contains(attempt.Metadata.Reason, `deployment gate "cm": no change request`) {
+						t.Errorf("expected the rejection of the gate as reason, got: %q", attempt.Metadata.Reason)
+					}
+					return nil
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				var applyErr *TransformerBatchApplyError
+				if !errors.As(err, &applyErr) || status.Code(applyErr.TransformerError) != codes.FailedPrecondition {
+					t.Fatalf("expected the gate to reject the deployment, got: %v", err)
+				}
+				var gateErr *GateRejectedError
+				if !errors.As(applyErr.TransformerError, &gateErr) {
+					t.Fatalf("expected a GateRejectedError, got: %v", applyErr.TransformerError)
+				}
+				if gateErr.Rejection.Gate != "cm" || gateErr.Rejection.Message != "no change request" {
+					t.Errorf("expected the rejection of the gate, got: %v", gateErr.Rejection)
+				}
+			}
+			// the gate is asked once, outside the transaction, and not again when the transaction runs with its decision
+			if requests.Load() != 1 {
+				t.Errorf("expected 1 request to the gate, got %d", requests.Load())
+			}
+		})
+	}
+}
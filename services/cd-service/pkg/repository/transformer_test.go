@@ -6419,3 +6419,389 @@ func TestManifestLockCreatedAt(t *testing.T) {
 		})
 	}
 }
+
+func TestEnvironmentsRequiringApproval(t *testing.T) {
+	production := "production"
+	configs := map[types.EnvName]config.EnvironmentConfig{
+		"dev": {
+			Upstream: &config.EnvironmentConfigUpstream{Latest: true},
+		},
+		"staging": {
+			Upstream: &config.EnvironmentConfigUpstream{Environment: "dev"},
+		},
+		"prod-de": {
+			Upstream:         &config.EnvironmentConfigUpstream{Environment: "staging"},
+			EnvironmentGroup: &production,
+		},
+		"prod-fr": {
+			Upstream:         &config.EnvironmentConfigUpstream{Environment: "staging"},
+			EnvironmentGroup: &production,
+		},
+	}
+	deployTo := func(env types.EnvName) Transformer {
+		return &DeployApplicationVersion{Environment: env, Application: "app1", Version: 1}
+	}
+	trainTo := func(target string, targetType api.ReleaseTrainRequest_TargetType) Transformer {
+		return &ReleaseTrain{Target: target, TargetType: targetType.String()}
+	}
+	tcs := []struct {
+		Name        string
+		Config      DeploymentApprovalConfig
+		Transformer Transformer
+		Expected    []types.EnvName
+	}{
+		{
+			Name:        "disabled",
+			Config:      DeploymentApprovalConfig{Enabled: false},
+			Transformer: deployTo("prod-de"),
+			Expected:    nil,
+		},
+		{
+			Name:        "deployment to a prod environment",
+			Config:      DeploymentApprovalConfig{Enabled: true},
+			Transformer: deployTo("prod-de"),
+			Expected:    []types.EnvName{"prod-de"},
+		},
+		{
+			Name:        "deployment to a non-prod environment",
+			Config:      DeploymentApprovalConfig{Enabled: true},
+			Transformer: deployTo("staging"),
+			Expected:    []types.EnvName{},
+		},
+		{
+			Name:        "release train to a prod environment group",
+			Config:      DeploymentApprovalConfig{Enabled: true},
+			Transformer: trainTo("production", api.ReleaseTrainRequest_ENVIRONMENTGROUP),
+			Expected:    []types.EnvName{"prod-de", "prod-fr"},
+		},
+		{
+			Name:        "release train to a non-prod environment",
+			Config:      DeploymentApprovalConfig{Enabled: true},
+			Transformer: trainTo("staging", api.ReleaseTrainRequest_ENVIRONMENT),
+			Expected:    []types.EnvName{},
+		},
+		{
+			Name:        "configured environments replace the prod priority",
+			Config:      DeploymentApprovalConfig{Enabled: true, Environments: []types.EnvName{"staging", "prod-fr"}},
+			Transformer: trainTo("production", api.ReleaseTrainRequest_ENVIRONMENTGROUP),
+			Expected:    []types.EnvName{"prod-fr"},
+		},
+		{
+			Name:        "configured non-prod environment",
+			Config:      DeploymentApprovalConfig{Enabled: true, Environments: []types.EnvName{"staging"}},
+			Transformer: deployTo("staging"),
+			Expected:    []types.EnvName{"staging"},
+		},
+		{
+			Name:        "other transformers never require an approval",
+			Config:      DeploymentApprovalConfig{Enabled: true},
+			Transformer: &CreateEnvironmentLock{Environment: "prod-de", LockId: "l1"},
+			Expected:    nil,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			actual := tc.Config.EnvironmentsRequiringApproval(configs, tc.Transformer)
+			if diff := cmp.Diff(tc.Expected, actual); diff != "" {
+				t.Errorf("environments mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCheckApprover(t *testing.T) {
+	approval := &db.DeploymentApproval{
+		ApprovalId:          "a1",
+		RequestedByEmail:    "requester@example.com",
+		RequestedByIdentity: "dex:requester@example.com",
+	}
+	tcs := []struct {
+		Name          string
+		Approval      *db.DeploymentApproval
+		User          auth.User
+		ExpectedError error
+	}{
+		{
+			Name:     "another user that logged in",
+			Approval: approval,
+			User:     auth.User{Email: "approver@example.com", Identity: "dex:approver@example.com"},
+		},
+		{
+			Name:          "user that did not log in",
+			Approval:      approval,
+			User:          auth.User{Email: "approver@example.com"},
+			ExpectedError: errMatcher{"rpc error: code = Unauthenticated desc = error: deployment approval a1 can only be approved by a user that logged in"},
+		},
+		{
+			Name:          "requester with another email",
+			Approval:      approval,
+			User:          auth.User{Email: "approver@example.com", Identity: "dex:requester@example.com"},
+			ExpectedError: errMatcher{"rpc error: code = FailedPrecondition desc = error: deployment approval a1 cannot be approved by the user that requested it"},
+		},
+		{
+			Name: "requester that did not log in",
+			Approval: &db.DeploymentApproval{
+				ApprovalId:       "a1",
+				RequestedByEmail: "requester@example.com",
+			},
+			User:          auth.User{Email: "Requester@example.com", Identity: "azure:1234"},
+			ExpectedError: errMatcher{"rpc error: code = FailedPrecondition desc = error: deployment approval a1 cannot be approved by the user that requested it"},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := checkApprover(context.Background(), tc.Approval, &tc.User)
+			if diff := cmp.Diff(tc.ExpectedError, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("error mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCreateApplicationVersionRequiresApproval(t *testing.T) {
+	tcs := []struct {
+		Name                string
+		Transformers        []Transformer
+		ExpectedEnvs        []types.EnvName
+		ExpectedDeployments int
+	}{
+		{
+			Name: "latest environment that requires an approval",
+			Transformers: []Transformer{
+				&CreateEnvironment{
+					Environment: envProduction,
+					Config:      config.EnvironmentConfig{Upstream: &config.EnvironmentConfigUpstream{Latest: true}},
+				},
+				&CreateApplicationVersion{
+					Application: "app1",
+					Manifests: map[types.EnvName]string{
+						envProduction: "productionmanifest",
+					},
+					Version: 1,
+				},
+			},
+			ExpectedEnvs:        []types.EnvName{envProduction},
+			ExpectedDeployments: 0,
+		},
+		{
+			Name: "latest environment without approval",
+			Transformers: []Transformer{
+				&CreateEnvironment{
+					Environment: envAcceptance,
+					Config:      config.EnvironmentConfig{Upstream: &config.EnvironmentConfigUpstream{Latest: true}},
+				},
+				&CreateApplicationVersion{
+					Application: "app1",
+					Manifests: map[types.EnvName]string{
+						envAcceptance: "acceptancemanifest",
+					},
+					Version: 1,
+				},
+			},
+			ExpectedEnvs:        nil,
+			ExpectedDeployments: 1,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			repo, _ := setupRepositoryTestWithConfig(t, RepositoryConfig{
+				ArgoCdGenerateFiles: true,
+				MaxNumThreads:       1,
+				DeploymentApproval: DeploymentApprovalConfig{
+					Enabled:      true,
+					Environments: []types.EnvName{envProduction},
+					Expiry:       time.Hour,
+				},
+			})
+			ctx := AddGeneratorToContext(testutilauth.MakeTestContext(), testutil.NewIncrementalUUIDGenerator())
+			r := repo.(*repository)
+			_ = r.DB.WithTransaction(ctx, false, func(ctx context.Context, transaction *sql.Tx) error {
+				_, _, _, applyErr := repo.ApplyTransformersInternal(ctx, transaction, tc.Transformers...)
+				if applyErr != nil {
+					t.Errorf("unexpected error: %v", applyErr)
+					return nil
+				}
+				approvals, err := r.DB.DBSelectPendingDeploymentApprovals(ctx, transaction)
+				if err != nil {
+					t.Errorf("could not read approvals: %v", err)
+					return nil
+				}
+				var actualEnvs []types.EnvName
+				for _, approval := range approvals {
+					actualEnvs = append(actualEnvs, approval.Environments...)
+				}
+				if diff := cmp.Diff(tc.ExpectedEnvs, actualEnvs); diff != "" {
+					t.Errorf("approval environments mismatch (-want, +got):\n%s", diff)
+				}
+				deployments := 0
+				for _, env := range []types.EnvName{envProduction, envAcceptance} {
+					deployment, err := r.DB.DBSelectLatestDeployment(ctx, transaction, "app1", env)
+					if err != nil {
+						t.Errorf("could not read deployment: %v", err)
+						return nil
+					}
+					if deployment != nil && deployment.ReleaseNumbers.Version != nil {
+						deployments++
+					}
+				}
+				if deployments != tc.ExpectedDeployments {
+					t.Errorf("expected %d deployments, got %d", tc.ExpectedDeployments, deployments)
+				}
+				return nil
+			})
+		})
+	}
+}
+
+func TestCheckAutoRollback(t *testing.T) {
+	tcs := []struct {
+		Name          string
+		User          auth.User
+		ExpectedError error
+	}{
+		{
+			Name: "rollout-service",
+			User: auth.User{Email: "kuberpult-auto-rollback@local", Identity: auth.RolloutServiceIdentity},
+		},
+		{
+			Name:          "user with the email of the rollout-service",
+			User:          auth.User{Email: "kuberpult-auto-rollback@local", Identity: ""},
+			ExpectedError: errMatcher{`rpc error: code = Unauthenticated desc = error: only the rollout-service can roll back app "app1" on environment "production" automatically`},
+		},
+		{
+			Name:          "user that logged in",
+			User:          auth.User{Email: "a@example.com", Identity: "dex:a@example.com"},
+			ExpectedError: errMatcher{`rpc error: code = Unauthenticated desc = error: only the rollout-service can roll back app "app1" on environment "production" automatically`},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			deployment := &DeployApplicationVersion{
+				Environment:  envProduction,
+				Application:  "app1",
+				Version:      1,
+				AutoRollback: &DeployApplicationVersionRollback{RolledBackVersion: 2, Reason: "unhealthy"},
+			}
+			ctx := auth.WriteUserToContext(context.Background(), tc.User)
+			// without Dex, the state is not needed
+			err := deployment.checkAutoRollback(ctx, nil, nil)
+			if diff := cmp.Diff(tc.ExpectedError, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("error mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestApproveAutoRollback(t *testing.T) {
+	repo, _ := setupRepositoryTestWithConfig(t, RepositoryConfig{
+		ArgoCdGenerateFiles: true,
+		MaxNumThreads:       1,
+		DeploymentApproval: DeploymentApprovalConfig{
+			Enabled:      true,
+			Environments: []types.EnvName{envProduction},
+			Expiry:       time.Hour,
+		},
+	})
+	ctx := AddGeneratorToContext(testutilauth.MakeTestContext(), testutil.NewIncrementalUUIDGenerator())
+	r := repo.(*repository)
+	const rolledBackCommit = "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	setup := []Transformer{
+		&CreateEnvironment{
+			Environment: envAcceptance,
+			Config:      config.EnvironmentConfig{Upstream: &config.EnvironmentConfigUpstream{Latest: true}},
+		},
+		&CreateEnvironment{
+			Environment: envProduction,
+			Config:      config.EnvironmentConfig{Upstream: &config.EnvironmentConfigUpstream{Environment: envAcceptance}},
+		},
+		&CreateApplicationVersion{
+			Application:    "app1",
+			Manifests:      map[types.EnvName]string{envAcceptance: "v1", envProduction: "v1"},
+			SourceCommitId: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+			Version:        1,
+		},
+		&CreateApplicationVersion{
+			Application:    "app1",
+			Manifests:      map[types.EnvName]string{envAcceptance: "v2", envProduction: "v2"},
+			SourceCommitId: rolledBackCommit,
+			Version:        2,
+		},
+		&DeployApplicationVersion{
+			Environment:   envProduction,
+			Application:   "app1",
+			Version:       2,
+			LockBehaviour: api.LockBehavior_FAIL,
+		},
+	}
+	rollback := &DeployApplicationVersion{
+		Environment:   envProduction,
+		Application:   "app1",
+		Version:       1,
+		LockBehaviour: api.LockBehavior_RECORD,
+		Author:        "rollout-service",
+		CiLink:        "https://ci.example.com/1",
+		AutoRollback:  &DeployApplicationVersionRollback{RolledBackVersion: 2, Reason: "unhealthy"},
+	}
+	request, err := NewDeploymentApprovalRequest(rollback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rolloutCtx := auth.WriteUserToContext(ctx, auth.User{Email: "kuberpult-auto-rollback@local", Name: "rollout-service", Identity: auth.RolloutServiceIdentity})
+	approverCtx := auth.WriteUserToContext(ctx, auth.User{Email: "approver@example.com", Name: "approver", Identity: "dex:approver@example.com"})
+
+	_ = r.DB.WithTransaction(ctx, false, func(ctx context.Context, transaction *sql.Tx) error {
+		if _, _, _, applyErr := repo.ApplyTransformersInternal(ctx, transaction, setup...); applyErr != nil {
+			t.Fatalf("unexpected error: %v", applyErr)
+		}
+		_, _, _, applyErr := repo.ApplyTransformersInternal(rolloutCtx, transaction, &CreateDeploymentApproval{
+			ApprovalId:   "a1",
+			Environments: []types.EnvName{envProduction},
+			Request:      request,
+			Expiry:       time.Hour,
+		})
+		if applyErr != nil {
+			t.Fatalf("unexpected error: %v", applyErr)
+		}
+		_, _, _, applyErr = repo.ApplyTransformersInternal(approverCtx, transaction, &ApproveDeployment{ApprovalId: "a1"})
+		if applyErr != nil {
+			t.Fatalf("unexpected error: %v", applyErr)
+		}
+
+		deployment, err := r.DB.DBSelectLatestDeployment(ctx, transaction, "app1", envProduction)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if deployment == nil || deployment.ReleaseNumbers.Version == nil || *deployment.ReleaseNumbers.Version != 1 {
+			t.Fatalf("expected version 1 to be deployed, got %+v", deployment)
+		}
+		expectedMetadata := db.DeploymentMetadata{
+			DeployedByName:  "rollout-service",
+			DeployedByEmail: "kuberpult-auto-rollback@local",
+			CiLink:          "https://ci.example.com/1",
+		}
+		if diff := cmp.Diff(expectedMetadata, deployment.Metadata); diff != "" {
+			t.Errorf("deployment metadata mismatch (-want, +got):\n%s", diff)
+		}
+		approval, err := r.DB.DBSelectDeploymentApproval(ctx, transaction, "a1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff("rollout-service", approval.Request.Deployment.Author); diff != "" {
+			t.Errorf("approval author mismatch (-want, +got):\n%s", diff)
+		}
+		events, err := r.DB.DBSelectAllEventsForCommit(ctx, transaction, rolledBackCommit, 0, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rollbackEvents := 0
+		for _, ev := range events {
+			if ev.EventType == event.EventTypeAutoRollback {
+				rollbackEvents++
+			}
+		}
+		if rollbackEvents != 1 {
+			t.Errorf("expected one auto-rollback event on the rolled back release, got %d in %+v", rollbackEvents, events)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,89 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/grpc"
+	"github.com/freiheit-com/kuberpult/pkg/types"
+)
+
+// PromoteAAWave deploys a version to the next concrete environment of an active/active environment
+// that is rolled out in waves. It is applied by the waves package, not on behalf of a user.
+// The manifest-repo-export-service writes the manifests of the concrete environment when it processes the event.
+type PromoteAAWave struct {
+	Environment           types.EnvName    `json:"env"`
+	Application           string           `json:"app"`
+	ConcreteEnvironment   string           `json:"concreteEnv"`
+	Version               uint64           `json:"version"`
+	Revision              uint64           `json:"revision"`
+	TransformerEslVersion db.TransformerID `json:"-"` // Tags the transformer with EventSourcingLight eslVersion
+}
+
+func (c *PromoteAAWave) GetDBEventType() db.EventType {
+	return db.EvtPromoteAAWave
+}
+
+func (c *PromoteAAWave) SetEslVersion(id db.TransformerID) {
+	c.TransformerEslVersion = id
+}
+
+func (c *PromoteAAWave) GetEslVersion() db.TransformerID {
+	return c.TransformerEslVersion
+}
+
+func (c *PromoteAAWave) Transform(
+	ctx context.Context,
+	state *State,
+	_ TransformerContext,
+	transaction *sql.Tx,
+) (string, error) {
+	now, err := state.DBHandler.DBReadTransactionTimestamp(ctx, transaction)
+	if err != nil {
+		return "", err
+	}
+	if now == nil {
+		return "", fmt.Errorf("could not get transaction timestamp: nil")
+	}
+	target := types.MakeReleaseNumbers(c.Version, c.Revision)
+	waves, err := state.DBHandler.DBSelectAAWaveDeployments(ctx, transaction, types.AppName(c.Application), c.Environment)
+	if err != nil {
+		return "", err
+	}
+	var wave *db.AAWaveDeployment
+	for _, w := range waves {
+		if w.ConcreteEnv == c.ConcreteEnvironment && types.Equal(w.Target, target) && !w.IsDeployed() {
+			wave = w
+		}
+	}
+	if wave == nil {
+		// a new deployment started a new wave in the meantime, or another replica promoted it already
+		return "", grpc.FailedPrecondition(ctx, fmt.Errorf("the wave of app %q on environment %q does not wait for version %v on %q", c.Application, c.Environment, target, c.ConcreteEnvironment))
+	}
+	updated, err := state.DBHandler.DBUpdateAAWaveDeployed(ctx, transaction, *wave, *now)
+	if err != nil {
+		return "", err
+	}
+	if !updated {
+		return "", fmt.Errorf("could not promote the wave of app %q on %q of environment %q", c.Application, c.ConcreteEnvironment, c.Environment)
+	}
+	return fmt.Sprintf("Promoted version %v of app %q to %q of environment %q", target, c.Application, c.ConcreteEnvironment, c.Environment), nil
+}
@@ -0,0 +1,362 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
+	"github.com/freiheit-com/kuberpult/pkg/auth"
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/grpc"
+	"github.com/freiheit-com/kuberpult/services/cd-service/pkg/webhooks"
+)
+
+const (
+	auditDefaultPageSize = 50
+	auditMaxPageSize     = 500
+	// The application, environment and team filters are applied after reading the events,
+	// so a page reads at most this many batches before it is returned with less entries.
+	auditMaxBatches = 10
+)
+
+// AuditServer reads the audit log from the event_sourcing_light table.
+type AuditServer struct {
+	DBHandler  *db.DBHandler
+	RBACConfig auth.RBACConfig
+}
+
+func (s *AuditServer) checkUserPermissions(ctx context.Context) error {
+	if !s.RBACConfig.DexEnabled {
+		return nil
+	}
+	user, err := auth.ReadUserFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("checkUserPermissions: user not found: %v", err)
+	}
+	return auth.CheckUserPermissions(s.RBACConfig, user, "*", "", "*", "*", auth.PermissionReadAuditLog)
+}
+
+func (s *AuditServer) GetAuditLog(ctx context.Context, in *api.GetAuditLogRequest) (*api.GetAuditLogResponse, error) {
+	if err := s.checkUserPermissions(ctx); err != nil {
+		return nil, grpc.AuthError(ctx, err)
+	}
+	filter, err := auditFilter(in)
+	if err != nil {
+		return nil, grpc.InvalidArgument(ctx, err)
+	}
+	cursor := db.EslVersion(0)
+	if in.PageToken != "" {
+		token, err := strconv.ParseUint(in.PageToken, 10, 64)
+		if err != nil || token == 0 {
+			return nil, grpc.InvalidArgument(ctx, fmt.Errorf("invalid page token '%s'", in.PageToken))
+		}
+		cursor = db.EslVersion(token)
+	}
+	pageSize := uint(in.PageSize)
+	if pageSize == 0 {
+		pageSize = auditDefaultPageSize
+	}
+	pageSize = min(pageSize, auditMaxPageSize)
+
+	response, err := db.WithTransactionT(s.DBHandler, ctx, 2, true, func(ctx context.Context, transaction *sql.Tx) (*api.GetAuditLogResponse, error) {
+		response := &api.GetAuditLogResponse{
+			Entries:       []*api.AuditLogEntry{},
+			NextPageToken: "",
+		}
+		teams := map[string]string{}
+		for range auditMaxBatches {
+			rows, err := s.DBHandler.DBSelectEslEventsBefore(ctx, transaction, filter, cursor, pageSize)
+			if err != nil {
+				return nil, err
+			}
+			for _, row := range rows {
+				cursor = row.EslVersion
+				entry, environments := transformEslEventToAuditEntry(row)
+				if entry.Team == "" && entry.Application != "" {
+					entry.Team, err = webhooks.LookupTeam(ctx, s.DBHandler, transaction, entry.Application, teams)
+					if err != nil {
+						return nil, err
+					}
+				}
+				if !auditEntryMatches(in, entry, environments) {
+					continue
+				}
+				response.Entries = append(response.Entries, entry)
+				if uint(len(response.Entries)) == pageSize {
+					response.NextPageToken = strconv.FormatUint(uint64(cursor), 10)
+					return response, nil
+				}
+			}
+			if uint(len(rows)) < pageSize {
+				return response, nil
+			}
+		}
+		response.NextPageToken = strconv.FormatUint(uint64(cursor), 10)
+		return response, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func auditFilter(in *api.GetAuditLogRequest) (db.EslEventFilter, error) {
+	filter := db.EslEventFilter{
+		From:       nil,
+		To:         nil,
+		EventTypes: nil,
+		Author:     in.Author,
+	}
+	if in.From != nil {
+		from := in.From.AsTime()
+		filter.From = &from
+	}
+	if in.To != nil {
+		to := in.To.AsTime()
+		filter.To = &to
+	}
+	if filter.From != nil && filter.To != nil && filter.From.After(*filter.To) {
+		return filter, fmt.Errorf("from must not be after to")
+	}
+	for _, eventType := range in.EventTypes {
+		if !slices.Contains(db.EventTypes, db.EventType(eventType)) {
+			return filter, fmt.Errorf("unknown event type '%s'", eventType)
+		}
+		filter.EventTypes = append(filter.EventTypes, db.EventType(eventType))
+	}
+	return filter, nil
+}
+
+func auditEntryMatches(in *api.GetAuditLogRequest, entry *api.AuditLogEntry, environments []string) bool {
+	if in.Application != "" && entry.Application != in.Application {
+		return false
+	}
+	if in.Environment != "" && !slices.Contains(environments, in.Environment) {
+		return false
+	}
+	if in.Team != "" && entry.Team != in.Team {
+		return false
+	}
+	return true
+}
+
+// auditEventData has the fields of all transformers that are relevant for the audit log.
+type auditEventData struct {
+	Application string `json:"app"`
+	// CleanupOldApplicationVersions is stored without json tags
+	ApplicationName     string   `json:"Application"`
+	Environment         string   `json:"env"`
+	EnvironmentGroup    string   `json:"envGroup"`
+	ConcreteEnvironment string   `json:"concreteEnv"`
+	ConcreteEnvName     string   `json:"concreteEnvName"`
+	Environments        []string `json:"environments"`
+	Team                string   `json:"team"`
+	Version             uint64   `json:"version"`
+	LockId              string   `json:"lockId"`
+	Message             string   `json:"message"`
+	Messages            []string `json:"Messages"`
+	Reason              string   `json:"reason"`
+	Target              string   `json:"target"`
+	FreezeId            string   `json:"freezeId"`
+	Freeze              struct {
+		Id string `json:"id"`
+	} `json:"freeze"`
+	ApprovalId string                       `json:"approvalId"`
+	Request    db.DeploymentApprovalRequest `json:"request"`
+	Scope      db.ReleaseRetentionScope     `json:"scope"`
+	Name       string                       `json:"name"`
+	// the release train of an EvtEnvReleaseTrain
+	Parent *struct {
+		Target string `json:"target"`
+	} `json:"Parent"`
+	Metadata db.ESLMetadata `json:"metadata"`
+}
+
+// transformEslEventToAuditEntry decodes the event. It also returns all environments that the event refers to.
+// Events that cannot be decoded are returned with the event type as summary.
+func transformEslEventToAuditEntry(row *db.EslEventRow) (*api.AuditLogEntry, []string) {
+	entry := &api.AuditLogEntry{
+		EslVersion:       int64(row.EslVersion),
+		CreatedAt:        timestamppb.New(row.Created),
+		EventType:        string(row.EventType),
+		AuthorName:       "",
+		AuthorEmail:      "",
+		Application:      "",
+		Environment:      "",
+		EnvironmentGroup: "",
+		Team:             "",
+		Summary:          string(row.EventType),
+		Json:             row.EventJson,
+	}
+	//exhaustruct:ignore
+	data := auditEventData{}
+	if err := json.Unmarshal([]byte(row.EventJson), &data); err != nil {
+		return entry, nil
+	}
+	entry.AuthorName = data.Metadata.AuthorName
+	entry.AuthorEmail = data.Metadata.AuthorEmail
+	entry.Application = data.Application
+	if entry.Application == "" {
+		entry.Application = data.ApplicationName
+	}
+	entry.Environment = data.Environment
+	entry.EnvironmentGroup = data.EnvironmentGroup
+	entry.Team = data.Team
+	switch row.EventType {
+	case db.EvtCreateEnvironmentGroupLock:
+		// the group is stored as env
+		entry.Environment, entry.EnvironmentGroup = "", data.Environment
+	case db.EvtCreateDeploymentApproval:
+		if data.Request.Deployment != nil {
+			entry.Application = string(data.Request.Deployment.Application)
+			entry.Environment = string(data.Request.Deployment.Environment)
+		}
+		if data.Request.ReleaseTrain != nil {
+			entry.Team = data.Request.ReleaseTrain.Team
+		}
+	case db.EvtSetReleaseRetentionPolicy, db.EvtDeleteReleaseRetentionPolicy:
+		if data.Scope == db.ReleaseRetentionScopeApplication {
+			entry.Application = data.Name
+		} else {
+			entry.Team = data.Name
+		}
+	}
+	entry.Summary = auditSummary(row.EventType, entry, &data)
+	environments := slices.Clone(data.Environments)
+	if entry.Environment != "" && !slices.Contains(environments, entry.Environment) {
+		environments = append(environments, entry.Environment)
+	}
+	return entry, environments
+}
+
+func auditSummary(eventType db.EventType, entry *api.AuditLogEntry, data *auditEventData) string {
+	app, env, group := entry.Application, entry.Environment, entry.EnvironmentGroup
+	switch eventType {
+	case db.EvtCreateApplicationVersion:
+		return fmt.Sprintf("Created version %d of application %s", data.Version, app)
+	case db.EvtDeployApplicationVersion:
+		return fmt.Sprintf("Deployed version %d of application %s to environment %s", data.Version, app, env)
+	case db.EvtCreateUndeployApplicationVersion:
+		return fmt.Sprintf("Created an undeploy version of application %s", app)
+	case db.EvtUndeployApplication:
+		return fmt.Sprintf("Undeployed application %s", app)
+	case db.EvtDeleteEnvFromApp:
+		return fmt.Sprintf("Removed environment %s from application %s", env, app)
+	case db.EvtCleanupOldApplicationVersions:
+		return fmt.Sprintf("Cleaned up old versions of application %s", app)
+	case db.EvtCreateEnvironmentLock:
+		return fmt.Sprintf("Created environment lock %s on environment %s with message %q", data.LockId, env, data.Message)
+	case db.EvtDeleteEnvironmentLock:
+		return withReason(fmt.Sprintf("Deleted environment lock %s on environment %s", data.LockId, env), data.Reason)
+	case db.EvtCreateEnvironmentGroupLock:
+		return fmt.Sprintf("Created environment group lock %s on environment group %s with message %q", data.LockId, group, data.Message)
+	case db.EvtDeleteEnvironmentGroupLock:
+		return fmt.Sprintf("Deleted environment group lock %s on environment group %s", data.LockId, group)
+	case db.EvtCreateEnvironmentApplicationLock:
+		return fmt.Sprintf("Created application lock %s for application %s on environment %s with message %q", data.LockId, app, env, data.Message)
+	case db.EvtDeleteEnvironmentApplicationLock:
+		return withReason(fmt.Sprintf("Deleted application lock %s for application %s on environment %s", data.LockId, app, env), data.Reason)
+	case db.EvtCreateEnvironmentTeamLock:
+		return fmt.Sprintf("Created team lock %s for team %s on environment %s with message %q", data.LockId, data.Team, env, data.Message)
+	case db.EvtDeleteEnvironmentTeamLock:
+		return withReason(fmt.Sprintf("Deleted team lock %s for team %s on environment %s", data.LockId, data.Team, env), data.Reason)
+	case db.EvtCreateManifestLock:
+		return fmt.Sprintf("Created manifest lock for application %s on environment %s with message %q", app, env, data.Message)
+	case db.EvtDeleteManifestLock:
+		return fmt.Sprintf("Deleted manifest lock for application %s on environment %s", app, env)
+	case db.EvtCreateEnvironment:
+		return fmt.Sprintf("Created or updated environment %s", env)
+	case db.EvtRenderEnvironment:
+		return fmt.Sprintf("Rendered environment %s", env)
+	case db.EvtDeleteEnvironment:
+		return fmt.Sprintf("Deleted environment %s", env)
+	case db.EvtExtendAAEnvironment:
+		return fmt.Sprintf("Added a concrete environment to the active-active environment %s", env)
+	case db.EvtDeleteAAEnvironmentConfig:
+		return fmt.Sprintf("Deleted the concrete environment %s of the active-active environment %s", data.ConcreteEnvName, env)
+	case db.EvtReleaseTrain:
+		return withTeam(fmt.Sprintf("Started a release train to %s", data.Target), data.Team)
+	case db.EvtEnvReleaseTrain:
+		target := env
+		if data.Parent != nil {
+			target = data.Parent.Target
+		}
+		return fmt.Sprintf("Ran the release train to %s on environment %s", target, env)
+	case db.EvtMigrationTransformer:
+		return "Migrated the manifest repository"
+	case db.EvtSkippedServices:
+		if data.Message != "" {
+			return "Skipped a service: " + data.Message
+		}
+		return "Skipped services: " + strings.Join(data.Messages, ", ")
+	case db.EvtCreateEnvironmentFreeze:
+		return fmt.Sprintf("Created freeze %s on environment %s", data.Freeze.Id, env)
+	case db.EvtDeleteEnvironmentFreeze:
+		return fmt.Sprintf("Deleted freeze %s on environment %s", data.FreezeId, env)
+	case db.EvtCreateEnvironmentGroupFreeze:
+		return fmt.Sprintf("Created freeze %s on environment group %s", data.Freeze.Id, group)
+	case db.EvtDeleteEnvironmentGroupFreeze:
+		return fmt.Sprintf("Deleted freeze %s on environment group %s", data.FreezeId, group)
+	case db.EvtCreateDeploymentApproval:
+		if data.Request.Deployment != nil {
+			return fmt.Sprintf("Requested approval %s to deploy version %d of application %s to environment %s", data.ApprovalId, data.Request.Deployment.Version, app, env)
+		}
+		if data.Request.ReleaseTrain != nil {
+			return fmt.Sprintf("Requested approval %s for a release train to %s", data.ApprovalId, data.Request.ReleaseTrain.Target)
+		}
+		return fmt.Sprintf("Requested approval %s", data.ApprovalId)
+	case db.EvtApproveDeployment:
+		return fmt.Sprintf("Approved %s", data.ApprovalId)
+	case db.EvtRejectDeployment:
+		return withReason(fmt.Sprintf("Rejected %s", data.ApprovalId), data.Reason)
+	case db.EvtPromoteAAWave:
+		return fmt.Sprintf("Promoted version %d of application %s to %s of environment %s", data.Version, app, data.ConcreteEnvironment, env)
+	case db.EvtCancelQueuedVersion:
+		return fmt.Sprintf("Cancelled the queued version %d of application %s on environment %s", data.Version, app, env)
+	case db.EvtDeployQueuedVersion:
+		return withReason(fmt.Sprintf("Deployed the queued version %d of application %s to environment %s", data.Version, app, env), data.Reason)
+	case db.EvtSetReleaseRetentionPolicy:
+		return fmt.Sprintf("Set the release retention policy of %s %s", data.Scope, data.Name)
+	case db.EvtDeleteReleaseRetentionPolicy:
+		return fmt.Sprintf("Deleted the release retention policy of %s %s", data.Scope, data.Name)
+	default:
+		return string(eventType)
+	}
+}
+
+func withReason(summary, reason string) string {
+	if reason == "" {
+		return summary
+	}
+	return summary + ": " + reason
+}
+
+func withTeam(summary, team string) string {
+	if team == "" {
+		return summary
+	}
+	return summary + " for team " + team
+}
@@ -0,0 +1,204 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
+	"github.com/freiheit-com/kuberpult/pkg/db"
+)
+
+func TestTransformEslEventToAuditEntry(t *testing.T) {
+	created := time.Date(2026, 10, 17, 9, 12, 45, 0, time.UTC)
+	tcs := []struct {
+		Name                 string
+		EventType            db.EventType
+		Json                 string
+		ExpectedEntry        *api.AuditLogEntry
+		ExpectedEnvironments []string
+	}{
+		{
+			Name:      "deployment",
+			EventType: db.EvtDeployApplicationVersion,
+			Json:      `{"env":"production","app":"payments","version":12,"metadata":{"authorEmail":"alice@example.com","authorName":"alice"}}`,
+			ExpectedEntry: &api.AuditLogEntry{
+				EventType:   string(db.EvtDeployApplicationVersion),
+				AuthorName:  "alice",
+				AuthorEmail: "alice@example.com",
+				Application: "payments",
+				Environment: "production",
+				Summary:     "Deployed version 12 of application payments to environment production",
+			},
+			ExpectedEnvironments: []string{"production"},
+		},
+		{
+			Name:      "team lock",
+			EventType: db.EvtCreateEnvironmentTeamLock,
+			Json:      `{"env":"staging","team":"payments-team","lockId":"l1","message":"incident","metadata":{"authorEmail":"bob@example.com","authorName":"bob"}}`,
+			ExpectedEntry: &api.AuditLogEntry{
+				EventType:   string(db.EvtCreateEnvironmentTeamLock),
+				AuthorName:  "bob",
+				AuthorEmail: "bob@example.com",
+				Environment: "staging",
+				Team:        "payments-team",
+				Summary:     `Created team lock l1 for team payments-team on environment staging with message "incident"`,
+			},
+			ExpectedEnvironments: []string{"staging"},
+		},
+		{
+			Name:      "environment group lock stores the group as env",
+			EventType: db.EvtCreateEnvironmentGroupLock,
+			Json:      `{"env":"prod-group","lockId":"l2","message":"release freeze","metadata":{}}`,
+			ExpectedEntry: &api.AuditLogEntry{
+				EventType:        string(db.EvtCreateEnvironmentGroupLock),
+				EnvironmentGroup: "prod-group",
+				Summary:          `Created environment group lock l2 on environment group prod-group with message "release freeze"`,
+			},
+			ExpectedEnvironments: nil,
+		},
+		{
+			Name:      "cleanup without json tags",
+			EventType: db.EvtCleanupOldApplicationVersions,
+			Json:      `{"Application":"payments","metadata":{}}`,
+			ExpectedEntry: &api.AuditLogEntry{
+				EventType:   string(db.EvtCleanupOldApplicationVersions),
+				Application: "payments",
+				Summary:     "Cleaned up old versions of application payments",
+			},
+			ExpectedEnvironments: nil,
+		},
+		{
+			Name:      "deployment approval",
+			EventType: db.EvtCreateDeploymentApproval,
+			Json:      `{"approvalId":"a1","environments":["production"],"request":{"deployment":{"env":"production","app":"payments","version":3}},"metadata":{}}`,
+			ExpectedEntry: &api.AuditLogEntry{
+				EventType:   string(db.EvtCreateDeploymentApproval),
+				Application: "payments",
+				Environment: "production",
+				Summary:     "Requested approval a1 to deploy version 3 of application payments to environment production",
+			},
+			ExpectedEnvironments: []string{"production"},
+		},
+		{
+			Name:      "retention policy of a team",
+			EventType: db.EvtSetReleaseRetentionPolicy,
+			Json:      `{"scope":"team","name":"payments-team","keepLast":5,"metadata":{}}`,
+			ExpectedEntry: &api.AuditLogEntry{
+				EventType: string(db.EvtSetReleaseRetentionPolicy),
+				Team:      "payments-team",
+				Summary:   "Set the release retention policy of team payments-team",
+			},
+			ExpectedEnvironments: nil,
+		},
+		{
+			Name:      "invalid json",
+			EventType: db.EvtDeleteEnvironment,
+			Json:      `not json`,
+			ExpectedEntry: &api.AuditLogEntry{
+				EventType: string(db.EvtDeleteEnvironment),
+				Summary:   string(db.EvtDeleteEnvironment),
+			},
+			ExpectedEnvironments: nil,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			entry, environments := transformEslEventToAuditEntry(&db.EslEventRow{
+				EslVersion: 7,
+				Created:    created,
+				EventType:  tc.EventType,
+				EventJson:  tc.Json,
+				TraceId:    nil,
+				SpanId:     nil,
+			})
+			tc.ExpectedEntry.EslVersion = 7
+			tc.ExpectedEntry.CreatedAt = timestamppb.New(created)
+			tc.ExpectedEntry.Json = tc.Json
+			if diff := cmp.Diff(tc.ExpectedEntry, entry, protocmp.Transform()); diff != "" {
+				t.Errorf("entry mismatch (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.ExpectedEnvironments, environments); diff != "" {
+				t.Errorf("environments mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAuditFilter(t *testing.T) {
+	from := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	tcs := []struct {
+		Name           string
+		Request        *api.GetAuditLogRequest
+		ExpectedFilter db.EslEventFilter
+		ExpectedError  string
+	}{
+		{
+			Name: "all filters",
+			Request: &api.GetAuditLogRequest{
+				From:       timestamppb.New(from),
+				To:         timestamppb.New(to),
+				EventTypes: []string{string(db.EvtDeployApplicationVersion)},
+				Author:     "alice@example.com",
+			},
+			ExpectedFilter: db.EslEventFilter{
+				From:       &from,
+				To:         &to,
+				EventTypes: []db.EventType{db.EvtDeployApplicationVersion},
+				Author:     "alice@example.com",
+			},
+		},
+		{
+			Name: "unknown event type",
+			Request: &api.GetAuditLogRequest{
+				EventTypes: []string{"Deploy"},
+			},
+			ExpectedError: "unknown event type 'Deploy'",
+		},
+		{
+			Name: "from after to",
+			Request: &api.GetAuditLogRequest{
+				From: timestamppb.New(to),
+				To:   timestamppb.New(from),
+			},
+			ExpectedError: "from must not be after to",
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			filter, err := auditFilter(tc.Request)
+			if tc.ExpectedError != "" {
+				if err == nil || err.Error() != tc.ExpectedError {
+					t.Fatalf("expected error %q, got %v", tc.ExpectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.ExpectedFilter, filter); diff != "" {
+				t.Errorf("filter mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -18,8 +18,11 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"strings"
 	"sync"
+	"time"
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
@@ -33,8 +36,12 @@ import (
 	"github.com/freiheit-com/kuberpult/pkg/db"
 	"github.com/freiheit-com/kuberpult/pkg/grpc"
 	"github.com/freiheit-com/kuberpult/pkg/logging"
+	"github.com/freiheit-com/kuberpult/pkg/signing"
 	"github.com/freiheit-com/kuberpult/pkg/types"
+	"github.com/freiheit-com/kuberpult/pkg/uuid"
 	"github.com/freiheit-com/kuberpult/pkg/valid"
+	"github.com/freiheit-com/kuberpult/services/cd-service/pkg/gates"
+	"github.com/freiheit-com/kuberpult/services/cd-service/pkg/policy"
 	"github.com/freiheit-com/kuberpult/services/cd-service/pkg/repository"
 )
 
@@ -68,6 +75,9 @@ type BatchServerConfig struct {
 	WriteCommitData      bool
 	AllowedCILinkDomains []string //Transformers that create releases or deploy them can only accept CI links from these domains
 	LockType             LockType
+	Policies             *policy.Engine // nil if no admission policies are configured
+	// PersistArgoEvents is true if the rollout-service writes the Argo CD events to the database, see validateWaves
+	PersistArgoEvents bool
 }
 
 type BatchServer struct {
@@ -122,6 +132,66 @@ func ValidateEnvironmentTeamLock(
 	return nil
 }
 
+func ValidateEnvironmentFreeze(
+	actionType string, // "create" | "delete"
+	env types.EnvName,
+	freezeId string,
+) error {
+	if !valid.EnvironmentName(env) {
+		return status.Error(codes.InvalidArgument, fmt.Sprintf("cannot %s environment freeze: invalid environment: '%s'", actionType, env))
+	}
+	return validateFreezeId(actionType, freezeId)
+}
+
+func ValidateDeploymentApproval(actionType string, approvalId string) error {
+	if approvalId == "" {
+		return status.Error(codes.InvalidArgument, fmt.Sprintf("cannot %s deployment: approval id must not be empty", actionType))
+	}
+	return nil
+}
+
+func releaseRetentionScopeFromApi(scope api.ReleaseRetentionScope, name string) (db.ReleaseRetentionScope, error) {
+	switch scope {
+	case api.ReleaseRetentionScope_RELEASE_RETENTION_SCOPE_APPLICATION:
+		if !valid.ApplicationName(types.AppName(name)) {
+			return "", status.Error(codes.InvalidArgument, fmt.Sprintf("invalid release retention policy: invalid application: '%s'", name))
+		}
+		return db.ReleaseRetentionScopeApplication, nil
+	case api.ReleaseRetentionScope_RELEASE_RETENTION_SCOPE_TEAM:
+		if name == "" {
+			return "", status.Error(codes.InvalidArgument, "invalid release retention policy: team must not be empty")
+		}
+		return db.ReleaseRetentionScopeTeam, nil
+	default:
+		return "", status.Error(codes.InvalidArgument, fmt.Sprintf("invalid release retention policy: unknown scope %s", scope))
+	}
+}
+
+func validateFreezeId(actionType string, freezeId string) error {
+	// freeze ids follow the same rules as lock ids
+	if !valid.LockId(freezeId) {
+		return status.Error(codes.InvalidArgument, fmt.Sprintf("cannot %s environment freeze: invalid freeze id: '%s'", actionType, freezeId))
+	}
+	return nil
+}
+
+func validateFreezes(freezes []config.EnvironmentFreeze) error {
+	knownIds := make(map[string]struct{})
+	for _, freeze := range freezes {
+		if err := validateFreezeId("create", freeze.Id); err != nil {
+			return err
+		}
+		if err := freeze.Validate(); err != nil {
+			return status.Error(codes.InvalidArgument, fmt.Sprintf("invalid environment freeze: %v", err))
+		}
+		if _, exists := knownIds[freeze.Id]; exists {
+			return status.Error(codes.InvalidArgument, fmt.Sprintf("freeze ids must be unique: '%s'", freeze.Id))
+		}
+		knownIds[freeze.Id] = struct{}{}
+	}
+	return nil
+}
+
 func ValidateDeployment(
 	env types.EnvName,
 	app types.AppName,
@@ -189,6 +259,54 @@ func ValidateEnvironment(
 			return status.Error(codes.InvalidArgument, fmt.Sprintf("one or more invalid environment names were provided: %v", invalidNames))
 		}
 	}
+	if upstream := environmentConfig.Upstream; upstream != nil && upstream.SoakTime != "" {
+		if upstream.Latest {
+			return status.Error(codes.InvalidArgument, "a soak time can only be specified for an upstream environment, not for latest")
+		}
+		if _, err := upstream.SoakDuration(); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	argoCdConfigs := []*config.EnvironmentConfigArgoCd{environmentConfig.ArgoCd}
+	if environmentConfig.ArgoCdConfigs != nil {
+		argoCdConfigs = environmentConfig.ArgoCdConfigs.ArgoCdConfigurations
+	}
+	for _, argoCdConfig := range argoCdConfigs {
+		if argoCdConfig == nil || argoCdConfig.FluxCd == nil {
+			continue
+		}
+		if _, err := argoCdConfig.FluxCd.IntervalDuration(); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	if err := signing.ValidatePolicy(environmentConfig.SignaturePolicy); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := gates.Validate(environmentConfig.DeploymentGates); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return validateFreezes(environmentConfig.Freezes)
+}
+
+// validateWaves checks that the waves of an active/active environment can make progress:
+// The next concrete environment is deployed once the previous one is healthy, which only the rollout-service
+// knows if it persists the Argo CD events. Flux CD does not report the health at all, so it needs a delay.
+func validateWaves(environmentConfig config.EnvironmentConfig, persistArgoEvents bool) error {
+	if environmentConfig.ArgoCdConfigs == nil || environmentConfig.ArgoCdConfigs.Waves == nil {
+		return nil
+	}
+	delay, err := environmentConfig.ArgoCdConfigs.Waves.DelayDuration()
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if !persistArgoEvents {
+		return status.Error(codes.InvalidArgument, "waves need the health of the concrete environments: enable rollout.persistArgoEvents")
+	}
+	for _, argoCdConfig := range environmentConfig.ArgoCdConfigs.ArgoCdConfigurations {
+		if argoCdConfig != nil && argoCdConfig.FluxCd != nil && delay == 0 {
+			return status.Error(codes.InvalidArgument, fmt.Sprintf("waves need a delay, because flux does not report the health of the concrete environment '%s'", argoCdConfig.ConcreteEnvName))
+		}
+	}
 	return nil
 }
 
@@ -219,6 +337,7 @@ func (d *BatchServer) processAction(
 		return &repository.DeleteEnvironmentLock{
 			Environment:           types.EnvName(act.Environment),
 			LockId:                act.LockId,
+			Reason:                "",
 			Authentication:        repository.Authentication{RBACConfig: d.RBACConfig},
 			TransformerEslVersion: 0,
 		}, nil, nil
@@ -247,6 +366,7 @@ func (d *BatchServer) processAction(
 			Environment:           types.EnvName(act.Environment),
 			Application:           types.AppName(act.Application),
 			LockId:                act.LockId,
+			Reason:                "",
 			Authentication:        repository.Authentication{RBACConfig: d.RBACConfig},
 			TransformerEslVersion: 0,
 		}, nil, nil
@@ -275,6 +395,7 @@ func (d *BatchServer) processAction(
 			Environment:           types.EnvName(act.Environment),
 			Team:                  act.Team,
 			LockId:                act.LockId,
+			Reason:                "",
 			Authentication:        repository.Authentication{RBACConfig: d.RBACConfig},
 			TransformerEslVersion: 0,
 		}, nil, nil
@@ -326,8 +447,16 @@ func (d *BatchServer) processAction(
 			// in that case, we still want to ignore locks (for emergency deployments)
 			b = api.LockBehavior_IGNORE
 		}
+		var autoRollback *repository.DeployApplicationVersionRollback
+		if act.AutoRollback != nil {
+			autoRollback = &repository.DeployApplicationVersionRollback{
+				RolledBackVersion: act.AutoRollback.RolledBackVersion,
+				Reason:            act.AutoRollback.Reason,
+			}
+		}
 		return &repository.DeployApplicationVersion{
 			SourceTrain:           nil,
+			AutoRollback:          autoRollback,
 			Environment:           types.EnvName(act.Environment),
 			Application:           types.AppName(act.Application),
 			Version:               act.Version,
@@ -396,6 +525,8 @@ func (d *BatchServer) processAction(
 				DeployToDownstreamEnvironments: downstreamEnvs,
 				Revision:                       in.Revision,
 				ArgoBracket:                    types.ArgoBracketName(in.ArgoBracket),
+				Dependencies:                   transformReleaseDependencies(in.Dependencies),
+				Signatures:                     transformReleaseSignatures(in.Signatures),
 			}, &api.BatchResult{
 				Result: &api.BatchResult_CreateReleaseResponse{
 					CreateReleaseResponse: &api.CreateReleaseResponse{
@@ -422,15 +553,25 @@ func (d *BatchServer) processAction(
 		}
 		upstream := transformUpstreamToConfig(conf.Upstream)
 		internalEnvironmentConfig := config.EnvironmentConfig{
-			Upstream:         upstream,
-			ArgoCd:           argocd,
-			EnvironmentGroup: conf.EnvironmentGroup,
-			ArgoCdConfigs:    configs,
-			IsActiveActive:   conf.IsActiveActive,
+			Upstream:           upstream,
+			ArgoCd:             argocd,
+			EnvironmentGroup:   conf.EnvironmentGroup,
+			ArgoCdConfigs:      configs,
+			IsActiveActive:     conf.IsActiveActive,
+			Freezes:            transformFreezesToConfig(conf.Freezes),
+			ManifestValidation: transformManifestValidationToConfig(conf.ManifestValidation),
+			SignaturePolicy:    transformSignaturePolicyToConfig(conf.SignaturePolicy),
+			DeploymentGates:    transformDeploymentGatesToConfig(conf.DeploymentGates),
 		}
 		if err := ValidateEnvironment(types.EnvName(in.Environment), internalEnvironmentConfig); err != nil {
 			return nil, nil, status.Error(codes.InvalidArgument, fmt.Sprintf("processAction: invalid environment. err: %v", err))
 		}
+		if err := validateWaves(internalEnvironmentConfig, d.Config.PersistArgoEvents); err != nil {
+			return nil, nil, status.Error(codes.InvalidArgument, fmt.Sprintf("processAction: invalid environment. err: %v", err))
+		}
+		if err := d.Repository.State().ManifestValidation.ValidateConfig(internalEnvironmentConfig.ManifestValidation); err != nil {
+			return nil, nil, status.Error(codes.InvalidArgument, fmt.Sprintf("processAction: invalid environment. err: %v", err))
+		}
 		transformer := &repository.CreateEnvironment{
 			Environment:           types.EnvName(in.Environment),
 			Config:                internalEnvironmentConfig,
@@ -459,6 +600,141 @@ func (d *BatchServer) processAction(
 			Authentication:        repository.Authentication{RBACConfig: d.RBACConfig},
 			TransformerEslVersion: 0,
 		}, nil, nil
+	case *api.BatchAction_CreateEnvironmentFreeze:
+		act := action.CreateEnvironmentFreeze
+		if act.Freeze == nil {
+			return nil, nil, status.Error(codes.InvalidArgument, "cannot create environment freeze: freeze must be provided")
+		}
+		freeze := transformFreezeToConfig(act.Freeze)
+		if err := ValidateEnvironmentFreeze("create", types.EnvName(act.Environment), freeze.Id); err != nil {
+			return nil, nil, err
+		}
+		if err := validateFreezes([]config.EnvironmentFreeze{freeze}); err != nil {
+			return nil, nil, err
+		}
+		return &repository.CreateEnvironmentFreeze{
+			Environment:           types.EnvName(act.Environment),
+			Freeze:                freeze,
+			Authentication:        repository.Authentication{RBACConfig: d.RBACConfig},
+			TransformerEslVersion: 0,
+		}, nil, nil
+	case *api.BatchAction_DeleteEnvironmentFreeze:
+		act := action.DeleteEnvironmentFreeze
+		if err := ValidateEnvironmentFreeze("delete", types.EnvName(act.Environment), act.FreezeId); err != nil {
+			return nil, nil, err
+		}
+		return &repository.DeleteEnvironmentFreeze{
+			Environment:           types.EnvName(act.Environment),
+			FreezeId:              act.FreezeId,
+			Authentication:        repository.Authentication{RBACConfig: d.RBACConfig},
+			TransformerEslVersion: 0,
+		}, nil, nil
+	case *api.BatchAction_CreateEnvironmentGroupFreeze:
+		act := action.CreateEnvironmentGroupFreeze
+		if act.Freeze == nil {
+			return nil, nil, status.Error(codes.InvalidArgument, "cannot create environment freeze: freeze must be provided")
+		}
+		freeze := transformFreezeToConfig(act.Freeze)
+		if err := validateFreezes([]config.EnvironmentFreeze{freeze}); err != nil {
+			return nil, nil, err
+		}
+		return &repository.CreateEnvironmentGroupFreeze{
+			EnvironmentGroup:      act.EnvironmentGroup,
+			Freeze:                freeze,
+			Authentication:        repository.Authentication{RBACConfig: d.RBACConfig},
+			TransformerEslVersion: 0,
+		}, nil, nil
+	case *api.BatchAction_DeleteEnvironmentGroupFreeze:
+		act := action.DeleteEnvironmentGroupFreeze
+		if err := validateFreezeId("delete", act.FreezeId); err != nil {
+			return nil, nil, err
+		}
+		return &repository.DeleteEnvironmentGroupFreeze{
+			EnvironmentGroup:      act.EnvironmentGroup,
+			FreezeId:              act.FreezeId,
+			Authentication:        repository.Authentication{RBACConfig: d.RBACConfig},
+			TransformerEslVersion: 0,
+		}, nil, nil
+	case *api.BatchAction_ApproveDeployment:
+		act := action.ApproveDeployment
+		if err := ValidateDeploymentApproval("approve", act.ApprovalId); err != nil {
+			return nil, nil, err
+		}
+		return &repository.ApproveDeployment{
+			ApprovalId:            act.ApprovalId,
+			WriteCommitData:       d.Config.WriteCommitData,
+			Repo:                  d.Repository,
+			AllowedDomains:        d.Config.AllowedCILinkDomains,
+			Authentication:        repository.Authentication{RBACConfig: d.RBACConfig},
+			TransformerEslVersion: 0,
+		}, nil, nil
+	case *api.BatchAction_RejectDeployment:
+		act := action.RejectDeployment
+		if err := ValidateDeploymentApproval("reject", act.ApprovalId); err != nil {
+			return nil, nil, err
+		}
+		return &repository.RejectDeployment{
+			ApprovalId:            act.ApprovalId,
+			Reason:                act.Reason,
+			Authentication:        repository.Authentication{RBACConfig: d.RBACConfig},
+			TransformerEslVersion: 0,
+		}, nil, nil
+	case *api.BatchAction_CancelQueuedVersion:
+		act := action.CancelQueuedVersion
+		if err := ValidateDeployment(types.EnvName(act.Environment), types.AppName(act.Application)); err != nil {
+			return nil, nil, err
+		}
+		return &repository.CancelQueuedVersion{
+			Environment:           types.EnvName(act.Environment),
+			Application:           types.AppName(act.Application),
+			Version:               act.Version,
+			Authentication:        repository.Authentication{RBACConfig: d.RBACConfig},
+			TransformerEslVersion: 0,
+		}, nil, nil
+	case *api.BatchAction_DeployQueuedVersion:
+		act := action.DeployQueuedVersion
+		if err := ValidateDeployment(types.EnvName(act.Environment), types.AppName(act.Application)); err != nil {
+			return nil, nil, err
+		}
+		if act.Reason == "" {
+			return nil, nil, status.Error(codes.InvalidArgument, "cannot deploy queued version: reason must not be empty")
+		}
+		return &repository.DeployQueuedVersion{
+			Environment:           types.EnvName(act.Environment),
+			Application:           types.AppName(act.Application),
+			Version:               act.Version,
+			Reason:                act.Reason,
+			WriteCommitData:       d.Config.WriteCommitData,
+			Authentication:        repository.Authentication{RBACConfig: d.RBACConfig},
+			TransformerEslVersion: 0,
+		}, nil, nil
+	case *api.BatchAction_SetReleaseRetentionPolicy:
+		act := action.SetReleaseRetentionPolicy
+		scope, err := releaseRetentionScopeFromApi(act.Scope, act.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &repository.SetReleaseRetentionPolicy{
+			Scope:                 scope,
+			Name:                  act.Name,
+			KeepLast:              act.KeepLast,
+			KeepDays:              act.KeepDays,
+			KeepProdDeployed:      act.KeepProdDeployed,
+			Authentication:        repository.Authentication{RBACConfig: d.RBACConfig},
+			TransformerEslVersion: 0,
+		}, nil, nil
+	case *api.BatchAction_DeleteReleaseRetentionPolicy:
+		act := action.DeleteReleaseRetentionPolicy
+		scope, err := releaseRetentionScopeFromApi(act.Scope, act.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &repository.DeleteReleaseRetentionPolicy{
+			Scope:                 scope,
+			Name:                  act.Name,
+			Authentication:        repository.Authentication{RBACConfig: d.RBACConfig},
+			TransformerEslVersion: 0,
+		}, nil, nil
 	case *api.BatchAction_DeleteEnvironment:
 		act := action.DeleteEnvironment
 		return &repository.DeleteEnvironment{
@@ -495,6 +771,35 @@ func (d *BatchServer) processAction(
 	return nil, nil, status.Error(codes.InvalidArgument, "processAction: cannot process action: invalid action type")
 }
 
+func transformReleaseDependencies(dependencies []*api.ReleaseDependency) []db.ReleaseDependency {
+	if len(dependencies) == 0 {
+		return nil
+	}
+	result := make([]db.ReleaseDependency, 0, len(dependencies))
+	for _, dependency := range dependencies {
+		result = append(result, db.ReleaseDependency{
+			Application: types.AppName(dependency.Application),
+			MinVersion:  dependency.MinVersion,
+		})
+	}
+	return result
+}
+
+func transformReleaseSignatures(signatures []*api.ReleaseSignature) []db.ReleaseSignature {
+	if len(signatures) == 0 {
+		return nil
+	}
+	result := make([]db.ReleaseSignature, 0, len(signatures))
+	for _, signature := range signatures {
+		result = append(result, db.ReleaseSignature{
+			Environment: types.EnvName(signature.Environment),
+			Type:        signature.Type,
+			Signature:   signature.Signature,
+		})
+	}
+	return result
+}
+
 var isolatedTransformersLock sync.RWMutex
 var isolatedTransformerNames = []db.EventType{db.EvtUndeployApplication, db.EvtDeleteEnvFromApp, db.EvtDeleteEnvironment}
 
@@ -543,6 +848,19 @@ func (d *BatchServer) ProcessBatch(
 		results = append(results, result)
 	}
 
+	policyWarnings, err := d.checkPolicies(ctx, *user, in.GetActions())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.addReleaseNotes(ctx, in.GetActions(), transformers, results); err != nil {
+		return nil, err
+	}
+
+	if err := d.requireDeploymentApprovals(ctx, transformers, results); err != nil {
+		return nil, err
+	}
+
 	// Add information about the transformer types for the root-level span of ProcessBatch
 	if len(transformers) > 0 && parentSpanExisted {
 		var transformerTag string
@@ -593,7 +911,133 @@ func (d *BatchServer) ProcessBatch(
 		}
 		return nil, err
 	}
-	return &api.BatchResponse{Results: results}, nil
+	return &api.BatchResponse{Results: results, PolicyWarnings: policyWarnings}, nil
+}
+
+// checkPolicies evaluates the admission policies for new releases, deployments and release trains.
+// The whole batch is rejected if a policy denies one of its actions.
+func (d *BatchServer) checkPolicies(ctx context.Context, user auth.User, actions []*api.BatchAction) ([]*api.PolicyWarning, error) {
+	engine := d.Config.Policies
+	if engine == nil {
+		return nil, nil
+	}
+	state := d.Repository.State()
+	now := time.Now().UTC()
+	inputs, err := db.WithTransactionMultipleEntriesT(state.DBHandler, ctx, true, func(ctx context.Context, transaction *sql.Tx) ([]*policy.Input, error) {
+		inputs := make([]*policy.Input, 0, len(actions))
+		for _, action := range actions {
+			input, err := policy.NewInput(ctx, state, transaction, action, user, now)
+			if err != nil {
+				return nil, err
+			}
+			inputs = append(inputs, input)
+		}
+		return inputs, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not read the state for the admission policies: %w", err)
+	}
+	warnings := []*api.PolicyWarning{}
+	for i, input := range inputs {
+		if input == nil {
+			continue
+		}
+		result, err := engine.Evaluate(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		if len(result.Deny) > 0 {
+			return nil, status.Error(codes.PermissionDenied, fmt.Sprintf("%s denied by policy: %s", input.Action, strings.Join(result.Deny, "; ")))
+		}
+		for _, message := range result.Warn {
+			logging.Warn(ctx, "policy warning", zap.String("action", input.Action), zap.String("message", message))
+			warnings = append(warnings, &api.PolicyWarning{
+				ActionIndex: uint32(i),
+				Message:     message,
+			})
+		}
+	}
+	return warnings, nil
+}
+
+// requireDeploymentApprovals replaces deployments and release trains to environments that require an approval
+// with pending approvals. They are executed later, once another user approves them.
+// The release notes of a release train are kept in the approval result.
+func (d *BatchServer) requireDeploymentApprovals(ctx context.Context, transformers []repository.Transformer, results []*api.BatchResult) error {
+	state := d.Repository.State()
+	approvalConfig := state.DeploymentApproval
+	if !approvalConfig.Enabled {
+		return nil
+	}
+	configs, err := db.WithTransactionT(state.DBHandler, ctx, db.DefaultNumRetries, true, func(ctx context.Context, transaction *sql.Tx) (*map[types.EnvName]config.EnvironmentConfig, error) {
+		configs, err := state.GetAllEnvironmentConfigs(ctx, transaction)
+		return &configs, err
+	})
+	if err != nil {
+		return err
+	}
+	for i, transformer := range transformers {
+		envs := approvalConfig.EnvironmentsRequiringApproval(*configs, transformer)
+		if len(envs) == 0 {
+			continue
+		}
+		request, err := repository.NewDeploymentApprovalRequest(transformer)
+		if err != nil {
+			return err
+		}
+		approvalId := uuid.RealUUIDGenerator{}.Generate()
+		transformers[i] = &repository.CreateDeploymentApproval{
+			ApprovalId:            approvalId,
+			Environments:          envs,
+			Request:               request,
+			Expiry:                approvalConfig.Expiry,
+			Authentication:        repository.Authentication{RBACConfig: d.RBACConfig},
+			TransformerEslVersion: 0,
+		}
+		results[i] = &api.BatchResult{
+			Result: &api.BatchResult_DeploymentApproval{
+				DeploymentApproval: &api.DeploymentApprovalResponse{
+					ApprovalId:           approvalId,
+					Environments:         types.EnvNamesToStrings(envs),
+					ReleaseNotes:         results[i].GetReleaseTrain().GetReleaseNotes(),
+					ReleaseNotesMarkdown: results[i].GetReleaseTrain().GetReleaseNotesMarkdown(),
+				},
+			},
+		}
+	}
+	return nil
+}
+
+// addReleaseNotes adds the release notes to the results of release trains that requested them.
+// They are based on the prognosis, so they have to be collected before the release train is applied,
+// and before requireDeploymentApprovals replaces release trains that require an approval.
+func (d *BatchServer) addReleaseNotes(ctx context.Context, actions []*api.BatchAction, transformers []repository.Transformer, results []*api.BatchResult) error {
+	for i, action := range actions {
+		response := results[i].GetReleaseTrain()
+		train, ok := transformers[i].(*repository.ReleaseTrain)
+		if !ok || response == nil || !action.GetReleaseTrain().GetIncludeReleaseNotes() {
+			continue
+		}
+		state := d.Repository.State()
+		const readOnly = false // the prognosis requires write access for a temporary table, see GetReleaseTrainPrognosis
+		notes, err := db.WithTransactionMultipleEntriesT(state.DBHandler, ctx, readOnly, func(ctx context.Context, transaction *sql.Tx) ([]*api.AppReleaseNotes, error) {
+			configs, err := state.GetAllEnvironmentConfigs(ctx, transaction)
+			if err != nil {
+				return nil, err
+			}
+			prognosis := train.Prognosis(ctx, state, transaction, configs)
+			if prognosis.Error != nil {
+				return nil, prognosis.Error
+			}
+			return ReleaseTrainReleaseNotes(ctx, state, transaction, prognosis)
+		})
+		if err != nil {
+			return err
+		}
+		response.ReleaseNotes = notes
+		response.ReleaseNotesMarkdown = RenderReleaseNotesMarkdown(notes)
+	}
+	return nil
 }
 
 func (d *BatchServer) handleError(applyErr *repository.TransformerBatchApplyError, err error) (*api.BatchResponse, error) {
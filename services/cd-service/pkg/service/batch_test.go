@@ -23,6 +23,7 @@ import (
 	"os/exec"
 	"path"
 	"testing"
+	"time"
 
 	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
 	"github.com/freiheit-com/kuberpult/pkg/auth"
@@ -649,10 +650,10 @@ func TestBatchServiceLimit(t *testing.T) {
 }
 
 func setupRepositoryTestWithDB(t *testing.T) (repository.Repository, error) {
-	return setupRepositoryTestWithAllOptions(t)
+	return setupRepositoryTestWithAllOptions(t, repository.DeploymentApprovalConfig{})
 }
 
-func setupRepositoryTestWithAllOptions(t *testing.T) (repository.Repository, error) {
+func setupRepositoryTestWithAllOptions(t *testing.T, deploymentApproval repository.DeploymentApprovalConfig) (repository.Repository, error) {
 	ctx := context.Background()
 	migrationsPath, err := db.CreateMigrationsPath(4)
 	if err != nil {
@@ -681,6 +682,7 @@ func setupRepositoryTestWithAllOptions(t *testing.T) (repository.Repository, err
 		URL:                 remoteDir,
 		ArgoCdGenerateFiles: true,
 		AllowBracketMoves:   true,
+		DeploymentApproval:  deploymentApproval,
 	}
 	if dbConfig != nil {
 
@@ -831,6 +833,82 @@ func TestReleaseTrain(t *testing.T) {
 	}
 }
 
+func TestReleaseTrainReleaseNotesWithApproval(t *testing.T) {
+	repo, err := setupRepositoryTestWithAllOptions(t, repository.DeploymentApprovalConfig{
+		Enabled:      true,
+		Environments: []types.EnvName{"production"},
+		Expiry:       time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("error setting up repository test: %v", err)
+	}
+	ctx := testutilauth.MakeTestContext()
+	err = repo.Apply(ctx,
+		&repository.CreateEnvironment{
+			Environment: "acceptance",
+			Config:      config.EnvironmentConfig{Upstream: &config.EnvironmentConfigUpstream{Latest: true}},
+		},
+		&repository.CreateEnvironment{
+			Environment: "production",
+			Config:      config.EnvironmentConfig{Upstream: &config.EnvironmentConfigUpstream{Environment: "acceptance"}},
+		},
+		&repository.CreateApplicationVersion{
+			Application:   "test",
+			Manifests:     map[types.EnvName]string{"acceptance": "v1", "production": "v1"},
+			Version:       1,
+			SourceMessage: "first",
+		},
+		&repository.DeployApplicationVersion{
+			Environment:   "production",
+			Application:   "test",
+			Version:       1,
+			LockBehaviour: api.LockBehavior_FAIL,
+		},
+		&repository.CreateApplicationVersion{
+			Application:   "test",
+			Manifests:     map[types.EnvName]string{"acceptance": "v2", "production": "v2"},
+			Version:       2,
+			SourceMessage: "second",
+		},
+	)
+	if err != nil {
+		t.Fatalf("error applying transformers: %v", err)
+	}
+
+	svc := &BatchServer{
+		Repository: repo,
+	}
+	resp, err := svc.ProcessBatch(ctx, &api.BatchRequest{
+		Actions: []*api.BatchAction{
+			{
+				Action: &api.BatchAction_ReleaseTrain{
+					ReleaseTrain: &api.ReleaseTrainRequest{
+						Target:              "production",
+						IncludeReleaseNotes: true,
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	approval := resp.Results[0].GetDeploymentApproval()
+	if approval == nil {
+		t.Fatalf("expected the release train to require an approval, got: %v", resp.Results[0])
+	}
+	notes := approval.GetReleaseNotes()
+	if len(notes) != 1 || notes[0].Application != "test" || notes[0].Environment != "production" || notes[0].FromVersion != 1 || notes[0].ToVersion != 2 {
+		t.Fatalf("expected the release notes of test on production from version 1 to 2, got: %v", notes)
+	}
+	if len(notes[0].Releases) != 1 || notes[0].Releases[0].SourceMessage != "second" {
+		t.Errorf("expected only version 2 in the release notes, got: %v", notes[0].Releases)
+	}
+	if approval.GetReleaseNotesMarkdown() != RenderReleaseNotesMarkdown(notes) {
+		t.Errorf("expected the markdown of the release notes, got: %q", approval.GetReleaseNotesMarkdown())
+	}
+}
+
 func TestCreateEnvironmentTrain(t *testing.T) {
 	tcs := []struct {
 		Name                 string
@@ -1221,3 +1299,247 @@ func TestActiveActiveEnvironmentNames(t *testing.T) {
 		})
 	}
 }
+
+func TestEnvironmentFreezeValidation(t *testing.T) {
+	weekend := config.EnvironmentFreeze{
+		Id:       "weekend",
+		Message:  "no deployments on the weekend",
+		Schedule: "0 0 * * 6",
+		Duration: "48h",
+		Start:    time.Time{},
+		End:      time.Time{},
+	}
+	tcs := []struct {
+		Name           string
+		InputEnvConfig config.EnvironmentConfig
+		valid          bool
+	}{
+		{
+			Name: "valid freeze",
+			InputEnvConfig: config.EnvironmentConfig{
+				Upstream: nil,
+				ArgoCd:   &config.EnvironmentConfigArgoCd{},
+				Freezes:  []config.EnvironmentFreeze{weekend},
+			},
+			valid: true,
+		},
+		{
+			Name: "invalid, freeze id contains a slash",
+			InputEnvConfig: config.EnvironmentConfig{
+				Upstream: nil,
+				ArgoCd:   &config.EnvironmentConfigArgoCd{},
+				Freezes: []config.EnvironmentFreeze{{
+					Id:       "week/end",
+					Message:  "",
+					Schedule: weekend.Schedule,
+					Duration: weekend.Duration,
+					Start:    time.Time{},
+					End:      time.Time{},
+				}},
+			},
+			valid: false,
+		},
+		{
+			Name: "invalid, freeze ids are not unique",
+			InputEnvConfig: config.EnvironmentConfig{
+				Upstream: nil,
+				ArgoCd:   &config.EnvironmentConfigArgoCd{},
+				Freezes:  []config.EnvironmentFreeze{weekend, weekend},
+			},
+			valid: false,
+		},
+		{
+			Name: "invalid, schedule cannot be parsed",
+			InputEnvConfig: config.EnvironmentConfig{
+				Upstream: nil,
+				ArgoCd:   &config.EnvironmentConfigArgoCd{},
+				Freezes: []config.EnvironmentFreeze{{
+					Id:       "weekend",
+					Message:  "",
+					Schedule: "every saturday",
+					Duration: weekend.Duration,
+					Start:    time.Time{},
+					End:      time.Time{},
+				}},
+			},
+			valid: false,
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			err := ValidateEnvironment("dev", tc.InputEnvConfig)
+
+			isValid := err == nil
+			if isValid != tc.valid {
+				t.Errorf("Invalid environment: %v, %v", tc.InputEnvConfig, err)
+			}
+		})
+	}
+}
+
+func TestEnvironmentFluxCdValidation(t *testing.T) {
+	tcs := []struct {
+		Name   string
+		FluxCd *config.EnvironmentConfigFluxCd
+		valid  bool
+	}{
+		{
+			Name:   "valid without flux",
+			FluxCd: nil,
+			valid:  true,
+		},
+		{
+			Name:   "valid with default interval",
+			FluxCd: &config.EnvironmentConfigFluxCd{},
+			valid:  true,
+		},
+		{
+			Name:   "valid interval",
+			FluxCd: &config.EnvironmentConfigFluxCd{Interval: "1m30s"},
+			valid:  true,
+		},
+		{
+			Name:   "invalid, interval cannot be parsed",
+			FluxCd: &config.EnvironmentConfigFluxCd{Interval: "often"},
+			valid:  false,
+		},
+		{
+			Name:   "invalid, interval is zero",
+			FluxCd: &config.EnvironmentConfigFluxCd{Interval: "0s"},
+			valid:  false,
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			envConfig := config.EnvironmentConfig{
+				ArgoCd: &config.EnvironmentConfigArgoCd{FluxCd: tc.FluxCd},
+			}
+			err := ValidateEnvironment("production", envConfig)
+
+			isValid := err == nil
+			if isValid != tc.valid {
+				t.Errorf("Invalid environment: %v, %v", envConfig, err)
+			}
+		})
+	}
+}
+
+func TestEnvironmentWavesValidation(t *testing.T) {
+	argoCdConfigs := func(waves *config.ArgoCDWaves, fluxCd *config.EnvironmentConfigFluxCd) *config.ArgoCDConfigs {
+		return &config.ArgoCDConfigs{
+			CommonEnvPrefix: conversion.FromString("aa"),
+			ArgoCdConfigurations: []*config.EnvironmentConfigArgoCd{
+				{ConcreteEnvName: "de-1"},
+				{ConcreteEnvName: "de-2", FluxCd: fluxCd},
+			},
+			Waves: waves,
+		}
+	}
+	tcs := []struct {
+		Name              string
+		ArgoCdConfigs     *config.ArgoCDConfigs
+		PersistArgoEvents bool
+		valid             bool
+	}{
+		{
+			Name:              "valid without waves",
+			ArgoCdConfigs:     argoCdConfigs(nil, nil),
+			PersistArgoEvents: false,
+			valid:             true,
+		},
+		{
+			Name:              "valid with persisted argo events",
+			ArgoCdConfigs:     argoCdConfigs(&config.ArgoCDWaves{}, nil),
+			PersistArgoEvents: true,
+			valid:             true,
+		},
+		{
+			Name:              "invalid without persisted argo events",
+			ArgoCdConfigs:     argoCdConfigs(&config.ArgoCDWaves{Delay: "30m"}, nil),
+			PersistArgoEvents: false,
+			valid:             false,
+		},
+		{
+			Name:              "invalid delay",
+			ArgoCdConfigs:     argoCdConfigs(&config.ArgoCDWaves{Delay: "soon"}, nil),
+			PersistArgoEvents: true,
+			valid:             false,
+		},
+		{
+			Name:              "valid flux with delay",
+			ArgoCdConfigs:     argoCdConfigs(&config.ArgoCDWaves{Delay: "30m"}, &config.EnvironmentConfigFluxCd{}),
+			PersistArgoEvents: true,
+			valid:             true,
+		},
+		{
+			Name:              "invalid flux without delay",
+			ArgoCdConfigs:     argoCdConfigs(&config.ArgoCDWaves{}, &config.EnvironmentConfigFluxCd{}),
+			PersistArgoEvents: true,
+			valid:             false,
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			envConfig := config.EnvironmentConfig{
+				ArgoCdConfigs: tc.ArgoCdConfigs,
+			}
+			err := validateWaves(envConfig, tc.PersistArgoEvents)
+
+			isValid := err == nil
+			if isValid != tc.valid {
+				t.Errorf("Invalid environment: %v, %v", envConfig, err)
+			}
+		})
+	}
+}
+
+func TestEnvironmentSoakTimeValidation(t *testing.T) {
+	tcs := []struct {
+		Name     string
+		Upstream *config.EnvironmentConfigUpstream
+		valid    bool
+	}{
+		{
+			Name:     "valid soak time on upstream environment",
+			Upstream: &config.EnvironmentConfigUpstream{Environment: "staging", Latest: false, SoakTime: "2h30m"},
+			valid:    true,
+		},
+		{
+			Name:     "invalid, soak time on latest",
+			Upstream: &config.EnvironmentConfigUpstream{Environment: "", Latest: true, SoakTime: "2h"},
+			valid:    false,
+		},
+		{
+			Name:     "invalid, soak time cannot be parsed",
+			Upstream: &config.EnvironmentConfigUpstream{Environment: "staging", Latest: false, SoakTime: "2 days"},
+			valid:    false,
+		},
+		{
+			Name:     "invalid, negative soak time",
+			Upstream: &config.EnvironmentConfigUpstream{Environment: "staging", Latest: false, SoakTime: "-1h"},
+			valid:    false,
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			envConfig := config.EnvironmentConfig{
+				Upstream: tc.Upstream,
+				ArgoCd:   &config.EnvironmentConfigArgoCd{},
+			}
+			err := ValidateEnvironment("production", envConfig)
+
+			isValid := err == nil
+			if isValid != tc.valid {
+				t.Errorf("Invalid environment: %v, %v", envConfig, err)
+			}
+		})
+	}
+}
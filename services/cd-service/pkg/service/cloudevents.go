@@ -0,0 +1,187 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
+	"github.com/freiheit-com/kuberpult/pkg/cloudevents"
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/event"
+	"github.com/freiheit-com/kuberpult/pkg/grpc"
+	"github.com/freiheit-com/kuberpult/pkg/logger"
+	"github.com/freiheit-com/kuberpult/services/cd-service/pkg/webhooks"
+)
+
+const (
+	cloudEventsPollInterval = time.Second
+	cloudEventsBatchSize    = 500
+)
+
+// CloudEventServer streams the commit events as CloudEvents.
+// The stream polls the commit events table, so that it also contains the events of the rollout-service.
+type CloudEventServer struct {
+	DBHandler *db.DBHandler
+	Shutdown  <-chan struct{}
+}
+
+func (s *CloudEventServer) StreamCloudEvents(in *api.StreamCloudEventsRequest, stream api.CloudEventService_StreamCloudEventsServer) error {
+	ctx := stream.Context()
+	for _, t := range in.Types {
+		if !isCloudEventType(t) {
+			return grpc.InvalidArgument(ctx, fmt.Errorf("unknown CloudEvent type %q", t))
+		}
+	}
+	cursor, err := s.startCursor(ctx, in.LastEventId)
+	if err != nil {
+		return err
+	}
+	// the header tells clients that the stream started, the first event may take a while
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+	for {
+		events, more, err := s.nextCloudEvents(ctx, cursor)
+		if err != nil {
+			return err
+		}
+		for _, ev := range events {
+			if len(in.Types) > 0 && !slices.Contains(in.Types, ev.Type) {
+				continue
+			}
+			if err := stream.Send(transformCloudEventToApi(ev)); err != nil {
+				logger.FromContext(ctx).Warn("cloudevents.stream.send", zap.String("id", ev.Id), zap.Error(err))
+				return err
+			}
+		}
+		if more {
+			continue
+		}
+		select {
+		case <-s.Shutdown:
+			return nil
+		case <-ctx.Done():
+			return nil
+		case <-time.After(cloudEventsPollInterval):
+		}
+	}
+}
+
+// startCursor returns the position of the event with the id, or the position before the events that are written from now on if id is empty.
+func (s *CloudEventServer) startCursor(ctx context.Context, id string) (*db.CommitEventPosition, error) {
+	return db.WithTransactionT(s.DBHandler, ctx, 2, true, func(ctx context.Context, transaction *sql.Tx) (*db.CommitEventPosition, error) {
+		if id == "" {
+			head, err := s.DBHandler.DBReadCommitEventsHead(ctx, transaction)
+			if err != nil {
+				return nil, err
+			}
+			return &head, nil
+		}
+		position, err := s.DBHandler.DBSelectCommitEventPosition(ctx, transaction, id)
+		if err != nil {
+			return nil, err
+		}
+		if position == nil {
+			return nil, grpc.NotFoundError(ctx, fmt.Errorf("event %s does not exist", id))
+		}
+		return position, nil
+	})
+}
+
+// nextCloudEvents returns the events after the cursor and moves the cursor.
+// more is true if there may be more events after the cursor.
+func (s *CloudEventServer) nextCloudEvents(ctx context.Context, cursor *db.CommitEventPosition) (_ []*cloudevents.Event, more bool, _ error) {
+	var rows []db.PositionedEventRow
+	events, err := db.WithTransactionMultipleEntriesT(s.DBHandler, ctx, true, func(ctx context.Context, transaction *sql.Tx) ([]*cloudevents.Event, error) {
+		var err error
+		rows, err = s.DBHandler.DBSelectCommitEventsAfter(ctx, transaction, *cursor, cloudEventsBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		teams := map[string]string{}
+		result := make([]*cloudevents.Event, 0, len(rows))
+		for _, row := range rows {
+			ev, err := s.toCloudEvent(ctx, transaction, row.EventRow, teams)
+			if err != nil {
+				return nil, err
+			}
+			if ev != nil {
+				result = append(result, ev)
+			}
+		}
+		return result, nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if len(rows) > 0 {
+		*cursor = rows[len(rows)-1].Position
+	}
+	return events, len(rows) == cloudEventsBatchSize, nil
+}
+
+// toCloudEvent returns nil for events that have no CloudEvent type.
+func (s *CloudEventServer) toCloudEvent(ctx context.Context, transaction *sql.Tx, row db.EventRow, teams map[string]string) (*cloudevents.Event, error) {
+	if _, ok := cloudevents.Types[row.EventType]; !ok {
+		return nil, nil
+	}
+	parsed, err := event.UnMarshallEvent(row.EventType, row.EventJson)
+	if err != nil {
+		logger.FromContext(ctx).Warn("cloudevents.event.invalid", zap.String("uuid", row.Uuid), zap.Error(err))
+		return nil, nil
+	}
+	subject := webhooks.SubjectOf(parsed.EventData)
+	team := subject.Team
+	if team == "" && subject.Application != "" {
+		team, err = webhooks.LookupTeam(ctx, s.DBHandler, transaction, subject.Application, teams)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return cloudevents.FromEvent(row.Uuid, row.Timestamp, row.CommitHash, team, parsed.EventData)
+}
+
+func isCloudEventType(t string) bool {
+	for _, known := range cloudevents.Types {
+		if known == t {
+			return true
+		}
+	}
+	return false
+}
+
+func transformCloudEventToApi(ev *cloudevents.Event) *api.CloudEvent {
+	return &api.CloudEvent{
+		Id:              ev.Id,
+		Source:          ev.Source,
+		SpecVersion:     ev.SpecVersion,
+		Type:            ev.Type,
+		Subject:         ev.Subject,
+		Time:            timestamppb.New(ev.Time),
+		DataContentType: ev.DataContentType,
+		Data:            string(ev.Data),
+	}
+}
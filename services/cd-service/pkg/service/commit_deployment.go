@@ -214,6 +214,7 @@ func getCommitReleaseNumber(jsonInput []byte) (uint64, error) {
 	releaseEvent := event.DBEventGo{
 		EventData: &event.NewRelease{
 			Environments: map[string]struct{}{},
+			Application:  nil,
 		},
 		EventMetadata: event.Metadata{
 			Uuid:           "",
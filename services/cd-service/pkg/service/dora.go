@@ -0,0 +1,73 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/grpc"
+	"github.com/freiheit-com/kuberpult/services/cd-service/pkg/dora"
+)
+
+const doraDefaultWindow = 30 * 24 * time.Hour
+
+// DoraMetricsServer computes the DORA metrics from the deployment history.
+type DoraMetricsServer struct {
+	DBHandler *db.DBHandler
+}
+
+func (s *DoraMetricsServer) GetDoraMetrics(ctx context.Context, in *api.GetDoraMetricsRequest) (*api.GetDoraMetricsResponse, error) {
+	from, to, err := doraTimeWindow(in, time.Now().UTC())
+	if err != nil {
+		return nil, grpc.InvalidArgument(ctx, err)
+	}
+	metrics, err := dora.Read(ctx, s.DBHandler, from, to, dora.Filter{
+		Team:        in.Team,
+		Application: in.Application,
+		Environment: in.Environment,
+		GroupBy:     in.GroupBy,
+	})
+	if err != nil {
+		return nil, grpc.InternalError(ctx, err)
+	}
+	return &api.GetDoraMetricsResponse{
+		From:    timestamppb.New(from),
+		To:      timestamppb.New(to),
+		Metrics: metrics,
+	}, nil
+}
+
+func doraTimeWindow(in *api.GetDoraMetricsRequest, now time.Time) (time.Time, time.Time, error) {
+	to := now
+	if in.To != nil {
+		to = in.To.AsTime()
+	}
+	from := to.Add(-doraDefaultWindow)
+	if in.From != nil {
+		from = in.From.AsTime()
+	}
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from %s must be before to %s", from.Format(time.RFC3339), to.Format(time.RFC3339))
+	}
+	return from, to, nil
+}
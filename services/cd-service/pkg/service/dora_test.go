@@ -0,0 +1,83 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
+)
+
+func TestDoraTimeWindow(t *testing.T) {
+	now := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+	tcs := []struct {
+		Name          string
+		Request       *api.GetDoraMetricsRequest
+		ExpectedFrom  time.Time
+		ExpectedTo    time.Time
+		ExpectedError string
+	}{
+		{
+			Name:         "default window",
+			Request:      &api.GetDoraMetricsRequest{},
+			ExpectedFrom: time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC),
+			ExpectedTo:   now,
+		},
+		{
+			Name:         "only to",
+			Request:      &api.GetDoraMetricsRequest{To: timestamppb.New(time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC))},
+			ExpectedFrom: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			ExpectedTo:   time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			Name:         "only from",
+			Request:      &api.GetDoraMetricsRequest{From: timestamppb.New(time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC))},
+			ExpectedFrom: time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC),
+			ExpectedTo:   now,
+		},
+		{
+			Name: "from after to",
+			Request: &api.GetDoraMetricsRequest{
+				From: timestamppb.New(time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC)),
+				To:   timestamppb.New(time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)),
+			},
+			ExpectedError: "from 2024-01-20T00:00:00Z must be before to 2024-01-10T00:00:00Z",
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			from, to, err := doraTimeWindow(tc.Request, now)
+			actual := ""
+			if err != nil {
+				actual = err.Error()
+			}
+			if diff := cmp.Diff(tc.ExpectedError, actual); diff != "" {
+				t.Errorf("error mismatch (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.ExpectedFrom, from); diff != "" {
+				t.Errorf("from mismatch (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.ExpectedTo, to); diff != "" {
+				t.Errorf("to mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
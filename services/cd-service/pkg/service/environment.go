@@ -20,10 +20,12 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
 	"github.com/freiheit-com/kuberpult/pkg/config"
 	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/mapper"
 	"github.com/freiheit-com/kuberpult/pkg/types"
 	"github.com/freiheit-com/kuberpult/services/cd-service/pkg/repository"
 )
@@ -53,11 +55,93 @@ func (o *EnvironmentServiceServer) GetEnvironmentConfig(
 
 func TransformEnvironmentConfigToApi(in config.EnvironmentConfig) *api.EnvironmentConfig {
 	return &api.EnvironmentConfig{
-		Upstream:         transformUpstreamToApi(in.Upstream),
-		Argocd:           transformArgoCdToApi(in.ArgoCd),
-		EnvironmentGroup: in.EnvironmentGroup,
-		ArgoConfigs:      transformArgoCdConfigsToApi(in.ArgoCdConfigs),
-		IsActiveActive:   in.IsActiveActive,
+		Upstream:           transformUpstreamToApi(in.Upstream),
+		Argocd:             transformArgoCdToApi(in.ArgoCd),
+		EnvironmentGroup:   in.EnvironmentGroup,
+		ArgoConfigs:        transformArgoCdConfigsToApi(in.ArgoCdConfigs),
+		IsActiveActive:     in.IsActiveActive,
+		Freezes:            mapper.TransformFreezes(in.Freezes),
+		ManifestValidation: mapper.TransformManifestValidation(in.ManifestValidation),
+		SignaturePolicy:    mapper.TransformSignaturePolicy(in.SignaturePolicy),
+		DeploymentGates:    mapper.TransformDeploymentGates(in.DeploymentGates),
+	}
+}
+
+func transformFreezeToConfig(in *api.EnvironmentFreeze) config.EnvironmentFreeze {
+	var start, end time.Time
+	if in.Start != nil {
+		start = in.Start.AsTime()
+	}
+	if in.End != nil {
+		end = in.End.AsTime()
+	}
+	return config.EnvironmentFreeze{
+		Id:       in.FreezeId,
+		Message:  in.Message,
+		Schedule: in.Schedule,
+		Duration: in.Duration,
+		Start:    start,
+		End:      end,
+	}
+}
+
+func transformFreezesToConfig(in []*api.EnvironmentFreeze) []config.EnvironmentFreeze {
+	var out []config.EnvironmentFreeze
+	for _, freeze := range in {
+		out = append(out, transformFreezeToConfig(freeze))
+	}
+	return out
+}
+
+func transformManifestValidationToConfig(in *api.EnvironmentConfig_ManifestValidation) *config.ManifestValidation {
+	if in == nil {
+		return nil
+	}
+	return &config.ManifestValidation{
+		Rules:           in.Rules,
+		DisallowedKinds: in.DisallowedKinds,
+	}
+}
+
+func transformDeploymentGatesToConfig(in []*api.EnvironmentConfig_DeploymentGate) []config.DeploymentGate {
+	var out []config.DeploymentGate
+	for _, gate := range in {
+		out = append(out, config.DeploymentGate{
+			Name: gate.Name,
+			Url:  gate.Url,
+		})
+	}
+	return out
+}
+
+func transformSignaturePolicyToConfig(in *api.EnvironmentConfig_SignaturePolicy) *config.SignaturePolicy {
+	if in == nil {
+		return nil
+	}
+	keys := make([]config.SigningKey, 0, len(in.Keys))
+	for _, key := range in.Keys {
+		keys = append(keys, config.SigningKey{
+			Name:      key.Name,
+			Type:      key.Type,
+			PublicKey: key.PublicKey,
+		})
+	}
+	return &config.SignaturePolicy{
+		Keys:      keys,
+		Threshold: in.Threshold,
+	}
+}
+
+func transformFluxCdToConfig(in *api.ArgoCDEnvironmentConfiguration_FluxCd) *config.EnvironmentConfigFluxCd {
+	if in == nil {
+		return nil
+	}
+	return &config.EnvironmentConfigFluxCd{
+		Namespace:           in.Namespace,
+		Interval:            in.Interval,
+		SecretRef:           in.SecretRef,
+		KubeConfigSecretRef: in.KubeConfigSecretRef,
+		ServiceAccountName:  in.ServiceAccountName,
 	}
 }
 
@@ -73,6 +157,7 @@ func transformArgoCdToConfig(conf *api.ArgoCDEnvironmentConfiguration) *config.E
 		IgnoreDifferences:        ignoreDifferences,
 		SyncOptions:              conf.SyncOptions,
 		ConcreteEnvName:          conf.ConcreteEnvName,
+		FluxCd:                   transformFluxCdToConfig(conf.Fluxcd),
 	}
 	return argocd
 }
@@ -85,12 +170,14 @@ func transformUpstreamToConfig(upstream *api.EnvironmentConfig_Upstream) *config
 		return &config.EnvironmentConfigUpstream{
 			Environment: "",
 			Latest:      true,
+			SoakTime:    upstream.GetSoakTime(),
 		}
 	}
 	if upstream.GetEnvironment() != "" {
 		return &config.EnvironmentConfigUpstream{
 			Latest:      false,
 			Environment: types.EnvName(upstream.GetEnvironment()),
+			SoakTime:    upstream.GetSoakTime(),
 		}
 	}
 	return nil
@@ -101,9 +188,14 @@ func transformUpstreamToApi(in *config.EnvironmentConfigUpstream) *api.Environme
 		return nil
 	}
 
+	var soakTime *string
+	if in.SoakTime != "" {
+		soakTime = &in.SoakTime
+	}
 	return &api.EnvironmentConfig_Upstream{
 		Environment: types.StringPtr(in.Environment),
 		Latest:      &in.Latest,
+		SoakTime:    soakTime,
 	}
 }
 
@@ -119,6 +211,7 @@ func transformArgoCdToApi(in *config.EnvironmentConfigArgoCd) *api.ArgoCDEnviron
 		Destination:            transformDestinationToApi(&in.Destination),
 		AccessList:             transformAccessEntryToApi(in.ClusterResourceWhitelist),
 		ConcreteEnvName:        in.ConcreteEnvName,
+		Fluxcd:                 mapper.TransformFluxCd(in.FluxCd),
 	}
 }
 
@@ -130,6 +223,12 @@ func transformArgoCdConfigsToApi(in *config.ArgoCDConfigs) *api.EnvironmentConfi
 	toReturn := &api.EnvironmentConfig_ArgoConfigs{
 		CommonEnvPrefix: *in.CommonEnvPrefix,
 		Configs:         make([]*api.ArgoCDEnvironmentConfiguration, 0),
+		Waves:           nil,
+	}
+	if in.Waves != nil {
+		toReturn.Waves = &api.EnvironmentConfig_ArgoWaves{
+			Delay: in.Waves.Delay,
+		}
 	}
 
 	for _, cfg := range in.ArgoCdConfigurations {
@@ -146,6 +245,12 @@ func transformArgoCdConfigsToConfig(in *api.EnvironmentConfig_ArgoConfigs) *conf
 	toReturn := &config.ArgoCDConfigs{
 		CommonEnvPrefix:      &in.CommonEnvPrefix,
 		ArgoCdConfigurations: make([]*config.EnvironmentConfigArgoCd, 0),
+		Waves:                nil,
+	}
+	if in.Waves != nil {
+		toReturn.Waves = &config.ArgoCDWaves{
+			Delay: in.Waves.Delay,
+		}
 	}
 
 	for _, cfg := range in.Configs {
@@ -32,10 +32,10 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 
 	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
-	"github.com/freiheit-com/kuberpult/pkg/config"
 	"github.com/freiheit-com/kuberpult/pkg/db"
 	"github.com/freiheit-com/kuberpult/pkg/logging"
 	"github.com/freiheit-com/kuberpult/pkg/mapper"
+	"github.com/freiheit-com/kuberpult/pkg/sorting"
 	"github.com/freiheit-com/kuberpult/pkg/tracing"
 	"github.com/freiheit-com/kuberpult/pkg/types"
 	"github.com/freiheit-com/kuberpult/services/cd-service/pkg/notify"
@@ -77,9 +77,10 @@ func (o *OverviewServiceServer) GetAppDetails(
 			Team:            "",
 			ArgoBracket:     "",
 		},
-		AppLocks:    make(map[string]*api.Locks),
-		Deployments: make(map[string]*api.Deployment),
-		TeamLocks:   make(map[string]*api.Locks),
+		AppLocks:         make(map[string]*api.Locks),
+		Deployments:      make(map[string]*api.Deployment),
+		TeamLocks:        make(map[string]*api.Locks),
+		WavesUnavailable: false,
 	}
 	resultApp, err := db.WithTransactionT(o.DBHandler, ctx, 2, true, func(ctx context.Context, transaction *sql.Tx) (*api.Application, error) {
 		var rels []types.ReleaseNumbers
@@ -93,8 +94,19 @@ func (o *OverviewServiceServer) GetAppDetails(
 			ArgoBracket:     "",
 		}
 
+		// a timestamp means that the app is reconstructed from the history tables as it was at that time
+		ts, err := resolveTimestamp(ctx, o.DBHandler, transaction, "", in.Timestamp, in.EslVersion)
+		if err != nil {
+			return nil, err
+		}
+
 		// bracket
-		appWithMetadata, err := o.DBHandler.DBSelectApp(ctx, transaction, types.AppName(appName))
+		var appWithMetadata *db.DBAppWithMetaData
+		if ts != nil {
+			appWithMetadata, err = o.DBHandler.DBSelectAppAtTimestamp(ctx, transaction, types.AppName(appName), *ts)
+		} else {
+			appWithMetadata, err = o.DBHandler.DBSelectApp(ctx, transaction, types.AppName(appName))
+		}
 		if err != nil {
 			return nil, fmt.Errorf("error finding app: %s: %w", appName, err)
 		}
@@ -110,22 +122,30 @@ func (o *OverviewServiceServer) GetAppDetails(
 
 		// Releases
 		result.Name = appName
-		retrievedReleasesOfApp, err := o.DBHandler.DBSelectAllReleasesOfApp(ctx, transaction, types.AppName(appName))
-		if err != nil {
-			logging.Info(ctx, "app without releases.", zap.Error(err))
-		}
-		if retrievedReleasesOfApp != nil {
-			rels = retrievedReleasesOfApp
-		}
+		var releases []*db.DBReleaseWithMetaData
+		if ts != nil {
+			releases, err = o.DBHandler.DBSelectReleasesOfAppAtTimestamp(ctx, transaction, types.AppName(appName), *ts, false)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			retrievedReleasesOfApp, err := o.DBHandler.DBSelectAllReleasesOfApp(ctx, transaction, types.AppName(appName))
+			if err != nil {
+				logging.Info(ctx, "app without releases.", zap.Error(err))
+			}
+			if retrievedReleasesOfApp != nil {
+				rels = retrievedReleasesOfApp
+			}
 
-		uintRels := make([]uint64, len(rels))
-		for idx, r := range rels {
-			uintRels[idx] = *r.Version
-		}
-		//Does not get the manifest and gets all releases at the same time
-		releases, err := o.DBHandler.DBSelectReleasesByVersionsAndRevision(ctx, transaction, types.AppName(appName), uintRels, false)
-		if err != nil {
-			return nil, err
+			uintRels := make([]uint64, len(rels))
+			for idx, r := range rels {
+				uintRels[idx] = *r.Version
+			}
+			//Does not get the manifest and gets all releases at the same time
+			releases, err = o.DBHandler.DBSelectReleasesByVersionsAndRevision(ctx, transaction, types.AppName(appName), uintRels, false)
+			if err != nil {
+				return nil, err
+			}
 		}
 
 		for _, currentRelease := range releases {
@@ -146,7 +166,12 @@ func (o *OverviewServiceServer) GetAppDetails(
 			result.Releases = append(result.Releases, tmp.ToProto())
 		}
 
-		appTeamName, err := o.Repository.State().GetApplicationTeamOwner(ctx, transaction, types.AppName(appName))
+		var appTeamName string
+		if ts != nil {
+			appTeamName, err = o.Repository.State().GetApplicationTeamOwnerAtTimestamp(ctx, transaction, types.AppName(appName), *ts)
+		} else {
+			appTeamName, err = o.Repository.State().GetApplicationTeamOwner(ctx, transaction, types.AppName(appName))
+		}
 		if err != nil {
 			return nil, fmt.Errorf("app team not found: %s", appName)
 		}
@@ -155,29 +180,23 @@ func (o *OverviewServiceServer) GetAppDetails(
 		if response == nil {
 			return nil, fmt.Errorf("app not found: '%s'", appName)
 		}
-		dbAllEnvs, err := o.DBHandler.DBSelectAllEnvironments(ctx, transaction)
+		envConfigs, err := o.Repository.State().GetAllEnvironmentConfigsAtTimestamp(ctx, transaction, ts)
 		if err != nil {
-			return nil, fmt.Errorf("unable to retrieve all environments, error: %w", err)
+			return nil, err
 		}
-		if dbAllEnvs == nil {
+		if envConfigs == nil {
 			return nil, nil
 		}
-		envs, err := o.DBHandler.DBSelectEnvironmentsBatch(ctx, transaction, dbAllEnvs)
-		if err != nil {
-			return nil, fmt.Errorf("unable to retrieve manifests for environments %v from the database, error: %w", dbAllEnvs, err)
-		}
-
-		envMap := make(map[types.EnvName]db.DBEnvironment)
-		envConfigs := make(map[types.EnvName]config.EnvironmentConfig)
-		for _, env := range *envs {
-			envMap[env.Name] = env
-			envConfigs[env.Name] = env.Config
-		}
 
 		envGroups := mapper.MapEnvironmentsToGroups(envConfigs)
 
 		// App Locks
-		appLocks, err := o.DBHandler.DBSelectAllActiveAppLocksForApp(ctx, transaction, types.AppName(appName))
+		var appLocks []db.ApplicationLock
+		if ts != nil {
+			appLocks, err = o.DBHandler.DBSelectAllActiveAppLocksForAppAtTimestamp(ctx, transaction, types.AppName(appName), *ts)
+		} else {
+			appLocks, err = o.DBHandler.DBSelectAllActiveAppLocksForApp(ctx, transaction, types.AppName(appName))
+		}
 		if err != nil {
 			return nil, fmt.Errorf("could not find application locks for app %s: %w", appName, err)
 		}
@@ -200,7 +219,12 @@ func (o *OverviewServiceServer) GetAppDetails(
 		}
 
 		// Team Locks
-		teamLocks, err := o.DBHandler.DBSelectAllActiveTeamLocksForTeam(ctx, transaction, result.Team)
+		var teamLocks []db.TeamLock
+		if ts != nil {
+			teamLocks, err = o.DBHandler.DBSelectAllActiveTeamLocksForTeamAtTimestamp(ctx, transaction, result.Team, *ts)
+		} else {
+			teamLocks, err = o.DBHandler.DBSelectAllActiveTeamLocksForTeam(ctx, transaction, result.Team)
+		}
 		if err != nil {
 			return nil, fmt.Errorf("could not find team locks for app %s: %w", appName, err)
 		}
@@ -223,16 +247,37 @@ func (o *OverviewServiceServer) GetAppDetails(
 		}
 
 		// Deployments
-		deployments, err := o.DBHandler.DBSelectAllLatestDeploymentsForApplication(ctx, transaction, types.AppName(appName))
+		var deployments map[types.EnvName]db.Deployment
+		if ts != nil {
+			deployments, err = o.DBHandler.DBSelectAllLatestDeploymentsForApplicationAtTimestamp(ctx, transaction, types.AppName(appName), *ts)
+		} else {
+			deployments, err = o.DBHandler.DBSelectAllLatestDeploymentsForApplication(ctx, transaction, types.AppName(appName))
+		}
 		if err != nil {
 			return nil, fmt.Errorf("could not obtain deployments for app %s: %w", appName, err)
 		}
 
-		queuedDeployments, err := o.DBHandler.DBSelectLatestDeploymentAttemptOnAllEnvironments(ctx, transaction, types.AppName(appName))
+		var queuedDeployments []*db.QueuedDeployment
+		if ts != nil {
+			queuedDeployments, err = o.DBHandler.DBSelectLatestDeploymentAttemptOnAllEnvironmentsAtTimestamp(ctx, transaction, types.AppName(appName), *ts)
+		} else {
+			queuedDeployments, err = o.DBHandler.DBSelectLatestDeploymentAttemptOnAllEnvironments(ctx, transaction, types.AppName(appName))
+		}
 		if err != nil {
 			return nil, err
 		}
 
+		// The progress of waves is not kept in the history, so it is only part of the current state:
+		var wavesPerEnv map[types.EnvName][]*db.AAWaveDeployment
+		if ts != nil {
+			response.WavesUnavailable = true
+		} else {
+			wavesPerEnv, err = o.DBHandler.DBSelectAAWaveDeploymentsForApp(ctx, transaction, types.AppName(appName))
+			if err != nil {
+				return nil, fmt.Errorf("could not obtain wave deployments for app %s: %w", appName, err)
+			}
+		}
+
 		// Cache queued versions to check with deployments
 		queuedVersions := make(map[types.EnvName]*uint64)
 		for _, queuedDeployment := range queuedDeployments {
@@ -259,6 +304,7 @@ func (o *OverviewServiceServer) GetAppDetails(
 					DeployTime:   timestamppb.New(currentDeployment.Created),
 				},
 				Revision: currentDeployment.ReleaseNumbers.Revision,
+				Waves:    wavesToApi(wavesPerEnv[envName]),
 			}
 
 			queuedVersion, ok := queuedVersions[envName]
@@ -275,6 +321,15 @@ func (o *OverviewServiceServer) GetAppDetails(
 		}
 		result.UndeploySummary = deriveUndeploySummary(types.AppName(appName), response.Deployments)
 		result.Warnings = CalculateWarnings(deployments, appLocks, envGroups)
+		if ts != nil {
+			// dependencies are checked against the current deployments, which would be misleading in the past
+			return result, nil
+		}
+		dependencyWarnings, err := o.calculateDependencyWarnings(ctx, transaction, response.Deployments, releases)
+		if err != nil {
+			return nil, fmt.Errorf("could not check dependencies of app %s: %w", appName, err)
+		}
+		result.Warnings = append(result.Warnings, dependencyWarnings...)
 		return result, nil
 	})
 	if err != nil {
@@ -284,6 +339,64 @@ func (o *OverviewServiceServer) GetAppDetails(
 	return response, nil
 }
 
+func wavesToApi(waves []*db.AAWaveDeployment) []*api.WaveDeployment {
+	if len(waves) == 0 {
+		return nil
+	}
+	result := make([]*api.WaveDeployment, 0, len(waves))
+	for _, wave := range waves {
+		apiWave := &api.WaveDeployment{
+			ConcreteEnvironment: wave.ConcreteEnv,
+			TargetVersion:       *wave.Target.Version,
+			DeployedVersion:     0,
+			DeployedAt:          nil,
+		}
+		if wave.Deployed != nil && wave.Deployed.Version != nil {
+			apiWave.DeployedVersion = *wave.Deployed.Version
+		}
+		if wave.DeployedAt != nil {
+			apiWave.DeployedAt = timestamppb.New(*wave.DeployedAt)
+		}
+		result = append(result, apiWave)
+	}
+	return result
+}
+
+// calculateDependencyWarnings warns about deployed releases whose dependencies are not deployed in the same environment,
+// e.g. because the dependency was rolled back after the release was deployed.
+func (o *OverviewServiceServer) calculateDependencyWarnings(ctx context.Context, transaction *sql.Tx, deployments map[string]*api.Deployment, releases []*db.DBReleaseWithMetaData) ([]*api.Warning, error) {
+	result := make([]*api.Warning, 0)
+	for _, envName := range sorting.SortKeys(deployments) {
+		deployment := deployments[envName]
+		release := getReleaseFromVersion(releases, types.ReleaseNumbers{Version: &deployment.Version, Revision: deployment.Revision})
+		if release == nil || len(release.Metadata.Dependencies) == 0 {
+			continue
+		}
+		unmet, err := repository.GetUnmetDependencies(ctx, o.Repository.State(), transaction, types.EnvName(envName), release.Metadata.Dependencies)
+		if err != nil {
+			return nil, err
+		}
+		for _, dependency := range unmet {
+			var deployedVersion uint64 = 0
+			if dependency.DeployedVersion != nil {
+				deployedVersion = *dependency.DeployedVersion
+			}
+			result = append(result, &api.Warning{
+				WarningType: &api.Warning_DependencyNotMet{
+					DependencyNotMet: &api.DependencyNotMet{
+						ThisVersion:               deployment.Version,
+						ThisEnvironment:           envName,
+						DependencyApplication:     string(dependency.Application),
+						DependencyMinVersion:      dependency.MinVersion,
+						DependencyDeployedVersion: deployedVersion,
+					},
+				},
+			})
+		}
+	}
+	return result, nil
+}
+
 func getReleaseFromVersion(releases []*db.DBReleaseWithMetaData, releaseNumber types.ReleaseNumbers) *db.DBReleaseWithMetaData {
 	for _, curr := range releases {
 		if *curr.ReleaseNumbers.Version == *releaseNumber.Version && curr.ReleaseNumbers.Revision == releaseNumber.Revision {
@@ -300,7 +413,7 @@ func (o *OverviewServiceServer) GetOverview(
 	span, ctx := tracer.StartSpanFromContext(ctx, "GetOverview")
 	defer span.Finish()
 
-	return o.getOverviewDB(ctx, o.Repository.State())
+	return o.getOverviewDB(ctx, o.Repository.State(), in)
 }
 
 func (o *OverviewServiceServer) GetAllAppLocks(ctx context.Context,
@@ -362,7 +475,16 @@ func (o *OverviewServiceServer) GetAllEnvTeamLocks(ctx context.Context,
 			AllEnvLocks:  make(map[string]*api.Locks),
 			AllTeamLocks: make(map[string]*api.AllTeamLocks),
 		}
-		allEnvLocks, err := o.DBHandler.DBSelectAllEnvLocksOfAllEnvs(ctx, transaction)
+		ts, err := resolveTimestamp(ctx, o.DBHandler, transaction, in.GitRevision, in.Timestamp, in.EslVersion)
+		if err != nil {
+			return nil, err
+		}
+		var allEnvLocks map[types.EnvName][]db.EnvironmentLock
+		if ts != nil {
+			allEnvLocks, err = o.DBHandler.DBSelectAllEnvLocksOfAllEnvsAtTimestamp(ctx, transaction, *ts)
+		} else {
+			allEnvLocks, err = o.DBHandler.DBSelectAllEnvLocksOfAllEnvs(ctx, transaction)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -386,7 +508,12 @@ func (o *OverviewServiceServer) GetAllEnvTeamLocks(ctx context.Context,
 				})
 			}
 		}
-		allTeamLocks, err := o.DBHandler.DBSelectAllTeamLocksOfAllEnvs(ctx, transaction)
+		var allTeamLocks map[types.EnvName]map[string][]db.TeamLock
+		if ts != nil {
+			allTeamLocks, err = o.DBHandler.DBSelectAllTeamLocksOfAllEnvsAtTimestamp(ctx, transaction, *ts)
+		} else {
+			allTeamLocks, err = o.DBHandler.DBSelectAllTeamLocksOfAllEnvs(ctx, transaction)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -456,13 +583,182 @@ func (o *OverviewServiceServer) GetAllManifestLocks(ctx context.Context,
 	})
 }
 
+func (o *OverviewServiceServer) GetPendingDeployments(ctx context.Context,
+	in *api.GetPendingDeploymentsRequest) (*api.GetPendingDeploymentsResponse, error) {
+
+	span, ctx := tracer.StartSpanFromContext(ctx, "GetPendingDeployments")
+	defer span.Finish()
+
+	return db.WithTransactionT(o.DBHandler, ctx, 2, true, func(ctx context.Context, transaction *sql.Tx) (*api.GetPendingDeploymentsResponse, error) {
+		now, err := o.DBHandler.DBReadTransactionTimestamp(ctx, transaction)
+		if err != nil {
+			return nil, err
+		}
+		if now == nil {
+			return nil, fmt.Errorf("could not get transaction timestamp: nil")
+		}
+		approvals, err := o.DBHandler.DBSelectPendingDeploymentApprovals(ctx, transaction)
+		if err != nil {
+			return nil, err
+		}
+		response := api.GetPendingDeploymentsResponse{
+			PendingDeployments: make([]*api.PendingDeployment, 0, len(approvals)),
+		}
+		for _, approval := range approvals {
+			if approval.IsExpired(*now) {
+				continue
+			}
+			response.PendingDeployments = append(response.PendingDeployments, pendingDeploymentToApi(approval))
+		}
+		return &response, nil
+	})
+}
+
+func (o *OverviewServiceServer) GetQueuedDeployments(ctx context.Context,
+	in *api.GetQueuedDeploymentsRequest) (*api.GetQueuedDeploymentsResponse, error) {
+
+	span, ctx := tracer.StartSpanFromContext(ctx, "GetQueuedDeployments")
+	defer span.Finish()
+	span.SetTag("environment", in.Environment)
+	span.SetTag("application", in.Application)
+
+	return db.WithTransactionT(o.DBHandler, ctx, 2, true, func(ctx context.Context, transaction *sql.Tx) (*api.GetQueuedDeploymentsResponse, error) {
+		var queuedDeployments []*db.QueuedDeployment
+		var err error
+		if in.Environment != "" {
+			queuedDeployments, err = o.DBHandler.DBSelectLatestDeploymentAttemptOfAllApps(ctx, transaction, types.EnvName(in.Environment))
+		} else if in.Application != "" {
+			queuedDeployments, err = o.DBHandler.DBSelectLatestDeploymentAttemptOnAllEnvironments(ctx, transaction, types.AppName(in.Application))
+		} else {
+			queuedDeployments, err = o.DBHandler.DBSelectAllLatestDeploymentAttempts(ctx, transaction)
+		}
+		if err != nil {
+			return nil, err
+		}
+		response := api.GetQueuedDeploymentsResponse{
+			QueuedDeployments: make([]*api.QueuedDeployment, 0, len(queuedDeployments)),
+		}
+		for _, queued := range queuedDeployments {
+			if queued.ReleaseNumbers.Version == nil {
+				continue
+			}
+			if in.Application != "" && queued.App != types.AppName(in.Application) {
+				continue
+			}
+			response.QueuedDeployments = append(response.QueuedDeployments, queuedDeploymentToApi(queued))
+		}
+		return &response, nil
+	})
+}
+
+func (o *OverviewServiceServer) GetReleaseRetentionPolicies(ctx context.Context,
+	_ *api.GetReleaseRetentionPoliciesRequest) (*api.GetReleaseRetentionPoliciesResponse, error) {
+
+	span, ctx := tracer.StartSpanFromContext(ctx, "GetReleaseRetentionPolicies")
+	defer span.Finish()
+
+	return db.WithTransactionT(o.DBHandler, ctx, 2, true, func(ctx context.Context, transaction *sql.Tx) (*api.GetReleaseRetentionPoliciesResponse, error) {
+		policies, err := o.DBHandler.DBSelectAllReleaseRetentionPolicies(ctx, transaction)
+		if err != nil {
+			return nil, err
+		}
+		response := api.GetReleaseRetentionPoliciesResponse{
+			Policies: make([]*api.ReleaseRetentionPolicy, 0, len(policies)),
+		}
+		for _, policy := range policies {
+			response.Policies = append(response.Policies, releaseRetentionPolicyToApi(policy))
+		}
+		return &response, nil
+	})
+}
+
+func releaseRetentionPolicyToApi(policy *db.ReleaseRetentionPolicy) *api.ReleaseRetentionPolicy {
+	scope := api.ReleaseRetentionScope_RELEASE_RETENTION_SCOPE_UNKNOWN
+	switch policy.Scope {
+	case db.ReleaseRetentionScopeApplication:
+		scope = api.ReleaseRetentionScope_RELEASE_RETENTION_SCOPE_APPLICATION
+	case db.ReleaseRetentionScopeTeam:
+		scope = api.ReleaseRetentionScope_RELEASE_RETENTION_SCOPE_TEAM
+	}
+	return &api.ReleaseRetentionPolicy{
+		Scope:            scope,
+		Name:             policy.Name,
+		KeepLast:         policy.KeepLast,
+		KeepDays:         policy.KeepDays,
+		KeepProdDeployed: policy.KeepProdDeployed,
+		CreatedAt:        timestamppb.New(policy.Created),
+	}
+}
+
+func queuedDeploymentToApi(queued *db.QueuedDeployment) *api.QueuedDeployment {
+	return &api.QueuedDeployment{
+		Environment: string(queued.Env),
+		Application: string(queued.App),
+		Version:     *queued.ReleaseNumbers.Version,
+		Revision:    queued.ReleaseNumbers.Revision,
+		QueuedAt:    timestamppb.New(queued.Created),
+		QueuedBy: &api.Actor{
+			Name:  queued.Metadata.QueuedByName,
+			Email: queued.Metadata.QueuedByEmail,
+		},
+		Reason: queued.Metadata.Reason,
+	}
+}
+
+func pendingDeploymentToApi(approval *db.DeploymentApproval) *api.PendingDeployment {
+	result := &api.PendingDeployment{
+		ApprovalId: approval.ApprovalId,
+		CreatedAt:  timestamppb.New(approval.Created),
+		ExpiresAt:  timestamppb.New(approval.ExpiresAt),
+		RequestedBy: &api.Actor{
+			Name:  approval.RequestedByName,
+			Email: approval.RequestedByEmail,
+		},
+		Environments: types.EnvNamesToStrings(approval.Environments),
+		Request:      nil,
+	}
+	if deployment := approval.Request.Deployment; deployment != nil {
+		result.Request = &api.PendingDeployment_Deploy{
+			//exhaustruct:ignore
+			Deploy: &api.DeployRequest{
+				Environment:  string(deployment.Environment),
+				Application:  string(deployment.Application),
+				Version:      deployment.Version,
+				Revision:     deployment.Revision,
+				LockBehavior: api.LockBehavior(api.LockBehavior_value[deployment.LockBehaviour]),
+			},
+		}
+	} else if train := approval.Request.ReleaseTrain; train != nil {
+		result.Request = &api.PendingDeployment_ReleaseTrain{
+			ReleaseTrain: &api.ReleaseTrainRequest{
+				Target:     train.Target,
+				Team:       train.Team,
+				CommitHash: train.CommitHash,
+				TargetType: api.ReleaseTrainRequest_TargetType(api.ReleaseTrainRequest_TargetType_value[train.TargetType]),
+				CiLink:     train.CiLink,
+				GitTag:     string(train.GitTag),
+			},
+		}
+	}
+	return result
+}
+
+// getOverviewDB calculates the overview at the point in time of the request, or the current overview if in is nil.
 func (o *OverviewServiceServer) getOverviewDB(
 	ctx context.Context,
-	s *repository.State) (*api.GetOverviewResponse, error) {
+	s *repository.State,
+	in *api.GetOverviewRequest) (*api.GetOverviewResponse, error) {
 
 	response, err := db.WithTransactionT[api.GetOverviewResponse](s.DBHandler, ctx, db.DefaultNumRetries, false, func(ctx context.Context, transaction *sql.Tx) (*api.GetOverviewResponse, error) {
-		var err2 error
-		response, err2 := o.getOverview(ctx, s, transaction)
+		var ts *time.Time
+		if in != nil {
+			var err2 error
+			ts, err2 = resolveTimestamp(ctx, s.DBHandler, transaction, in.GitRevision, in.Timestamp, in.EslVersion)
+			if err2 != nil {
+				return nil, err2
+			}
+		}
+		response, err2 := o.getOverview(ctx, s, transaction, ts)
 		if err2 != nil {
 			return nil, err2
 		}
@@ -478,6 +774,7 @@ func (o *OverviewServiceServer) getOverview(
 	ctx context.Context,
 	s *repository.State,
 	transaction *sql.Tx,
+	ts *time.Time,
 ) (*api.GetOverviewResponse, error) {
 	span, ctx := tracer.StartSpanFromContext(ctx, "CalculateOverview")
 	defer span.Finish()
@@ -491,7 +788,7 @@ func (o *OverviewServiceServer) getOverview(
 	}
 	result.ManifestRepoUrl = o.RepositoryConfig.URL
 	result.Branch = o.RepositoryConfig.Branch
-	if envs, err := s.GetAllEnvironmentConfigs(ctx, transaction); err != nil {
+	if envs, err := s.GetAllEnvironmentConfigsAtTimestamp(ctx, transaction, ts); err != nil {
 		return nil, err
 	} else {
 		result.EnvironmentGroups = mapper.MapEnvironmentsToGroups(envs)
@@ -518,17 +815,21 @@ func (o *OverviewServiceServer) getOverview(
 				Priority:           api.Priority_PROD,
 				Name:               string(envName),
 				Config: &api.EnvironmentConfig{
-					Upstream:         mapper.TransformUpstream(config.Upstream),
-					Argocd:           &argocd,
-					EnvironmentGroup: &groupName,
-					ArgoConfigs:      argocdConfigs,
+					Upstream:           mapper.TransformUpstream(config.Upstream),
+					Argocd:             &argocd,
+					EnvironmentGroup:   &groupName,
+					ArgoConfigs:        argocdConfigs,
+					Freezes:            mapper.TransformFreezes(config.Freezes),
+					ManifestValidation: mapper.TransformManifestValidation(config.ManifestValidation),
+					SignaturePolicy:    mapper.TransformSignaturePolicy(config.SignaturePolicy),
+					DeploymentGates:    mapper.TransformDeploymentGates(config.DeploymentGates),
 				},
 			}
 			envInGroup.Config = env.Config
 		}
 	}
 
-	if appTeams, err := s.GetAllApplicationsTeamOwner(ctx, transaction, nil); err != nil {
+	if appTeams, err := s.GetAllApplicationsTeamOwner(ctx, transaction, ts); err != nil {
 		return nil, err
 	} else {
 		for appName, team := range appTeams {
@@ -565,7 +866,7 @@ func (o *OverviewServiceServer) StreamOverview(in *api.GetOverviewRequest,
 			loaded := o.response.Load()
 			var ov *api.GetOverviewResponse = nil
 			if loaded == nil {
-				ov, err := o.getOverviewDB(stream.Context(), o.Repository.State())
+				ov, err := o.getOverviewDB(stream.Context(), o.Repository.State(), nil)
 				if err != nil {
 					return fmt.Errorf("could not load overview")
 				}
@@ -948,7 +1249,7 @@ func (o *OverviewServiceServer) getChangedBrackets(ctx context.Context, changedA
 }
 
 func (o *OverviewServiceServer) update(s *repository.State) {
-	r, err := o.getOverviewDB(o.Context, s)
+	r, err := o.getOverviewDB(o.Context, s, nil)
 	if err != nil {
 		logging.Error(o.Context, "error getting overview:", zap.Error(err))
 		return
@@ -0,0 +1,330 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package service
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/grpc"
+	"github.com/freiheit-com/kuberpult/pkg/types"
+	"github.com/freiheit-com/kuberpult/pkg/valid"
+	"github.com/freiheit-com/kuberpult/services/cd-service/pkg/repository"
+)
+
+// ReleaseNotesServer collects the releases between two versions of an application,
+// so that CI pipelines do not have to assemble changelogs themselves.
+type ReleaseNotesServer struct {
+	Repository repository.Repository
+}
+
+func (s *ReleaseNotesServer) GetReleaseNotes(ctx context.Context, in *api.GetReleaseNotesRequest) (*api.GetReleaseNotesResponse, error) {
+	if err := validateReleaseNotesRequest(in); err != nil {
+		return nil, grpc.InvalidArgument(ctx, err)
+	}
+	state := s.Repository.State()
+	notes, err := db.WithTransactionMultipleEntriesT(state.DBHandler, ctx, true, func(ctx context.Context, transaction *sql.Tx) ([]*api.AppReleaseNotes, error) {
+		if in.SourceEnvironment != "" {
+			return promotionReleaseNotes(ctx, state, transaction, in)
+		}
+		notes, err := applicationReleaseNotes(ctx, state, transaction, in)
+		if err != nil {
+			return nil, err
+		}
+		return []*api.AppReleaseNotes{notes}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.GetReleaseNotesResponse{
+		Apps:     notes,
+		Markdown: RenderReleaseNotesMarkdown(notes),
+	}, nil
+}
+
+func validateReleaseNotesRequest(in *api.GetReleaseNotesRequest) error {
+	if in.Application != "" && !valid.ApplicationName(types.AppName(in.Application)) {
+		return fmt.Errorf("invalid application name '%s'", in.Application)
+	}
+	for _, env := range []string{in.Environment, in.SourceEnvironment} {
+		if env != "" && !valid.EnvironmentName(types.EnvName(env)) {
+			return fmt.Errorf("invalid environment name '%s'", env)
+		}
+	}
+	if in.Team != "" && !valid.TeamName(in.Team) {
+		return fmt.Errorf("invalid team name '%s'", in.Team)
+	}
+	if in.SourceEnvironment != "" {
+		if in.Environment == "" {
+			return fmt.Errorf("the environment is required together with the source environment")
+		}
+		if in.FromVersion != 0 || in.ToVersion != 0 {
+			return fmt.Errorf("versions cannot be combined with the source environment")
+		}
+		return nil
+	}
+	if in.Application == "" {
+		return fmt.Errorf("either the application or the source environment is required")
+	}
+	if in.Team != "" {
+		return fmt.Errorf("the team can only be combined with the source environment")
+	}
+	if in.ToVersion != 0 && in.ToVersion <= in.FromVersion {
+		return fmt.Errorf("the to version %d must be greater than the from version %d", in.ToVersion, in.FromVersion)
+	}
+	return nil
+}
+
+// applicationReleaseNotes returns the releases of one application. Without from_version, the release notes start
+// after the version deployed on the environment, without to_version they end with the latest release.
+func applicationReleaseNotes(ctx context.Context, state *repository.State, transaction *sql.Tx, in *api.GetReleaseNotesRequest) (*api.AppReleaseNotes, error) {
+	app := types.AppName(in.Application)
+	from := in.FromVersion
+	if from == 0 && in.Environment != "" {
+		deployment, err := state.DBHandler.DBSelectLatestDeployment(ctx, transaction, app, types.EnvName(in.Environment))
+		if err != nil {
+			return nil, err
+		}
+		if deployment != nil && deployment.ReleaseNumbers.Version != nil {
+			from = *deployment.ReleaseNumbers.Version
+		}
+	}
+	to := in.ToVersion
+	if to == 0 {
+		latest, err := state.DBHandler.DBSelectLatestReleaseOfApp(ctx, transaction, app, true)
+		if err != nil {
+			return nil, err
+		}
+		if latest == nil {
+			return nil, grpc.NotFoundError(ctx, fmt.Errorf("application '%s' has no releases", app))
+		}
+		to = *latest.ReleaseNumbers.Version
+	}
+	return collectReleaseNotes(ctx, state, transaction, app, types.EnvName(in.Environment), from, to)
+}
+
+// promotionReleaseNotes returns the release notes of every application that has a newer version
+// on the source environment than on the environment.
+func promotionReleaseNotes(ctx context.Context, state *repository.State, transaction *sql.Tx, in *api.GetReleaseNotesRequest) ([]*api.AppReleaseNotes, error) {
+	source, err := state.GetAllLatestDeployments(ctx, transaction, types.EnvName(in.SourceEnvironment), nil)
+	if err != nil {
+		return nil, err
+	}
+	target, err := state.GetAllLatestDeployments(ctx, transaction, types.EnvName(in.Environment), nil)
+	if err != nil {
+		return nil, err
+	}
+	versions := map[types.AppName]releaseNotesRange{}
+	for app, sourceVersion := range source {
+		if in.Application != "" && string(app) != in.Application {
+			continue
+		}
+		if sourceVersion.Version == nil {
+			continue
+		}
+		if in.Team != "" {
+			team, err := state.GetApplicationTeamOwner(ctx, transaction, app)
+			if err != nil {
+				return nil, err
+			}
+			if team != in.Team {
+				continue
+			}
+		}
+		var from uint64
+		if targetVersion, ok := target[app]; ok && targetVersion.Version != nil {
+			from = *targetVersion.Version
+		}
+		versions[app] = releaseNotesRange{From: from, To: *sourceVersion.Version}
+	}
+	return collectReleaseNotesForEnvironment(ctx, state, transaction, types.EnvName(in.Environment), versions)
+}
+
+// ReleaseTrainReleaseNotes returns the release notes of all deployments that the release train prognosis contains.
+func ReleaseTrainReleaseNotes(ctx context.Context, state *repository.State, transaction *sql.Tx, prognosis repository.ReleaseTrainPrognosis) ([]*api.AppReleaseNotes, error) {
+	envs := make([]types.EnvName, 0, len(prognosis.EnvironmentPrognoses))
+	for env := range prognosis.EnvironmentPrognoses {
+		envs = append(envs, env)
+	}
+	types.Sort(envs)
+	result := []*api.AppReleaseNotes{}
+	for _, env := range envs {
+		envPrognosis := prognosis.EnvironmentPrognoses[env]
+		if envPrognosis.SkipCause != nil {
+			continue
+		}
+		versions := map[types.AppName]releaseNotesRange{}
+		for app, appPrognosis := range envPrognosis.AppsPrognoses {
+			if appPrognosis.SkipCause != nil || appPrognosis.Version.Version == nil {
+				continue
+			}
+			var from uint64
+			if deployed, ok := envPrognosis.AllLatestDeployments[app]; ok && deployed.Version != nil {
+				from = *deployed.Version
+			}
+			versions[app] = releaseNotesRange{From: from, To: *appPrognosis.Version.Version}
+		}
+		notes, err := collectReleaseNotesForEnvironment(ctx, state, transaction, env, versions)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, notes...)
+	}
+	return result, nil
+}
+
+type releaseNotesRange struct {
+	From uint64
+	To   uint64
+}
+
+// collectReleaseNotesForEnvironment skips applications that would not get a newer version.
+func collectReleaseNotesForEnvironment(ctx context.Context, state *repository.State, transaction *sql.Tx, env types.EnvName, versions map[types.AppName]releaseNotesRange) ([]*api.AppReleaseNotes, error) {
+	apps := make([]types.AppName, 0, len(versions))
+	for app, versionRange := range versions {
+		if versionRange.To > versionRange.From {
+			apps = append(apps, app)
+		}
+	}
+	slices.Sort(apps)
+	result := make([]*api.AppReleaseNotes, 0, len(apps))
+	for _, app := range apps {
+		notes, err := collectReleaseNotes(ctx, state, transaction, app, env, versions[app].From, versions[app].To)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, notes)
+	}
+	return result, nil
+}
+
+// collectReleaseNotes returns the releases after from and up to (including) to, newest first.
+func collectReleaseNotes(ctx context.Context, state *repository.State, transaction *sql.Tx, app types.AppName, env types.EnvName, from, to uint64) (*api.AppReleaseNotes, error) {
+	releases, err := state.DBHandler.DBSelectReleasesBetweenVersions(ctx, transaction, app, from, to, true)
+	if err != nil {
+		return nil, fmt.Errorf("could not get releases of app %s: %w", app, err)
+	}
+	return releaseNotesFromReleases(app, env, from, to, releases), nil
+}
+
+func releaseNotesFromReleases(app types.AppName, env types.EnvName, from, to uint64, releases []*db.DBReleaseWithMetaData) *api.AppReleaseNotes {
+	result := &api.AppReleaseNotes{
+		Application: string(app),
+		Environment: string(env),
+		FromVersion: from,
+		ToVersion:   to,
+		Releases:    []*api.Release{},
+	}
+	for _, release := range releases {
+		version := *release.ReleaseNumbers.Version
+		if version <= from || version > to {
+			continue
+		}
+		result.Releases = append(result.Releases, (&repository.Release{
+			Version:         version,
+			UndeployVersion: release.Metadata.UndeployVersion,
+			SourceAuthor:    release.Metadata.SourceAuthor,
+			SourceCommitId:  release.Metadata.SourceCommitId,
+			SourceMessage:   release.Metadata.SourceMessage,
+			CreatedAt:       release.Created,
+			DisplayVersion:  release.Metadata.DisplayVersion,
+			IsMinor:         release.Metadata.IsMinor,
+			IsPrepublish:    release.Metadata.IsPrepublish,
+			Environments:    release.Environments,
+			CiLink:          release.Metadata.CiLink,
+			Revision:        release.ReleaseNumbers.Revision,
+		}).ToProto())
+	}
+	slices.SortStableFunc(result.Releases, func(a, b *api.Release) int {
+		if a.Version != b.Version {
+			return cmp.Compare(b.Version, a.Version)
+		}
+		return cmp.Compare(b.Revision, a.Revision)
+	})
+	return result
+}
+
+// RenderReleaseNotesMarkdown renders the release notes so that CI can post them, e.g. as a pull request comment.
+func RenderReleaseNotesMarkdown(notes []*api.AppReleaseNotes) string {
+	if len(notes) == 0 {
+		return "No changes.\n"
+	}
+	var b strings.Builder
+	for i, app := range notes {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("## " + app.Application)
+		if app.Environment != "" {
+			b.WriteString(" on " + app.Environment)
+		}
+		if app.FromVersion == 0 {
+			fmt.Fprintf(&b, " (up to version %d)\n\n", app.ToVersion)
+		} else {
+			fmt.Fprintf(&b, " (version %d → %d)\n\n", app.FromVersion, app.ToVersion)
+		}
+		if len(app.Releases) == 0 {
+			b.WriteString("No releases.\n")
+			continue
+		}
+		for _, release := range app.Releases {
+			b.WriteString("- " + releaseNotesLine(release) + "\n")
+		}
+	}
+	return b.String()
+}
+
+func releaseNotesLine(release *api.Release) string {
+	version := fmt.Sprintf("%d", release.Version)
+	if release.Revision != 0 {
+		version = fmt.Sprintf("%d.%d", release.Version, release.Revision)
+	}
+	if release.DisplayVersion != "" {
+		version = fmt.Sprintf("%s (%s)", release.DisplayVersion, version)
+	}
+	parts := []string{"**" + version + "**"}
+	if release.SourceCommitId != "" {
+		commit := release.SourceCommitId
+		if len(commit) > 8 {
+			commit = commit[:8]
+		}
+		parts = append(parts, "`"+commit+"`")
+	}
+	message, _, _ := strings.Cut(strings.TrimSpace(release.SourceMessage), "\n")
+	if release.UndeployVersion {
+		message = "Undeploy version"
+	}
+	if message != "" {
+		parts = append(parts, message)
+	}
+	if release.PrNumber != "" && !strings.Contains(message, "#"+release.PrNumber) {
+		parts = append(parts, "(#"+release.PrNumber+")")
+	}
+	if release.SourceAuthor != "" {
+		parts = append(parts, "by "+release.SourceAuthor)
+	}
+	if release.CiLink != "" {
+		parts = append(parts, "([CI]("+release.CiLink+"))")
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,235 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/types"
+)
+
+func TestReleaseNotesFromReleases(t *testing.T) {
+	created := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	release := func(version uint64, message string) *db.DBReleaseWithMetaData {
+		return &db.DBReleaseWithMetaData{
+			ReleaseNumbers: types.MakeReleaseNumberVersion(version),
+			Created:        created,
+			App:            "payments",
+			Metadata: db.DBReleaseMetaData{
+				SourceAuthor:   "alice",
+				SourceCommitId: "0123456789abcdef",
+				SourceMessage:  message,
+				DisplayVersion: "",
+				CiLink:         "https://ci.example.com/1",
+			},
+			Environments: []types.EnvName{},
+		}
+	}
+	tcs := []struct {
+		Name     string
+		From     uint64
+		To       uint64
+		Releases []*db.DBReleaseWithMetaData
+		Expected []uint64
+	}{
+		{
+			Name:     "only the releases after from and up to to",
+			From:     2,
+			To:       4,
+			Releases: []*db.DBReleaseWithMetaData{release(5, "five"), release(4, "four"), release(3, "three"), release(2, "two")},
+			Expected: []uint64{4, 3},
+		},
+		{
+			Name:     "sorted newest first",
+			From:     0,
+			To:       3,
+			Releases: []*db.DBReleaseWithMetaData{release(1, "one"), release(3, "three"), release(2, "two")},
+			Expected: []uint64{3, 2, 1},
+		},
+		{
+			Name:     "no releases in between",
+			From:     3,
+			To:       4,
+			Releases: []*db.DBReleaseWithMetaData{release(5, "five")},
+			Expected: []uint64{},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			notes := releaseNotesFromReleases("payments", "production", tc.From, tc.To, tc.Releases)
+			if notes.Application != "payments" || notes.Environment != "production" || notes.FromVersion != tc.From || notes.ToVersion != tc.To {
+				t.Errorf("unexpected release notes header: %v", notes)
+			}
+			actual := []uint64{}
+			for _, release := range notes.Releases {
+				actual = append(actual, release.Version)
+			}
+			if diff := cmp.Diff(tc.Expected, actual); diff != "" {
+				t.Errorf("versions mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+	t.Run("release fields", func(t *testing.T) {
+		notes := releaseNotesFromReleases("payments", "", 0, 1, []*db.DBReleaseWithMetaData{release(1, "Fix rounding (#42)")})
+		expected := &api.Release{
+			Version:        1,
+			SourceCommitId: "0123456789abcdef",
+			SourceAuthor:   "alice",
+			SourceMessage:  "Fix rounding (#42)",
+			CreatedAt:      timestamppb.New(created),
+			PrNumber:       "42",
+			Environments:   []string{},
+			CiLink:         "https://ci.example.com/1",
+		}
+		if diff := cmp.Diff([]*api.Release{expected}, notes.Releases, protocmp.Transform()); diff != "" {
+			t.Errorf("release mismatch (-want, +got):\n%s", diff)
+		}
+	})
+}
+
+func TestRenderReleaseNotesMarkdown(t *testing.T) {
+	tcs := []struct {
+		Name     string
+		Notes    []*api.AppReleaseNotes
+		Expected string
+	}{
+		{
+			Name:     "no changes",
+			Notes:    []*api.AppReleaseNotes{},
+			Expected: "No changes.\n",
+		},
+		{
+			Name: "promotion of two apps",
+			Notes: []*api.AppReleaseNotes{
+				{
+					Application: "billing",
+					Environment: "production",
+					FromVersion: 7,
+					ToVersion:   9,
+					Releases: []*api.Release{
+						{
+							Version:        9,
+							DisplayVersion: "1.9.0",
+							SourceCommitId: "0123456789abcdef",
+							SourceAuthor:   "alice",
+							SourceMessage:  "Add invoices (#12)\n\nlong description",
+							PrNumber:       "12",
+							CiLink:         "https://ci.example.com/9",
+						},
+						{
+							Version:       8,
+							Revision:      1,
+							SourceAuthor:  "bob",
+							SourceMessage: "Fix totals",
+							PrNumber:      "",
+						},
+					},
+				},
+				{
+					Application: "payments",
+					Environment: "production",
+					FromVersion: 0,
+					ToVersion:   1,
+					Releases:    []*api.Release{},
+				},
+			},
+			Expected: "## billing on production (version 7 → 9)\n\n" +
+				"- **1.9.0 (9)** `01234567` Add invoices (#12) by alice ([CI](https://ci.example.com/9))\n" +
+				"- **8.1** Fix totals by bob\n" +
+				"\n## payments on production (up to version 1)\n\n" +
+				"No releases.\n",
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			actual := RenderReleaseNotesMarkdown(tc.Notes)
+			if diff := cmp.Diff(tc.Expected, actual); diff != "" {
+				t.Errorf("markdown mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateReleaseNotesRequest(t *testing.T) {
+	tcs := []struct {
+		Name          string
+		Request       *api.GetReleaseNotesRequest
+		ExpectedError string
+	}{
+		{
+			Name:    "application on environment",
+			Request: &api.GetReleaseNotesRequest{Application: "payments", Environment: "production"},
+		},
+		{
+			Name:    "two versions",
+			Request: &api.GetReleaseNotesRequest{Application: "payments", FromVersion: 3, ToVersion: 7},
+		},
+		{
+			Name:    "promotion",
+			Request: &api.GetReleaseNotesRequest{Environment: "production", SourceEnvironment: "staging", Team: "finance"},
+		},
+		{
+			Name:          "neither application nor source environment",
+			Request:       &api.GetReleaseNotesRequest{Environment: "production"},
+			ExpectedError: "either the application or the source environment is required",
+		},
+		{
+			Name:          "source environment without environment",
+			Request:       &api.GetReleaseNotesRequest{SourceEnvironment: "staging"},
+			ExpectedError: "the environment is required together with the source environment",
+		},
+		{
+			Name:          "versions with source environment",
+			Request:       &api.GetReleaseNotesRequest{Environment: "production", SourceEnvironment: "staging", ToVersion: 3},
+			ExpectedError: "versions cannot be combined with the source environment",
+		},
+		{
+			Name:          "to version not greater than from version",
+			Request:       &api.GetReleaseNotesRequest{Application: "payments", FromVersion: 7, ToVersion: 7},
+			ExpectedError: "the to version 7 must be greater than the from version 7",
+		},
+		{
+			Name:          "team without source environment",
+			Request:       &api.GetReleaseNotesRequest{Application: "payments", Team: "finance"},
+			ExpectedError: "the team can only be combined with the source environment",
+		},
+		{
+			Name:          "invalid environment",
+			Request:       &api.GetReleaseNotesRequest{Application: "payments", Environment: "Prod!"},
+			ExpectedError: "invalid environment name 'Prod!'",
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := validateReleaseNotesRequest(tc.Request)
+			actual := ""
+			if err != nil {
+				actual = err.Error()
+			}
+			if diff := cmp.Diff(tc.ExpectedError, actual); diff != "" {
+				t.Errorf("error mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
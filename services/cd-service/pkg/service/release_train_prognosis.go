@@ -23,6 +23,7 @@ import (
 
 	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
 	"github.com/freiheit-com/kuberpult/pkg/auth"
+	"github.com/freiheit-com/kuberpult/pkg/mapper"
 	rp "github.com/freiheit-com/kuberpult/services/cd-service/pkg/repository"
 )
 
@@ -74,6 +75,9 @@ func (s *ReleaseTrainPrognosisServer) GetReleaseTrainPrognosis(ctx context.Conte
 		case envPrognosis.SkipCause != nil:
 			retEnvPrognosis.Outcome = envPrognosis.SkipCause
 			retEnvPrognosis.EnvLocks = envPrognosis.EnvLocks
+			if envPrognosis.ActiveFreeze != nil {
+				retEnvPrognosis.ActiveFreeze = mapper.TransformFreeze(*envPrognosis.ActiveFreeze)
+			}
 		case envPrognosis.Error != nil:
 			// this case should never be reached since an error in the environment prognosis is propagated to the release train prognosis
 			return nil, fmt.Errorf("error in an environment release train, environment: %s, error: %w", envName, envPrognosis.Error)
@@ -577,3 +577,137 @@ func TestReleaseTrainAppSkip(t *testing.T) {
 		})
 	}
 }
+
+func TestReleaseTrainPrognosisSoakTime(t *testing.T) {
+	type TestCase struct {
+		Name             string
+		SoakTime         string
+		ExpectedResponse *api.GetReleaseTrainPrognosisResponse
+	}
+
+	tcs := []TestCase{
+		{
+			Name:     "version that was just deployed upstream is too young",
+			SoakTime: "1h",
+			ExpectedResponse: &api.GetReleaseTrainPrognosisResponse{
+				EnvsPrognoses: map[string]*api.ReleaseTrainEnvPrognosis{
+					"staging": {
+						Outcome: &api.ReleaseTrainEnvPrognosis_AppsPrognoses{
+							AppsPrognoses: &api.ReleaseTrainEnvPrognosis_AppsPrognosesWrapper{
+								Prognoses: map[string]*api.ReleaseTrainAppPrognosis{
+									"potato-app": {
+										Outcome: &api.ReleaseTrainAppPrognosis_SkipCause{
+											SkipCause: api.ReleaseTrainAppSkipCause_APP_UPSTREAM_VERSION_TOO_YOUNG,
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:     "version that soaked long enough is promoted",
+			SoakTime: "1ns",
+			ExpectedResponse: &api.GetReleaseTrainPrognosisResponse{
+				EnvsPrognoses: map[string]*api.ReleaseTrainEnvPrognosis{
+					"staging": {
+						Outcome: &api.ReleaseTrainEnvPrognosis_AppsPrognoses{
+							AppsPrognoses: &api.ReleaseTrainEnvPrognosis_AppsPrognosesWrapper{
+								Prognoses: map[string]*api.ReleaseTrainAppPrognosis{
+									"potato-app": {
+										Outcome: &api.ReleaseTrainAppPrognosis_DeployedVersion{
+											DeployedVersion: &api.ReleaseTrainPrognosisDeployedVersion{
+												Version:  2,
+												Revision: 0,
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			repo, err := setupRepositoryTestWithDB(t)
+			if err != nil {
+				t.Fatalf("error setting up repository test: %v", err)
+			}
+			ctx := testutilauth.MakeTestContext()
+			setup := []rp.Transformer{
+				&rp.CreateEnvironment{
+					Environment: "development",
+					Config: config.EnvironmentConfig{
+						Upstream: &config.EnvironmentConfigUpstream{
+							Environment: "",
+							Latest:      true,
+						},
+					},
+				},
+				&rp.CreateEnvironment{
+					Environment: "staging",
+					Config: config.EnvironmentConfig{
+						Upstream: &config.EnvironmentConfigUpstream{
+							Environment: "development",
+							Latest:      false,
+							SoakTime:    tc.SoakTime,
+						},
+					},
+				},
+				&rp.CreateApplicationVersion{
+					Application: "potato-app",
+					Manifests: map[types.EnvName]string{
+						"development": "",
+						"staging":     "",
+					},
+					Version: 1,
+				},
+				&rp.CreateApplicationVersion{
+					Application: "potato-app",
+					Manifests: map[types.EnvName]string{
+						"development": "",
+						"staging":     "",
+					},
+					Version: 2,
+				},
+				&rp.DeployApplicationVersion{
+					Environment: "development",
+					Application: "potato-app",
+					Version:     2,
+				},
+				&rp.DeployApplicationVersion{
+					Environment: "staging",
+					Application: "potato-app",
+					Version:     1,
+				},
+			}
+
+			err = repo.State().DBHandler.WithTransaction(ctx, false, func(ctx context.Context, transaction *sql.Tx) error {
+				_, _, _, err2 := repo.ApplyTransformersInternal(testutilauth.MakeTestContext(), transaction, setup...)
+				if err2 != nil {
+					return err2
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("error during setup, error: %v", err)
+			}
+
+			sv := &ReleaseTrainPrognosisServer{Repository: repo}
+			resp, err := sv.GetReleaseTrainPrognosis(context.Background(), &api.ReleaseTrainRequest{Target: "staging"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.ExpectedResponse, resp, protocmp.Transform()); diff != "" {
+				t.Fatalf("expected response mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
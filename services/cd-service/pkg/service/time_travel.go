@@ -0,0 +1,70 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/grpc"
+)
+
+// resolveTimestamp translates the point in time of an overview request into a database timestamp.
+// It returns nil if no point in time was requested, which means that the current state should be used.
+func resolveTimestamp(ctx context.Context, dbHandler *db.DBHandler, transaction *sql.Tx, gitRevision string, timestamp *timestamppb.Timestamp, eslVersion uint64) (*time.Time, error) {
+	set := 0
+	for _, isSet := range []bool{gitRevision != "", timestamp != nil, eslVersion != 0} {
+		if isSet {
+			set++
+		}
+	}
+	if set > 1 {
+		return nil, grpc.InvalidArgument(ctx, fmt.Errorf("at most one of git revision, timestamp and esl version can be set"))
+	}
+	switch {
+	case gitRevision != "":
+		ts, err := dbHandler.DBReadCommitHashTransactionTimestamp(ctx, transaction, gitRevision)
+		if err != nil {
+			return nil, err
+		}
+		if ts == nil {
+			return nil, grpc.NotFoundError(ctx, fmt.Errorf("could not find timestamp that corresponds to the git revision '%s'", gitRevision))
+		}
+		return ts, nil
+	case timestamp != nil:
+		if err := timestamp.CheckValid(); err != nil {
+			return nil, grpc.InvalidArgument(ctx, fmt.Errorf("invalid timestamp: %w", err))
+		}
+		ts := timestamp.AsTime()
+		return &ts, nil
+	case eslVersion != 0:
+		event, err := dbHandler.DBReadEslEventLaterThan(ctx, transaction, db.EslVersion(eslVersion-1))
+		if err != nil {
+			return nil, err
+		}
+		if event == nil || event.EslVersion != db.EslVersion(eslVersion) {
+			return nil, grpc.NotFoundError(ctx, fmt.Errorf("could not find event with esl version %d", eslVersion))
+		}
+		return &event.Created, nil
+	}
+	return nil, nil
+}
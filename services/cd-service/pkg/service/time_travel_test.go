@@ -0,0 +1,80 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestResolveTimestamp(t *testing.T) {
+	at := time.Date(2026, 10, 17, 14, 3, 0, 0, time.UTC)
+	tcs := []struct {
+		Name          string
+		GitRevision   string
+		Timestamp     *timestamppb.Timestamp
+		EslVersion    uint64
+		Expected      *time.Time
+		ExpectedError string
+	}{
+		{
+			Name:     "nothing set means the current state",
+			Expected: nil,
+		},
+		{
+			Name:      "timestamp",
+			Timestamp: timestamppb.New(at),
+			Expected:  &at,
+		},
+		{
+			Name:          "timestamp and esl version",
+			Timestamp:     timestamppb.New(at),
+			EslVersion:    12,
+			ExpectedError: "rpc error: code = InvalidArgument desc = error: at most one of git revision, timestamp and esl version can be set",
+		},
+		{
+			Name:          "git revision and timestamp",
+			GitRevision:   "cafe",
+			Timestamp:     timestamppb.New(at),
+			ExpectedError: "rpc error: code = InvalidArgument desc = error: at most one of git revision, timestamp and esl version can be set",
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			actual, err := resolveTimestamp(context.Background(), nil, nil, tc.GitRevision, tc.Timestamp, tc.EslVersion)
+			if tc.ExpectedError != "" {
+				if err == nil {
+					t.Fatalf("expected error %q but got none", tc.ExpectedError)
+				}
+				if diff := cmp.Diff(tc.ExpectedError, err.Error()); diff != "" {
+					t.Errorf("error mismatch (-want, +got):\n%s", diff)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.Expected, actual); diff != "" {
+				t.Errorf("timestamp mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
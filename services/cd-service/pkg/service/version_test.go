@@ -178,7 +178,7 @@ func TestGetManifests(t *testing.T) {
 		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
-			repo, err := setupRepositoryTestWithAllOptions(t)
+			repo, err := setupRepositoryTestWithAllOptions(t, repository.DeploymentApprovalConfig{})
 			if err != nil {
 				t.Fatalf("error setting up repository test: %v", err)
 			}
@@ -0,0 +1,284 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
+	"github.com/freiheit-com/kuberpult/pkg/auth"
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/event"
+	"github.com/freiheit-com/kuberpult/pkg/grpc"
+	"github.com/freiheit-com/kuberpult/services/cd-service/pkg/webhooks"
+)
+
+// WebhookServer manages the webhook subscriptions. The deliveries are sent by the webhooks.Worker.
+type WebhookServer struct {
+	DBHandler  *db.DBHandler
+	RBACConfig auth.RBACConfig
+}
+
+func (s *WebhookServer) checkUserPermissions(ctx context.Context) error {
+	if !s.RBACConfig.DexEnabled {
+		return nil
+	}
+	user, err := auth.ReadUserFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("checkUserPermissions: user not found: %v", err)
+	}
+	return auth.CheckUserPermissions(s.RBACConfig, user, "*", "", "*", "*", auth.PermissionManageWebhooks)
+}
+
+func (s *WebhookServer) CreateWebhookSubscription(ctx context.Context, in *api.CreateWebhookSubscriptionRequest) (*api.CreateWebhookSubscriptionResponse, error) {
+	if err := s.checkUserPermissions(ctx); err != nil {
+		return nil, grpc.AuthError(ctx, err)
+	}
+	user, err := auth.ReadUserFromContext(ctx)
+	if err != nil {
+		return nil, grpc.AuthError(ctx, fmt.Errorf("creating a webhook subscription requires a user: %v", err))
+	}
+	filter := transformWebhookFilterToDB(in.Filter)
+	if err := webhooks.Validate(in.Url, filter); err != nil {
+		return nil, grpc.InvalidArgument(ctx, err)
+	}
+	if in.Secret == "" {
+		return nil, grpc.InvalidArgument(ctx, fmt.Errorf("webhook subscription needs a secret to sign the payloads"))
+	}
+	subscription, err := db.WithTransactionT(s.DBHandler, ctx, 2, false, func(ctx context.Context, transaction *sql.Tx) (*db.WebhookSubscription, error) {
+		now, err := s.DBHandler.DBReadTransactionTimestamp(ctx, transaction)
+		if err != nil {
+			return nil, err
+		}
+		subscription := db.WebhookSubscription{
+			Id:             0,
+			Url:            in.Url,
+			Secret:         in.Secret,
+			Filter:         filter,
+			Format:         transformWebhookFormatToDB(in.Format),
+			Created:        *now,
+			CreatedByName:  user.Name,
+			CreatedByEmail: user.Email,
+		}
+		subscription.Id, err = s.DBHandler.DBInsertWebhookSubscription(ctx, transaction, subscription)
+		if err != nil {
+			return nil, err
+		}
+		return &subscription, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.CreateWebhookSubscriptionResponse{
+		Subscription: transformWebhookSubscriptionToApi(*subscription),
+	}, nil
+}
+
+func (s *WebhookServer) DeleteWebhookSubscription(ctx context.Context, in *api.DeleteWebhookSubscriptionRequest) (*api.DeleteWebhookSubscriptionResponse, error) {
+	if err := s.checkUserPermissions(ctx); err != nil {
+		return nil, grpc.AuthError(ctx, err)
+	}
+	err := s.DBHandler.WithTransactionR(ctx, 2, false, func(ctx context.Context, transaction *sql.Tx) error {
+		deleted, err := s.DBHandler.DBDeleteWebhookSubscription(ctx, transaction, in.Id)
+		if err != nil {
+			return err
+		}
+		if !deleted {
+			return grpc.NotFoundError(ctx, fmt.Errorf("webhook subscription %d does not exist", in.Id))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.DeleteWebhookSubscriptionResponse{}, nil
+}
+
+func (s *WebhookServer) GetWebhookSubscriptions(ctx context.Context, _ *api.GetWebhookSubscriptionsRequest) (*api.GetWebhookSubscriptionsResponse, error) {
+	if err := s.checkUserPermissions(ctx); err != nil {
+		return nil, grpc.AuthError(ctx, err)
+	}
+	subscriptions, err := db.WithTransactionMultipleEntriesT(s.DBHandler, ctx, true, func(ctx context.Context, transaction *sql.Tx) ([]db.WebhookSubscription, error) {
+		return s.DBHandler.DBSelectAllWebhookSubscriptions(ctx, transaction)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*api.WebhookSubscription, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		result = append(result, transformWebhookSubscriptionToApi(subscription))
+	}
+	return &api.GetWebhookSubscriptionsResponse{Subscriptions: result}, nil
+}
+
+func (s *WebhookServer) GetWebhookDeliveries(ctx context.Context, in *api.GetWebhookDeliveriesRequest) (*api.GetWebhookDeliveriesResponse, error) {
+	if err := s.checkUserPermissions(ctx); err != nil {
+		return nil, grpc.AuthError(ctx, err)
+	}
+	if in.PageNumber < 0 {
+		return nil, grpc.InvalidArgument(ctx, fmt.Errorf("page number must not be negative"))
+	}
+	var status *db.WebhookDeliveryStatus
+	if in.Status != nil {
+		dbStatus := transformWebhookDeliveryStatusToDB(*in.Status)
+		status = &dbStatus
+	}
+	deliveries, err := db.WithTransactionMultipleEntriesT(s.DBHandler, ctx, true, func(ctx context.Context, transaction *sql.Tx) ([]db.WebhookDelivery, error) {
+		// NOTE: We add one so we know if there is more to load
+		return s.DBHandler.DBSelectWebhookDeliveries(ctx, transaction, in.SubscriptionId, status, PAGESIZE+1, uint64(in.PageNumber)*PAGESIZE)
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := &api.GetWebhookDeliveriesResponse{
+		Deliveries: make([]*api.WebhookDelivery, 0, len(deliveries)),
+		LoadMore:   false,
+	}
+	if len(deliveries) > PAGESIZE {
+		response.LoadMore = true
+		deliveries = deliveries[:PAGESIZE]
+	}
+	for _, delivery := range deliveries {
+		response.Deliveries = append(response.Deliveries, transformWebhookDeliveryToApi(delivery))
+	}
+	return response, nil
+}
+
+// RedeliverWebhook queues the delivery again, also if it was already delivered.
+func (s *WebhookServer) RedeliverWebhook(ctx context.Context, in *api.RedeliverWebhookRequest) (*api.RedeliverWebhookResponse, error) {
+	if err := s.checkUserPermissions(ctx); err != nil {
+		return nil, grpc.AuthError(ctx, err)
+	}
+	err := s.DBHandler.WithTransactionR(ctx, 2, false, func(ctx context.Context, transaction *sql.Tx) error {
+		delivery, err := s.DBHandler.DBSelectWebhookDelivery(ctx, transaction, in.DeliveryId)
+		if err != nil {
+			return err
+		}
+		if delivery == nil {
+			return grpc.NotFoundError(ctx, fmt.Errorf("webhook delivery %d does not exist", in.DeliveryId))
+		}
+		now, err := s.DBHandler.DBReadTransactionTimestamp(ctx, transaction)
+		if err != nil {
+			return err
+		}
+		delivery.Status = db.WebhookDeliveryStatusPending
+		delivery.Attempts = 0
+		delivery.NextAttempt = *now
+		return s.DBHandler.DBUpdateWebhookDelivery(ctx, transaction, *delivery)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.RedeliverWebhookResponse{}, nil
+}
+
+func transformWebhookFilterToDB(filter *api.WebhookFilter) db.WebhookFilter {
+	if filter == nil {
+		return db.WebhookFilter{EventTypes: nil, Applications: nil, Teams: nil, Environments: nil}
+	}
+	eventTypes := make([]event.EventType, 0, len(filter.EventTypes))
+	for _, eventType := range filter.EventTypes {
+		eventTypes = append(eventTypes, event.EventType(eventType))
+	}
+	return db.WebhookFilter{
+		EventTypes:   eventTypes,
+		Applications: filter.Applications,
+		Teams:        filter.Teams,
+		Environments: filter.Environments,
+	}
+}
+
+// transformWebhookSubscriptionToApi leaves out the secret
+func transformWebhookSubscriptionToApi(subscription db.WebhookSubscription) *api.WebhookSubscription {
+	eventTypes := make([]string, 0, len(subscription.Filter.EventTypes))
+	for _, eventType := range subscription.Filter.EventTypes {
+		eventTypes = append(eventTypes, string(eventType))
+	}
+	return &api.WebhookSubscription{
+		Id:  subscription.Id,
+		Url: subscription.Url,
+		Filter: &api.WebhookFilter{
+			EventTypes:   eventTypes,
+			Applications: subscription.Filter.Applications,
+			Teams:        subscription.Filter.Teams,
+			Environments: subscription.Filter.Environments,
+		},
+		CreatedAt:      timestamppb.New(subscription.Created),
+		CreatedByName:  subscription.CreatedByName,
+		CreatedByEmail: subscription.CreatedByEmail,
+		Format:         transformWebhookFormatToApi(subscription.Format),
+	}
+}
+
+func transformWebhookFormatToDB(format api.WebhookFormat) db.WebhookFormat {
+	if format == api.WebhookFormat_WEBHOOK_FORMAT_CLOUDEVENTS {
+		return db.WebhookFormatCloudEvents
+	}
+	return db.WebhookFormatKuberpult
+}
+
+func transformWebhookFormatToApi(format db.WebhookFormat) api.WebhookFormat {
+	if format == db.WebhookFormatCloudEvents {
+		return api.WebhookFormat_WEBHOOK_FORMAT_CLOUDEVENTS
+	}
+	return api.WebhookFormat_WEBHOOK_FORMAT_KUBERPULT
+}
+
+func transformWebhookDeliveryToApi(delivery db.WebhookDelivery) *api.WebhookDelivery {
+	var lastAttempt *timestamppb.Timestamp
+	if delivery.LastAttempt != nil {
+		lastAttempt = timestamppb.New(*delivery.LastAttempt)
+	}
+	var status api.WebhookDeliveryStatus
+	switch delivery.Status {
+	case db.WebhookDeliveryStatusDelivered:
+		status = api.WebhookDeliveryStatus_WEBHOOK_DELIVERY_DELIVERED
+	case db.WebhookDeliveryStatusFailed:
+		status = api.WebhookDeliveryStatus_WEBHOOK_DELIVERY_FAILED
+	default:
+		status = api.WebhookDeliveryStatus_WEBHOOK_DELIVERY_PENDING
+	}
+	return &api.WebhookDelivery{
+		Id:             delivery.Id,
+		SubscriptionId: delivery.SubscriptionId,
+		EventUuid:      delivery.EventUuid,
+		EventType:      string(delivery.EventType),
+		Status:         status,
+		Attempts:       delivery.Attempts,
+		CreatedAt:      timestamppb.New(delivery.Created),
+		NextAttemptAt:  timestamppb.New(delivery.NextAttempt),
+		LastAttemptAt:  lastAttempt,
+		ResponseStatus: int32(delivery.ResponseStatus),
+		LastError:      delivery.LastError,
+		Payload:        delivery.Payload,
+	}
+}
+
+func transformWebhookDeliveryStatusToDB(status api.WebhookDeliveryStatus) db.WebhookDeliveryStatus {
+	switch status {
+	case api.WebhookDeliveryStatus_WEBHOOK_DELIVERY_DELIVERED:
+		return db.WebhookDeliveryStatusDelivered
+	case api.WebhookDeliveryStatus_WEBHOOK_DELIVERY_FAILED:
+		return db.WebhookDeliveryStatusFailed
+	default:
+		return db.WebhookDeliveryStatusPending
+	}
+}
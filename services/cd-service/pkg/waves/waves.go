@@ -0,0 +1,238 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+// Package waves rolls out deployments to active/active environments one concrete environment after the other.
+//
+// The DeployApplicationVersion transformer deploys a new version only to the first concrete environment of an
+// environment with waves, see config.WaveEnvironments. The Promoter then deploys it to the next concrete environment
+// with the PromoteAAWave transformer, as soon as the rollout-service reports the previous one as healthy, or the
+// configured delay passed. The manifest-repo-export-service writes the manifests when it processes the event.
+package waves
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/freiheit-com/kuberpult/pkg/auth"
+	"github.com/freiheit-com/kuberpult/pkg/config"
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/logging"
+	"github.com/freiheit-com/kuberpult/pkg/setup"
+	"github.com/freiheit-com/kuberpult/pkg/types"
+	"github.com/freiheit-com/kuberpult/services/cd-service/pkg/repository"
+)
+
+// PromoterUser is the system actor that promotes waves.
+var PromoterUser = auth.User{
+	DexAuthContext: nil,
+	Email:          "kuberpult@freiheit.com",
+	Name:           "kuberpult",
+	Identity:       "",
+}
+
+type Promoter struct {
+	Repository repository.Repository
+	DBHandler  *db.DBHandler
+	Interval   time.Duration
+}
+
+// Run is the BackgroundFunc registered in cmd/server.go.
+// It runs until ctx is cancelled and promotes the waves every Interval.
+func (p *Promoter) Run(ctx context.Context, health *setup.HealthReporter) error {
+	return health.Retry(ctx, func() error {
+		health.ReportReady("promoting waves")
+		for {
+			if err := p.PromoteOnce(ctx); err != nil {
+				logging.Error(ctx, "could not promote waves", zap.Error(err))
+			}
+			select {
+			case <-ctx.Done():
+				return setup.Permanent(nil)
+			case <-time.After(p.Interval):
+			}
+		}
+	})
+}
+
+// PromoteOnce deploys the next concrete environment of every wave that is ready for it.
+func (p *Promoter) PromoteOnce(ctx context.Context) error {
+	var unfinished []*db.AAWaveDeployment
+	err := p.DBHandler.WithTransactionR(ctx, 2, true, func(ctx context.Context, transaction *sql.Tx) error {
+		var err error
+		unfinished, err = p.DBHandler.DBSelectUnfinishedAAWaveDeployments(ctx, transaction)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	userCtx := auth.WriteUserToContext(ctx, PromoterUser)
+	for _, wave := range groupWaves(unfinished) {
+		if err := p.promote(userCtx, wave); err != nil {
+			// one broken wave should not block the others
+			logging.Error(ctx, "could not promote wave",
+				zap.String("app", string(wave[0].App)),
+				zap.String("env", string(wave[0].Env)),
+				zap.Error(err))
+		}
+	}
+	return nil
+}
+
+func (p *Promoter) promote(ctx context.Context, wave []*db.AAWaveDeployment) error {
+	var next *db.AAWaveDeployment
+	var healthy bool
+	err := p.DBHandler.WithTransactionR(ctx, 2, false, func(ctx context.Context, transaction *sql.Tx) error {
+		var err error
+		next, healthy, err = p.readyWave(ctx, transaction, wave)
+		return err
+	})
+	if err != nil || next == nil {
+		return err
+	}
+	logging.Info(ctx, "promoting wave",
+		zap.String("app", string(next.App)),
+		zap.String("env", string(next.Env)),
+		zap.String("concreteEnv", next.ConcreteEnv),
+		zap.Bool("healthy", healthy))
+	// the transformer writes the event for the manifest-repo-export-service, and checks
+	// that no new deployment started a new wave since we read it
+	return p.Repository.Apply(ctx, &repository.PromoteAAWave{
+		Environment:           next.Env,
+		Application:           string(next.App),
+		ConcreteEnvironment:   next.ConcreteEnv,
+		Version:               *next.Target.Version,
+		Revision:              next.Target.Revision,
+		TransformerEslVersion: 0,
+	})
+}
+
+// readyWave returns the next concrete environment of the wave if it can get the target version, and
+// whether the previous one is healthy. Waves of environments that are no longer rolled out in waves are dropped.
+func (p *Promoter) readyWave(ctx context.Context, transaction *sql.Tx, wave []*db.AAWaveDeployment) (*db.AAWaveDeployment, bool, error) {
+	app, env := wave[0].App, wave[0].Env
+	dbEnv, err := p.DBHandler.DBSelectEnvironment(ctx, transaction, env)
+	if err != nil {
+		return nil, false, err
+	}
+	deployment, err := p.DBHandler.DBSelectLatestDeployment(ctx, transaction, app, env)
+	if err != nil {
+		return nil, false, err
+	}
+	if dbEnv == nil || config.WaveEnvironments(&dbEnv.Config) == nil || deployment == nil || !types.Equal(deployment.ReleaseNumbers, wave[0].Target) {
+		// the environment is no longer rolled out in waves, or the app was undeployed in the meantime
+		logging.Info(ctx, "dropping outdated wave", zap.String("app", string(app)), zap.String("env", string(env)))
+		return nil, false, p.DBHandler.DBDeleteAAWaveDeployments(ctx, transaction, app, env)
+	}
+	previous, next := nextWave(wave)
+	if next == nil {
+		return nil, false, nil
+	}
+	delay, err := dbEnv.Config.ArgoCdConfigs.Waves.DelayDuration()
+	if err != nil {
+		return nil, false, err
+	}
+	now, err := p.DBHandler.DBReadTransactionTimestamp(ctx, transaction)
+	if err != nil {
+		return nil, false, err
+	}
+	if now == nil {
+		return nil, false, fmt.Errorf("could not get transaction timestamp: nil")
+	}
+	var healthy bool
+	if previous != nil {
+		prefix := ""
+		if dbEnv.Config.ArgoCdConfigs.CommonEnvPrefix != nil {
+			prefix = *dbEnv.Config.ArgoCdConfigs.CommonEnvPrefix
+		}
+		argoEvent, err := p.DBHandler.DBReadArgoEvent(ctx, transaction, app, types.EnvName(prefix+"-"+string(env)+"-"+previous.ConcreteEnv))
+		if err != nil {
+			return nil, false, err
+		}
+		healthy = isHealthy(argoEvent, previous)
+	}
+	if !isReady(previous, healthy, delay, *now) {
+		return nil, false, nil
+	}
+	return next, healthy, nil
+}
+
+// groupWaves splits the rows into one slice per app and environment, keeping the order of the rows.
+func groupWaves(rows []*db.AAWaveDeployment) [][]*db.AAWaveDeployment {
+	result := [][]*db.AAWaveDeployment{}
+	for _, row := range rows {
+		last := len(result) - 1
+		if last >= 0 && result[last][0].App == row.App && result[last][0].Env == row.Env {
+			result[last] = append(result[last], row)
+			continue
+		}
+		result = append(result, []*db.AAWaveDeployment{row})
+	}
+	return result
+}
+
+// nextWave returns the first concrete environment that does not have the target version yet, and the one before it.
+func nextWave(wave []*db.AAWaveDeployment) (previous *db.AAWaveDeployment, next *db.AAWaveDeployment) {
+	for _, row := range wave {
+		if !row.IsDeployed() {
+			return previous, row
+		}
+		previous = row
+	}
+	return nil, nil
+}
+
+// isReady returns true if the next concrete environment can get the target version.
+func isReady(previous *db.AAWaveDeployment, previousHealthy bool, delay time.Duration, now time.Time) bool {
+	if previous == nil {
+		return true
+	}
+	if previousHealthy {
+		return true
+	}
+	return delay > 0 && previous.DeployedAt != nil && !now.Before(previous.DeployedAt.Add(delay))
+}
+
+// argoEvent is the part of the events that the rollout-service persists that we need to know if an app is healthy
+type argoEvent struct {
+	SyncStatusCode   string
+	HealthStatusCode string
+	OperationState   *struct {
+		FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	}
+}
+
+// isHealthy returns true if Argo CD synced the app on the concrete environment after the target version was deployed there, and it is healthy.
+func isHealthy(event *db.ArgoEvent, wave *db.AAWaveDeployment) bool {
+	if event == nil || event.Discarded || wave.DeployedAt == nil {
+		return false
+	}
+	var parsed argoEvent
+	if err := json.Unmarshal(event.JsonEvent, &parsed); err != nil {
+		return false
+	}
+	if parsed.SyncStatusCode != "Synced" || parsed.HealthStatusCode != "Healthy" {
+		return false
+	}
+	if parsed.OperationState == nil || parsed.OperationState.FinishedAt == nil {
+		return false
+	}
+	// a sync that finished before the deployment does not tell us anything about the new version
+	return parsed.OperationState.FinishedAt.After(*wave.DeployedAt)
+}
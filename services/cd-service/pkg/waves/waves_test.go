@@ -0,0 +1,201 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+package waves
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/types"
+)
+
+var (
+	deployedAt = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	v1         = types.MakeReleaseNumbers(1, 0)
+	v2         = types.MakeReleaseNumbers(2, 0)
+)
+
+func makeWave(concreteEnv string, position int, deployed *types.ReleaseNumbers) *db.AAWaveDeployment {
+	wave := &db.AAWaveDeployment{
+		App:           "app",
+		Env:           "production",
+		ConcreteEnv:   concreteEnv,
+		Position:      position,
+		Target:        v2,
+		TargetCreated: deployedAt,
+		Deployed:      deployed,
+		DeployedAt:    nil,
+		TransformerID: 1,
+	}
+	if deployed != nil {
+		wave.DeployedAt = &deployedAt
+	}
+	return wave
+}
+
+func TestNextWave(t *testing.T) {
+	tcs := []struct {
+		Name             string
+		Wave             []*db.AAWaveDeployment
+		ExpectedPrevious string
+		ExpectedNext     string
+	}{
+		{
+			Name:             "second concrete environment is next",
+			Wave:             []*db.AAWaveDeployment{makeWave("de-1", 0, &v2), makeWave("de-2", 1, &v1), makeWave("de-3", 2, nil)},
+			ExpectedPrevious: "de-1",
+			ExpectedNext:     "de-2",
+		},
+		{
+			Name:             "last concrete environment is next",
+			Wave:             []*db.AAWaveDeployment{makeWave("de-1", 0, &v2), makeWave("de-2", 1, &v2), makeWave("de-3", 2, nil)},
+			ExpectedPrevious: "de-2",
+			ExpectedNext:     "de-3",
+		},
+		{
+			Name:             "finished wave",
+			Wave:             []*db.AAWaveDeployment{makeWave("de-1", 0, &v2), makeWave("de-2", 1, &v2)},
+			ExpectedPrevious: "",
+			ExpectedNext:     "",
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			previous, next := nextWave(tc.Wave)
+			actualPrevious, actualNext := "", ""
+			if previous != nil {
+				actualPrevious = previous.ConcreteEnv
+			}
+			if next != nil {
+				actualNext = next.ConcreteEnv
+			}
+			if actualPrevious != tc.ExpectedPrevious || actualNext != tc.ExpectedNext {
+				t.Errorf("expected previous %q and next %q, got %q and %q", tc.ExpectedPrevious, tc.ExpectedNext, actualPrevious, actualNext)
+			}
+		})
+	}
+}
+
+func TestIsReady(t *testing.T) {
+	tcs := []struct {
+		Name     string
+		Healthy  bool
+		Delay    time.Duration
+		Now      time.Time
+		Expected bool
+	}{
+		{
+			Name:     "healthy",
+			Healthy:  true,
+			Now:      deployedAt.Add(time.Minute),
+			Expected: true,
+		},
+		{
+			Name:     "not healthy without delay",
+			Healthy:  false,
+			Now:      deployedAt.Add(24 * time.Hour),
+			Expected: false,
+		},
+		{
+			Name:     "not healthy before the delay passed",
+			Healthy:  false,
+			Delay:    time.Hour,
+			Now:      deployedAt.Add(59 * time.Minute),
+			Expected: false,
+		},
+		{
+			Name:     "not healthy after the delay passed",
+			Healthy:  false,
+			Delay:    time.Hour,
+			Now:      deployedAt.Add(time.Hour),
+			Expected: true,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			actual := isReady(makeWave("de-1", 0, &v2), tc.Healthy, tc.Delay, tc.Now)
+			if actual != tc.Expected {
+				t.Errorf("expected ready %t, got %t", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestIsHealthy(t *testing.T) {
+	tcs := []struct {
+		Name     string
+		Event    *db.ArgoEvent
+		Expected bool
+	}{
+		{
+			Name:     "no event",
+			Event:    nil,
+			Expected: false,
+		},
+		{
+			Name: "synced and healthy after the deployment",
+			Event: &db.ArgoEvent{
+				JsonEvent: []byte(`{"SyncStatusCode": "Synced", "HealthStatusCode": "Healthy", "OperationState": {"finishedAt": "2024-06-01T12:05:00Z"}}`),
+			},
+			Expected: true,
+		},
+		{
+			Name: "synced and healthy before the deployment",
+			Event: &db.ArgoEvent{
+				JsonEvent: []byte(`{"SyncStatusCode": "Synced", "HealthStatusCode": "Healthy", "OperationState": {"finishedAt": "2024-06-01T11:55:00Z"}}`),
+			},
+			Expected: false,
+		},
+		{
+			Name: "progressing",
+			Event: &db.ArgoEvent{
+				JsonEvent: []byte(`{"SyncStatusCode": "Synced", "HealthStatusCode": "Progressing", "OperationState": {"finishedAt": "2024-06-01T12:05:00Z"}}`),
+			},
+			Expected: false,
+		},
+		{
+			Name: "discarded event",
+			Event: &db.ArgoEvent{
+				JsonEvent: []byte(`{"SyncStatusCode": "Synced", "HealthStatusCode": "Healthy", "OperationState": {"finishedAt": "2024-06-01T12:05:00Z"}}`),
+				Discarded: true,
+			},
+			Expected: false,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			actual := isHealthy(tc.Event, makeWave("de-1", 0, &v2))
+			if actual != tc.Expected {
+				t.Errorf("expected healthy %t, got %t", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestGroupWaves(t *testing.T) {
+	a1 := &db.AAWaveDeployment{App: "a", Env: "production", ConcreteEnv: "de-1"} //exhaustruct:ignore
+	a2 := &db.AAWaveDeployment{App: "a", Env: "production", ConcreteEnv: "de-2"} //exhaustruct:ignore
+	b1 := &db.AAWaveDeployment{App: "b", Env: "production", ConcreteEnv: "de-1"} //exhaustruct:ignore
+	c1 := &db.AAWaveDeployment{App: "b", Env: "staging", ConcreteEnv: "de-1"}    //exhaustruct:ignore
+	actual := groupWaves([]*db.AAWaveDeployment{a1, a2, b1, c1})
+	expected := [][]*db.AAWaveDeployment{{a1, a2}, {b1}, {c1}}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("groups mismatch (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,495 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+// Package webhooks delivers kuberpult events to external HTTP endpoints.
+//
+// The Worker reads new rows of the commit events table, matches them against the subscriptions
+// and stores one delivery per event and matching subscription. Pending deliveries are POSTed as Payload,
+// signed with the HMAC-SHA256 of the subscription secret, and retried with an exponential backoff
+// until the endpoint answers with a 2xx status or Config.MaxAttempts is reached.
+// Deliveries are sent at least once, receivers should use the delivery header to detect duplicates.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/freiheit-com/kuberpult/pkg/backoff"
+	"github.com/freiheit-com/kuberpult/pkg/cloudevents"
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/event"
+	"github.com/freiheit-com/kuberpult/pkg/logger"
+	"github.com/freiheit-com/kuberpult/pkg/setup"
+	"github.com/freiheit-com/kuberpult/pkg/types"
+)
+
+const (
+	// HeaderEvent is the type of the event, e.g. "deployment"
+	HeaderEvent = "X-Kuberpult-Event"
+	// HeaderDelivery is the id of the delivery, it stays the same for retries and redeliveries
+	HeaderDelivery = "X-Kuberpult-Delivery"
+	// HeaderSignature is "sha256=" followed by the hex encoded HMAC-SHA256 of the body, see Sign
+	HeaderSignature = "X-Kuberpult-Signature-256"
+)
+
+const (
+	maxEventsPerRun = 1000
+	// maxDeliveriesPerRun limits the requests per run, the remaining deliveries are sent in the next run
+	maxDeliveriesPerRun = 100
+	maxErrorLength      = 1000
+)
+
+// EventTypes are the event types that subscriptions can filter on.
+var EventTypes = []event.EventType{
+	event.EventTypeNewRelease,
+	event.EventTypeDeployment,
+	event.EventTypeLockPreventedDeployment,
+	event.EventTypeFreezePreventedDeployment,
+	event.EventTypeAutoRollback,
+	event.EventTypeReplaceBy,
+	event.EventTypeLockCreated,
+	event.EventTypeLockDeleted,
+	event.EventTypeEnvironmentChanged,
+	event.EventTypeRolloutStatusChanged,
+}
+
+type Config struct {
+	// Interval is the time between two runs of the worker
+	Interval time.Duration
+	// Timeout of a single request
+	Timeout time.Duration
+	// MaxAttempts is the number of attempts after which a delivery is marked as failed
+	MaxAttempts uint
+	// InitialBackoff is the delay before the first retry, it doubles with every retry up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+var DefaultConfig = Config{
+	Interval:       10 * time.Second,
+	Timeout:        10 * time.Second,
+	MaxAttempts:    10,
+	InitialBackoff: 30 * time.Second,
+	MaxBackoff:     time.Hour,
+}
+
+// Payload is the body of a webhook request.
+type Payload struct {
+	// Id is the uuid of the event
+	Id        string          `json:"id"`
+	Type      event.EventType `json:"type"`
+	Timestamp time.Time       `json:"timestamp"`
+	// CommitHash is the source commit that the event belongs to, lock and environment events have none
+	CommitHash string `json:"commitHash,omitempty"`
+	// Team is the team of the application of the event, or the team of a team lock
+	Team string      `json:"team,omitempty"`
+	Data event.Event `json:"data"`
+}
+
+// Subject is what subscriptions filter on besides the event type.
+type Subject struct {
+	Application  string
+	Team         string
+	Environments []string
+}
+
+// SubjectOf returns the application and environments of an event. The team is only set for team locks.
+func SubjectOf(ev event.Event) Subject {
+	switch e := ev.(type) {
+	case *event.NewRelease:
+		envs := make([]string, 0, len(e.Environments))
+		for env := range e.Environments {
+			envs = append(envs, env)
+		}
+		slices.Sort(envs)
+		app := ""
+		if e.Application != nil {
+			app = *e.Application
+		}
+		return Subject{Application: app, Team: "", Environments: envs}
+	case *event.Deployment:
+		return Subject{Application: e.Application, Team: "", Environments: []string{e.Environment}}
+	case *event.LockPreventedDeployment:
+		return Subject{Application: e.Application, Team: "", Environments: []string{e.Environment}}
+	case *event.FreezePreventedDeployment:
+		return Subject{Application: e.Application, Team: "", Environments: []string{e.Environment}}
+	case *event.AutoRollback:
+		return Subject{Application: e.Application, Team: "", Environments: []string{e.Environment}}
+	case *event.ReplacedBy:
+		return Subject{Application: e.Application, Team: "", Environments: []string{e.Environment}}
+	case *event.LockCreated:
+		return Subject{Application: e.Application, Team: e.Team, Environments: []string{e.Environment}}
+	case *event.LockDeleted:
+		return Subject{Application: e.Application, Team: e.Team, Environments: []string{e.Environment}}
+	case *event.EnvironmentChanged:
+		return Subject{Application: "", Team: "", Environments: []string{e.Environment}}
+	case *event.RolloutStatusChanged:
+		return Subject{Application: e.Application, Team: "", Environments: []string{e.Environment}}
+	default:
+		return Subject{Application: "", Team: "", Environments: nil}
+	}
+}
+
+// Matches returns whether the filter selects the event. An empty list in the filter matches everything,
+// a non-empty list never matches events without application, team or environment.
+func Matches(filter db.WebhookFilter, eventType event.EventType, subject Subject) bool {
+	if len(filter.EventTypes) > 0 && !slices.Contains(filter.EventTypes, eventType) {
+		return false
+	}
+	if len(filter.Applications) > 0 && !slices.Contains(filter.Applications, subject.Application) {
+		return false
+	}
+	if len(filter.Teams) > 0 && !slices.Contains(filter.Teams, subject.Team) {
+		return false
+	}
+	if len(filter.Environments) > 0 && !slices.ContainsFunc(subject.Environments, func(env string) bool {
+		return slices.Contains(filter.Environments, env)
+	}) {
+		return false
+	}
+	return true
+}
+
+// Validate checks the url and the filter of a new subscription.
+func Validate(subscriptionUrl string, filter db.WebhookFilter) error {
+	u, err := url.Parse(subscriptionUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook subscription needs an http or https url, got %q", subscriptionUrl)
+	}
+	for _, eventType := range filter.EventTypes {
+		if !slices.Contains(EventTypes, eventType) {
+			return fmt.Errorf("unknown event type %q", eventType)
+		}
+	}
+	for name, values := range map[string][]string{"application": filter.Applications, "team": filter.Teams, "environment": filter.Environments} {
+		if slices.Contains(values, "") {
+			return fmt.Errorf("the %s filter of a webhook subscription cannot contain an empty name", name)
+		}
+	}
+	return nil
+}
+
+// Sign returns the value of HeaderSignature for body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// RecordAttempt returns the delivery updated with the result of an attempt at now.
+// statusCode is 0 if the endpoint did not answer.
+func (c Config) RecordAttempt(delivery db.WebhookDelivery, now time.Time, statusCode int, attemptErr error) db.WebhookDelivery {
+	delivery.Attempts++
+	delivery.LastAttempt = &now
+	delivery.ResponseStatus = statusCode
+	if attemptErr == nil {
+		delivery.Status = db.WebhookDeliveryStatusDelivered
+		delivery.LastError = ""
+		return delivery
+	}
+	delivery.LastError = attemptErr.Error()
+	if len(delivery.LastError) > maxErrorLength {
+		delivery.LastError = delivery.LastError[:maxErrorLength]
+	}
+	if uint(delivery.Attempts) >= c.MaxAttempts {
+		delivery.Status = db.WebhookDeliveryStatusFailed
+		return delivery
+	}
+	delivery.NextAttempt = now.Add(c.retryDelay(delivery.Attempts))
+	return delivery
+}
+
+// retryDelay is the delay after the given number of failed attempts
+func (c Config) retryDelay(attempts uint32) time.Duration {
+	b := backoff.MakeSimpleBackoff(c.InitialBackoff, c.MaxBackoff)
+	delay := c.InitialBackoff
+	for i := uint32(1); i < attempts && !b.IsAtMax(); i++ {
+		delay = b.NextBackOff()
+	}
+	return delay
+}
+
+type Worker struct {
+	dbHandler *db.DBHandler
+	config    Config
+	client    *http.Client
+}
+
+func New(dbHandler *db.DBHandler, cfg Config) *Worker {
+	return &Worker{
+		dbHandler: dbHandler,
+		config:    cfg,
+		client:    &http.Client{Timeout: cfg.Timeout}, //exhaustruct:ignore
+	}
+}
+
+// Run is the BackgroundFunc registered in cmd/server.go.
+// It runs until ctx is cancelled and matches new events and sends due deliveries every Config.Interval.
+func (w *Worker) Run(ctx context.Context, health *setup.HealthReporter) error {
+	return health.Retry(ctx, func() error {
+		health.ReportReady("delivering webhooks")
+		for {
+			if err := w.enqueue(ctx); err != nil {
+				return err
+			}
+			if err := w.deliver(ctx); err != nil {
+				return err
+			}
+			select {
+			case <-ctx.Done():
+				return setup.Permanent(nil)
+			case <-time.After(w.config.Interval):
+			}
+		}
+	})
+}
+
+// enqueue inserts deliveries for the events after the cursor and moves the cursor forward.
+func (w *Worker) enqueue(ctx context.Context) error {
+	err := w.dbHandler.WithTransactionR(ctx, 2, false, func(ctx context.Context, transaction *sql.Tx) error {
+		cursor, err := w.dbHandler.DBReadWebhookCursor(ctx, transaction)
+		if err != nil {
+			return err
+		}
+		if cursor == nil {
+			// the first run only delivers events that are written from now on
+			head, err := w.dbHandler.DBReadCommitEventsHead(ctx, transaction)
+			if err != nil {
+				return err
+			}
+			return w.dbHandler.DBWriteWebhookCursor(ctx, transaction, head)
+		}
+		subscriptions, err := w.dbHandler.DBSelectAllWebhookSubscriptions(ctx, transaction)
+		if err != nil {
+			return err
+		}
+		events, err := w.dbHandler.DBSelectCommitEventsAfter(ctx, transaction, *cursor, maxEventsPerRun)
+		if err != nil {
+			return err
+		}
+		if len(subscriptions) > 0 {
+			teams := map[string]string{}
+			for _, row := range events {
+				if err := w.enqueueEvent(ctx, transaction, row.EventRow, subscriptions, teams); err != nil {
+					return err
+				}
+			}
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		return w.dbHandler.DBWriteWebhookCursor(ctx, transaction, events[len(events)-1].Position)
+	})
+	if err != nil {
+		return fmt.Errorf("webhooks: could not enqueue events: %w", err)
+	}
+	return nil
+}
+
+func (w *Worker) enqueueEvent(ctx context.Context, transaction *sql.Tx, row db.EventRow, subscriptions []db.WebhookSubscription, teams map[string]string) error {
+	if !slices.Contains(EventTypes, row.EventType) {
+		return nil
+	}
+	parsed, err := event.UnMarshallEvent(row.EventType, row.EventJson)
+	if err != nil {
+		logger.FromContext(ctx).Warn("webhooks.event.invalid", zap.String("uuid", row.Uuid), zap.Error(err))
+		return nil
+	}
+	subject := SubjectOf(parsed.EventData)
+	if subject.Team == "" && subject.Application != "" {
+		subject.Team, err = LookupTeam(ctx, w.dbHandler, transaction, subject.Application, teams)
+		if err != nil {
+			return err
+		}
+	}
+	payloads := map[db.WebhookFormat][]byte{}
+	for _, subscription := range subscriptions {
+		if row.Timestamp.Before(subscription.Created) || !Matches(subscription.Filter, row.EventType, subject) {
+			continue
+		}
+		payload, ok := payloads[subscription.Format]
+		if !ok {
+			payload, err = encodePayload(subscription.Format, row, subject.Team, parsed.EventData)
+			if err != nil {
+				return err
+			}
+			payloads[subscription.Format] = payload
+		}
+		err = w.dbHandler.DBInsertWebhookDelivery(ctx, transaction, db.WebhookDelivery{
+			Id:             0,
+			SubscriptionId: subscription.Id,
+			EventUuid:      row.Uuid,
+			EventType:      row.EventType,
+			Payload:        string(payload),
+			Status:         db.WebhookDeliveryStatusPending,
+			Attempts:       0,
+			Created:        row.Timestamp,
+			NextAttempt:    row.Timestamp,
+			LastAttempt:    nil,
+			ResponseStatus: 0,
+			LastError:      "",
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LookupTeam returns the team of the application, or "" if it does not exist. The result is cached in teams.
+func LookupTeam(ctx context.Context, dbHandler *db.DBHandler, transaction *sql.Tx, application string, teams map[string]string) (string, error) {
+	if team, ok := teams[application]; ok {
+		return team, nil
+	}
+	app, err := dbHandler.DBSelectApp(ctx, transaction, types.AppName(application))
+	if err != nil {
+		return "", err
+	}
+	team := ""
+	if app != nil {
+		team = app.Metadata.Team
+	}
+	teams[application] = team
+	return team, nil
+}
+
+func encodePayload(format db.WebhookFormat, row db.EventRow, team string, ev event.Event) ([]byte, error) {
+	var payload any = Payload{
+		Id:         row.Uuid,
+		Type:       row.EventType,
+		Timestamp:  row.Timestamp,
+		CommitHash: row.CommitHash,
+		Team:       team,
+		Data:       ev,
+	}
+	if format == db.WebhookFormatCloudEvents {
+		cloudEvent, err := cloudevents.FromEvent(row.Uuid, row.Timestamp, row.CommitHash, team, ev)
+		if err != nil {
+			return nil, err
+		}
+		payload = cloudEvent
+	}
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode event %s: %w", row.Uuid, err)
+	}
+	return encoded, nil
+}
+
+// deliver sends the due deliveries. Each result is stored in its own transaction,
+// so that the requests are not sent while a transaction is open.
+func (w *Worker) deliver(ctx context.Context) error {
+	type due struct {
+		subscription db.WebhookSubscription
+		delivery     db.WebhookDelivery
+	}
+	dueDeliveries, err := db.WithTransactionMultipleEntriesT(w.dbHandler, ctx, true, func(ctx context.Context, transaction *sql.Tx) ([]due, error) {
+		now, err := w.dbHandler.DBReadTransactionTimestamp(ctx, transaction)
+		if err != nil {
+			return nil, err
+		}
+		deliveries, err := w.dbHandler.DBSelectDueWebhookDeliveries(ctx, transaction, *now, maxDeliveriesPerRun)
+		if err != nil || len(deliveries) == 0 {
+			return nil, err
+		}
+		subscriptions, err := w.dbHandler.DBSelectAllWebhookSubscriptions(ctx, transaction)
+		if err != nil {
+			return nil, err
+		}
+		result := make([]due, 0, len(deliveries))
+		for _, delivery := range deliveries {
+			idx := slices.IndexFunc(subscriptions, func(s db.WebhookSubscription) bool {
+				return s.Id == delivery.SubscriptionId
+			})
+			if idx != -1 {
+				result = append(result, due{subscription: subscriptions[idx], delivery: delivery})
+			}
+		}
+		return result, nil
+	})
+	if err != nil {
+		return fmt.Errorf("webhooks: could not read due deliveries: %w", err)
+	}
+	log := logger.FromContext(ctx)
+	for _, d := range dueDeliveries {
+		statusCode, sendErr := w.send(ctx, d.subscription, d.delivery)
+		err := w.dbHandler.WithTransactionR(ctx, 2, false, func(ctx context.Context, transaction *sql.Tx) error {
+			now, err := w.dbHandler.DBReadTransactionTimestamp(ctx, transaction)
+			if err != nil {
+				return err
+			}
+			return w.dbHandler.DBUpdateWebhookDelivery(ctx, transaction, w.config.RecordAttempt(d.delivery, *now, statusCode, sendErr))
+		})
+		fields := []zap.Field{
+			zap.Int64("delivery", d.delivery.Id),
+			zap.Int64("subscription", d.subscription.Id),
+			zap.String("event", d.delivery.EventUuid),
+			zap.Int("status", statusCode),
+		}
+		if err != nil {
+			return fmt.Errorf("webhooks: could not store the result of delivery %d: %w", d.delivery.Id, err)
+		}
+		if sendErr != nil {
+			log.Warn("webhooks.delivery.failed", append(fields, zap.Error(sendErr))...)
+		} else {
+			log.Info("webhooks.delivery.sent", fields...)
+		}
+	}
+	return nil
+}
+
+// send POSTs the payload of the delivery and returns the status code of the response, or 0 if there is none.
+func (w *Worker) send(ctx context.Context, subscription db.WebhookSubscription, delivery db.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	if subscription.Format == db.WebhookFormatCloudEvents {
+		req.Header.Set("Content-Type", cloudevents.ContentType)
+	} else {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("User-Agent", "kuberpult-webhooks")
+	req.Header.Set(HeaderEvent, string(delivery.EventType))
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.Id, 10))
+	req.Header.Set(HeaderSignature, Sign(subscription.Secret, body))
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	// read a bit of the body so that the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint answered with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
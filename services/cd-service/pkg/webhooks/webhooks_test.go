@@ -0,0 +1,337 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/freiheit-com/kuberpult/pkg/conversion"
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/event"
+)
+
+func TestMatches(t *testing.T) {
+	deployment := Subject{Application: "frontend", Team: "shop", Environments: []string{"production"}}
+	release := SubjectOf(&event.NewRelease{
+		Environments: map[string]struct{}{"staging": {}, "production": {}},
+		Application:  conversion.FromString("frontend"),
+	})
+	environment := SubjectOf(&event.EnvironmentChanged{Environment: "production", Change: event.EnvironmentCreated})
+
+	tcs := []struct {
+		Name      string
+		Filter    db.WebhookFilter
+		EventType event.EventType
+		Subject   Subject
+		Expected  bool
+	}{
+		{
+			Name:      "empty filter matches everything",
+			Filter:    db.WebhookFilter{},
+			EventType: event.EventTypeEnvironmentChanged,
+			Subject:   environment,
+			Expected:  true,
+		},
+		{
+			Name:      "event type",
+			Filter:    db.WebhookFilter{EventTypes: []event.EventType{event.EventTypeLockCreated, event.EventTypeDeployment}},
+			EventType: event.EventTypeDeployment,
+			Subject:   deployment,
+			Expected:  true,
+		},
+		{
+			Name:      "other event type",
+			Filter:    db.WebhookFilter{EventTypes: []event.EventType{event.EventTypeLockCreated}},
+			EventType: event.EventTypeDeployment,
+			Subject:   deployment,
+			Expected:  false,
+		},
+		{
+			Name:      "application, team and environment",
+			Filter:    db.WebhookFilter{Applications: []string{"frontend"}, Teams: []string{"shop"}, Environments: []string{"production"}},
+			EventType: event.EventTypeDeployment,
+			Subject:   deployment,
+			Expected:  true,
+		},
+		{
+			Name:      "other team",
+			Filter:    db.WebhookFilter{Teams: []string{"payments"}},
+			EventType: event.EventTypeDeployment,
+			Subject:   deployment,
+			Expected:  false,
+		},
+		{
+			Name:      "one of the environments of a release",
+			Filter:    db.WebhookFilter{Environments: []string{"production"}},
+			EventType: event.EventTypeNewRelease,
+			Subject:   release,
+			Expected:  true,
+		},
+		{
+			Name:      "events without application do not match an application filter",
+			Filter:    db.WebhookFilter{Applications: []string{"frontend"}},
+			EventType: event.EventTypeEnvironmentChanged,
+			Subject:   environment,
+			Expected:  false,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			if actual := Matches(tc.Filter, tc.EventType, tc.Subject); actual != tc.Expected {
+				t.Fatalf("expected %v, got %v", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tcs := []struct {
+		Name          string
+		Url           string
+		Filter        db.WebhookFilter
+		ExpectedError string
+	}{
+		{
+			Name:          "valid",
+			Url:           "https://hooks.example.com/kuberpult",
+			Filter:        db.WebhookFilter{EventTypes: []event.EventType{event.EventTypeDeployment}, Teams: []string{"shop"}},
+			ExpectedError: "",
+		},
+		{
+			Name:          "relative url",
+			Url:           "/kuberpult",
+			Filter:        db.WebhookFilter{},
+			ExpectedError: `webhook subscription needs an http or https url, got "/kuberpult"`,
+		},
+		{
+			Name:          "unknown event type",
+			Url:           "https://hooks.example.com",
+			Filter:        db.WebhookFilter{EventTypes: []event.EventType{event.EventTypeDBMigrationEventType}},
+			ExpectedError: `unknown event type "db-migration"`,
+		},
+		{
+			Name:          "empty environment",
+			Url:           "https://hooks.example.com",
+			Filter:        db.WebhookFilter{Environments: []string{""}},
+			ExpectedError: "the environment filter of a webhook subscription cannot contain an empty name",
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := Validate(tc.Url, tc.Filter)
+			actual := ""
+			if err != nil {
+				actual = err.Error()
+			}
+			if actual != tc.ExpectedError {
+				t.Fatalf("expected error %q, got %q", tc.ExpectedError, actual)
+			}
+		})
+	}
+}
+
+func TestSign(t *testing.T) {
+	// echo -n '{"id":"1"}' | openssl dgst -sha256 -hmac secret
+	expected := "sha256=6146142a2ce0159e84c0767881e4ec80bc397da62526e7d19f70795eb79460c0"
+	if actual := Sign("secret", []byte(`{"id":"1"}`)); actual != expected {
+		t.Fatalf("expected signature %q, got %q", expected, actual)
+	}
+}
+
+func TestRecordAttempt(t *testing.T) {
+	cfg := Config{
+		Interval:       time.Second,
+		Timeout:        time.Second,
+		MaxAttempts:    4,
+		InitialBackoff: time.Minute,
+		MaxBackoff:     3 * time.Minute,
+	}
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	pending := func(attempts uint32) db.WebhookDelivery {
+		return db.WebhookDelivery{
+			Id:          1,
+			Status:      db.WebhookDeliveryStatusPending,
+			Attempts:    attempts,
+			NextAttempt: now,
+		} //exhaustruct:ignore
+	}
+
+	tcs := []struct {
+		Name        string
+		Delivery    db.WebhookDelivery
+		StatusCode  int
+		Err         error
+		Expected    db.WebhookDeliveryStatus
+		NextAttempt time.Time
+	}{
+		{
+			Name:        "delivered",
+			Delivery:    pending(0),
+			StatusCode:  204,
+			Err:         nil,
+			Expected:    db.WebhookDeliveryStatusDelivered,
+			NextAttempt: now,
+		},
+		{
+			Name:        "first retry",
+			Delivery:    pending(0),
+			StatusCode:  500,
+			Err:         errors.New("endpoint answered with status 500"),
+			Expected:    db.WebhookDeliveryStatusPending,
+			NextAttempt: now.Add(time.Minute),
+		},
+		{
+			Name:        "second retry doubles the delay",
+			Delivery:    pending(1),
+			StatusCode:  0,
+			Err:         errors.New("connection refused"),
+			Expected:    db.WebhookDeliveryStatusPending,
+			NextAttempt: now.Add(2 * time.Minute),
+		},
+		{
+			Name:        "delay is capped",
+			Delivery:    pending(2),
+			StatusCode:  0,
+			Err:         errors.New("connection refused"),
+			Expected:    db.WebhookDeliveryStatusPending,
+			NextAttempt: now.Add(3 * time.Minute),
+		},
+		{
+			Name:        "failed after the last attempt",
+			Delivery:    pending(3),
+			StatusCode:  503,
+			Err:         errors.New("endpoint answered with status 503"),
+			Expected:    db.WebhookDeliveryStatusFailed,
+			NextAttempt: now,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			actual := cfg.RecordAttempt(tc.Delivery, now, tc.StatusCode, tc.Err)
+			if actual.Status != tc.Expected {
+				t.Fatalf("expected status %s, got %s", tc.Expected, actual.Status)
+			}
+			if actual.Attempts != tc.Delivery.Attempts+1 {
+				t.Fatalf("expected %d attempts, got %d", tc.Delivery.Attempts+1, actual.Attempts)
+			}
+			if !actual.NextAttempt.Equal(tc.NextAttempt) {
+				t.Fatalf("expected next attempt %v, got %v", tc.NextAttempt, actual.NextAttempt)
+			}
+			if actual.ResponseStatus != tc.StatusCode || actual.LastAttempt == nil || !actual.LastAttempt.Equal(now) {
+				t.Fatalf("attempt not recorded: %+v", actual)
+			}
+		})
+	}
+}
+
+func TestSend(t *testing.T) {
+	var received *http.Request
+	var receivedBody []byte
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		receivedBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	worker := New(nil, DefaultConfig)
+	subscription := db.WebhookSubscription{Id: 3, Url: server.URL, Secret: "s3cret"} //exhaustruct:ignore
+	delivery := db.WebhookDelivery{
+		Id:        42,
+		EventType: event.EventTypeDeployment,
+		Payload:   `{"id":"uuid","type":"deployment"}`,
+	} //exhaustruct:ignore
+
+	statusCode, err := worker.send(context.Background(), subscription, delivery)
+	if err != nil || statusCode != http.StatusOK {
+		t.Fatalf("expected a successful delivery, got %d: %v", statusCode, err)
+	}
+	expectedHeaders := map[string]string{
+		"Content-Type":  "application/json",
+		HeaderEvent:     "deployment",
+		HeaderDelivery:  "42",
+		HeaderSignature: Sign("s3cret", []byte(delivery.Payload)),
+	}
+	actualHeaders := map[string]string{}
+	for name := range expectedHeaders {
+		actualHeaders[name] = received.Header.Get(name)
+	}
+	if diff := cmp.Diff(expectedHeaders, actualHeaders); diff != "" {
+		t.Fatalf("headers mismatch (-want, +got):\n%s", diff)
+	}
+	if string(receivedBody) != delivery.Payload {
+		t.Fatalf("expected body %q, got %q", delivery.Payload, receivedBody)
+	}
+
+	status = http.StatusBadGateway
+	statusCode, err = worker.send(context.Background(), subscription, delivery)
+	if err == nil || statusCode != http.StatusBadGateway {
+		t.Fatalf("expected a failed delivery with status 502, got %d: %v", statusCode, err)
+	}
+
+	status = http.StatusOK
+	subscription.Format = db.WebhookFormatCloudEvents
+	if _, err := worker.send(context.Background(), subscription, delivery); err != nil {
+		t.Fatal(err)
+	}
+	if contentType := received.Header.Get("Content-Type"); contentType != "application/cloudevents+json" {
+		t.Fatalf("expected the cloudevents content type, got %q", contentType)
+	}
+}
+
+func TestEncodePayload(t *testing.T) {
+	row := db.EventRow{
+		Uuid:       "00000000-0000-0000-0000-000000000001",
+		Timestamp:  time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC),
+		CommitHash: "abc",
+		EventType:  event.EventTypeEnvironmentChanged,
+	} //exhaustruct:ignore
+	ev := &event.EnvironmentChanged{Environment: "production", Change: event.EnvironmentCreated}
+	tcs := []struct {
+		Format   db.WebhookFormat
+		Expected string
+	}{
+		{
+			Format:   db.WebhookFormatKuberpult,
+			Expected: `{"id":"00000000-0000-0000-0000-000000000001","type":"environment-changed","timestamp":"2026-10-17T09:00:00Z","commitHash":"abc","data":{"Environment":"production","Change":"created"}}`,
+		},
+		{
+			Format:   db.WebhookFormatCloudEvents,
+			Expected: `{"specversion":"1.0","id":"00000000-0000-0000-0000-000000000001","source":"/kuberpult/cd-service","type":"com.freiheit.kuberpult.environment.changed.v1","subject":"environments/production","time":"2026-10-17T09:00:00Z","datacontenttype":"application/json","data":{"environment":"production","change":"created"}}`,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(string(tc.Format), func(t *testing.T) {
+			actual, err := encodePayload(tc.Format, row, "", ev)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.Expected, string(actual)); diff != "" {
+				t.Fatalf("payload mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
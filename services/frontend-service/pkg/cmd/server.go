@@ -275,6 +275,7 @@ func runServer(ctx context.Context) error {
 		DexAuthContext: nil,
 		Email:          c.GitAuthorEmail,
 		Name:           c.GitAuthorName,
+		Identity:       "",
 	}
 
 	if c.AzureEnableAuth {
@@ -357,8 +358,13 @@ func runServer(ctx context.Context) error {
 
 	releaseTrainPrognosisClient := api.NewReleaseTrainPrognosisServiceClient(cdCon)
 	commitDeploymentsClient := api.NewCommitDeploymentServiceClient(cdCon)
+	overviewClient := api.NewOverviewServiceClient(cdCon)
+	cloudEventClient := api.NewCloudEventServiceClient(cdCon)
+	auditClient := api.NewAuditServiceClient(cdCon)
+	releaseNotesClient := api.NewReleaseNotesServiceClient(cdCon)
+	doraMetricsClient := api.NewDoraMetricsServiceClient(cdCon)
 	gproxy := &GrpcProxy{
-		OverviewClient:              api.NewOverviewServiceClient(cdCon),
+		OverviewClient:              overviewClient,
 		BatchClient:                 batchClient,
 		RolloutServiceClient:        rolloutClient,
 		ProductSummaryClient:        api.NewProductSummaryServiceClient(cdCon),
@@ -367,6 +373,11 @@ func runServer(ctx context.Context) error {
 		EnvironmentServiceClient:    api.NewEnvironmentServiceClient(cdCon),
 		ReleaseTrainPrognosisClient: releaseTrainPrognosisClient,
 		EslServiceClient:            api.NewEslServiceClient(cdCon),
+		WebhookServiceClient:        api.NewWebhookServiceClient(cdCon),
+		CloudEventClient:            cloudEventClient,
+		AuditClient:                 auditClient,
+		ReleaseNotesClient:          releaseNotesClient,
+		DoraMetricsClient:           doraMetricsClient,
 	}
 	api.RegisterOverviewServiceServer(gsrv, gproxy)
 	api.RegisterBatchServiceServer(gsrv, gproxy)
@@ -377,6 +388,11 @@ func runServer(ctx context.Context) error {
 	api.RegisterReleaseTrainPrognosisServiceServer(gsrv, gproxy)
 	api.RegisterEslServiceServer(gsrv, gproxy)
 	api.RegisterVersionServiceServer(gsrv, gproxy)
+	api.RegisterWebhookServiceServer(gsrv, gproxy)
+	api.RegisterCloudEventServiceServer(gsrv, gproxy)
+	api.RegisterAuditServiceServer(gsrv, gproxy)
+	api.RegisterReleaseNotesServiceServer(gsrv, gproxy)
+	api.RegisterDoraMetricsServiceServer(gsrv, gproxy)
 
 	frontendConfigService := &service.FrontendConfigServiceServer{
 		Config: config.FrontendConfig{
@@ -415,6 +431,11 @@ func runServer(ctx context.Context) error {
 		ReleaseTrainPrognosisClient: releaseTrainPrognosisClient,
 		CommitDeploymentsClient:     commitDeploymentsClient,
 		ManifestRepoGitClient:       manifestRepoGitClient,
+		OverviewClient:              overviewClient,
+		CloudEventClient:            cloudEventClient,
+		AuditClient:                 auditClient,
+		ReleaseNotesClient:          releaseNotesClient,
+		DoraMetricsClient:           doraMetricsClient,
 
 		Config:    *c,
 		KeyRing:   pgpKeyRing,
@@ -657,6 +678,7 @@ func getRequestAuthorFromGoogleIAP(ctx context.Context, c *config.ServerConfig,
 		Name:           "",
 		DexAuthContext: nil,
 		Email:          payload.Claims["email"].(string),
+		Identity:       "iap:" + payload.Subject,
 	}
 	return u
 }
@@ -673,7 +695,11 @@ func getRequestAuthorFromAzure(ctx context.Context, r *http.Request, jwks *keyfu
 			name, _ := claims["name"].(string)
 			email, _ := claims["email"].(string)
 			if email != "" {
-				return &auth.User{Name: name, Email: email}, nil
+				identity, _ := claims["oid"].(string)
+				if identity == "" {
+					identity = email
+				}
+				return &auth.User{Name: name, Email: email, Identity: "azure:" + identity, DexAuthContext: nil}, nil
 			}
 		}
 	}
@@ -697,6 +723,8 @@ func (p *Auth) serveHTTPInner(ctx context.Context, w http.ResponseWriter, r *htt
 	span, ctx := tracer.StartSpanFromContext(ctx, "ServeHTTP")
 	defer span.Finish()
 	span.SetTag("uri", r.URL)
+	// The identity is only set by kuberpult after verifying the login, never by the client:
+	r.Header.Del(auth.HeaderUserIdentity)
 	var user *auth.User = nil
 	var err error
 	var source string
@@ -713,14 +741,16 @@ func (p *Auth) serveHTTPInner(ctx context.Context, w http.ResponseWriter, r *htt
 	if p.serverConfig.DexEnabled {
 		source = "dex"
 		dexServiceURL := auth.GetDexServiceURL(p.serverConfig.DexFullNameOverride)
-		dexAuthContext := getUserFromDex(r, p.serverConfig.DexClientId, p.serverConfig.DexBaseURL, dexServiceURL, p.Policy, p.serverConfig.DexUseClusterInternalCommunication)
+		dexAuthContext, identity := getUserFromDex(r, p.serverConfig.DexClientId, p.serverConfig.DexBaseURL, dexServiceURL, p.Policy, p.serverConfig.DexUseClusterInternalCommunication)
 		if dexAuthContext == nil {
 			logging.Info(ctx, "No role assigned for Dex user", zap.Any("user", user))
 		} else {
 			if user == nil {
-				user = &p.DefaultUser
+				defaultUser := p.DefaultUser
+				user = &defaultUser
 			}
 			user.DexAuthContext = dexAuthContext
+			user.Identity = identity
 		}
 	}
 	if user != nil {
@@ -746,19 +776,26 @@ func (p *Auth) serveHTTPInner(ctx context.Context, w http.ResponseWriter, r *htt
 	return nil
 }
 
-func getUserFromDex(req *http.Request, clientID, baseURL, dexServiceURL string, policy *auth.RBACPolicies, useClusterInternalCommunication bool) *auth.DexAuthContext {
+// getUserFromDex returns the roles and the identity of the user that logged in with Dex.
+func getUserFromDex(req *http.Request, clientID, baseURL, dexServiceURL string, policy *auth.RBACPolicies, useClusterInternalCommunication bool) (*auth.DexAuthContext, string) {
 	_, err := auth.GetContextFromDex(req.Context(), req, clientID, baseURL, dexServiceURL, policy, useClusterInternalCommunication)
 	if err != nil {
 		logging.Info(req.Context(), "could not get context from dex", zap.Error(err))
-		return nil
+		return nil, ""
 	}
 	headerRole64 := req.Header.Get(auth.HeaderUserRole)
 	headerRole, err := auth.Decode64(headerRole64)
 	if err != nil {
 		logging.Info(req.Context(), "could not decode user role", zap.String("headerRole64", headerRole64), zap.Error(err))
-		return nil
+		return nil, ""
 	}
-	return &auth.DexAuthContext{Role: strings.Split(headerRole, ",")}
+	// GetContextFromDex only sets the identity if the token contains an email
+	identity, err := auth.Decode64(req.Header.Get(auth.HeaderUserIdentity))
+	if err != nil {
+		logging.Info(req.Context(), "could not decode user identity", zap.Error(err))
+		identity = ""
+	}
+	return &auth.DexAuthContext{Role: strings.Split(headerRole, ",")}, identity
 }
 
 // GrpcProxy passes through gRPC messages to another server.
@@ -776,6 +813,11 @@ type GrpcProxy struct {
 	EnvironmentServiceClient    api.EnvironmentServiceClient
 	ReleaseTrainPrognosisClient api.ReleaseTrainPrognosisServiceClient
 	EslServiceClient            api.EslServiceClient
+	WebhookServiceClient        api.WebhookServiceClient
+	CloudEventClient            api.CloudEventServiceClient
+	AuditClient                 api.AuditServiceClient
+	ReleaseNotesClient          api.ReleaseNotesServiceClient
+	DoraMetricsClient           api.DoraMetricsServiceClient
 }
 
 func (p *GrpcProxy) ProcessBatch(
@@ -827,6 +869,24 @@ func (p *GrpcProxy) GetAllManifestLocks(
 	return p.OverviewClient.GetAllManifestLocks(ctx, in)
 }
 
+func (p *GrpcProxy) GetPendingDeployments(
+	ctx context.Context,
+	in *api.GetPendingDeploymentsRequest) (*api.GetPendingDeploymentsResponse, error) {
+	return p.OverviewClient.GetPendingDeployments(ctx, in)
+}
+
+func (p *GrpcProxy) GetQueuedDeployments(
+	ctx context.Context,
+	in *api.GetQueuedDeploymentsRequest) (*api.GetQueuedDeploymentsResponse, error) {
+	return p.OverviewClient.GetQueuedDeployments(ctx, in)
+}
+
+func (p *GrpcProxy) GetReleaseRetentionPolicies(
+	ctx context.Context,
+	in *api.GetReleaseRetentionPoliciesRequest) (*api.GetReleaseRetentionPoliciesResponse, error) {
+	return p.OverviewClient.GetReleaseRetentionPolicies(ctx, in)
+}
+
 func (p *GrpcProxy) GetGitTags(
 	ctx context.Context,
 	in *api.GetGitTagsRequest) (*api.GetGitTagsResponse, error) {
@@ -1019,3 +1079,58 @@ func (p *GrpcProxy) GetManifests(ctx context.Context, in *api.GetManifestsReques
 	}
 	return p.VersionClient.GetManifests(ctx, in)
 }
+
+func (p *GrpcProxy) CreateWebhookSubscription(ctx context.Context, in *api.CreateWebhookSubscriptionRequest) (*api.CreateWebhookSubscriptionResponse, error) {
+	return p.WebhookServiceClient.CreateWebhookSubscription(ctx, in)
+}
+
+func (p *GrpcProxy) DeleteWebhookSubscription(ctx context.Context, in *api.DeleteWebhookSubscriptionRequest) (*api.DeleteWebhookSubscriptionResponse, error) {
+	return p.WebhookServiceClient.DeleteWebhookSubscription(ctx, in)
+}
+
+func (p *GrpcProxy) GetWebhookSubscriptions(ctx context.Context, in *api.GetWebhookSubscriptionsRequest) (*api.GetWebhookSubscriptionsResponse, error) {
+	return p.WebhookServiceClient.GetWebhookSubscriptions(ctx, in)
+}
+
+func (p *GrpcProxy) GetWebhookDeliveries(ctx context.Context, in *api.GetWebhookDeliveriesRequest) (*api.GetWebhookDeliveriesResponse, error) {
+	return p.WebhookServiceClient.GetWebhookDeliveries(ctx, in)
+}
+
+func (p *GrpcProxy) RedeliverWebhook(ctx context.Context, in *api.RedeliverWebhookRequest) (*api.RedeliverWebhookResponse, error) {
+	return p.WebhookServiceClient.RedeliverWebhook(ctx, in)
+}
+
+func (p *GrpcProxy) GetAuditLog(ctx context.Context, in *api.GetAuditLogRequest) (*api.GetAuditLogResponse, error) {
+	return p.AuditClient.GetAuditLog(ctx, in)
+}
+
+func (p *GrpcProxy) GetReleaseNotes(ctx context.Context, in *api.GetReleaseNotesRequest) (*api.GetReleaseNotesResponse, error) {
+	return p.ReleaseNotesClient.GetReleaseNotes(ctx, in)
+}
+
+func (p *GrpcProxy) GetDoraMetrics(ctx context.Context, in *api.GetDoraMetricsRequest) (*api.GetDoraMetricsResponse, error) {
+	return p.DoraMetricsClient.GetDoraMetrics(ctx, in)
+}
+
+func (p *GrpcProxy) StreamCloudEvents(in *api.StreamCloudEventsRequest, stream api.CloudEventService_StreamCloudEventsServer) error {
+	resp, err := p.CloudEventClient.StreamCloudEvents(stream.Context(), in)
+	if err != nil {
+		return err
+	}
+	header, err := resp.Header()
+	if err != nil {
+		return err
+	}
+	if err := stream.SendHeader(header); err != nil {
+		return err
+	}
+	for {
+		item, err := resp.Recv()
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(item); err != nil {
+			return err
+		}
+	}
+}
@@ -644,7 +644,7 @@ func TestGetRequestAuthorFromAzure(t *testing.T) {
 			Authorization: validToken,
 			ClientId:      testClientId,
 			TenantId:      testTenantId,
-			ExpectedUser:  &auth.User{Name: testName, Email: testEmail},
+			ExpectedUser:  &auth.User{Name: testName, Email: testEmail, Identity: "azure:" + testEmail},
 		},
 		{
 			Name:          "valid JWT takes priority over author headers",
@@ -653,7 +653,7 @@ func TestGetRequestAuthorFromAzure(t *testing.T) {
 			AuthorEmail:   auth.Encode64("ci@example.com"),
 			ClientId:      testClientId,
 			TenantId:      testTenantId,
-			ExpectedUser:  &auth.User{Name: testName, Email: testEmail},
+			ExpectedUser:  &auth.User{Name: testName, Email: testEmail, Identity: "azure:" + testEmail},
 		},
 		{
 			Name:          "nil JWKS skips JWT validation and falls back to author headers",
@@ -200,6 +200,8 @@ func (s Server) handleAPIEnvironmentApplications(w http.ResponseWriter, req *htt
 	switch function {
 	case "commit":
 		s.handleAPIEnvironmentApplicationCommit(w, req, environment, application, tail)
+	case "queued-version":
+		s.handleAPIEnvironmentApplicationQueuedVersion(w, req, environment, application, tail)
 	default:
 		http.Error(w, fmt.Sprintf("unknown function '%s'", function), http.StatusNotFound)
 	}
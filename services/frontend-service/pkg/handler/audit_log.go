@@ -0,0 +1,92 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
+	"github.com/freiheit-com/kuberpult/pkg/logging"
+)
+
+// handleAuditLog handles GET /api/audit-log/. The query parameters "from" and "to" (RFC 3339), "eventType" (repeatable),
+// "author", "application", "environment", "team", "pageSize" and "pageToken" are passed on to the AuditService.
+func (s Server) handleAuditLog(ctx context.Context, w http.ResponseWriter, req *http.Request, tail string) {
+	if tail != "/" {
+		http.Error(w, "invalid path", http.StatusNotFound)
+		return
+	}
+	if req.Method != http.MethodGet {
+		http.Error(w, fmt.Sprintf("unsupported method '%s'", req.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	query := req.URL.Query()
+	request := &api.GetAuditLogRequest{
+		From:        nil,
+		To:          nil,
+		EventTypes:  query["eventType"],
+		Author:      query.Get("author"),
+		Application: query.Get("application"),
+		Environment: query.Get("environment"),
+		Team:        query.Get("team"),
+		PageSize:    0,
+		PageToken:   query.Get("pageToken"),
+	}
+	for name, target := range map[string]**timestamppb.Timestamp{"from": &request.From, "to": &request.To} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid %s '%s' (expected RFC 3339)", name, value), http.StatusBadRequest)
+			return
+		}
+		*target = timestamppb.New(parsed)
+	}
+	if value := query.Get("pageSize"); value != "" {
+		pageSize, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid pageSize '%s'", value), http.StatusBadRequest)
+			return
+		}
+		request.PageSize = uint32(pageSize)
+	}
+	resp, err := s.AuditClient.GetAuditLog(ctx, request)
+	if err != nil {
+		handleGRPCError(ctx, w, err)
+		return
+	}
+	jsonResponse, err := protojson.Marshal(resp)
+	if err != nil {
+		logging.Error(ctx, "Failed to marshal response of audit log", zap.Error(err))
+		http.Error(w, fmt.Sprintf("failed to marshal response of audit log: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(jsonResponse)
+	_, _ = w.Write([]byte("\n"))
+}
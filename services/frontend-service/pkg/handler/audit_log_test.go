@@ -0,0 +1,129 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
+)
+
+type mockAuditClient struct {
+	request *api.GetAuditLogRequest
+}
+
+func (m *mockAuditClient) GetAuditLog(_ context.Context, in *api.GetAuditLogRequest, _ ...grpc.CallOption) (*api.GetAuditLogResponse, error) {
+	m.request = in
+	return &api.GetAuditLogResponse{
+		Entries: []*api.AuditLogEntry{
+			{
+				EslVersion:  12,
+				EventType:   "CreateEnvironmentLock",
+				AuthorEmail: "alice@example.com",
+				Environment: "production",
+				Summary:     "Created environment lock l1 on environment production",
+			},
+		},
+		NextPageToken: "12",
+	}, nil
+}
+
+func TestHandleAuditLog(t *testing.T) {
+	tcs := []struct {
+		name               string
+		method             string
+		path               string
+		expectedStatusCode int
+		expectedRequest    *api.GetAuditLogRequest
+		expectedBody       string
+	}{
+		{
+			name:               "without filters",
+			method:             http.MethodGet,
+			path:               "/api/audit-log",
+			expectedStatusCode: http.StatusOK,
+			expectedRequest:    &api.GetAuditLogRequest{},
+			expectedBody:       `{"entries":[{"eslVersion":"12","eventType":"CreateEnvironmentLock","authorEmail":"alice@example.com","environment":"production","summary":"Created environment lock l1 on environment production"}],"nextPageToken":"12"}` + "\n",
+		},
+		{
+			name:               "with all filters",
+			method:             http.MethodGet,
+			path:               "/api/audit-log?from=2026-10-01T00:00:00Z&to=2026-10-17T00:00:00Z&eventType=CreateEnvironmentLock&eventType=DeleteEnvironmentLock&author=alice%40example.com&application=app1&environment=production&team=team1&pageSize=10&pageToken=20",
+			expectedStatusCode: http.StatusOK,
+			expectedRequest: &api.GetAuditLogRequest{
+				From:        timestamppb.New(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)),
+				To:          timestamppb.New(time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)),
+				EventTypes:  []string{"CreateEnvironmentLock", "DeleteEnvironmentLock"},
+				Author:      "alice@example.com",
+				Application: "app1",
+				Environment: "production",
+				Team:        "team1",
+				PageSize:    10,
+				PageToken:   "20",
+			},
+			expectedBody: `{"entries":[{"eslVersion":"12","eventType":"CreateEnvironmentLock","authorEmail":"alice@example.com","environment":"production","summary":"Created environment lock l1 on environment production"}],"nextPageToken":"12"}` + "\n",
+		},
+		{
+			name:               "invalid time",
+			method:             http.MethodGet,
+			path:               "/api/audit-log?from=yesterday",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       "invalid from 'yesterday' (expected RFC 3339)\n",
+		},
+		{
+			name:               "unsupported method",
+			method:             http.MethodPost,
+			path:               "/api/audit-log",
+			expectedStatusCode: http.StatusMethodNotAllowed,
+			expectedBody:       "unsupported method 'POST'\n",
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			auditClient := &mockAuditClient{}
+			s := Server{
+				AuditClient: auditClient,
+			}
+			w := httptest.NewRecorder()
+			s.HandleAPI(w, httptest.NewRequest(tc.method, tc.path, nil))
+			if w.Code != tc.expectedStatusCode {
+				t.Errorf("expected status code %d, got %d", tc.expectedStatusCode, w.Code)
+			}
+			body := strings.ReplaceAll(w.Body.String(), ", ", ",")
+			body = strings.ReplaceAll(body, ": ", ":")
+			expectedBody := strings.ReplaceAll(tc.expectedBody, ": ", ":")
+			if diff := cmp.Diff(expectedBody, body); diff != "" {
+				t.Errorf("response mismatch (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedRequest, auditClient.request, protocmp.Transform()); diff != "" {
+				t.Errorf("request mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
+	"github.com/freiheit-com/kuberpult/pkg/cloudevents"
+	"github.com/freiheit-com/kuberpult/pkg/logging"
+)
+
+// handleCloudEvents streams the CloudEvents as server-sent events.
+// A client resumes with the Last-Event-ID header, or the lastEventId parameter if it cannot set headers.
+func (s Server) handleCloudEvents(ctx context.Context, w http.ResponseWriter, r *http.Request, tail string) {
+	if tail != "/" {
+		http.Error(w, "invalid path", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, fmt.Sprintf("unsupported method '%s'", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	lastEventId := r.Header.Get("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = r.URL.Query().Get("lastEventId")
+	}
+	stream, err := s.CloudEventClient.StreamCloudEvents(ctx, &api.StreamCloudEventsRequest{
+		LastEventId: lastEventId,
+		Types:       r.URL.Query()["type"],
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to stream cloudevents: %v", err), http.StatusInternalServerError)
+		return
+	}
+	// the cd-service sends the header once the stream started, so errors of the request arrive before it
+	if _, err := stream.Header(); err != nil {
+		writeCloudEventStreamError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		ev, err := stream.Recv()
+		if err != nil {
+			if !errors.Is(err, io.EOF) && status.Code(err) != codes.Canceled {
+				logging.Error(ctx, "Failed to receive cloudevents", zap.Error(err))
+			}
+			return
+		}
+		if err := writeServerSentEvent(w, ev); err != nil {
+			logging.Error(ctx, "Failed to write cloudevent", zap.Error(err))
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+func writeCloudEventStreamError(w http.ResponseWriter, err error) {
+	switch status.Code(err) {
+	case codes.NotFound:
+		http.Error(w, status.Convert(err).Message(), http.StatusNotFound)
+	case codes.InvalidArgument:
+		http.Error(w, status.Convert(err).Message(), http.StatusBadRequest)
+	default:
+		http.Error(w, fmt.Sprintf("failed to stream cloudevents: %v", err), http.StatusInternalServerError)
+	}
+}
+
+// writeServerSentEvent writes the structured mode json of the CloudEvent as data of the server-sent event.
+func writeServerSentEvent(w io.Writer, ev *api.CloudEvent) error {
+	structured, err := json.Marshal(cloudevents.Event{
+		SpecVersion:     ev.SpecVersion,
+		Id:              ev.Id,
+		Source:          ev.Source,
+		Type:            ev.Type,
+		Subject:         ev.Subject,
+		Time:            ev.Time.AsTime(),
+		DataContentType: ev.DataContentType,
+		Data:            json.RawMessage(ev.Data),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", ev.Id, ev.Type, structured)
+	return err
+}
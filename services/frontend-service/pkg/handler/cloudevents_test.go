@@ -0,0 +1,137 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package handler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
+)
+
+type mockCloudEventStream struct {
+	grpc.ClientStream
+	headerErr error
+	events    []*api.CloudEvent
+}
+
+func (m *mockCloudEventStream) Header() (metadata.MD, error) {
+	return metadata.MD{}, m.headerErr
+}
+
+func (m *mockCloudEventStream) Recv() (*api.CloudEvent, error) {
+	if len(m.events) == 0 {
+		return nil, io.EOF
+	}
+	ev := m.events[0]
+	m.events = m.events[1:]
+	return ev, nil
+}
+
+type mockCloudEventServiceClient struct {
+	stream  *mockCloudEventStream
+	request *api.StreamCloudEventsRequest
+}
+
+func (m *mockCloudEventServiceClient) StreamCloudEvents(_ context.Context, in *api.StreamCloudEventsRequest, _ ...grpc.CallOption) (grpc.ServerStreamingClient[api.CloudEvent], error) {
+	m.request = in
+	return m.stream, nil
+}
+
+func TestHandleCloudEvents(t *testing.T) {
+	deployment := &api.CloudEvent{
+		Id:              "00000000-0000-0000-0000-000000000002",
+		Source:          "/kuberpult/cd-service",
+		SpecVersion:     "1.0",
+		Type:            "com.freiheit.kuberpult.deployment.created.v1",
+		Subject:         "environments/production/applications/app",
+		Time:            timestamppb.New(time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)),
+		DataContentType: "application/json",
+		Data:            `{"application":"app"}`,
+	}
+	tcs := []struct {
+		Name            string
+		Url             string
+		LastEventId     string
+		HeaderErr       error
+		ExpectedStatus  int
+		ExpectedBody    string
+		ExpectedRequest *api.StreamCloudEventsRequest
+	}{
+		{
+			Name:           "resume with the header",
+			Url:            "/cloudevents/?type=com.freiheit.kuberpult.deployment.created.v1",
+			LastEventId:    "00000000-0000-0000-0000-000000000001",
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody: "id: 00000000-0000-0000-0000-000000000002\n" +
+				"event: com.freiheit.kuberpult.deployment.created.v1\n" +
+				`data: {"specversion":"1.0","id":"00000000-0000-0000-0000-000000000002","source":"/kuberpult/cd-service","type":"com.freiheit.kuberpult.deployment.created.v1","subject":"environments/production/applications/app","time":"2026-10-17T09:00:00Z","datacontenttype":"application/json","data":{"application":"app"}}` + "\n\n",
+			ExpectedRequest: &api.StreamCloudEventsRequest{
+				LastEventId: "00000000-0000-0000-0000-000000000001",
+				Types:       []string{"com.freiheit.kuberpult.deployment.created.v1"},
+			},
+		},
+		{
+			Name:           "unknown last event",
+			Url:            "/cloudevents/?lastEventId=missing",
+			HeaderErr:      status.Error(codes.NotFound, "event missing does not exist"),
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedBody:   "event missing does not exist\n",
+			ExpectedRequest: &api.StreamCloudEventsRequest{
+				LastEventId: "missing",
+				Types:       nil,
+			},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			client := &mockCloudEventServiceClient{
+				stream:  &mockCloudEventStream{headerErr: tc.HeaderErr, events: []*api.CloudEvent{deployment}}, //exhaustruct:ignore
+				request: nil,
+			}
+			s := Server{CloudEventClient: client} //exhaustruct:ignore
+			r := httptest.NewRequest(http.MethodGet, tc.Url, nil)
+			if tc.LastEventId != "" {
+				r.Header.Set("Last-Event-ID", tc.LastEventId)
+			}
+			w := httptest.NewRecorder()
+			s.handleCloudEvents(r.Context(), w, r, "/")
+			if w.Code != tc.ExpectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.ExpectedStatus, w.Code, w.Body.String())
+			}
+			if diff := cmp.Diff(tc.ExpectedBody, w.Body.String()); diff != "" {
+				t.Fatalf("body mismatch (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.ExpectedRequest, client.request, cmp.Comparer(func(a, b *api.StreamCloudEventsRequest) bool {
+				return a.LastEventId == b.LastEventId && cmp.Equal(a.Types, b.Types)
+			})); diff != "" {
+				t.Fatalf("request mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
+)
+
+type mockDoraMetricsClient struct {
+	request *api.GetDoraMetricsRequest
+}
+
+func (m *mockDoraMetricsClient) GetDoraMetrics(_ context.Context, in *api.GetDoraMetricsRequest, _ ...grpc.CallOption) (*api.GetDoraMetricsResponse, error) {
+	m.request = in
+	leadTime := 3600.0
+	return &api.GetDoraMetricsResponse{
+		From: timestamppb.New(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+		To:   timestamppb.New(time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)),
+		Metrics: []*api.DoraMetrics{
+			{
+				Team:              "finance",
+				Deployments:       3,
+				DeploymentsPerDay: 0.1,
+				LeadTimeSeconds:   &leadTime,
+			},
+		},
+	}, nil
+}
+
+func TestHandleDoraMetrics(t *testing.T) {
+	tcs := []struct {
+		name               string
+		method             string
+		path               string
+		expectedStatusCode int
+		expectedRequest    *api.GetDoraMetricsRequest
+		expectedBody       string
+	}{
+		{
+			name:               "filters and grouping",
+			method:             http.MethodGet,
+			path:               "/api/dora-metrics?from=2024-01-01T00:00:00Z&to=2024-01-31T00:00:00Z&environment=production&groupBy=team&groupBy=application",
+			expectedStatusCode: http.StatusOK,
+			expectedRequest: &api.GetDoraMetricsRequest{
+				From:        timestamppb.New(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+				To:          timestamppb.New(time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)),
+				Environment: "production",
+				GroupBy:     []api.GetDoraMetricsRequest_GroupBy{api.GetDoraMetricsRequest_TEAM, api.GetDoraMetricsRequest_APPLICATION},
+			},
+			expectedBody: `{"from":"2024-01-01T00:00:00Z","to":"2024-01-31T00:00:00Z","metrics":[{"team":"finance","deployments":"3","deploymentsPerDay":0.1,"leadTimeSeconds":3600}]}` + "\n",
+		},
+		{
+			name:               "invalid time",
+			method:             http.MethodGet,
+			path:               "/api/dora-metrics?from=yesterday",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       "invalid from 'yesterday' (expected RFC 3339)\n",
+		},
+		{
+			name:               "invalid grouping",
+			method:             http.MethodGet,
+			path:               "/api/dora-metrics?groupBy=cluster",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       "invalid groupBy 'cluster' (expected team, application or environment)\n",
+		},
+		{
+			name:               "unsupported method",
+			method:             http.MethodPost,
+			path:               "/api/dora-metrics",
+			expectedStatusCode: http.StatusMethodNotAllowed,
+			expectedBody:       "unsupported method 'POST'\n",
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			doraMetricsClient := &mockDoraMetricsClient{}
+			s := Server{
+				DoraMetricsClient: doraMetricsClient,
+			}
+			w := httptest.NewRecorder()
+			s.HandleAPI(w, httptest.NewRequest(tc.method, tc.path, nil))
+			if w.Code != tc.expectedStatusCode {
+				t.Errorf("expected status code %d, got %d", tc.expectedStatusCode, w.Code)
+			}
+			body := strings.ReplaceAll(w.Body.String(), ", ", ",")
+			body = strings.ReplaceAll(body, ": ", ":")
+			expectedBody := strings.ReplaceAll(tc.expectedBody, ", ", ",")
+			if diff := cmp.Diff(expectedBody, body); diff != "" {
+				t.Errorf("response mismatch (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedRequest, doraMetricsClient.request, protocmp.Transform()); diff != "" {
+				t.Errorf("request mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -43,6 +43,11 @@ type Server struct {
 	ReleaseTrainPrognosisClient api.ReleaseTrainPrognosisServiceClient
 	CommitDeploymentsClient     api.CommitDeploymentServiceClient
 	ManifestRepoGitClient       api.ManifestExportGitServiceClient
+	OverviewClient              api.OverviewServiceClient
+	CloudEventClient            api.CloudEventServiceClient
+	AuditClient                 api.AuditServiceClient
+	ReleaseNotesClient          api.ReleaseNotesServiceClient
+	DoraMetricsClient           api.DoraMetricsServiceClient
 	//
 	Config    config.ServerConfig
 	KeyRing   openpgp.KeyRing
@@ -84,6 +89,16 @@ func (s Server) HandleAPI(w http.ResponseWriter, req *http.Request) {
 		s.handleCommitDeployments(req.Context(), w, req, tail)
 	case "process-delay":
 		s.handleProcessDelay(req.Context(), w, req, tail)
+	case "queued-deployments":
+		s.handleQueuedDeployments(req.Context(), w, req, tail)
+	case "cloudevents":
+		s.handleCloudEvents(req.Context(), w, req, tail)
+	case "audit-log":
+		s.handleAuditLog(req.Context(), w, req, tail)
+	case "release-notes":
+		s.handleReleaseNotes(req.Context(), w, req, tail)
+	case "dora-metrics":
+		s.handleDoraMetrics(req.Context(), w, req, tail)
 	default:
 		http.Error(w, fmt.Sprintf("unknown endpoint 'api/%s'", group), http.StatusNotFound)
 	}
@@ -223,6 +238,7 @@ func (s *PublicApiServer) GetCommitDeployments(w http.ResponseWriter, r *http.Re
 		Email:          s.S.User.Email,
 		Name:           s.S.User.Name,
 		DexAuthContext: nil,
+		Identity:       "",
 	})
 
 	s.S.handleCommitDeployments(ctx, w, r, commitHash)
@@ -0,0 +1,146 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
+	"github.com/freiheit-com/kuberpult/pkg/logging"
+	xpath "github.com/freiheit-com/kuberpult/pkg/path"
+)
+
+// handleQueuedDeployments lists the queued versions, optionally filtered by the "environment" and "application" query parameters.
+func (s Server) handleQueuedDeployments(ctx context.Context, w http.ResponseWriter, req *http.Request, tail string) {
+	if tail != "/" {
+		http.Error(w, "invalid path", http.StatusNotFound)
+		return
+	}
+	if req.Method != http.MethodGet {
+		http.Error(w, fmt.Sprintf("unsupported method '%s'", req.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	resp, err := s.OverviewClient.GetQueuedDeployments(ctx, &api.GetQueuedDeploymentsRequest{
+		Environment: req.URL.Query().Get("environment"),
+		Application: req.URL.Query().Get("application"),
+	})
+	if err != nil {
+		handleGRPCError(ctx, w, err)
+		return
+	}
+	jsonResponse, err := protojson.Marshal(resp)
+	if err != nil {
+		logging.Error(ctx, "Failed to marshal response of queued deployments", zap.Error(err))
+		http.Error(w, fmt.Sprintf("failed to marshal response of queued deployments: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(jsonResponse)
+	_, _ = w.Write([]byte("\n"))
+}
+
+// handleAPIEnvironmentApplicationQueuedVersion handles
+// DELETE /api/environments/<env>/applications/<app>/queued-version/<version> to cancel a queued version and
+// POST /api/environments/<env>/applications/<app>/queued-version/<version>/deploy to deploy it ignoring locks.
+func (s Server) handleAPIEnvironmentApplicationQueuedVersion(w http.ResponseWriter, req *http.Request, environment, application, tail string) {
+	versionStr, tail := xpath.Shift(tail)
+	if versionStr == "" {
+		http.Error(w, "missing version", http.StatusNotFound)
+		return
+	}
+	version, err := strconv.ParseUint(versionStr, 10, 64)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid version '%s'", versionStr), http.StatusBadRequest)
+		return
+	}
+	function, tail := xpath.Shift(tail)
+	if tail != "/" {
+		http.Error(w, fmt.Sprintf("queued-version does not accept additional path arguments, got: '%s'", tail), http.StatusNotFound)
+		return
+	}
+	switch function {
+	case "":
+		if req.Method != http.MethodDelete {
+			http.Error(w, fmt.Sprintf("unsupported method '%s'", req.Method), http.StatusMethodNotAllowed)
+			return
+		}
+		s.handleCancelQueuedVersion(w, req, environment, application, version)
+	case "deploy":
+		if req.Method != http.MethodPost {
+			http.Error(w, fmt.Sprintf("unsupported method '%s'", req.Method), http.StatusMethodNotAllowed)
+			return
+		}
+		s.handleDeployQueuedVersion(w, req, environment, application, version)
+	default:
+		http.Error(w, fmt.Sprintf("unknown function '%s'", function), http.StatusNotFound)
+	}
+}
+
+func (s Server) handleCancelQueuedVersion(w http.ResponseWriter, req *http.Request, environment, application string, version uint64) {
+	_, err := s.BatchClient.ProcessBatch(req.Context(), &api.BatchRequest{Actions: []*api.BatchAction{
+		{Action: &api.BatchAction_CancelQueuedVersion{
+			CancelQueuedVersion: &api.CancelQueuedVersionRequest{
+				Environment: environment,
+				Application: application,
+				Version:     version,
+			},
+		}},
+	}})
+	if err != nil {
+		handleGRPCError(req.Context(), w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s Server) handleDeployQueuedVersion(w http.ResponseWriter, req *http.Request, environment, application string, version uint64) {
+	if s.checkContentType(w, req) {
+		return
+	}
+	var body deployQueuedVersionRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if body.Reason == "" {
+		http.Error(w, "Please provide a reason in body", http.StatusBadRequest)
+		return
+	}
+	_, err := s.BatchClient.ProcessBatch(req.Context(), &api.BatchRequest{Actions: []*api.BatchAction{
+		{Action: &api.BatchAction_DeployQueuedVersion{
+			DeployQueuedVersion: &api.DeployQueuedVersionRequest{
+				Environment: environment,
+				Application: application,
+				Version:     version,
+				Reason:      body.Reason,
+			},
+		}},
+	}})
+	if err != nil {
+		handleGRPCError(req.Context(), w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,200 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package handler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
+)
+
+type mockQueuedDeploymentsOverviewClient struct {
+	api.OverviewServiceClient
+	request *api.GetQueuedDeploymentsRequest
+}
+
+func (m *mockQueuedDeploymentsOverviewClient) GetQueuedDeployments(_ context.Context, in *api.GetQueuedDeploymentsRequest, _ ...grpc.CallOption) (*api.GetQueuedDeploymentsResponse, error) {
+	m.request = in
+	return &api.GetQueuedDeploymentsResponse{
+		QueuedDeployments: []*api.QueuedDeployment{
+			{
+				Environment: "production",
+				Application: "app1",
+				Version:     2,
+				Reason:      "environment lock: maintenance",
+			},
+		},
+	}, nil
+}
+
+func TestHandleQueuedDeployments(t *testing.T) {
+	tcs := []struct {
+		name               string
+		method             string
+		path               string
+		expectedStatusCode int
+		expectedRequest    *api.GetQueuedDeploymentsRequest
+		expectedBody       string
+	}{
+		{
+			name:               "list all queued deployments",
+			method:             http.MethodGet,
+			path:               "/api/queued-deployments",
+			expectedStatusCode: http.StatusOK,
+			expectedRequest:    &api.GetQueuedDeploymentsRequest{},
+			expectedBody:       `{"queuedDeployments":[{"environment":"production","application":"app1","version":"2","reason":"environment lock: maintenance"}]}` + "\n",
+		},
+		{
+			name:               "filter by environment and application",
+			method:             http.MethodGet,
+			path:               "/api/queued-deployments?environment=production&application=app1",
+			expectedStatusCode: http.StatusOK,
+			expectedRequest: &api.GetQueuedDeploymentsRequest{
+				Environment: "production",
+				Application: "app1",
+			},
+			expectedBody: `{"queuedDeployments":[{"environment":"production","application":"app1","version":"2","reason":"environment lock: maintenance"}]}` + "\n",
+		},
+		{
+			name:               "unsupported method",
+			method:             http.MethodPost,
+			path:               "/api/queued-deployments",
+			expectedStatusCode: http.StatusMethodNotAllowed,
+			expectedBody:       "unsupported method 'POST'\n",
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			overviewClient := &mockQueuedDeploymentsOverviewClient{}
+			s := Server{
+				OverviewClient: overviewClient,
+			}
+			w := httptest.NewRecorder()
+			s.HandleAPI(w, httptest.NewRequest(tc.method, tc.path, nil))
+			if w.Code != tc.expectedStatusCode {
+				t.Errorf("expected status code %d, got %d", tc.expectedStatusCode, w.Code)
+			}
+			body := strings.ReplaceAll(w.Body.String(), ", ", ",")
+			body = strings.ReplaceAll(body, ": ", ":")
+			expectedBody := strings.ReplaceAll(tc.expectedBody, ": ", ":")
+			if diff := cmp.Diff(expectedBody, body); diff != "" {
+				t.Errorf("response mismatch (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedRequest, overviewClient.request, protocmp.Transform()); diff != "" {
+				t.Errorf("request mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestHandleQueuedVersion(t *testing.T) {
+	tcs := []struct {
+		name               string
+		method             string
+		path               string
+		body               string
+		expectedStatusCode int
+		expectedBatch      *api.BatchRequest
+	}{
+		{
+			name:               "cancel a queued version",
+			method:             http.MethodDelete,
+			path:               "/api/environments/production/applications/app1/queued-version/2",
+			expectedStatusCode: http.StatusOK,
+			expectedBatch: &api.BatchRequest{Actions: []*api.BatchAction{
+				{Action: &api.BatchAction_CancelQueuedVersion{
+					CancelQueuedVersion: &api.CancelQueuedVersionRequest{
+						Environment: "production",
+						Application: "app1",
+						Version:     2,
+					},
+				}},
+			}},
+		},
+		{
+			name:               "deploy a queued version",
+			method:             http.MethodPost,
+			path:               "/api/environments/production/applications/app1/queued-version/2/deploy",
+			body:               `{"reason":"hotfix"}`,
+			expectedStatusCode: http.StatusOK,
+			expectedBatch: &api.BatchRequest{Actions: []*api.BatchAction{
+				{Action: &api.BatchAction_DeployQueuedVersion{
+					DeployQueuedVersion: &api.DeployQueuedVersionRequest{
+						Environment: "production",
+						Application: "app1",
+						Version:     2,
+						Reason:      "hotfix",
+					},
+				}},
+			}},
+		},
+		{
+			name:               "deploy requires a reason",
+			method:             http.MethodPost,
+			path:               "/api/environments/production/applications/app1/queued-version/2/deploy",
+			body:               `{}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "invalid version",
+			method:             http.MethodDelete,
+			path:               "/api/environments/production/applications/app1/queued-version/latest",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "cancel with the wrong method",
+			method:             http.MethodPost,
+			path:               "/api/environments/production/applications/app1/queued-version/2",
+			expectedStatusCode: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			batchClient := &mockBatchClient{batchResponse: &api.BatchResponse{}}
+			s := Server{
+				BatchClient: batchClient,
+			}
+			var body io.Reader
+			if tc.body != "" {
+				body = strings.NewReader(tc.body)
+			}
+			req := httptest.NewRequest(tc.method, tc.path, body)
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			s.HandleAPI(w, req)
+			if w.Code != tc.expectedStatusCode {
+				t.Errorf("expected status code %d, got %d: %s", tc.expectedStatusCode, w.Code, w.Body.String())
+			}
+			if diff := cmp.Diff(tc.expectedBatch, batchClient.batchRequest, protocmp.Transform()); diff != "" {
+				t.Errorf("batch request mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -35,12 +35,15 @@ import (
 
 	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
 	"github.com/freiheit-com/kuberpult/pkg/logging"
+	"github.com/freiheit-com/kuberpult/pkg/signing"
 	"github.com/freiheit-com/kuberpult/pkg/types"
 	"github.com/freiheit-com/kuberpult/pkg/valid"
 )
 
 var (
 	manifestFieldRx = regexp.MustCompile(`\Amanifests\[([^]]+)\]\z`)
+	// signatures[<env>] are openpgp signatures, the other types are prefixed with their name
+	signatureFieldRx = regexp.MustCompile(`\A(?:(ssh|cosign)_)?signatures\[([^]]+)\]\z`)
 	// matches hex strings with 7 - 40 chars
 	commitIdRx = regexp.MustCompile(`\A[0-9a-f]{7,40}\z`)
 	// parses anything that looks like "name <mail@host.com>"
@@ -122,6 +125,8 @@ func (s Server) HandleRelease(w http.ResponseWriter, r *http.Request, tail strin
 		IsPrepublish:                   false,
 		DeployToDownstreamEnvironments: []string{},
 		Revision:                       0,
+		Dependencies:                   nil,
+		Signatures:                     nil,
 	}
 	if err := r.ParseMultipartForm(MAXIMUM_MULTIPART_SIZE); err != nil {
 		w.WriteHeader(400)
@@ -308,6 +313,24 @@ func (s Server) HandleRelease(w http.ResponseWriter, r *http.Request, tail strin
 		}
 	}
 
+	if dependencies, ok := form.Value["dependency"]; ok {
+		parsed, err := parseDependencies(dependencies)
+		if err != nil {
+			w.WriteHeader(400)
+			_, _ = fmt.Fprintf(w, "Invalid dependency: %s", err)
+			return
+		}
+		tf.Dependencies = parsed
+	}
+
+	signatures, err := readSignatures(form)
+	if err != nil {
+		w.WriteHeader(400)
+		_, _ = fmt.Fprintf(w, "Invalid signature: %s", err)
+		return
+	}
+	tf.Signatures = signatures
+
 	response, err := s.BatchClient.ProcessBatch(ctx, &api.BatchRequest{Actions: []*api.BatchAction{
 		{
 			Action: &api.BatchAction_CreateRelease{
@@ -336,6 +359,56 @@ func (s Server) HandleRelease(w http.ResponseWriter, r *http.Request, tail strin
 	writeCorrespondingResponse(ctx, w, r, releaseResponse, err)
 }
 
+// parseDependencies parses dependencies of the form "<application>>=<version>"
+func parseDependencies(values []string) ([]*api.ReleaseDependency, error) {
+	result := make([]*api.ReleaseDependency, 0, len(values))
+	for _, value := range values {
+		application, version, found := strings.Cut(value, ">=")
+		if !found {
+			return nil, fmt.Errorf("'%s' must have the form <application>>=<version>", value)
+		}
+		minVersion, err := strconv.ParseUint(strings.TrimSpace(version), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' has an invalid version: %w", value, err)
+		}
+		result = append(result, &api.ReleaseDependency{
+			Application: strings.TrimSpace(application),
+			MinVersion:  minVersion,
+		})
+	}
+	return result, nil
+}
+
+// readSignatures reads the signatures of the manifests, so that they can be checked against the signature policies of the environments
+func readSignatures(form *multipart.Form) ([]*api.ReleaseSignature, error) {
+	var result []*api.ReleaseSignature
+	for k, v := range form.File {
+		match := signatureFieldRx.FindStringSubmatch(k)
+		if match == nil {
+			continue
+		}
+		signatureType := match[1]
+		if signatureType == "" {
+			signatureType = signing.TypeOpenPGP
+		}
+		for _, file := range v {
+			content, err := readMultipartFile(file)
+			if err != nil {
+				return nil, err
+			}
+			if len(content) == 0 {
+				return nil, fmt.Errorf("'%s' is empty", k)
+			}
+			result = append(result, &api.ReleaseSignature{
+				Environment: match[2],
+				Type:        signatureType,
+				Signature:   string(content),
+			})
+		}
+	}
+	return result, nil
+}
+
 func checkParameterCardinality(w http.ResponseWriter, paramName string, paramValues []string) bool {
 	if len(paramValues) != 1 {
 		w.WriteHeader(400)
@@ -370,7 +443,9 @@ func (s Server) handleApiRelease(w http.ResponseWriter, r *http.Request, tail st
 		DeployToDownstreamEnvironments: []string{},
 		Revision:                       0,
 
-		ArgoBracket: "",
+		ArgoBracket:  "",
+		Dependencies: nil,
+		Signatures:   nil,
 	}
 	if err := r.ParseMultipartForm(MAXIMUM_MULTIPART_SIZE); err != nil {
 		w.WriteHeader(400)
@@ -402,7 +477,11 @@ func (s Server) handleApiRelease(w http.ResponseWriter, r *http.Request, tail st
 	for k, v := range form.File {
 		match := manifestFieldRx.FindStringSubmatch(k)
 		if match == nil {
-			// it's not a manifest
+			if signatureFieldRx.MatchString(k) {
+				// signatures are read below
+				continue
+			}
+			// it's neither a manifest nor a signature, that's an error
 			w.WriteHeader(400)
 			_, _ = fmt.Fprintf(w, "Invalid manifest form file: '%s'. Must match '%s'", k, manifestFieldRx)
 			return
@@ -536,6 +615,22 @@ func (s Server) handleApiRelease(w http.ResponseWriter, r *http.Request, tail st
 	if deployToDownstreamEnvironments, ok := form.Value["deploy_to_downstream_environments"]; ok {
 		tf.DeployToDownstreamEnvironments = deployToDownstreamEnvironments
 	}
+	if dependencies, ok := form.Value["dependency"]; ok {
+		parsed, err := parseDependencies(dependencies)
+		if err != nil {
+			w.WriteHeader(400)
+			_, _ = fmt.Fprintf(w, "Provided dependency is not valid: %s", err)
+			return
+		}
+		tf.Dependencies = parsed
+	}
+	signatures, err := readSignatures(form)
+	if err != nil {
+		w.WriteHeader(400)
+		_, _ = fmt.Fprintf(w, "Provided signature is not valid: %s", err)
+		return
+	}
+	tf.Signatures = signatures
 	response, err := s.BatchClient.ProcessBatch(ctx, &api.BatchRequest{Actions: []*api.BatchAction{
 		{
 			Action: &api.BatchAction_CreateRelease{
@@ -610,6 +705,11 @@ func writeCorrespondingResponse(ctx context.Context, w http.ResponseWriter, r *h
 			jsonBlob, err := json.Marshal(firstResponse)
 			writeReleaseResponse(w, r, jsonBlob, err, http.StatusUnprocessableEntity)
 		}
+	case *api.CreateReleaseResponse_InvalidManifest:
+		{
+			jsonBlob, err := json.Marshal(firstResponse)
+			writeReleaseResponse(w, r, jsonBlob, err, http.StatusBadRequest)
+		}
 	default:
 		{
 			jsonBlob, err := json.Marshal(releaseResponse)
@@ -0,0 +1,91 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
+	"github.com/freiheit-com/kuberpult/pkg/logging"
+)
+
+// handleReleaseNotes handles GET /api/release-notes/. The query parameters "application", "environment",
+// "sourceEnvironment", "fromVersion", "toVersion" and "team" are passed on to the ReleaseNotesService.
+// With "format=markdown" only the rendered markdown is returned.
+func (s Server) handleReleaseNotes(ctx context.Context, w http.ResponseWriter, req *http.Request, tail string) {
+	if tail != "/" {
+		http.Error(w, "invalid path", http.StatusNotFound)
+		return
+	}
+	if req.Method != http.MethodGet {
+		http.Error(w, fmt.Sprintf("unsupported method '%s'", req.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	query := req.URL.Query()
+	format := query.Get("format")
+	if format != "" && format != "json" && format != "markdown" {
+		http.Error(w, fmt.Sprintf("invalid format '%s' (expected json or markdown)", format), http.StatusBadRequest)
+		return
+	}
+	request := &api.GetReleaseNotesRequest{
+		Application:       query.Get("application"),
+		Environment:       query.Get("environment"),
+		FromVersion:       0,
+		ToVersion:         0,
+		SourceEnvironment: query.Get("sourceEnvironment"),
+		Team:              query.Get("team"),
+	}
+	for name, target := range map[string]*uint64{"fromVersion": &request.FromVersion, "toVersion": &request.ToVersion} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		version, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid %s '%s'", name, value), http.StatusBadRequest)
+			return
+		}
+		*target = version
+	}
+	resp, err := s.ReleaseNotesClient.GetReleaseNotes(ctx, request)
+	if err != nil {
+		handleGRPCError(ctx, w, err)
+		return
+	}
+	if format == "markdown" {
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(resp.Markdown))
+		return
+	}
+	jsonResponse, err := protojson.Marshal(resp)
+	if err != nil {
+		logging.Error(ctx, "Failed to marshal response of release notes", zap.Error(err))
+		http.Error(w, fmt.Sprintf("failed to marshal response of release notes: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(jsonResponse)
+	_, _ = w.Write([]byte("\n"))
+}
@@ -0,0 +1,145 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
+)
+
+type mockReleaseNotesClient struct {
+	request *api.GetReleaseNotesRequest
+}
+
+func (m *mockReleaseNotesClient) GetReleaseNotes(_ context.Context, in *api.GetReleaseNotesRequest, _ ...grpc.CallOption) (*api.GetReleaseNotesResponse, error) {
+	m.request = in
+	return &api.GetReleaseNotesResponse{
+		Apps: []*api.AppReleaseNotes{
+			{
+				Application: "payments",
+				Environment: "production",
+				FromVersion: 2,
+				ToVersion:   3,
+				Releases: []*api.Release{
+					{Version: 3, SourceMessage: "Fix rounding (#42)", PrNumber: "42"},
+				},
+			},
+		},
+		Markdown: "## payments on production (version 2 → 3)\n\n- **3** Fix rounding (#42)\n",
+	}, nil
+}
+
+func TestHandleReleaseNotes(t *testing.T) {
+	tcs := []struct {
+		name               string
+		method             string
+		path               string
+		expectedStatusCode int
+		expectedRequest    *api.GetReleaseNotesRequest
+		expectedBody       string
+	}{
+		{
+			name:               "application on environment as json",
+			method:             http.MethodGet,
+			path:               "/api/release-notes?application=payments&environment=production",
+			expectedStatusCode: http.StatusOK,
+			expectedRequest: &api.GetReleaseNotesRequest{
+				Application: "payments",
+				Environment: "production",
+			},
+			expectedBody: `{"apps":[{"application":"payments","environment":"production","fromVersion":"2","toVersion":"3","releases":[{"version":"3","sourceMessage":"Fix rounding (#42)","prNumber":"42"}]}],"markdown":"## payments on production (version 2 → 3)\n\n- **3** Fix rounding (#42)\n"}` + "\n",
+		},
+		{
+			name:               "promotion as markdown",
+			method:             http.MethodGet,
+			path:               "/api/release-notes?environment=production&sourceEnvironment=staging&team=finance&format=markdown",
+			expectedStatusCode: http.StatusOK,
+			expectedRequest: &api.GetReleaseNotesRequest{
+				Environment:       "production",
+				SourceEnvironment: "staging",
+				Team:              "finance",
+			},
+			expectedBody: "## payments on production (version 2 → 3)\n\n- **3** Fix rounding (#42)\n",
+		},
+		{
+			name:               "two versions",
+			method:             http.MethodGet,
+			path:               "/api/release-notes?application=payments&fromVersion=2&toVersion=3",
+			expectedStatusCode: http.StatusOK,
+			expectedRequest: &api.GetReleaseNotesRequest{
+				Application: "payments",
+				FromVersion: 2,
+				ToVersion:   3,
+			},
+			expectedBody: `{"apps":[{"application":"payments","environment":"production","fromVersion":"2","toVersion":"3","releases":[{"version":"3","sourceMessage":"Fix rounding (#42)","prNumber":"42"}]}],"markdown":"## payments on production (version 2 → 3)\n\n- **3** Fix rounding (#42)\n"}` + "\n",
+		},
+		{
+			name:               "invalid version",
+			method:             http.MethodGet,
+			path:               "/api/release-notes?application=payments&toVersion=latest",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       "invalid toVersion 'latest'\n",
+		},
+		{
+			name:               "invalid format",
+			method:             http.MethodGet,
+			path:               "/api/release-notes?application=payments&format=html",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       "invalid format 'html' (expected json or markdown)\n",
+		},
+		{
+			name:               "unsupported method",
+			method:             http.MethodPost,
+			path:               "/api/release-notes",
+			expectedStatusCode: http.StatusMethodNotAllowed,
+			expectedBody:       "unsupported method 'POST'\n",
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			releaseNotesClient := &mockReleaseNotesClient{}
+			s := Server{
+				ReleaseNotesClient: releaseNotesClient,
+			}
+			w := httptest.NewRecorder()
+			s.HandleAPI(w, httptest.NewRequest(tc.method, tc.path, nil))
+			if w.Code != tc.expectedStatusCode {
+				t.Errorf("expected status code %d, got %d", tc.expectedStatusCode, w.Code)
+			}
+			body := strings.ReplaceAll(w.Body.String(), ", ", ",")
+			body = strings.ReplaceAll(body, ": ", ":")
+			expectedBody := strings.ReplaceAll(tc.expectedBody, ": ", ":")
+			if diff := cmp.Diff(expectedBody, body); diff != "" {
+				t.Errorf("response mismatch (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedRequest, releaseNotesClient.request, protocmp.Transform()); diff != "" {
+				t.Errorf("request mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -122,7 +122,12 @@ func (s Server) handleReleaseTrainExecution(w http.ResponseWriter, req *http.Req
 		handleGRPCError(req.Context(), w, err)
 		return
 	}
-	jsonStr, err := json.Marshal(response.Results[0].GetReleaseTrain())
+	var result any = response.Results[0].GetReleaseTrain()
+	if approval := response.Results[0].GetDeploymentApproval(); approval != nil {
+		// the release train requires an approval, the release notes are in the approval
+		result = approval
+	}
+	jsonStr, err := json.Marshal(result)
 	if err != nil {
 		return
 	}
@@ -176,12 +181,13 @@ func (s Server) handleAPIReleaseTrainExecution(w http.ResponseWriter, req *http.
 	}
 
 	tf := &api.ReleaseTrainRequest{
-		CommitHash: commitHash,
-		Target:     target,
-		Team:       teamParam,
-		TargetType: TargetType,
-		CiLink:     "",
-		GitTag:     gitTagParam,
+		CommitHash:          commitHash,
+		Target:              target,
+		Team:                teamParam,
+		TargetType:          TargetType,
+		CiLink:              "",
+		GitTag:              gitTagParam,
+		IncludeReleaseNotes: queryParams.Get("releaseNotes") == "true",
 	}
 	if req.Body != nil {
 		type releaseTrainBody struct {
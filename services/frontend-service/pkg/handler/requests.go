@@ -27,3 +27,7 @@ type releaseTrainRequest struct {
 	Signature string `json:"signature,omitempty"`
 	CiLink    string `json:"ciLink,omitempty"`
 }
+
+type deployQueuedVersionRequest struct {
+	Reason string `json:"reason"`
+}
@@ -61,6 +61,7 @@ func authorize(ctx context.Context, jwks *keyfunc.JWKS, clientId string, tenantI
 			DexAuthContext: nil,
 			Email:          claims["email"].(string),
 			Name:           claims["name"].(string),
+			Identity:       "",
 		}
 	}
 
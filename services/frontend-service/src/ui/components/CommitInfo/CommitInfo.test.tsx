@@ -145,6 +145,20 @@ test('CommitInfo component renders commit info when the response is valid', () =
                             },
                         },
                     },
+                    {
+                        uuid: '00000000-0000-0000-0000-000000000007',
+                        createdAt: new Date('2024-02-13T09:46:00Z'),
+                        eventType: {
+                            $case: 'autoRollbackEvent',
+                            autoRollbackEvent: {
+                                application: 'app',
+                                environment: 'dev',
+                                rolledBackVersion: 2,
+                                restoredVersion: 1,
+                                reason: 'the rollout of version 2 stayed in error for more than 10m0s',
+                            },
+                        },
+                    },
                 ],
             },
             expectedTitle: 'Commit: tomato',
@@ -178,6 +192,11 @@ test('CommitInfo component renders commit info when the response is valid', () =
                         'Application app was blocked from deploying due to a team lock with message "locked"',
                         'dev',
                     ],
+                    [
+                        '2024-02-13T09:46:00',
+                        'Application app was rolled back automatically from version 2 to version 1: the rollout of version 2 stayed in error for more than 10m0s',
+                        'dev',
+                    ],
                 ],
             },
         },
@@ -254,6 +254,24 @@ const eventDescription = (event: Event): [JSX.Element, string] => {
                 </span>,
                 inner.environment,
             ];
+        case 'freezePreventedDeploymentEvent':
+            const freeze = tp.freezePreventedDeploymentEvent;
+            return [
+                <span>
+                    Application <b>{freeze.application}</b> was blocked from deploying due to the freeze{' '}
+                    <b>{freeze.freezeId}</b> with message "{freeze.freezeMessage}"
+                </span>,
+                freeze.environment,
+            ];
+        case 'autoRollbackEvent':
+            const rollback = tp.autoRollbackEvent;
+            return [
+                <span>
+                    Application <b>{rollback.application}</b> was rolled back automatically from version{' '}
+                    {rollback.rolledBackVersion} to version {rollback.restoredVersion}: {rollback.reason}
+                </span>,
+                rollback.environment,
+            ];
         case 'replacedByEvent':
             return [
                 <span>
@@ -265,6 +283,32 @@ const eventDescription = (event: Event): [JSX.Element, string] => {
                 </span>,
                 tp.replacedByEvent.environment,
             ];
+        case 'lockCreatedEvent':
+            return [
+                <span>
+                    A {tp.lockCreatedEvent.lockType} lock was created with message "{tp.lockCreatedEvent.message}"
+                </span>,
+                tp.lockCreatedEvent.environment,
+            ];
+        case 'lockDeletedEvent':
+            return [<span>A {tp.lockDeletedEvent.lockType} lock was deleted</span>, tp.lockDeletedEvent.environment];
+        case 'environmentChangedEvent':
+            return [
+                <span>
+                    Environment <b>{tp.environmentChangedEvent.environment}</b> was{' '}
+                    {tp.environmentChangedEvent.change}
+                </span>,
+                tp.environmentChangedEvent.environment,
+            ];
+        case 'rolloutStatusChangedEvent':
+            const rollout = tp.rolloutStatusChangedEvent;
+            return [
+                <span>
+                    Rollout of application <b>{rollout.application}</b> in version {rollout.version} is now{' '}
+                    <b>{rollout.status}</b>
+                </span>,
+                rollout.environment,
+            ];
     }
 };
 
@@ -276,6 +320,8 @@ const lockTypeName = (tp: LockPreventedDeploymentEvent_LockType): string => {
             return 'an environment lock';
         case LockPreventedDeploymentEvent_LockType.LOCK_TYPE_TEAM:
             return 'a team lock';
+        case LockPreventedDeploymentEvent_LockType.LOCK_TYPE_GATE:
+            return 'a deployment gate';
         case LockPreventedDeploymentEvent_LockType.LOCK_TYPE_UNKNOWN:
         case LockPreventedDeploymentEvent_LockType.UNRECOGNIZED:
             return 'an unknown lock';
@@ -103,6 +103,9 @@ const EnvPrognosisOutcomeSkipped: React.FC<{ skipCause: ReleaseTrainEnvSkipCause
         case ReleaseTrainEnvSkipCause.ENV_IS_LOCKED:
             return <p>Release train on this environment is skipped because it is locked.</p>;
 
+        case ReleaseTrainEnvSkipCause.ENV_IS_FROZEN:
+            return <p>Release train on this environment is skipped because it is frozen.</p>;
+
         case ReleaseTrainEnvSkipCause.ENV_HAS_BOTH_UPSTREAM_LATEST_AND_UPSTREAM_ENV:
             return (
                 <p>
@@ -211,6 +214,27 @@ const AppPrognosisOutcomeSkipCell: React.FC<{ skipCause: ReleaseTrainAppSkipCaus
             return <p>Application release is skipped due to a team lock</p>;
         case ReleaseTrainAppSkipCause.NO_TEAM_PERMISSION:
             return <p>Application release is skipped because the user is not on the team of the application</p>;
+        case ReleaseTrainAppSkipCause.APP_UPSTREAM_VERSION_TOO_YOUNG:
+            return (
+                <p>
+                    Application release is skipped because the version has not been in the upstream environment for the
+                    soak time yet.
+                </p>
+            );
+        case ReleaseTrainAppSkipCause.APP_DEPENDENCY_NOT_MET:
+            return (
+                <p>
+                    Application release is skipped because it depends on a version of another application that is
+                    neither deployed in the environment nor part of the release train.
+                </p>
+            );
+        case ReleaseTrainAppSkipCause.APP_SIGNATURE_POLICY_NOT_MET:
+            return (
+                <p>
+                    Application release is skipped because its manifest is not signed by the keys that the environment
+                    requires.
+                </p>
+            );
         case ReleaseTrainAppSkipCause.UNRECOGNIZED:
         default:
             return <p>Application release it skipped due to an unrecognized reason</p>;
@@ -14,7 +14,7 @@ along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>
 
 Copyright freiheit.com*/
 import * as React from 'react';
-import { Application, UnusualDeploymentOrder, UpstreamNotDeployed, Warning } from '../../../api/api';
+import { Application, DependencyNotMet, UnusualDeploymentOrder, UpstreamNotDeployed, Warning } from '../../../api/api';
 import { updateWarnings } from '../../utils/store';
 
 export const WarningBoxes: React.FC<{ application: Application | undefined }> = (props) => {
@@ -75,6 +75,35 @@ export const WarningUpstreamNotDeployed: React.FC<{ warning: UpstreamNotDeployed
     );
 };
 
+export const WarningDependencyNotMet: React.FC<{ warning: DependencyNotMet }> = (props) => {
+    const warning = props.warning;
+    const deployed =
+        warning.dependencyDeployedVersion === 0
+            ? 'is not deployed there'
+            : 'is deployed in version ' + String(warning.dependencyDeployedVersion);
+    const tooltip =
+        'Version ' +
+        String(warning.thisVersion) +
+        ' requires ' +
+        warning.dependencyApplication +
+        ' in version ' +
+        String(warning.dependencyMinVersion) +
+        ' or newer on ' +
+        warning.thisEnvironment +
+        '. Suggestion: Deploy a newer version of ' +
+        warning.dependencyApplication +
+        ' to ' +
+        warning.thisEnvironment +
+        '.';
+
+    return (
+        <div className={'warning'} title={tooltip}>
+            <b>Warning: {warning.dependencyApplication}</b> {deployed} on <b>{warning.thisEnvironment}</b>, but version{' '}
+            {warning.dependencyMinVersion} is required! ⓘ
+        </div>
+    );
+};
+
 export const WarningBox: React.FC<{ warning: Warning }> = (props) => {
     const { warning } = props;
     switch (warning.warningType?.$case) {
@@ -82,6 +111,8 @@ export const WarningBox: React.FC<{ warning: Warning }> = (props) => {
             return <WarningBoxUnusualDeploymentOrder warning={warning.warningType.unusualDeploymentOrder} />;
         case 'upstreamNotDeployed':
             return <WarningUpstreamNotDeployed warning={warning.warningType.upstreamNotDeployed} />;
+        case 'dependencyNotMet':
+            return <WarningDependencyNotMet warning={warning.warningType.dependencyNotMet} />;
         default:
             // eslint-disable-next-line no-console
             console.error('Warning type not recognized: ', JSON.stringify(warning));
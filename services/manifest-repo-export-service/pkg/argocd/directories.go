@@ -37,3 +37,46 @@ func BracketPaths(env types.EnvName, bracket types.ArgoBracketName, appName type
 		BracketPath:      manifestFilename,
 	}
 }
+
+// WavesDirectory returns the directory that contains the manifests of an app for each concrete environment
+// of an active/active environment that is rolled out in waves
+func WavesDirectory(env types.EnvName, appName types.AppName) string {
+	return filepath.Join("environments", string(env), "applications", string(appName), "waves")
+}
+
+// WaveManifestDirectory returns the directory of the manifests of an app on one concrete environment
+// of an active/active environment that is rolled out in waves
+func WaveManifestDirectory(env types.EnvName, appName types.AppName, concreteEnv string) string {
+	return filepath.Join(WavesDirectory(env, appName), concreteEnv, "manifests")
+}
+
+// ArgoCdRootFile returns the file that the Argo CD applications of an environment are rendered to
+func ArgoCdRootFile(fullyQualifiedEnvName string) string {
+	return filepath.Join("argocd", string(V1Alpha1), fmt.Sprintf("%s.yaml", fullyQualifiedEnvName))
+}
+
+// FluxRootFile returns the file that the root kustomization of an environment and its git repository are rendered to.
+// The root kustomizations of all environments are in the same directory,
+// so that Flux can be bootstrapped with one kustomization that points to it.
+func FluxRootFile(fullyQualifiedEnvName string) string {
+	return filepath.Join("flux", string(FluxV1), "root", fmt.Sprintf("%s.yaml", fullyQualifiedEnvName))
+}
+
+// FluxKustomizationsDirectory returns the directory that the root kustomization of an environment points to
+func FluxKustomizationsDirectory(fullyQualifiedEnvName string) string {
+	return filepath.Join("flux", string(FluxV1), "environments", fullyQualifiedEnvName)
+}
+
+// FluxKustomizationsFile returns the file that the kustomizations of the apps of an environment are rendered to
+func FluxKustomizationsFile(fullyQualifiedEnvName string) string {
+	return filepath.Join(FluxKustomizationsDirectory(fullyQualifiedEnvName), "kustomizations.yaml")
+}
+
+// RootFiles returns all files that the root app of an environment can be rendered to, no matter if it uses Argo CD or Flux
+func RootFiles(fullyQualifiedEnvName string) []string {
+	return []string{
+		ArgoCdRootFile(fullyQualifiedEnvName),
+		FluxRootFile(fullyQualifiedEnvName),
+		FluxKustomizationsFile(fullyQualifiedEnvName),
+	}
+}
@@ -0,0 +1,208 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package argocd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"sigs.k8s.io/yaml"
+
+	"github.com/freiheit-com/kuberpult/pkg/config"
+	"github.com/freiheit-com/kuberpult/services/manifest-repo-export-service/pkg/argocd/fluxv1"
+)
+
+// RenderFluxV1Root returns the git repository of an environment and its root kustomization,
+// which is the Flux equivalent of the Argo CD root app: it points to the kustomizations of the apps.
+func RenderFluxV1Root(gitUrl string, gitBranch string, info *EnvironmentInfo) ([]byte, error) {
+	fluxCfg := info.ArgoCDConfig.FluxCd
+	interval, err := fluxInterval(fluxCfg)
+	if err != nil {
+		return nil, err
+	}
+	name := info.GetFullyQualifiedName()
+	repository := fluxv1.GitRepository{
+		TypeMeta: fluxv1.GitRepositoryTypeMeta,
+		ObjectMeta: fluxv1.ObjectMeta{
+			Name:        name,
+			Namespace:   fluxNamespace(fluxCfg),
+			Annotations: nil,
+			Labels:      nil,
+		},
+		Spec: fluxv1.GitRepositorySpec{
+			URL:       gitUrl,
+			SecretRef: nil,
+			Interval:  interval,
+			Reference: &fluxv1.GitRepositoryRef{
+				Branch: gitBranch,
+			},
+		},
+	}
+	if fluxCfg.SecretRef != "" {
+		repository.Spec.SecretRef = &fluxv1.LocalObjectReference{
+			Name: fluxCfg.SecretRef,
+		}
+	}
+	root := fluxv1.Kustomization{
+		TypeMeta: fluxv1.KustomizationTypeMeta,
+		ObjectMeta: fluxv1.ObjectMeta{
+			Name:        name,
+			Namespace:   fluxNamespace(fluxCfg),
+			Annotations: nil,
+			Labels:      nil,
+		},
+		Spec: fluxv1.KustomizationSpec{
+			Interval: interval,
+			Path:     manifestPathToFluxFormat(FluxKustomizationsDirectory(name)),
+			// Apps that are removed from the environment are removed from the cluster, like with the Argo CD root app
+			Prune:              true,
+			SourceRef:          gitRepositoryRef(name),
+			TargetNamespace:    "",
+			KubeConfig:         nil,
+			ServiceAccountName: "",
+		},
+	}
+	buf := []string{}
+	for _, obj := range []any{&repository, &root} {
+		content, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, string(content))
+	}
+	return ([]byte)(strings.Join(buf, "---\n")), nil
+}
+
+// RenderFluxV1 returns one Flux kustomization per app of an environment
+func RenderFluxV1(ctx context.Context, info *EnvironmentInfo, appsData []AppData, pointToBrackets bool, allowBracketMoves bool) ([]byte, error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "RenderFluxV1")
+	defer span.Finish()
+	buf := []string{}
+	for _, appData := range appsData {
+		kustomization, err := RenderFluxKustomization(ctx, info, appData, pointToBrackets, allowBracketMoves)
+		if err != nil {
+			return nil, err
+		}
+		if kustomization == "" {
+			continue
+		}
+		buf = append(buf, kustomization)
+	}
+	return ([]byte)(strings.Join(buf, "---\n")), nil
+}
+
+// RenderFluxKustomization returns the Flux kustomization of one app of an environment,
+// or an empty string if the app has no manifests to point to.
+func RenderFluxKustomization(_ context.Context, info *EnvironmentInfo, appData AppData, pointToBrackets bool, allowBracketMoves bool) (string, error) {
+	cfg := info.ArgoCDConfig
+	interval, err := fluxInterval(cfg.FluxCd)
+	if err != nil {
+		return "", err
+	}
+	manifestPaths, err := appManifestPaths(info, appData, pointToBrackets)
+	if err != nil {
+		return "", err
+	}
+	if len(manifestPaths) == 0 {
+		// a kustomization without a path would apply the whole repository
+		return "", nil
+	}
+	if len(manifestPaths) > 1 {
+		return "", fmt.Errorf("found too many (%d) manifest paths for flux kustomization %s", len(manifestPaths), appData.ArgoAppName)
+	}
+	name := appData.ArgoAppName
+	teamNames := []string{}
+	teamNames = append(teamNames, appData.ReferencedAppTeams...)
+	teamsAnnotation := generateTeamNameAnnotationValue(teamNames)
+	annotations := map[string]string{}
+	for k, v := range cfg.ApplicationAnnotations {
+		annotations[k] = v
+	}
+	addKuberpultAnnotations(annotations, info, name, teamsAnnotation)
+	targetNamespace := ""
+	if cfg.Destination.Namespace != nil {
+		targetNamespace = *cfg.Destination.Namespace
+	} else if cfg.Destination.ApplicationNamespace != nil {
+		targetNamespace = *cfg.Destination.ApplicationNamespace
+	}
+	var kubeConfig *fluxv1.KubeConfigReference
+	if cfg.FluxCd.KubeConfigSecretRef != "" {
+		kubeConfig = &fluxv1.KubeConfigReference{
+			SecretRef: fluxv1.SecretKeyReference{
+				Name: cfg.FluxCd.KubeConfigSecretRef,
+			},
+		}
+	}
+	kustomization := fluxv1.Kustomization{
+		TypeMeta: fluxv1.KustomizationTypeMeta,
+		ObjectMeta: fluxv1.ObjectMeta{
+			Name:        fmt.Sprintf("%s-%s", info.GetFullyQualifiedName(), name),
+			Namespace:   fluxNamespace(cfg.FluxCd),
+			Annotations: annotations,
+			Labels: map[string]string{
+				"com.freiheit.kuberpult/teams": teamsAnnotation,
+			},
+		},
+		Spec: fluxv1.KustomizationSpec{
+			Interval: interval,
+			Path:     manifestPathToFluxFormat(manifestPaths[0]),
+			// When bracket moves are allowed we render with prune=false, so Flux does not
+			// delete resources left behind by a move.
+			Prune:              !allowBracketMoves,
+			SourceRef:          gitRepositoryRef(info.GetFullyQualifiedName()),
+			TargetNamespace:    targetNamespace,
+			KubeConfig:         kubeConfig,
+			ServiceAccountName: cfg.FluxCd.ServiceAccountName,
+		},
+	}
+	content, err := yaml.Marshal(&kustomization)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+func fluxNamespace(cfg *config.EnvironmentConfigFluxCd) string {
+	if cfg.Namespace == "" {
+		return config.DefaultFluxNamespace
+	}
+	return cfg.Namespace
+}
+
+func fluxInterval(cfg *config.EnvironmentConfigFluxCd) (string, error) {
+	if _, err := cfg.IntervalDuration(); err != nil {
+		return "", err
+	}
+	if cfg.Interval == "" {
+		return config.DefaultFluxInterval, nil
+	}
+	return cfg.Interval, nil
+}
+
+func gitRepositoryRef(name string) fluxv1.CrossNamespaceSourceReference {
+	return fluxv1.CrossNamespaceSourceReference{
+		Kind: fluxv1.GitRepositoryTypeMeta.Kind,
+		Name: name,
+	}
+}
+
+func manifestPathToFluxFormat(path string) string {
+	// paths are relative to the root of the git repository and by convention start with "./"
+	return "./" + path
+}
@@ -0,0 +1,339 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package argocd
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/freiheit-com/kuberpult/pkg/config"
+	"github.com/freiheit-com/kuberpult/pkg/conversion"
+)
+
+func TestRenderFluxV1Root(t *testing.T) {
+	tcs := []struct {
+		Name           string
+		FluxCd         *config.EnvironmentConfigFluxCd
+		IsAAEnv        bool
+		ExpectedResult string
+		ExpectedError  string
+	}{
+		{
+			Name:   "defaults",
+			FluxCd: &config.EnvironmentConfigFluxCd{},
+			ExpectedResult: `apiVersion: source.toolkit.fluxcd.io/v1
+kind: GitRepository
+metadata:
+  name: test-env
+  namespace: flux-system
+spec:
+  interval: 5m
+  ref:
+    branch: branch-name
+  url: https://git.example.com/
+---
+apiVersion: kustomize.toolkit.fluxcd.io/v1
+kind: Kustomization
+metadata:
+  name: test-env
+  namespace: flux-system
+spec:
+  interval: 5m
+  path: ./flux/v1/environments/test-env
+  prune: true
+  sourceRef:
+    kind: GitRepository
+    name: test-env
+`,
+		},
+		{
+			Name:    "active/active environment with secret",
+			IsAAEnv: true,
+			FluxCd: &config.EnvironmentConfigFluxCd{
+				Namespace: "flux",
+				Interval:  "1m",
+				SecretRef: "git-credentials",
+			},
+			ExpectedResult: `apiVersion: source.toolkit.fluxcd.io/v1
+kind: GitRepository
+metadata:
+  name: AA-test-env-de-1
+  namespace: flux
+spec:
+  interval: 1m
+  ref:
+    branch: branch-name
+  secretRef:
+    name: git-credentials
+  url: https://git.example.com/
+---
+apiVersion: kustomize.toolkit.fluxcd.io/v1
+kind: Kustomization
+metadata:
+  name: AA-test-env-de-1
+  namespace: flux
+spec:
+  interval: 1m
+  path: ./flux/v1/environments/AA-test-env-de-1
+  prune: true
+  sourceRef:
+    kind: GitRepository
+    name: AA-test-env-de-1
+`,
+		},
+		{
+			Name: "invalid interval",
+			FluxCd: &config.EnvironmentConfigFluxCd{
+				Interval: "sometimes",
+			},
+			ExpectedError: `invalid flux interval "sometimes": time: invalid duration "sometimes"`,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			info := &EnvironmentInfo{
+				ArgoCDConfig: &config.EnvironmentConfigArgoCd{
+					ConcreteEnvName: "de-1",
+					FluxCd:          tc.FluxCd,
+				},
+				CommonPrefix:          "AA",
+				ParentEnvironmentName: "test-env",
+				IsAAEnv:               tc.IsAAEnv,
+			}
+			actual, err := RenderFluxV1Root("https://git.example.com/", "branch-name", info)
+			if tc.ExpectedError != "" {
+				if err == nil || err.Error() != tc.ExpectedError {
+					t.Fatalf("expected error %q, got %v", tc.ExpectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.ExpectedResult, string(actual)); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRenderFluxV1(t *testing.T) {
+	tcs := []struct {
+		Name              string
+		ArgoCd            *config.EnvironmentConfigArgoCd
+		AppData           []AppData
+		PointToBrackets   bool
+		AllowBracketMoves bool
+		ExpectedResult    string
+	}{
+		{
+			Name: "no apps",
+			ArgoCd: &config.EnvironmentConfigArgoCd{
+				FluxCd: &config.EnvironmentConfigFluxCd{},
+			},
+			AppData:        nil,
+			ExpectedResult: ``,
+		},
+		{
+			Name: "apps with target namespace and remote cluster",
+			ArgoCd: &config.EnvironmentConfigArgoCd{
+				Destination: config.ArgoCdDestination{
+					Name:                 "cluster",
+					Namespace:            nil,
+					ApplicationNamespace: conversion.FromString("apps"),
+				},
+				ApplicationAnnotations: map[string]string{
+					"example.com/owner": "platform",
+				},
+				FluxCd: &config.EnvironmentConfigFluxCd{
+					Interval:            "10m",
+					KubeConfigSecretRef: "cluster-kubeconfig",
+					ServiceAccountName:  "kuberpult",
+				},
+			},
+			AppData: []AppData{
+				{
+					ArgoAppName:        "app1",
+					ReferencedAppTeams: []string{"team1"},
+				},
+				{
+					ArgoAppName:        "app2",
+					ReferencedAppTeams: []string{"team2"},
+					ManifestPath:       "environments/test-env/applications/app2/waves/de-1/manifests",
+				},
+			},
+			ExpectedResult: `apiVersion: kustomize.toolkit.fluxcd.io/v1
+kind: Kustomization
+metadata:
+  annotations:
+    com.freiheit.kuberpult/aa-parent-environment: test-env
+    com.freiheit.kuberpult/application: app1
+    com.freiheit.kuberpult/environment: test-env
+    com.freiheit.kuberpult/teams: team1
+    example.com/owner: platform
+  labels:
+    com.freiheit.kuberpult/teams: team1
+  name: test-env-app1
+  namespace: flux-system
+spec:
+  interval: 10m
+  kubeConfig:
+    secretRef:
+      name: cluster-kubeconfig
+  path: ./environments/test-env/applications/app1/manifests
+  prune: true
+  serviceAccountName: kuberpult
+  sourceRef:
+    kind: GitRepository
+    name: test-env
+  targetNamespace: apps
+---
+apiVersion: kustomize.toolkit.fluxcd.io/v1
+kind: Kustomization
+metadata:
+  annotations:
+    com.freiheit.kuberpult/aa-parent-environment: test-env
+    com.freiheit.kuberpult/application: app2
+    com.freiheit.kuberpult/environment: test-env
+    com.freiheit.kuberpult/teams: team2
+    example.com/owner: platform
+  labels:
+    com.freiheit.kuberpult/teams: team2
+  name: test-env-app2
+  namespace: flux-system
+spec:
+  interval: 10m
+  kubeConfig:
+    secretRef:
+      name: cluster-kubeconfig
+  path: ./environments/test-env/applications/app2/waves/de-1/manifests
+  prune: true
+  serviceAccountName: kuberpult
+  sourceRef:
+    kind: GitRepository
+    name: test-env
+  targetNamespace: apps
+`,
+		},
+		{
+			Name: "brackets with bracket moves",
+			ArgoCd: &config.EnvironmentConfigArgoCd{
+				FluxCd: &config.EnvironmentConfigFluxCd{},
+			},
+			AppData: []AppData{
+				{
+					ArgoAppName:        "bracket1",
+					ReferencedAppTeams: []string{"team2", "team1"},
+				},
+				{
+					// apps without manifests do not get a kustomization
+					ArgoAppName:        "bracket2",
+					ReferencedAppTeams: nil,
+				},
+			},
+			PointToBrackets:   true,
+			AllowBracketMoves: true,
+			ExpectedResult: `apiVersion: kustomize.toolkit.fluxcd.io/v1
+kind: Kustomization
+metadata:
+  annotations:
+    com.freiheit.kuberpult/aa-parent-environment: test-env
+    com.freiheit.kuberpult/application: bracket1
+    com.freiheit.kuberpult/environment: test-env
+    com.freiheit.kuberpult/teams: team1_team2
+  labels:
+    com.freiheit.kuberpult/teams: team1_team2
+  name: test-env-bracket1
+  namespace: flux-system
+spec:
+  interval: 5m
+  path: ./environments/test-env/brackets/bracket1
+  prune: false
+  sourceRef:
+    kind: GitRepository
+    name: test-env
+`,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			info := &EnvironmentInfo{
+				ArgoCDConfig:          tc.ArgoCd,
+				ParentEnvironmentName: "test-env",
+			}
+			actual, err := RenderFluxV1(context.Background(), info, tc.AppData, tc.PointToBrackets, tc.AllowBracketMoves)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.ExpectedResult, string(actual)); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRenderSelectsFlux(t *testing.T) {
+	tcs := []struct {
+		Name          string
+		FluxCd        *config.EnvironmentConfigFluxCd
+		ExpectedFiles []string
+	}{
+		{
+			Name:          "argo cd",
+			FluxCd:        nil,
+			ExpectedFiles: []string{"argocd/v1alpha1/test-env.yaml"},
+		},
+		{
+			Name:   "flux",
+			FluxCd: &config.EnvironmentConfigFluxCd{},
+			ExpectedFiles: []string{
+				"flux/v1/environments/test-env/kustomizations.yaml",
+				"flux/v1/root/test-env.yaml",
+			},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			info := &EnvironmentInfo{
+				ArgoCDConfig: &config.EnvironmentConfigArgoCd{
+					FluxCd: tc.FluxCd,
+				},
+				ParentEnvironmentName: "test-env",
+			}
+			actual, err := Render(context.Background(), "https://git.example.com/", "branch-name", info, nil, &RenderOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			files := []string{}
+			for file := range actual {
+				files = append(files, file)
+			}
+			slices.Sort(files)
+			if diff := cmp.Diff(tc.ExpectedFiles, files); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+			for _, file := range files {
+				if !slices.Contains(RootFiles("test-env"), file) {
+					t.Errorf("file %s is not one of the root files of the environment", file)
+				}
+			}
+		})
+	}
+}
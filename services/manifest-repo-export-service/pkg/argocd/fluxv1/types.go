@@ -0,0 +1,108 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package fluxv1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// See https://pkg.go.dev/k8s.io/apimachinery/pkg/apis/meta/v1#ObjectMeta for more fields, if necessary
+type ObjectMeta struct {
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+}
+
+// This file is a subset of https://github.com/fluxcd/kustomize-controller/blob/v1.3.0/api/v1/kustomization_types.go
+// and https://github.com/fluxcd/source-controller/blob/v1.3.0/api/v1/gitrepository_types.go
+// Importing the flux projects directly would drag in a huge number of dependencies.
+var KustomizationTypeMeta metav1.TypeMeta = metav1.TypeMeta{
+	APIVersion: "kustomize.toolkit.fluxcd.io/v1",
+	Kind:       "Kustomization",
+}
+
+type Kustomization struct {
+	metav1.TypeMeta `json:",inline"`
+	ObjectMeta      `json:"metadata"`
+	Spec            KustomizationSpec `json:"spec"`
+}
+
+type KustomizationSpec struct {
+	// Interval at which to reconcile the Kustomization, e.g. "5m"
+	Interval string `json:"interval"`
+	// Path to the directory containing the manifests, relative to the root of the source
+	Path string `json:"path,omitempty"`
+	// Prune enables garbage collection of the resources that are no longer part of the source
+	Prune bool `json:"prune"`
+	// SourceRef is the reference of the source where the manifests are
+	SourceRef CrossNamespaceSourceReference `json:"sourceRef"`
+	// TargetNamespace sets or overrides the namespace of all resources
+	TargetNamespace string `json:"targetNamespace,omitempty"`
+	// KubeConfig is the reference of the kubeconfig of a remote cluster, the resources are applied
+	// to the cluster of the controller if omitted
+	KubeConfig *KubeConfigReference `json:"kubeConfig,omitempty"`
+	// ServiceAccountName is the service account that is impersonated when applying the resources
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+}
+
+type CrossNamespaceSourceReference struct {
+	// Kind of the source, e.g. "GitRepository"
+	Kind string `json:"kind"`
+	// Name of the source
+	Name string `json:"name"`
+}
+
+type KubeConfigReference struct {
+	// SecretRef is the secret containing the kubeconfig under the key "value" or "value.yaml"
+	SecretRef SecretKeyReference `json:"secretRef"`
+}
+
+type SecretKeyReference struct {
+	Name string `json:"name"`
+}
+
+var GitRepositoryTypeMeta metav1.TypeMeta = metav1.TypeMeta{
+	APIVersion: "source.toolkit.fluxcd.io/v1",
+	Kind:       "GitRepository",
+}
+
+type GitRepository struct {
+	metav1.TypeMeta `json:",inline"`
+	ObjectMeta      `json:"metadata"`
+	Spec            GitRepositorySpec `json:"spec"`
+}
+
+type GitRepositorySpec struct {
+	// URL of the git repository
+	URL string `json:"url"`
+	// SecretRef is the secret containing the credentials for the git repository
+	SecretRef *LocalObjectReference `json:"secretRef,omitempty"`
+	// Interval at which the git repository is fetched, e.g. "5m"
+	Interval string `json:"interval"`
+	// Reference specifies the revision to check out
+	Reference *GitRepositoryRef `json:"ref,omitempty"`
+}
+
+type LocalObjectReference struct {
+	Name string `json:"name"`
+}
+
+type GitRepositoryRef struct {
+	// Branch to check out
+	Branch string `json:"branch,omitempty"`
+}
@@ -46,11 +46,15 @@ type RootAppFiltering struct {
 
 type ApiVersion string
 
-const V1Alpha1 ApiVersion = "v1alpha1"
+const (
+	V1Alpha1 ApiVersion = "v1alpha1"
+	FluxV1   ApiVersion = "v1"
+)
 
 type AppData struct {
 	ArgoAppName        string   // name of the bracket if bracket mode is on
 	ReferencedAppTeams []string // names of the teams
+	ManifestPath       string   // overrides the path of the manifests if bracket mode is off, used for waves
 }
 
 type AppTeam struct {
@@ -79,7 +83,9 @@ func (e *EnvironmentInfo) GetFullyQualifiedName() string {
 	return string(e.ParentEnvironmentName)
 }
 
-func Render(ctx context.Context, gitUrl string, gitBranch string, info *EnvironmentInfo, appsData []AppData, options *RenderOptions) (map[ApiVersion][]byte, error) {
+// Render returns the root app of an environment, keyed by the files it has to be written to, see RootFiles.
+// Environments with a Flux CD configuration are rendered as Flux kustomizations, all others as Argo CD applications.
+func Render(ctx context.Context, gitUrl string, gitBranch string, info *EnvironmentInfo, appsData []AppData, options *RenderOptions) (map[string][]byte, error) {
 	span, _ := tracer.StartSpanFromContext(ctx, "Render")
 	defer span.Finish()
 	if options == nil {
@@ -88,11 +94,24 @@ func Render(ctx context.Context, gitUrl string, gitBranch string, info *Environm
 	if info.ArgoCDConfig == nil {
 		return nil, fmt.Errorf("no ArgoCd configured for environment %s", info.GetFullyQualifiedName())
 	}
-	result := map[ApiVersion][]byte{}
+	result := map[string][]byte{}
+	if info.ArgoCDConfig.FluxCd != nil {
+		root, err := RenderFluxV1Root(gitUrl, gitBranch, info)
+		if err != nil {
+			return nil, err
+		}
+		kustomizations, err := RenderFluxV1(ctx, info, appsData, options.PointToBrackets, options.AllowBracketMoves)
+		if err != nil {
+			return nil, err
+		}
+		result[FluxRootFile(info.GetFullyQualifiedName())] = root
+		result[FluxKustomizationsFile(info.GetFullyQualifiedName())] = kustomizations
+		return result, nil
+	}
 	if content, err := RenderV1Alpha1(ctx, gitUrl, gitBranch, info, appsData, options.PointToBrackets, options.AllowBracketMoves); err != nil {
 		return nil, err
 	} else {
-		result[V1Alpha1] = content
+		result[ArgoCdRootFile(info.GetFullyQualifiedName())] = content
 	}
 	return result, nil
 }
@@ -201,33 +220,21 @@ func RenderAppEnv(
 		argoProjectName = info.ArgoProjectNameOverride
 	}
 
-	manifestPaths := []string{}
+	manifestPaths, err := appManifestPaths(info, appData, pointToBrackets)
+	if err != nil {
+		return "", err
+	}
 	manifestPathsArgoFormat := ""
-	teamNames := []string{}
-	if len(appData.ReferencedAppTeams) > 0 {
-		teamNames = append(teamNames, appData.ReferencedAppTeams...)
-		if pointToBrackets {
-			// in bracket mode we just point to the bracket, we don't even need to know all the app names:
-			paths := BracketPaths(info.ParentEnvironmentName, types.ArgoBracketName(appData.ArgoAppName), "")
-			manifestPathsArgoFormat = manifestPathToArgoFormat(paths.BracketDirectory)
-			manifestPaths = append(manifestPaths, paths.BracketDirectory)
-		} else {
-			if len(appData.ReferencedAppTeams) > 1 {
-				return "", fmt.Errorf("found too many (%d) referenced teams in non-bracket mode", len(appData.ReferencedAppTeams))
-			}
-			manifestPath := filepath.Join("environments", string(info.ParentEnvironmentName), "applications", appData.ArgoAppName, "manifests")
-			manifestPaths = append(manifestPaths, manifestPath)
-			manifestPathsArgoFormat = manifestPathsArgoFormat + manifestPathToArgoFormat(manifestPath)
-		}
+	for _, manifestPath := range manifestPaths {
+		manifestPathsArgoFormat = manifestPathsArgoFormat + manifestPathToArgoFormat(manifestPath)
 	}
+	teamNames := []string{}
+	teamNames = append(teamNames, appData.ReferencedAppTeams...)
 	teamsAnnotation := generateTeamNameAnnotationValue(teamNames)
 	for k, v := range applicationAnnotations {
 		annotations[k] = v
 	}
-	annotations["com.freiheit.kuberpult/teams"] = teamsAnnotation
-	annotations["com.freiheit.kuberpult/application"] = name
-	annotations["com.freiheit.kuberpult/environment"] = info.GetFullyQualifiedName()
-	annotations["com.freiheit.kuberpult/aa-parent-environment"] = string(info.ParentEnvironmentName)
+	addKuberpultAnnotations(annotations, info, name, teamsAnnotation)
 	// This annotation is so that argoCd does not invalidate *everything* in the whole repo when receiving a git webhook.
 	// It has to start with a "/" to be absolute to the git repo.
 	// See https://argo-cd.readthedocs.io/en/stable/operator-manual/high_availability/#webhook-and-manifest-paths-annotation
@@ -266,6 +273,33 @@ func RenderAppEnv(
 	return string(content), nil
 }
 
+// appManifestPaths returns the directories of the manifests that the app of an environment points to
+func appManifestPaths(info *EnvironmentInfo, appData AppData, pointToBrackets bool) ([]string, error) {
+	if len(appData.ReferencedAppTeams) == 0 {
+		return []string{}, nil
+	}
+	if pointToBrackets {
+		// in bracket mode we just point to the bracket, we don't even need to know all the app names:
+		paths := BracketPaths(info.ParentEnvironmentName, types.ArgoBracketName(appData.ArgoAppName), "")
+		return []string{paths.BracketDirectory}, nil
+	}
+	if len(appData.ReferencedAppTeams) > 1 {
+		return nil, fmt.Errorf("found too many (%d) referenced teams in non-bracket mode", len(appData.ReferencedAppTeams))
+	}
+	manifestPath := filepath.Join("environments", string(info.ParentEnvironmentName), "applications", appData.ArgoAppName, "manifests")
+	if appData.ManifestPath != "" {
+		manifestPath = appData.ManifestPath
+	}
+	return []string{manifestPath}, nil
+}
+
+func addKuberpultAnnotations(annotations map[string]string, info *EnvironmentInfo, name string, teamsAnnotation string) {
+	annotations["com.freiheit.kuberpult/teams"] = teamsAnnotation
+	annotations["com.freiheit.kuberpult/application"] = name
+	annotations["com.freiheit.kuberpult/environment"] = info.GetFullyQualifiedName()
+	annotations["com.freiheit.kuberpult/aa-parent-environment"] = string(info.ParentEnvironmentName)
+}
+
 func manifestPathToArgoFormat(path string) string {
 	// manifestPaths must begin with a / and are separated by ";"
 	// see https://argo-cd.readthedocs.io/en/stable/operator-manual/high_availability/#manifest-paths-annotation
@@ -719,6 +719,64 @@ spec:
       allowEmpty: true
       prune: true
       selfHeal: true
+`,
+		},
+		{
+			name: "AA environment with waves",
+			config: config.EnvironmentConfig{
+				ArgoCd: &config.EnvironmentConfigArgoCd{
+					ConcreteEnvName: "dev-2",
+					Destination: config.ArgoCdDestination{
+						Namespace:            nil,
+						AppProjectNamespace:  conversion.FromString("bar1"),
+						ApplicationNamespace: nil,
+					},
+				},
+			},
+			appData: []AppData{
+				{
+					ArgoAppName:        "app1",
+					ReferencedAppTeams: []string{"some-team"},
+					ManifestPath:       "environments/test-env/applications/app1/waves/dev-2/manifests",
+				},
+			},
+			want: `apiVersion: argoproj.io/v1alpha1
+kind: AppProject
+metadata:
+  name: AA-test-env-dev-2
+spec:
+  description: AA-test-env-dev-2
+  destinations:
+  - namespace: bar1
+  sourceRepos:
+  - '*'
+---
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  annotations:
+    argocd.argoproj.io/manifest-generate-paths: /environments/test-env/applications/app1/waves/dev-2/manifests;
+    com.freiheit.kuberpult/aa-parent-environment: test-env
+    com.freiheit.kuberpult/application: app1
+    com.freiheit.kuberpult/environment: AA-test-env-dev-2
+    com.freiheit.kuberpult/teams: some-team
+  finalizers:
+  - resources-finalizer.argocd.argoproj.io
+  labels:
+    com.freiheit.kuberpult/teams: some-team
+  name: AA-test-env-dev-2-app1
+spec:
+  destination: {}
+  project: AA-test-env-dev-2
+  sources:
+  - path: environments/test-env/applications/app1/waves/dev-2/manifests
+    repoURL: https://git.example.com/
+    targetRevision: branch-name
+  syncPolicy:
+    automated:
+      allowEmpty: true
+      prune: true
+      selfHeal: true
 `,
 		},
 		{
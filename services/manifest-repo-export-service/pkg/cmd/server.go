@@ -101,9 +101,14 @@ func Run(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	gitUrl, err := valid.ReadEnvVar("KUBERPULT_GIT_URL")
-	if err != nil {
-		return err
+	// if set, the manifests are written into this directory and the git url is not used
+	exportDirectory := valid.ReadEnvVarWithDefault("KUBERPULT_EXPORT_DIRECTORY", "")
+	gitUrl := valid.ReadEnvVarWithDefault("KUBERPULT_GIT_URL", "")
+	if exportDirectory != "" {
+		// nothing is pushed, but the generated Argo CD applications and Flux sources point to the repository that the clusters read from
+		gitUrl = valid.ReadEnvVarWithDefault("KUBERPULT_EXPORT_REPO_URL", "")
+	} else if gitUrl == "" {
+		return fmt.Errorf("KUBERPULT_GIT_URL is required unless KUBERPULT_EXPORT_DIRECTORY is set")
 	}
 	gitBranch, err := valid.ReadEnvVar("KUBERPULT_GIT_BRANCH")
 	if err != nil {
@@ -117,6 +122,25 @@ func Run(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	gitSigningKey := valid.ReadEnvVarWithDefault("KUBERPULT_GIT_SIGNING_KEY", "")
+	gitSigningFormat := valid.ReadEnvVarWithDefault("KUBERPULT_GIT_SIGNING_FORMAT", repository.SigningFormatOpenPGP)
+
+	gitMirrors, err := parseGitMirrors(valid.ReadEnvVarWithDefault("KUBERPULT_GIT_MIRRORS", ""))
+	if err != nil {
+		return err
+	}
+
+	var commitMessages *repository.CommitMessageTemplates
+	if rawCommitMessages := valid.ReadEnvVarWithDefault("KUBERPULT_COMMIT_MESSAGE_TEMPLATES", ""); rawCommitMessages != "" {
+		var commitMessageConfig repository.CommitMessageConfig
+		if err := json.Unmarshal([]byte(rawCommitMessages), &commitMessageConfig); err != nil {
+			return fmt.Errorf("could not parse KUBERPULT_COMMIT_MESSAGE_TEMPLATES: %w", err)
+		}
+		commitMessages, err = repository.NewCommitMessageTemplates(commitMessageConfig)
+		if err != nil {
+			return fmt.Errorf("invalid KUBERPULT_COMMIT_MESSAGE_TEMPLATES: %w", err)
+		}
+	}
 
 	enableMetricsString, err := valid.ReadEnvVar("KUBERPULT_ENABLE_METRICS")
 	if err != nil {
@@ -343,6 +367,12 @@ func Run(ctx context.Context) error {
 		Certificates: repository.Certificates{
 			KnownHostsFile: gitSshKnownHosts,
 		},
+		Signing: repository.Signing{
+			KeyPath: gitSigningKey,
+			Format:  gitSigningFormat,
+		},
+		CommitMessages:      commitMessages,
+		Mirrors:             gitMirrors,
 		Branch:              gitBranch,
 		NetworkTimeout:      time.Duration(networkTimeoutSeconds) * time.Second,
 		ReleaseVersionLimit: uint(releaseVersionLimit),
@@ -354,6 +384,8 @@ func Run(ctx context.Context) error {
 
 		ArgoRenderOptions: &renderOptions,
 		ArgoProjectNames:  &allArgoProjectNames, // note that this is empty here, we'll fill it later
+
+		ExportDirectory: exportDirectory,
 	}
 	repo, err := repository.New(ctx, cfg)
 	if err != nil {
@@ -774,6 +806,46 @@ func HandleGitTagPush(ctx context.Context, repo repository.Repository, gitTag ty
 	}
 }
 
+// gitMirror is the json format of one entry in KUBERPULT_GIT_MIRRORS.
+type gitMirror struct {
+	Name       string `json:"name"`
+	URL        string `json:"url"`
+	SshKey     string `json:"sshKey"`
+	KnownHosts string `json:"knownHosts"`
+}
+
+func parseGitMirrors(raw string) ([]repository.MirrorConfig, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var mirrors []gitMirror
+	if err := json.Unmarshal([]byte(raw), &mirrors); err != nil {
+		return nil, fmt.Errorf("could not parse KUBERPULT_GIT_MIRRORS: %w", err)
+	}
+	result := make([]repository.MirrorConfig, 0, len(mirrors))
+	names := map[string]struct{}{}
+	for _, m := range mirrors {
+		if m.Name == "" || m.URL == "" {
+			return nil, fmt.Errorf("invalid KUBERPULT_GIT_MIRRORS: every mirror needs a name and an url")
+		}
+		if _, exists := names[m.Name]; exists {
+			return nil, fmt.Errorf("invalid KUBERPULT_GIT_MIRRORS: mirror '%s' is configured twice", m.Name)
+		}
+		names[m.Name] = struct{}{}
+		result = append(result, repository.MirrorConfig{
+			Name: m.Name,
+			URL:  m.URL,
+			Credentials: repository.Credentials{
+				SshKey: m.SshKey,
+			},
+			Certificates: repository.Certificates{
+				KnownHostsFile: m.KnownHosts,
+			},
+		})
+	}
+	return result, nil
+}
+
 func measureGitTagPushFailures(ctx context.Context, ddMetrics statsd.ClientInterface, gitTag types.GitTag) {
 	if ddMetrics != nil {
 		metricName := "manifest_export_tag_push_failures"
@@ -978,6 +1050,33 @@ func getTransformer(_ context.Context, eslEventType db.EventType) (repository.Tr
 	case db.EvtDeleteEnvironment:
 		//exhaustruct:ignore
 		return &repository.DeleteEnvironment{}, nil
+	case db.EvtCreateEnvironmentFreeze:
+		//exhaustruct:ignore
+		return &repository.CreateEnvironmentFreeze{}, nil
+	case db.EvtDeleteEnvironmentFreeze:
+		//exhaustruct:ignore
+		return &repository.DeleteEnvironmentFreeze{}, nil
+	case db.EvtCreateEnvironmentGroupFreeze:
+		//exhaustruct:ignore
+		return &repository.CreateEnvironmentGroupFreeze{}, nil
+	case db.EvtDeleteEnvironmentGroupFreeze:
+		//exhaustruct:ignore
+		return &repository.DeleteEnvironmentGroupFreeze{}, nil
+	case db.EvtCreateDeploymentApproval:
+		//exhaustruct:ignore
+		return &repository.CreateDeploymentApproval{}, nil
+	case db.EvtApproveDeployment:
+		//exhaustruct:ignore
+		return &repository.ApproveDeployment{}, nil
+	case db.EvtRejectDeployment:
+		//exhaustruct:ignore
+		return &repository.RejectDeployment{}, nil
+	case db.EvtCancelQueuedVersion:
+		//exhaustruct:ignore
+		return &repository.CancelQueuedVersion{}, nil
+	case db.EvtDeployQueuedVersion:
+		//exhaustruct:ignore
+		return &repository.DeployQueuedVersion{}, nil
 	case db.EvtExtendAAEnvironment:
 		//exhaustruct:ignore
 		return &repository.ExtendAAEnvironment{}, nil
@@ -987,6 +1086,15 @@ func getTransformer(_ context.Context, eslEventType db.EventType) (repository.Tr
 	case db.EvtRenderEnvironment:
 		//exhaustruct:ignore
 		return &repository.RenderEnvironment{}, nil
+	case db.EvtPromoteAAWave:
+		//exhaustruct:ignore
+		return &repository.PromoteAAWave{}, nil
+	case db.EvtSetReleaseRetentionPolicy:
+		//exhaustruct:ignore
+		return &repository.SetReleaseRetentionPolicy{}, nil
+	case db.EvtDeleteReleaseRetentionPolicy:
+		//exhaustruct:ignore
+		return &repository.DeleteReleaseRetentionPolicy{}, nil
 	}
 	return nil, fmt.Errorf("could not find transformer for event type %v", eslEventType)
 }
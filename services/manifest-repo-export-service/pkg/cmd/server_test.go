@@ -106,6 +106,72 @@ func TestParseEnvironmentOverrides(t *testing.T) {
 	}
 }
 
+func TestParseGitMirrors(t *testing.T) {
+	tcs := []struct {
+		Name          string
+		Input         string
+		Expected      []repository.MirrorConfig
+		ExpectedError string
+	}{
+		{
+			Name:     "no mirrors",
+			Input:    "",
+			Expected: nil,
+		},
+		{
+			Name:  "two mirrors",
+			Input: `[{"name":"eu","url":"ssh://git@eu.example.com/manifests.git","sshKey":"/etc/mirrors/eu-identity","knownHosts":"/etc/mirrors/eu-known_hosts"},{"name":"us","url":"ssh://git@us.example.com/manifests.git"}]`,
+			Expected: []repository.MirrorConfig{
+				{
+					Name:         "eu",
+					URL:          "ssh://git@eu.example.com/manifests.git",
+					Credentials:  repository.Credentials{SshKey: "/etc/mirrors/eu-identity"},
+					Certificates: repository.Certificates{KnownHostsFile: "/etc/mirrors/eu-known_hosts"},
+				},
+				{
+					Name:         "us",
+					URL:          "ssh://git@us.example.com/manifests.git",
+					Credentials:  repository.Credentials{SshKey: ""},
+					Certificates: repository.Certificates{KnownHostsFile: ""},
+				},
+			},
+		},
+		{
+			Name:          "missing url",
+			Input:         `[{"name":"eu"}]`,
+			ExpectedError: "invalid KUBERPULT_GIT_MIRRORS: every mirror needs a name and an url",
+		},
+		{
+			Name:          "duplicate name",
+			Input:         `[{"name":"eu","url":"a"},{"name":"eu","url":"b"}]`,
+			ExpectedError: "invalid KUBERPULT_GIT_MIRRORS: mirror 'eu' is configured twice",
+		},
+		{
+			Name:          "invalid json",
+			Input:         `{"name":"eu"}`,
+			ExpectedError: "could not parse KUBERPULT_GIT_MIRRORS: json: cannot unmarshal object into Go value of type []cmd.gitMirror",
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			actual, err := parseGitMirrors(tc.Input)
+			if err != nil {
+				if diff := cmp.Diff(tc.ExpectedError, err.Error()); diff != "" {
+					t.Fatalf("error mismatch (-want, +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.ExpectedError != "" {
+				t.Fatalf("expected error '%s', got none", tc.ExpectedError)
+			}
+			if diff := cmp.Diff(tc.Expected, actual); diff != "" {
+				t.Errorf("mirrors mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestPushGitTags(t *testing.T) {
 	var setup = makeSetupTransformer()
 	tcs := []struct {
@@ -0,0 +1,338 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"text/template"
+
+	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/types"
+)
+
+// DefaultCommitMessageTemplate is used for all event types that have no template of their own.
+const DefaultCommitMessageTemplate = "default"
+
+// CommitMessageConfig configures the messages of the commits in the manifest repository.
+// All values are go templates that are executed with CommitMessageData.
+type CommitMessageConfig struct {
+	// Templates by event type (e.g. "DeployApplicationVersion") or DefaultCommitMessageTemplate.
+	// Event types without a template keep the message that kuberpult generates.
+	Templates map[string]CommitMessageTemplate `json:"templates,omitempty"`
+	// Trailers are appended to every commit message in this order. Trailers with an empty value are left out.
+	Trailers []CommitMessageTrailer `json:"trailers,omitempty"`
+}
+
+type CommitMessageTemplate struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body,omitempty"`
+}
+
+// CommitMessageTrailer is a git trailer like `Kuberpult-Esl-Id: 42`.
+type CommitMessageTrailer struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// CommitMessageData is available in the commit message templates. Fields that do not apply to an event are empty.
+type CommitMessageData struct {
+	EventType string
+	// Message is the complete message that kuberpult generates without templates.
+	Message        string
+	Application    string
+	Environment    string
+	Version        string
+	DisplayVersion string
+	SourceCommitId string
+	Author         string
+	AuthorEmail    string
+	EslId          db.TransformerID
+	Team           string
+	// EnvironmentGroup is set for group locks, group freezes and release trains that target a group.
+	EnvironmentGroup string
+	// ApprovalId is set for the events of deployment approvals.
+	ApprovalId string
+}
+
+type commitMessageTemplate struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+type commitMessageTrailer struct {
+	key   string
+	value *template.Template
+}
+
+// CommitMessageTemplates are the parsed templates of a CommitMessageConfig.
+type CommitMessageTemplates struct {
+	templates map[string]commitMessageTemplate
+	trailers  []commitMessageTrailer
+}
+
+// NewCommitMessageTemplates parses the templates and checks that they can be executed.
+func NewCommitMessageTemplates(cfg CommitMessageConfig) (*CommitMessageTemplates, error) {
+	result := &CommitMessageTemplates{
+		templates: map[string]commitMessageTemplate{},
+		trailers:  []commitMessageTrailer{},
+	}
+	parse := func(name, text string) (*template.Template, error) {
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("parsing commit message template %s: %w", name, err)
+		}
+		var sb strings.Builder
+		//exhaustruct:ignore
+		if err := tmpl.Execute(&sb, CommitMessageData{}); err != nil {
+			return nil, fmt.Errorf("executing commit message template %s: %w", name, err)
+		}
+		return tmpl, nil
+	}
+	for eventType, tmpl := range cfg.Templates {
+		if strings.TrimSpace(tmpl.Subject) == "" {
+			return nil, fmt.Errorf("commit message template %s has no subject", eventType)
+		}
+		subject, err := parse(eventType+".subject", tmpl.Subject)
+		if err != nil {
+			return nil, err
+		}
+		body, err := parse(eventType+".body", tmpl.Body)
+		if err != nil {
+			return nil, err
+		}
+		result.templates[eventType] = commitMessageTemplate{subject: subject, body: body}
+	}
+	for _, trailer := range cfg.Trailers {
+		if trailer.Key == "" || strings.ContainsAny(trailer.Key, ": \n") {
+			return nil, fmt.Errorf("invalid commit message trailer key '%s'", trailer.Key)
+		}
+		value, err := parse(trailer.Key, trailer.Value)
+		if err != nil {
+			return nil, err
+		}
+		result.trailers = append(result.trailers, commitMessageTrailer{key: trailer.Key, value: value})
+	}
+	return result, nil
+}
+
+// Render returns the commit message for the data. Subject, body and trailers are separated by empty lines.
+func (t *CommitMessageTemplates) Render(data CommitMessageData) (string, error) {
+	execute := func(tmpl *template.Template) (string, error) {
+		var sb strings.Builder
+		if err := tmpl.Execute(&sb, data); err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(sb.String()), nil
+	}
+	paragraphs := []string{}
+	tmpl, ok := t.templates[data.EventType]
+	if !ok {
+		tmpl, ok = t.templates[DefaultCommitMessageTemplate]
+	}
+	if ok {
+		subject, err := execute(tmpl.subject)
+		if err != nil {
+			return "", err
+		}
+		// the subject is a single line, the rest of it belongs to the body
+		paragraphs = append(paragraphs, strings.Join(strings.Fields(subject), " "))
+		body, err := execute(tmpl.body)
+		if err != nil {
+			return "", err
+		}
+		if body != "" {
+			paragraphs = append(paragraphs, body)
+		}
+	} else {
+		paragraphs = append(paragraphs, strings.TrimSpace(data.Message))
+	}
+	trailers := []string{}
+	for _, trailer := range t.trailers {
+		value, err := execute(trailer.value)
+		if err != nil {
+			return "", err
+		}
+		if value == "" {
+			continue
+		}
+		trailers = append(trailers, fmt.Sprintf("%s: %s", trailer.key, strings.Join(strings.Fields(value), " ")))
+	}
+	if len(trailers) > 0 {
+		paragraphs = append(paragraphs, strings.Join(trailers, "\n"))
+	}
+	return strings.Join(paragraphs, "\n\n"), nil
+}
+
+// commitMessageData collects the data of the transformer for the commit message templates.
+func commitMessageData(ctx context.Context, state *State, transaction *sql.Tx, transformer Transformer, message string) (CommitMessageData, error) {
+	data := CommitMessageData{
+		EventType:        string(transformer.GetDBEventType()),
+		Message:          message,
+		Application:      "",
+		Environment:      "",
+		Version:          "",
+		DisplayVersion:   "",
+		SourceCommitId:   "",
+		Author:           transformer.GetMetadata().AuthorName,
+		AuthorEmail:      transformer.GetMetadata().AuthorEmail,
+		EslId:            transformer.GetEslVersion(),
+		Team:             "",
+		EnvironmentGroup: "",
+		ApprovalId:       "",
+	}
+	var release *types.ReleaseNumbers
+	switch t := transformer.(type) {
+	case *CreateApplicationVersion:
+		data.Application = t.Application
+		release = &types.ReleaseNumbers{Version: &t.Version, Revision: t.Revision}
+	case *CleanupOldApplicationVersions:
+		data.Application = t.Application
+	case *DeployApplicationVersion:
+		data.Application, data.Environment = t.Application, string(t.Environment)
+		release = &types.ReleaseNumbers{Version: &t.Version, Revision: t.Revision}
+	case *PromoteAAWave:
+		data.Application, data.Environment = t.Application, string(t.Environment)
+		release = &types.ReleaseNumbers{Version: &t.Version, Revision: t.Revision}
+	case *CancelQueuedVersion:
+		data.Application, data.Environment = t.Application, string(t.Environment)
+		release = &types.ReleaseNumbers{Version: &t.Version, Revision: 0}
+	case *DeployQueuedVersion:
+		data.Application, data.Environment = t.Application, string(t.Environment)
+		release = &types.ReleaseNumbers{Version: &t.Version, Revision: 0}
+	case *CreateUndeployApplicationVersion:
+		data.Application = t.Application
+	case *UndeployApplication:
+		data.Application = string(t.Application)
+	case *DeleteEnvFromApp:
+		data.Application, data.Environment = string(t.Application), string(t.Environment)
+	case *CreateEnvironmentApplicationLock:
+		data.Application, data.Environment = t.Application, string(t.Environment)
+	case *DeleteEnvironmentApplicationLock:
+		data.Application, data.Environment = t.Application, string(t.Environment)
+	case *CreateEnvironmentTeamLock:
+		data.Environment, data.Team = string(t.Environment), t.Team
+	case *DeleteEnvironmentTeamLock:
+		data.Environment, data.Team = string(t.Environment), t.Team
+	case *CreateEnvironmentLock:
+		data.Environment = string(t.Environment)
+	case *DeleteEnvironmentLock:
+		data.Environment = string(t.Environment)
+	case *CreateEnvironment:
+		data.Environment = string(t.Environment)
+	case *DeleteEnvironment:
+		data.Environment = string(t.Environment)
+	case *RenderEnvironment:
+		data.Environment = string(t.Environment)
+	case *ExtendAAEnvironment:
+		data.Environment = string(t.Environment)
+	case *DeleteAAEnvironmentConfig:
+		data.Environment = string(t.Environment)
+	case *CreateEnvironmentFreeze:
+		data.Environment = string(t.Environment)
+	case *DeleteEnvironmentFreeze:
+		data.Environment = string(t.Environment)
+	case *CreateEnvironmentGroupLock:
+		data.EnvironmentGroup = t.EnvironmentGroup
+	case *DeleteEnvironmentGroupLock:
+		data.EnvironmentGroup = t.EnvironmentGroup
+	case *CreateEnvironmentGroupFreeze:
+		data.EnvironmentGroup = t.EnvironmentGroup
+	case *DeleteEnvironmentGroupFreeze:
+		data.EnvironmentGroup = t.EnvironmentGroup
+	case *ReleaseTrain:
+		setReleaseTrainTarget(&data, t.Target, t.TargetType, t.Team)
+	case *CreateDeploymentApproval:
+		data.ApprovalId = t.ApprovalId
+	case *ApproveDeployment:
+		data.ApprovalId = t.ApprovalId
+	case *RejectDeployment:
+		data.ApprovalId = t.ApprovalId
+	case *SetReleaseRetentionPolicy:
+		setReleaseRetentionPolicyScope(&data, t.Scope, t.Name)
+	case *DeleteReleaseRetentionPolicy:
+		setReleaseRetentionPolicyScope(&data, t.Scope, t.Name)
+	}
+	if data.ApprovalId != "" && state.DBHandler != nil {
+		approval, err := state.DBHandler.DBSelectDeploymentApproval(ctx, transaction, data.ApprovalId)
+		if err != nil {
+			return data, err
+		}
+		if approval != nil {
+			release = setDeploymentApproval(&data, approval)
+		}
+	}
+	if data.Application == "" || state.DBHandler == nil {
+		return data, nil
+	}
+	app, err := state.DBHandler.DBSelectApp(ctx, transaction, types.AppName(data.Application))
+	if err != nil {
+		return data, err
+	}
+	if app != nil {
+		data.Team = app.Metadata.Team
+	}
+	if release == nil {
+		return data, nil
+	}
+	data.Version = release.String()
+	dbRelease, err := state.DBHandler.DBSelectReleaseByVersion(ctx, transaction, types.AppName(data.Application), *release, false)
+	if err != nil {
+		return data, err
+	}
+	if dbRelease != nil {
+		data.DisplayVersion = dbRelease.Metadata.DisplayVersion
+		data.SourceCommitId = dbRelease.Metadata.SourceCommitId
+	}
+	return data, nil
+}
+
+func setReleaseTrainTarget(data *CommitMessageData, target, targetType, team string) {
+	if targetType == api.ReleaseTrainRequest_ENVIRONMENT.String() {
+		data.Environment = target
+	} else {
+		// release trains without a target type prefer environment groups, see getEnvironmentGroupsEnvironmentsOrEnvironment
+		data.EnvironmentGroup = target
+	}
+	data.Team = team
+}
+
+func setReleaseRetentionPolicyScope(data *CommitMessageData, scope db.ReleaseRetentionScope, name string) {
+	switch scope {
+	case db.ReleaseRetentionScopeApplication:
+		data.Application = name
+	case db.ReleaseRetentionScopeTeam:
+		data.Team = name
+	}
+}
+
+// setDeploymentApproval sets the target of the approved deployment and returns its release, if it is a single deployment.
+func setDeploymentApproval(data *CommitMessageData, approval *db.DeploymentApproval) *types.ReleaseNumbers {
+	if train := approval.Request.ReleaseTrain; train != nil {
+		setReleaseTrainTarget(data, train.Target, train.TargetType, train.Team)
+		return nil
+	}
+	deployment := approval.Request.Deployment
+	if deployment == nil {
+		return nil
+	}
+	data.Application, data.Environment = string(deployment.Application), string(deployment.Environment)
+	return &types.ReleaseNumbers{Version: &deployment.Version, Revision: deployment.Revision}
+}
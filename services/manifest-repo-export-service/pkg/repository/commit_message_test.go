@@ -0,0 +1,500 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package repository
+
+import (
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/types"
+)
+
+func TestCommitMessageTemplates(t *testing.T) {
+	deployment := CommitMessageData{
+		EventType:      string(db.EvtDeployApplicationVersion),
+		Message:        "deployed version 3 of \"app\" to \"dev\"",
+		Application:    "app",
+		Environment:    "dev",
+		Version:        "3",
+		DisplayVersion: "v1.2.3",
+		SourceCommitId: "cafe",
+		Author:         "Jane",
+		AuthorEmail:    "jane@example.com",
+		EslId:          42,
+		Team:           "team-a",
+	}
+	tcs := []struct {
+		Name            string
+		Config          CommitMessageConfig
+		Data            CommitMessageData
+		ExpectedMessage string
+		ExpectedError   string
+	}{
+		{
+			Name: "template for the event type",
+			Config: CommitMessageConfig{
+				Templates: map[string]CommitMessageTemplate{
+					string(db.EvtDeployApplicationVersion): {
+						Subject: "Deploy {{.Application}} {{.DisplayVersion}} to {{.Environment}}",
+						Body:    "Team: {{.Team}}\nAuthor: {{.Author}} <{{.AuthorEmail}}>",
+					},
+					DefaultCommitMessageTemplate: {Subject: "other", Body: ""},
+				},
+				Trailers: nil,
+			},
+			Data:            deployment,
+			ExpectedMessage: "Deploy app v1.2.3 to dev\n\nTeam: team-a\nAuthor: Jane <jane@example.com>",
+		},
+		{
+			Name: "default template",
+			Config: CommitMessageConfig{
+				Templates: map[string]CommitMessageTemplate{
+					DefaultCommitMessageTemplate: {Subject: "[{{.EventType}}] {{.Message}}", Body: ""},
+				},
+				Trailers: nil,
+			},
+			Data:            deployment,
+			ExpectedMessage: "[DeployApplicationVersion] deployed version 3 of \"app\" to \"dev\"",
+		},
+		{
+			Name:            "no template keeps the message",
+			Config:          CommitMessageConfig{Templates: nil, Trailers: nil},
+			Data:            deployment,
+			ExpectedMessage: "deployed version 3 of \"app\" to \"dev\"",
+		},
+		{
+			Name: "subject is a single line",
+			Config: CommitMessageConfig{
+				Templates: map[string]CommitMessageTemplate{
+					DefaultCommitMessageTemplate: {Subject: "{{.Application}}\n{{.Environment}}", Body: ""},
+				},
+				Trailers: nil,
+			},
+			Data:            deployment,
+			ExpectedMessage: "app dev",
+		},
+		{
+			Name: "trailers",
+			Config: CommitMessageConfig{
+				Templates: nil,
+				Trailers: []CommitMessageTrailer{
+					{Key: "Kuberpult-Esl-Id", Value: "{{.EslId}}"},
+					{Key: "Source-Commit", Value: "{{.SourceCommitId}}"},
+				},
+			},
+			Data:            deployment,
+			ExpectedMessage: "deployed version 3 of \"app\" to \"dev\"\n\nKuberpult-Esl-Id: 42\nSource-Commit: cafe",
+		},
+		{
+			Name: "empty trailers are left out",
+			Config: CommitMessageConfig{
+				Templates: nil,
+				Trailers: []CommitMessageTrailer{
+					{Key: "Source-Commit", Value: "{{.SourceCommitId}}"},
+				},
+			},
+			Data: CommitMessageData{
+				EventType:      string(db.EvtCreateEnvironment),
+				Message:        "create environment \"dev\"",
+				Application:    "",
+				Environment:    "dev",
+				Version:        "",
+				DisplayVersion: "",
+				SourceCommitId: "",
+				Author:         "",
+				AuthorEmail:    "",
+				EslId:          1,
+				Team:           "",
+			},
+			ExpectedMessage: "create environment \"dev\"",
+		},
+		{
+			Name: "invalid template",
+			Config: CommitMessageConfig{
+				Templates: map[string]CommitMessageTemplate{
+					DefaultCommitMessageTemplate: {Subject: "{{.Application", Body: ""},
+				},
+				Trailers: nil,
+			},
+			ExpectedError: "parsing commit message template default.subject: template: default.subject:1: unclosed action",
+		},
+		{
+			Name: "unknown field",
+			Config: CommitMessageConfig{
+				Templates: map[string]CommitMessageTemplate{
+					DefaultCommitMessageTemplate: {Subject: "{{.App}}", Body: ""},
+				},
+				Trailers: nil,
+			},
+			ExpectedError: "executing commit message template default.subject: template: default.subject:1:2: executing \"default.subject\" at <.App>: can't evaluate field App in type repository.CommitMessageData",
+		},
+		{
+			Name: "empty subject",
+			Config: CommitMessageConfig{
+				Templates: map[string]CommitMessageTemplate{
+					DefaultCommitMessageTemplate: {Subject: " ", Body: "body"},
+				},
+				Trailers: nil,
+			},
+			ExpectedError: "commit message template default has no subject",
+		},
+		{
+			Name: "invalid trailer key",
+			Config: CommitMessageConfig{
+				Templates: nil,
+				Trailers: []CommitMessageTrailer{
+					{Key: "Source Commit", Value: "{{.SourceCommitId}}"},
+				},
+			},
+			ExpectedError: "invalid commit message trailer key 'Source Commit'",
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			templates, err := NewCommitMessageTemplates(tc.Config)
+			if err != nil {
+				if diff := cmp.Diff(tc.ExpectedError, err.Error()); diff != "" {
+					t.Fatalf("error mismatch (-want, +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.ExpectedError != "" {
+				t.Fatalf("expected error '%s', got none", tc.ExpectedError)
+			}
+			message, err := templates.Render(tc.Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.ExpectedMessage, message); diff != "" {
+				t.Errorf("message mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCommitMessageData(t *testing.T) {
+	tcs := []struct {
+		Name         string
+		Transformer  Transformer
+		ExpectedData CommitMessageData
+	}{
+		{
+			Name: "environment lock",
+			Transformer: &CreateEnvironmentLock{
+				Environment:           "dev",
+				LockId:                "l1",
+				Message:               "msg",
+				TransformerEslVersion: 7,
+				TransformerMetadata:   TransformerMetadata{AuthorName: "Jane", AuthorEmail: "jane@example.com"},
+			},
+			ExpectedData: CommitMessageData{
+				EventType:      string(db.EvtCreateEnvironmentLock),
+				Message:        "message",
+				Application:    "",
+				Environment:    "dev",
+				Version:        "",
+				DisplayVersion: "",
+				SourceCommitId: "",
+				Author:         "Jane",
+				AuthorEmail:    "jane@example.com",
+				EslId:          7,
+				Team:           "",
+			},
+		},
+		{
+			Name: "team lock",
+			Transformer: &DeleteEnvironmentTeamLock{
+				Environment:           "dev",
+				Team:                  "team-a",
+				LockId:                "l1",
+				TransformerEslVersion: 8,
+				TransformerMetadata:   TransformerMetadata{AuthorName: "", AuthorEmail: ""},
+			},
+			ExpectedData: CommitMessageData{
+				EventType:      string(db.EvtDeleteEnvironmentTeamLock),
+				Message:        "message",
+				Application:    "",
+				Environment:    "dev",
+				Version:        "",
+				DisplayVersion: "",
+				SourceCommitId: "",
+				Author:         "",
+				AuthorEmail:    "",
+				EslId:          8,
+				Team:           "team-a",
+			},
+		},
+		{
+			Name: "undeploy",
+			Transformer: &UndeployApplication{
+				Application:           types.AppName("app"),
+				TransformerEslVersion: 9,
+				TransformerMetadata:   TransformerMetadata{AuthorName: "", AuthorEmail: ""},
+			},
+			ExpectedData: CommitMessageData{
+				EventType:      string(db.EvtUndeployApplication),
+				Message:        "message",
+				Application:    "app",
+				Environment:    "",
+				Version:        "",
+				DisplayVersion: "",
+				SourceCommitId: "",
+				Author:         "",
+				AuthorEmail:    "",
+				EslId:          9,
+				Team:           "",
+			},
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			//exhaustruct:ignore
+			state := &State{}
+			data, err := commitMessageData(context.Background(), state, nil, tc.Transformer, "message")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.ExpectedData, data); diff != "" {
+				t.Errorf("data mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// readEventTypes returns all event types that are declared in the db package.
+func readEventTypes(t *testing.T) []db.EventType {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "../../../../pkg/db/db.go", nil, 0)
+	if err != nil {
+		t.Fatalf("could not parse the db package: %v", err)
+	}
+	result := []db.EventType{}
+	for _, decl := range file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			valueSpec := spec.(*ast.ValueSpec)
+			if ident, ok := valueSpec.Type.(*ast.Ident); !ok || ident.Name != "EventType" {
+				continue
+			}
+			for _, value := range valueSpec.Values {
+				eventType, err := strconv.Unquote(value.(*ast.BasicLit).Value)
+				if err != nil {
+					t.Fatalf("could not read event type: %v", err)
+				}
+				result = append(result, db.EventType(eventType))
+			}
+		}
+	}
+	if len(result) == 0 {
+		t.Fatal("no event types found in the db package")
+	}
+	return result
+}
+
+func TestCommitMessageDataCoversAllEventTypes(t *testing.T) {
+	// these events are not processed by the manifest-repo-export-service, see getTransformer:
+	notExported := map[db.EventType]bool{
+		db.EvtEnvReleaseTrain:    true,
+		db.EvtSkippedServices:    true,
+		db.EvtCreateManifestLock: true,
+		db.EvtDeleteManifestLock: true,
+	}
+	// EventType, Message and EslId of the expected data are set by the test.
+	// Version, DisplayVersion and SourceCommitId are read from the database, which is not part of this test.
+	tcs := map[db.EventType]struct {
+		Transformer  Transformer
+		ExpectedData CommitMessageData
+	}{
+		db.EvtCreateApplicationVersion: {
+			Transformer:  &CreateApplicationVersion{Application: "app", Version: 3},
+			ExpectedData: CommitMessageData{Application: "app"},
+		},
+		db.EvtDeployApplicationVersion: {
+			Transformer:  &DeployApplicationVersion{Application: "app", Environment: "dev", Version: 3},
+			ExpectedData: CommitMessageData{Application: "app", Environment: "dev"},
+		},
+		db.EvtCreateUndeployApplicationVersion: {
+			Transformer:  &CreateUndeployApplicationVersion{Application: "app"},
+			ExpectedData: CommitMessageData{Application: "app"},
+		},
+		db.EvtUndeployApplication: {
+			Transformer:  &UndeployApplication{Application: "app"},
+			ExpectedData: CommitMessageData{Application: "app"},
+		},
+		db.EvtDeleteEnvFromApp: {
+			Transformer:  &DeleteEnvFromApp{Application: "app", Environment: "dev"},
+			ExpectedData: CommitMessageData{Application: "app", Environment: "dev"},
+		},
+		db.EvtCreateEnvironmentLock: {
+			Transformer:  &CreateEnvironmentLock{Environment: "dev"},
+			ExpectedData: CommitMessageData{Environment: "dev"},
+		},
+		db.EvtDeleteEnvironmentLock: {
+			Transformer:  &DeleteEnvironmentLock{Environment: "dev"},
+			ExpectedData: CommitMessageData{Environment: "dev"},
+		},
+		db.EvtCreateEnvironmentTeamLock: {
+			Transformer:  &CreateEnvironmentTeamLock{Environment: "dev", Team: "team-a"},
+			ExpectedData: CommitMessageData{Environment: "dev", Team: "team-a"},
+		},
+		db.EvtDeleteEnvironmentTeamLock: {
+			Transformer:  &DeleteEnvironmentTeamLock{Environment: "dev", Team: "team-a"},
+			ExpectedData: CommitMessageData{Environment: "dev", Team: "team-a"},
+		},
+		db.EvtCreateEnvironmentGroupLock: {
+			Transformer:  &CreateEnvironmentGroupLock{EnvironmentGroup: "staging"},
+			ExpectedData: CommitMessageData{EnvironmentGroup: "staging"},
+		},
+		db.EvtDeleteEnvironmentGroupLock: {
+			Transformer:  &DeleteEnvironmentGroupLock{EnvironmentGroup: "staging"},
+			ExpectedData: CommitMessageData{EnvironmentGroup: "staging"},
+		},
+		db.EvtCreateEnvironment: {
+			Transformer:  &CreateEnvironment{Environment: "dev"},
+			ExpectedData: CommitMessageData{Environment: "dev"},
+		},
+		db.EvtRenderEnvironment: {
+			Transformer:  &RenderEnvironment{Environment: "dev"},
+			ExpectedData: CommitMessageData{Environment: "dev"},
+		},
+		db.EvtDeleteEnvironment: {
+			Transformer:  &DeleteEnvironment{Environment: "dev"},
+			ExpectedData: CommitMessageData{Environment: "dev"},
+		},
+		db.EvtCreateEnvironmentApplicationLock: {
+			Transformer:  &CreateEnvironmentApplicationLock{Application: "app", Environment: "dev"},
+			ExpectedData: CommitMessageData{Application: "app", Environment: "dev"},
+		},
+		db.EvtDeleteEnvironmentApplicationLock: {
+			Transformer:  &DeleteEnvironmentApplicationLock{Application: "app", Environment: "dev"},
+			ExpectedData: CommitMessageData{Application: "app", Environment: "dev"},
+		},
+		db.EvtReleaseTrain: {
+			Transformer:  &ReleaseTrain{Target: "staging", TargetType: "ENVIRONMENTGROUP", Team: "team-a"},
+			ExpectedData: CommitMessageData{EnvironmentGroup: "staging", Team: "team-a"},
+		},
+		db.EvtMigrationTransformer: {
+			Transformer:  &MigrationTransformer{},
+			ExpectedData: CommitMessageData{},
+		},
+		db.EvtCleanupOldApplicationVersions: {
+			Transformer:  &CleanupOldApplicationVersions{Application: "app"},
+			ExpectedData: CommitMessageData{Application: "app"},
+		},
+		db.EvtExtendAAEnvironment: {
+			Transformer:  &ExtendAAEnvironment{Environment: "prod"},
+			ExpectedData: CommitMessageData{Environment: "prod"},
+		},
+		db.EvtDeleteAAEnvironmentConfig: {
+			Transformer:  &DeleteAAEnvironmentConfig{Environment: "prod", ConcreteEnvironmentName: "prod-de"},
+			ExpectedData: CommitMessageData{Environment: "prod"},
+		},
+		db.EvtCreateEnvironmentFreeze: {
+			Transformer:  &CreateEnvironmentFreeze{Environment: "prod"},
+			ExpectedData: CommitMessageData{Environment: "prod"},
+		},
+		db.EvtDeleteEnvironmentFreeze: {
+			Transformer:  &DeleteEnvironmentFreeze{Environment: "prod"},
+			ExpectedData: CommitMessageData{Environment: "prod"},
+		},
+		db.EvtCreateEnvironmentGroupFreeze: {
+			Transformer:  &CreateEnvironmentGroupFreeze{EnvironmentGroup: "production"},
+			ExpectedData: CommitMessageData{EnvironmentGroup: "production"},
+		},
+		db.EvtDeleteEnvironmentGroupFreeze: {
+			Transformer:  &DeleteEnvironmentGroupFreeze{EnvironmentGroup: "production"},
+			ExpectedData: CommitMessageData{EnvironmentGroup: "production"},
+		},
+		db.EvtCreateDeploymentApproval: {
+			Transformer:  &CreateDeploymentApproval{ApprovalId: "a1"},
+			ExpectedData: CommitMessageData{ApprovalId: "a1"},
+		},
+		db.EvtApproveDeployment: {
+			Transformer:  &ApproveDeployment{ApprovalId: "a1"},
+			ExpectedData: CommitMessageData{ApprovalId: "a1"},
+		},
+		db.EvtRejectDeployment: {
+			Transformer:  &RejectDeployment{ApprovalId: "a1"},
+			ExpectedData: CommitMessageData{ApprovalId: "a1"},
+		},
+		db.EvtPromoteAAWave: {
+			Transformer:  &PromoteAAWave{Application: "app", Environment: "prod", Version: 3},
+			ExpectedData: CommitMessageData{Application: "app", Environment: "prod"},
+		},
+		db.EvtCancelQueuedVersion: {
+			Transformer:  &CancelQueuedVersion{Application: "app", Environment: "dev", Version: 3},
+			ExpectedData: CommitMessageData{Application: "app", Environment: "dev"},
+		},
+		db.EvtDeployQueuedVersion: {
+			Transformer:  &DeployQueuedVersion{Application: "app", Environment: "dev", Version: 3},
+			ExpectedData: CommitMessageData{Application: "app", Environment: "dev"},
+		},
+		db.EvtSetReleaseRetentionPolicy: {
+			Transformer:  &SetReleaseRetentionPolicy{Scope: db.ReleaseRetentionScopeApplication, Name: "app"},
+			ExpectedData: CommitMessageData{Application: "app"},
+		},
+		db.EvtDeleteReleaseRetentionPolicy: {
+			Transformer:  &DeleteReleaseRetentionPolicy{Scope: db.ReleaseRetentionScopeTeam, Name: "team-a"},
+			ExpectedData: CommitMessageData{Team: "team-a"},
+		},
+	}
+	for _, eventType := range readEventTypes(t) {
+		t.Run(string(eventType), func(t *testing.T) {
+			tc, ok := tcs[eventType]
+			if notExported[eventType] {
+				if ok {
+					t.Fatalf("event type %s is not exported, but has a test case", eventType)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("event type %s has no test case: add it to commitMessageData and to this test", eventType)
+			}
+			if tc.Transformer.GetDBEventType() != eventType {
+				t.Fatalf("the transformer of %s has the event type %s", eventType, tc.Transformer.GetDBEventType())
+			}
+			tc.Transformer.SetEslVersion(1)
+			//exhaustruct:ignore
+			state := &State{}
+			data, err := commitMessageData(context.Background(), state, nil, tc.Transformer, "message")
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := tc.ExpectedData
+			expected.EventType = string(eventType)
+			expected.Message = "message"
+			expected.EslId = 1
+			if diff := cmp.Diff(expected, data); diff != "" {
+				t.Errorf("data mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-billy/v5/util"
+	git "github.com/libgit2/git2go/v34"
+	"go.uber.org/zap"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	"github.com/freiheit-com/kuberpult/pkg/logging"
+	kpfs "github.com/freiheit-com/kuberpult/services/manifest-repo-export-service/pkg/fs"
+)
+
+// The export directory contains one snapshot directory per exported commit and the symlink `current`,
+// which points to the latest snapshot. Readers (e.g. rsync) should always read through `current`.
+const (
+	exportCurrentLink    = "current"
+	exportSnapshotPrefix = ".export-"
+	exportLinkPrefix     = ".current-"
+)
+
+// exportDirectory writes the tree of the branch into a new snapshot and then swaps the `current` symlink to it.
+// Renaming the symlink is atomic, so readers see either the old or the new snapshot, but never a partial one.
+func (r *repository) exportDirectory(ctx context.Context) error {
+	span, ctx := tracer.StartSpanFromContext(ctx, "exportDirectory")
+	defer span.Finish()
+
+	dir := r.config.ExportDirectory
+	head, err := r.GetHeadCommitId()
+	if err != nil {
+		return fmt.Errorf("reading head commit: %w", err)
+	}
+	commit, err := r.repository.LookupCommit(head)
+	if err != nil {
+		return fmt.Errorf("looking up commit %s: %w", head, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return fmt.Errorf("looking up tree of commit %s: %w", head, err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating export directory: %w", err)
+	}
+
+	currentLink := filepath.Join(dir, exportCurrentLink)
+	previous, err := os.Readlink(currentLink)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("reading %s: %w", currentLink, err)
+	}
+	snapshot := exportSnapshotPrefix + head.String()
+	if previous == snapshot {
+		return nil
+	}
+	staging := filepath.Join(dir, snapshot)
+	// a previous export may have been interrupted
+	if err := os.RemoveAll(staging); err != nil {
+		return fmt.Errorf("removing %s: %w", staging, err)
+	}
+	if err := writeTree(r.repository, tree, staging); err != nil {
+		return fmt.Errorf("writing commit %s to %s: %w", head, staging, err)
+	}
+
+	link := filepath.Join(dir, exportLinkPrefix+head.String())
+	if err := os.Remove(link); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("removing %s: %w", link, err)
+	}
+	if err := os.Symlink(snapshot, link); err != nil {
+		return fmt.Errorf("creating symlink %s: %w", link, err)
+	}
+	if err := os.Rename(link, currentLink); err != nil {
+		return fmt.Errorf("swapping %s: %w", currentLink, err)
+	}
+	logging.Info(ctx, "exported manifests to directory", zap.String("directory", dir), zap.String("commit", head.String()))
+
+	// The previous snapshot is kept, because readers may still be copying it.
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("reading export directory: %w", err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if name == snapshot || name == previous || !(strings.HasPrefix(name, exportSnapshotPrefix) || strings.HasPrefix(name, exportLinkPrefix)) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, name)); err != nil {
+			logging.Warn(ctx, "could not remove old export.", zap.String("name", name), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+func writeTree(repo *git.Repository, tree *git.Tree, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return tree.Walk(func(root string, entry *git.TreeEntry) error {
+		target := filepath.Join(dir, filepath.FromSlash(root), entry.Name)
+		switch entry.Filemode {
+		case git.FilemodeTree:
+			return os.MkdirAll(target, 0755)
+		case git.FilemodeBlob, git.FilemodeBlobExecutable, git.FilemodeLink:
+			blob, err := repo.LookupBlob(entry.Id)
+			if err != nil {
+				return err
+			}
+			defer blob.Free()
+			if entry.Filemode == git.FilemodeLink {
+				return os.Symlink(string(blob.Contents()), target)
+			}
+			var perm os.FileMode = 0644
+			if entry.Filemode == git.FilemodeBlobExecutable {
+				perm = 0755
+			}
+			return os.WriteFile(target, blob.Contents(), perm)
+		default:
+			return fmt.Errorf("unsupported file mode %o of %s", entry.Filemode, target)
+		}
+	})
+}
+
+// readExportDirectory returns the commit of the current snapshot, or nil if nothing was exported yet.
+// If the commit is not in the local repository (e.g. after a restart), the snapshot is imported as a new commit.
+// The snapshot contains only the files, so this commit has no parents and the history before the restart is lost.
+func (r *repository) readExportDirectory(ctx context.Context) (*git.Oid, error) {
+	dir := r.config.ExportDirectory
+	snapshot, err := os.Readlink(filepath.Join(dir, exportCurrentLink))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading current export: %w", err)
+	}
+	if oid, err := git.NewOid(strings.TrimPrefix(snapshot, exportSnapshotPrefix)); err == nil {
+		if _, err := r.repository.LookupCommit(oid); err == nil {
+			return oid, nil
+		}
+	}
+
+	logging.Info(ctx, "importing exported manifests", zap.String("directory", dir), zap.String("snapshot", snapshot))
+	filesystem := kpfs.NewEmptyTreeBuildFS(r.repository)
+	source := filepath.Join(dir, snapshot)
+	err = filepath.WalkDir(source, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		name := filepath.ToSlash(rel)
+		switch {
+		case entry.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return filesystem.Symlink(target, name)
+		case entry.IsDir():
+			return filesystem.MkdirAll(name, 0777)
+		default:
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			return util.WriteFile(filesystem, name, content, 0666)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("importing %s: %w", source, err)
+	}
+	treeId, err := filesystem.Insert()
+	if err != nil {
+		return nil, err
+	}
+	tree, err := r.repository.LookupTree(treeId)
+	if err != nil {
+		return nil, err
+	}
+	sig := r.makeGitSignature()
+	return r.repository.CreateCommit("", sig, sig, fmt.Sprintf("Import exported manifests from %s", snapshot), tree)
+}
+
+// resetToExportDirectory is FetchAndReset for the export directory.
+func (r *repository) resetToExportDirectory(ctx context.Context) error {
+	rev, err := r.readExportDirectory(ctx)
+	if err != nil {
+		return err
+	}
+	branch := fmt.Sprintf("refs/heads/%s", r.config.Branch)
+	if rev == nil {
+		ref, err := r.repository.References.Lookup(branch)
+		if err != nil {
+			var gerr *git.GitError
+			if errors.As(err, &gerr) && gerr.Code == git.ErrorCodeNotFound {
+				return nil
+			}
+			return err
+		}
+		return ref.Delete()
+	}
+	_, err = r.repository.References.Create(branch, rev, true, "reset branch")
+	return err
+}
@@ -0,0 +1,332 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/google/go-cmp/cmp"
+	git "github.com/libgit2/git2go/v34"
+
+	"github.com/freiheit-com/kuberpult/pkg/config"
+	"github.com/freiheit-com/kuberpult/pkg/testutilauth"
+	"github.com/freiheit-com/kuberpult/pkg/types"
+	"github.com/freiheit-com/kuberpult/services/manifest-repo-export-service/pkg/argocd"
+	"github.com/freiheit-com/kuberpult/services/manifest-repo-export-service/pkg/fs"
+)
+
+// commitFiles creates a commit on master with the files and symlinks (name -> target) and returns its id.
+func commitFiles(t *testing.T, repo *git.Repository, files map[string]string, symlinks map[string]string) *git.Oid {
+	filesystem := fs.NewEmptyTreeBuildFS(repo)
+	for name, content := range files {
+		if err := util.WriteFile(filesystem, name, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, target := range symlinks {
+		if err := filesystem.MkdirAll(filepath.Dir(name), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := filesystem.Symlink(target, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	treeId, err := filesystem.Insert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := repo.LookupTree(treeId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := &git.Signature{Name: "test", Email: "test@example.com", When: time.Now()}
+	var parents []*git.Commit
+	if ref, err := repo.References.Lookup("refs/heads/master"); err == nil {
+		parent, err := repo.LookupCommit(ref.Target())
+		if err != nil {
+			t.Fatal(err)
+		}
+		parents = append(parents, parent)
+	}
+	oid, err := repo.CreateCommit("refs/heads/master", sig, sig, "test", tree, parents...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return oid
+}
+
+// readExport returns all files (content) and symlinks ("-> target") below dir.
+func readExport(t *testing.T, dir string) map[string]string {
+	result := map[string]string{}
+	// filepath.Walk does not follow the symlink `current`
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			result[rel] = "-> " + target
+		case !info.IsDir():
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			result[rel] = string(content)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestExportDirectory(t *testing.T) {
+	ctx := context.Background()
+	exportDir := filepath.Join(t.TempDir(), "export")
+	repo, err := git.InitRepository(t.TempDir(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &repository{
+		config: &RepositoryConfig{
+			Branch:          "master",
+			CommitterName:   "kuberpult",
+			CommitterEmail:  "kuberpult@example.com",
+			ExportDirectory: exportDir,
+		},
+		repository: repo,
+	}
+
+	files := map[string]string{
+		"environments/dev/applications/app/manifests/manifests.yaml":  "kind: ConfigMap\n",
+		"applications/app/releases/1/environments/dev/manifests.yaml": "kind: ConfigMap\n",
+		"argocd/v1alpha1/dev.yaml":                                    "kind: Application\n",
+	}
+	symlinks := map[string]string{
+		"environments/dev/applications/app/version": "../../../../applications/app/releases/1",
+	}
+	commitFiles(t, repo, files, symlinks)
+	if err := r.exportDirectory(ctx); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"environments/dev/applications/app/manifests/manifests.yaml":  "kind: ConfigMap\n",
+		"applications/app/releases/1/environments/dev/manifests.yaml": "kind: ConfigMap\n",
+		"argocd/v1alpha1/dev.yaml":                                    "kind: Application\n",
+		"environments/dev/applications/app/version":                   "-> ../../../../applications/app/releases/1",
+	}
+	if diff := cmp.Diff(expected, readExport(t, filepath.Join(exportDir, exportCurrentLink))); diff != "" {
+		t.Errorf("export mismatch (-want, +got):\n%s", diff)
+	}
+	// exporting the same commit again changes nothing
+	if err := r.exportDirectory(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	files["argocd/v1alpha1/dev.yaml"] = "kind: Application\nmetadata: {}\n"
+	second := commitFiles(t, repo, files, symlinks)
+	if err := r.exportDirectory(ctx); err != nil {
+		t.Fatal(err)
+	}
+	third := commitFiles(t, repo, files, nil)
+	if err := r.exportDirectory(ctx); err != nil {
+		t.Fatal(err)
+	}
+	current, err := os.Readlink(filepath.Join(exportDir, exportCurrentLink))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(exportSnapshotPrefix+third.String(), current); diff != "" {
+		t.Errorf("current snapshot mismatch (-want, +got):\n%s", diff)
+	}
+	entries, err := os.ReadDir(exportDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	// only the current and the previous snapshot are kept
+	expectedNames := []string{exportSnapshotPrefix + second.String(), exportSnapshotPrefix + third.String(), exportCurrentLink}
+	sort.Strings(expectedNames)
+	if diff := cmp.Diff(expectedNames, names); diff != "" {
+		t.Errorf("export directory mismatch (-want, +got):\n%s", diff)
+	}
+
+	// the commit of the current snapshot is known to this repository
+	rev, err := r.readExportDirectory(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(third.String(), rev.String()); diff != "" {
+		t.Errorf("commit mismatch (-want, +got):\n%s", diff)
+	}
+
+	// a new repository (e.g. after a restart) imports the current snapshot
+	newRepo, err := git.InitRepository(t.TempDir(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2 := &repository{
+		config:     r.config,
+		repository: newRepo,
+	}
+	rev, err = r2.readExportDirectory(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := newRepo.LookupCommit(rev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported := fs.NewTreeBuildFS(newRepo, commit.TreeId())
+	for name, content := range files {
+		actual, err := util.ReadFile(imported, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(content, string(actual)); diff != "" {
+			t.Errorf("imported %s mismatch (-want, +got):\n%s", name, diff)
+		}
+	}
+	if err := r2.resetToExportDirectory(ctx); err != nil {
+		t.Fatal(err)
+	}
+	head, err := r2.GetHeadCommitId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.IsZero() {
+		t.Errorf("expected the branch to point to the imported commit")
+	}
+}
+
+func TestExportDirectoryRepoURL(t *testing.T) {
+	const repoURL = "https://git.air-gapped.example.com/manifests.git"
+	_, dbHandler, repoConfig := SetupRepositoryTestWithDB(t)
+	exportDir := filepath.Join(t.TempDir(), "export")
+	repoConfig.Path = filepath.Join(t.TempDir(), "repository")
+	repoConfig.ExportDirectory = exportDir
+
+	ctx := testutilauth.MakeTestContext()
+	repoConfig.URL = ""
+	_, err := New(ctx, *repoConfig)
+	if err == nil || !strings.Contains(err.Error(), "KUBERPULT_EXPORT_REPO_URL") {
+		t.Fatalf("expected an error about the missing url, got %v", err)
+	}
+
+	repoConfig.URL = repoURL
+	r, err := New(ctx, *repoConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := r.(*repository)
+	transformers := []Transformer{
+		&CreateEnvironment{
+			Environment: "production",
+			Config: config.EnvironmentConfig{Upstream: &config.EnvironmentConfigUpstream{Latest: true}, ArgoCd: &config.EnvironmentConfigArgoCd{
+				Destination: config.ArgoCdDestination{Server: "production"},
+			}},
+			TransformerMetadata: TransformerMetadata{AuthorName: "test", AuthorEmail: "testmail@example.com"},
+		},
+		&CreateEnvironment{
+			Environment: "flux",
+			Config: config.EnvironmentConfig{Upstream: &config.EnvironmentConfigUpstream{Latest: true}, ArgoCd: &config.EnvironmentConfigArgoCd{
+				Destination: config.ArgoCdDestination{Server: "flux"},
+				FluxCd:      &config.EnvironmentConfigFluxCd{},
+			}},
+			TransformerMetadata: TransformerMetadata{AuthorName: "test", AuthorEmail: "testmail@example.com"},
+		},
+		&CreateApplicationVersion{
+			Application:         "test",
+			Manifests:           map[types.EnvName]string{"production": "manifest"},
+			Version:             1,
+			TransformerMetadata: TransformerMetadata{AuthorName: "test", AuthorEmail: "testmail@example.com"},
+		},
+		&DeployApplicationVersion{
+			Application:         "test",
+			Environment:         "production",
+			Version:             1,
+			TransformerMetadata: TransformerMetadata{AuthorName: "test", AuthorEmail: "testmail@example.com"},
+		},
+	}
+	for i, tr := range transformers {
+		err := dbHandler.WithTransaction(ctx, false, func(ctx context.Context, transaction *sql.Tx) error {
+			prepareDatabaseLikeCdService(ctx, transaction, tr, dbHandler, t, "testmail@example.com", "test")
+			_, applyErr := repo.ApplyTransformer(ctx, transaction, tr)
+			if applyErr != nil {
+				return applyErr
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("transformer[%d]: %v", i, err)
+		}
+	}
+	if err := repo.PushRepo(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	exported := readExport(t, filepath.Join(exportDir, exportCurrentLink))
+	if argo := exported["argocd/v1alpha1/production.yaml"]; !strings.Contains(argo, "repoURL: "+repoURL+"\n") {
+		t.Errorf("expected the argo cd application to point to %s, got:\n%s", repoURL, argo)
+	}
+	if flux := exported[argocd.FluxRootFile("flux")]; !strings.Contains(flux, "url: "+repoURL+"\n") {
+		t.Errorf("expected the flux git repository to point to %s, got:\n%s", repoURL, flux)
+	}
+}
+
+func TestExportDirectoryRejectsTags(t *testing.T) {
+	ctx := context.Background()
+	cfg := &RepositoryConfig{
+		Branch:          "master",
+		ExportDirectory: filepath.Join(t.TempDir(), "export"),
+	}
+	r := &repository{
+		config: cfg,
+	}
+	if err := r.PushTag(ctx, "v1.0.0"); !errors.Is(err, ErrTagsNotExported) {
+		t.Errorf("expected %v, got %v", ErrTagsNotExported, err)
+	}
+	if _, err := GetTags(ctx, nil, *cfg, t.TempDir()); !errors.Is(err, ErrTagsNotExported) {
+		t.Errorf("expected %v, got %v", ErrTagsNotExported, err)
+	}
+}
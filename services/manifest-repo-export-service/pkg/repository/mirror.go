@@ -0,0 +1,453 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	backoff "github.com/cenkalti/backoff/v4"
+	git "github.com/libgit2/git2go/v34"
+	"go.uber.org/zap"
+
+	"github.com/freiheit-com/kuberpult/pkg/logging"
+	"github.com/freiheit-com/kuberpult/pkg/types"
+	"github.com/freiheit-com/kuberpult/services/manifest-repo-export-service/pkg/notify"
+)
+
+// MirrorConfig is an additional remote that receives the branch and the tags after they were pushed to the primary remote.
+type MirrorConfig struct {
+	// Name identifies the mirror in the logs, metrics and the git sync status
+	Name         string
+	URL          string
+	Credentials  Credentials
+	Certificates Certificates
+}
+
+// MirrorStatus is the sync state of one mirror.
+// It is not persisted: after a restart, the mirror reads CommitId and PendingTags from its refs before the first push.
+type MirrorStatus struct {
+	Name string
+	// CommitId is the commit that was last pushed to the mirror, empty if nothing was pushed yet
+	CommitId string
+	// TargetCommitId is the commit that was last pushed to the primary remote
+	TargetCommitId string
+	// PendingTags were pushed to the primary remote, but not yet to the mirror
+	PendingTags []types.GitTag
+	LastAttempt time.Time
+	LastSuccess time.Time
+	// LastError is the error of the last attempt, nil if it was successful
+	LastError           error
+	ConsecutiveFailures uint
+}
+
+// Synced is true if the mirror has everything that was pushed to the primary remote.
+func (s MirrorStatus) Synced() bool {
+	return s.CommitId == s.TargetCommitId && len(s.PendingTags) == 0
+}
+
+// mirrorRef points to the commit that was last pushed to the primary remote.
+// The mirrors push this ref instead of the branch, because the branch may already contain commits that are not pushed yet.
+func mirrorRef(branch string) string {
+	return fmt.Sprintf("refs/kuberpult/mirror/%s", branch)
+}
+
+func defaultMirrorBackOffProvider() backoff.BackOff {
+	eb := backoff.NewExponentialBackOff()
+	eb.InitialInterval = 5 * time.Second
+	eb.MaxInterval = 5 * time.Minute
+	// mirrors are retried until they succeed
+	eb.MaxElapsedTime = 0
+	return eb
+}
+
+// mirror pushes to one mirror remote in the background, so that a slow or unavailable mirror does not block the primary remote.
+type mirror struct {
+	config          MirrorConfig
+	path            string
+	branch          string
+	networkTimeout  time.Duration
+	credentials     *credentialsStore
+	certificates    *certificateStore
+	backOffProvider func() backoff.BackOff
+	ddMetrics       statsd.ClientInterface
+	notify          *notify.Notify
+	trigger         chan struct{}
+	// resynced is true after the state of the mirror was read from its refs. It is only used by run.
+	resynced bool
+
+	mx     sync.Mutex
+	status MirrorStatus
+}
+
+func newMirror(ctx context.Context, cfg MirrorConfig, repoCfg *RepositoryConfig, n *notify.Notify) (*mirror, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("mirror with url '%s' has no name", cfg.URL)
+	}
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("mirror '%s' has no url", cfg.Name)
+	}
+	var credentials *credentialsStore
+	var certificates *certificateStore
+	var err error
+	if strings.HasPrefix(cfg.URL, "./") || strings.HasPrefix(cfg.URL, "/") {
+		logging.Info(ctx, "mirror url indicates a local directory. Ignoring credentials and certificates.", zap.String("mirror", cfg.Name))
+	} else {
+		credentials, err = cfg.Credentials.load()
+		if err != nil {
+			return nil, fmt.Errorf("loading credentials of mirror '%s': %w", cfg.Name, err)
+		}
+		certificates, err = cfg.Certificates.load()
+		if err != nil {
+			return nil, fmt.Errorf("loading certificates of mirror '%s': %w", cfg.Name, err)
+		}
+	}
+	return &mirror{
+		config:          cfg,
+		path:            repoCfg.Path,
+		branch:          repoCfg.Branch,
+		networkTimeout:  repoCfg.NetworkTimeout,
+		credentials:     credentials,
+		certificates:    certificates,
+		backOffProvider: defaultMirrorBackOffProvider,
+		ddMetrics:       repoCfg.DDMetrics,
+		notify:          n,
+		trigger:         make(chan struct{}, 1),
+		resynced:        false,
+		mx:              sync.Mutex{},
+		status: MirrorStatus{
+			Name:                cfg.Name,
+			CommitId:            "",
+			TargetCommitId:      "",
+			PendingTags:         nil,
+			LastAttempt:         time.Time{},
+			LastSuccess:         time.Time{},
+			LastError:           nil,
+			ConsecutiveFailures: 0,
+		},
+	}, nil
+}
+
+// restore is called on startup with the state of the primary remote.
+// All tags of the primary remote are pending until resync removed the ones that the mirror already has.
+func (m *mirror) restore(commitId string, tags []types.GitTag) {
+	m.mx.Lock()
+	m.status.TargetCommitId = commitId
+	m.status.PendingTags = append(append([]types.GitTag(nil), tags...), m.status.PendingTags...)
+	m.mx.Unlock()
+	m.wakeUp()
+}
+
+// commitPushed is called after the primary remote received the commit that mirrorRef points to.
+func (m *mirror) commitPushed(commitId string) {
+	m.mx.Lock()
+	m.status.TargetCommitId = commitId
+	m.mx.Unlock()
+	m.wakeUp()
+}
+
+// tagPushed is called after the primary remote received the tag.
+func (m *mirror) tagPushed(tag types.GitTag) {
+	m.mx.Lock()
+	m.status.PendingTags = append(m.status.PendingTags, tag)
+	m.mx.Unlock()
+	m.wakeUp()
+}
+
+func (m *mirror) wakeUp() {
+	select {
+	case m.trigger <- struct{}{}:
+	default:
+	}
+}
+
+func (m *mirror) Status() MirrorStatus {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	result := m.status
+	result.PendingTags = append([]types.GitTag(nil), m.status.PendingTags...)
+	return result
+}
+
+// run pushes to the mirror whenever the primary remote changed, and retries failed pushes until they succeed.
+func (m *mirror) run(ctx context.Context) {
+	defer logging.HandlePanic(true)
+	eb := m.backOffProvider()
+	var retry <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.trigger:
+		case <-retry:
+		}
+		retry = nil
+		if m.Status().Synced() {
+			continue
+		}
+		err := m.push(ctx)
+		if err != nil {
+			d := eb.NextBackOff()
+			if d == backoff.Stop {
+				d = time.Minute
+			}
+			logging.Warn(ctx, "error pushing to mirror, will try again.", zap.String("mirror", m.config.Name), zap.Duration("retryIn", d), zap.Error(err))
+			retry = time.After(d)
+		} else {
+			eb.Reset()
+		}
+		m.measurePushFailures(ctx, err != nil)
+		m.notify.Notify()
+	}
+}
+
+func (m *mirror) push(ctx context.Context) error {
+	var tags []types.GitTag
+	pushCommit := false
+	commitId := ""
+	err := m.resync(ctx)
+	if err == nil {
+		m.mx.Lock()
+		tags = append([]types.GitTag(nil), m.status.PendingTags...)
+		pushCommit = m.status.CommitId != m.status.TargetCommitId
+		m.mx.Unlock()
+
+		commitId, err = m.pushRefs(ctx, pushCommit, tags)
+	}
+
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	m.status.LastAttempt = time.Now()
+	m.status.LastError = err
+	if err != nil {
+		m.status.ConsecutiveFailures++
+		return err
+	}
+	m.status.ConsecutiveFailures = 0
+	m.status.LastSuccess = m.status.LastAttempt
+	if pushCommit {
+		m.status.CommitId = commitId
+	}
+	// tags that were added during the push stay pending
+	m.status.PendingTags = m.status.PendingTags[len(tags):]
+	return nil
+}
+
+// resync reads the branch and the tags of the mirror once before the first push,
+// so that after a restart the mirror neither receives the branch again nor misses the pending tags.
+func (m *mirror) resync(ctx context.Context) error {
+	if m.resynced {
+		return nil
+	}
+	repo, err := git.OpenRepository(m.path)
+	if err != nil {
+		return fmt.Errorf("opening repository: %w", err)
+	}
+	defer repo.Free()
+
+	var heads []git.RemoteHead
+	//exhaustruct:ignore
+	RemoteCallbacks := git.RemoteCallbacks{
+		CredentialsCallback:      m.credentials.CredentialsCallback(ctx),
+		CertificateCheckCallback: m.certificates.CertificateCheckCallback(ctx),
+	}
+	proxyOptions := git.ProxyOptions{
+		Type: git.ProxyTypeNone,
+		Url:  "",
+	}
+	err = useAnonymousRemote(ctx, repo, m.config.URL, m.networkTimeout, func(remote *git.Remote) error {
+		if err := remote.ConnectFetch(&RemoteCallbacks, &proxyOptions, nil); err != nil {
+			return err
+		}
+		heads, err = remote.Ls()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("listing refs of mirror '%s': %w", m.config.Name, err)
+	}
+	commitId := ""
+	mirroredTags := map[types.GitTag]bool{}
+	for _, head := range heads {
+		if head.Name == fmt.Sprintf("refs/heads/%s", m.branch) {
+			commitId = head.Id.String()
+		} else if tag, ok := strings.CutPrefix(head.Name, "refs/tags/"); ok {
+			// annotated tags are listed twice, the second time with the peeled commit
+			mirroredTags[types.GitTag(strings.TrimSuffix(tag, "^{}"))] = true
+		}
+	}
+
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	m.status.CommitId = commitId
+	pendingTags := []types.GitTag{}
+	for _, tag := range m.status.PendingTags {
+		if !mirroredTags[tag] {
+			pendingTags = append(pendingTags, tag)
+			// tags may be pending twice, if they were pushed after the startup, but before resync
+			mirroredTags[tag] = true
+		}
+	}
+	m.status.PendingTags = pendingTags
+	m.resynced = true
+	return nil
+}
+
+// pushRefs pushes mirrorRef and the tags and returns the commit that mirrorRef pointed to.
+func (m *mirror) pushRefs(ctx context.Context, pushCommit bool, tags []types.GitTag) (string, error) {
+	// libgit2 repositories must not be shared between goroutines, so the mirror opens its own.
+	repo, err := git.OpenRepository(m.path)
+	if err != nil {
+		return "", fmt.Errorf("opening repository: %w", err)
+	}
+	defer repo.Free()
+
+	refspecs := []string{}
+	expected := map[string]bool{}
+	commitId := ""
+	if pushCommit {
+		ref, err := repo.References.Lookup(mirrorRef(m.branch))
+		if err != nil {
+			return "", fmt.Errorf("looking up %s: %w", mirrorRef(m.branch), err)
+		}
+		commitId = ref.Target().String()
+		// the mirrors follow the primary remote, so they are overwritten even if they diverged
+		refspecs = append(refspecs, fmt.Sprintf("+%s:refs/heads/%s", mirrorRef(m.branch), m.branch))
+		expected[fmt.Sprintf("refs/heads/%s", m.branch)] = false
+	}
+	for _, tag := range tags {
+		refspecs = append(refspecs, fmt.Sprintf("refs/tags/%s:refs/tags/%s", tag, tag))
+		expected[fmt.Sprintf("refs/tags/%s", tag)] = false
+	}
+	if len(refspecs) == 0 {
+		return commitId, nil
+	}
+
+	rejected := []string{}
+	//exhaustruct:ignore
+	RemoteCallbacks := git.RemoteCallbacks{
+		CredentialsCallback:      m.credentials.CredentialsCallback(ctx),
+		CertificateCheckCallback: m.certificates.CertificateCheckCallback(ctx),
+		PushUpdateReferenceCallback: func(refName string, status string) error {
+			if status != "" {
+				rejected = append(rejected, fmt.Sprintf("%s: %s", refName, status))
+				return nil
+			}
+			expected[refName] = true
+			return nil
+		},
+	}
+	pushOptions := git.PushOptions{
+		PbParallelism: 0,
+		Headers:       nil,
+		ProxyOptions: git.ProxyOptions{
+			Type: git.ProxyTypeNone,
+			Url:  "",
+		},
+		RemoteCallbacks: RemoteCallbacks,
+	}
+	err = useAnonymousRemote(ctx, repo, m.config.URL, m.networkTimeout, func(remote *git.Remote) error {
+		return remote.Push(refspecs, &pushOptions)
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(rejected) > 0 {
+		sort.Strings(rejected)
+		return "", fmt.Errorf("mirror '%s' rejected %s", m.config.Name, strings.Join(rejected, ", "))
+	}
+	for refName, ok := range expected {
+		if !ok {
+			return "", fmt.Errorf("mirror '%s' did not confirm %s", m.config.Name, refName)
+		}
+	}
+	return commitId, nil
+}
+
+func (m *mirror) measurePushFailures(ctx context.Context, failure bool) {
+	if m.ddMetrics == nil {
+		return
+	}
+	var value float64 = 0
+	if failure {
+		value = 1
+	}
+	if err := m.ddMetrics.Gauge("manifest_export_mirror_push_failures", value, []string{fmt.Sprintf("kuberpult_mirror:%s", m.config.Name)}, 1); err != nil {
+		logging.Error(ctx, "Error in ddMetrics.Gauge.", zap.Error(err))
+	}
+}
+
+// mirrorCommit updates mirrorRef and notifies all mirrors. It must be called after a successful push to the primary remote.
+func (r *repository) mirrorCommit(ctx context.Context) {
+	if len(r.mirrors) == 0 {
+		return
+	}
+	head, err := r.GetHeadCommitId()
+	if err != nil {
+		logging.Error(ctx, "could not read head commit for mirrors.", zap.Error(err))
+		return
+	}
+	if _, err := r.repository.References.Create(mirrorRef(r.config.Branch), head, true, "mirror"); err != nil {
+		logging.Error(ctx, "could not update mirror reference.", zap.Error(err))
+		return
+	}
+	for _, m := range r.mirrors {
+		m.commitPushed(head.String())
+	}
+}
+
+// restoreMirrors starts the mirrors from the commit and the tags of the primary remote, because their sync state is not persisted.
+func (r *repository) restoreMirrors(rev *git.Oid) error {
+	if len(r.mirrors) == 0 || rev == nil {
+		return nil
+	}
+	if _, err := r.repository.References.Create(mirrorRef(r.config.Branch), rev, true, "mirror"); err != nil {
+		return err
+	}
+	names, err := r.repository.Tags.List()
+	if err != nil {
+		return fmt.Errorf("listing tags: %w", err)
+	}
+	sort.Strings(names)
+	tags := make([]types.GitTag, 0, len(names))
+	for _, name := range names {
+		tags = append(tags, types.GitTag(name))
+	}
+	for _, m := range r.mirrors {
+		m.restore(rev.String(), tags)
+	}
+	return nil
+}
+
+func (r *repository) mirrorTag(tag types.GitTag) {
+	for _, m := range r.mirrors {
+		m.tagPushed(tag)
+	}
+}
+
+// MirrorStatuses returns the sync state of the mirrors in the configured order.
+func (r *repository) MirrorStatuses() []MirrorStatus {
+	result := make([]MirrorStatus, 0, len(r.mirrors))
+	for _, m := range r.mirrors {
+		result = append(result, m.Status())
+	}
+	return result
+}
@@ -0,0 +1,265 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"os/exec"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/freiheit-com/kuberpult/pkg/config"
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/testutilauth"
+	"github.com/freiheit-com/kuberpult/pkg/types"
+)
+
+func TestMirrorStatusSynced(t *testing.T) {
+	tcs := []struct {
+		Name     string
+		Status   MirrorStatus
+		Expected bool
+	}{
+		{
+			Name:     "nothing pushed yet",
+			Status:   MirrorStatus{CommitId: "", TargetCommitId: "", PendingTags: nil},
+			Expected: true,
+		},
+		{
+			Name:     "commit pending",
+			Status:   MirrorStatus{CommitId: "a", TargetCommitId: "b", PendingTags: nil},
+			Expected: false,
+		},
+		{
+			Name:     "tag pending",
+			Status:   MirrorStatus{CommitId: "b", TargetCommitId: "b", PendingTags: []types.GitTag{"v1"}},
+			Expected: false,
+		},
+		{
+			Name:     "up to date",
+			Status:   MirrorStatus{CommitId: "b", TargetCommitId: "b", PendingTags: []types.GitTag{}},
+			Expected: true,
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			if actual := tc.Status.Synced(); actual != tc.Expected {
+				t.Errorf("expected %v, got %v", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func initBareRepository(t *testing.T, dir string) {
+	if out, err := exec.Command("git", "init", "--bare", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+}
+
+func revParse(t *testing.T, dir string, ref string) string {
+	out, err := exec.Command("git", "--git-dir", dir, "rev-parse", ref).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git rev-parse %s: %v\n%s", ref, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func waitForMirror(t *testing.T, repo *repository, name string, done func(MirrorStatus) bool) MirrorStatus {
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		for _, status := range repo.MirrorStatuses() {
+			if status.Name == name && done(status) {
+				return status
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("mirror %s did not reach the expected state: %+v", name, repo.MirrorStatuses())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestMirrors(t *testing.T) {
+	_, dbHandler, repoConfig := SetupRepositoryTestWithDB(t)
+	mirrorDir := path.Join(t.TempDir(), "mirror")
+	initBareRepository(t, mirrorDir)
+	repoConfig.Mirrors = []MirrorConfig{
+		{Name: "working", URL: "file://" + mirrorDir, Credentials: Credentials{SshKey: ""}, Certificates: Certificates{KnownHostsFile: ""}},
+		{Name: "broken", URL: "file://" + path.Join(t.TempDir(), "does-not-exist"), Credentials: Credentials{SshKey: ""}, Certificates: Certificates{KnownHostsFile: ""}},
+	}
+	ctx, cancel := context.WithCancel(testutilauth.MakeTestContext())
+	defer cancel()
+	r, err := New(ctx, *repoConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := r.(*repository)
+
+	devEnv := &CreateEnvironment{
+		Environment: "dev",
+		Config: config.EnvironmentConfig{
+			Upstream: &config.EnvironmentConfigUpstream{Latest: true},
+			ArgoCd:   &config.EnvironmentConfigArgoCd{Destination: config.ArgoCdDestination{Server: "dev-server"}},
+		},
+		TransformerMetadata:   TransformerMetadata{AuthorName: "test", AuthorEmail: "test@example.com"},
+		TransformerEslVersion: 1,
+	}
+	err = dbHandler.WithTransaction(ctx, false, func(ctx context.Context, transaction *sql.Tx) error {
+		if err := dbHandler.DBWriteEslEventInternal(ctx, db.EvtCreateEnvironment, transaction, interface{}(nil), db.ESLMetadata{}); err != nil {
+			return err
+		}
+		prepareDatabaseLikeCdService(ctx, transaction, devEnv, dbHandler, t, "test@example.com", "test")
+		return repo.Apply(ctx, transaction, devEnv)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a commit that is not pushed to the primary remote is not mirrored
+	for _, status := range repo.MirrorStatuses() {
+		if status.TargetCommitId != "" {
+			t.Fatalf("expected no target commit before the push, got %+v", status)
+		}
+	}
+
+	// the broken mirror must not fail the push to the primary remote
+	if err := repo.PushRepo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.PushTag(ctx, "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	head, err := repo.GetHeadCommitId()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	working := waitForMirror(t, repo, "working", func(s MirrorStatus) bool {
+		return s.Synced() && s.CommitId != ""
+	})
+	if diff := cmp.Diff(head.String(), working.CommitId); diff != "" {
+		t.Errorf("mirrored commit mismatch (-want, +got):\n%s", diff)
+	}
+	if working.LastError != nil {
+		t.Errorf("expected no error, got %v", working.LastError)
+	}
+	if diff := cmp.Diff(head.String(), revParse(t, mirrorDir, "refs/heads/master")); diff != "" {
+		t.Errorf("mirror branch mismatch (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(head.String(), revParse(t, mirrorDir, "refs/tags/v1.0.0^{commit}")); diff != "" {
+		t.Errorf("mirror tag mismatch (-want, +got):\n%s", diff)
+	}
+
+	broken := waitForMirror(t, repo, "broken", func(s MirrorStatus) bool {
+		return s.LastError != nil
+	})
+	if broken.Synced() || broken.ConsecutiveFailures == 0 {
+		t.Errorf("expected the broken mirror to be unsynced, got %+v", broken)
+	}
+	if diff := cmp.Diff(head.String(), broken.TargetCommitId); diff != "" {
+		t.Errorf("target commit mismatch (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]types.GitTag{"v1.0.0"}, broken.PendingTags); diff != "" {
+		t.Errorf("pending tags mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestMirrorsAfterRestart(t *testing.T) {
+	_, dbHandler, repoConfig := SetupRepositoryTestWithDB(t)
+	syncedDir := path.Join(t.TempDir(), "synced")
+	initBareRepository(t, syncedDir)
+	repoConfig.Mirrors = []MirrorConfig{
+		{Name: "synced", URL: "file://" + syncedDir, Credentials: Credentials{SshKey: ""}, Certificates: Certificates{KnownHostsFile: ""}},
+	}
+	ctx, cancel := context.WithCancel(testutilauth.MakeTestContext())
+	r, err := New(ctx, *repoConfig)
+	if err != nil {
+		cancel()
+		t.Fatal(err)
+	}
+	repo := r.(*repository)
+	devEnv := &CreateEnvironment{
+		Environment: "dev",
+		Config: config.EnvironmentConfig{
+			Upstream: &config.EnvironmentConfigUpstream{Latest: true},
+			ArgoCd:   &config.EnvironmentConfigArgoCd{Destination: config.ArgoCdDestination{Server: "dev-server"}},
+		},
+		TransformerMetadata:   TransformerMetadata{AuthorName: "test", AuthorEmail: "test@example.com"},
+		TransformerEslVersion: 1,
+	}
+	err = dbHandler.WithTransaction(ctx, false, func(ctx context.Context, transaction *sql.Tx) error {
+		if err := dbHandler.DBWriteEslEventInternal(ctx, db.EvtCreateEnvironment, transaction, interface{}(nil), db.ESLMetadata{}); err != nil {
+			return err
+		}
+		prepareDatabaseLikeCdService(ctx, transaction, devEnv, dbHandler, t, "test@example.com", "test")
+		return repo.Apply(ctx, transaction, devEnv)
+	})
+	if err != nil {
+		cancel()
+		t.Fatal(err)
+	}
+	if err := repo.PushRepo(ctx); err != nil {
+		cancel()
+		t.Fatal(err)
+	}
+	if err := repo.PushTag(ctx, "v1.0.0"); err != nil {
+		cancel()
+		t.Fatal(err)
+	}
+	head, err := repo.GetHeadCommitId()
+	if err != nil {
+		cancel()
+		t.Fatal(err)
+	}
+	waitForMirror(t, repo, "synced", func(s MirrorStatus) bool {
+		return s.Synced() && s.CommitId != ""
+	})
+	cancel()
+
+	// the restarted service has a new local repository and a new mirror that has nothing yet
+	addedDir := path.Join(t.TempDir(), "added")
+	initBareRepository(t, addedDir)
+	repoConfig.Path = path.Join(t.TempDir(), "restarted")
+	repoConfig.Mirrors = append(repoConfig.Mirrors, MirrorConfig{Name: "added", URL: "file://" + addedDir, Credentials: Credentials{SshKey: ""}, Certificates: Certificates{KnownHostsFile: ""}})
+	ctx, cancel = context.WithCancel(testutilauth.MakeTestContext())
+	defer cancel()
+	r, err = New(ctx, *repoConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restarted := r.(*repository)
+
+	for _, name := range []string{"synced", "added"} {
+		status := waitForMirror(t, restarted, name, func(s MirrorStatus) bool {
+			return s.Synced() && s.CommitId != ""
+		})
+		if diff := cmp.Diff(head.String(), status.CommitId); diff != "" {
+			t.Errorf("%s: mirrored commit mismatch (-want, +got):\n%s", name, diff)
+		}
+	}
+	if diff := cmp.Diff(head.String(), revParse(t, addedDir, "refs/heads/master")); diff != "" {
+		t.Errorf("mirror branch mismatch (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(head.String(), revParse(t, addedDir, "refs/tags/v1.0.0^{commit}")); diff != "" {
+		t.Errorf("mirror tag mismatch (-want, +got):\n%s", diff)
+	}
+}
@@ -76,6 +76,7 @@ type Repository interface {
 	GetHeadCommitId() (*git.Oid, error)
 	FixCommitsTimestamp(ctx context.Context, state State) error
 	Notify() *notify.Notify
+	MirrorStatuses() []MirrorStatus
 	createCommit(ctx context.Context, state *State, transformer Transformer, commitMsg []string) (_ *git.Oid, _ *git.Oid, resultError *TransformerBatchApplyError)
 }
 
@@ -122,6 +123,10 @@ type repository struct {
 	config       *RepositoryConfig
 	credentials  *credentialsStore
 	certificates *certificateStore
+	// signer is nil if commits and tags are not signed
+	signer signer
+	// mirrors push to the additional remotes in the background
+	mirrors []*mirror
 
 	repository *git.Repository
 
@@ -146,6 +151,10 @@ type RepositoryConfig struct {
 	// Optional Config
 	Credentials    Credentials
 	Certificates   Certificates
+	Signing        Signing
+	CommitMessages *CommitMessageTemplates
+	// Mirrors receive the branch and the tags after each successful push
+	Mirrors        []MirrorConfig
 	CommitterEmail string
 	CommitterName  string
 	// default branch is master
@@ -163,6 +172,9 @@ type RepositoryConfig struct {
 	ArgoCdGenerateFiles bool
 	ArgoProjectNames    *argocd.AllArgoProjectNameOverrides
 	ArgoRenderOptions   *argocd.RenderOptions
+
+	// ExportDirectory replaces the remote: if set, the manifests are written into this directory instead of pushed to URL
+	ExportDirectory string
 }
 
 func openOrCreate(path string) (*git.Repository, error) {
@@ -223,7 +235,12 @@ func New(ctx context.Context, cfg RepositoryConfig) (Repository, error) {
 	var credentials *credentialsStore
 	var certificates *certificateStore
 	var err error
-	if strings.HasPrefix(cfg.URL, "./") || strings.HasPrefix(cfg.URL, "/") {
+	if cfg.ExportDirectory != "" && cfg.ArgoCdGenerateFiles && cfg.URL == "" {
+		return nil, fmt.Errorf("the url of the exported repository is required to generate the Argo CD and Flux files, set KUBERPULT_EXPORT_REPO_URL")
+	}
+	if cfg.ExportDirectory != "" {
+		logging.Info(ctx, "manifests are exported to a directory. Ignoring credentials and certificates.")
+	} else if strings.HasPrefix(cfg.URL, "./") || strings.HasPrefix(cfg.URL, "/") {
 		logging.Info(ctx, "git url indicates a local directory. Ignoring credentials and certificates.")
 	} else {
 		credentials, err = cfg.Credentials.load()
@@ -235,86 +252,127 @@ func New(ctx context.Context, cfg RepositoryConfig) (Repository, error) {
 			return nil, err
 		}
 	}
+	signer, err := cfg.Signing.load()
+	if err != nil {
+		return nil, err
+	}
 
-	if repo2, err := openOrCreate(cfg.Path); err != nil {
+	repo2, err := openOrCreate(cfg.Path)
+	if err != nil {
 		return nil, err
+	}
+	result := &repository{
+		config:           &cfg,
+		credentials:      credentials,
+		certificates:     certificates,
+		signer:           signer,
+		repository:       repo2,
+		backOffProvider:  defaultBackOffProvider,
+		DB:               cfg.DBHandler,
+		notify:           notify.Notify{},
+		ddMetrics:        cfg.DDMetrics,
+		ArgoProjectNames: cfg.ArgoProjectNames,
+		mirrors:          nil,
+	}
+	for _, mirrorCfg := range cfg.Mirrors {
+		m, err := newMirror(ctx, mirrorCfg, &cfg, &result.notify)
+		if err != nil {
+			return nil, err
+		}
+		result.mirrors = append(result.mirrors, m)
+	}
+	var rev *git.Oid
+	if cfg.ExportDirectory != "" {
+		// the export directory replaces the remote, so the branch starts where the last export ended
+		rev, err = result.readExportDirectory(ctx)
+		if err != nil {
+			return nil, err
+		}
 	} else {
-		// configure remotes
-		if remote, err := repo2.Remotes.CreateAnonymous(cfg.URL); err != nil {
+		rev, err = result.fetchRemoteBranch(ctx)
+		if err != nil {
 			return nil, err
-		} else {
-			result := &repository{
-				config:           &cfg,
-				credentials:      credentials,
-				certificates:     certificates,
-				repository:       repo2,
-				backOffProvider:  defaultBackOffProvider,
-				DB:               cfg.DBHandler,
-				notify:           notify.Notify{},
-				ddMetrics:        cfg.DDMetrics,
-				ArgoProjectNames: cfg.ArgoProjectNames,
-			}
-			fetchSpec := fmt.Sprintf("+refs/heads/%s:refs/remotes/origin/%s", cfg.Branch, cfg.Branch)
-			//exhaustruct:ignore
-			RemoteCallbacks := git.RemoteCallbacks{
-				UpdateTipsCallback: func(refname string, a *git.Oid, b *git.Oid) error {
-					return nil
-				},
-				CredentialsCallback:      credentials.CredentialsCallback(ctx),
-				CertificateCheckCallback: certificates.CertificateCheckCallback(ctx),
-			}
-			fetchOptions := git.FetchOptions{
-				Prune:           git.FetchPruneUnspecified,
-				UpdateFetchhead: false,
-				DownloadTags:    git.DownloadTagsUnspecified,
-				Headers:         nil,
-				ProxyOptions: git.ProxyOptions{
-					Type: git.ProxyTypeNone,
-					Url:  "",
-				},
-				RemoteCallbacks: RemoteCallbacks,
-			}
-			err := remote.Fetch([]string{fetchSpec}, &fetchOptions, "fetching")
-			if err != nil {
-				return nil, err
-			}
-			var rev *git.Oid
-			if remoteRef, err := repo2.References.Lookup(fmt.Sprintf("refs/remotes/origin/%s", cfg.Branch)); err != nil {
-				var gerr *git.GitError
-				if errors.As(err, &gerr) && gerr.Code == git.ErrorCodeNotFound {
-					// not found
-					// nothing to do
-				} else {
-					return nil, err
-				}
-			} else {
-				rev = remoteRef.Target()
-				if _, err := repo2.References.Create(fmt.Sprintf("refs/heads/%s", cfg.Branch), rev, true, "reset branch"); err != nil {
-					return nil, err
-				}
-			}
+		}
+	}
+	if rev != nil {
+		if _, err := repo2.References.Create(fmt.Sprintf("refs/heads/%s", cfg.Branch), rev, true, "reset branch"); err != nil {
+			return nil, err
+		}
+		// the mirrors catch up with the primary remote even if there are no new events
+		if err := result.restoreMirrors(rev); err != nil {
+			return nil, err
+		}
+	}
 
-			// check that we can build the current state
-			state, err := result.StateAt(nil)
-			if err != nil {
-				return nil, err
-			}
+	// check that we can build the current state
+	state, err := result.StateAt(nil)
+	if err != nil {
+		return nil, err
+	}
 
-			if state == nil || state.DBHandler == nil {
-				return nil, fmt.Errorf("no database configured")
-			}
-			// Check configuration for errors and abort early if any:
-			err = state.DBHandler.WithTransaction(ctx, true, func(ctx context.Context, transaction *sql.Tx) error {
-				_, err = state.GetEnvironmentConfigsAndValidate(ctx, transaction)
-				return err
-			})
-			if err != nil {
-				return nil, err
-			}
+	if state == nil || state.DBHandler == nil {
+		return nil, fmt.Errorf("no database configured")
+	}
+	// Check configuration for errors and abort early if any:
+	err = state.DBHandler.WithTransaction(ctx, true, func(ctx context.Context, transaction *sql.Tx) error {
+		_, err = state.GetEnvironmentConfigsAndValidate(ctx, transaction)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range result.mirrors {
+		go m.run(ctx)
+	}
+	return result, nil
+}
 
-			return result, nil
+// fetchRemoteBranch fetches the branch from the remote and returns its commit, or nil if the remote has no such branch.
+func (r *repository) fetchRemoteBranch(ctx context.Context) (*git.Oid, error) {
+	remote, err := r.repository.Remotes.CreateAnonymous(r.config.URL)
+	if err != nil {
+		return nil, err
+	}
+	fetchSpecs := []string{fmt.Sprintf("+refs/heads/%s:refs/remotes/origin/%s", r.config.Branch, r.config.Branch)}
+	if len(r.mirrors) > 0 {
+		// the mirrors need the tags of the primary remote to find their pending tags
+		fetchSpecs = append(fetchSpecs, "+refs/tags/*:refs/tags/*")
+	}
+	//exhaustruct:ignore
+	RemoteCallbacks := git.RemoteCallbacks{
+		UpdateTipsCallback: func(refname string, a *git.Oid, b *git.Oid) error {
+			return nil
+		},
+		CredentialsCallback:      r.credentials.CredentialsCallback(ctx),
+		CertificateCheckCallback: r.certificates.CertificateCheckCallback(ctx),
+	}
+	fetchOptions := git.FetchOptions{
+		Prune:           git.FetchPruneUnspecified,
+		UpdateFetchhead: false,
+		DownloadTags:    git.DownloadTagsUnspecified,
+		Headers:         nil,
+		ProxyOptions: git.ProxyOptions{
+			Type: git.ProxyTypeNone,
+			Url:  "",
+		},
+		RemoteCallbacks: RemoteCallbacks,
+	}
+	err = remote.Fetch(fetchSpecs, &fetchOptions, "fetching")
+	if err != nil {
+		return nil, err
+	}
+	remoteRef, err := r.repository.References.Lookup(fmt.Sprintf("refs/remotes/origin/%s", r.config.Branch))
+	if err != nil {
+		var gerr *git.GitError
+		if errors.As(err, &gerr) && gerr.Code == git.ErrorCodeNotFound {
+			// not found
+			// nothing to do
+			return nil, nil
 		}
+		return nil, err
 	}
+	return remoteRef.Target(), nil
 }
 
 func (r *repository) applyTransformerBatches(ctx context.Context, transformer Transformer, allowFetchAndReset bool, transaction *sql.Tx) (*TransformerResult, error) {
@@ -341,11 +399,15 @@ func (r *repository) applyTransformerBatches(ctx context.Context, transformer Tr
 }
 
 func (r *repository) useRemote(callback func(*git.Remote) error) error {
-	remote, err := r.repository.Remotes.CreateAnonymous(r.config.URL)
+	return useAnonymousRemote(context.Background(), r.repository, r.config.URL, r.config.NetworkTimeout, callback)
+}
+
+func useAnonymousRemote(ctx context.Context, repo *git.Repository, url string, timeout time.Duration, callback func(*git.Remote) error) error {
+	remote, err := repo.Remotes.CreateAnonymous(url)
 	if err != nil {
-		return fmt.Errorf("opening remote %q: %w", r.config.URL, err)
+		return fmt.Errorf("opening remote %q: %w", url, err)
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), r.config.NetworkTimeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 	errCh := make(chan error, 1)
 	go func() {
@@ -449,6 +511,13 @@ func (r *repository) ProcessQueueOnce(ctx context.Context, t Transformer, tx *sq
 }
 
 func (r *repository) PushRepo(ctx context.Context) error {
+	if r.config.ExportDirectory != "" {
+		if err := r.exportDirectory(ctx); err != nil {
+			return fmt.Errorf("could not export to directory '%s': %w", r.config.ExportDirectory, err)
+		}
+		r.mirrorCommit(ctx)
+		return nil
+	}
 	var pushSuccess = true
 	//exhaustruct:ignore
 	RemoteCallbacks := git.RemoteCallbacks{
@@ -491,6 +560,7 @@ func (r *repository) PushRepo(ctx context.Context) error {
 			return fmt.Errorf("failed to push - this indicates that branch protection is enabled in '%s' on branch '%s'", r.config.URL, r.config.Branch)
 		}
 	}
+	r.mirrorCommit(ctx)
 	return nil
 }
 
@@ -683,6 +753,18 @@ func (r *repository) ApplyTransformer(ctx context.Context, transaction *sql.Tx,
 			return nil, &TransformerBatchApplyError{TransformerError: fmt.Errorf("%s: %w", "failure in afterTransform", err), Index: -1}
 		}
 
+		if r.config.CommitMessages != nil {
+			data, err := commitMessageData(ctx, state, transaction, transformer, strings.Join(commitMsg, "\n"))
+			if err != nil {
+				return nil, &TransformerBatchApplyError{TransformerError: fmt.Errorf("%s: %w", "failure collecting commit message data", err), Index: -1}
+			}
+			message, err := r.config.CommitMessages.Render(data)
+			if err != nil {
+				return nil, &TransformerBatchApplyError{TransformerError: fmt.Errorf("%s: %w", "failure rendering commit message", err), Index: -1}
+			}
+			commitMsg = []string{message}
+		}
+
 		oldCommitId, newCommitId, applyError := r.createCommit(ctx, state, transformer, commitMsg)
 		if applyError != nil {
 			return nil, applyError
@@ -732,6 +814,7 @@ func (r *repository) createCommit(ctx context.Context, state *State, transformer
 		Email:          transformerMetadata.AuthorEmail,
 		Name:           transformerMetadata.AuthorName,
 		DexAuthContext: nil,
+		Identity:       "",
 	}
 
 	author := &git.Signature{
@@ -747,14 +830,20 @@ func (r *repository) createCommit(ctx context.Context, state *State, transformer
 	}
 	oldCommitId := rev
 
-	newCommitId, createErr := r.repository.CreateCommitFromIds(
-		fmt.Sprintf("refs/heads/%s", r.config.Branch),
-		author,
-		committer,
-		strings.Join(commitMsg, "\n"),
-		treeId,
-		rev,
-	)
+	var newCommitId *git.Oid
+	var createErr error
+	if r.signer == nil {
+		newCommitId, createErr = r.repository.CreateCommitFromIds(
+			fmt.Sprintf("refs/heads/%s", r.config.Branch),
+			author,
+			committer,
+			strings.Join(commitMsg, "\n"),
+			treeId,
+			rev,
+		)
+	} else {
+		newCommitId, createErr = r.createSignedCommit(author, committer, strings.Join(commitMsg, "\n"), treeId, state.Commit)
+	}
 	if createErr != nil {
 		return nil, nil, &TransformerBatchApplyError{
 			TransformerError: fmt.Errorf("%s: %w", "createCommitFromIds failed", createErr),
@@ -764,6 +853,66 @@ func (r *repository) createCommit(ctx context.Context, state *State, transformer
 	return oldCommitId, newCommitId, nil
 }
 
+// createSignedCommit creates a commit like CreateCommitFromIds, but with a signature in the gpgsig header.
+func (r *repository) createSignedCommit(author, committer *git.Signature, message string, treeId *git.Oid, parent *git.Commit) (*git.Oid, error) {
+	tree, err := r.repository.LookupTree(treeId)
+	if err != nil {
+		return nil, fmt.Errorf("lookupTree: %w", err)
+	}
+	defer tree.Free()
+	parents := []*git.Commit{}
+	if parent != nil {
+		parents = append(parents, parent)
+	}
+	content, err := r.repository.CreateCommitBuffer(author, committer, git.MessageEncodingUTF8, message, tree, parents...)
+	if err != nil {
+		return nil, fmt.Errorf("createCommitBuffer: %w", err)
+	}
+	signature, err := r.signer.sign(content)
+	if err != nil {
+		return nil, fmt.Errorf("signing commit: %w", err)
+	}
+	// libgit2 adds the newline after the header itself
+	commitId, err := r.repository.CreateCommitWithSignature(string(content), strings.TrimSuffix(signature, "\n"), "")
+	if err != nil {
+		return nil, fmt.Errorf("createCommitWithSignature: %w", err)
+	}
+	_, err = r.repository.References.Create(fmt.Sprintf("refs/heads/%s", r.config.Branch), commitId, true, fmt.Sprintf("commit: %s", strings.SplitN(message, "\n", 2)[0]))
+	if err != nil {
+		return nil, fmt.Errorf("updating branch: %w", err)
+	}
+	return commitId, nil
+}
+
+// createSignedTag creates an annotated tag like Tags.Create, but with a signature appended to the message, as `git tag -s` does.
+func (r *repository) createSignedTag(name string, target *git.Oid, tagger *git.Signature, message string) (*git.Oid, error) {
+	offset := tagger.Offset()
+	sign := '+'
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+	content := fmt.Sprintf("object %s\ntype commit\ntag %s\ntagger %s <%s> %d %c%02d%02d\n\n%s\n",
+		target, name, tagger.Name, tagger.Email, tagger.When.Unix(), sign, offset/60, offset%60, strings.TrimSuffix(message, "\n"))
+	signature, err := r.signer.sign([]byte(content))
+	if err != nil {
+		return nil, fmt.Errorf("signing tag: %w", err)
+	}
+	odb, err := r.repository.Odb()
+	if err != nil {
+		return nil, fmt.Errorf("odb: %w", err)
+	}
+	tagId, err := odb.Write([]byte(content+signature), git.ObjectTag)
+	if err != nil {
+		return nil, fmt.Errorf("writing tag: %w", err)
+	}
+	_, err = r.repository.References.Create(fmt.Sprintf("refs/tags/%s", name), tagId, false, "")
+	if err != nil {
+		return nil, fmt.Errorf("creating tag reference: %w", err)
+	}
+	return tagId, nil
+}
+
 func (r *repository) makeGitSignature() *git.Signature {
 	return &git.Signature{
 		Name:  r.config.CommitterName,
@@ -784,6 +933,9 @@ func (r *repository) shouldCreateNewCommit(commitMessages []string) bool {
 func (r *repository) FetchAndReset(ctx context.Context) error {
 	span, ctx := tracer.StartSpanFromContext(ctx, "FetchAndReset")
 	defer span.Finish()
+	if r.config.ExportDirectory != "" {
+		return r.resetToExportDirectory(ctx)
+	}
 	fetchSpec := fmt.Sprintf("+refs/heads/%s:refs/remotes/origin/%s", r.config.Branch, r.config.Branch)
 	//exhaustruct:ignore
 	RemoteCallbacks := git.RemoteCallbacks{
@@ -918,6 +1070,9 @@ func (r *repository) Push(ctx context.Context, pushAction func() error) error {
 func (r *repository) PushTag(ctx context.Context, tag types.GitTag) error {
 	span, ctx := tracer.StartSpanFromContext(ctx, "PushTag")
 	defer span.Finish()
+	if r.config.ExportDirectory != "" {
+		return fmt.Errorf("could not push tag '%s': %w", tag, ErrTagsNotExported)
+	}
 
 	currentCommit, err := r.GetHeadCommitId()
 	if err != nil {
@@ -930,7 +1085,11 @@ func (r *repository) PushTag(ctx context.Context, tag types.GitTag) error {
 
 	sig := r.makeGitSignature()
 	tagMessage := fmt.Sprintf("Kuberpult-generated tag %s", tag)
-	_, err = r.repository.Tags.Create(string(tag), lookedUpCommit, sig, tagMessage)
+	if r.signer == nil {
+		_, err = r.repository.Tags.Create(string(tag), lookedUpCommit, sig, tagMessage)
+	} else {
+		_, err = r.createSignedTag(string(tag), currentCommit, sig, tagMessage)
+	}
 	if err != nil {
 		return fmt.Errorf("tag.Create: %w", err)
 	}
@@ -965,6 +1124,7 @@ func (r *repository) PushTag(ctx context.Context, tag types.GitTag) error {
 	if !pushResult.Success {
 		return fmt.Errorf("push ran with success='%v' Status='%s', actualRefName='%s', expectedRefName='%s'", pushResult.Success, pushResult.Status, pushResult.ActualRefName, pushResult.ExpectedRefName)
 	}
+	r.mirrorTag(tag)
 	return nil
 }
 
@@ -1026,7 +1186,7 @@ func (r *repository) updateArgoCdApps(ctx context.Context, transaction *sql.Tx,
 					continue
 				}
 			}
-			err := r.processApp(ctx, transaction, state, env, cfg.ArgoCdConfigs.CommonEnvPrefix, currentArgoCdConfiguration, true, ts, eslVersion, fsMutex)
+			err := r.processApp(ctx, transaction, state, env, cfg.ArgoCdConfigs.CommonEnvPrefix, currentArgoCdConfiguration, true, cfg.ArgoCdConfigs.Waves != nil, ts, eslVersion, fsMutex)
 			if err != nil {
 				return err
 			}
@@ -1048,7 +1208,7 @@ func (r *repository) updateArgoCdApps(ctx context.Context, transaction *sql.Tx,
 			conf = cfg.ArgoCd
 		}
 
-		err := r.processApp(ctx, transaction, state, env, nil, conf, false, ts, eslVersion, fsMutex)
+		err := r.processApp(ctx, transaction, state, env, nil, conf, false, false, ts, eslVersion, fsMutex)
 
 		if err != nil {
 			return err
@@ -1065,6 +1225,7 @@ func (r *repository) processApp(
 	commonEnvPrefix *string,
 	currentArgoCdConfiguration *config.EnvironmentConfigArgoCd,
 	isAAEnv bool,
+	waves bool,
 	ts time.Time,
 	eslVersion db.TransformerID,
 	fsMutex *sync.Mutex,
@@ -1093,7 +1254,7 @@ func (r *repository) processApp(
 			environmentInfo.ArgoProjectNameOverride = ""
 		}
 	}
-	err := r.processArgoAppForEnv(ctx, transaction, state, environmentInfo, ts, eslVersion, fsMutex)
+	err := r.processArgoAppForEnv(ctx, transaction, state, environmentInfo, waves, ts, eslVersion, fsMutex)
 	return err
 }
 
@@ -1105,7 +1266,7 @@ func appTeamsToMap(appTeams []db.AppWithTeam) map[types.AppName]string {
 	return result
 }
 
-func (r *repository) processArgoAppForEnv(ctx context.Context, transaction *sql.Tx, state *State, info *argocd.EnvironmentInfo, timestamp time.Time, eslVersion db.TransformerID, fsMutex *sync.Mutex) error {
+func (r *repository) processArgoAppForEnv(ctx context.Context, transaction *sql.Tx, state *State, info *argocd.EnvironmentInfo, waves bool, timestamp time.Time, eslVersion db.TransformerID, fsMutex *sync.Mutex) error {
 	_, appTeams, err := state.DBHandler.DBSelectEnvironmentApplicationsAtTimestamp(ctx, transaction, info.ParentEnvironmentName, timestamp)
 	if err != nil {
 		return err
@@ -1121,6 +1282,12 @@ func (r *repository) processArgoAppForEnv(ctx context.Context, transaction *sql.
 		return fmt.Errorf("could not find bracket at %v: %w", eslVersion, err)
 	}
 	appData := CalculateAppDataWithBrackets(ctx, allBrackets, appTeams, deploymentsPerApp)
+	if opts := r.config.ArgoRenderOptions; waves && opts != nil && !opts.PointToBrackets {
+		appData, err = applyWavesSynced(state.Filesystem, info, appData, fsMutex)
+		if err != nil {
+			return err
+		}
+	}
 	spanCollectData.Finish()
 
 	spanRenderAndWrite, ctx := tracer.StartSpanFromContext(ctx, "RenderAndWrite")
@@ -1205,6 +1372,7 @@ func CalculateAppDataWithBrackets(
 		appData = append(appData, argocd.AppData{
 			ArgoAppName:        string(bracketName),
 			ReferencedAppTeams: bracketsTeamNames,
+			ManifestPath:       "",
 		})
 	}
 	slices.SortFunc(appData, func(a, b argocd.AppData) int {
@@ -1213,6 +1381,39 @@ func CalculateAppDataWithBrackets(
 	return appData
 }
 
+func applyWavesSynced(filesystem billy.Filesystem, info *argocd.EnvironmentInfo, appData []argocd.AppData, fsMutex *sync.Mutex) ([]argocd.AppData, error) {
+	fsMutex.Lock()
+	defer fsMutex.Unlock()
+	return ApplyWaves(filesystem, info, appData)
+}
+
+// ApplyWaves points the apps on a concrete environment of an active/active environment that is rolled out in waves
+// to the manifests of that concrete environment. Apps that are rolled out in waves, but that the wave did not reach
+// on this concrete environment yet, are left out. Apps that were never rolled out in waves keep the default manifests.
+func ApplyWaves(filesystem billy.Filesystem, info *argocd.EnvironmentInfo, appData []argocd.AppData) ([]argocd.AppData, error) {
+	result := make([]argocd.AppData, 0, len(appData))
+	for _, data := range appData {
+		app := types.AppName(data.ArgoAppName)
+		if _, err := filesystem.Stat(argocd.WavesDirectory(info.ParentEnvironmentName, app)); err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				return nil, err
+			}
+			result = append(result, data)
+			continue
+		}
+		manifestDirectory := argocd.WaveManifestDirectory(info.ParentEnvironmentName, app, info.ArgoCDConfig.ConcreteEnvName)
+		if _, err := filesystem.Stat(manifestDirectory); err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				return nil, err
+			}
+			continue
+		}
+		data.ManifestPath = manifestDirectory
+		result = append(result, data)
+	}
+	return result, nil
+}
+
 func isAppInBracket(bracketMap map[types.ArgoBracketName]db.AppNames, name types.AppName) bool {
 	for _, value := range bracketMap {
 		if slices.Contains(value, name) {
@@ -1226,7 +1427,7 @@ func writeArgoCdRootEnvManifestsSynced(
 	ctx context.Context,
 	filesystem billy.Filesystem,
 	info *argocd.EnvironmentInfo,
-	manifests map[argocd.ApiVersion][]byte,
+	manifests map[string][]byte,
 	fsMutex *sync.Mutex,
 ) error {
 	span, _, _ := tracing.StartSpanFromContext(ctx, "writeArgoCdRootEnvManifestsSynced") // We have a separate span here to see how long we wait for the mutex
@@ -1240,19 +1441,57 @@ func writeArgoCdRootEnvManifests(
 	ctx context.Context,
 	filesystem billy.Filesystem,
 	info *argocd.EnvironmentInfo,
-	manifests map[argocd.ApiVersion][]byte,
+	manifests map[string][]byte,
 ) error {
 	span, _, onErr := tracing.StartSpanFromContext(ctx, "writeArgoCdRootEnvManifests")
 	defer span.Finish()
-	for apiVersion, content := range manifests {
-		if err := filesystem.MkdirAll(filesystem.Join("argocd", string(apiVersion)), 0777); err != nil {
+	for target, content := range manifests {
+		if err := filesystem.MkdirAll(filepath.Dir(target), 0777); err != nil {
 			return onErr(err)
 		}
-		target := getArgoCdAAEnvFileName(filesystem, types.EnvName(info.CommonPrefix), info.ParentEnvironmentName, types.EnvName(info.ArgoCDConfig.ConcreteEnvName), info.IsAAEnv)
 		if err := util.WriteFile(filesystem, target, content, 0666); err != nil {
 			return onErr(err)
 		}
 	}
+	// an environment that switched between Argo CD and Flux must not keep the files of the other one:
+	for _, target := range argocd.RootFiles(info.GetFullyQualifiedName()) {
+		if _, ok := manifests[target]; ok {
+			continue
+		}
+		if err := removeRootEnvManifest(filesystem, target); err != nil {
+			return onErr(err)
+		}
+	}
+	return nil
+}
+
+// removeRootEnvManifests removes all files of the root app of an environment, both for Argo CD and Flux
+func removeRootEnvManifests(filesystem billy.Filesystem, fullyQualifiedEnvName string) error {
+	for _, target := range argocd.RootFiles(fullyQualifiedEnvName) {
+		if err := removeRootEnvManifest(filesystem, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func removeRootEnvManifest(filesystem billy.Filesystem, target string) error {
+	if _, err := filesystem.Stat(target); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if err := filesystem.Remove(target); err != nil {
+		return fmt.Errorf("error deleting root app file %q: %w", target, err)
+	}
+	// the kustomizations of a Flux environment have their own directory, which must not stay behind empty:
+	dir := filepath.Dir(target)
+	if entries, err := filesystem.ReadDir(dir); err == nil && len(entries) == 0 {
+		if err := filesystem.Remove(dir); err != nil {
+			return fmt.Errorf("error deleting root app directory %q: %w", dir, err)
+		}
+	}
 	return nil
 }
 
@@ -1499,6 +1738,8 @@ func (s *State) WriteAllReleases(ctx context.Context, transaction *sql.Tx, app t
 				IsMinor:         false,
 				IsPrepublish:    false,
 				CiLink:          "",
+				Dependencies:    nil,
+				Signatures:      nil,
 			},
 			Environments: []types.EnvName{},
 		}
@@ -2343,6 +2584,8 @@ func (s *State) GetTeamName(application string) (string, error) {
 
 var ErrInvalidJson = errors.New("JSON file is not valid")
 
+var ErrTagsNotExported = errors.New("git tags are not supported when the manifests are exported to a directory")
+
 func envExists(envConfigs map[types.EnvName]config.EnvironmentConfig, envNameToSearchFor types.EnvName) bool {
 	if _, found := envConfigs[envNameToSearchFor]; found {
 		return true
@@ -2716,6 +2959,9 @@ func (s *State) ProcessQueue(ctx context.Context, transaction *sql.Tx, filesyste
 func GetTags(ctx context.Context, handler *db.DBHandler, cfg RepositoryConfig, repoName string) (tags []*api.TagData, err error) {
 	span, ctx := tracer.StartSpanFromContext(ctx, "getTags")
 	defer func() { span.Finish(tracer.WithError(err)) }()
+	if cfg.ExportDirectory != "" {
+		return nil, ErrTagsNotExported
+	}
 	repo, err := openOrCreate(repoName)
 	if err != nil {
 		return nil, fmt.Errorf("unable to open/create repo: %v", err)
@@ -29,6 +29,8 @@ import (
 
 	"github.com/DataDog/datadog-go/v5/statsd"
 	"github.com/cenkalti/backoff/v4"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	git "github.com/libgit2/git2go/v34"
@@ -292,6 +294,76 @@ func TestCalculateAppDatWithBrackets(t *testing.T) {
 	}
 }
 
+func TestApplyWaves(t *testing.T) {
+	info := &argocd.EnvironmentInfo{
+		ArgoCDConfig:            testutil.MakeDummyArgoCdConfig("de-2"),
+		CommonPrefix:            "aa",
+		ParentEnvironmentName:   "production",
+		IsAAEnv:                 true,
+		ArgoProjectNameOverride: "",
+	}
+	tcs := []struct {
+		Name            string
+		ExistingFiles   []string
+		InputAppData    []argocd.AppData
+		ExpectedAppData []argocd.AppData
+	}{
+		{
+			Name:          "apps that were never rolled out in waves keep their manifests",
+			ExistingFiles: []string{"environments/production/applications/app1/manifests/manifests.yaml"},
+			InputAppData: []argocd.AppData{
+				{ArgoAppName: "app1", ReferencedAppTeams: []string{"t1"}, ManifestPath: ""},
+			},
+			ExpectedAppData: []argocd.AppData{
+				{ArgoAppName: "app1", ReferencedAppTeams: []string{"t1"}, ManifestPath: ""},
+			},
+		},
+		{
+			Name: "apps that the wave reached point to the manifests of the concrete environment",
+			ExistingFiles: []string{
+				"environments/production/applications/app1/waves/de-1/manifests/manifests.yaml",
+				"environments/production/applications/app1/waves/de-2/manifests/manifests.yaml",
+			},
+			InputAppData: []argocd.AppData{
+				{ArgoAppName: "app1", ReferencedAppTeams: []string{"t1"}, ManifestPath: ""},
+			},
+			ExpectedAppData: []argocd.AppData{
+				{ArgoAppName: "app1", ReferencedAppTeams: []string{"t1"}, ManifestPath: "environments/production/applications/app1/waves/de-2/manifests"},
+			},
+		},
+		{
+			Name: "apps that the wave did not reach yet are left out",
+			ExistingFiles: []string{
+				"environments/production/applications/app1/waves/de-1/manifests/manifests.yaml",
+				"environments/production/applications/app2/manifests/manifests.yaml",
+			},
+			InputAppData: []argocd.AppData{
+				{ArgoAppName: "app1", ReferencedAppTeams: []string{"t1"}, ManifestPath: ""},
+				{ArgoAppName: "app2", ReferencedAppTeams: []string{"t2"}, ManifestPath: ""},
+			},
+			ExpectedAppData: []argocd.AppData{
+				{ArgoAppName: "app2", ReferencedAppTeams: []string{"t2"}, ManifestPath: ""},
+			},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			fs := memfs.New()
+			for _, file := range tc.ExistingFiles {
+				if err := util.WriteFile(fs, file, []byte("manifest"), 0666); err != nil {
+					t.Fatal(err)
+				}
+			}
+			actualAppData, err := ApplyWaves(fs, info, tc.InputAppData)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			testutil.DiffOrFail(t, "", tc.ExpectedAppData, actualAppData)
+		})
+	}
+}
+
 func TestRetrySsh(t *testing.T) {
 	tcs := []struct {
 		Name              string
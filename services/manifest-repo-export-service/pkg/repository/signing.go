@@ -0,0 +1,163 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package repository
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	SigningFormatOpenPGP = "openpgp"
+	SigningFormatSSH     = "ssh"
+
+	// sshSignatureNamespace is the namespace git uses for ssh signatures, see `git help config` (gpg.ssh).
+	sshSignatureNamespace = "git"
+)
+
+// Signing configures the key that commits and tags are signed with.
+type Signing struct {
+	// path of an unencrypted private key, signing is disabled if empty
+	KeyPath string
+	// SigningFormatOpenPGP (default) or SigningFormatSSH, like `gpg.format` of git
+	Format string
+}
+
+// signer creates the signature that git stores in the gpgsig header of a commit or appends to the message of a tag.
+type signer interface {
+	sign(content []byte) (string, error)
+}
+
+func (s *Signing) load() (signer, error) {
+	if s.KeyPath == "" {
+		return nil, nil
+	}
+	content, err := os.ReadFile(s.KeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading signing key: %w", err)
+	}
+	switch s.Format {
+	case SigningFormatOpenPGP, "":
+		return newOpenPGPSigner(content)
+	case SigningFormatSSH:
+		return newSSHSigner(content)
+	default:
+		return nil, fmt.Errorf("unknown signing format '%s', expected '%s' or '%s'", s.Format, SigningFormatOpenPGP, SigningFormatSSH)
+	}
+}
+
+type openPGPSigner struct {
+	entity *openpgp.Entity
+}
+
+func newOpenPGPSigner(armoredKey []byte) (*openPGPSigner, error) {
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(armoredKey))
+	if err != nil {
+		return nil, fmt.Errorf("reading openpgp signing key: %w", err)
+	}
+	for _, entity := range entities {
+		if entity.PrivateKey == nil {
+			continue
+		}
+		if entity.PrivateKey.Encrypted {
+			return nil, fmt.Errorf("openpgp signing key %s is encrypted", entity.PrimaryKey.KeyIdString())
+		}
+		return &openPGPSigner{entity: entity}, nil
+	}
+	return nil, fmt.Errorf("no openpgp private key found")
+}
+
+func (s *openPGPSigner) sign(content []byte) (string, error) {
+	var signature strings.Builder
+	if err := openpgp.ArmoredDetachSign(&signature, s.entity, bytes.NewReader(content), nil); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(signature.String(), "\n") + "\n", nil
+}
+
+type sshSigner struct {
+	signer ssh.Signer
+}
+
+func newSSHSigner(pemKey []byte) (*sshSigner, error) {
+	key, err := ssh.ParsePrivateKey(pemKey)
+	if err != nil {
+		return nil, fmt.Errorf("reading ssh signing key: %w", err)
+	}
+	return &sshSigner{signer: key}, nil
+}
+
+// sign creates a signature in the format of `ssh-keygen -Y sign`, which git uses if `gpg.format` is `ssh`.
+// See https://github.com/openssh/openssh-portable/blob/master/PROTOCOL.sshsig for the format.
+func (s *sshSigner) sign(content []byte) (string, error) {
+	hash := sha512.Sum512(content)
+	signedData := append([]byte("SSHSIG"), ssh.Marshal(struct {
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Hash          []byte
+	}{
+		Namespace:     sshSignatureNamespace,
+		Reserved:      "",
+		HashAlgorithm: "sha512",
+		Hash:          hash[:],
+	})...)
+	var signature *ssh.Signature
+	var err error
+	if algorithmSigner, ok := s.signer.(ssh.AlgorithmSigner); ok && s.signer.PublicKey().Type() == ssh.KeyAlgoRSA {
+		// ssh signatures must not use sha1, which is the default for rsa keys
+		signature, err = algorithmSigner.SignWithAlgorithm(rand.Reader, signedData, ssh.KeyAlgoRSASHA512)
+	} else {
+		signature, err = s.signer.Sign(rand.Reader, signedData)
+	}
+	if err != nil {
+		return "", err
+	}
+	blob := append([]byte("SSHSIG"), ssh.Marshal(struct {
+		Version       uint32
+		PublicKey     []byte
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Signature     []byte
+	}{
+		Version:       1,
+		PublicKey:     s.signer.PublicKey().Marshal(),
+		Namespace:     sshSignatureNamespace,
+		Reserved:      "",
+		HashAlgorithm: "sha512",
+		Signature:     ssh.Marshal(signature),
+	})...)
+	encoded := base64.StdEncoding.EncodeToString(blob)
+	var armored strings.Builder
+	armored.WriteString("-----BEGIN SSH SIGNATURE-----\n")
+	for len(encoded) > 70 {
+		armored.WriteString(encoded[:70] + "\n")
+		encoded = encoded[70:]
+	}
+	armored.WriteString(encoded + "\n")
+	armored.WriteString("-----END SSH SIGNATURE-----\n")
+	return armored.String(), nil
+}
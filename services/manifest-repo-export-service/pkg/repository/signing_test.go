@@ -0,0 +1,311 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package repository
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"database/sql"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/google/go-cmp/cmp"
+	git "github.com/libgit2/git2go/v34"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/freiheit-com/kuberpult/pkg/config"
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/testutilauth"
+)
+
+func writeOpenPGPKey(t *testing.T) (*openpgp.Entity, string) {
+	t.Helper()
+	entity, err := openpgp.NewEntity("kuberpult", "", "kuberpult@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.SerializePrivate(w, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(t.TempDir(), "key.asc")
+	if err := os.WriteFile(keyPath, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return entity, keyPath
+}
+
+func writeSSHKey(t *testing.T, privateKey any) (ssh.PublicKey, string) {
+	t.Helper()
+	block, err := ssh.MarshalPrivateKey(privateKey, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(t.TempDir(), "id")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return signer.PublicKey(), keyPath
+}
+
+func verifyOpenPGPSignature(entity *openpgp.Entity, content, signature string) error {
+	_, err := openpgp.CheckArmoredDetachedSignature(openpgp.EntityList{entity}, strings.NewReader(content), strings.NewReader(signature), nil)
+	return err
+}
+
+// verifySSHSignature verifies the signature like `ssh-keygen -Y verify -n git` does.
+func verifySSHSignature(publicKey ssh.PublicKey, content, signature string) error {
+	encoded := strings.TrimPrefix(strings.TrimSpace(signature), "-----BEGIN SSH SIGNATURE-----")
+	encoded = strings.TrimSuffix(encoded, "-----END SSH SIGNATURE-----")
+	blob, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(encoded, "\n", ""))
+	if err != nil {
+		return err
+	}
+	var parsed struct {
+		Version       uint32
+		PublicKey     []byte
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Signature     []byte
+	}
+	if err := ssh.Unmarshal(bytes.TrimPrefix(blob, []byte("SSHSIG")), &parsed); err != nil {
+		return err
+	}
+	if !bytes.Equal(parsed.PublicKey, publicKey.Marshal()) {
+		return errors.New("wrong public key")
+	}
+	if parsed.Namespace != "git" || parsed.HashAlgorithm != "sha512" {
+		return errors.New("wrong namespace or hash algorithm")
+	}
+	var sshSignature ssh.Signature
+	if err := ssh.Unmarshal(parsed.Signature, &sshSignature); err != nil {
+		return err
+	}
+	hash := sha512.Sum512([]byte(content))
+	signedData := append([]byte("SSHSIG"), ssh.Marshal(struct {
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Hash          []byte
+	}{"git", "", "sha512", hash[:]})...)
+	return publicKey.Verify(signedData, &sshSignature)
+}
+
+func TestSigning(t *testing.T) {
+	entity, openPGPKeyPath := writeOpenPGPKey(t)
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed25519PublicKey, ed25519KeyPath := writeSSHKey(t, ed25519Key)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPublicKey, rsaKeyPath := writeSSHKey(t, rsaKey)
+
+	tcs := []struct {
+		Name          string
+		Signing       Signing
+		Verify        func(content, signature string) error
+		ExpectedError string
+	}{
+		{
+			Name:    "openpgp",
+			Signing: Signing{KeyPath: openPGPKeyPath, Format: SigningFormatOpenPGP},
+			Verify: func(content, signature string) error {
+				return verifyOpenPGPSignature(entity, content, signature)
+			},
+		},
+		{
+			Name:    "openpgp is the default",
+			Signing: Signing{KeyPath: openPGPKeyPath, Format: ""},
+			Verify: func(content, signature string) error {
+				return verifyOpenPGPSignature(entity, content, signature)
+			},
+		},
+		{
+			Name:    "ssh with ed25519 key",
+			Signing: Signing{KeyPath: ed25519KeyPath, Format: SigningFormatSSH},
+			Verify: func(content, signature string) error {
+				return verifySSHSignature(ed25519PublicKey, content, signature)
+			},
+		},
+		{
+			Name:    "ssh with rsa key",
+			Signing: Signing{KeyPath: rsaKeyPath, Format: SigningFormatSSH},
+			Verify: func(content, signature string) error {
+				return verifySSHSignature(rsaPublicKey, content, signature)
+			},
+		},
+		{
+			Name:          "ssh key as openpgp",
+			Signing:       Signing{KeyPath: ed25519KeyPath, Format: SigningFormatOpenPGP},
+			ExpectedError: "reading openpgp signing key: openpgp: invalid argument: no armored data found",
+		},
+		{
+			Name:          "unknown format",
+			Signing:       Signing{KeyPath: ed25519KeyPath, Format: "x509"},
+			ExpectedError: "unknown signing format 'x509', expected 'openpgp' or 'ssh'",
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			signer, err := tc.Signing.load()
+			if err != nil {
+				if diff := cmp.Diff(tc.ExpectedError, err.Error()); diff != "" {
+					t.Fatalf("error mismatch (-want, +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.ExpectedError != "" {
+				t.Fatalf("expected error '%s', got none", tc.ExpectedError)
+			}
+			const content = "tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n\nmessage\n"
+			signature, err := signer.sign([]byte(content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasSuffix(signature, "\n") {
+				t.Errorf("expected signature to end with a newline: %q", signature)
+			}
+			if err := tc.Verify(content, signature); err != nil {
+				t.Errorf("expected valid signature, got %v", err)
+			}
+			if err := tc.Verify("other content", signature); err == nil {
+				t.Errorf("expected invalid signature for other content")
+			}
+		})
+	}
+}
+
+func TestSigningDisabled(t *testing.T) {
+	signer, err := (&Signing{KeyPath: "", Format: ""}).load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer != nil {
+		t.Errorf("expected no signer, got %v", signer)
+	}
+}
+
+func TestSignedCommitsAndTags(t *testing.T) {
+	entity, keyPath := writeOpenPGPKey(t)
+	_, dbHandler, repoConfig := SetupRepositoryTestWithDB(t)
+	repoConfig.Signing = Signing{KeyPath: keyPath, Format: SigningFormatOpenPGP}
+	r, err := New(testutilauth.MakeTestContext(), *repoConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := r.(*repository)
+	ctx := testutilauth.MakeTestContext()
+
+	devEnv := &CreateEnvironment{
+		Environment: "dev",
+		Config: config.EnvironmentConfig{
+			Upstream: &config.EnvironmentConfigUpstream{Latest: true},
+			ArgoCd:   &config.EnvironmentConfigArgoCd{Destination: config.ArgoCdDestination{Server: "dev-server"}},
+		},
+		TransformerMetadata:   TransformerMetadata{AuthorName: "test", AuthorEmail: "test@example.com"},
+		TransformerEslVersion: 1,
+	}
+	err = dbHandler.WithTransaction(ctx, false, func(ctx context.Context, transaction *sql.Tx) error {
+		if err := dbHandler.DBWriteEslEventInternal(ctx, db.EvtCreateEnvironment, transaction, interface{}(nil), db.ESLMetadata{}); err != nil {
+			return err
+		}
+		prepareDatabaseLikeCdService(ctx, transaction, devEnv, dbHandler, t, "test@example.com", "test")
+		return repo.Apply(ctx, transaction, devEnv)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	head, err := repo.GetHeadCommitId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := repo.repository.LookupCommit(head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, signedContent, err := commit.ExtractSignature()
+	if err != nil {
+		t.Fatalf("expected a signed commit: %v", err)
+	}
+	if err := verifyOpenPGPSignature(entity, signedContent, signature); err != nil {
+		t.Errorf("expected valid commit signature, got %v", err)
+	}
+
+	if err := repo.PushTag(ctx, "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	tagRef, err := repo.repository.References.Lookup("refs/tags/v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag, err := repo.repository.LookupTag(tagRef.Target())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(head.String(), tag.TargetId().String()); diff != "" {
+		t.Errorf("tag target mismatch (-want, +got):\n%s", diff)
+	}
+	odb, err := repo.repository.Odb()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tagObject, err := odb.Read(tagRef.Target())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tagObject.Type() != git.ObjectTag {
+		t.Fatalf("expected an annotated tag, got %v", tagObject.Type())
+	}
+	rawTag := string(tagObject.Data())
+	signatureStart := strings.Index(rawTag, "-----BEGIN PGP SIGNATURE-----")
+	if signatureStart < 0 {
+		t.Fatalf("expected a signed tag, got:\n%s", rawTag)
+	}
+	if err := verifyOpenPGPSignature(entity, rawTag[:signatureStart], rawTag[signatureStart:]); err != nil {
+		t.Errorf("expected valid tag signature, got %v", err)
+	}
+}
@@ -43,6 +43,7 @@ import (
 	"github.com/freiheit-com/kuberpult/pkg/db"
 	"github.com/freiheit-com/kuberpult/pkg/event"
 	"github.com/freiheit-com/kuberpult/pkg/logging"
+	"github.com/freiheit-com/kuberpult/pkg/mapper"
 	"github.com/freiheit-com/kuberpult/pkg/sorting"
 	"github.com/freiheit-com/kuberpult/pkg/types"
 	"github.com/freiheit-com/kuberpult/pkg/uuid"
@@ -411,9 +412,27 @@ func (c *DeployApplicationVersion) Transform(
 	}
 
 	if state.ArgoRenderOptions.RenderApps {
-		if _, err := writeManifestsIfNoManifestLock(ctx, state.DBHandler, transaction, fsys, fsys.Join(manifestsDir, "manifests.yaml"), manifestContent, types.AppName(c.Application), c.Environment); err != nil {
+		manifestsFile := fsys.Join(manifestsDir, "manifests.yaml")
+		concreteEnvs, err := getWaveEnvironments(ctx, state, transaction, envName)
+		if err != nil {
+			return "", err
+		}
+		if concreteEnvs != nil {
+			// this has to happen before we overwrite the manifests that the other concrete environments still use
+			if err := seedWaveManifests(fsys, envName, types.AppName(c.Application), concreteEnvs[1:], manifestsFile); err != nil {
+				return "", err
+			}
+		}
+		hasManifestLock, err := writeManifestsIfNoManifestLock(ctx, state.DBHandler, transaction, fsys, manifestsFile, manifestContent, types.AppName(c.Application), c.Environment)
+		if err != nil {
 			return "", err
 		}
+		if concreteEnvs != nil && !hasManifestLock {
+			// the first concrete environment gets the new version right away, the others get it when the wave reaches them
+			if err := writeWaveManifests(fsys, envName, types.AppName(c.Application), concreteEnvs[0], manifestContent); err != nil {
+				return "", err
+			}
+		}
 	}
 
 	tCtx.AddAppEnv(types.AppName(c.Application), c.Environment)
@@ -528,6 +547,51 @@ func cleanupBracketFile(ctx context.Context, state *State, transaction *sql.Tx,
 	return nil
 }
 
+// getWaveEnvironments returns the concrete environments of the environment if it is rolled out in waves, see config.WaveEnvironments
+func getWaveEnvironments(ctx context.Context, state *State, transaction *sql.Tx, env types.EnvName) ([]string, error) {
+	dbEnv, err := state.DBHandler.DBSelectEnvironment(ctx, transaction, env)
+	if err != nil {
+		return nil, fmt.Errorf("could not get environment %s: %w", env, err)
+	}
+	if dbEnv == nil {
+		return nil, nil
+	}
+	return config.WaveEnvironments(&dbEnv.Config), nil
+}
+
+// seedWaveManifests copies the current manifests of an app to its concrete environments, when the app is
+// rolled out in waves for the first time. That way the concrete environments keep their version until the wave reaches them.
+func seedWaveManifests(fsys billy.Filesystem, env types.EnvName, app types.AppName, concreteEnvs []string, currentManifestsFile string) error {
+	if _, err := fsys.Stat(argocd.WavesDirectory(env, app)); err == nil {
+		// the app was already rolled out in waves
+		return nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	currentManifest, err := util.ReadFile(fsys, currentManifestsFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			// nothing was deployed so far
+			return nil
+		}
+		return err
+	}
+	for _, concreteEnv := range concreteEnvs {
+		if err := writeWaveManifests(fsys, env, app, concreteEnv, string(currentManifest)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeWaveManifests(fsys billy.Filesystem, env types.EnvName, app types.AppName, concreteEnv string, manifest string) error {
+	dir := argocd.WaveManifestDirectory(env, app, concreteEnv)
+	if err := fsys.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	return writeManifests(fsys, fsys.Join(dir, "manifests.yaml"), manifest)
+}
+
 type CreateEnvironmentLock struct {
 	Authentication        `json:"-"`
 	TransformerMetadata   `json:"metadata"`
@@ -817,7 +881,7 @@ func (c *CreateApplicationVersion) Transform(
 
 // Finds old releases for an application: Checks for the oldest release that is currently deployed on any environment
 // Releases older that the oldest deployed release are eligible for deletion. releaseVersionsLimit
-func findOldApplicationVersions(ctx context.Context, transaction *sql.Tx, state *State, appName string, allEnvironments AllEnvironments) ([]types.ReleaseNumbers, error) {
+func findOldApplicationVersions(ctx context.Context, transaction *sql.Tx, state *State, appName string, allEnvironments AllEnvironments, eslVersion db.TransformerID) ([]types.ReleaseNumbers, error) {
 	// 1) get release in each env:
 	var envConfigs AllEnvironments
 	if allEnvironments == nil {
@@ -853,7 +917,13 @@ func findOldApplicationVersions(ctx context.Context, transaction *sql.Tx, state
 		return types.GreaterOrEqual(versions[i], oldestDeployedVersion)
 	})
 
-	if positionOfOldestVersion < (int(state.ReleaseVersionsLimit) - 1) {
+	// the policy may have changed since the cd-service processed the event
+	policy, err := state.DBHandler.DBSelectReleaseRetentionPolicyForAppAt(ctx, transaction, types.AppName(appName), eslVersion)
+	if err != nil {
+		return nil, err
+	}
+	releaseVersionsLimit := int(policy.ReleaseVersionsLimit(state.ReleaseVersionsLimit))
+	if positionOfOldestVersion < (releaseVersionsLimit - 1) {
 		return nil, nil
 	}
 	indexToKeep := positionOfOldestVersion - 1
@@ -869,14 +939,17 @@ func findOldApplicationVersions(ctx context.Context, transaction *sql.Tx, state
 		} else if !release.Metadata.IsMinor && !release.Metadata.IsPrepublish {
 			majorsCount += 1
 		}
-		if majorsCount >= int(state.ReleaseVersionsLimit) {
+		if majorsCount >= releaseVersionsLimit {
 			break
 		}
 	}
 	if indexToKeep < 0 {
 		return nil, nil
 	}
-	return versions[0:indexToKeep], nil
+	if policy == nil {
+		return versions[0:indexToKeep], nil
+	}
+	return state.DBHandler.DBFilterReleasesRetainedByPolicy(ctx, transaction, types.AppName(appName), policy, mapper.ProdEnvironments(envConfigs), versions[0:indexToKeep], eslVersion)
 }
 
 type CreateEnvironmentTeamLock struct {
@@ -1307,7 +1380,7 @@ func (c *CleanupOldApplicationVersions) Transform(
 	}()
 	fs := state.Filesystem
 	var oldVersions []types.ReleaseNumbers
-	oldVersions, err = findOldApplicationVersions(ctx, transaction, state, c.Application, c.AllEnvironmentsPreloaded)
+	oldVersions, err = findOldApplicationVersions(ctx, transaction, state, c.Application, c.AllEnvironmentsPreloaded, c.TransformerEslVersion)
 	if err != nil {
 		return "", fmt.Errorf("cleanup: could not get application releases for app '%s': %w", c.Application, err)
 	}
@@ -1862,6 +1935,7 @@ func (u *UndeployApplication) Transform(
 type CreateEnvironmentGroupLock struct {
 	Authentication        `json:"-"`
 	TransformerMetadata   `json:"metadata"`
+	EnvironmentGroup      string           `json:"env"`
 	TransformerEslVersion db.TransformerID `json:"-"` // Tags the transformer with EventSourcingLight eslVersion
 	CreationTimestamp     time.Time        `json:"-"`
 }
@@ -1905,6 +1979,7 @@ func (c *CreateEnvironmentGroupLock) Transform(
 type DeleteEnvironmentGroupLock struct {
 	Authentication        `json:"-"`
 	TransformerMetadata   `json:"metadata"`
+	EnvironmentGroup      string           `json:"envGroup"`
 	TransformerEslVersion db.TransformerID `json:"-"` // Tags the transformer with EventSourcingLight eslVersion
 	CreationTimestamp     time.Time        `json:"-"`
 }
@@ -2005,6 +2080,9 @@ func (d *DeleteEnvironment) Transform(ctx context.Context, state *State, t Trans
 		} else if err != nil {
 			return "", fmt.Errorf("error deleting the environment's argocd app file %q: %w", argoCdAppFile, err)
 		}
+		if err := removeRootEnvManifests(fs, string(d.Environment)); err != nil {
+			return "", err
+		}
 	}
 	envDir := fs.Join("environments", string(d.Environment))
 	err = fs.Remove(envDir)
@@ -2123,6 +2201,9 @@ func deleteAAEnvironment(ctx context.Context, fs billy.Filesystem, env types.Env
 	} else if err != nil {
 		return fmt.Errorf("error deleting AA environment's argocd app file %q: %w", argoCdAppFile, err)
 	}
+	if err := removeRootEnvManifests(fs, *envConfig.ArgoCdConfigs.CommonEnvPrefix+"-"+string(env)+"-"+string(concreteEnv)); err != nil {
+		return err
+	}
 	for idx, currentConfig := range envConfig.ArgoCdConfigs.ArgoCdConfigurations {
 		if types.EnvName(currentConfig.ConcreteEnvName) == concreteEnv {
 			envConfig.ArgoCdConfigs.ArgoCdConfigurations = append(envConfig.ArgoCdConfigs.ArgoCdConfigurations[:idx], envConfig.ArgoCdConfigs.ArgoCdConfigurations[idx+1:]...)
@@ -2169,3 +2250,670 @@ func writeEnvironmentConfigurationToManifestRepo(fs billy.Filesystem, configFile
 	}
 	return nil
 }
+
+type CreateEnvironmentFreeze struct {
+	Authentication        `json:"-"`
+	TransformerMetadata   `json:"metadata"`
+	Environment           types.EnvName            `json:"env"`
+	Freeze                config.EnvironmentFreeze `json:"freeze"`
+	TransformerEslVersion db.TransformerID         `json:"-"` // Tags the transformer with EventSourcingLight eslVersion
+	CreationTimestamp     time.Time                `json:"-"`
+}
+
+func (c *CreateEnvironmentFreeze) GetCreationTimestamp() time.Time {
+	return c.CreationTimestamp
+}
+
+func (c *CreateEnvironmentFreeze) SetCreationTimestamp(ts time.Time) {
+	c.CreationTimestamp = ts
+}
+
+var _ Transformer = &CreateEnvironmentFreeze{} // ensure we implement the interface
+
+func (c *CreateEnvironmentFreeze) GetGitTag() types.GitTag {
+	return ""
+}
+
+func (c *CreateEnvironmentFreeze) GetEslVersion() db.TransformerID {
+	return c.TransformerEslVersion
+}
+
+func (c *CreateEnvironmentFreeze) SetEslVersion(eslVersion db.TransformerID) {
+	c.TransformerEslVersion = eslVersion
+}
+
+func (c *CreateEnvironmentFreeze) GetDBEventType() db.EventType {
+	return db.EvtCreateEnvironmentFreeze
+}
+
+func (c *CreateEnvironmentFreeze) Transform(
+	_ context.Context,
+	_ *State,
+	_ TransformerContext,
+	_ *sql.Tx,
+) (string, error) {
+	// Freezes are only stored in the environment config in the database, there is nothing to write to the manifest repo
+	return GetNoOpMessage(c)
+}
+
+type DeleteEnvironmentFreeze struct {
+	Authentication        `json:"-"`
+	TransformerMetadata   `json:"metadata"`
+	Environment           types.EnvName    `json:"env"`
+	FreezeId              string           `json:"freezeId"`
+	TransformerEslVersion db.TransformerID `json:"-"` // Tags the transformer with EventSourcingLight eslVersion
+	CreationTimestamp     time.Time        `json:"-"`
+}
+
+func (c *DeleteEnvironmentFreeze) GetCreationTimestamp() time.Time {
+	return c.CreationTimestamp
+}
+
+func (c *DeleteEnvironmentFreeze) SetCreationTimestamp(ts time.Time) {
+	c.CreationTimestamp = ts
+}
+
+var _ Transformer = &DeleteEnvironmentFreeze{} // ensure we implement the interface
+
+func (c *DeleteEnvironmentFreeze) GetGitTag() types.GitTag {
+	return ""
+}
+
+func (c *DeleteEnvironmentFreeze) GetEslVersion() db.TransformerID {
+	return c.TransformerEslVersion
+}
+
+func (c *DeleteEnvironmentFreeze) SetEslVersion(eslVersion db.TransformerID) {
+	c.TransformerEslVersion = eslVersion
+}
+
+func (c *DeleteEnvironmentFreeze) GetDBEventType() db.EventType {
+	return db.EvtDeleteEnvironmentFreeze
+}
+
+func (c *DeleteEnvironmentFreeze) Transform(
+	_ context.Context,
+	_ *State,
+	_ TransformerContext,
+	_ *sql.Tx,
+) (string, error) {
+	// Freezes are only stored in the environment config in the database, there is nothing to write to the manifest repo
+	return GetNoOpMessage(c)
+}
+
+type CreateEnvironmentGroupFreeze struct {
+	Authentication        `json:"-"`
+	TransformerMetadata   `json:"metadata"`
+	EnvironmentGroup      string                   `json:"envGroup"`
+	Freeze                config.EnvironmentFreeze `json:"freeze"`
+	TransformerEslVersion db.TransformerID         `json:"-"` // Tags the transformer with EventSourcingLight eslVersion
+	CreationTimestamp     time.Time                `json:"-"`
+}
+
+func (c *CreateEnvironmentGroupFreeze) GetCreationTimestamp() time.Time {
+	return c.CreationTimestamp
+}
+
+func (c *CreateEnvironmentGroupFreeze) SetCreationTimestamp(ts time.Time) {
+	c.CreationTimestamp = ts
+}
+
+var _ Transformer = &CreateEnvironmentGroupFreeze{} // ensure we implement the interface
+
+func (c *CreateEnvironmentGroupFreeze) GetGitTag() types.GitTag {
+	return ""
+}
+
+func (c *CreateEnvironmentGroupFreeze) GetEslVersion() db.TransformerID {
+	return c.TransformerEslVersion
+}
+
+func (c *CreateEnvironmentGroupFreeze) SetEslVersion(eslVersion db.TransformerID) {
+	c.TransformerEslVersion = eslVersion
+}
+
+func (c *CreateEnvironmentGroupFreeze) GetDBEventType() db.EventType {
+	return db.EvtCreateEnvironmentGroupFreeze
+}
+
+func (c *CreateEnvironmentGroupFreeze) Transform(
+	_ context.Context,
+	_ *State,
+	_ TransformerContext,
+	_ *sql.Tx,
+) (string, error) {
+	// Freezes are only stored in the environment config in the database, there is nothing to write to the manifest repo
+	return GetNoOpMessage(c)
+}
+
+type DeleteEnvironmentGroupFreeze struct {
+	Authentication        `json:"-"`
+	TransformerMetadata   `json:"metadata"`
+	EnvironmentGroup      string           `json:"envGroup"`
+	FreezeId              string           `json:"freezeId"`
+	TransformerEslVersion db.TransformerID `json:"-"` // Tags the transformer with EventSourcingLight eslVersion
+	CreationTimestamp     time.Time        `json:"-"`
+}
+
+func (c *DeleteEnvironmentGroupFreeze) GetCreationTimestamp() time.Time {
+	return c.CreationTimestamp
+}
+
+func (c *DeleteEnvironmentGroupFreeze) SetCreationTimestamp(ts time.Time) {
+	c.CreationTimestamp = ts
+}
+
+var _ Transformer = &DeleteEnvironmentGroupFreeze{} // ensure we implement the interface
+
+func (c *DeleteEnvironmentGroupFreeze) GetGitTag() types.GitTag {
+	return ""
+}
+
+func (c *DeleteEnvironmentGroupFreeze) GetEslVersion() db.TransformerID {
+	return c.TransformerEslVersion
+}
+
+func (c *DeleteEnvironmentGroupFreeze) SetEslVersion(eslVersion db.TransformerID) {
+	c.TransformerEslVersion = eslVersion
+}
+
+func (c *DeleteEnvironmentGroupFreeze) GetDBEventType() db.EventType {
+	return db.EvtDeleteEnvironmentGroupFreeze
+}
+
+func (c *DeleteEnvironmentGroupFreeze) Transform(
+	_ context.Context,
+	_ *State,
+	_ TransformerContext,
+	_ *sql.Tx,
+) (string, error) {
+	// Freezes are only stored in the environment config in the database, there is nothing to write to the manifest repo
+	return GetNoOpMessage(c)
+}
+
+type CreateDeploymentApproval struct {
+	Authentication        `json:"-"`
+	TransformerMetadata   `json:"metadata"`
+	ApprovalId            string           `json:"approvalId"`
+	TransformerEslVersion db.TransformerID `json:"-"` // Tags the transformer with EventSourcingLight eslVersion
+	CreationTimestamp     time.Time        `json:"-"`
+}
+
+func (c *CreateDeploymentApproval) GetCreationTimestamp() time.Time {
+	return c.CreationTimestamp
+}
+
+func (c *CreateDeploymentApproval) SetCreationTimestamp(ts time.Time) {
+	c.CreationTimestamp = ts
+}
+
+var _ Transformer = &CreateDeploymentApproval{} // ensure we implement the interface
+
+func (c *CreateDeploymentApproval) GetGitTag() types.GitTag {
+	return ""
+}
+
+func (c *CreateDeploymentApproval) GetEslVersion() db.TransformerID {
+	return c.TransformerEslVersion
+}
+
+func (c *CreateDeploymentApproval) SetEslVersion(eslVersion db.TransformerID) {
+	c.TransformerEslVersion = eslVersion
+}
+
+func (c *CreateDeploymentApproval) GetDBEventType() db.EventType {
+	return db.EvtCreateDeploymentApproval
+}
+
+func (c *CreateDeploymentApproval) Transform(
+	_ context.Context,
+	_ *State,
+	_ TransformerContext,
+	_ *sql.Tx,
+) (string, error) {
+	// Nothing is deployed until the approval is granted
+	return GetNoOpMessage(c)
+}
+
+type ApproveDeployment struct {
+	Authentication        `json:"-"`
+	TransformerMetadata   `json:"metadata"`
+	ApprovalId            string           `json:"approvalId"`
+	WriteCommitData       bool             `json:"writeCommitData"`
+	TransformerEslVersion db.TransformerID `json:"-"` // Tags the transformer with EventSourcingLight eslVersion
+	CreationTimestamp     time.Time        `json:"-"`
+}
+
+func (c *ApproveDeployment) GetCreationTimestamp() time.Time {
+	return c.CreationTimestamp
+}
+
+func (c *ApproveDeployment) SetCreationTimestamp(ts time.Time) {
+	c.CreationTimestamp = ts
+}
+
+var _ Transformer = &ApproveDeployment{} // ensure we implement the interface
+
+func (c *ApproveDeployment) GetGitTag() types.GitTag {
+	return ""
+}
+
+func (c *ApproveDeployment) GetEslVersion() db.TransformerID {
+	return c.TransformerEslVersion
+}
+
+func (c *ApproveDeployment) SetEslVersion(eslVersion db.TransformerID) {
+	c.TransformerEslVersion = eslVersion
+}
+
+func (c *ApproveDeployment) GetDBEventType() db.EventType {
+	return db.EvtApproveDeployment
+}
+
+func (c *ApproveDeployment) Transform(
+	ctx context.Context,
+	state *State,
+	t TransformerContext,
+	transaction *sql.Tx,
+) (string, error) {
+	approval, err := state.DBHandler.DBSelectDeploymentApproval(ctx, transaction, c.ApprovalId)
+	if err != nil {
+		return "", err
+	}
+	if approval == nil {
+		return "", fmt.Errorf("deployment approval %s not found", c.ApprovalId)
+	}
+	if train := approval.Request.ReleaseTrain; train != nil {
+		// the release train was executed with the eslVersion of the approval, so it finds its deployments with it
+		err = t.Execute(ctx, &ReleaseTrain{
+			Authentication:        c.Authentication,
+			TransformerMetadata:   c.TransformerMetadata,
+			Target:                train.Target,
+			Team:                  train.Team,
+			CommitHash:            train.CommitHash,
+			WriteCommitData:       c.WriteCommitData,
+			Repo:                  nil,
+			TransformerEslVersion: c.TransformerEslVersion,
+			TargetType:            train.TargetType,
+			GitTag:                train.GitTag,
+			CreationTimestamp:     c.CreationTimestamp,
+		}, transaction)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Approved release train %s by %s", c.ApprovalId, approval.DecidedByEmail), nil
+	}
+	deployments, err := state.DBHandler.DBSelectDeploymentsByTransformerID(ctx, transaction, c.TransformerEslVersion)
+	if err != nil {
+		return "", err
+	}
+	if len(deployments) == 0 {
+		// the deployment was prevented by a lock
+		return GetNoOpMessage(c)
+	}
+	author := ""
+	if request := approval.Request.Deployment; request != nil {
+		author = request.Author
+	}
+	for _, deployment := range deployments {
+		if deployment.ReleaseNumbers.Version == nil {
+			continue
+		}
+		err = t.Execute(ctx, &DeployApplicationVersion{
+			Authentication:           c.Authentication,
+			TransformerMetadata:      c.TransformerMetadata,
+			Environment:              deployment.Env,
+			Application:              string(deployment.App),
+			Version:                  *deployment.ReleaseNumbers.Version,
+			Revision:                 deployment.ReleaseNumbers.Revision,
+			LockBehaviour:            api.LockBehavior_RECORD,
+			WriteCommitData:          c.WriteCommitData,
+			SourceTrain:              nil,
+			Author:                   author,
+			TransformerEslVersion:    c.TransformerEslVersion,
+			CreationTimestamp:        c.CreationTimestamp,
+			AllEnvironmentsPreloaded: nil,
+		}, transaction)
+		if err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("Approved deployment %s by %s", c.ApprovalId, approval.DecidedByEmail), nil
+}
+
+type RejectDeployment struct {
+	Authentication        `json:"-"`
+	TransformerMetadata   `json:"metadata"`
+	ApprovalId            string           `json:"approvalId"`
+	Reason                string           `json:"reason"`
+	TransformerEslVersion db.TransformerID `json:"-"` // Tags the transformer with EventSourcingLight eslVersion
+	CreationTimestamp     time.Time        `json:"-"`
+}
+
+func (c *RejectDeployment) GetCreationTimestamp() time.Time {
+	return c.CreationTimestamp
+}
+
+func (c *RejectDeployment) SetCreationTimestamp(ts time.Time) {
+	c.CreationTimestamp = ts
+}
+
+var _ Transformer = &RejectDeployment{} // ensure we implement the interface
+
+func (c *RejectDeployment) GetGitTag() types.GitTag {
+	return ""
+}
+
+func (c *RejectDeployment) GetEslVersion() db.TransformerID {
+	return c.TransformerEslVersion
+}
+
+func (c *RejectDeployment) SetEslVersion(eslVersion db.TransformerID) {
+	c.TransformerEslVersion = eslVersion
+}
+
+func (c *RejectDeployment) GetDBEventType() db.EventType {
+	return db.EvtRejectDeployment
+}
+
+func (c *RejectDeployment) Transform(
+	_ context.Context,
+	_ *State,
+	_ TransformerContext,
+	_ *sql.Tx,
+) (string, error) {
+	// A rejected deployment was never executed, so there is nothing to remove from the manifest repo
+	return GetNoOpMessage(c)
+}
+
+type CancelQueuedVersion struct {
+	Authentication        `json:"-"`
+	TransformerMetadata   `json:"metadata"`
+	Environment           types.EnvName    `json:"env"`
+	Application           string           `json:"app"`
+	Version               uint64           `json:"version"`
+	TransformerEslVersion db.TransformerID `json:"-"` // Tags the transformer with EventSourcingLight eslVersion
+	CreationTimestamp     time.Time        `json:"-"`
+}
+
+func (c *CancelQueuedVersion) GetCreationTimestamp() time.Time {
+	return c.CreationTimestamp
+}
+
+func (c *CancelQueuedVersion) SetCreationTimestamp(ts time.Time) {
+	c.CreationTimestamp = ts
+}
+
+var _ Transformer = &CancelQueuedVersion{} // ensure we implement the interface
+
+func (c *CancelQueuedVersion) GetGitTag() types.GitTag {
+	return ""
+}
+
+func (c *CancelQueuedVersion) GetEslVersion() db.TransformerID {
+	return c.TransformerEslVersion
+}
+
+func (c *CancelQueuedVersion) SetEslVersion(eslVersion db.TransformerID) {
+	c.TransformerEslVersion = eslVersion
+}
+
+func (c *CancelQueuedVersion) GetDBEventType() db.EventType {
+	return db.EvtCancelQueuedVersion
+}
+
+func (c *CancelQueuedVersion) Transform(
+	_ context.Context,
+	state *State,
+	_ TransformerContext,
+	_ *sql.Tx,
+) (string, error) {
+	if err := state.DeleteQueuedVersionIfExists(c.Environment, c.Application); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Cancelled queued version %d of app %q in env %q", c.Version, c.Application, c.Environment), nil
+}
+
+type DeployQueuedVersion struct {
+	Authentication        `json:"-"`
+	TransformerMetadata   `json:"metadata"`
+	Environment           types.EnvName    `json:"env"`
+	Application           string           `json:"app"`
+	Version               uint64           `json:"version"`
+	Reason                string           `json:"reason"`
+	WriteCommitData       bool             `json:"writeCommitData"`
+	TransformerEslVersion db.TransformerID `json:"-"` // Tags the transformer with EventSourcingLight eslVersion
+	CreationTimestamp     time.Time        `json:"-"`
+}
+
+func (c *DeployQueuedVersion) GetCreationTimestamp() time.Time {
+	return c.CreationTimestamp
+}
+
+func (c *DeployQueuedVersion) SetCreationTimestamp(ts time.Time) {
+	c.CreationTimestamp = ts
+}
+
+var _ Transformer = &DeployQueuedVersion{} // ensure we implement the interface
+
+func (c *DeployQueuedVersion) GetGitTag() types.GitTag {
+	return ""
+}
+
+func (c *DeployQueuedVersion) GetEslVersion() db.TransformerID {
+	return c.TransformerEslVersion
+}
+
+func (c *DeployQueuedVersion) SetEslVersion(eslVersion db.TransformerID) {
+	c.TransformerEslVersion = eslVersion
+}
+
+func (c *DeployQueuedVersion) GetDBEventType() db.EventType {
+	return db.EvtDeployQueuedVersion
+}
+
+func (c *DeployQueuedVersion) Transform(
+	ctx context.Context,
+	state *State,
+	t TransformerContext,
+	transaction *sql.Tx,
+) (string, error) {
+	// the cd-service wrote the deployment with the eslVersion of this event
+	deployments, err := state.DBHandler.DBSelectDeploymentsByTransformerID(ctx, transaction, c.TransformerEslVersion)
+	if err != nil {
+		return "", err
+	}
+	if len(deployments) == 0 {
+		return GetNoOpMessage(c)
+	}
+	for _, deployment := range deployments {
+		if deployment.ReleaseNumbers.Version == nil {
+			continue
+		}
+		err = t.Execute(ctx, &DeployApplicationVersion{
+			Authentication:           c.Authentication,
+			TransformerMetadata:      c.TransformerMetadata,
+			Environment:              deployment.Env,
+			Application:              string(deployment.App),
+			Version:                  *deployment.ReleaseNumbers.Version,
+			Revision:                 deployment.ReleaseNumbers.Revision,
+			LockBehaviour:            api.LockBehavior_IGNORE,
+			WriteCommitData:          c.WriteCommitData,
+			SourceTrain:              nil,
+			Author:                   "",
+			TransformerEslVersion:    c.TransformerEslVersion,
+			CreationTimestamp:        c.CreationTimestamp,
+			AllEnvironmentsPreloaded: nil,
+		}, transaction)
+		if err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("Deployed queued version %d of app %q to env %q ignoring locks: %s", c.Version, c.Application, c.Environment, c.Reason), nil
+}
+
+// PromoteAAWave deploys a version to the next concrete environment of an active/active environment
+// that is rolled out in waves. The event is written by the waves package of the cd-service.
+type PromoteAAWave struct {
+	TransformerMetadata   `json:"metadata"`
+	Environment           types.EnvName    `json:"env"`
+	Application           string           `json:"app"`
+	ConcreteEnvironment   string           `json:"concreteEnv"`
+	Version               uint64           `json:"version"`
+	Revision              uint64           `json:"revision"`
+	TransformerEslVersion db.TransformerID `json:"-"` // Tags the transformer with EventSourcingLight eslVersion
+	CreationTimestamp     time.Time        `json:"-"`
+}
+
+func (c *PromoteAAWave) GetCreationTimestamp() time.Time {
+	return c.CreationTimestamp
+}
+
+func (c *PromoteAAWave) SetCreationTimestamp(ts time.Time) {
+	c.CreationTimestamp = ts
+}
+
+var _ Transformer = &PromoteAAWave{} // ensure we implement the interface
+
+func (c *PromoteAAWave) GetGitTag() types.GitTag {
+	return ""
+}
+
+func (c *PromoteAAWave) GetEslVersion() db.TransformerID {
+	return c.TransformerEslVersion
+}
+
+func (c *PromoteAAWave) SetEslVersion(eslVersion db.TransformerID) {
+	c.TransformerEslVersion = eslVersion
+}
+
+func (c *PromoteAAWave) GetDBEventType() db.EventType {
+	return db.EvtPromoteAAWave
+}
+
+func (c *PromoteAAWave) Transform(
+	ctx context.Context,
+	state *State,
+	tCtx TransformerContext,
+	transaction *sql.Tx,
+) (string, error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "PromoteAAWave::Transform")
+	defer span.Finish()
+	span.SetTag("application", c.Application)
+	span.SetTag("environment", c.Environment)
+	span.SetTag("concreteEnvironment", c.ConcreteEnvironment)
+	if !state.ArgoRenderOptions.RenderApps {
+		return GetNoOpMessage(c)
+	}
+	releaseNumbers := types.MakeReleaseNumbers(c.Version, c.Revision)
+	release, err := state.DBHandler.DBSelectReleaseByVersion(ctx, transaction, types.AppName(c.Application), releaseNumbers, true)
+	if err != nil {
+		return "", err
+	}
+	if release == nil {
+		return "", fmt.Errorf("release of app %s with version %v not found", c.Application, releaseNumbers)
+	}
+	hasLock, err := state.DBHandler.DBHasActiveManifestLock(ctx, transaction, types.AppName(c.Application), c.Environment)
+	if err != nil {
+		return "", err
+	}
+	if hasLock {
+		return GetNoOpMessage(c)
+	}
+	fsys := state.Filesystem
+	if err := writeWaveManifests(fsys, c.Environment, types.AppName(c.Application), c.ConcreteEnvironment, release.Manifests.Manifests[c.Environment]); err != nil {
+		return "", err
+	}
+	tCtx.AddAppEnv(types.AppName(c.Application), c.Environment)
+	return fmt.Sprintf("deployed version %v of %q to %q of %q", releaseNumbers, c.Application, c.ConcreteEnvironment, c.Environment), nil
+}
+
+// SetReleaseRetentionPolicy only changes the policy in the database, it is applied by the next cleanup of old releases.
+type SetReleaseRetentionPolicy struct {
+	Authentication        `json:"-"`
+	TransformerMetadata   `json:"metadata"`
+	Scope                 db.ReleaseRetentionScope `json:"scope"`
+	Name                  string                   `json:"name"`
+	TransformerEslVersion db.TransformerID         `json:"-"` // Tags the transformer with EventSourcingLight eslVersion
+	CreationTimestamp     time.Time                `json:"-"`
+}
+
+func (c *SetReleaseRetentionPolicy) GetCreationTimestamp() time.Time {
+	return c.CreationTimestamp
+}
+
+func (c *SetReleaseRetentionPolicy) SetCreationTimestamp(ts time.Time) {
+	c.CreationTimestamp = ts
+}
+
+var _ Transformer = &SetReleaseRetentionPolicy{} // ensure we implement the interface
+
+func (c *SetReleaseRetentionPolicy) GetGitTag() types.GitTag {
+	return ""
+}
+
+func (c *SetReleaseRetentionPolicy) GetEslVersion() db.TransformerID {
+	return c.TransformerEslVersion
+}
+
+func (c *SetReleaseRetentionPolicy) SetEslVersion(eslVersion db.TransformerID) {
+	c.TransformerEslVersion = eslVersion
+}
+
+func (c *SetReleaseRetentionPolicy) GetDBEventType() db.EventType {
+	return db.EvtSetReleaseRetentionPolicy
+}
+
+func (c *SetReleaseRetentionPolicy) Transform(
+	_ context.Context,
+	_ *State,
+	_ TransformerContext,
+	_ *sql.Tx,
+) (string, error) {
+	return GetNoOpMessage(c)
+}
+
+// DeleteReleaseRetentionPolicy only changes the policy in the database, it is applied by the next cleanup of old releases.
+type DeleteReleaseRetentionPolicy struct {
+	Authentication        `json:"-"`
+	TransformerMetadata   `json:"metadata"`
+	Scope                 db.ReleaseRetentionScope `json:"scope"`
+	Name                  string                   `json:"name"`
+	TransformerEslVersion db.TransformerID         `json:"-"` // Tags the transformer with EventSourcingLight eslVersion
+	CreationTimestamp     time.Time                `json:"-"`
+}
+
+func (c *DeleteReleaseRetentionPolicy) GetCreationTimestamp() time.Time {
+	return c.CreationTimestamp
+}
+
+func (c *DeleteReleaseRetentionPolicy) SetCreationTimestamp(ts time.Time) {
+	c.CreationTimestamp = ts
+}
+
+var _ Transformer = &DeleteReleaseRetentionPolicy{} // ensure we implement the interface
+
+func (c *DeleteReleaseRetentionPolicy) GetGitTag() types.GitTag {
+	return ""
+}
+
+func (c *DeleteReleaseRetentionPolicy) GetEslVersion() db.TransformerID {
+	return c.TransformerEslVersion
+}
+
+func (c *DeleteReleaseRetentionPolicy) SetEslVersion(eslVersion db.TransformerID) {
+	c.TransformerEslVersion = eslVersion
+}
+
+func (c *DeleteReleaseRetentionPolicy) GetDBEventType() db.EventType {
+	return db.EvtDeleteReleaseRetentionPolicy
+}
+
+func (c *DeleteReleaseRetentionPolicy) Transform(
+	_ context.Context,
+	_ *State,
+	_ TransformerContext,
+	_ *sql.Tx,
+) (string, error) {
+	return GetNoOpMessage(c)
+}
@@ -19,6 +19,7 @@ package service
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"slices"
 	"sync"
@@ -28,6 +29,7 @@ import (
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 
 	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
@@ -70,6 +72,9 @@ func (s *GitServer) GetGitTags(ctx context.Context, _ *api.GetGitTagsRequest) (*
 		return nil, fmt.Errorf("tagsPath must not be empty")
 	}
 	tags, err := repository.GetTags(ctx, s.DBHandler, s.Config, s.Config.TagsPath)
+	if errors.Is(err, repository.ErrTagsNotExported) {
+		return nil, grpcErrors.FailedPrecondition(ctx, err)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("unable to get tags from repository: %v", err)
 	}
@@ -199,9 +204,40 @@ func (s *GitServer) GetGitSyncStatus(ctx context.Context, _ *api.GetGitSyncStatu
 		response.AppStatuses = toApiStatuses(append(unsyncedStatuses, syncFailedStatuses...))
 		return nil
 	})
+	response.MirrorStatuses = toApiMirrorStatuses(s.Repository.MirrorStatuses())
 	return response, err
 }
 
+func toApiMirrorStatuses(statuses []repository.MirrorStatus) []*api.MirrorSyncStatus {
+	result := make([]*api.MirrorSyncStatus, 0, len(statuses))
+	for _, m := range statuses {
+		mirrorStatus := &api.MirrorSyncStatus{
+			Name:                m.Name,
+			Status:              api.GitSyncStatus_GIT_SYNC_STATUS_UNSYNCED,
+			CommitId:            m.CommitId,
+			TargetCommitId:      m.TargetCommitId,
+			PendingTags:         make([]string, 0, len(m.PendingTags)),
+			LastSuccess:         nil,
+			LastError:           "",
+			ConsecutiveFailures: uint64(m.ConsecutiveFailures),
+		}
+		for _, tag := range m.PendingTags {
+			mirrorStatus.PendingTags = append(mirrorStatus.PendingTags, string(tag))
+		}
+		if !m.LastSuccess.IsZero() {
+			mirrorStatus.LastSuccess = timestamppb.New(m.LastSuccess)
+		}
+		if m.LastError != nil {
+			mirrorStatus.Status = api.GitSyncStatus_GIT_SYNC_STATUS_ERROR
+			mirrorStatus.LastError = m.LastError.Error()
+		} else if m.Synced() {
+			mirrorStatus.Status = api.GitSyncStatus_GIT_SYNC_STATUS_SYNCED
+		}
+		result = append(result, mirrorStatus)
+	}
+	return result
+}
+
 func toApiStatuses(statuses []db.GitSyncData) map[string]*api.EnvSyncStatus {
 	toFill := make(map[string]*api.EnvSyncStatus)
 	for _, currStatus := range statuses {
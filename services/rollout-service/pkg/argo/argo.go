@@ -381,6 +381,10 @@ func (a *ArgoAppProcessor) ProcessArgoOverview(ctx context.Context, l *zap.Logge
 				isBracket := currentAppDetails.Application.ArgoBracket == currentApp && a.isBracketEnv(parentEnvironment.Name)
 				if isAAEnv(parentEnvironment.Config) {
 					for _, cfg := range parentEnvironment.Config.ArgoConfigs.Configs { //Active/Active environments have multiple argo cd configurations
+						if cfg.Fluxcd != nil {
+							// rendered for Flux, so there are no Argo CD apps to manage
+							continue
+						}
 						targetEnvName := a.extractFullyQualifiedEnvironmentName(parentEnvironment.Config.ArgoConfigs.CommonEnvPrefix, parentEnvironment.Name, cfg)
 						appInfo := &AppInfo{
 							ApplicationName:              currentApp,
@@ -391,10 +395,11 @@ func (a *ArgoAppProcessor) ProcessArgoOverview(ctx context.Context, l *zap.Logge
 							IsBracket:                    isBracket,
 							BracketSnapshotEslId:         bracketSnapshotEslId,
 							LostMembersTo:                argoOv.LostMembersTo[currentApp],
+							WaveEnvironment:              waveEnvironment(currentAppDetails.Deployments[parentEnvironment.Name], cfg.ConcreteEnvName),
 						}
 						a.ProcessAppChange(ctx, appInfo, currentAppDetails, overview, argoOv.AppDetails)
 					}
-				} else {
+				} else if parentEnvironment.Config.Argocd.GetFluxcd() == nil {
 					appInfo := &AppInfo{
 						ApplicationName:              currentApp,
 						EnvironmentName:              parentEnvironment.Name,
@@ -404,6 +409,7 @@ func (a *ArgoAppProcessor) ProcessArgoOverview(ctx context.Context, l *zap.Logge
 						IsBracket:                    isBracket,
 						BracketSnapshotEslId:         bracketSnapshotEslId,
 						LostMembersTo:                argoOv.LostMembersTo[currentApp],
+						WaveEnvironment:              "",
 					}
 					a.ProcessAppChange(ctx, appInfo, currentAppDetails, overview, argoOv.AppDetails)
 				}
@@ -1211,6 +1217,19 @@ type AppInfo struct {
 	// LostMembersTo names the brackets that gained members this bracket lost in
 	// the current change (only set for bracket apps; see ArgoOverview.LostMembersTo).
 	LostMembersTo []string
+	// WaveEnvironment is the concrete environment of an active/active environment, if the app is rolled
+	// out to it in waves. The manifests of the concrete environment are then in their own directory.
+	WaveEnvironment string
+}
+
+// waveEnvironment returns the concrete environment, if the deployment is rolled out to it in waves, or "" otherwise.
+func waveEnvironment(deployment *api.Deployment, concreteEnv string) string {
+	for _, wave := range deployment.GetWaves() {
+		if wave.ConcreteEnvironment == concreteEnv {
+			return concreteEnv
+		}
+	}
+	return ""
 }
 
 func (a *ArgoAppProcessor) isKnownArgoApp(appName, envName string, appsKnownToArgo map[string]*v1alpha1.Application) *v1alpha1.Application {
@@ -1461,6 +1480,9 @@ func CreateArgoApplication(overview *api.GetOverviewResponse, appInfo *AppInfo)
 			bracketName = fmt.Sprintf("%s@%d", bracketName, appInfo.BracketSnapshotEslId)
 		}
 		manifestPath = filepath.Join("environments", appInfo.ParentEnvironmentName, "brackets", bracketName)
+	} else if appInfo.WaveEnvironment != "" {
+		// same directory as argocd.WaveManifestDirectory in the manifest-repo-export-service
+		manifestPath = filepath.Join("environments", appInfo.ParentEnvironmentName, "applications", appInfo.ApplicationName, "waves", appInfo.WaveEnvironment, "manifests")
 	} else {
 		manifestPath = filepath.Join("environments", appInfo.ParentEnvironmentName, "applications", appInfo.ApplicationName, "manifests")
 	}
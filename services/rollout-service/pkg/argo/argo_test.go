@@ -3163,6 +3163,178 @@ func TestProcessArgoOverviewEmptyBracketsNotDeleted(t *testing.T) {
 	}
 }
 
+func TestProcessArgoOverviewSkipsFluxEnvironments(t *testing.T) {
+	ctx := context.Background()
+	mockClient := &mockApplicationServiceClient{}
+	argoProcessor := &ArgoAppProcessor{
+		ApplicationClient:     mockClient,
+		ManageArgoAppsEnabled: true,
+		ManageArgoAppsFilter:  []string{"*"},
+		KnownApps:             map[string]map[string]*v1alpha1.Application{},
+		trigger:               make(chan argoTrigger, 10),
+		ArgoApps:              make(chan *v1alpha1.ApplicationWatchEvent, 10),
+		pendingDeletions:      []PendingDeletion{},
+
+		maxProcessedTransformerEslId: &atomic.Int64{},
+	}
+	deployment := &api.Deployment{
+		Version: 1,
+		DeploymentMetaData: &api.Deployment_DeploymentMetaData{
+			DeployTime: timestamppb.New(time.Unix(123456789, 0)),
+		},
+	}
+	environment := func(name string, fluxCd *api.ArgoCDEnvironmentConfiguration_FluxCd) *api.Environment {
+		return &api.Environment{
+			Name: name,
+			Config: &api.EnvironmentConfig{
+				Argocd: &api.ArgoCDEnvironmentConfiguration{
+					Destination: &api.ArgoCDEnvironmentConfiguration_Destination{
+						Name:   name,
+						Server: "test-server",
+					},
+					Fluxcd: fluxCd,
+				},
+			},
+		}
+	}
+	argoOv := &ArgoOverview{
+		AppDetails: map[string]*api.GetAppDetailsResponse{
+			"foo": {
+				//exhaustruct:ignore
+				Application: &api.Application{Name: "foo", Team: "team"},
+				Deployments: map[string]*api.Deployment{
+					"development": deployment,
+					"staging":     deployment,
+				},
+			},
+		},
+		Overview: &api.GetOverviewResponse{
+			EnvironmentGroups: []*api.EnvironmentGroup{
+				{
+					EnvironmentGroupName: "group",
+					Environments: []*api.Environment{
+						environment("development", nil),
+						environment("staging", &api.ArgoCDEnvironmentConfiguration_FluxCd{}),
+					},
+				},
+			},
+			GitRevision: "1234",
+		},
+	}
+
+	argoProcessor.ProcessArgoOverview(ctx, logger.FromContext(ctx), argoOv)
+
+	var created []string
+	for _, app := range mockClient.Apps {
+		if app.LastEvent == "ADDED" {
+			created = append(created, app.App.Name)
+		}
+	}
+	if diff := testutil.CmpDiff([]string{"development-foo"}, created); diff != "" {
+		t.Errorf("created apps mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestProcessArgoOverviewWaves(t *testing.T) {
+	ctx := context.Background()
+	mockClient := &mockApplicationServiceClient{}
+	argoProcessor := &ArgoAppProcessor{
+		ApplicationClient:     mockClient,
+		ManageArgoAppsEnabled: true,
+		ManageArgoAppsFilter:  []string{"*"},
+		KnownApps:             map[string]map[string]*v1alpha1.Application{},
+		trigger:               make(chan argoTrigger, 10),
+		ArgoApps:              make(chan *v1alpha1.ApplicationWatchEvent, 10),
+		pendingDeletions:      []PendingDeletion{},
+
+		maxProcessedTransformerEslId: &atomic.Int64{},
+	}
+	deployment := func(waves []*api.WaveDeployment) *api.Deployment {
+		return &api.Deployment{
+			Version: 2,
+			DeploymentMetaData: &api.Deployment_DeploymentMetaData{
+				DeployTime: timestamppb.New(time.Unix(123456789, 0)),
+			},
+			Waves: waves,
+		}
+	}
+	argoOv := &ArgoOverview{
+		AppDetails: map[string]*api.GetAppDetailsResponse{
+			"foo": {
+				//exhaustruct:ignore
+				Application: &api.Application{Name: "foo", Team: "team"},
+				Deployments: map[string]*api.Deployment{
+					"staging": deployment([]*api.WaveDeployment{
+						{ConcreteEnvironment: "de-1", TargetVersion: 2, DeployedVersion: 2},
+						{ConcreteEnvironment: "de-2", TargetVersion: 2, DeployedVersion: 1},
+					}),
+				},
+			},
+			"bar": {
+				//exhaustruct:ignore
+				Application: &api.Application{Name: "bar", Team: "team"},
+				Deployments: map[string]*api.Deployment{
+					// deployed before the environment was rolled out in waves
+					"staging": deployment(nil),
+				},
+			},
+		},
+		Overview: &api.GetOverviewResponse{
+			EnvironmentGroups: []*api.EnvironmentGroup{
+				{
+					EnvironmentGroupName: "group",
+					Environments: []*api.Environment{
+						{
+							Name: "staging",
+							Config: &api.EnvironmentConfig{
+								ArgoConfigs: &api.EnvironmentConfig_ArgoConfigs{
+									CommonEnvPrefix: "test",
+									Configs: []*api.ArgoCDEnvironmentConfiguration{
+										{
+											ConcreteEnvName: "de-1",
+											Destination: &api.ArgoCDEnvironmentConfiguration_Destination{
+												Name:   "staging",
+												Server: "test-server",
+											},
+										},
+										{
+											ConcreteEnvName: "de-2",
+											Destination: &api.ArgoCDEnvironmentConfiguration_Destination{
+												Name:   "staging",
+												Server: "test-server",
+											},
+										},
+									},
+									Waves: &api.EnvironmentConfig_ArgoWaves{},
+								},
+							},
+						},
+					},
+				},
+			},
+			GitRevision: "1234",
+		},
+	}
+
+	argoProcessor.ProcessArgoOverview(ctx, logger.FromContext(ctx), argoOv)
+
+	paths := map[string]string{}
+	for _, app := range mockClient.Apps {
+		if app.LastEvent == "ADDED" {
+			paths[app.App.Name] = app.App.Spec.Source.Path
+		}
+	}
+	expected := map[string]string{
+		"test-staging-de-1-foo": "environments/staging/applications/foo/waves/de-1/manifests",
+		"test-staging-de-2-foo": "environments/staging/applications/foo/waves/de-2/manifests",
+		"test-staging-de-1-bar": "environments/staging/applications/bar/manifests",
+		"test-staging-de-2-bar": "environments/staging/applications/bar/manifests",
+	}
+	if diff := testutil.CmpDiff(expected, paths); diff != "" {
+		t.Errorf("manifest paths mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func TestDrainPendingDeletionsByName(t *testing.T) {
 	tcs := []struct {
 		Name string
@@ -47,6 +47,8 @@ import (
 	"github.com/freiheit-com/kuberpult/services/rollout-service/pkg/metrics"
 	"github.com/freiheit-com/kuberpult/services/rollout-service/pkg/notifier"
 	"github.com/freiheit-com/kuberpult/services/rollout-service/pkg/revolution"
+	"github.com/freiheit-com/kuberpult/services/rollout-service/pkg/rollback"
+	"github.com/freiheit-com/kuberpult/services/rollout-service/pkg/rolloutevents"
 	"github.com/freiheit-com/kuberpult/services/rollout-service/pkg/service"
 	"github.com/freiheit-com/kuberpult/services/rollout-service/pkg/undeploy"
 	"github.com/freiheit-com/kuberpult/services/rollout-service/pkg/versions"
@@ -91,6 +93,15 @@ type Config struct {
 	RevolutionDoraMaxEventAge time.Duration `default:"0" split_words:"true"`
 	RevolutionDoraDryRun      bool          `split_words:"true" default:"false"`
 
+	AutoRollbackEnabled       bool                     `split_words:"true" default:"false"`
+	AutoRollbackEnvironments  map[string]time.Duration `split_words:"true" default:""`
+	AutoRollbackMaxAge        time.Duration            `split_words:"true" default:"1h"`
+	AutoRollbackCheckInterval time.Duration            `split_words:"true" default:"30s"`
+	AutoRollbackDexRole       string                   `split_words:"true" default:""`
+	AutoRollbackDryRun        bool                     `split_words:"true" default:"false"`
+
+	RolloutEventsEnabled bool `split_words:"true" default:"false"`
+
 	ManageArgoApplicationsEnabled bool     `split_words:"true" default:"true"`
 	ManageArgoApplicationsFilter  []string `split_words:"true" default:"sreteam"`
 
@@ -141,6 +152,27 @@ func (config *Config) RevolutionConfig() (revolution.Config, error) {
 	}, nil
 }
 
+func (config *Config) RollbackConfig() (rollback.Config, error) {
+	if len(config.AutoRollbackEnvironments) == 0 {
+		return rollback.Config{}, fmt.Errorf("KUBERPULT_AUTO_ROLLBACK_ENVIRONMENTS must contain at least one environment")
+	}
+	for env, gracePeriod := range config.AutoRollbackEnvironments {
+		if gracePeriod <= 0 {
+			return rollback.Config{}, fmt.Errorf("KUBERPULT_AUTO_ROLLBACK_ENVIRONMENTS: grace period of environment %q must be positive", env)
+		}
+	}
+	if config.AutoRollbackCheckInterval <= 0 {
+		return rollback.Config{}, fmt.Errorf("KUBERPULT_AUTO_ROLLBACK_CHECK_INTERVAL must be positive")
+	}
+	return rollback.Config{
+		GracePeriods:  config.AutoRollbackEnvironments,
+		MaxAge:        config.AutoRollbackMaxAge,
+		CheckInterval: config.AutoRollbackCheckInterval,
+		DexRole:       config.AutoRollbackDexRole,
+		DryRun:        config.AutoRollbackDryRun,
+	}, nil
+}
+
 func RunServer() {
 	var config Config
 	err := logger.Wrap(context.Background(), func(ctx context.Context) error {
@@ -156,13 +188,13 @@ func RunServer() {
 	}
 }
 
-func getGrpcClients(_ context.Context, config Config) (api.OverviewServiceClient, api.VersionServiceClient, error) {
+func getGrpcClients(_ context.Context, config Config) (api.OverviewServiceClient, api.VersionServiceClient, api.BatchServiceClient, error) {
 	const megaBytes int = 1024 * 1024
 	var cred = insecure.NewCredentials()
 	if config.CdServerSecure {
 		systemRoots, err := x509.SystemCertPool()
 		if err != nil {
-			return nil, nil, fmt.Errorf("failed to read CA certificates")
+			return nil, nil, nil, fmt.Errorf("failed to read CA certificates")
 		}
 		//exhaustruct:ignore
 		cred = credentials.NewTLS(&tls.Config{
@@ -187,15 +219,15 @@ func getGrpcClients(_ context.Context, config Config) (api.OverviewServiceClient
 
 	con, err := grpc.NewClient(config.CdServer, grpcClientOpts...)
 	if err != nil {
-		return nil, nil, fmt.Errorf("error dialling %s: %w", config.CdServer, err)
+		return nil, nil, nil, fmt.Errorf("error dialling %s: %w", config.CdServer, err)
 	}
 
 	versionServiceCon, err := grpc.NewClient(config.VersionServer, grpcClientOpts...)
 	if err != nil {
-		return nil, nil, fmt.Errorf("error dialling %s: %w", config.VersionServer, err)
+		return nil, nil, nil, fmt.Errorf("error dialling %s: %w", config.VersionServer, err)
 	}
 
-	return api.NewOverviewServiceClient(con), api.NewVersionServiceClient(versionServiceCon), nil
+	return api.NewOverviewServiceClient(con), api.NewVersionServiceClient(versionServiceCon), api.NewBatchServiceClient(con), nil
 }
 
 func runServer(ctx context.Context, config Config) error {
@@ -288,7 +320,7 @@ func runServer(ctx context.Context, config Config) error {
 	}
 	defer argoio.Close(closer)
 
-	overviewGrpc, versionGrpc, err := getGrpcClients(ctx, config)
+	overviewGrpc, versionGrpc, batchGrpc, err := getGrpcClients(ctx, config)
 	if err != nil {
 		return fmt.Errorf("connecting to cd service %q: %w", config.CdServer, err)
 	}
@@ -379,6 +411,34 @@ func runServer(ctx context.Context, config Config) error {
 		})
 	}
 
+	if config.AutoRollbackEnabled {
+		rollbackConfig, err := config.RollbackConfig()
+		if err != nil {
+			return err
+		}
+		autoRollback := rollback.New(rollbackConfig, batchGrpc, dbHandler)
+		backgroundTasks = append(backgroundTasks, setup.BackgroundTaskConfig{
+			Shutdown: nil,
+			Name:     "auto rollback",
+			Run: func(ctx context.Context, health *setup.HealthReporter) error {
+				health.ReportReady("watching rollouts")
+				return autoRollback.Subscribe(ctx, broadcast)
+			},
+		})
+	}
+
+	if config.RolloutEventsEnabled {
+		rolloutEvents := rolloutevents.New(dbHandler)
+		backgroundTasks = append(backgroundTasks, setup.BackgroundTaskConfig{
+			Shutdown: nil,
+			Name:     "rollout events",
+			Run: func(ctx context.Context, health *setup.HealthReporter) error {
+				health.ReportReady("recording")
+				return rolloutEvents.Subscribe(ctx, broadcast)
+			},
+		})
+	}
+
 	backgroundTasks = append(backgroundTasks, setup.BackgroundTaskConfig{
 		Shutdown: nil,
 		Name:     "create metrics",
@@ -19,9 +19,12 @@ package cmd
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/freiheit-com/kuberpult/services/rollout-service/pkg/rollback"
 )
 
 // Used to compare two error message strings, needed because errors.Is(fmt.Errorf(text),fmt.Errorf(text)) == false
@@ -130,3 +133,62 @@ func TestClientConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestRollbackConfig(t *testing.T) {
+	tcs := []struct {
+		Name           string
+		Config         Config
+		ExpectedError  error
+		ExpectedConfig rollback.Config
+	}{
+		{
+			Name: "valid config",
+			Config: Config{
+				AutoRollbackEnvironments:  map[string]time.Duration{"production": 10 * time.Minute},
+				AutoRollbackMaxAge:        time.Hour,
+				AutoRollbackCheckInterval: 30 * time.Second,
+				AutoRollbackDexRole:       "Developer",
+			},
+			ExpectedConfig: rollback.Config{
+				GracePeriods:  map[string]time.Duration{"production": 10 * time.Minute},
+				MaxAge:        time.Hour,
+				CheckInterval: 30 * time.Second,
+				DexRole:       "Developer",
+				DryRun:        false,
+			},
+		},
+		{
+			Name: "no environments",
+			Config: Config{
+				AutoRollbackCheckInterval: 30 * time.Second,
+			},
+			ExpectedError: errMatcher{"KUBERPULT_AUTO_ROLLBACK_ENVIRONMENTS must contain at least one environment"},
+		},
+		{
+			Name: "no grace period",
+			Config: Config{
+				AutoRollbackEnvironments:  map[string]time.Duration{"production": 0},
+				AutoRollbackCheckInterval: 30 * time.Second,
+			},
+			ExpectedError: errMatcher{"KUBERPULT_AUTO_ROLLBACK_ENVIRONMENTS: grace period of environment \"production\" must be positive"},
+		},
+		{
+			Name: "no check interval",
+			Config: Config{
+				AutoRollbackEnvironments: map[string]time.Duration{"production": 10 * time.Minute},
+			},
+			ExpectedError: errMatcher{"KUBERPULT_AUTO_ROLLBACK_CHECK_INTERVAL must be positive"},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			rollbackConfig, err := tc.Config.RollbackConfig()
+			if diff := cmp.Diff(tc.ExpectedError, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("error mismatch (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.ExpectedConfig, rollbackConfig); diff != "" {
+				t.Errorf("config mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
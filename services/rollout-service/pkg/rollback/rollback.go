@@ -0,0 +1,301 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+// Package rollback rolls back rollouts that stay degraded after a deployment.
+//
+// The Subscriber watches the rollout status of every app on the configured
+// environments. When a freshly deployed version stays in error or unhealthy
+// for longer than the grace period of its environment, the previously
+// deployed version is deployed again through the cd-service. The app is
+// locked in the same batch, so that the degraded version is not deployed
+// again until a human removes the lock.
+package rollback
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
+	"github.com/freiheit-com/kuberpult/pkg/auth"
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/logger"
+	"github.com/freiheit-com/kuberpult/pkg/types"
+	"github.com/freiheit-com/kuberpult/services/rollout-service/pkg/service"
+)
+
+// RollbackUser is the actor of all rollbacks in the cd-service.
+var RollbackUser = auth.User{
+	DexAuthContext: nil,
+	Email:          "kuberpult-auto-rollback@local",
+	Name:           "kuberpult-auto-rollback",
+	Identity:       auth.RolloutServiceIdentity,
+}
+
+// The number of deployments that are searched for a previous version.
+const historyLimit = 20
+
+type Config struct {
+	// GracePeriods maps the environments with automatic rollbacks to the
+	// time a rollout may stay degraded before it is rolled back.
+	GracePeriods map[string]time.Duration
+	// MaxAge is the time after a deployment in which it is considered fresh.
+	// Older deployments are never rolled back. If 0, all deployments are fresh.
+	MaxAge time.Duration
+	// CheckInterval is the time between two checks of the grace periods.
+	CheckInterval time.Duration
+	// DexRole is sent to the cd-service as the role of RollbackUser, if set.
+	DexRole string
+	DryRun  bool
+}
+
+// PreviousVersionFunc returns the version and revision that was deployed before current,
+// or nil if there is none.
+type PreviousVersionFunc func(ctx context.Context, application, environment string, current uint64) (*types.ReleaseNumbers, error)
+
+type degradation struct {
+	version uint64
+	status  api.RolloutStatus
+	since   time.Time
+}
+
+type Subscriber struct {
+	config          Config
+	batchClient     api.BatchServiceClient
+	previousVersion PreviousVersionFunc
+	// degraded rollouts by app and environment
+	state map[service.Key]*degradation
+	// versions restored by a rollback, they are never rolled back themselves
+	restored map[service.Key]uint64
+	// Used to simulate the current time in tests
+	now func() time.Time
+	// The ready function is needed to sync tests
+	ready func()
+}
+
+func New(config Config, batchClient api.BatchServiceClient, dbHandler *db.DBHandler) *Subscriber {
+	return &Subscriber{
+		config:      config,
+		batchClient: batchClient,
+		previousVersion: func(ctx context.Context, application, environment string, current uint64) (*types.ReleaseNumbers, error) {
+			return PreviousVersion(ctx, dbHandler, application, environment, current)
+		},
+		state:    map[service.Key]*degradation{},
+		restored: map[service.Key]uint64{},
+		now:      time.Now,
+		ready:    func() {},
+	}
+}
+
+func (s *Subscriber) Subscribe(ctx context.Context, b *service.Broadcast) error {
+	for {
+		err := s.subscribeOnce(ctx, b)
+		select {
+		case <-ctx.Done():
+			return err
+		default:
+		}
+	}
+}
+
+func (s *Subscriber) subscribeOnce(ctx context.Context, b *service.Broadcast) error {
+	events, ch, unsub := b.Start()
+	defer unsub()
+	for _, ev := range events {
+		s.process(ev)
+	}
+	s.ready()
+	ticker := time.NewTicker(s.config.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			s.process(ev)
+		case <-ticker.C:
+			s.check(ctx)
+		}
+	}
+}
+
+func isDegraded(status api.RolloutStatus) bool {
+	return status == api.RolloutStatus_ROLLOUT_STATUS_ERROR || status == api.RolloutStatus_ROLLOUT_STATUS_UNHEALTHY
+}
+
+// process records since when the deployed version of an app is degraded.
+func (s *Subscriber) process(ev *service.BroadcastEvent) {
+	if _, ok := s.config.GracePeriods[ev.Environment]; !ok {
+		return
+	}
+	if ev.KuberpultVersion == nil || !isDegraded(ev.RolloutStatus) {
+		delete(s.state, ev.Key)
+		return
+	}
+	// brackets bundle several apps, so there is no single previous version for them:
+	version, ok := ev.KuberpultVersion.Version.ToUint64()
+	if !ok {
+		delete(s.state, ev.Key)
+		return
+	}
+	if restored, ok := s.restored[ev.Key]; ok && restored == version {
+		return
+	}
+	if s.config.MaxAge != 0 && ev.KuberpultVersion.DeployedAt.Add(s.config.MaxAge).Before(s.now()) {
+		delete(s.state, ev.Key)
+		return
+	}
+	if current := s.state[ev.Key]; current != nil && current.version == version {
+		// the grace period starts with the first degraded status of a version
+		current.status = ev.RolloutStatus
+		return
+	}
+	s.state[ev.Key] = &degradation{
+		version: version,
+		status:  ev.RolloutStatus,
+		since:   s.now(),
+	}
+}
+
+// check rolls back all rollouts that are degraded for longer than their grace period.
+func (s *Subscriber) check(ctx context.Context) {
+	now := s.now()
+	for key, d := range s.state {
+		gracePeriod := s.config.GracePeriods[key.Environment]
+		if d.since.Add(gracePeriod).After(now) {
+			continue
+		}
+		l := logger.FromContext(ctx).With(
+			zap.String("application", key.Application),
+			zap.String("environment", key.Environment),
+			zap.Uint64("version", d.version),
+		)
+		previous, err := s.previousVersion(ctx, key.Application, key.Environment, d.version)
+		if err != nil {
+			l.Error("rollback.previous.error", zap.Error(err))
+			continue
+		}
+		if previous == nil {
+			l.Warn("rollback.previous.notfound")
+			delete(s.state, key)
+			continue
+		}
+		request := rollbackRequest(key, d, *previous, gracePeriod)
+		if s.config.DryRun {
+			l.Warn("rollback.dryrun", zap.Uint64("previousVersion", *previous.Version), zap.Uint64("previousRevision", previous.Revision))
+			delete(s.state, key)
+			continue
+		}
+		if _, err := s.batchClient.ProcessBatch(s.grpcContext(ctx), request); err != nil {
+			// the rollback is retried with the next check
+			l.Error("rollback.failed", zap.Uint64("previousVersion", *previous.Version), zap.Error(err))
+			continue
+		}
+		l.Info("rollback.done", zap.Uint64("previousVersion", *previous.Version), zap.Uint64("previousRevision", previous.Revision))
+		delete(s.state, key)
+		s.restored[key] = *previous.Version
+	}
+}
+
+func (s *Subscriber) grpcContext(ctx context.Context) context.Context {
+	ctx = auth.WriteUserToGrpcContext(ctx, RollbackUser)
+	if s.config.DexRole != "" {
+		ctx = auth.WriteUserRoleToGrpcContext(ctx, s.config.DexRole)
+	}
+	return ctx
+}
+
+func statusName(status api.RolloutStatus) string {
+	if status == api.RolloutStatus_ROLLOUT_STATUS_UNHEALTHY {
+		return "unhealthy"
+	}
+	return "in error"
+}
+
+// rollbackRequest locks the app and deploys the previous version. The deployment
+// ignores locks, otherwise it would be queued behind the lock of the same batch.
+func rollbackRequest(key service.Key, d *degradation, previous types.ReleaseNumbers, gracePeriod time.Duration) *api.BatchRequest {
+	reason := fmt.Sprintf("the rollout of version %d stayed %s for more than %s", d.version, statusName(d.status), gracePeriod)
+	return &api.BatchRequest{
+		Actions: []*api.BatchAction{
+			{
+				Action: &api.BatchAction_CreateEnvironmentApplicationLock{
+					CreateEnvironmentApplicationLock: &api.CreateEnvironmentApplicationLockRequest{
+						Environment:       key.Environment,
+						Application:       key.Application,
+						LockId:            fmt.Sprintf("auto-rollback-%d", d.version),
+						Message:           fmt.Sprintf("Automatic rollback to version %d: %s. Delete this lock when the issue is fixed.", *previous.Version, reason),
+						CiLink:            "",
+						SuggestedLifeTime: nil,
+					},
+				},
+			},
+			{
+				Action: &api.BatchAction_Deploy{
+					//exhaustruct:ignore
+					Deploy: &api.DeployRequest{
+						Environment:  key.Environment,
+						Application:  key.Application,
+						Version:      *previous.Version,
+						Revision:     previous.Revision,
+						LockBehavior: api.LockBehavior_IGNORE,
+						AutoRollback: &api.AutoRollback{
+							RolledBackVersion: d.version,
+							Reason:            reason,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// PreviousVersion returns the last version and revision deployed to the environment before current.
+func PreviousVersion(ctx context.Context, dbHandler *db.DBHandler, application, environment string, current uint64) (*types.ReleaseNumbers, error) {
+	return db.WithTransactionT(dbHandler, ctx, 1, true, func(ctx context.Context, tx *sql.Tx) (*types.ReleaseNumbers, error) {
+		history, err := dbHandler.DBSelectDeploymentHistory(ctx, tx, types.AppName(application), environment, historyLimit)
+		if err != nil {
+			return nil, err
+		}
+		return previousVersion(history, current), nil
+	})
+}
+
+// previousVersion expects the history to be ordered from newest to oldest.
+func previousVersion(history []db.Deployment, current uint64) *types.ReleaseNumbers {
+	seenCurrent := false
+	for _, deployment := range history {
+		version := deployment.ReleaseNumbers.Version
+		if version == nil {
+			// an undeployment, there is nothing to go back to
+			return nil
+		}
+		if *version == current {
+			seenCurrent = true
+			continue
+		}
+		if seenCurrent {
+			return &deployment.ReleaseNumbers
+		}
+	}
+	return nil
+}
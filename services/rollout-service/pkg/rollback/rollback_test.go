@@ -0,0 +1,233 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package rollback
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/types"
+	"github.com/freiheit-com/kuberpult/services/rollout-service/pkg/service"
+	"github.com/freiheit-com/kuberpult/services/rollout-service/pkg/versions"
+)
+
+type mockBatchClient struct {
+	api.BatchServiceClient
+	requests []*api.BatchRequest
+}
+
+func (m *mockBatchClient) ProcessBatch(_ context.Context, in *api.BatchRequest, _ ...grpc.CallOption) (*api.BatchResponse, error) {
+	m.requests = append(m.requests, in)
+	return &api.BatchResponse{Results: nil}, nil
+}
+
+func releaseNumbers(version, revision uint64) types.ReleaseNumbers {
+	return types.ReleaseNumbers{Version: &version, Revision: revision}
+}
+
+func TestRollback(t *testing.T) {
+	start := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+	event := func(env string, version string, status api.RolloutStatus, deployedAt time.Time) *service.BroadcastEvent {
+		return &service.BroadcastEvent{
+			Key:              service.Key{Application: "app", Environment: env},
+			EnvironmentGroup: env,
+			Team:             "",
+			IsProduction:     nil,
+			ArgocdVersion:    &versions.VersionInfo{Version: types.RolloutAppBracketVersion(version), SourceCommitId: "", DeployedAt: deployedAt},
+			KuberpultVersion: &versions.VersionInfo{Version: types.RolloutAppBracketVersion(version), SourceCommitId: "", DeployedAt: deployedAt},
+			RolloutStatus:    status,
+		}
+	}
+	type step struct {
+		Event *service.BroadcastEvent
+		// time since start at which the event arrives, or the check runs if Event is nil
+		At time.Duration
+	}
+	tcs := []struct {
+		Name             string
+		Steps            []step
+		ExpectedRequests []*api.BatchRequest
+	}{
+		{
+			Name: "rolls back after the grace period",
+			Steps: []step{
+				{Event: event("production", "2", api.RolloutStatus_ROLLOUT_STATUS_ERROR, start)},
+				{At: 9 * time.Minute},
+				{At: 10 * time.Minute},
+				{At: 11 * time.Minute},
+			},
+			ExpectedRequests: []*api.BatchRequest{
+				rollbackRequest(service.Key{Application: "app", Environment: "production"}, &degradation{version: 2, status: api.RolloutStatus_ROLLOUT_STATUS_ERROR, since: start}, releaseNumbers(1, 3), 10*time.Minute),
+			},
+		},
+		{
+			Name: "recovered rollouts are not rolled back",
+			Steps: []step{
+				{Event: event("production", "2", api.RolloutStatus_ROLLOUT_STATUS_UNHEALTHY, start)},
+				{Event: event("production", "2", api.RolloutStatus_ROLLOUT_STATUS_SUCCESFUL, start), At: 5 * time.Minute},
+				{At: 11 * time.Minute},
+			},
+			ExpectedRequests: nil,
+		},
+		{
+			Name: "the grace period starts with the first degraded status",
+			Steps: []step{
+				{Event: event("production", "2", api.RolloutStatus_ROLLOUT_STATUS_ERROR, start)},
+				{Event: event("production", "2", api.RolloutStatus_ROLLOUT_STATUS_UNHEALTHY, start), At: 5 * time.Minute},
+				{At: 10 * time.Minute},
+			},
+			ExpectedRequests: []*api.BatchRequest{
+				rollbackRequest(service.Key{Application: "app", Environment: "production"}, &degradation{version: 2, status: api.RolloutStatus_ROLLOUT_STATUS_UNHEALTHY, since: start}, releaseNumbers(1, 3), 10*time.Minute),
+			},
+		},
+		{
+			Name: "environments without configuration are ignored",
+			Steps: []step{
+				{Event: event("staging", "2", api.RolloutStatus_ROLLOUT_STATUS_ERROR, start)},
+				{At: time.Hour},
+			},
+			ExpectedRequests: nil,
+		},
+		{
+			Name: "old deployments are not rolled back",
+			Steps: []step{
+				{Event: event("production", "2", api.RolloutStatus_ROLLOUT_STATUS_ERROR, start.Add(-2*time.Hour))},
+				{At: time.Hour},
+			},
+			ExpectedRequests: nil,
+		},
+		{
+			Name: "brackets are not rolled back",
+			Steps: []step{
+				{Event: event("production", "1:2:", api.RolloutStatus_ROLLOUT_STATUS_ERROR, start)},
+				{At: time.Hour},
+			},
+			ExpectedRequests: nil,
+		},
+		{
+			Name: "restored versions are not rolled back again",
+			Steps: []step{
+				{Event: event("production", "2", api.RolloutStatus_ROLLOUT_STATUS_ERROR, start)},
+				{At: 10 * time.Minute},
+				{Event: event("production", "1", api.RolloutStatus_ROLLOUT_STATUS_ERROR, start.Add(10*time.Minute)), At: 11 * time.Minute},
+				{At: 30 * time.Minute},
+			},
+			ExpectedRequests: []*api.BatchRequest{
+				rollbackRequest(service.Key{Application: "app", Environment: "production"}, &degradation{version: 2, status: api.RolloutStatus_ROLLOUT_STATUS_ERROR, since: start}, releaseNumbers(1, 3), 10*time.Minute),
+			},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := context.Background()
+			client := &mockBatchClient{BatchServiceClient: nil, requests: nil}
+			now := start
+			s := &Subscriber{
+				config: Config{
+					GracePeriods:  map[string]time.Duration{"production": 10 * time.Minute},
+					MaxAge:        time.Hour,
+					CheckInterval: time.Minute,
+					DexRole:       "",
+					DryRun:        false,
+				},
+				batchClient: client,
+				previousVersion: func(_ context.Context, _, _ string, current uint64) (*types.ReleaseNumbers, error) {
+					previous := releaseNumbers(current-1, 3)
+					return &previous, nil
+				},
+				state:    map[service.Key]*degradation{},
+				restored: map[service.Key]uint64{},
+				now:      func() time.Time { return now },
+				ready:    func() {},
+			}
+			for _, st := range tc.Steps {
+				now = start.Add(st.At)
+				if st.Event != nil {
+					s.process(st.Event)
+				} else {
+					s.check(ctx)
+				}
+			}
+			if diff := cmp.Diff(tc.ExpectedRequests, client.requests, protocmp.Transform()); diff != "" {
+				t.Errorf("requests mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPreviousVersion(t *testing.T) {
+	version := func(v uint64) *uint64 {
+		return &v
+	}
+	deployment := func(version *uint64) db.Deployment {
+		//exhaustruct:ignore
+		return db.Deployment{ReleaseNumbers: types.ReleaseNumbers{Version: version, Revision: 0}}
+	}
+	previous := func(v uint64, revision uint64) *types.ReleaseNumbers {
+		numbers := releaseNumbers(v, revision)
+		return &numbers
+	}
+	tcs := []struct {
+		Name     string
+		History  []db.Deployment
+		Expected *types.ReleaseNumbers
+	}{
+		{
+			Name:     "the deployment before the current one",
+			History:  []db.Deployment{deployment(version(3)), deployment(version(2)), deployment(version(1))},
+			Expected: previous(2, 0),
+		},
+		{
+			Name:     "re-deployments of the current version are skipped",
+			History:  []db.Deployment{deployment(version(3)), deployment(version(3)), deployment(version(1))},
+			Expected: previous(1, 0),
+		},
+		{
+			Name: "the revision of the previous deployment",
+			History: []db.Deployment{
+				deployment(version(3)),
+				//exhaustruct:ignore
+				{ReleaseNumbers: types.ReleaseNumbers{Version: version(2), Revision: 4}},
+			},
+			Expected: previous(2, 4),
+		},
+		{
+			Name:     "nothing to go back to after an undeployment",
+			History:  []db.Deployment{deployment(version(3)), deployment(nil), deployment(version(1))},
+			Expected: nil,
+		},
+		{
+			Name:     "first deployment",
+			History:  []db.Deployment{deployment(version(3))},
+			Expected: nil,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.Expected, previousVersion(tc.History, 3)); diff != "" {
+				t.Errorf("previous version mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+// Package rolloutevents records changes of the rollout status as commit events.
+//
+// The cd-service forwards the commit events to webhooks and as CloudEvents, so this is how
+// rollout status changes reach external systems. The event is written to the commit of the
+// version that kuberpult deployed.
+package rolloutevents
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
+	"github.com/freiheit-com/kuberpult/pkg/db"
+	"github.com/freiheit-com/kuberpult/pkg/event"
+	"github.com/freiheit-com/kuberpult/pkg/logger"
+	"github.com/freiheit-com/kuberpult/pkg/uuid"
+	"github.com/freiheit-com/kuberpult/services/rollout-service/pkg/service"
+)
+
+// RecordFunc writes the event for the commit.
+type RecordFunc func(ctx context.Context, commitHash string, ev *event.RolloutStatusChanged) error
+
+type rollout struct {
+	version string
+	status  string
+}
+
+type Subscriber struct {
+	record RecordFunc
+	// the last recorded rollout by app and environment
+	state map[service.Key]rollout
+	// The ready function is needed to sync tests
+	ready func()
+}
+
+func New(dbHandler *db.DBHandler) *Subscriber {
+	return &Subscriber{
+		record: func(ctx context.Context, commitHash string, ev *event.RolloutStatusChanged) error {
+			return dbHandler.WithTransactionR(ctx, 2, false, func(ctx context.Context, transaction *sql.Tx) error {
+				return dbHandler.DBWriteRolloutStatusChangedEvent(ctx, transaction, uuid.RealUUIDGenerator{}.Generate(), commitHash, ev)
+			})
+		},
+		state: map[service.Key]rollout{},
+		ready: func() {},
+	}
+}
+
+func (s *Subscriber) Subscribe(ctx context.Context, b *service.Broadcast) error {
+	for {
+		err := s.subscribeOnce(ctx, b)
+		select {
+		case <-ctx.Done():
+			return err
+		default:
+		}
+	}
+}
+
+func (s *Subscriber) subscribeOnce(ctx context.Context, b *service.Broadcast) error {
+	events, ch, unsub := b.Start()
+	defer unsub()
+	// the current state is not a change, it is only remembered
+	for _, ev := range events {
+		s.state[ev.Key] = rolloutOf(ev)
+	}
+	s.ready()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			s.process(ctx, ev)
+		}
+	}
+}
+
+func (s *Subscriber) process(ctx context.Context, ev *service.BroadcastEvent) {
+	current := rolloutOf(ev)
+	if previous, ok := s.state[ev.Key]; ok && previous == current {
+		return
+	}
+	s.state[ev.Key] = current
+	commitHash := ""
+	if ev.KuberpultVersion != nil {
+		commitHash = ev.KuberpultVersion.SourceCommitId
+	}
+	err := s.record(ctx, commitHash, &event.RolloutStatusChanged{
+		Application: ev.Application,
+		Environment: ev.Environment,
+		Version:     current.version,
+		Status:      current.status,
+	})
+	if err != nil {
+		// the next change is recorded again, a missing event is better than stopping the rollout-service
+		logger.FromContext(ctx).Warn("rolloutevents.record", zap.String("application", ev.Application), zap.String("environment", ev.Environment), zap.Error(err))
+	}
+}
+
+func rolloutOf(ev *service.BroadcastEvent) rollout {
+	version := ""
+	if ev.KuberpultVersion != nil {
+		if v, ok := ev.KuberpultVersion.Version.ToUint64(); ok {
+			version = strconv.FormatUint(v, 10)
+		}
+	}
+	return rollout{version: version, status: StatusName(ev.RolloutStatus)}
+}
+
+// StatusName is the name of the status in events.
+func StatusName(status api.RolloutStatus) string {
+	switch status {
+	case api.RolloutStatus_ROLLOUT_STATUS_SUCCESFUL:
+		return "successful"
+	case api.RolloutStatus_ROLLOUT_STATUS_PROGRESSING:
+		return "progressing"
+	case api.RolloutStatus_ROLLOUT_STATUS_PENDING:
+		return "pending"
+	case api.RolloutStatus_ROLLOUT_STATUS_ERROR:
+		return "error"
+	case api.RolloutStatus_ROLLOUT_STATUS_UNHEALTHY:
+		return "unhealthy"
+	default:
+		return "unknown"
+	}
+}
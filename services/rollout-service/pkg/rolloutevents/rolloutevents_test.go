@@ -0,0 +1,104 @@
+/*This file is part of kuberpult.
+
+Kuberpult is free software: you can redistribute it and/or modify
+it under the terms of the Expat(MIT) License as published by
+the Free Software Foundation.
+
+Kuberpult is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+MIT License for more details.
+
+You should have received a copy of the MIT License
+along with kuberpult. If not, see <https://directory.fsf.org/wiki/License:Expat>.
+
+Copyright freiheit.com*/
+
+package rolloutevents
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	api "github.com/freiheit-com/kuberpult/pkg/api/v1"
+	"github.com/freiheit-com/kuberpult/pkg/event"
+	"github.com/freiheit-com/kuberpult/pkg/types"
+	"github.com/freiheit-com/kuberpult/services/rollout-service/pkg/service"
+	"github.com/freiheit-com/kuberpult/services/rollout-service/pkg/versions"
+)
+
+type recorded struct {
+	CommitHash string
+	Event      event.RolloutStatusChanged
+}
+
+func TestProcess(t *testing.T) {
+	broadcastEvent := func(version string, status api.RolloutStatus) *service.BroadcastEvent {
+		//exhaustruct:ignore
+		return &service.BroadcastEvent{
+			Key: service.Key{Application: "app", Environment: "production"},
+			//exhaustruct:ignore
+			KuberpultVersion: &versions.VersionInfo{Version: types.RolloutAppBracketVersion(version), SourceCommitId: "commit-" + version},
+			RolloutStatus:    status,
+		}
+	}
+	tcs := []struct {
+		Name     string
+		Events   []*service.BroadcastEvent
+		Expected []recorded
+	}{
+		{
+			Name: "records status changes",
+			Events: []*service.BroadcastEvent{
+				broadcastEvent("2", api.RolloutStatus_ROLLOUT_STATUS_PROGRESSING),
+				broadcastEvent("2", api.RolloutStatus_ROLLOUT_STATUS_UNHEALTHY),
+			},
+			Expected: []recorded{
+				{CommitHash: "commit-2", Event: event.RolloutStatusChanged{Application: "app", Environment: "production", Version: "2", Status: "progressing"}},
+				{CommitHash: "commit-2", Event: event.RolloutStatusChanged{Application: "app", Environment: "production", Version: "2", Status: "unhealthy"}},
+			},
+		},
+		{
+			Name: "ignores events without a change",
+			Events: []*service.BroadcastEvent{
+				broadcastEvent("2", api.RolloutStatus_ROLLOUT_STATUS_SUCCESFUL),
+				broadcastEvent("2", api.RolloutStatus_ROLLOUT_STATUS_SUCCESFUL),
+			},
+			Expected: []recorded{
+				{CommitHash: "commit-2", Event: event.RolloutStatusChanged{Application: "app", Environment: "production", Version: "2", Status: "successful"}},
+			},
+		},
+		{
+			Name: "records a new version with the same status",
+			Events: []*service.BroadcastEvent{
+				broadcastEvent("2", api.RolloutStatus_ROLLOUT_STATUS_SUCCESFUL),
+				broadcastEvent("3", api.RolloutStatus_ROLLOUT_STATUS_SUCCESFUL),
+			},
+			Expected: []recorded{
+				{CommitHash: "commit-2", Event: event.RolloutStatusChanged{Application: "app", Environment: "production", Version: "2", Status: "successful"}},
+				{CommitHash: "commit-3", Event: event.RolloutStatusChanged{Application: "app", Environment: "production", Version: "3", Status: "successful"}},
+			},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			var actual []recorded
+			s := &Subscriber{
+				record: func(_ context.Context, commitHash string, ev *event.RolloutStatusChanged) error {
+					actual = append(actual, recorded{CommitHash: commitHash, Event: *ev})
+					return nil
+				},
+				state: map[service.Key]rollout{},
+				ready: func() {},
+			}
+			for _, ev := range tc.Events {
+				s.process(context.Background(), ev)
+			}
+			if diff := cmp.Diff(tc.Expected, actual); diff != "" {
+				t.Fatalf("recorded events mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
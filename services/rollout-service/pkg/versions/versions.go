@@ -49,6 +49,7 @@ var RolloutServiceUser auth.User = auth.User{
 	DexAuthContext: nil,
 	Email:          "kuberpult-rollout-service@local",
 	Name:           "kuberpult-rollout-service",
+	Identity:       auth.RolloutServiceIdentity,
 }
 
 type VersionClient interface {
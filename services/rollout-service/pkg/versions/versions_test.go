@@ -147,6 +147,18 @@ func (m *mockOverviewClient) GetAllManifestLocks(ctx context.Context, in *api.Ge
 	return nil, nil
 }
 
+func (m *mockOverviewClient) GetPendingDeployments(ctx context.Context, in *api.GetPendingDeploymentsRequest, opts ...grpc.CallOption) (*api.GetPendingDeploymentsResponse, error) {
+	return nil, nil
+}
+
+func (m *mockOverviewClient) GetQueuedDeployments(ctx context.Context, in *api.GetQueuedDeploymentsRequest, opts ...grpc.CallOption) (*api.GetQueuedDeploymentsResponse, error) {
+	return nil, nil
+}
+
+func (m *mockOverviewClient) GetReleaseRetentionPolicies(ctx context.Context, in *api.GetReleaseRetentionPoliciesRequest, opts ...grpc.CallOption) (*api.GetReleaseRetentionPoliciesResponse, error) {
+	return nil, nil
+}
+
 var _ api.OverviewServiceClient = (*mockOverviewClient)(nil)
 
 func TestGetVersion_Bracket(t *testing.T) {